@@ -7,13 +7,25 @@ package core
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sync"
 
 	"azugo.io/core/cache"
+	"azugo.io/core/cert"
+	"azugo.io/core/chaos"
 	"azugo.io/core/config"
+	"azugo.io/core/degrade"
+	"azugo.io/core/diagnostics"
+	"azugo.io/core/drain"
 	"azugo.io/core/instrumenter"
+	"azugo.io/core/logging"
+	"azugo.io/core/network"
+	"azugo.io/core/scrub"
+	"azugo.io/core/slo"
+	"azugo.io/core/templates"
 	"azugo.io/core/validation"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -29,23 +41,68 @@ type App struct {
 	validate *validation.Validate
 
 	// Logger
-	loglock sync.Mutex
-	logger  *zap.Logger
+	loglock   sync.Mutex
+	logger    *zap.Logger
+	logLevels *logging.Controller
+	scrubber  *scrub.Scrubber
 
 	// Configuration
 	config *config.Configuration
 
 	// Cache
-	cache *cache.Cache
+	cache       *cache.Cache
+	redisClient redis.UniversalClient
+
+	// Outbound network
+	netlock sync.Mutex
+	network *network.Network
+
+	// Fault injection
+	chaoslock sync.Mutex
+	chaos     *chaos.Injector
+
+	// Graceful degradation
+	degradelock sync.Mutex
+	degrade     *degrade.Engine
+
+	// Graceful draining
+	drainlock sync.Mutex
+	drainer   *drain.Drainer
+
+	// Dependency SLO tracking
+	slolock sync.Mutex
+	slo     *slo.Tracker
+
+	// Self-diagnostics
+	diaglock     sync.Mutex
+	diagnostics  *diagnostics.Registry
+	cacheLatency *diagnostics.Latency
+
+	// Templates
+	tpllock   sync.Mutex
+	templates *templates.Engine
+
+	// TLS certificates
+	tlslock      sync.Mutex
+	certificates *cert.SNIProvider
 
 	// Tasks
 	stlock  sync.RWMutex
 	tasks   []Tasker
 	started bool
 
+	// Warmup
+	warmlock     sync.Mutex
+	warmers      []*warmer
+	warmedUp     bool
+	warmupReport WarmupReport
+
 	// Instrumenter
 	instrumenter instrumenter.Instrumenter
 
+	// Services container
+	services *Services
+
 	// App settings
 	AppVer       string
 	AppBuiltWith string
@@ -66,6 +123,10 @@ func New() *App {
 		instrumenter: instrumenter.NullInstrumenter,
 
 		validate: validation.New(),
+
+		services: NewServices(),
+
+		cacheLatency: diagnostics.NewLatency(0),
 	}
 }
 
@@ -122,6 +183,14 @@ func (a *App) Config() *config.Configuration {
 	return a.config
 }
 
+// ConfigHandler returns admin HTTP handler that responds with the effective
+// configuration with secret values redacted.
+//
+// Application scrubber rules are applied in addition to config.RedactRules.
+func (a *App) ConfigHandler() http.Handler {
+	return a.Config().Handler(a.Scrubber().Rules()...)
+}
+
 // Instrumentation defines callback to be used as instrumenter.
 func (a *App) Instrumentation(instr instrumenter.Instrumenter) {
 	if instr == nil {
@@ -139,23 +208,44 @@ func (a *App) Instrumenter() instrumenter.Instrumenter {
 // Start web application.
 func (a *App) Start() error {
 	a.initLogger()
+	if a.Log().Core().Enabled(zap.DebugLevel) {
+		a.Log().Debug("Effective configuration", zap.Any("config", a.Config().Dump(a.Scrubber().Rules()...)))
+	}
 	if err := a.initCache(); err != nil {
 		return err
 	}
+	if err := a.initNetwork(); err != nil {
+		return err
+	}
+	if err := a.initCertificates(); err != nil {
+		return err
+	}
 	if err := a.startTasks(); err != nil {
 		return err
 	}
 
 	a.Log().Info(fmt.Sprintf("Starting %s...", a.String()))
 
-	return nil
+	return a.warmup()
 }
 
 // Stop application and its services
 func (a *App) Stop() {
+	a.drain()
+
 	a.bgstop()
 
+	a.warmlock.Lock()
+	a.warmedUp = false
+	a.warmlock.Unlock()
+
 	a.stopTasks()
 
+	a.services.Close()
+
 	a.closeCache()
+
+	if a.network != nil {
+		a.network.CloseIdleConnections()
+	}
 }
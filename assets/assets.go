@@ -0,0 +1,259 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"azugo.io/core/templates"
+)
+
+var (
+	cssURLRe    = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)`)
+	cssImportRe = regexp.MustCompile(`@import\s+(['"])([^'"]+)(['"])`)
+)
+
+type asset struct {
+	name        string
+	fingerprint string
+	etag        string
+	content     []byte
+	modTime     time.Time
+}
+
+// Pipeline fingerprints static assets and serves them with cache headers.
+//
+// Each asset is available under its original name and fingerprinted name containing
+// content hash, for example "css/app.css" and "css/app.3f2a1b4c.css". Fingerprinted
+// names are served with immutable cache headers so browsers and shared HTTP caches
+// can keep them without revalidation, original names must be revalidated using ETag.
+//
+// References to other assets in rewritten assets (CSS by default) are replaced with
+// fingerprinted names, so that hash of the referencing asset changes when referenced
+// asset changes.
+type Pipeline struct {
+	opt    *options
+	assets map[string]*asset
+	served map[string]*asset
+}
+
+// New returns new asset pipeline with all assets loaded and fingerprinted.
+func New(opts ...Option) (*Pipeline, error) {
+	p := &Pipeline{
+		opt:    newOptions(opts...),
+		assets: make(map[string]*asset),
+		served: make(map[string]*asset),
+	}
+
+	sources := make(map[string][]byte)
+	modTimes := make(map[string]time.Time)
+	err := fs.WalkDir(p.opt.FS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		data, err := fs.ReadFile(p.opt.FS, name)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sources[name] = data
+		modTimes[name] = info.ModTime()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	visiting := make(map[string]bool)
+	var process func(name string) *asset
+	process = func(name string) *asset {
+		if a, ok := p.assets[name]; ok {
+			return a
+		}
+		if visiting[name] {
+			// Circular reference, keep original name.
+			return nil
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		content, ok := sources[name]
+		if !ok {
+			return nil
+		}
+		if p.rewritable(name) {
+			content = p.rewrite(name, content, process)
+		}
+
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		ext := path.Ext(name)
+		a := &asset{
+			name:        name,
+			fingerprint: strings.TrimSuffix(name, ext) + "." + hash[:p.opt.HashLength] + ext,
+			etag:        strconv.Quote(hash[:32]),
+			content:     content,
+			modTime:     modTimes[name],
+		}
+		p.assets[name] = a
+		p.served[a.name] = a
+		p.served[a.fingerprint] = a
+		return a
+	}
+	for name := range sources {
+		process(name)
+	}
+
+	return p, nil
+}
+
+func (p *Pipeline) rewritable(name string) bool {
+	ext := path.Ext(name)
+	for _, e := range p.opt.Rewrite {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// rewrite replaces references to other assets with fingerprinted names.
+func (p *Pipeline) rewrite(name string, content []byte, process func(name string) *asset) []byte {
+	replace := func(re *regexp.Regexp, content []byte) []byte {
+		return re.ReplaceAllFunc(content, func(m []byte) []byte {
+			sm := re.FindSubmatch(m)
+			if !bytes.Equal(sm[1], sm[3]) {
+				return m
+			}
+			ref := string(sm[2])
+			target, ok := p.resolveRef(name, ref)
+			if !ok {
+				return m
+			}
+			a := process(target)
+			if a == nil {
+				return m
+			}
+			refPath, suffix := ref, ""
+			if i := strings.IndexAny(ref, "?#"); i >= 0 {
+				refPath, suffix = ref[:i], ref[i:]
+			}
+			newRef := refPath[:strings.LastIndexByte(refPath, '/')+1] + path.Base(a.fingerprint) + suffix
+			return bytes.Replace(m, sm[2], []byte(newRef), 1)
+		})
+	}
+	content = replace(cssURLRe, content)
+	return replace(cssImportRe, content)
+}
+
+// resolveRef resolves reference in the asset to the asset name.
+func (p *Pipeline) resolveRef(name, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if len(ref) == 0 || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "//") || strings.Contains(ref, ":") {
+		return "", false
+	}
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	var target string
+	if strings.HasPrefix(ref, "/") {
+		if !strings.HasPrefix(ref, p.opt.Prefix) {
+			return "", false
+		}
+		target = strings.TrimPrefix(ref, p.opt.Prefix)
+	} else {
+		target = path.Join(path.Dir(name), ref)
+	}
+	if target == name {
+		return "", false
+	}
+	return target, true
+}
+
+// Path returns URL path of the fingerprinted asset.
+//
+// If asset does not exist, URL path of the original name is returned.
+func (p *Pipeline) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if a, ok := p.assets[name]; ok {
+		return p.opt.Prefix + a.fingerprint
+	}
+	return p.opt.Prefix + name
+}
+
+// Hash returns content hash of the asset or empty string if asset does not exist.
+func (p *Pipeline) Hash(name string) string {
+	a, ok := p.assets[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return ""
+	}
+	return strings.Trim(a.etag, `"`)
+}
+
+// Funcs returns template functions to reference assets from templates.
+//
+// Function "asset" returns URL path of the fingerprinted asset.
+func (p *Pipeline) Funcs() templates.Funcs {
+	return templates.Funcs{
+		"asset": p.Path,
+	}
+}
+
+// ServeHTTP serves assets under the configured prefix.
+func (p *Pipeline) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, p.opt.Prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, p.opt.Prefix)
+	a, ok := p.served[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	h := w.Header()
+	h.Set("ETag", a.etag)
+	if name == a.fingerprint {
+		h.Set("Cache-Control", CacheControl(p.opt.MaxAge, true))
+	} else {
+		h.Set("Cache-Control", "no-cache")
+	}
+	http.ServeContent(w, r, path.Base(a.name), a.modTime, bytes.NewReader(a.content))
+}
+
+// CacheControl returns Cache-Control header value for public responses cached for
+// specified duration. Immutable responses are not revalidated by clients until expired.
+func CacheControl(maxAge time.Duration, immutable bool) string {
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	v := fmt.Sprintf("public, max-age=%d", int64(maxAge/time.Second))
+	if immutable {
+		v += ", immutable"
+	}
+	return v
+}
@@ -0,0 +1,126 @@
+package assets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"azugo.io/core/templates"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"css/app.css":   {Data: []byte(`@import "base.css"; body { background: url('../img/bg.png?v=1'); } .logo { background: url(/static/img/logo.svg); } .ext { background: url(https://example.com/x.png); }`)},
+		"css/base.css":  {Data: []byte(`html { margin: 0; }`)},
+		"img/bg.png":    {Data: []byte("png")},
+		"img/logo.svg":  {Data: []byte("<svg/>")},
+		"js/app.min.js": {Data: []byte(`console.log("app.css")`)},
+	}
+}
+
+func TestPipelineFingerprint(t *testing.T) {
+	p, err := New(FS{testFS()})
+	require.NoError(t, err)
+
+	bg := p.Path("img/bg.png")
+	assert.Regexp(t, `^/static/img/bg\.[0-9a-f]{8}\.png$`, bg)
+	assert.Regexp(t, `^/static/js/app\.min\.[0-9a-f]{8}\.js$`, p.Path("/js/app.min.js"))
+	assert.Equal(t, "/static/missing.js", p.Path("missing.js"))
+	assert.Len(t, p.Hash("img/bg.png"), 32)
+	assert.Empty(t, p.Hash("missing.js"))
+
+	css := string(p.assets["css/app.css"].content)
+	assert.Contains(t, css, `@import "`+strings.TrimPrefix(p.Path("css/base.css"), "/static/css/")+`"`)
+	assert.Contains(t, css, `url('../img/`+strings.TrimPrefix(bg, "/static/img/")+`?v=1')`)
+	assert.Contains(t, css, `url(/static/img/`+strings.TrimPrefix(p.Path("img/logo.svg"), "/static/img/")+`)`)
+	assert.Contains(t, css, `url(https://example.com/x.png)`)
+
+	// JavaScript is not rewritten by default.
+	assert.Equal(t, `console.log("app.css")`, string(p.assets["js/app.min.js"].content))
+}
+
+func TestPipelineHashChangesWithReference(t *testing.T) {
+	fsys := testFS()
+	p1, err := New(FS{fsys})
+	require.NoError(t, err)
+
+	fsys["img/bg.png"] = &fstest.MapFile{Data: []byte("png2")}
+	p2, err := New(FS{fsys})
+	require.NoError(t, err)
+
+	assert.NotEqual(t, p1.Path("img/bg.png"), p2.Path("img/bg.png"))
+	assert.NotEqual(t, p1.Path("css/app.css"), p2.Path("css/app.css"))
+	assert.Equal(t, p1.Path("css/base.css"), p2.Path("css/base.css"))
+}
+
+func TestPipelineCircularReference(t *testing.T) {
+	p, err := New(FS{fstest.MapFS{
+		"a.css": {Data: []byte(`@import "b.css";`)},
+		"b.css": {Data: []byte(`@import "a.css";`)},
+	}})
+	require.NoError(t, err)
+
+	assert.NotEqual(t, "/static/a.css", p.Path("a.css"))
+	assert.NotEqual(t, "/static/b.css", p.Path("b.css"))
+}
+
+func TestPipelineServeHTTP(t *testing.T) {
+	p, err := New(FS{testFS()}, Prefix("assets"), MaxAge(24*time.Hour))
+	require.NoError(t, err)
+
+	path := p.Path("img/logo.svg")
+	require.True(t, strings.HasPrefix(path, "/assets/img/logo."))
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<svg/>", w.Body.String())
+	assert.Equal(t, "public, max-age=86400, immutable", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/assets/img/logo.svg", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+
+	r := httptest.NewRequest(http.MethodGet, "/assets/img/logo.svg", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/assets/img/missing.svg", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestPipelineTemplateFuncs(t *testing.T) {
+	p, err := New(FS{testFS()})
+	require.NoError(t, err)
+
+	e := templates.New(templates.FS{FS: fstest.MapFS{
+		"index.html": {Data: []byte(`<script src="{{ asset "js/app.min.js" }}"></script>`)},
+	}}, p.Funcs())
+
+	s, err := e.String(context.TODO(), "index.html", nil)
+	require.NoError(t, err)
+	assert.Equal(t, `<script src="`+p.Path("js/app.min.js")+`"></script>`, s)
+}
+
+func TestCacheControl(t *testing.T) {
+	assert.Equal(t, "no-cache", CacheControl(0, true))
+	assert.Equal(t, "public, max-age=60", CacheControl(time.Minute, false))
+}
@@ -0,0 +1,89 @@
+package assets
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+type options struct {
+	FS         fs.FS
+	Prefix     string
+	MaxAge     time.Duration
+	HashLength int
+	Rewrite    []string
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Prefix:     "/static/",
+		MaxAge:     365 * 24 * time.Hour,
+		HashLength: 8,
+		Rewrite:    []string{".css"},
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.FS == nil {
+		opt.FS = os.DirFS("static")
+	}
+	if opt.HashLength < 4 || opt.HashLength > 64 {
+		opt.HashLength = 8
+	}
+	return opt
+}
+
+// Option for the asset pipeline.
+type Option interface {
+	apply(*options)
+}
+
+// FS is a file system to load static assets from.
+type FS struct {
+	fs.FS
+}
+
+func (f FS) apply(o *options) {
+	o.FS = f.FS
+}
+
+// Dir is a directory to load static assets from. Defaults to "static".
+type Dir string
+
+func (d Dir) apply(o *options) {
+	o.FS = os.DirFS(string(d))
+}
+
+// Prefix is an URL path prefix assets are served from. Defaults to "/static/".
+type Prefix string
+
+func (p Prefix) apply(o *options) {
+	o.Prefix = "/" + strings.Trim(string(p), "/") + "/"
+	if o.Prefix == "//" {
+		o.Prefix = "/"
+	}
+}
+
+// MaxAge is a cache lifetime of fingerprinted assets. Defaults to one year.
+type MaxAge time.Duration
+
+func (m MaxAge) apply(o *options) {
+	o.MaxAge = time.Duration(m)
+}
+
+// HashLength is a number of hex characters of the content hash used in
+// fingerprinted file names. Defaults to 8.
+type HashLength int
+
+func (l HashLength) apply(o *options) {
+	o.HashLength = int(l)
+}
+
+// Rewrite are file extensions of assets in which references to other assets are
+// rewritten to fingerprinted names. Defaults to ".css".
+type Rewrite []string
+
+func (r Rewrite) apply(o *options) {
+	o.Rewrite = r
+}
@@ -0,0 +1,559 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/keyring"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultPrefix is a default prefix for the backup blob names.
+	DefaultPrefix = "backups"
+
+	// InstrumentationBackup is an instrumentation operation for making a backup.
+	InstrumentationBackup = "backup"
+	// InstrumentationRestore is an instrumentation operation for restoring from a backup.
+	InstrumentationRestore = "backup-restore"
+
+	manifestName = "manifest.json"
+	componentDir = "components/"
+	blobExt      = ".tar.gz"
+	encryptedExt = ".enc"
+	idFormat     = "20060102T150405.000000000Z"
+)
+
+// ErrBackupNotFound is returned when backup is not found in the storage.
+var ErrBackupNotFound = errors.New("backup not found")
+
+// Handler backs up and restores state of the component.
+type Handler interface {
+	// Backup writes component state to the writer.
+	Backup(ctx context.Context, w io.Writer) error
+	// Restore replaces component state with the state read from the reader.
+	Restore(ctx context.Context, r io.Reader) error
+}
+
+// Freezer can be implemented by handlers to stop accepting changes while
+// backup of all components is made so that backup is consistent across them.
+type Freezer interface {
+	// Freeze component state and return function to release it.
+	Freeze(ctx context.Context) (func(), error)
+}
+
+// Funcs implements Handler interface using functions.
+type Funcs struct {
+	BackupFunc  func(ctx context.Context, w io.Writer) error
+	RestoreFunc func(ctx context.Context, r io.Reader) error
+}
+
+// Backup calls BackupFunc.
+func (f Funcs) Backup(ctx context.Context, w io.Writer) error {
+	return f.BackupFunc(ctx, w)
+}
+
+// Restore calls RestoreFunc.
+func (f Funcs) Restore(ctx context.Context, r io.Reader) error {
+	return f.RestoreFunc(ctx, r)
+}
+
+// Component is a description of the component state in backup.
+type Component struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes backup contents.
+type Manifest struct {
+	ID         string      `json:"id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Encrypted  bool        `json:"-"`
+	Components []Component `json:"components"`
+}
+
+type component struct {
+	name    string
+	handler Handler
+}
+
+// Manager coordinates backups and restores of registered components to the storage.
+//
+// Manager implements core.Tasker interface to make scheduled backups.
+type Manager struct {
+	storage      Storage
+	prefix       string
+	interval     time.Duration
+	scheduler    Scheduler
+	retain       int
+	keyring      *keyring.KeyRing
+	instrumenter instrumenter.Instrumenter
+	logger       *zap.Logger
+	now          func() time.Time
+
+	lock       sync.Mutex
+	components []component
+
+	// run serializes backups and restores.
+	run sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates new backup manager storing backups in the storage.
+func New(storage Storage, opts ...Option) *Manager {
+	opt := newOptions(opts...)
+
+	return &Manager{
+		storage:      storage,
+		prefix:       strings.TrimSuffix(opt.Prefix, "/"),
+		interval:     opt.Interval,
+		scheduler:    opt.Schedule,
+		retain:       opt.Retain,
+		keyring:      opt.KeyRing,
+		instrumenter: opt.Instrumenter,
+		logger:       opt.Logger,
+		now:          time.Now,
+	}
+}
+
+// Register component backup handler with a unique name.
+func (m *Manager) Register(name string, h Handler) error {
+	if len(name) == 0 || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("invalid backup component name %q", name)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, c := range m.components {
+		if c.name == name {
+			return fmt.Errorf("backup component %q already registered", name)
+		}
+	}
+	m.components = append(m.components, component{name: name, handler: h})
+	return nil
+}
+
+func (m *Manager) registered() []component {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]component(nil), m.components...)
+}
+
+func (m *Manager) blobName(id string) string {
+	name := id + blobExt
+	if m.keyring != nil {
+		name += encryptedExt
+	}
+	return path.Join(m.prefix, name)
+}
+
+// freeze all components that support it.
+func freeze(ctx context.Context, components []component) (func(), error) {
+	release := make([]func(), 0, len(components))
+	unfreeze := func() {
+		for i := len(release) - 1; i >= 0; i-- {
+			release[i]()
+		}
+	}
+	for _, c := range components {
+		f, ok := c.handler.(Freezer)
+		if !ok {
+			continue
+		}
+		r, err := f.Freeze(ctx)
+		if err != nil {
+			unfreeze()
+			return nil, fmt.Errorf("failed to freeze backup component %q: %w", c.name, err)
+		}
+		release = append(release, r)
+	}
+	return unfreeze, nil
+}
+
+// Backup makes a consistent backup of all registered components and stores it in the storage.
+func (m *Manager) Backup(ctx context.Context) (*Manifest, error) {
+	m.run.Lock()
+	defer m.run.Unlock()
+
+	components := m.registered()
+	manifest := &Manifest{
+		CreatedAt:  m.now().UTC(),
+		Encrypted:  m.keyring != nil,
+		Components: make([]Component, 0, len(components)),
+	}
+	manifest.ID = manifest.CreatedAt.Format(idFormat)
+
+	finish := m.instrumenter.Observe(ctx, InstrumentationBackup, manifest.ID)
+
+	data, err := m.snapshot(ctx, components, manifest)
+	if err != nil {
+		finish(err)
+		return nil, err
+	}
+	if m.keyring != nil {
+		if data, err = m.keyring.Encrypt(data); err != nil {
+			finish(err)
+			return nil, err
+		}
+	}
+	if err := m.storage.Put(ctx, m.blobName(manifest.ID), bytes.NewReader(data)); err != nil {
+		finish(err)
+		return nil, err
+	}
+	finish(nil)
+
+	if err := m.prune(ctx); err != nil {
+		m.logger.Warn("failed to remove old backups", zap.Error(err))
+	}
+	return manifest, nil
+}
+
+// snapshot returns archive with backups of all components.
+func (m *Manager) snapshot(ctx context.Context, components []component, manifest *Manifest) ([]byte, error) {
+	unfreeze, err := freeze(ctx, components)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([][]byte, 0, len(components))
+	for _, c := range components {
+		buf := &bytes.Buffer{}
+		if err := c.handler.Backup(ctx, buf); err != nil {
+			unfreeze()
+			return nil, fmt.Errorf("failed to backup component %q: %w", c.name, err)
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		manifest.Components = append(manifest.Components, Component{
+			Name:   c.name,
+			Size:   int64(buf.Len()),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+		parts = append(parts, buf.Bytes())
+	}
+	unfreeze()
+
+	out := &bytes.Buffer{}
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	mbuf, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(tw, manifestName, manifest.CreatedAt, mbuf); err != nil {
+		return nil, err
+	}
+	for i, c := range manifest.Components {
+		if err := writeEntry(tw, componentDir+c.Name, manifest.CreatedAt, parts[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func writeEntry(tw *tar.Writer, name string, modTime time.Time, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// List returns IDs of the backups in the storage, oldest first.
+func (m *Manager) List(ctx context.Context) ([]string, error) {
+	names, err := m.storage.List(ctx, m.prefix+"/")
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSuffix(path.Base(name), encryptedExt)
+		if !strings.HasSuffix(name, blobExt) {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, blobExt))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// prune removes old backups over the retention limit.
+func (m *Manager) prune(ctx context.Context) error {
+	if m.retain <= 0 {
+		return nil
+	}
+	ids, err := m.List(ctx)
+	if err != nil {
+		return err
+	}
+	for len(ids) > m.retain {
+		if err := m.storage.Delete(ctx, m.blobName(ids[0])); err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// open reads and verifies backup archive returning its manifest and component states.
+func (m *Manager) open(ctx context.Context, id string) (*Manifest, map[string][]byte, error) {
+	r, err := m.storage.Get(ctx, m.blobName(id))
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil, nil, ErrBackupNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(r)
+	_ = r.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	if m.keyring != nil {
+		if data, err = m.keyring.Decrypt(data); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid backup archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var manifest *Manifest
+	parts := make(map[string][]byte)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid backup archive: %w", err)
+		}
+		buf, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid backup archive: %w", err)
+		}
+		switch {
+		case h.Name == manifestName:
+			manifest = &Manifest{}
+			if err := json.Unmarshal(buf, manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid backup manifest: %w", err)
+			}
+			manifest.Encrypted = m.keyring != nil
+		case strings.HasPrefix(h.Name, componentDir):
+			parts[strings.TrimPrefix(h.Name, componentDir)] = buf
+		}
+	}
+	if manifest == nil {
+		return nil, nil, errors.New("invalid backup archive: manifest missing")
+	}
+	for _, c := range manifest.Components {
+		buf, ok := parts[c.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid backup archive: component %q missing", c.Name)
+		}
+		sum := sha256.Sum256(buf)
+		if hex.EncodeToString(sum[:]) != c.SHA256 {
+			return nil, nil, fmt.Errorf("invalid backup archive: component %q checksum mismatch", c.Name)
+		}
+	}
+	return manifest, parts, nil
+}
+
+// Manifest returns manifest of the backup.
+func (m *Manager) Manifest(ctx context.Context, id string) (*Manifest, error) {
+	manifest, _, err := m.open(ctx, id)
+	return manifest, err
+}
+
+// Latest returns ID of the latest backup. Returns ErrBackupNotFound if there are no backups.
+func (m *Manager) Latest(ctx context.Context) (string, error) {
+	ids, err := m.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(ids) == 0 {
+		return "", ErrBackupNotFound
+	}
+	return ids[len(ids)-1], nil
+}
+
+// Restore registered components from the backup with specified ID. If ID is empty
+// latest backup is used.
+//
+// If component names are specified, only those components are restored, otherwise
+// all registered components that are present in the backup are restored.
+func (m *Manager) Restore(ctx context.Context, id string, names ...string) error {
+	m.run.Lock()
+	defer m.run.Unlock()
+
+	if len(id) == 0 {
+		var err error
+		if id, err = m.Latest(ctx); err != nil {
+			return err
+		}
+	}
+
+	finish := m.instrumenter.Observe(ctx, InstrumentationRestore, id)
+
+	_, parts, err := m.open(ctx, id)
+	if err != nil {
+		finish(err)
+		return err
+	}
+
+	components := m.registered()
+	if len(names) > 0 {
+		selected := make([]component, 0, len(names))
+		for _, name := range names {
+			var found bool
+			for _, c := range components {
+				if c.name == name {
+					selected = append(selected, c)
+					found = true
+					break
+				}
+			}
+			if !found {
+				err := fmt.Errorf("backup component %q not registered", name)
+				finish(err)
+				return err
+			}
+			if _, ok := parts[name]; !ok {
+				err := fmt.Errorf("backup component %q not found in backup %s", name, id)
+				finish(err)
+				return err
+			}
+		}
+		components = selected
+	}
+
+	unfreeze, err := freeze(ctx, components)
+	if err != nil {
+		finish(err)
+		return err
+	}
+	defer unfreeze()
+
+	for _, c := range components {
+		buf, ok := parts[c.name]
+		if !ok {
+			m.logger.Warn("backup component not found in backup", zap.String("backup.component", c.name), zap.String("backup.id", id))
+			continue
+		}
+		if err := c.handler.Restore(ctx, bytes.NewReader(buf)); err != nil {
+			err = fmt.Errorf("failed to restore component %q: %w", c.name, err)
+			finish(err)
+			return err
+		}
+	}
+	finish(nil)
+	return nil
+}
+
+// Name returns task name.
+func (m *Manager) Name() string {
+	return "backup"
+}
+
+// Start scheduled backups if interval or schedule is set.
+func (m *Manager) Start(ctx context.Context) error {
+	if m.interval <= 0 && m.scheduler == nil {
+		return nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stop != nil {
+		return nil
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+
+	go m.schedule(ctx, m.stop, m.done)
+	return nil
+}
+
+func (m *Manager) schedule(ctx context.Context, stop, done chan struct{}) {
+	defer close(done)
+
+	for {
+		next := m.next()
+		if next.IsZero() {
+			return
+		}
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-stop:
+			t.Stop()
+			return
+		case <-t.C:
+			if _, err := m.Backup(ctx); err != nil {
+				m.logger.Error("scheduled backup failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// next returns time of the next scheduled backup.
+func (m *Manager) next() time.Time {
+	now := m.now()
+	if m.scheduler != nil {
+		return m.scheduler.Next(now)
+	}
+	return now.Add(m.interval)
+}
+
+// Stop scheduled backups.
+func (m *Manager) Stop() {
+	m.lock.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done = nil, nil
+	m.lock.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
@@ -0,0 +1,240 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/keyring"
+	"azugo.io/core/settings"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testComponent struct {
+	state  string
+	frozen atomic.Int32
+}
+
+func (c *testComponent) Backup(_ context.Context, w io.Writer) error {
+	if c.frozen.Load() == 0 {
+		return errors.New("not frozen")
+	}
+	_, err := io.WriteString(w, c.state)
+	return err
+}
+
+func (c *testComponent) Restore(_ context.Context, r io.Reader) error {
+	buf, err := io.ReadAll(r)
+	c.state = string(buf)
+	return err
+}
+
+func (c *testComponent) Freeze(_ context.Context) (func(), error) {
+	c.frozen.Add(1)
+	return func() { c.frozen.Add(-1) }, nil
+}
+
+func newTestSettings(t *testing.T) *settings.Store {
+	t.Helper()
+
+	c := cache.New(cache.MemoryCache)
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(c.Close)
+
+	s, err := settings.New(c)
+	require.NoError(t, err)
+	return s
+}
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+
+	store := newTestSettings(t)
+	limit, err := settings.Register(store, "limit", 10)
+	require.NoError(t, err)
+	greeting, err := settings.Register(store, "greeting", "hello")
+	require.NoError(t, err)
+	require.NoError(t, limit.Set(ctx, 20, ""))
+
+	ring := keyring.New(keyring.Key{ID: "k1", Secret: []byte("0123456789abcdef0123456789abcdef")})
+	comp := &testComponent{state: "v1"}
+
+	m := New(Dir(t.TempDir()))
+	require.NoError(t, m.Register("settings", store))
+	require.NoError(t, m.Register("keyring", ring))
+	require.NoError(t, m.Register("component", comp))
+	assert.Error(t, m.Register("component", comp))
+	assert.Error(t, m.Register("a/b", comp))
+
+	manifest, err := m.Backup(ctx)
+	require.NoError(t, err)
+	require.Len(t, manifest.Components, 3)
+	assert.Equal(t, "settings", manifest.Components[0].Name)
+	assert.Equal(t, int32(0), comp.frozen.Load())
+
+	require.NoError(t, limit.Set(ctx, 30, ""))
+	require.NoError(t, greeting.Set(ctx, "hi", ""))
+	ring.Rotate(keyring.Key{ID: "k2", Secret: []byte("abcdef0123456789abcdef0123456789")})
+	comp.state = "v2"
+
+	ids, err := m.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{manifest.ID}, ids)
+
+	// Restore single component.
+	require.NoError(t, m.Restore(ctx, manifest.ID, "component"))
+	assert.Equal(t, "v1", comp.state)
+	assert.Len(t, ring.Keys(), 2)
+
+	require.NoError(t, m.Restore(ctx, ""))
+
+	v, err := limit.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 20, v)
+	g, err := greeting.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", g)
+	rec, err := store.Record(ctx, "greeting")
+	require.NoError(t, err)
+	assert.Nil(t, rec)
+
+	keys := ring.Keys()
+	require.Len(t, keys, 1)
+	assert.Equal(t, "k1", keys[0].ID)
+
+	assert.Error(t, m.Restore(ctx, manifest.ID, "unknown"))
+	assert.ErrorIs(t, m.Restore(ctx, "missing"), ErrBackupNotFound)
+}
+
+func TestBackupEncryptedRetention(t *testing.T) {
+	ctx := context.Background()
+
+	storage := &Memory{}
+	ring := keyring.New(keyring.Key{ID: "k1", Secret: []byte("0123456789abcdef0123456789abcdef")})
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := New(storage, Retain(2), KeyRing{ring})
+	m.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	comp := &testComponent{}
+	require.NoError(t, m.Register("component", comp))
+
+	for _, state := range []string{"a", "b", "c"} {
+		comp.state = state
+		_, err := m.Backup(ctx)
+		require.NoError(t, err)
+	}
+
+	ids, err := m.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+
+	names, err := storage.List(ctx, DefaultPrefix+"/")
+	require.NoError(t, err)
+	for _, name := range names {
+		assert.True(t, strings.HasSuffix(name, ".tar.gz.enc"))
+	}
+
+	require.NoError(t, m.Restore(ctx, ids[0]))
+	assert.Equal(t, "b", comp.state)
+
+	manifest, err := m.Manifest(ctx, ids[1])
+	require.NoError(t, err)
+	assert.True(t, manifest.Encrypted)
+
+	// Backup can not be read without the key.
+	other := New(storage, KeyRing{keyring.New(keyring.Key{ID: "k1", Secret: []byte("abcdef0123456789abcdef0123456789")})})
+	_, err = other.Manifest(ctx, ids[1])
+	assert.Error(t, err)
+}
+
+func TestBackupSchedule(t *testing.T) {
+	storage := &Memory{}
+	m := New(storage, Interval(10*time.Millisecond))
+	require.NoError(t, m.Register("component", Funcs{
+		BackupFunc: func(ctx context.Context, w io.Writer) error {
+			_, err := io.WriteString(w, "state")
+			return err
+		},
+		RestoreFunc: func(ctx context.Context, r io.Reader) error {
+			return nil
+		},
+	}))
+
+	require.NoError(t, m.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		ids, err := m.List(context.Background())
+		return err == nil && len(ids) > 0
+	}, time.Second, 5*time.Millisecond)
+	m.Stop()
+	m.Stop()
+}
+
+type scheduleFunc func(t time.Time) time.Time
+
+func (f scheduleFunc) Next(t time.Time) time.Time {
+	return f(t)
+}
+
+func TestBackupCustomSchedule(t *testing.T) {
+	storage := &Memory{}
+	runs := 0
+	m := New(storage, Schedule{scheduleFunc(func(t time.Time) time.Time {
+		runs++
+		if runs > 2 {
+			return time.Time{}
+		}
+		return t.Add(5 * time.Millisecond)
+	})})
+	require.NoError(t, m.Register("component", Funcs{
+		BackupFunc: func(ctx context.Context, w io.Writer) error {
+			_, err := io.WriteString(w, "state")
+			return err
+		},
+		RestoreFunc: func(ctx context.Context, r io.Reader) error {
+			return nil
+		},
+	}))
+
+	require.NoError(t, m.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		ids, err := m.List(context.Background())
+		return err == nil && len(ids) == 2
+	}, time.Second, 5*time.Millisecond)
+	m.Stop()
+	assert.Equal(t, 3, runs)
+}
+
+func TestDirStorage(t *testing.T) {
+	ctx := context.Background()
+	d := Dir(t.TempDir())
+
+	require.NoError(t, d.Put(ctx, "a/b.txt", strings.NewReader("data")))
+
+	r, err := d.Get(ctx, "a/b.txt")
+	require.NoError(t, err)
+	buf, err := io.ReadAll(r)
+	require.NoError(t, err)
+	_ = r.Close()
+	assert.Equal(t, "data", string(buf))
+
+	names, err := d.List(ctx, "a/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/b.txt"}, names)
+
+	require.NoError(t, d.Delete(ctx, "a/b.txt"))
+	_, err = d.Get(ctx, "a/b.txt")
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+
+	assert.Error(t, d.Put(ctx, "../escape", strings.NewReader("data")))
+}
@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"time"
+
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/keyring"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	Prefix       string
+	Interval     time.Duration
+	Schedule     Scheduler
+	Retain       int
+	KeyRing      *keyring.KeyRing
+	Instrumenter instrumenter.Instrumenter
+	Logger       *zap.Logger
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Prefix: DefaultPrefix,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return opt
+}
+
+// Option for the backup manager.
+type Option interface {
+	apply(*options)
+}
+
+// Prefix is a prefix for the backup blob names in the storage.
+type Prefix string
+
+func (p Prefix) apply(o *options) {
+	o.Prefix = string(p)
+}
+
+// Interval is an interval to make scheduled backups. Zero disables scheduled backups.
+type Interval time.Duration
+
+func (i Interval) apply(o *options) {
+	o.Interval = time.Duration(i)
+}
+
+// Scheduler returns next time to run after the given time, for example config.Cron.
+// Zero time stops scheduling.
+type Scheduler interface {
+	Next(t time.Time) time.Time
+}
+
+// Schedule makes scheduled backups at times returned by the scheduler. Overrides Interval.
+type Schedule struct {
+	Scheduler
+}
+
+func (s Schedule) apply(o *options) {
+	o.Schedule = s.Scheduler
+}
+
+// Retain is a number of latest backups to keep in the storage. Zero keeps all backups.
+type Retain int
+
+func (r Retain) apply(o *options) {
+	o.Retain = int(r)
+}
+
+// KeyRing to encrypt backups with.
+type KeyRing struct {
+	*keyring.KeyRing
+}
+
+func (k KeyRing) apply(o *options) {
+	o.KeyRing = k.KeyRing
+}
+
+// Instrumenter to observe backups and restores.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
+
+// Logger to log scheduled backup errors.
+type Logger struct {
+	*zap.Logger
+}
+
+func (l Logger) apply(o *options) {
+	o.Logger = l.Logger
+}
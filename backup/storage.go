@@ -0,0 +1,183 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrBlobNotFound is returned when blob is not found in the storage.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// Storage is a blob storage to store backups in.
+type Storage interface {
+	// Put stores blob with the name.
+	Put(ctx context.Context, name string, r io.Reader) error
+	// Get returns blob content. Returns ErrBlobNotFound if blob does not exist.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns names of the blobs with the prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete blob from the storage.
+	Delete(ctx context.Context, name string) error
+}
+
+// Dir is a blob storage in the local file system directory.
+type Dir string
+
+func (d Dir) path(name string) (string, error) {
+	p := filepath.Join(string(d), filepath.FromSlash(name))
+	if !strings.HasPrefix(p, filepath.Clean(string(d))+string(filepath.Separator)) {
+		return "", errors.New("invalid blob name")
+	}
+	return p, nil
+}
+
+// Put stores blob in the directory. Blob is written to temporary file first
+// and renamed so that partial blobs are never visible.
+func (d Dir) Put(ctx context.Context, name string, r io.Reader) error {
+	p, err := d.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+// Get opens blob from the directory.
+func (d Dir) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	p, err := d.path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+// List returns names of the blobs in the directory with the prefix.
+func (d Dir) List(ctx context.Context, prefix string) ([]string, error) {
+	names := make([]string, 0)
+	err := filepath.WalkDir(string(d), func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(string(d), p)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete blob from the directory.
+func (d Dir) Delete(ctx context.Context, name string) error {
+	p, err := d.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Memory is an in-memory blob storage.
+type Memory struct {
+	lock  sync.RWMutex
+	blobs map[string][]byte
+}
+
+// Put stores blob in memory.
+func (m *Memory) Put(ctx context.Context, name string, r io.Reader) error {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.blobs == nil {
+		m.blobs = make(map[string][]byte)
+	}
+	m.blobs[name] = buf
+	return nil
+}
+
+// Get returns blob from memory.
+func (m *Memory) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	buf, ok := m.blobs[name]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(buf)), nil
+}
+
+// List returns names of the blobs in memory with the prefix.
+func (m *Memory) List(ctx context.Context, prefix string) ([]string, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	names := make([]string, 0, len(m.blobs))
+	for name := range m.blobs {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete blob from memory.
+func (m *Memory) Delete(ctx context.Context, name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.blobs, name)
+	return nil
+}
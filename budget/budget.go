@@ -0,0 +1,128 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package budget splits the request deadline across downstream calls so that
+// a single slow dependency can not consume the whole request budget.
+package budget
+
+import (
+	"context"
+	"time"
+)
+
+// Downstream call kinds.
+const (
+	Cache    = "cache"
+	Database = "database"
+	HTTP     = "http"
+)
+
+// Budget of the request deadline.
+type Budget struct {
+	opts     *options
+	deadline time.Time
+	total    time.Duration
+	now      func() time.Time
+}
+
+// New creates budget from the context deadline or default timeout if context
+// has no deadline.
+func New(ctx context.Context, opts ...Option) *Budget {
+	b := &Budget{
+		opts: newOptions(opts...),
+		now:  time.Now,
+	}
+	start := b.now()
+	if d, ok := ctx.Deadline(); ok {
+		b.deadline = d
+	} else if b.opts.Timeout > 0 {
+		b.deadline = start.Add(b.opts.Timeout)
+	}
+	if !b.deadline.IsZero() {
+		b.total = b.deadline.Sub(start) - b.opts.Reserve
+	}
+	return b
+}
+
+// Deadline returns request deadline. Returns false if budget is unlimited.
+func (b *Budget) Deadline() (time.Time, bool) {
+	return b.deadline, !b.deadline.IsZero()
+}
+
+// Remaining returns budget remaining for the downstream calls. Returns zero if
+// budget has been exhausted and -1 if budget is unlimited.
+func (b *Budget) Remaining() time.Duration {
+	if b.deadline.IsZero() {
+		return -1
+	}
+	left := b.deadline.Sub(b.now()) - b.opts.Reserve
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// Exhausted returns true if there is no budget remaining for the downstream calls.
+func (b *Budget) Exhausted() bool {
+	return b.Remaining() == 0
+}
+
+// Allot returns duration the next downstream call of the kind can take.
+//
+// Call gets the configured ratio of the total budget, but not less than its
+// minimum and never more than the remaining budget. Calls of kinds without
+// configured share can use all remaining budget. Returns -1 if budget is
+// unlimited.
+func (b *Budget) Allot(kind string) time.Duration {
+	left := b.Remaining()
+	if left <= 0 {
+		return left
+	}
+	s, ok := b.opts.Shares[kind]
+	if !ok || s.Ratio <= 0 {
+		return left
+	}
+	d := time.Duration(float64(b.total) * s.Ratio)
+	if d < s.Min {
+		d = s.Min
+	}
+	if d > left {
+		d = left
+	}
+	return d
+}
+
+// Context returns context for the downstream call of the kind with deadline
+// limited by its allotted budget.
+func (b *Budget) Context(ctx context.Context, kind string) (context.Context, context.CancelFunc) {
+	d := b.Allot(kind)
+	if d < 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, b.now().Add(d))
+}
+
+type budgetContextKey struct{}
+
+// NewContext returns context with the budget.
+func NewContext(ctx context.Context, b *Budget) context.Context {
+	return context.WithValue(ctx, budgetContextKey{}, b)
+}
+
+// FromContext returns budget from the context or nil if context has no budget.
+func FromContext(ctx context.Context) *Budget {
+	b, _ := ctx.Value(budgetContextKey{}).(*Budget)
+	return b
+}
+
+// WithTimeout returns context for the downstream call of the kind limited by
+// the budget in the context. If context has no budget, only cancelable context
+// is returned.
+func WithTimeout(ctx context.Context, kind string) (context.Context, context.CancelFunc) {
+	b := FromContext(ctx)
+	if b == nil {
+		return context.WithCancel(ctx)
+	}
+	return b.Context(ctx, kind)
+}
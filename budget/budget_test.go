@@ -0,0 +1,127 @@
+package budget
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBudget(ctx context.Context, now *time.Time, opts ...Option) *Budget {
+	b := New(ctx, opts...)
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+func TestBudgetAllot(t *testing.T) {
+	now := time.Now()
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(time.Second))
+	defer cancel()
+
+	b := New(ctx,
+		Share{Name: Cache, Ratio: 0.1, Min: 200 * time.Millisecond},
+		Share{Name: Database, Ratio: 0.5},
+		Reserve(100*time.Millisecond),
+	)
+	b.now = func() time.Time { return now }
+	b.total = 900 * time.Millisecond
+
+	assert.Equal(t, 900*time.Millisecond, b.Remaining())
+	// Minimum is used when ratio of the budget is smaller.
+	assert.Equal(t, 200*time.Millisecond, b.Allot(Cache))
+	assert.Equal(t, 450*time.Millisecond, b.Allot(Database))
+	assert.Equal(t, 900*time.Millisecond, b.Allot(HTTP))
+
+	// Allotted budget never exceeds remaining budget.
+	now = now.Add(600 * time.Millisecond)
+	assert.Equal(t, 300*time.Millisecond, b.Allot(Database))
+	assert.Equal(t, 200*time.Millisecond, b.Allot(Cache))
+
+	now = now.Add(400 * time.Millisecond)
+	assert.True(t, b.Exhausted())
+	assert.Equal(t, time.Duration(0), b.Allot(Cache))
+
+	cctx, ccancel := b.Context(context.Background(), Cache)
+	defer ccancel()
+	d, ok := cctx.Deadline()
+	require.True(t, ok)
+	assert.Equal(t, now, d)
+}
+
+func TestBudgetUnlimited(t *testing.T) {
+	now := time.Now()
+	b := testBudget(context.Background(), &now, Share{Name: Cache, Ratio: 0.1})
+
+	_, ok := b.Deadline()
+	assert.False(t, ok)
+	assert.Equal(t, time.Duration(-1), b.Allot(Cache))
+	assert.False(t, b.Exhausted())
+
+	ctx, cancel := b.Context(context.Background(), Cache)
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+
+	b = New(context.Background(), DefaultTimeout(time.Second), Share{Name: Cache, Ratio: 0.1})
+	d, ok := b.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Second), d, 100*time.Millisecond)
+	assert.InDelta(t, float64(100*time.Millisecond), float64(b.Allot(Cache)), float64(time.Millisecond))
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), Cache)
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ctx = NewContext(ctx, New(ctx, Share{Name: Cache, Ratio: 0.1}))
+	require.NotNil(t, FromContext(ctx))
+
+	cctx, ccancel := WithTimeout(ctx, Cache)
+	defer ccancel()
+	d, ok := cctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(100*time.Millisecond), d, 50*time.Millisecond)
+}
+
+func TestMiddlewareAndTransport(t *testing.T) {
+	var upstream time.Duration
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := r.Context().Deadline()
+		assert.True(t, ok)
+
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		start := time.Now()
+		resp, err := client.Do(req)
+		upstream = time.Since(start)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.NoError(t, r.Context().Err(), "request budget must not be exhausted")
+		w.WriteHeader(http.StatusNoContent)
+	}), DefaultTimeout(2*time.Second), Share{Name: HTTP, Ratio: 0.05})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Less(t, upstream, 500*time.Millisecond)
+}
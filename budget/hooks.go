@@ -0,0 +1,71 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package budget
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Middleware adds deadline budget to the request context.
+//
+// If request context has no deadline, default timeout option limits the whole
+// request.
+func Middleware(next http.Handler, opts ...Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := New(r.Context(), opts...)
+		ctx := NewContext(r.Context(), b)
+		if d, ok := b.Deadline(); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, d)
+			defer cancel()
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+// cancelBody cancels request context when response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if FromContext(req.Context()) == nil {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := WithTimeout(req.Context(), HTTP)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// Transport wraps HTTP client transport to limit outbound calls by the HTTP
+// share of the budget in the request context.
+//
+// If next is nil, http.DefaultTransport is used.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{
+		next: next,
+	}
+}
@@ -0,0 +1,58 @@
+package budget
+
+import (
+	"time"
+)
+
+type options struct {
+	Shares  map[string]Share
+	Reserve time.Duration
+	Timeout time.Duration
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Shares: make(map[string]Share),
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	return opt
+}
+
+// Option for the deadline budget.
+type Option interface {
+	apply(*options)
+}
+
+// Share of the request budget for the downstream calls of the same kind.
+type Share struct {
+	// Name of the downstream call kind, for example Cache, Database or HTTP.
+	Name string
+	// Ratio is a part of the total request budget single call can use. Zero
+	// allows call to use all remaining budget.
+	Ratio float64
+	// Min is a minimum duration of the call even if the ratio of the budget is
+	// smaller. It never exceeds remaining budget.
+	Min time.Duration
+}
+
+func (s Share) apply(o *options) {
+	o.Shares[s.Name] = s
+}
+
+// Reserve is a duration of the budget reserved for the request itself that is
+// not given to the downstream calls, for example to write the response.
+type Reserve time.Duration
+
+func (r Reserve) apply(o *options) {
+	o.Reserve = time.Duration(r)
+}
+
+// DefaultTimeout is a total budget used when incoming context has no deadline.
+// Zero means budget is unlimited and downstream calls do not get a deadline.
+type DefaultTimeout time.Duration
+
+func (t DefaultTimeout) apply(o *options) {
+	o.Timeout = time.Duration(t)
+}
@@ -5,7 +5,19 @@
 package core
 
 import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
 	"azugo.io/core/cache"
+	"azugo.io/core/cert"
+	"azugo.io/core/config"
+	"azugo.io/core/degrade"
+	"azugo.io/core/diagnostics"
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/network"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func (a *App) initCache() error {
@@ -14,27 +26,70 @@ func (a *App) initCache() error {
 	}
 
 	conf := a.Config().Cache
-	opts := []cache.CacheOption{
-		conf.Type,
-		cache.Instrumenter(a.Instrumenter()),
-	}
-	if conf.TTL > 0 {
-		opts = append(opts, cache.DefaultTTL(conf.TTL))
+	opts := append(conf.Options(),
+		cache.Instrumenter(instrumenter.CombinedInstrumenter(a.Instrumenter(), a.cacheLatency.Instrumenter("cache-"), a.sloInstrumenter("cache", "cache-"))),
+		cache.Scrub{Scrubber: a.Scrubber()},
+		cache.ModeSwitch{},
+	)
+	if a.redisClient != nil {
+		opts = append(opts, cache.RedisClient{UniversalClient: a.redisClient})
 	}
-	if len(conf.ConnectionString) != 0 {
-		opts = append(opts, cache.ConnectionString(conf.ConnectionString))
+	if usesRemoteCache(conf) {
+		client, err := a.cacheHTTPClient(conf)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, cache.HTTPClient{Client: client})
 	}
-	if len(conf.Password) != 0 {
-		opts = append(opts, cache.ConnectionPassword(conf.Password))
+	a.cache = cache.New(opts...)
+	for name, conf := range conf.Instances {
+		cache.Define(a.cache, name, conf.Options()...)
 	}
-	if len(conf.KeyPrefix) != 0 {
-		opts = append(opts, cache.KeyPrefix(conf.KeyPrefix))
+
+	a.degradelock.Lock()
+	if a.degrade != nil {
+		a.degrade.Watch("cache", degrade.PingCheck(a.cache), 0)
 	}
-	a.cache = cache.New(opts...)
+	a.degradelock.Unlock()
+
+	a.registerDiagnostic("cache", cacheCheck(a.cache))
+	a.registerDiagnostic("cache-latency", diagnostics.LatencyCheck(a.cacheLatency, time.Duration(a.Config().Diagnostics.CacheLatency)))
 
 	return a.cache.Start(a.BackgroundContext())
 }
 
+// usesRemoteCache returns true if cache or any declared cache instance is remote.
+func usesRemoteCache(conf *config.Cache) bool {
+	if conf.Type == cache.RemoteCache {
+		return true
+	}
+	for _, i := range conf.Instances {
+		if i != nil && i.Type == cache.RemoteCache {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheHTTPClient returns HTTP client for remote cache instances that presents
+// configured client certificate.
+func (a *App) cacheHTTPClient(conf *config.Cache) (*http.Client, error) {
+	if len(conf.ClientCertificate) == 0 {
+		return a.HTTPClient(), nil
+	}
+	crt, err := cert.ParseTLSCertificateFromFile(conf.ClientCertificate)
+	if err != nil {
+		return nil, err
+	}
+	c := a.Network().With(network.ClientCertificateFunc(func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return crt, nil
+	})).Client()
+	if a.Config().Chaos.Enabled {
+		c = a.Chaos().Client(c)
+	}
+	return c, nil
+}
+
 func (a *App) closeCache() {
 	if a.cache == nil {
 		return
@@ -42,6 +97,15 @@ func (a *App) closeCache() {
 	a.cache.Close()
 }
 
+// SetCacheRedisClient sets externally managed Redis client to be used by the cache
+// instead of creating one from the configuration.
+//
+// Must be called before application is started. Client is not closed when
+// application is stopped.
+func (a *App) SetCacheRedisClient(client redis.UniversalClient) {
+	a.redisClient = client
+}
+
 func (a *App) Cache() *cache.Cache {
 	if a.cache == nil {
 		if err := a.initCache(); err != nil {
@@ -50,3 +114,13 @@ func (a *App) Cache() *cache.Cache {
 	}
 	return a.cache
 }
+
+// CacheDiffHandler returns admin HTTP handler that responds with difference
+// between cached and freshly loaded value of the cache instance to check
+// whether cached value is stale.
+//
+// Handler calls cache instance loader so it must only be served on the
+// administrative endpoint.
+func (a *App) CacheDiffHandler() http.Handler {
+	return a.Cache().DiffHandler()
+}
@@ -0,0 +1,215 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotSupported is returned when operation is not supported by the cache instance.
+var ErrNotSupported = errors.New("operation not supported by cache instance")
+
+type analyzeOptions struct {
+	SampleSize       int
+	Buckets          []time.Duration
+	ForecastInterval time.Duration
+}
+
+// AnalyzeOption is an option for the cache analysis.
+type AnalyzeOption interface {
+	applyAnalyze(*analyzeOptions)
+}
+
+// SampleSize is a maximum number of keys sampled for the analysis or reconciliation.
+// Defaults to 1000 for the analysis and all keys for the reconciliation.
+type SampleSize int
+
+func (s SampleSize) applyAnalyze(o *analyzeOptions) {
+	o.SampleSize = int(s)
+}
+
+// TTLBuckets are upper bounds of the TTL histogram buckets.
+type TTLBuckets []time.Duration
+
+func (b TTLBuckets) applyAnalyze(o *analyzeOptions) {
+	o.Buckets = b
+}
+
+// ForecastInterval is a duration of the expiry forecast interval. Defaults to 1 minute.
+type ForecastInterval time.Duration
+
+func (i ForecastInterval) applyAnalyze(o *analyzeOptions) {
+	o.ForecastInterval = time.Duration(i)
+}
+
+// TTLBucket is a TTL histogram bucket.
+type TTLBucket struct {
+	// UpperBound is an inclusive upper bound of the bucket TTL.
+	UpperBound time.Duration
+	// Count is a number of keys in the bucket.
+	Count int
+}
+
+// ExpiryWave is a number of keys expiring in the forecast interval.
+type ExpiryWave struct {
+	// Start is a start of the forecast interval.
+	Start time.Time
+	// Count is a number of keys expiring in the interval.
+	Count int
+}
+
+// TTLReport is a result of the cache instance TTL analysis.
+type TTLReport struct {
+	// Sampled is a number of sampled keys.
+	Sampled int
+	// NoExpiration is a number of sampled keys without TTL.
+	NoExpiration int
+	// NoExpirationKeys are sampled keys without TTL.
+	NoExpirationKeys []string
+	// Buckets is a TTL histogram of keys with TTL. Keys with TTL larger than
+	// the last bucket bound are counted in the last bucket with zero upper bound.
+	Buckets []TTLBucket
+	// Waves are forecasted expiry waves in chronological order.
+	Waves []ExpiryWave
+}
+
+// Peak returns forecast interval with the most keys expiring.
+func (r *TTLReport) Peak() ExpiryWave {
+	var peak ExpiryWave
+	for _, w := range r.Waves {
+		if w.Count > peak.Count {
+			peak = w
+		}
+	}
+	return peak
+}
+
+// ttlSampler represents cache instance that can sample key TTLs.
+type ttlSampler interface {
+	sampleTTL(ctx context.Context, limit int) (map[string]time.Duration, error)
+}
+
+// instanceUnwrapper represents cache instance wrapping another cache instance.
+type instanceUnwrapper[T any] interface {
+	unwrap() CacheInstance[T]
+}
+
+// lookupInstance returns cache instance or wrapped cache instance implementing interface I.
+func lookupInstance[T any, I any](instance CacheInstance[T]) (I, bool) {
+	for {
+		if i, ok := any(instance).(I); ok {
+			return i, true
+		}
+		u, ok := instance.(instanceUnwrapper[T])
+		if !ok {
+			var i I
+			return i, false
+		}
+		instance = u.unwrap()
+	}
+}
+
+// AnalyzeTTL samples keys of the cache instance and reports TTL distribution
+// and forecasted expiry waves.
+//
+// Only Redis cache instances are supported, other instances return ErrNotSupported.
+func AnalyzeTTL[T any](ctx context.Context, instance CacheInstance[T], opts ...AnalyzeOption) (*TTLReport, error) {
+	opt := &analyzeOptions{
+		SampleSize:       1000,
+		Buckets:          []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour},
+		ForecastInterval: time.Minute,
+	}
+	for _, o := range opts {
+		o.applyAnalyze(opt)
+	}
+
+	sampler, ok := lookupInstance[T, ttlSampler](instance)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	now := time.Now()
+	ttls, err := sampler.sampleTTL(ctx, opt.SampleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := append([]time.Duration{}, opt.Buckets...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	r := &TTLReport{
+		Sampled: len(ttls),
+		Buckets: make([]TTLBucket, len(bounds)+1),
+	}
+	for i, b := range bounds {
+		r.Buckets[i].UpperBound = b
+	}
+
+	waves := make(map[int64]int)
+	for key, ttl := range ttls {
+		if ttl <= 0 {
+			r.NoExpiration++
+			r.NoExpirationKeys = append(r.NoExpirationKeys, key)
+			continue
+		}
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i] >= ttl })
+		r.Buckets[i].Count++
+		if opt.ForecastInterval > 0 {
+			waves[int64(ttl/opt.ForecastInterval)]++
+		}
+	}
+	sort.Strings(r.NoExpirationKeys)
+
+	r.Waves = make([]ExpiryWave, 0, len(waves))
+	for i, n := range waves {
+		r.Waves = append(r.Waves, ExpiryWave{
+			Start: now.Add(time.Duration(i) * opt.ForecastInterval),
+			Count: n,
+		})
+	}
+	sort.Slice(r.Waves, func(i, j int) bool { return r.Waves[i].Start.Before(r.Waves[j].Start) })
+
+	return r, nil
+}
+
+func (c *redisCache[T]) sampleTTL(ctx context.Context, limit int) (map[string]time.Duration, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+
+	ttls := make(map[string]time.Duration)
+	err := scanKeys(ctx, c.con, c.prefix, func(node redis.Cmdable, keys []string) error {
+		if limit > 0 && len(keys) > limit-len(ttls) {
+			keys = keys[:limit-len(ttls)]
+		}
+		cmds := make([]*redis.DurationCmd, len(keys))
+		if _, err := node.Pipelined(ctx, func(p redis.Pipeliner) error {
+			for i, key := range keys {
+				cmds[i] = p.PTTL(ctx, key)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for i, cmd := range cmds {
+			// Key has expired after it was scanned.
+			if cmd.Val() == -2 {
+				continue
+			}
+			ttls[strings.TrimPrefix(keys[i], c.prefix)] = cmd.Val()
+		}
+		if limit > 0 && len(ttls) >= limit {
+			return errStopScan
+		}
+		return nil
+	})
+	return ttls, err
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeTTL(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "test", WithReadOnly())
+	require.NoError(t, err)
+
+	for n := 0; n < 10; n++ {
+		require.NoError(t, s.Set("test:short"+strconv.Itoa(n), `"v"`))
+		s.SetTTL("test:short"+strconv.Itoa(n), 30*time.Second)
+	}
+	for n := 0; n < 5; n++ {
+		require.NoError(t, s.Set("test:long"+strconv.Itoa(n), `"v"`))
+		s.SetTTL("test:long"+strconv.Itoa(n), 2*time.Hour)
+	}
+	require.NoError(t, s.Set("test:forever", `"v"`))
+	require.NoError(t, s.Set("other:key", `"v"`))
+
+	r, err := AnalyzeTTL(context.TODO(), i, TTLBuckets{time.Minute, time.Hour})
+	require.NoError(t, err)
+
+	assert.Equal(t, 16, r.Sampled)
+	assert.Equal(t, 1, r.NoExpiration)
+	assert.Equal(t, []string{"forever"}, r.NoExpirationKeys)
+	assert.Equal(t, []TTLBucket{
+		{UpperBound: time.Minute, Count: 10},
+		{UpperBound: time.Hour, Count: 0},
+		{Count: 5},
+	}, r.Buckets)
+	require.Len(t, r.Waves, 2)
+	assert.Equal(t, 10, r.Peak().Count)
+
+	r, err = AnalyzeTTL(context.TODO(), i, SampleSize(3))
+	require.NoError(t, err)
+	assert.Equal(t, 3, r.Sampled)
+}
+
+func TestAnalyzeTTLNotSupported(t *testing.T) {
+	c := New(MemoryCache)
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	_, err = AnalyzeTTL(context.TODO(), i)
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
@@ -0,0 +1,154 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/scrub"
+)
+
+type componentKey struct{}
+
+// WithComponent returns context with the calling component name that is recorded
+// for cache operations when auditing is enabled.
+func WithComponent(ctx context.Context, component string) context.Context {
+	return context.WithValue(ctx, componentKey{}, component)
+}
+
+// ComponentFromContext returns calling component name from the context.
+func ComponentFromContext(ctx context.Context) string {
+	c, _ := ctx.Value(componentKey{}).(string)
+	return c
+}
+
+// Audit records calling component for cache operations so that it can be
+// determined which service and code path has written the value.
+type Audit struct {
+	// Component is a component name used when it is not set in the context.
+	Component string
+	// Caller records code location of the cache operation caller.
+	Caller bool
+	// Envelope stores audit information together with the value in remote cache backends.
+	Envelope bool
+
+	scrubber *scrub.Scrubber
+}
+
+func (a Audit) applyCache(c *cacheOptions) {
+	c.Audit = &a
+}
+
+// Scrub applies scrubbing rules to audit information and values published
+// in cache events so that sensitive data does not leak into telemetry.
+//
+// Audit component and caller are matched by "component" and "caller" field names,
+// event values are matched by their JSON field paths.
+type Scrub struct {
+	*scrub.Scrubber
+}
+
+func (s Scrub) applyCache(c *cacheOptions) {
+	c.Scrubber = s.Scrubber
+}
+
+func (a *Audit) component(ctx context.Context) string {
+	c := ComponentFromContext(ctx)
+	if len(c) == 0 {
+		c = a.Component
+	}
+	if len(c) == 0 {
+		return c
+	}
+	c, _ = a.scrubber.String("component", c)
+	return c
+}
+
+// caller returns scrubbed code location of the cache operation caller.
+func (a *Audit) caller() string {
+	c := caller()
+	if len(c) == 0 {
+		return c
+	}
+	c, _ = a.scrubber.String("caller", c)
+	return c
+}
+
+// callerSkipPrefixes are packages whose frames are skipped when determining caller.
+var callerSkipPrefixes = []string{
+	"azugo.io/core/cache.",
+	"azugo.io/core/instrumenter.",
+	"runtime.",
+}
+
+func skipCallerFrame(f runtime.Frame) bool {
+	// Tests are callers even when in the same package.
+	if strings.HasSuffix(f.File, "_test.go") {
+		return false
+	}
+	for _, p := range callerSkipPrefixes {
+		if strings.HasPrefix(f.Function, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// caller returns the first code location outside of the cache package.
+func caller() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !skipCallerFrame(f) {
+			return fmt.Sprintf("%s (%s:%d)", f.Function, f.File, f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// labels returns audit labels for the operation.
+func (a *Audit) labels(ctx context.Context) []any {
+	labels := make([]any, 0, 2)
+	if c := a.component(ctx); len(c) != 0 {
+		labels = append(labels, instrumenter.Label{Name: "component", Value: c})
+	}
+	if a.Caller {
+		if c := a.caller(); len(c) != 0 {
+			labels = append(labels, instrumenter.Label{Name: "caller", Value: c})
+		}
+	}
+	return labels
+}
+
+// header returns envelope header for the write operation.
+func (a *Audit) header(ctx context.Context) *EnvelopeHeader {
+	h := &EnvelopeHeader{
+		Component: a.component(ctx),
+		WrittenAt: time.Now().UTC(),
+	}
+	if a.Caller {
+		h.Caller = a.caller()
+	}
+	return h
+}
+
+// auditInstrumenter returns instrumenter that adds audit labels to the operation arguments.
+func auditInstrumenter(instr instrumenter.Instrumenter, a *Audit) instrumenter.Instrumenter {
+	if instr == nil {
+		return nil
+	}
+	return func(ctx context.Context, op string, args ...any) func(err error) {
+		return instr(ctx, op, append(args, a.labels(ctx)...)...)
+	}
+}
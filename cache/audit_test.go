@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/scrub"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelope(t *testing.T) {
+	buf, err := encodeEnvelope(&EnvelopeHeader{Component: "billing"}, []byte(`"value"`))
+	require.NoError(t, err)
+
+	h, payload, ok := DecodeEnvelope(buf)
+	require.True(t, ok)
+	assert.Equal(t, "billing", h.Component)
+	assert.Equal(t, `"value"`, string(payload))
+
+	_, payload, ok = DecodeEnvelope([]byte(`"legacy"`))
+	assert.False(t, ok)
+	assert.Equal(t, `"legacy"`, string(payload))
+}
+
+func TestAuditLabels(t *testing.T) {
+	labels := make(map[string]string)
+	instr := Instrumenter(func(ctx context.Context, op string, args ...any) func(err error) {
+		if op == InstrumentationCacheSet {
+			for _, a := range args {
+				if l, ok := a.(instrumenter.Label); ok {
+					labels[l.Name] = l.Value
+				}
+			}
+		}
+		return func(err error) {}
+	})
+
+	c := New(MemoryCache, instr, Audit{Component: "default", Caller: true})
+	require.NoError(t, c.Start(context.Background()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(WithComponent(context.Background(), "billing"), "key", "value"))
+
+	assert.Equal(t, "billing", labels["component"])
+	assert.True(t, strings.HasPrefix(labels["caller"], "azugo.io/core/cache.TestAuditLabels"), labels["caller"])
+}
+
+func TestAuditScrub(t *testing.T) {
+	labels := make(map[string]string)
+	instr := Instrumenter(func(ctx context.Context, op string, args ...any) func(err error) {
+		if op == InstrumentationCacheSet {
+			for _, a := range args {
+				if l, ok := a.(instrumenter.Label); ok {
+					labels[l.Name] = l.Value
+				}
+			}
+		}
+		return func(err error) {}
+	})
+
+	s := scrub.New(scrub.Rule{Field: "caller", Action: scrub.Drop}, scrub.Rule{Field: "component", Action: scrub.Mask})
+	c := New(MemoryCache, instr, Audit{Component: "default", Caller: true}, Scrub{s})
+	require.NoError(t, c.Start(context.Background()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(WithComponent(context.Background(), "user@example.com"), "key", "value"))
+
+	assert.Equal(t, scrub.Masked, labels["component"])
+	assert.NotContains(t, labels, "caller")
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+)
+
+const (
+	InstrumentationCacheShed = "cache-shed"
+)
+
+// ErrBackpressure is returned when cache sheds load and no stale value is available.
+var ErrBackpressure = errors.New("cache loader overloaded")
+
+// Backpressure is an adaptive loader backpressure configuration for the cache instance.
+//
+// When loader latency or error rate exceeds thresholds, cache stops calling the loader
+// for the cooldown period and serves last loaded (stale) values or fails fast with
+// ErrBackpressure. Loader executions are also limited to the maximum number of
+// concurrent calls per cache instance.
+type Backpressure struct {
+	// MaxConcurrent is a maximum number of concurrent loader executions.
+	// Zero means no limit.
+	MaxConcurrent int
+	// LatencyThreshold is an average loader latency that trips load shedding.
+	// Zero disables latency tracking.
+	LatencyThreshold time.Duration
+	// ErrorRateThreshold is a loader error rate (0..1) that trips load shedding.
+	// Zero disables error rate tracking.
+	ErrorRateThreshold float64
+	// MinSamples is a minimum number of loader calls before thresholds are applied.
+	// Defaults to 10.
+	MinSamples int
+	// Cooldown is a time to shed load after thresholds are exceeded.
+	// Defaults to 5 seconds.
+	Cooldown time.Duration
+	// StaleEntries is a maximum number of last loaded values to keep for serving
+	// while shedding load. Defaults to 1000, negative value disables stale values.
+	StaleEntries int
+}
+
+func (b Backpressure) applyCache(c *cacheOptions) {
+	c.Backpressure = &b
+}
+
+// ewmaWeight is a weight of the newest sample in moving averages.
+const ewmaWeight = 0.1
+
+type loaderGuard[T any] struct {
+	conf         Backpressure
+	instrumenter instrumenter.Instrumenter
+	sem          chan struct{}
+
+	lock       sync.Mutex
+	samples    int
+	latency    float64
+	errorRate  float64
+	shedUntil  time.Time
+	stale      map[string]T
+	staleOrder []string
+}
+
+func newLoaderGuard[T any](conf Backpressure, instr instrumenter.Instrumenter) *loaderGuard[T] {
+	if conf.MinSamples <= 0 {
+		conf.MinSamples = 10
+	}
+	if conf.Cooldown <= 0 {
+		conf.Cooldown = 5 * time.Second
+	}
+	if conf.StaleEntries == 0 {
+		conf.StaleEntries = 1000
+	}
+	g := &loaderGuard[T]{
+		conf:         conf,
+		instrumenter: instr,
+		stale:        make(map[string]T),
+	}
+	if conf.MaxConcurrent > 0 {
+		g.sem = make(chan struct{}, conf.MaxConcurrent)
+	}
+	return g
+}
+
+func (g *loaderGuard[T]) shedding(now time.Time) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return now.Before(g.shedUntil)
+}
+
+func (g *loaderGuard[T]) record(key string, v T, d time.Duration, err error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	var failed float64
+	// Missing value is a valid loader result.
+	if err != nil && !isKeyNotFound(err) {
+		failed = 1
+	}
+	if g.samples == 0 {
+		g.latency = float64(d)
+		g.errorRate = failed
+	} else {
+		g.latency += ewmaWeight * (float64(d) - g.latency)
+		g.errorRate += ewmaWeight * (failed - g.errorRate)
+	}
+	g.samples++
+
+	if g.samples >= g.conf.MinSamples &&
+		((g.conf.LatencyThreshold > 0 && time.Duration(g.latency) > g.conf.LatencyThreshold) ||
+			(g.conf.ErrorRateThreshold > 0 && g.errorRate > g.conf.ErrorRateThreshold)) {
+		g.shedUntil = time.Now().Add(g.conf.Cooldown)
+		// Start measuring from scratch after cooldown.
+		g.samples = 0
+	}
+
+	if err != nil || g.conf.StaleEntries < 0 {
+		return
+	}
+	if _, ok := g.stale[key]; !ok {
+		if len(g.staleOrder) >= g.conf.StaleEntries {
+			delete(g.stale, g.staleOrder[0])
+			g.staleOrder = g.staleOrder[1:]
+		}
+		g.staleOrder = append(g.staleOrder, key)
+	}
+	g.stale[key] = v
+}
+
+func (g *loaderGuard[T]) shed(ctx context.Context, key string) (T, error) {
+	finish := g.instrumenter.Observe(ctx, InstrumentationCacheShed, key)
+
+	g.lock.Lock()
+	v, ok := g.stale[key]
+	g.lock.Unlock()
+
+	if ok {
+		finish(nil)
+		return v, nil
+	}
+	finish(ErrBackpressure)
+	return v, ErrBackpressure
+}
+
+func (g *loaderGuard[T]) wrap(loader func(ctx context.Context, key string) (T, error)) func(ctx context.Context, key string) (T, error) {
+	return func(ctx context.Context, key string) (T, error) {
+		if g.shedding(time.Now()) {
+			return g.shed(ctx, key)
+		}
+
+		if g.sem != nil {
+			select {
+			case g.sem <- struct{}{}:
+				defer func() { <-g.sem }()
+			default:
+				return g.shed(ctx, key)
+			}
+		}
+
+		start := time.Now()
+		v, err := loader(ctx, key)
+		g.record(key, v, time.Since(start), err)
+		return v, err
+	}
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackpressureErrorRate(t *testing.T) {
+	var fail atomic.Bool
+	var calls atomic.Int32
+	g := newLoaderGuard[any](Backpressure{ErrorRateThreshold: 0.5, MinSamples: 2, Cooldown: time.Minute}, nil)
+	load := g.wrap(func(ctx context.Context, key string) (any, error) {
+		calls.Add(1)
+		if fail.Load() {
+			return nil, errors.New("origin down")
+		}
+		return "value", nil
+	})
+
+	v, err := load(context.Background(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	fail.Store(true)
+	for i := 0; i < 10; i++ {
+		_, _ = load(context.Background(), "key")
+	}
+	n := calls.Load()
+	assert.Less(t, n, int32(11), "loader must not be called while shedding load")
+
+	v, err = load(context.Background(), "key")
+	require.NoError(t, err, "stale value must be served")
+	assert.Equal(t, "value", v)
+
+	_, err = load(context.Background(), "other")
+	assert.ErrorIs(t, err, ErrBackpressure)
+	assert.Equal(t, n, calls.Load())
+}
+
+func TestBackpressureMaxConcurrent(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	g := newLoaderGuard[any](Backpressure{MaxConcurrent: 1}, nil)
+	load := g.wrap(func(ctx context.Context, key string) (any, error) {
+		close(started)
+		<-release
+		return "value", nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = load(context.Background(), "key")
+	}()
+	<-started
+
+	_, err := load(context.Background(), "key")
+	assert.ErrorIs(t, err, ErrBackpressure)
+
+	close(release)
+	wg.Wait()
+}
+
+func BenchmarkLoaderStampede(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts []CacheOption
+	}{
+		{"unprotected", nil},
+		{"backpressure", []CacheOption{Backpressure{MaxConcurrent: 4, LatencyThreshold: 50 * time.Microsecond}}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			var calls atomic.Int64
+			opts := append([]CacheOption{
+				MemoryCache,
+				// Expire immediately so that every read hits the loader.
+				DefaultTTL(time.Nanosecond),
+				Loader[string](func(ctx context.Context, key string) (string, error) {
+					calls.Add(1)
+					time.Sleep(100 * time.Microsecond)
+					return "value", nil
+				}),
+			}, bench.opts...)
+
+			c := New(opts...)
+			require.NoError(b, c.Start(context.Background()))
+			defer c.Close()
+
+			i, err := Create[string](c, "bench")
+			require.NoError(b, err)
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_, _ = i.Get(context.Background(), "key")
+				}
+			})
+			b.ReportMetric(float64(calls.Load())/float64(b.N), "loads/op")
+		})
+	}
+}
@@ -0,0 +1,557 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
+)
+
+// CacheInstanceBatcher represents cache instance methods that operate on
+// multiple keys in a single round trip.
+type CacheInstanceBatcher[T any] interface {
+	// GetMulti returns values of the keys found in cache. Keys that are not
+	// found and can not be loaded are not included in the result.
+	GetMulti(ctx context.Context, keys []string, opts ...ItemOption[T]) (map[string]T, error)
+	// SetMulti sets multiple values in cache.
+	SetMulti(ctx context.Context, items map[string]T, opts ...ItemOption[T]) error
+	// DeleteMulti deletes multiple values from cache.
+	DeleteMulti(ctx context.Context, keys ...string) error
+}
+
+// GetMulti returns values of the keys from the cache instance.
+//
+// Keys that are not found and can not be loaded are not included in the result.
+// If cache instance does not support batch operations, values are read one by
+// one and missing values are loaded concurrently. Missing keys of cache
+// instances not implemented by this package can not be distinguished and are
+// returned with the default value.
+func GetMulti[T any](ctx context.Context, instance CacheInstance[T], keys []string, opts ...ItemOption[T]) (map[string]T, error) {
+	if b, ok := instance.(CacheInstanceBatcher[T]); ok {
+		return b.GetMulti(ctx, keys, opts...)
+	}
+	s := instanceState(instance)
+	values := make(map[string]T, len(keys))
+	var missing []string
+	for _, key := range keys {
+		if s == nil {
+			v, err := instance.Get(ctx, key, opts...)
+			if isKeyNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			values[key] = v
+			continue
+		}
+		v, found, err := lookup(ctx, instance, key, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			values[key] = v
+		} else if s.loader {
+			missing = append(missing, key)
+		}
+	}
+	// Missing values are read again so that cache instance loader loads and stores them.
+	err := loadMulti(ctx, missing, values, func(ctx context.Context, key string) (T, error) {
+		return instance.Get(ctx, key, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// SetMulti sets multiple values in the cache instance.
+//
+// If cache instance does not support batch operations, values are set one by one.
+func SetMulti[T any](ctx context.Context, instance CacheInstance[T], items map[string]T, opts ...ItemOption[T]) error {
+	if b, ok := instance.(CacheInstanceBatcher[T]); ok {
+		return b.SetMulti(ctx, items, opts...)
+	}
+	for key, value := range items {
+		if err := instance.Set(ctx, key, value, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteMulti deletes multiple values from the cache instance.
+//
+// If cache instance does not support batch operations, values are deleted one by one.
+func DeleteMulti[T any](ctx context.Context, instance CacheInstance[T], keys ...string) error {
+	if b, ok := instance.(CacheInstanceBatcher[T]); ok {
+		return b.DeleteMulti(ctx, keys...)
+	}
+	for _, key := range keys {
+		if err := instance.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multiLoadConcurrency is a maximum number of values of the missing keys
+// loaded concurrently by GetMulti.
+const multiLoadConcurrency = 8
+
+// loadMulti loads values of the missing keys concurrently and adds them to
+// values. Keys that are not found by load are skipped.
+func loadMulti[T any](ctx context.Context, keys []string, values map[string]T, load func(ctx context.Context, key string) (T, error)) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	var lock sync.Mutex
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(multiLoadConcurrency)
+	for _, key := range keys {
+		key := key
+		g.Go(func() error {
+			v, err := load(ctx, key)
+			if isKeyNotFound(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			values[key] = v
+			lock.Unlock()
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// observeMulti starts instrumentation of the operation for every key and
+// returns function to finish all of them.
+func observeMulti(ctx context.Context, instr instrumenter.Instrumenter, op string, keys []string) func(err error) {
+	finish := make([]func(error), 0, len(keys))
+	for _, key := range keys {
+		finish = append(finish, instr.Observe(ctx, op, key))
+	}
+	return func(err error) {
+		for _, fn := range finish {
+			fn(err)
+		}
+	}
+}
+
+// multiKey returns true if connection supports multi-key commands for keys
+// in different hash slots.
+func (c *redisCache[T]) multiKey() bool {
+	switch c.con.(type) {
+	case *redis.ClusterClient, *redis.Ring:
+		return false
+	}
+	return true
+}
+
+func (c *redisCache[T]) GetMulti(ctx context.Context, keys []string, opts ...ItemOption[T]) (map[string]T, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	if len(keys) == 0 {
+		return map[string]T{}, nil
+	}
+
+	opt := c.items.resolve(opts...)
+
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = c.prefix + key
+	}
+	finish := observeMulti(ctx, c.instrumenter, InstrumentationCacheGet, full)
+
+	cmds := make([]*redis.StringCmd, len(keys))
+	if c.multiKey() {
+		vals, err := c.con.MGet(ctx, full...).Result()
+		if err != nil {
+			finish(err)
+			return nil, err
+		}
+		for i, v := range vals {
+			if s, ok := v.(string); ok {
+				cmds[i] = redis.NewStringResult(s, nil)
+			} else {
+				cmds[i] = redis.NewStringResult("", redis.Nil)
+			}
+		}
+	} else {
+		_, err := c.con.Pipelined(ctx, func(p redis.Pipeliner) error {
+			for i, key := range full {
+				cmds[i] = p.Get(ctx, key)
+			}
+			return nil
+		})
+		if err != nil && err != redis.Nil {
+			finish(err)
+			return nil, err
+		}
+	}
+
+	values := make(map[string]T, len(keys))
+	var missing []string
+	for i, key := range keys {
+		raw, err := c.deref(ctx, cmds[i])
+		if err == redis.Nil {
+			missing = append(missing, key)
+			continue
+		}
+		if err != nil {
+			finish(err)
+			return nil, err
+		}
+		val := new(T)
+		if err := c.unmarshal(opt, []byte(raw), val); err != nil {
+			err = fmt.Errorf("invalid cache value: %w", err)
+			if c.quarantine == nil {
+				finish(err)
+				return nil, err
+			}
+			c.quarantineValue(ctx, key, raw, err, true)
+			missing = append(missing, key)
+			continue
+		}
+		values[key] = *val
+	}
+	if c.loader != nil {
+		err := loadMulti(ctx, missing, values, func(ctx context.Context, key string) (T, error) {
+			return loadAndStore(ctx, c.loader, c.Set, key, opt, opts...)
+		})
+		if err != nil {
+			finish(err)
+			return nil, err
+		}
+	}
+	finish(nil)
+	return values, nil
+}
+
+func (c *redisCache[T]) SetMulti(ctx context.Context, items map[string]T, opts ...ItemOption[T]) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	opt := c.items.resolve(opts...)
+
+	keys := make([]string, 0, len(items))
+	full := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+		full = append(full, c.prefix+key)
+	}
+	finish := observeMulti(ctx, c.instrumenter, InstrumentationCacheSet, full)
+
+	bufs := make([][]byte, len(keys))
+	ttls := make([]time.Duration, len(keys))
+	for i, key := range keys {
+		ttl, err := c.ttlGuard.expiration(ctx, key, opt.TTL)
+		if err != nil {
+			finish(err)
+			return err
+		}
+		buf, err := c.marshal(ctx, opt, items[key])
+		if err != nil {
+			err = fmt.Errorf("invalid cache value: %w", err)
+			finish(err)
+			return err
+		}
+		bufs[i], ttls[i] = buf, ttl
+	}
+
+	if c.dedup != nil {
+		// Deduplicated values are stored using script per key.
+		for i, key := range keys {
+			if err := c.store(ctx, key, bufs[i], ttls[i]); err != nil {
+				finish(err)
+				return err
+			}
+			if err := c.tag(ctx, key, opt.Tags, ttls[i]); err != nil {
+				finish(err)
+				return err
+			}
+		}
+		finish(nil)
+		return nil
+	}
+
+	_, err := c.con.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, key := range full {
+			p.Set(ctx, key, string(bufs[i]), ttls[i])
+		}
+		return nil
+	})
+	for _, key := range full {
+		c.coalesce.forget(key)
+	}
+	for i := 0; err == nil && i < len(keys); i++ {
+		err = c.tag(ctx, keys[i], opt.Tags, ttls[i])
+	}
+	finish(err)
+	return err
+}
+
+func (c *redisCache[T]) DeleteMulti(ctx context.Context, keys ...string) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = c.prefix + key
+	}
+	finish := observeMulti(ctx, c.instrumenter, InstrumentationCacheDelete, full)
+
+	if c.dedup != nil {
+		// References to deduplicated values must be released per key.
+		for _, key := range keys {
+			if err := c.del(ctx, key); err != nil {
+				finish(err)
+				return err
+			}
+		}
+		finish(nil)
+		return nil
+	}
+
+	var err error
+	if c.multiKey() {
+		err = c.con.Del(ctx, full...).Err()
+	} else {
+		_, err = c.con.Pipelined(ctx, func(p redis.Pipeliner) error {
+			for _, key := range full {
+				p.Del(ctx, key)
+			}
+			return nil
+		})
+	}
+	for _, key := range full {
+		c.coalesce.forget(key)
+	}
+	finish(err)
+	return err
+}
+
+func (c *memoryCache[T]) GetMulti(ctx context.Context, keys []string, opts ...ItemOption[T]) (map[string]T, error) {
+	if c.cache == nil {
+		return nil, ErrCacheClosed
+	}
+
+	values := make(map[string]T, len(keys))
+	var missing []string
+	for _, key := range keys {
+		finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, key)
+		if v, found := c.cache.Get(key); found {
+			values[key] = v.(memoryEntry[T]).value
+		} else {
+			missing = append(missing, key)
+		}
+		finish(nil)
+	}
+	if c.loader == nil {
+		return values, nil
+	}
+	opt := c.items.resolve(opts...)
+	err := loadMulti(ctx, missing, values, func(ctx context.Context, key string) (T, error) {
+		return c.getWithLoader(ctx, key, opt.TTL)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (c *memoryCache[T]) SetMulti(ctx context.Context, items map[string]T, opts ...ItemOption[T]) error {
+	for key, value := range items {
+		if err := c.Set(ctx, key, value, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *memoryCache[T]) DeleteMulti(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := c.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *lruCache[T]) GetMulti(ctx context.Context, keys []string, opts ...ItemOption[T]) (map[string]T, error) {
+	finish := observeMulti(ctx, c.instrumenter, InstrumentationCacheGet, keys)
+
+	values := make(map[string]T, len(keys))
+	var missing []string
+
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		finish(ErrCacheClosed)
+		return nil, ErrCacheClosed
+	}
+	for _, key := range keys {
+		if e, ok := c.lookup(key); ok {
+			values[key] = e.value
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	c.lock.Unlock()
+
+	if c.loader == nil {
+		finish(nil)
+		return values, nil
+	}
+
+	opt := c.defaults.resolve(opts...)
+	err := loadMulti(ctx, missing, values, func(ctx context.Context, key string) (T, error) {
+		v, err := c.loader(ctx, key)
+		if err != nil {
+			return v, err
+		}
+		return v, c.set(ctx, key, v, opt.TTL)
+	})
+	finish(err)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (c *lruCache[T]) SetMulti(ctx context.Context, items map[string]T, opts ...ItemOption[T]) error {
+	for key, value := range items {
+		if err := c.Set(ctx, key, value, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *lruCache[T]) DeleteMulti(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := c.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *readOnlyCache[T]) GetMulti(ctx context.Context, keys []string, opts ...ItemOption[T]) (map[string]T, error) {
+	return GetMulti(ctx, c.CacheInstance, keys, opts...)
+}
+
+func (c *readOnlyCache[T]) SetMulti(ctx context.Context, items map[string]T, _ ...ItemOption[T]) error {
+	for key := range items {
+		if err := c.reject(ctx, InstrumentationCacheSet, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *readOnlyCache[T]) DeleteMulti(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := c.reject(ctx, InstrumentationCacheDelete, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *modeCache[T]) GetMulti(ctx context.Context, keys []string, opts ...ItemOption[T]) (map[string]T, error) {
+	if m, _ := c.current(); m == ModeNormal {
+		return GetMulti(ctx, c.CacheInstance, keys, opts...)
+	}
+	values := make(map[string]T, len(keys))
+	var missing []string
+	for _, key := range keys {
+		v, err := c.peek(ctx, key, opts...)
+		if isKeyNotFound(err) {
+			if c.loader != nil {
+				missing = append(missing, key)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[key] = v
+	}
+	err := loadMulti(ctx, missing, values, func(ctx context.Context, key string) (T, error) {
+		return c.Get(ctx, key, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (c *modeCache[T]) SetMulti(ctx context.Context, items map[string]T, opts ...ItemOption[T]) error {
+	m, st := c.current()
+	if m == ModeReadOnly {
+		return ErrReadOnly
+	}
+	if err := SetMulti(ctx, c.CacheInstance, items, opts...); err != nil {
+		return err
+	}
+	if m == ModeFreeze {
+		for key, value := range items {
+			c.freeze(st, key, value)
+		}
+	}
+	return nil
+}
+
+func (c *modeCache[T]) DeleteMulti(ctx context.Context, keys ...string) error {
+	if m, _ := c.current(); m == ModeReadOnly {
+		return ErrReadOnly
+	}
+	err := DeleteMulti(ctx, c.CacheInstance, keys...)
+	c.forget(keys...)
+	return err
+}
+
+func (c *slidingCache[T]) GetMulti(ctx context.Context, keys []string, opts ...ItemOption[T]) (map[string]T, error) {
+	values, err := GetMulti(ctx, c.CacheInstance, keys, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if ttl := c.ttl(opts...); ttl > 0 {
+		for key := range values {
+			if err := c.slide(ctx, ttl, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return values, nil
+}
+
+func (c *slidingCache[T]) SetMulti(ctx context.Context, items map[string]T, opts ...ItemOption[T]) error {
+	return SetMulti(ctx, c.CacheInstance, items, opts...)
+}
+
+func (c *slidingCache[T]) DeleteMulti(ctx context.Context, keys ...string) error {
+	return DeleteMulti(ctx, c.CacheInstance, keys...)
+}
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testMapCache[T any] map[string]T
+
+func (c testMapCache[T]) Get(_ context.Context, key string, _ ...ItemOption[T]) (T, error) {
+	return c[key], nil
+}
+
+func (c testMapCache[T]) Exists(_ context.Context, key string) (bool, error) {
+	_, ok := c[key]
+	return ok, nil
+}
+
+func (c testMapCache[T]) Pop(_ context.Context, key string) (T, error) {
+	v := c[key]
+	delete(c, key)
+	return v, nil
+}
+
+func (c testMapCache[T]) Set(_ context.Context, key string, value T, _ ...ItemOption[T]) error {
+	c[key] = value
+	return nil
+}
+
+func (c testMapCache[T]) Delete(_ context.Context, key string) error {
+	delete(c, key)
+	return nil
+}
+
+func testBatch(t *testing.T, i CacheInstance[string]) {
+	t.Helper()
+
+	_, ok := i.(CacheInstanceBatcher[string])
+	require.True(t, ok)
+
+	require.NoError(t, SetMulti(context.TODO(), i, map[string]string{
+		"a": "1",
+		"b": "2",
+		"c": "3",
+	}, TTL[string](time.Minute)))
+
+	values, err := GetMulti(context.TODO(), i, []string{"a", "b", "missing", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2", "c": "3"}, values)
+
+	require.NoError(t, DeleteMulti(context.TODO(), i, "a", "c", "missing"))
+
+	values, err = GetMulti(context.TODO(), i, []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"b": "2"}, values)
+
+	values, err = GetMulti[string](context.TODO(), i, nil)
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestRedisCacheBatch(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testBatch(t, i)
+
+	assert.True(t, s.Exists("test:b"))
+	assert.Greater(t, s.TTL("test:b"), time.Duration(0))
+
+	require.NoError(t, s.Set("test:broken", "{"))
+	_, err = GetMulti(context.TODO(), i, []string{"b", "broken"})
+	assert.Error(t, err)
+}
+
+func TestRedisCacheBatchLoader(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+
+	var loaded []string
+	i, err := Create[string](c, "test", Loader[string](func(_ context.Context, key string) (string, error) {
+		loaded = append(loaded, key)
+		if key == "fail" {
+			return "", errors.New("failed")
+		}
+		return "loaded:" + key, nil
+	}))
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "a", "1"))
+
+	values, err := GetMulti(context.TODO(), i, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "loaded:b"}, values)
+	assert.Equal(t, []string{"b"}, loaded)
+	assert.True(t, s.Exists("test:b"))
+
+	_, err = GetMulti(context.TODO(), i, []string{"a", "fail"})
+	assert.EqualError(t, err, "failed")
+}
+
+func TestRedisCacheBatchLoaderConcurrent(t *testing.T) {
+	c, _ := newMiniRedisCache(t)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	i, err := Create[string](c, "test", Loader[string](func(_ context.Context, key string) (string, error) {
+		wg.Done()
+		// Both missing values are loaded at the same time.
+		wg.Wait()
+		return "loaded:" + key, nil
+	}))
+	require.NoError(t, err)
+
+	values, err := GetMulti(context.TODO(), i, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "loaded:a", "b": "loaded:b"}, values)
+}
+
+func TestRedisCacheBatchDeduplicate(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "test", Deduplicate{MinSize: 1})
+	require.NoError(t, err)
+	testBatch(t, i)
+
+	require.NoError(t, DeleteMulti(context.TODO(), i, "b"))
+	assert.Empty(t, s.Keys())
+}
+
+func TestMemoryCacheBatch(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "memory")
+	require.NoError(t, err)
+	testBatch(t, i)
+
+	i, err = Create[string](c, "lru", MemoryLimit{MaxItems: 10})
+	require.NoError(t, err)
+	testBatch(t, i)
+}
+
+func TestReadOnlyCacheBatch(t *testing.T) {
+	c := New(MemoryCache)
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test", WithReadOnly())
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, SetMulti(context.TODO(), i, map[string]string{"a": "1"}), ErrReadOnly)
+	assert.ErrorIs(t, DeleteMulti(context.TODO(), i, "a"), ErrReadOnly)
+	values, err := GetMulti(context.TODO(), i, []string{"a"})
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestBatchFallback(t *testing.T) {
+	i := testMapCache[string]{}
+
+	require.NoError(t, SetMulti[string](context.TODO(), i, map[string]string{"a": "1", "b": "2"}))
+	values, err := GetMulti[string](context.TODO(), i, []string{"a", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "missing": ""}, values)
+
+	require.NoError(t, DeleteMulti[string](context.TODO(), i, "a", "b"))
+	assert.Empty(t, i)
+}
+
+func TestBatchMissingKeys(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	loader := Loader[string](func(_ context.Context, key string) (string, error) {
+		if key == "unknown" {
+			return "", ErrKeyNotFound{Key: key}
+		}
+		return "loaded:" + key, nil
+	})
+	tests := []struct {
+		name     string
+		opts     []CacheOption
+		expected map[string]string
+	}{
+		{"batch", nil, map[string]string{"a": "1"}},
+		{"fallback", []CacheOption{Events{Publisher: &testPublisher{}}}, map[string]string{"a": "1"}},
+		{"batch-loader", []CacheOption{loader}, map[string]string{"a": "1", "b": "loaded:b"}},
+		{"fallback-loader", []CacheOption{Events{Publisher: &testPublisher{}}, loader}, map[string]string{"a": "1", "b": "loaded:b"}},
+	}
+	for _, tt := range tests {
+		i, err := Create[string](c, tt.name, tt.opts...)
+		require.NoError(t, err)
+		require.NoError(t, i.Set(context.TODO(), "a", "1"))
+
+		values, err := GetMulti(context.TODO(), i, []string{"a", "b", "unknown"})
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, values, tt.name)
+	}
+}
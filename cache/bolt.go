@@ -0,0 +1,578 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// DefaultBoltGCInterval is a default interval for deleting expired items from bolt database.
+	DefaultBoltGCInterval = 5 * time.Minute
+	// DefaultBoltCompactRatio is a default ratio of free space in bolt database file that triggers compaction.
+	DefaultBoltCompactRatio = 0.5
+
+	// boltExpiresSize is a size of expiration timestamp prepended to stored values.
+	boltExpiresSize = 8
+	// boltCompactTxSize is a maximum size of the transaction used to copy data when compacting.
+	boltCompactTxSize = 64 << 20
+)
+
+// BoltOptions are bolt cache connection options.
+type BoltOptions struct {
+	// Path to the database file.
+	Path string
+	// Timeout to wait for the database file lock.
+	Timeout time.Duration
+}
+
+// ParseBoltURL parses bolt connection string in the format
+// bolt:///var/lib/app/cache.db?timeout=1s
+//
+// Relative path can be specified as bolt://data/cache.db.
+func ParseBoltURL(v string) (*BoltOptions, error) {
+	if !strings.HasPrefix(v, "bolt://") {
+		return nil, fmt.Errorf("bolt: invalid URL: %s", v)
+	}
+	p, query, _ := strings.Cut(strings.TrimPrefix(v, "bolt://"), "?")
+	if len(p) == 0 {
+		return nil, errors.New("bolt: database path not specified")
+	}
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("bolt: invalid URL query: %w", err)
+	}
+
+	o := &BoltOptions{
+		Path:    filepath.Clean(p),
+		Timeout: time.Second,
+	}
+	if t := q.Get("timeout"); len(t) != 0 {
+		if o.Timeout, err = time.ParseDuration(t); err != nil {
+			return nil, fmt.Errorf("bolt: invalid timeout: %w", err)
+		}
+	}
+	return o, nil
+}
+
+// BoltStorage configures maintenance of the bolt database file.
+//
+// Database file is shared by all cache instances using the same path so
+// options of the first opened instance are used.
+type BoltStorage struct {
+	// GCInterval is an interval for deleting expired items. Defaults to
+	// DefaultBoltGCInterval, negative value disables deleting expired items.
+	GCInterval time.Duration
+	// CompactInterval is an interval for checking if database file should be
+	// compacted. Zero disables compaction.
+	CompactInterval time.Duration
+	// CompactRatio is a minimum ratio of free space in the database file to compact it.
+	// Defaults to DefaultBoltCompactRatio.
+	CompactRatio float64
+	// NoSync skips fsync after each write. Faster, but recent writes can be lost on crash.
+	NoSync bool
+}
+
+func (s BoltStorage) applyCache(o *cacheOptions) {
+	o.BoltStorage = &s
+}
+
+// boltFile is a bolt database file shared by cache instances.
+type boltFile struct {
+	path    string
+	storage BoltStorage
+	timeout time.Duration
+	refs    int
+	stop    chan struct{}
+	done    chan struct{}
+
+	// lock guards db that is replaced during compaction.
+	lock sync.RWMutex
+	db   *bolt.DB
+}
+
+// boltFiles shares open database files within the process as bolt locks the
+// file exclusively.
+var boltFiles = struct {
+	lock  sync.Mutex
+	files map[string]*boltFile
+}{
+	files: make(map[string]*boltFile),
+}
+
+func openBolt(path string, timeout time.Duration, noSync bool) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	db.NoSync = noSync
+	return db, nil
+}
+
+// acquireBoltFile opens the database file or returns already opened one.
+func acquireBoltFile(conf *BoltOptions, storage BoltStorage) (*boltFile, error) {
+	path, err := filepath.Abs(conf.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	boltFiles.lock.Lock()
+	defer boltFiles.lock.Unlock()
+
+	if f, ok := boltFiles.files[path]; ok {
+		f.refs++
+		return f, nil
+	}
+
+	if storage.GCInterval == 0 {
+		storage.GCInterval = DefaultBoltGCInterval
+	}
+	if storage.CompactRatio <= 0 {
+		storage.CompactRatio = DefaultBoltCompactRatio
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := openBolt(path, conf.Timeout, storage.NoSync)
+	if err != nil {
+		return nil, err
+	}
+	f := &boltFile{
+		path:    path,
+		storage: storage,
+		timeout: conf.Timeout,
+		refs:    1,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		db:      db,
+	}
+	boltFiles.files[path] = f
+	go f.maintain()
+	return f, nil
+}
+
+// release closes the database file when last cache instance is closed.
+func (f *boltFile) release() error {
+	boltFiles.lock.Lock()
+	f.refs--
+	if f.refs > 0 {
+		boltFiles.lock.Unlock()
+		return nil
+	}
+	delete(boltFiles.files, f.path)
+	boltFiles.lock.Unlock()
+
+	close(f.stop)
+	<-f.done
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.db.Close()
+}
+
+func (f *boltFile) view(fn func(tx *bolt.Tx) error) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.db.View(fn)
+}
+
+func (f *boltFile) update(fn func(tx *bolt.Tx) error) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.db.Update(fn)
+}
+
+// maintain periodically deletes expired items and compacts the database file.
+func (f *boltFile) maintain() {
+	defer close(f.done)
+
+	var gc, compact <-chan time.Time
+	if f.storage.GCInterval > 0 {
+		t := time.NewTicker(f.storage.GCInterval)
+		defer t.Stop()
+		gc = t.C
+	}
+	if f.storage.CompactInterval > 0 {
+		t := time.NewTicker(f.storage.CompactInterval)
+		defer t.Stop()
+		compact = t.C
+	}
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-gc:
+			_, _ = f.gc(time.Now())
+		case <-compact:
+			_, _ = f.compact(false)
+		}
+	}
+}
+
+// gc deletes expired items from all buckets and returns number of deleted items.
+func (f *boltFile) gc(now time.Time) (int, error) {
+	deleted := 0
+	err := f.update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, b *bolt.Bucket) error {
+			c := b.Cursor()
+			for k, v := c.First(); k != nil; {
+				if boltExpired(v, now) {
+					if err := c.Delete(); err != nil {
+						return err
+					}
+					deleted++
+					// Cursor is moved to the next item after delete.
+					k, v = c.Seek(k)
+					continue
+				}
+				k, v = c.Next()
+			}
+			return nil
+		})
+	})
+	return deleted, err
+}
+
+// compact rewrites database file to release free space if ratio of free space
+// exceeds configured threshold or force is set. Returns true if file was compacted.
+func (f *boltFile) compact(force bool) (bool, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !force {
+		fi, err := os.Stat(f.path)
+		if err != nil || fi.Size() == 0 {
+			return false, err
+		}
+		if float64(f.db.Stats().FreeAlloc)/float64(fi.Size()) < f.storage.CompactRatio {
+			return false, nil
+		}
+	}
+
+	tmp := f.path + ".compact"
+	_ = os.Remove(tmp)
+	dst, err := openBolt(tmp, f.timeout, true)
+	if err != nil {
+		return false, err
+	}
+	if err := bolt.Compact(dst, f.db, boltCompactTxSize); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(tmp)
+		return false, err
+	}
+	if err := dst.Sync(); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(tmp)
+		return false, err
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return false, err
+	}
+	if err := f.db.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return false, err
+	}
+	renameErr := os.Rename(tmp, f.path)
+	// Reopen database even if rename failed to keep cache working.
+	db, err := openBolt(f.path, f.timeout, f.storage.NoSync)
+	if err != nil {
+		return false, err
+	}
+	f.db = db
+	if renameErr != nil {
+		_ = os.Remove(tmp)
+		return false, renameErr
+	}
+	return true, nil
+}
+
+// boltExpired returns true if stored value has expired.
+func boltExpired(v []byte, now time.Time) bool {
+	if len(v) < boltExpiresSize {
+		return true
+	}
+	exp := int64(binary.BigEndian.Uint64(v))
+	return exp != 0 && exp <= now.UnixNano()
+}
+
+type boltCache[T any] struct {
+	file         *boltFile
+	bucket       []byte
+	now          func() time.Time
+	closed       atomic.Bool
+	prefix       string
+	items        itemDefaults[T]
+	loader       func(ctx context.Context, key string) (T, error)
+	instrumenter instrumenter.Instrumenter
+	audit        *Audit
+	version      int
+	migrations   map[int]MigrationFunc
+	ttlGuard     *TTLGuard
+
+	backendState
+}
+
+func newBoltCache[T any](name string, opts ...CacheOption) (CacheInstance[T], error) {
+	opt := newCacheOptions(opts...)
+
+	conf, err := ParseBoltURL(opt.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	var storage BoltStorage
+	if opt.BoltStorage != nil {
+		storage = *opt.BoltStorage
+	}
+	file, err := acquireBoltFile(conf, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPrefix := opt.KeyPrefix
+	if keyPrefix != "" {
+		keyPrefix += ":"
+	}
+	c := &boltCache[T]{
+		file:         file,
+		bucket:       []byte(keyPrefix + name),
+		now:          time.Now,
+		prefix:       keyPrefix + name + ":",
+		items:        newItemDefaults[T](opt),
+		loader:       newLoader[T](opt),
+		instrumenter: opt.Instrumenter,
+		audit:        opt.Audit,
+		version:      opt.SchemaVersion,
+		migrations:   opt.Migrations,
+		ttlGuard:     opt.TTLGuard,
+	}
+	if err := file.update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(c.bucket)
+		return err
+	}); err != nil {
+		_ = file.release()
+		return nil, err
+	}
+	return c, nil
+}
+
+// get returns stored value of the key that has not expired. If del is set
+// value is deleted in the same transaction.
+func (c *boltCache[T]) get(key string, del bool) ([]byte, bool, error) {
+	var buf []byte
+	fn := func(tx *bolt.Tx) error {
+		b := tx.Bucket(c.bucket)
+		v := b.Get([]byte(key))
+		if v == nil || boltExpired(v, c.now()) {
+			return nil
+		}
+		// Value is valid only during the transaction.
+		buf = append([]byte{}, v[boltExpiresSize:]...)
+		if del {
+			return b.Delete([]byte(key))
+		}
+		return nil
+	}
+	var err error
+	if del {
+		err = c.file.update(fn)
+	} else {
+		err = c.file.view(fn)
+	}
+	return buf, buf != nil, err
+}
+
+func (c *boltCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, true, opts...)
+}
+
+// peek returns value from cache without calling the loader.
+func (c *boltCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, false, opts...)
+}
+
+// read returns value from cache. Missing value is loaded and stored if load is
+// true, otherwise ErrKeyNotFound error is returned.
+func (c *boltCache[T]) read(ctx context.Context, key string, load bool, opts ...ItemOption[T]) (T, error) {
+	val := new(T)
+	if c.closed.Load() {
+		return *val, ErrCacheClosed
+	}
+	opt := c.items.resolve(opts...)
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+
+	buf, ok, err := c.get(key, false)
+	if err != nil {
+		finish(err)
+		return *val, err
+	}
+	if !ok {
+		if !load {
+			finish(nil)
+			return *val, ErrKeyNotFound{Key: key}
+		}
+		v, err := loadAndStore(ctx, c.loader, c.Set, key, opt, opts...)
+		finish(err)
+		return v, err
+	}
+	if err := decodeValue(c.migrations, c.version, opt, buf, val); err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return *val, err
+	}
+	finish(nil)
+	return *val, nil
+}
+
+func (c *boltCache[T]) Exists(ctx context.Context, key string) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	var ok bool
+	err := c.file.view(func(tx *bolt.Tx) error {
+		v := tx.Bucket(c.bucket).Get([]byte(key))
+		ok = v != nil && !boltExpired(v, c.now())
+		return nil
+	})
+	finish(err)
+	return ok, err
+}
+
+func (c *boltCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	val := new(T)
+	if c.closed.Load() {
+		return *val, ErrCacheClosed
+	}
+
+	finishG := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	finishD := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+key)
+
+	buf, ok, err := c.get(key, true)
+	if err != nil {
+		finishD(err)
+		finishG(err)
+		return *val, err
+	}
+	if !ok {
+		finishD(nil)
+		finishG(nil)
+		return *val, ErrKeyNotFound{Key: key}
+	}
+	if err := decodeValue(c.migrations, c.version, c.items.resolve(), buf, val); err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finishD(err)
+		finishG(err)
+		return *val, err
+	}
+	finishD(nil)
+	finishG(nil)
+	return *val, nil
+}
+
+// put stores value in the bucket. If nx is true, value is stored only if key
+// does not exist or has expired. Returns true if value was stored.
+func (c *boltCache[T]) put(ctx context.Context, key string, value T, nx bool, opts ...ItemOption[T]) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	opt := c.items.resolve(opts...)
+	ttl, err := c.ttlGuard.expiration(ctx, key, opt.TTL)
+	if err != nil {
+		finish(err)
+		return false, err
+	}
+	buf, err := encodeValue(ctx, c.audit, c.version, opt, value)
+	if err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return false, err
+	}
+
+	now := c.now()
+	v := make([]byte, boltExpiresSize+len(buf))
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(v, uint64(now.Add(ttl).UnixNano()))
+	}
+	copy(v[boltExpiresSize:], buf)
+
+	stored := true
+	err = c.file.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(c.bucket)
+		if nx {
+			if old := b.Get([]byte(key)); old != nil && !boltExpired(old, now) {
+				stored = false
+				return nil
+			}
+		}
+		return b.Put([]byte(key), v)
+	})
+	finish(err)
+	return stored && err == nil, err
+}
+
+func (c *boltCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	_, err := c.put(ctx, key, value, false, opts...)
+	return err
+}
+
+// SetNX stores value only if key does not exist or has expired. Returns true if value was stored.
+func (c *boltCache[T]) SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error) {
+	return c.put(ctx, key, value, true, opts...)
+}
+
+func (c *boltCache[T]) Delete(ctx context.Context, key string) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+key)
+
+	err := c.file.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(c.bucket).Delete([]byte(key))
+	})
+	finish(err)
+	return err
+}
+
+func (c *boltCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	return c.items.resolve(opts...)
+}
+
+func (c *boltCache[T]) Ping(ctx context.Context) error {
+	if c.closed.Load() {
+		return nil
+	}
+	return c.file.view(func(tx *bolt.Tx) error {
+		if tx.Bucket(c.bucket) == nil {
+			return fmt.Errorf("bolt: bucket %q not found", c.bucket)
+		}
+		return nil
+	})
+}
+
+func (c *boltCache[T]) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	return c.file.release()
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBoltCache[T any](t *testing.T, path string, opts ...CacheOption) *boltCache[T] {
+	t.Helper()
+
+	opts = append([]CacheOption{BoltCache, ConnectionString("bolt://" + path)}, opts...)
+	c, err := newBoltCache[T]("test", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.(*boltCache[T]).Close() })
+	return c.(*boltCache[T])
+}
+
+func TestParseBoltURL(t *testing.T) {
+	o, err := ParseBoltURL("bolt:///var/lib/app/cache.db?timeout=5s")
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/app/cache.db", o.Path)
+	assert.Equal(t, 5*time.Second, o.Timeout)
+
+	o, err = ParseBoltURL("bolt://data/cache.db")
+	require.NoError(t, err)
+	assert.Equal(t, "data/cache.db", o.Path)
+	assert.Equal(t, time.Second, o.Timeout)
+
+	_, err = ParseBoltURL("bolt://")
+	assert.Error(t, err)
+	_, err = ParseBoltURL("bolt://cache.db?timeout=soon")
+	assert.Error(t, err)
+	_, err = ParseBoltURL("/var/lib/app/cache.db")
+	assert.Error(t, err)
+	require.NoError(t, ValidateConnectionString(BoltCache, "bolt://cache.db"))
+	assert.Error(t, ValidateConnectionString(BoltCache, ""))
+}
+
+func TestBoltCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "cache.db")
+	c := newTestBoltCache[string](t, path, DefaultTTL(time.Minute))
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(context.TODO(), "key", "value"))
+	require.NoError(t, c.Set(context.TODO(), "short", "value", TTL[string](time.Second)))
+	require.NoError(t, c.Set(context.TODO(), "forever", "value", TTL[string](0)))
+
+	v, err := c.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	now = now.Add(2 * time.Second)
+	v, err = c.Get(context.TODO(), "short", DefaultValue[string]{"default"})
+	require.NoError(t, err)
+	assert.Equal(t, "default", v)
+	_, err = c.Pop(context.TODO(), "short")
+	assert.Equal(t, ErrKeyNotFound{Key: "short"}, err)
+
+	v, err = c.Pop(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	_, err = c.Pop(context.TODO(), "key")
+	assert.Equal(t, ErrKeyNotFound{Key: "key"}, err)
+
+	require.NoError(t, c.Delete(context.TODO(), "forever"))
+	v, err = c.Get(context.TODO(), "forever")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+
+	require.NoError(t, c.Ping(context.TODO()))
+	require.NoError(t, c.Close())
+	_, err = c.Get(context.TODO(), "key")
+	assert.ErrorIs(t, err, ErrCacheClosed)
+}
+
+func TestBoltCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	c := newTestBoltCache[int](t, path, KeyPrefix("app"))
+	// Instances share database file.
+	other := newTestBoltCache[int](t, path)
+	require.NoError(t, c.Set(context.TODO(), "key", 42))
+	require.NoError(t, other.Set(context.TODO(), "key", 7))
+	require.NoError(t, c.Close())
+	require.NoError(t, other.Close())
+
+	c = newTestBoltCache[int](t, path, KeyPrefix("app"))
+	v, err := c.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+}
+
+func TestBoltCacheMaintenance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	c := newTestBoltCache[string](t, path, BoltStorage{GCInterval: -1, CompactRatio: 0.1})
+
+	value := strings.Repeat("x", 1024)
+	for i := 0; i < 500; i++ {
+		require.NoError(t, c.Set(context.TODO(), strconv.Itoa(i), value, TTL[string](time.Millisecond)))
+	}
+	require.NoError(t, c.Set(context.TODO(), "keep", "value"))
+
+	n, err := c.file.gc(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 500, n)
+
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+	ok, err := c.file.compact(false)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size())
+
+	ok, err = c.file.compact(false)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	v, err := c.Get(context.TODO(), "keep")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+}
@@ -8,6 +8,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -34,10 +36,17 @@ func (e ErrKeyNotFound) Error() string {
 
 // Cache represents a cache.
 type Cache struct {
-	options     []CacheOption
-	cache       map[string]any
-	redisCon    redis.Cmdable
-	redisConStr string
+	options  []CacheOption
+	lock     sync.Mutex
+	cache    map[string]any
+	redisCon redis.Cmdable
+	redisRef *connRef
+	conns    connManager
+	pubsub   memoryPubSub
+	modeLock sync.Mutex
+	modes    map[string]*modeSwitch
+	diffLock sync.Mutex
+	diffs    map[string]func(ctx context.Context, key string) (*ValueDiff, error)
 }
 
 // New creates a new cache with specified type.
@@ -53,6 +62,8 @@ func New(opts ...CacheOption) *Cache {
 type CacheInstance[T any] interface {
 	// Get value from cache. If value is not found, it will return default value.
 	Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error)
+	// Exists checks if value exists in cache without decoding it or calling loader.
+	Exists(ctx context.Context, key string) (bool, error)
 	// Pop returns value from tha cache and deletes it. If value is not found, it will return ErrKeyNotFound error.
 	Pop(ctx context.Context, key string) (T, error)
 	// Set value in cache.
@@ -78,20 +89,14 @@ func (c *Cache) Start(ctx context.Context) error {
 
 	finish := opt.Instrumenter.Observe(ctx, InstrumentationCacheStart)
 
-	if opt.Type == RedisCache || opt.Type == RedisClusterCache {
-		var err error
-		var con redis.Cmdable
-		if opt.Type == RedisCache {
-			con, err = newRedisClient(opt.ConnectionString, opt.ConnectionPassword)
-		} else {
-			con, err = newRedisClusterClient(opt.ConnectionString, opt.ConnectionPassword)
-		}
+	if opt.Type.isRedis() {
+		ref, err := c.conns.acquireConn(opt)
 		if err != nil {
 			finish(err)
 			return err
 		}
-		c.redisCon = con
-		c.redisConStr = opt.ConnectionString
+		c.redisRef = ref
+		c.redisCon = ref.con
 	}
 	finish(nil)
 	return nil
@@ -104,22 +109,22 @@ func (c *Cache) Close() {
 	finish := opt.Instrumenter.Observe(context.Background(), InstrumentationCacheClose)
 	defer finish(nil)
 
-	if opt.Type == RedisCache {
-		v := c.redisCon.(*redis.Client)
-		_ = v.Close()
-		c.redisCon = nil
-	}
-	if opt.Type == RedisClusterCache {
-		v := c.redisCon.(*redis.ClusterClient)
-		_ = v.Close()
-		c.redisCon = nil
-	}
-	for _, i := range c.cache {
-		if c, ok := i.(CacheInstanceCloser); ok {
-			c.Close()
-		}
-	}
+	c.lock.Lock()
+	instances := c.cache
 	c.cache = nil
+	c.lock.Unlock()
+
+	c.diffLock.Lock()
+	c.diffs = nil
+	c.diffLock.Unlock()
+
+	for _, i := range instances {
+		closeInstance(i)
+	}
+	// Shared client is closed when cache and all its instances are closed.
+	_ = c.redisRef.Release()
+	c.redisRef = nil
+	c.redisCon = nil
 }
 
 // Ping cache and all its instances.
@@ -128,13 +133,20 @@ func (c *Cache) Ping(ctx context.Context) error {
 
 	finish := opt.Instrumenter.Observe(ctx, InstrumentationCachePing)
 
-	if (opt.Type == RedisCache || opt.Type == RedisClusterCache) && c.redisCon != nil {
-		if s := c.redisCon.Ping(ctx); s != nil && s.Err() != nil {
-			finish(s.Err())
-			return s.Err()
+	if opt.Type.isRedis() && c.redisCon != nil {
+		if err := pingRedis(ctx, c.redisCon); err != nil {
+			finish(err)
+			return err
 		}
 	}
+	c.lock.Lock()
+	instances := make([]any, 0, len(c.cache))
 	for _, i := range c.cache {
+		instances = append(instances, i)
+	}
+	c.lock.Unlock()
+
+	for _, i := range instances {
 		if c, ok := i.(CacheInstancePinger); ok {
 			if err := c.Ping(ctx); err != nil {
 				finish(err)
@@ -146,11 +158,71 @@ func (c *Cache) Ping(ctx context.Context) error {
 	return nil
 }
 
+// redisInfoFields are Redis INFO fields returned by Info.
+var redisInfoFields = []string{
+	"redis_version",
+	"redis_mode",
+	"role",
+	"uptime_in_seconds",
+	"connected_clients",
+	"blocked_clients",
+	"rejected_connections",
+	"used_memory_human",
+	"maxmemory_human",
+	"mem_fragmentation_ratio",
+	"instantaneous_ops_per_sec",
+	"keyspace_hits",
+	"keyspace_misses",
+	"expired_keys",
+	"evicted_keys",
+	"connected_slaves",
+}
+
+// Info returns highlights of the Redis server INFO used for diagnostics.
+//
+// Returns ErrNotSupported if cache is not using Redis.
+func (c *Cache) Info(ctx context.Context) (map[string]string, error) {
+	opt := newCacheOptions(c.options...)
+	if !opt.Type.isRedis() || c.redisCon == nil {
+		return nil, ErrNotSupported
+	}
+	raw, err := c.redisCon.Info(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]string)
+	for _, line := range strings.Split(raw, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && !strings.HasPrefix(k, "#") {
+			all[k] = v
+		}
+	}
+	info := make(map[string]string, len(redisInfoFields))
+	for _, k := range redisInfoFields {
+		if v, ok := all[k]; ok {
+			info[k] = v
+		}
+	}
+	return info, nil
+}
+
 // Get returns pre-configured cache instance by name.
+//
+// Cache instance declared with Define is created on first use.
 func Get[T any](cache *Cache, name string) (CacheInstance[T], error) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
 	i, ok := cache.cache[name]
 	if !ok {
-		return nil, errors.New("cache not found")
+		if _, defined := newCacheOptions(cache.options...).InstanceDefaults[name]; !defined {
+			return nil, errors.New("cache not found")
+		}
+		c, err := create[T](cache, name)
+		if err != nil {
+			return nil, err
+		}
+		cache.cache[name] = c
+		return c, nil
 	}
 	r, ok := i.(CacheInstance[T])
 	if !ok {
@@ -160,47 +232,149 @@ func Get[T any](cache *Cache, name string) (CacheInstance[T], error) {
 }
 
 // Create new cache instance with specified name and options.
+//
+// Options declared for the cache instance name with Define are applied before
+// the specified options.
 func Create[T any](cache *Cache, name string, opts ...CacheOption) (CacheInstance[T], error) {
-	opt := append(append([]CacheOption{}, cache.options...), opts...)
+	c, err := create[T](cache, name, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.cache[name] = c
+	return c, nil
+}
+
+func create[T any](cache *Cache, name string, opts ...CacheOption) (CacheInstance[T], error) {
+	opt := instanceOptions[T](cache, name, opts...)
 
 	o := newCacheOptions(opt...)
 
+	if o.ReadOnly != nil && o.Loader != nil {
+		return nil, errors.New("loader can not be used with read-only cache instance")
+	}
+	if o.ReadOnly != nil && o.SlidingExpiration != nil {
+		return nil, errors.New("sliding expiration can not be used with read-only cache instance")
+	}
+	if err := validateLoader[T](o); err != nil {
+		return nil, err
+	}
+	if o.TTLJitter < 0 || o.TTLJitter > 100 {
+		return nil, errors.New("ttl jitter must be between 0 and 100 percent")
+	}
+	if o.ModeSwitch != nil {
+		if _, err := ParseMode(string(o.ModeSwitch.Mode)); err != nil {
+			return nil, err
+		}
+	}
+	if o.MemoryLimit != nil && o.MemoryCost != nil {
+		return nil, errors.New("memory limit can not be used together with memory cost")
+	}
+	if o.MemorySnapshot != nil && o.MemoryCost != nil {
+		return nil, errors.New("memory snapshot can not be used together with memory cost")
+	}
+	if o.Deduplicate != nil && o.Type != RedisCache && o.Type != RedisSentinelCache {
+		return nil, errors.New("deduplication is supported only for standalone redis cache instances")
+	}
+
 	var c CacheInstance[T]
 	var err error
 
 	switch o.Type {
 	case MemoryCache:
-		c, err = newMemoryCache[T](opt...)
+		if o.MemoryLimit != nil || o.MemorySnapshot != nil {
+			c, err = newLRUCache[T](opt...)
+		} else {
+			c, err = newMemoryCache[T](opt...)
+		}
 		if err != nil {
 			return nil, err
 		}
-	case RedisCache:
-		con := cache.redisCon
-		if o.ConnectionString != cache.redisConStr {
-			con, err = newRedisClient(o.ConnectionString, o.ConnectionPassword)
-			if err != nil {
-				return nil, err
-			}
+	case RedisCache, RedisClusterCache, RedisRingCache, RedisSentinelCache:
+		ref, err := cache.conns.acquireConn(o)
+		if err != nil {
+			return nil, err
 		}
-		c, err = newRedisCache[T](name, con, opt...)
+		c, err = newRedisCache[T](name, ref, opt...)
 		if err != nil {
+			_ = ref.Release()
 			return nil, err
 		}
-	case RedisClusterCache:
-		con := cache.redisCon
-		if o.ConnectionString != cache.redisConStr {
-			con, err = newRedisClusterClient(o.ConnectionString, o.ConnectionPassword)
-			if err != nil {
-				return nil, err
-			}
+	case MemcachedCache:
+		c, err = newMemcachedCache[T](name, opt...)
+		if err != nil {
+			return nil, err
+		}
+	case NATSCache:
+		c, err = newNATSCache[T](name, opt...)
+		if err != nil {
+			return nil, err
+		}
+	case EtcdCache:
+		c, err = newEtcdCache[T](name, opt...)
+		if err != nil {
+			return nil, err
+		}
+	case DynamoDBCache:
+		c, err = newDynamoDBCache[T](name, opt...)
+		if err != nil {
+			return nil, err
+		}
+	case PostgresCache:
+		c, err = newPostgresCache[T](name, opt...)
+		if err != nil {
+			return nil, err
 		}
-		c, err = newRedisCache[T](name, con, opt...)
+	case BoltCache:
+		c, err = newBoltCache[T](name, opt...)
+		if err != nil {
+			return nil, err
+		}
+	case RemoteCache:
+		c, err = newRemoteCache[T](name, opt...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p, ok := c.(stateProvider); ok {
+		p.state().loader = o.Loader != nil
+	}
+	if c != nil && o.Replication != nil {
+		c, err = newReplicatedCache(c, o.Type, name, opt...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c != nil && o.Tiered != nil {
+		c, err = newTieredCache(cache, c, o.Type, name, opt...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c != nil && o.Events != nil {
+		c, err = newEventCache(c, name, opt...)
 		if err != nil {
 			return nil, err
 		}
 	}
 	if c != nil {
-		cache.cache[name] = c
+		if o.ReadOnly != nil {
+			c = newReadOnlyCache(c, *o.ReadOnly)
+		}
+		if o.ModeSwitch != nil {
+			c = newModeCache(cache, c, name, opt...)
+		}
+		if o.SlidingExpiration != nil {
+			if _, ok := c.(CacheInstanceTTL[T]); !ok {
+				closeInstance(c)
+				return nil, errors.New("sliding expiration is not supported by cache instance")
+			}
+			c = newSlidingCache(c, *o.SlidingExpiration, o.Loader != nil)
+		}
+		registerDiff(cache, name, c, o)
 		return c, nil
 	}
 	return nil, errors.New("unsupported cache type")
@@ -226,5 +400,86 @@ func ValidateConnectionString(typ CacheType, connStr string) error {
 		}
 		return nil
 	}
+	if typ == RedisRingCache {
+		if len(connStr) == 0 {
+			return errors.New("ring connection string can not be empty")
+		}
+		if _, err := ParseRedisRingURL(connStr); err != nil {
+			return err
+		}
+		return nil
+	}
+	if typ == RedisSentinelCache {
+		if len(connStr) == 0 {
+			return errors.New("sentinel connection string can not be empty")
+		}
+		if _, err := ParseRedisSentinelURL(connStr); err != nil {
+			return err
+		}
+		return nil
+	}
+	if typ == MemcachedCache {
+		if len(connStr) == 0 {
+			return errors.New("memcached connection string can not be empty")
+		}
+		if _, err := ParseMemcachedURL(connStr); err != nil {
+			return err
+		}
+		return nil
+	}
+	if typ == NATSCache {
+		if len(connStr) == 0 {
+			return errors.New("nats connection string can not be empty")
+		}
+		if _, err := ParseNATSURL(connStr); err != nil {
+			return err
+		}
+		return nil
+	}
+	if typ == EtcdCache {
+		if len(connStr) == 0 {
+			return errors.New("etcd connection string can not be empty")
+		}
+		if _, err := ParseEtcdURL(connStr); err != nil {
+			return err
+		}
+		return nil
+	}
+	if typ == DynamoDBCache {
+		if len(connStr) == 0 {
+			return errors.New("dynamodb connection string can not be empty")
+		}
+		if _, err := ParseDynamoDBURL(connStr); err != nil {
+			return err
+		}
+		return nil
+	}
+	if typ == PostgresCache {
+		if len(connStr) == 0 {
+			return errors.New("postgres connection string can not be empty")
+		}
+		if _, err := ParsePostgresURL(connStr); err != nil {
+			return err
+		}
+		return nil
+	}
+	if typ == BoltCache {
+		if len(connStr) == 0 {
+			return errors.New("bolt connection string can not be empty")
+		}
+		if _, err := ParseBoltURL(connStr); err != nil {
+			return err
+		}
+		return nil
+	}
+	if typ == RemoteCache {
+		if len(connStr) == 0 {
+			return errors.New("remote connection string can not be empty")
+		}
+		if _, err := ParseRemoteURL(connStr); err != nil {
+			return err
+		}
+		return nil
+	}
 	return nil
 }
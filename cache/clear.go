@@ -0,0 +1,201 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	bolt "go.etcd.io/bbolt"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// CacheInstanceClear represents cache instance that can delete all its values.
+type CacheInstanceClear[T any] interface {
+	// Clear deletes all values of the cache instance.
+	Clear(ctx context.Context) error
+}
+
+// Clear deletes all values stored under the cache instance prefix.
+//
+// It can be used to wipe cache namespace after deployment. Values stored
+// concurrently with the clear may be left in the cache. Cache instances that
+// can not enumerate their values return ErrNotSupported.
+func Clear[T any](ctx context.Context, instance CacheInstance[T]) error {
+	if s, ok := instance.(CacheInstanceClear[T]); ok {
+		return s.Clear(ctx)
+	}
+	return ErrNotSupported
+}
+
+// Clear deletes all keys with the instance prefix including deduplicated
+// payloads. Keys are found using SCAN command and deleted in batches so that
+// Redis is not blocked by the KEYS command.
+func (c *redisCache[T]) Clear(ctx context.Context) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix)
+
+	err := scanKeys(ctx, c.con, c.prefix, func(node redis.Cmdable, keys []string) error {
+		// Keys in the same batch can belong to different cluster slots so
+		// they are deleted one by one in a pipeline.
+		_, err := node.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, k := range keys {
+				pipe.Del(ctx, k)
+			}
+			return nil
+		})
+		return err
+	})
+	finish(err)
+	return err
+}
+
+func (c *memoryCache[T]) Clear(ctx context.Context) error {
+	if c.cache == nil {
+		return ErrCacheClosed
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, "")
+	c.cache.Clear()
+	c.tags.reset()
+	finish(nil)
+	return nil
+}
+
+func (c *lruCache[T]) Clear(ctx context.Context) error {
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, "")
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		finish(ErrCacheClosed)
+		return ErrCacheClosed
+	}
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.bytes = 0
+	finish(nil)
+	return nil
+}
+
+// Clear recreates the instance bucket.
+func (c *boltCache[T]) Clear(ctx context.Context) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix)
+
+	err := c.file.update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(c.bucket); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		_, err := tx.CreateBucket(c.bucket)
+		return err
+	})
+	finish(err)
+	return err
+}
+
+func (c *postgresCache[T]) Clear(ctx context.Context) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix)
+
+	_, err := c.db.Exec(ctx, `DELETE FROM `+c.table+` WHERE key LIKE $1`,
+		postgresLikeEscaper.Replace(c.prefix)+"%")
+	finish(err)
+	return err
+}
+
+// Clear deletes all keys with the instance prefix and revokes their leases.
+func (c *etcdCache[T]) Clear(ctx context.Context) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix)
+
+	resp, err := c.client.Delete(ctx, c.prefix, clientv3.WithPrefix(), clientv3.WithPrevKV())
+	if err != nil {
+		finish(err)
+		return err
+	}
+	c.revoke(ctx, resp.PrevKvs)
+	finish(nil)
+	return nil
+}
+
+// Clear deletes all keys of the instance bucket.
+func (c *natsCache[T]) Clear(ctx context.Context) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix)
+
+	keys, err := c.kv.Keys(nats.Context(ctx))
+	if errors.Is(err, nats.ErrNoKeysFound) {
+		finish(nil)
+		return nil
+	}
+	if err != nil {
+		finish(err)
+		return err
+	}
+	for _, k := range keys {
+		if err := c.kv.Delete(k); err != nil && !errors.Is(err, nats.ErrKeyNotFound) {
+			finish(err)
+			return err
+		}
+	}
+	finish(nil)
+	return nil
+}
+
+func (c *readOnlyCache[T]) Clear(ctx context.Context) error {
+	return c.reject(ctx, InstrumentationCacheDelete, "")
+}
+
+func (c *modeCache[T]) Clear(ctx context.Context) error {
+	if m, _ := c.current(); m == ModeReadOnly {
+		return ErrReadOnly
+	}
+	err := Clear(ctx, c.CacheInstance)
+	c.forgetAll()
+	return err
+}
+
+func (c *slidingCache[T]) Clear(ctx context.Context) error {
+	return Clear(ctx, c.CacheInstance)
+}
+
+func (c *eventCache[T]) Clear(ctx context.Context) error {
+	return Clear(ctx, c.CacheInstance)
+}
+
+// Clear deletes values only in the primary cache instance.
+func (c *replicatedCache[T]) Clear(ctx context.Context) error {
+	return Clear(ctx, c.CacheInstance)
+}
+
+// Clear deletes values in the remote cache instance and local tiers of all
+// application instances.
+func (c *tieredCache[T]) Clear(ctx context.Context) error {
+	if err := Clear(ctx, c.CacheInstance); err != nil {
+		return err
+	}
+	c.forgetAll()
+	_ = c.local.Clear(ctx)
+	c.invalidateAll(ctx)
+	return nil
+}
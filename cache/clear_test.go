@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClear checks that Clear deletes all values of the instance but not
+// values of the other instance.
+func testClear(t *testing.T, i, other CacheInstance[string], n int) {
+	t.Helper()
+
+	ctx := context.TODO()
+	for k := 0; k < n; k++ {
+		require.NoError(t, i.Set(ctx, "key"+strconv.Itoa(k), "value"))
+	}
+	require.NoError(t, other.Set(ctx, "key0", "other"))
+
+	require.NoError(t, Clear(ctx, i))
+
+	for k := 0; k < n; k++ {
+		ok, err := i.Exists(ctx, "key"+strconv.Itoa(k))
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+	v, err := other.Get(ctx, "key0")
+	require.NoError(t, err)
+	assert.Equal(t, "other", v)
+
+	// Instance can still be used after clear.
+	require.NoError(t, i.Set(ctx, "key0", "value"))
+	v, err = i.Get(ctx, "key0")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+}
+
+func TestRedisCacheClear(t *testing.T) {
+	c, s := newMiniRedisCache(t, Deduplicate{MinSize: 1})
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	other, err := Create[string](c, "other")
+	require.NoError(t, err)
+
+	// More keys than single SCAN batch.
+	testClear(t, i, other, 2*scanBatchSize+10)
+
+	// Only value stored after clear and its payload are left.
+	left := make([]string, 0)
+	for _, k := range s.Keys() {
+		if strings.HasPrefix(k, "test:") {
+			left = append(left, k)
+		}
+	}
+	assert.Len(t, left, 2)
+	assert.Contains(t, left, "test:key0")
+}
+
+func TestMemoryCacheClear(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	other, err := Create[string](c, "other")
+	require.NoError(t, err)
+	testClear(t, i, other, 10)
+}
+
+func TestLRUCacheClear(t *testing.T) {
+	i, err := newLRUCache[string]()
+	require.NoError(t, err)
+	defer closeInstance(i)
+	other, err := newLRUCache[string]()
+	require.NoError(t, err)
+	defer closeInstance(other)
+
+	testClear(t, i, other, 10)
+	items, _ := i.(*lruCache[string]).Len()
+	assert.Equal(t, 1, items)
+}
+
+func TestBoltCacheClear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	c := newTestBoltCache[string](t, path)
+	other := newTestBoltCache[string](t, path, KeyPrefix("other"))
+	testClear(t, c, other, 10)
+}
+
+func TestPostgresCacheClear(t *testing.T) {
+	c, db := newTestPostgresCache[string](t)
+	conf, err := ParsePostgresURL("postgres://localhost/test?table=cache.items")
+	require.NoError(t, err)
+	other := newPostgresDBCache[string](db, "other", conf, newCacheOptions())
+	testClear(t, c, other, 10)
+	assert.Contains(t, db.queries, `DELETE FROM "cache"."items" WHERE key LIKE $1`)
+}
+
+func TestEtcdCacheClear(t *testing.T) {
+	c, client := newTestEtcdCache[string](t)
+	other := newEtcdClientCache[string](client, "other", newCacheOptions())
+
+	require.NoError(t, c.Set(context.TODO(), "ttl", "value", TTL[string](time.Minute)))
+	testClear(t, c, other, 10)
+	assert.Empty(t, client.leases)
+}
+
+func TestNATSCacheClear(t *testing.T) {
+	c, _ := newTestNATSCache[string](t)
+	other, _ := newTestNATSCache[string](t)
+	testClear(t, c, other, 10)
+
+	// Empty bucket can be cleared.
+	require.NoError(t, c.Clear(context.TODO()))
+	require.NoError(t, c.Clear(context.TODO()))
+}
+
+func TestClearWrappers(t *testing.T) {
+	i, err := newLRUCache[string]()
+	require.NoError(t, err)
+	defer closeInstance(i)
+
+	assert.ErrorIs(t, Clear(context.TODO(), newReadOnlyCache(i, ReadOnly{})), ErrReadOnly)
+	assert.ErrorIs(t, Clear[string](context.TODO(), testMapCache[string]{}), ErrNotSupported)
+
+	c, _ := newTestMemcachedCache(t)
+	mi, err := Create[string](c, "test")
+	require.NoError(t, err)
+	assert.ErrorIs(t, Clear(context.TODO(), mi), ErrNotSupported)
+}
+
+func TestTieredCacheClear(t *testing.T) {
+	i1, i2, _ := newTieredTestCaches(t, Tiered{TTL: time.Hour})
+
+	require.NoError(t, i1.Set(context.TODO(), "key", "value"))
+	_, err := i2.Get(context.TODO(), "key")
+	require.NoError(t, err)
+
+	require.NoError(t, Clear(context.TODO(), i1))
+	assert.Eventually(t, func() bool {
+		ok, err := i2.Exists(context.TODO(), "key")
+		return err == nil && !ok
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ClusterSlots is a number of hash slots in Redis Cluster.
+const ClusterSlots = 16384
+
+// ClusterSlot returns Redis Cluster hash slot for the key.
+//
+// If key contains hash tag (non-empty substring between first `{` and following `}`)
+// only hash tag is used to calculate the slot.
+func ClusterSlot(key string) int {
+	return int(crc16(hashTag(key)) % ClusterSlots)
+}
+
+// hashTag returns part of the key that is used for hash slot calculation.
+func hashTag(key string) string {
+	s := strings.IndexByte(key, '{')
+	if s < 0 {
+		return key
+	}
+	e := strings.IndexByte(key[s+1:], '}')
+	if e <= 0 {
+		return key
+	}
+	return key[s+1 : s+1+e]
+}
+
+// hasHashTag returns true if key contains non-empty hash tag.
+func hasHashTag(key string) bool {
+	return hashTag(key) != key
+}
+
+// crc16 implements CRC16-CCITT (XMODEM) checksum used by Redis Cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// checkClusterPrefix returns error if cache instance key prefix would place
+// all keys of the instance in the same Redis Cluster hash slot.
+func checkClusterPrefix(con redis.Cmdable, prefix string) error {
+	if _, ok := con.(*redis.ClusterClient); !ok {
+		return nil
+	}
+	if hasHashTag(prefix) {
+		return fmt.Errorf("cache key prefix %q contains hash tag that would store all keys in single cluster slot", prefix)
+	}
+	return nil
+}
+
+// expandClusterHosts converts comma separated list of hosts in Redis Cluster
+// connection string to addr query parameters.
+func expandClusterHosts(v string) (string, error) {
+	scheme, rest, ok := strings.Cut(v, "://")
+	if !ok {
+		return v, nil
+	}
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	authority, tail := rest[:end], rest[end:]
+	var userinfo string
+	if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+		userinfo, authority = authority[:i+1], authority[i+1:]
+	}
+	hosts := strings.Split(authority, ",")
+	if len(hosts) == 1 {
+		return v, nil
+	}
+
+	u, err := url.Parse(scheme + "://" + userinfo + strings.TrimSpace(hosts[0]) + tail)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for _, h := range hosts[1:] {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			h = net.JoinHostPort(h, "6379")
+		}
+		q.Add("addr", h)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// pingRedis checks connection to Redis. For Redis Cluster every shard is checked.
+func pingRedis(ctx context.Context, con redis.Cmdable) error {
+	if cc, ok := con.(*redis.ClusterClient); ok {
+		return cc.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return shard.Ping(ctx).Err()
+		})
+	}
+	return con.Ping(ctx).Err()
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Coalesce combines identical reads of the same key in the window into single
+// Redis call, reducing load for extremely hot keys.
+//
+// Reads join the call that is in flight or has been started within the window.
+// Writes to the key from the same cache instance end the window.
+type Coalesce struct {
+	// Window is a time window to coalesce reads in. Defaults to 2 milliseconds.
+	Window time.Duration
+}
+
+func (c Coalesce) applyCache(o *cacheOptions) {
+	o.Coalesce = &c
+}
+
+type coalescedCall struct {
+	done    chan struct{}
+	started time.Time
+	s       *redis.StringCmd
+}
+
+type coalescer struct {
+	window time.Duration
+	lock   sync.Mutex
+	calls  map[string]*coalescedCall
+}
+
+func newCoalescer(conf *Coalesce) *coalescer {
+	if conf == nil {
+		return nil
+	}
+	window := conf.Window
+	if window <= 0 {
+		window = 2 * time.Millisecond
+	}
+	return &coalescer{
+		window: window,
+		calls:  make(map[string]*coalescedCall),
+	}
+}
+
+func (g *coalescer) do(key string, fn func() *redis.StringCmd) *redis.StringCmd {
+	g.lock.Lock()
+	if call, ok := g.calls[key]; ok {
+		select {
+		case <-call.done:
+			if time.Since(call.started) > g.window {
+				break
+			}
+			g.lock.Unlock()
+			return call.s
+		default:
+			g.lock.Unlock()
+			<-call.done
+			return call.s
+		}
+	}
+	call := &coalescedCall{
+		done:    make(chan struct{}),
+		started: time.Now(),
+	}
+	g.calls[key] = call
+	g.lock.Unlock()
+
+	call.s = fn()
+	close(call.done)
+
+	if d := g.window - time.Since(call.started); d > 0 {
+		time.AfterFunc(d, func() {
+			g.remove(key, call)
+		})
+	} else {
+		g.remove(key, call)
+	}
+	return call.s
+}
+
+func (g *coalescer) remove(key string, call *coalescedCall) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
+
+// forget ends coalescing window of the key.
+func (g *coalescer) forget(key string) {
+	if g == nil {
+		return
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	delete(g.calls, key)
+}
+
+// get returns value of the key coalescing identical reads if enabled.
+func (c *redisCache[T]) get(ctx context.Context, key string) *redis.StringCmd {
+	if c.coalesce == nil {
+		return c.fetch(ctx, key)
+	}
+	return c.coalesce.do(key, func() *redis.StringCmd {
+		return c.fetch(ctx, key)
+	})
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newRedisConnection returns new Redis client for the cache type.
+func newRedisConnection(typ CacheType, constr, password string, vnodes int) (redis.Cmdable, error) {
+	switch typ {
+	case RedisClusterCache:
+		return newRedisClusterClient(constr, password)
+	case RedisRingCache:
+		return newRedisRingClient(constr, password, vnodes)
+	case RedisSentinelCache:
+		return newRedisSentinelClient(constr, password)
+	default:
+		return newRedisClient(constr, password)
+	}
+}
+
+// RedisClient is an externally managed Redis client to use instead of creating
+// one from the connection string.
+//
+// Client is not closed when cache or its instances are closed. If cache type is
+// not set to one of Redis cache types, it is set based on the client type.
+type RedisClient struct {
+	redis.UniversalClient
+}
+
+func (c RedisClient) applyCache(o *cacheOptions) {
+	o.RedisClient = c.UniversalClient
+}
+
+// closeConnection closes Redis client.
+func closeConnection(con redis.Cmdable) error {
+	if c, ok := con.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type sharedConn struct {
+	key  string
+	con  redis.Cmdable
+	refs int
+}
+
+// connManager shares Redis clients with the same connection settings between
+// the cache and its instances.
+type connManager struct {
+	lock  sync.Mutex
+	conns map[string]*sharedConn
+}
+
+// acquire returns reference to the shared Redis client creating it if needed.
+func (m *connManager) acquire(typ CacheType, constr, password string, vnodes int) (*connRef, error) {
+	key := string(typ) + "\x00" + constr + "\x00" + password + "\x00" + strconv.Itoa(vnodes)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	conn, ok := m.conns[key]
+	if !ok {
+		con, err := newRedisConnection(typ, constr, password, vnodes)
+		if err != nil {
+			return nil, err
+		}
+		conn = &sharedConn{
+			key: key,
+			con: con,
+		}
+		if m.conns == nil {
+			m.conns = make(map[string]*sharedConn)
+		}
+		m.conns[key] = conn
+	}
+	conn.refs++
+
+	return &connRef{
+		con: conn.con,
+		release: func() error {
+			return m.release(conn)
+		},
+	}, nil
+}
+
+func (m *connManager) release(conn *sharedConn) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	conn.refs--
+	if conn.refs > 0 {
+		return nil
+	}
+	delete(m.conns, conn.key)
+	return closeConnection(conn.con)
+}
+
+// connRef is a reference to the Redis client that is released exactly once.
+type connRef struct {
+	con     redis.Cmdable
+	once    sync.Once
+	release func() error
+}
+
+// newBorrowedConn returns reference to the externally managed Redis client that
+// is never closed when released.
+func newBorrowedConn(con redis.Cmdable) *connRef {
+	return &connRef{
+		con: con,
+		release: func() error {
+			return nil
+		},
+	}
+}
+
+// acquireConn returns reference to the Redis client configured in cache options.
+func (m *connManager) acquireConn(opt *cacheOptions) (*connRef, error) {
+	if opt.RedisClient != nil {
+		return newBorrowedConn(opt.RedisClient), nil
+	}
+	return m.acquire(opt.Type, opt.ConnectionString, opt.ConnectionPassword, opt.VirtualNodes)
+}
+
+// newOwnedConn returns reference to the Redis client that is closed when released.
+func newOwnedConn(con redis.Cmdable) *connRef {
+	return &connRef{
+		con: con,
+		release: func() error {
+			return closeConnection(con)
+		},
+	}
+}
+
+// Release reference to the Redis client. Client is closed when last reference is released.
+func (r *connRef) Release() error {
+	if r == nil {
+		return nil
+	}
+	var err error
+	r.once.Do(func() {
+		err = r.release()
+	})
+	return err
+}
+
+// closeInstance closes cache instance if it supports closing.
+func closeInstance(i any) {
+	switch c := i.(type) {
+	case CacheInstanceCloser:
+		c.Close()
+	case interface{ Close() error }:
+		_ = c.Close()
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedConnection(t *testing.T) {
+	c, _ := newMiniRedisCache(t)
+	other := miniredis.RunT(t)
+	ctx := context.TODO()
+
+	a, err := Create[string](c, "a")
+	require.NoError(t, err)
+	b, err := Create[string](c, "b")
+	require.NoError(t, err)
+	d1, err := Create[string](c, "d1", ConnectionString("redis://"+other.Addr()))
+	require.NoError(t, err)
+	d2, err := Create[string](c, "d2", ConnectionString("redis://"+other.Addr()))
+	require.NoError(t, err)
+
+	assert.Len(t, c.conns.conns, 2)
+
+	// Closing one instance must not break others sharing the client.
+	closeInstance(a)
+	closeInstance(a)
+	assert.ErrorIs(t, a.Set(ctx, "key", "value"), ErrCacheClosed)
+	require.NoError(t, b.Set(ctx, "key", "value"))
+
+	closeInstance(d1)
+	require.NoError(t, d2.Set(ctx, "key", "value"))
+	assert.True(t, other.Exists("d2:key"))
+
+	closeInstance(d2)
+	assert.Len(t, c.conns.conns, 1)
+
+	c.Close()
+	assert.Empty(t, c.conns.conns)
+	assert.ErrorIs(t, b.Set(ctx, "key", "value"), ErrCacheClosed)
+}
+
+func TestRedisClientExternal(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	c := New(RedisClient{client})
+	require.NoError(t, c.Start(context.TODO()))
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+	assert.True(t, s.Exists("test:key"))
+	require.NoError(t, c.Ping(context.TODO()))
+
+	c.Close()
+
+	// Externally managed client must stay open.
+	require.NoError(t, client.Ping(context.TODO()).Err())
+	v, err := client.Get(context.TODO(), "test:key").Result()
+	require.NoError(t, err)
+	assert.Equal(t, `"value"`, v)
+}
+
+func TestRedisClientExternalType(t *testing.T) {
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{"localhost:7000"}})
+	defer cluster.Close()
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	assert.Equal(t, RedisClusterCache, newCacheOptions(RedisClient{cluster}).Type)
+	assert.Equal(t, RedisCache, newCacheOptions(RedisClient{client}).Type)
+	assert.Equal(t, RedisSentinelCache, newCacheOptions(RedisSentinelCache, RedisClient{client}).Type)
+}
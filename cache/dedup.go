@@ -0,0 +1,168 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// refMagic prefixes values that reference deduplicated payload.
+var refMagic = string([]byte{0xc1, 'A', 'Z', 'R'})
+
+// blobKeyPrefix is a key prefix of deduplicated payloads in the cache instance namespace.
+const blobKeyPrefix = "~blob:"
+
+// Deduplicate stores large values only once under the content hash key with reference
+// counting, and keys store only reference to the payload.
+//
+// Payload expires not earlier than the last key referencing it. Only supported for
+// standalone and sentinel managed Redis cache instances.
+type Deduplicate struct {
+	// MinSize is a minimum encoded value size in bytes to be deduplicated. Defaults to 1024.
+	MinSize int
+}
+
+func (d Deduplicate) applyCache(c *cacheOptions) {
+	c.Deduplicate = &d
+}
+
+func (d *Deduplicate) minSize() int {
+	if d.MinSize <= 0 {
+		return 1024
+	}
+	return d.MinSize
+}
+
+// dedupSetScript stores value or reference to the payload and releases previously
+// referenced payload.
+//
+// KEYS[1] - key, KEYS[2] - optional payload key.
+// ARGV[1] - value, ARGV[2] - payload, ARGV[3] - TTL in milliseconds,
+// ARGV[4] - reference prefix, ARGV[5] - payload key prefix,
+// ARGV[6] - optional "NX" to store value only if key does not exist.
+var dedupSetScript = redis.NewScript(`
+local old = redis.call('GET', KEYS[1])
+if old and ARGV[6] == 'NX' then
+	return false
+end
+local ttl = tonumber(ARGV[3])
+if old and old ~= ARGV[1] and string.sub(old, 1, 4) == ARGV[4] then
+	local blob = ARGV[5] .. string.sub(old, 5)
+	if redis.call('HINCRBY', blob, 'refs', -1) <= 0 then
+		redis.call('DEL', blob)
+	end
+end
+if #KEYS > 1 then
+	local created = 0
+	if old ~= ARGV[1] or redis.call('EXISTS', KEYS[2]) == 0 then
+		created = redis.call('HSETNX', KEYS[2], 'data', ARGV[2])
+		redis.call('HINCRBY', KEYS[2], 'refs', 1)
+	end
+	local bttl = redis.call('PTTL', KEYS[2])
+	if ttl <= 0 then
+		redis.call('PERSIST', KEYS[2])
+	elseif created == 1 or (bttl >= 0 and bttl < ttl) then
+		redis.call('PEXPIRE', KEYS[2], ttl)
+	end
+end
+if ttl > 0 then
+	return redis.call('SET', KEYS[1], ARGV[1], 'PX', ttl)
+end
+return redis.call('SET', KEYS[1], ARGV[1])
+`)
+
+// dedupReleaseScript returns payload and releases the reference to it.
+//
+// KEYS[1] - payload key.
+var dedupReleaseScript = redis.NewScript(`
+local data = redis.call('HGET', KEYS[1], 'data')
+if redis.call('HINCRBY', KEYS[1], 'refs', -1) <= 0 then
+	redis.call('DEL', KEYS[1])
+end
+return data
+`)
+
+func (c *redisCache[T]) blobKey(ref string) string {
+	return c.prefix + blobKeyPrefix + strings.TrimPrefix(ref, refMagic)
+}
+
+// store stores encoded value with the key.
+func (c *redisCache[T]) store(ctx context.Context, key string, buf []byte, ttl time.Duration) error {
+	_, err := c.put(ctx, key, buf, ttl, false)
+	return err
+}
+
+// put stores encoded value with the key. If nx is true, value is stored only
+// if key does not exist. Returns true if value was stored.
+func (c *redisCache[T]) put(ctx context.Context, key string, buf []byte, ttl time.Duration, nx bool) (bool, error) {
+	defer c.coalesce.forget(c.prefix + key)
+
+	if c.dedup == nil {
+		if nx {
+			return c.con.SetNX(ctx, c.prefix+key, string(buf), ttl).Result()
+		}
+		return true, c.con.Set(ctx, c.prefix+key, string(buf), ttl).Err()
+	}
+
+	keys := []string{c.prefix + key}
+	args := []any{string(buf), "", ttl.Milliseconds(), refMagic, c.prefix + blobKeyPrefix}
+	if nx {
+		args = append(args, "NX")
+	}
+
+	if len(buf) >= c.dedup.minSize() {
+		h := sha256.Sum256(buf)
+		ref := refMagic + hex.EncodeToString(h[:])
+		keys = append(keys, c.blobKey(ref))
+		args[0], args[1] = ref, string(buf)
+	}
+	err := dedupSetScript.Run(ctx, c.con, keys, args...).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// deref returns stored value resolving reference to the deduplicated payload.
+func (c *redisCache[T]) deref(ctx context.Context, s *redis.StringCmd) (string, error) {
+	if s.Err() != nil {
+		return "", s.Err()
+	}
+	if c.dedup == nil || !strings.HasPrefix(s.Val(), refMagic) {
+		return s.Val(), nil
+	}
+	return c.con.HGet(ctx, c.blobKey(s.Val()), "data").Result()
+}
+
+// release returns removed value resolving and releasing reference to the deduplicated payload.
+func (c *redisCache[T]) release(ctx context.Context, s *redis.StringCmd) (string, error) {
+	if s.Err() != nil {
+		return "", s.Err()
+	}
+	if c.dedup == nil || !strings.HasPrefix(s.Val(), refMagic) {
+		return s.Val(), nil
+	}
+	return dedupReleaseScript.Run(ctx, c.con, []string{c.blobKey(s.Val())}).Text()
+}
+
+// del deletes the key releasing reference to the deduplicated payload.
+func (c *redisCache[T]) del(ctx context.Context, key string) error {
+	defer c.coalesce.forget(c.prefix + key)
+
+	if c.dedup == nil {
+		return c.con.Del(ctx, c.prefix+key).Err()
+	}
+	_, err := c.release(ctx, c.con.GetDel(ctx, c.prefix+key))
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
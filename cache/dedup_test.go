@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisCacheDeduplicate(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+	ctx := context.TODO()
+
+	i, err := Create[string](c, "test", Deduplicate{MinSize: 100})
+	require.NoError(t, err)
+
+	blob := strings.Repeat("x", 200)
+	require.NoError(t, i.Set(ctx, "a", blob, TTL[string](time.Minute)))
+	require.NoError(t, i.Set(ctx, "b", blob, TTL[string](time.Hour)))
+	require.NoError(t, i.Set(ctx, "small", "value"))
+
+	blobs := func() []string {
+		keys := make([]string, 0)
+		for _, k := range s.Keys() {
+			if strings.HasPrefix(k, "test:"+blobKeyPrefix) {
+				keys = append(keys, k)
+			}
+		}
+		return keys
+	}
+	require.Len(t, blobs(), 1)
+	assert.Equal(t, "2", s.HGet(blobs()[0], "refs"))
+	assert.Equal(t, time.Hour, s.TTL(blobs()[0]))
+
+	raw, err := s.Get("test:small")
+	require.NoError(t, err)
+	assert.Equal(t, `"value"`, raw)
+
+	v, err := i.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, blob, v)
+
+	v, err = i.Pop(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, blob, v)
+	assert.Equal(t, "1", s.HGet(blobs()[0], "refs"))
+
+	// Overwriting with small value releases the payload.
+	require.NoError(t, i.Set(ctx, "b", "small"))
+	assert.Empty(t, blobs())
+
+	require.NoError(t, i.Set(ctx, "c", blob))
+	require.NoError(t, i.Delete(ctx, "c"))
+	assert.Empty(t, blobs())
+}
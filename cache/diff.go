@@ -0,0 +1,270 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"azugo.io/core/scrub"
+
+	"github.com/goccy/go-json"
+)
+
+// ValueDiff is a difference between the cached value and the value returned
+// by the loader.
+type ValueDiff struct {
+	// Instance is a name of the cache instance.
+	Instance string `json:"instance,omitempty"`
+	// Key of the value.
+	Key string `json:"key"`
+	// Cached is true if value is found in cache.
+	Cached bool `json:"cached"`
+	// Loaded is true if loader returned value.
+	Loaded bool `json:"loaded"`
+	// Stale is true if cached value differs from the loaded value.
+	Stale bool `json:"stale"`
+	// Changes are differences of the value fields.
+	Changes []FieldDiff `json:"changes,omitempty"`
+}
+
+// FieldDiff is a difference of the value field.
+type FieldDiff struct {
+	// Path of the field in JSON representation of the value, for example
+	// "address.city" or "items[2].price". Empty for the whole value.
+	Path string `json:"path"`
+	// Cached is a decoded JSON value of the field in cache.
+	Cached any `json:"cached"`
+	// Fresh is a decoded JSON value of the field returned by the loader.
+	Fresh any `json:"fresh"`
+}
+
+// DiffValue reads value from the cache instance backend without calling its
+// loader or changing its TTL, loads fresh value using the loader and returns
+// field level difference between them to check whether cached value is stale.
+//
+// Values are compared in their JSON representation. Values of fields are
+// scrubbed with scrubber rules if scrubber is not nil.
+func DiffValue[T any](ctx context.Context, instance CacheInstance[T], key string, loader Loader[T], scrubber *scrub.Scrubber) (*ValueDiff, error) {
+	if loader == nil {
+		return nil, ErrNotSupported
+	}
+	d := &ValueDiff{Key: key}
+
+	var cached, fresh any
+	// Instance mode and sliding expiration do not apply to the backend.
+	v, err := peek(ctx, backendInstance(instance), key)
+	if err != nil && !isKeyNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		if cached, err = decodeTree(v); err != nil {
+			return nil, err
+		}
+		d.Cached = true
+	}
+
+	v, err = loader(ctx, key)
+	if err != nil && !isKeyNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		if fresh, err = decodeTree(v); err != nil {
+			return nil, err
+		}
+		d.Loaded = true
+	}
+
+	if d.Cached != d.Loaded {
+		d.Changes = []FieldDiff{{Cached: cached, Fresh: fresh}}
+	} else {
+		d.Changes = diffTree("", cached, fresh, nil)
+	}
+	d.Stale = len(d.Changes) != 0
+	if scrubber.Enabled() {
+		for i := range d.Changes {
+			d.Changes[i].Cached = scrubField(scrubber, d.Changes[i].Path, d.Changes[i].Cached)
+			d.Changes[i].Fresh = scrubField(scrubber, d.Changes[i].Path, d.Changes[i].Fresh)
+		}
+	}
+	return d, nil
+}
+
+// decodeTree returns value decoded from its JSON representation.
+func decodeTree(v any) (any, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree any
+	if err := json.Unmarshal(buf, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// diffTree appends differences of the decoded JSON values to changes.
+func diffTree(path string, a, b any, changes []FieldDiff) []FieldDiff {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := k
+			if len(path) != 0 {
+				p = path + "." + k
+			}
+			changes = diffTree(p, av[k], bv[k], changes)
+		}
+		return changes
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			break
+		}
+		n := len(av)
+		if len(bv) > n {
+			n = len(bv)
+		}
+		for i := 0; i < n; i++ {
+			var x, y any
+			if i < len(av) {
+				x = av[i]
+			}
+			if i < len(bv) {
+				y = bv[i]
+			}
+			changes = diffTree(path+"["+strconv.Itoa(i)+"]", x, y, changes)
+		}
+		return changes
+	}
+	if !reflect.DeepEqual(a, b) {
+		changes = append(changes, FieldDiff{Path: path, Cached: a, Fresh: b})
+	}
+	return changes
+}
+
+// scrubField returns scrubbed value of the field. Array elements share scrub
+// path of the array field.
+func scrubField(s *scrub.Scrubber, path string, v any) any {
+	var p strings.Builder
+	for len(path) != 0 {
+		i := strings.IndexByte(path, '[')
+		if i < 0 {
+			p.WriteString(path)
+			break
+		}
+		p.WriteString(path[:i])
+		j := strings.IndexByte(path[i:], ']')
+		if j < 0 {
+			break
+		}
+		path = path[i+j+1:]
+	}
+	v, ok := s.Any(p.String(), v)
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+// registerDiff registers function to diff values of the cache instance with
+// its loader.
+func registerDiff[T any](cache *Cache, name string, instance CacheInstance[T], o *cacheOptions) {
+	loader, _ := o.Loader.(Loader[T])
+	scrubber := o.Scrubber
+
+	cache.diffLock.Lock()
+	defer cache.diffLock.Unlock()
+
+	if cache.diffs == nil {
+		cache.diffs = make(map[string]func(ctx context.Context, key string) (*ValueDiff, error))
+	}
+	cache.diffs[name] = func(ctx context.Context, key string) (*ValueDiff, error) {
+		return DiffValue(ctx, instance, key, loader, scrubber)
+	}
+}
+
+// Diff returns difference between the value cached in the cache instance with
+// the name and the value returned by the cache instance loader.
+//
+// Returns ErrNotSupported if cache instance has no loader.
+func (c *Cache) Diff(ctx context.Context, name, key string) (*ValueDiff, error) {
+	c.diffLock.Lock()
+	fn, ok := c.diffs[name]
+	c.diffLock.Unlock()
+
+	if !ok {
+		return nil, errors.New("cache not found")
+	}
+	d, err := fn(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	d.Instance = name
+	return d, nil
+}
+
+// DiffHandler returns admin HTTP handler that responds with difference between
+// cached and freshly loaded value of the cache instance and key specified by
+// "instance" and "key" query parameters.
+//
+// Handler calls the loader so it must only be served on the administrative
+// endpoint.
+func (c *Cache) DiffHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		default:
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		name, key := r.URL.Query().Get("instance"), r.URL.Query().Get("key")
+		if len(name) == 0 || len(key) == 0 {
+			http.Error(w, "instance and key parameters are required", http.StatusBadRequest)
+			return
+		}
+
+		c.diffLock.Lock()
+		_, ok := c.diffs[name]
+		c.diffLock.Unlock()
+		if !ok {
+			http.Error(w, "cache not found", http.StatusNotFound)
+			return
+		}
+
+		d, err := c.Diff(r.Context(), name, key)
+		if errors.Is(err, ErrNotSupported) {
+			http.Error(w, "cache instance has no loader", http.StatusNotImplemented)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(d)
+	})
+}
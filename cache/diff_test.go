@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"azugo.io/core/scrub"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAccount struct {
+	Name     string   `json:"name"`
+	Password string   `json:"password"`
+	Roles    []string `json:"roles"`
+}
+
+func TestDiffValue(t *testing.T) {
+	c := New(CacheType(MemoryCache), MemorySyncWrites(true), Scrub{scrub.New(scrub.Rules{{Field: "password", Action: scrub.Mask}})})
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	fresh := map[string]testAccount{
+		"john": {Name: "John", Password: "new", Roles: []string{"admin", "user"}},
+		"jane": {Name: "Jane", Roles: []string{"user"}},
+	}
+	i, err := Create[testAccount](c, "accounts", Loader[testAccount](func(_ context.Context, key string) (testAccount, error) {
+		if v, ok := fresh[key]; ok {
+			return v, nil
+		}
+		return testAccount{}, ErrKeyNotFound{Key: key}
+	}))
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "john", testAccount{Name: "Johnny", Password: "old", Roles: []string{"admin"}}))
+	require.NoError(t, i.Set(context.TODO(), "jane", fresh["jane"]))
+	require.NoError(t, i.Set(context.TODO(), "gone", testAccount{Name: "Gone"}))
+
+	d, err := c.Diff(context.TODO(), "accounts", "john")
+	require.NoError(t, err)
+	assert.Equal(t, "accounts", d.Instance)
+	assert.True(t, d.Cached)
+	assert.True(t, d.Loaded)
+	assert.True(t, d.Stale)
+	assert.Equal(t, []FieldDiff{
+		{Path: "name", Cached: "Johnny", Fresh: "John"},
+		{Path: "password", Cached: scrub.Masked, Fresh: scrub.Masked},
+		{Path: "roles[1]", Cached: nil, Fresh: "user"},
+	}, d.Changes)
+
+	d, err = c.Diff(context.TODO(), "accounts", "jane")
+	require.NoError(t, err)
+	assert.False(t, d.Stale)
+	assert.Empty(t, d.Changes)
+
+	d, err = c.Diff(context.TODO(), "accounts", "gone")
+	require.NoError(t, err)
+	assert.True(t, d.Cached)
+	assert.False(t, d.Loaded)
+	assert.True(t, d.Stale)
+	require.Len(t, d.Changes, 1)
+	assert.Empty(t, d.Changes[0].Path)
+	assert.Nil(t, d.Changes[0].Fresh)
+
+	// Loader is not used to read cached value.
+	d, err = c.Diff(context.TODO(), "accounts", "missing")
+	require.NoError(t, err)
+	assert.False(t, d.Cached)
+	assert.False(t, d.Stale)
+	ok, err := i.Exists(context.TODO(), "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = c.Diff(context.TODO(), "unknown", "john")
+	assert.Error(t, err)
+}
+
+func TestDiffHandler(t *testing.T) {
+	c := New(CacheType(MemoryCache), MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "values", Loader[string](func(_ context.Context, key string) (string, error) {
+		return "fresh", nil
+	}))
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "key", "cached"))
+	_, err = Create[string](c, "plain")
+	require.NoError(t, err)
+
+	h := c.DiffHandler()
+	request := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?"+query, nil))
+		return w
+	}
+
+	w := request("instance=values&key=key")
+	require.Equal(t, http.StatusOK, w.Code)
+	var d ValueDiff
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+	assert.True(t, d.Stale)
+	assert.Equal(t, []FieldDiff{{Cached: "cached", Fresh: "fresh"}}, d.Changes)
+
+	assert.Equal(t, http.StatusBadRequest, request("instance=values").Code)
+	assert.Equal(t, http.StatusNotFound, request("instance=unknown&key=key").Code)
+	assert.Equal(t, http.StatusNotImplemented, request("instance=plain&key=key").Code)
+}
+
+func TestDiffValueModes(t *testing.T) {
+	c := New(CacheType(MemoryCache), MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+
+	var calls int
+	i, err := Create[string](c, "values", ModeSwitch{Mode: ModeBypass}, SlidingExpiration{TTL: time.Hour}, Loader[string](func(_ context.Context, key string) (string, error) {
+		calls++
+		return "fresh", nil
+	}))
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "key", "cached", TTL[string](time.Minute)))
+
+	// Cached value is read from backend in bypass mode without sliding its TTL.
+	d, err := c.Diff(context.TODO(), "values", "key")
+	require.NoError(t, err)
+	assert.True(t, d.Cached)
+	assert.Equal(t, []FieldDiff{{Cached: "cached", Fresh: "fresh"}}, d.Changes)
+	assert.Equal(t, 1, calls)
+	ttl, err := GetTTL(context.TODO(), i, "key")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Minute)
+
+	// Instances are not diffed after cache is closed.
+	c.Close()
+	_, err = c.Diff(context.TODO(), "values", "key")
+	assert.Error(t, err)
+}
@@ -0,0 +1,476 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/internal/awsauth"
+
+	"github.com/goccy/go-json"
+)
+
+// DynamoDBOptions are DynamoDB cache connection options.
+type DynamoDBOptions struct {
+	// Region of the table.
+	Region string
+	// Table name to store items in.
+	Table string
+	// Endpoint overrides DynamoDB endpoint, for example to use DynamoDB Local.
+	Endpoint string
+	// AccessKeyID of the AWS credentials.
+	AccessKeyID string
+	// SecretAccessKey of the AWS credentials.
+	SecretAccessKey string
+	// SessionToken of the temporary AWS credentials.
+	SessionToken string
+	// KeyAttribute is a name of the table partition key attribute. Defaults to "key".
+	KeyAttribute string
+	// ValueAttribute is a name of the value attribute. Defaults to "value".
+	ValueAttribute string
+	// TTLAttribute is a name of the attribute configured as table TTL attribute. Defaults to "ttl".
+	TTLAttribute string
+}
+
+// ParseDynamoDBURL parses DynamoDB connection string in the format
+// dynamodb://[access_key_id:secret_access_key@]region/table?endpoint=http://localhost:8000
+//
+// Attribute names can be changed using key_attribute, value_attribute and ttl_attribute
+// query parameters. If credentials are not provided they are loaded from AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+func ParseDynamoDBURL(v string) (*DynamoDBOptions, error) {
+	u, err := url.Parse(v)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "dynamodb" {
+		return nil, fmt.Errorf("dynamodb: invalid URL scheme: %s", u.Scheme)
+	}
+
+	o := &DynamoDBOptions{
+		Region: u.Host,
+		Table:  strings.Trim(u.Path, "/"),
+	}
+	if len(o.Region) == 0 {
+		return nil, errors.New("dynamodb: region not specified")
+	}
+	if len(o.Table) == 0 || strings.Contains(o.Table, "/") {
+		return nil, fmt.Errorf("dynamodb: invalid table name: %q", o.Table)
+	}
+	var creds awsauth.Credentials
+	if u.User != nil {
+		creds.AccessKeyID = u.User.Username()
+		creds.SecretAccessKey, _ = u.User.Password()
+	}
+	creds = creds.Resolve()
+	o.AccessKeyID = creds.AccessKeyID
+	o.SecretAccessKey = creds.SecretAccessKey
+	o.SessionToken = creds.SessionToken
+
+	q := u.Query()
+	if e := q.Get("endpoint"); len(e) != 0 {
+		o.Endpoint = e
+	}
+	if t := q.Get("session_token"); len(t) != 0 {
+		o.SessionToken = t
+	}
+	o.KeyAttribute = q.Get("key_attribute")
+	if len(o.KeyAttribute) == 0 {
+		o.KeyAttribute = "key"
+	}
+	o.ValueAttribute = q.Get("value_attribute")
+	if len(o.ValueAttribute) == 0 {
+		o.ValueAttribute = "value"
+	}
+	o.TTLAttribute = q.Get("ttl_attribute")
+	if len(o.TTLAttribute) == 0 {
+		o.TTLAttribute = "ttl"
+	}
+	return o, nil
+}
+
+// dynamodbAttr is a DynamoDB attribute value.
+type dynamodbAttr struct {
+	S string `json:"S,omitempty"`
+	N string `json:"N,omitempty"`
+	B []byte `json:"B,omitempty"`
+}
+
+type dynamodbItem map[string]dynamodbAttr
+
+type dynamodbError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+func (e *dynamodbError) Error() string {
+	return fmt.Sprintf("dynamodb: %s: %s", e.Type, e.Message)
+}
+
+func (e *dynamodbError) is(typ string) bool {
+	return strings.HasSuffix(e.Type, "#"+typ) || e.Type == typ
+}
+
+type dynamodbCache[T any] struct {
+	conf         *DynamoDBOptions
+	endpoint     string
+	client       *http.Client
+	creds        awsauth.Credentials
+	closed       atomic.Bool
+	prefix       string
+	items        itemDefaults[T]
+	loader       func(ctx context.Context, key string) (T, error)
+	instrumenter instrumenter.Instrumenter
+	audit        *Audit
+	version      int
+	migrations   map[int]MigrationFunc
+	ttlGuard     *TTLGuard
+	now          func() time.Time
+
+	backendState
+}
+
+func newDynamoDBCache[T any](prefix string, opts ...CacheOption) (CacheInstance[T], error) {
+	opt := newCacheOptions(opts...)
+
+	conf, err := ParseDynamoDBURL(opt.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	if len(opt.ConnectionPassword) != 0 {
+		// If password is provided override provided in connection string.
+		conf.SecretAccessKey = opt.ConnectionPassword
+	}
+
+	keyPrefix := opt.KeyPrefix
+	if keyPrefix != "" {
+		keyPrefix += ":"
+	}
+
+	endpoint := conf.Endpoint
+	if len(endpoint) == 0 {
+		endpoint = "https://dynamodb." + conf.Region + ".amazonaws.com/"
+	}
+	client := opt.HTTPClient
+	if client == nil {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+		}
+	}
+
+	return &dynamodbCache[T]{
+		conf:     conf,
+		endpoint: endpoint,
+		client:   client,
+		creds: awsauth.Credentials{
+			AccessKeyID:     conf.AccessKeyID,
+			SecretAccessKey: conf.SecretAccessKey,
+			SessionToken:    conf.SessionToken,
+		},
+		prefix:       keyPrefix + prefix + ":",
+		items:        newItemDefaults[T](opt),
+		loader:       newLoader[T](opt),
+		instrumenter: opt.Instrumenter,
+		audit:        opt.Audit,
+		version:      opt.SchemaVersion,
+		migrations:   opt.Migrations,
+		ttlGuard:     opt.TTLGuard,
+		now:          time.Now,
+	}, nil
+}
+
+// do calls DynamoDB API operation.
+func (c *dynamodbCache[T]) do(ctx context.Context, op string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "DynamoDB_20120810."+op)
+	awsauth.Sign(req, body, c.creds, c.conf.Region, "dynamodb", time.Now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		e := &dynamodbError{}
+		_ = json.NewDecoder(resp.Body).Decode(e)
+		if len(e.Type) == 0 {
+			e.Type = strconv.Itoa(resp.StatusCode)
+		}
+		return e
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid dynamodb response: %w", err)
+	}
+	return nil
+}
+
+func (c *dynamodbCache[T]) itemKey(key string) dynamodbItem {
+	return dynamodbItem{c.conf.KeyAttribute: {S: c.prefix + key}}
+}
+
+// value returns item value or false if item does not exist or has expired.
+//
+// DynamoDB deletes expired items in background with a delay so expiration is
+// also checked on read.
+func (c *dynamodbCache[T]) value(item dynamodbItem) ([]byte, bool) {
+	if len(item) == 0 {
+		return nil, false
+	}
+	if ttl, ok := item[c.conf.TTLAttribute]; ok && len(ttl.N) != 0 {
+		exp, err := strconv.ParseInt(ttl.N, 10, 64)
+		if err == nil && exp > 0 && c.now().Unix() >= exp {
+			return nil, false
+		}
+	}
+	return item[c.conf.ValueAttribute].B, true
+}
+
+// item returns item with value and expiration attributes.
+func (c *dynamodbCache[T]) item(key string, buf []byte, ttl time.Duration) dynamodbItem {
+	item := c.itemKey(key)
+	item[c.conf.ValueAttribute] = dynamodbAttr{B: buf}
+	if ttl > 0 {
+		// TTL attribute has second precision so round up to not expire early.
+		exp := c.now().Add(ttl + time.Second - 1).Unix()
+		item[c.conf.TTLAttribute] = dynamodbAttr{N: strconv.FormatInt(exp, 10)}
+	}
+	return item
+}
+
+func (c *dynamodbCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, true, opts...)
+}
+
+// peek returns value from cache without calling the loader.
+func (c *dynamodbCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, false, opts...)
+}
+
+// read returns value from cache. Missing value is loaded and stored if load is
+// true, otherwise ErrKeyNotFound error is returned.
+func (c *dynamodbCache[T]) read(ctx context.Context, key string, load bool, opts ...ItemOption[T]) (T, error) {
+	val := new(T)
+	if c.closed.Load() {
+		return *val, ErrCacheClosed
+	}
+	opt := c.items.resolve(opts...)
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+
+	var out struct {
+		Item dynamodbItem `json:"Item"`
+	}
+	if err := c.do(ctx, "GetItem", map[string]any{
+		"TableName":      c.conf.Table,
+		"Key":            c.itemKey(key),
+		"ConsistentRead": true,
+	}, &out); err != nil {
+		finish(err)
+		return *val, err
+	}
+	buf, ok := c.value(out.Item)
+	if !ok {
+		if !load {
+			finish(nil)
+			return *val, ErrKeyNotFound{Key: key}
+		}
+		v, err := loadAndStore(ctx, c.loader, c.Set, key, opt, opts...)
+		finish(err)
+		return v, err
+	}
+	if err := decodeValue(c.migrations, c.version, opt, buf, val); err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return *val, err
+	}
+	finish(nil)
+	return *val, nil
+}
+
+// Exists checks if value exists in cache reading only item key and expiration attributes.
+func (c *dynamodbCache[T]) Exists(ctx context.Context, key string) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+
+	var out struct {
+		Item dynamodbItem `json:"Item"`
+	}
+	err := c.do(ctx, "GetItem", map[string]any{
+		"TableName":            c.conf.Table,
+		"Key":                  c.itemKey(key),
+		"ConsistentRead":       true,
+		"ProjectionExpression": "#k, #t",
+		"ExpressionAttributeNames": map[string]string{
+			"#k": c.conf.KeyAttribute,
+			"#t": c.conf.TTLAttribute,
+		},
+	}, &out)
+	finish(err)
+	if err != nil {
+		return false, err
+	}
+	_, ok := c.value(out.Item)
+	return ok, nil
+}
+
+func (c *dynamodbCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	val := new(T)
+	if c.closed.Load() {
+		return *val, ErrCacheClosed
+	}
+
+	finishG := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	finishD := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+key)
+
+	// Delete returns old item atomically so only one of concurrent callers gets it.
+	var out struct {
+		Attributes dynamodbItem `json:"Attributes"`
+	}
+	if err := c.do(ctx, "DeleteItem", map[string]any{
+		"TableName":    c.conf.Table,
+		"Key":          c.itemKey(key),
+		"ReturnValues": "ALL_OLD",
+	}, &out); err != nil {
+		finishD(err)
+		finishG(err)
+		return *val, err
+	}
+	buf, ok := c.value(out.Attributes)
+	if !ok {
+		finishD(nil)
+		finishG(nil)
+		return *val, ErrKeyNotFound{Key: key}
+	}
+	if err := decodeValue(c.migrations, c.version, c.items.resolve(), buf, val); err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finishD(err)
+		finishG(err)
+		return *val, err
+	}
+	finishD(nil)
+	finishG(nil)
+	return *val, nil
+}
+
+func (c *dynamodbCache[T]) put(ctx context.Context, key string, value T, nx bool, opts ...ItemOption[T]) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	opt := c.items.resolve(opts...)
+	ttl, err := c.ttlGuard.expiration(ctx, key, opt.TTL)
+	if err != nil {
+		finish(err)
+		return false, err
+	}
+	buf, err := encodeValue(ctx, c.audit, c.version, opt, value)
+	if err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return false, err
+	}
+
+	in := map[string]any{
+		"TableName": c.conf.Table,
+		"Item":      c.item(key, buf, ttl),
+	}
+	if nx {
+		// Expired items that are not yet deleted by DynamoDB are treated as absent.
+		in["ConditionExpression"] = "attribute_not_exists(#k) OR (attribute_exists(#t) AND #t <= :now)"
+		in["ExpressionAttributeNames"] = map[string]string{
+			"#k": c.conf.KeyAttribute,
+			"#t": c.conf.TTLAttribute,
+		}
+		in["ExpressionAttributeValues"] = dynamodbItem{
+			":now": {N: strconv.FormatInt(c.now().Unix(), 10)},
+		}
+	}
+	if err := c.do(ctx, "PutItem", in, nil); err != nil {
+		var derr *dynamodbError
+		if nx && errors.As(err, &derr) && derr.is("ConditionalCheckFailedException") {
+			finish(nil)
+			return false, nil
+		}
+		finish(err)
+		return false, err
+	}
+	finish(nil)
+	return true, nil
+}
+
+func (c *dynamodbCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	_, err := c.put(ctx, key, value, false, opts...)
+	return err
+}
+
+// SetNX stores value only if the key does not exist using conditional write.
+//
+// Returns true if value was stored.
+func (c *dynamodbCache[T]) SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error) {
+	return c.put(ctx, key, value, true, opts...)
+}
+
+func (c *dynamodbCache[T]) Delete(ctx context.Context, key string) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+key)
+
+	if err := c.do(ctx, "DeleteItem", map[string]any{
+		"TableName": c.conf.Table,
+		"Key":       c.itemKey(key),
+	}, nil); err != nil {
+		finish(err)
+		return err
+	}
+	finish(nil)
+	return nil
+}
+
+func (c *dynamodbCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	return c.items.resolve(opts...)
+}
+
+func (c *dynamodbCache[T]) Ping(ctx context.Context) error {
+	if c.closed.Load() {
+		return nil
+	}
+	return c.do(ctx, "DescribeTable", map[string]any{
+		"TableName": c.conf.Table,
+	}, nil)
+}
+
+func (c *dynamodbCache[T]) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	c.client.CloseIdleConnections()
+	return nil
+}
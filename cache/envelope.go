@@ -0,0 +1,66 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// envelopeMagic prefixes values stored in envelope format. It can not be
+// a start of valid JSON or MessagePack encoded value used as legacy format.
+var envelopeMagic = []byte{0xc1, 'A', 'Z', 'E'}
+
+// EnvelopeHeader is a metadata stored together with value in remote cache backends.
+type EnvelopeHeader struct {
+	// Version is a schema version of the value.
+	Version int `json:"version,omitempty"`
+	// Component is a name of the component that has written the value.
+	Component string `json:"component,omitempty"`
+	// Caller is a code location that has written the value.
+	Caller string `json:"caller,omitempty"`
+	// WrittenAt is a time when value was written.
+	WrittenAt time.Time `json:"written_at,omitempty"`
+	// TTLOverride is a TTL requested by the writer overriding the cache instance
+	// default TTL. Zero means that default TTL was used.
+	TTLOverride time.Duration `json:"ttl_override,omitempty"`
+}
+
+// encodeEnvelope returns payload wrapped in envelope with header.
+func encodeEnvelope(h *EnvelopeHeader, payload []byte) ([]byte, error) {
+	header, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, len(envelopeMagic)+4+len(header)+len(payload))
+	buf = append(buf, envelopeMagic...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(header)))
+	buf = append(buf, header...)
+	buf = append(buf, payload...)
+	return buf, nil
+}
+
+// DecodeEnvelope returns envelope header and payload from the raw value stored in
+// remote cache backend.
+//
+// If value is not stored in envelope format, ok is false and data is returned as payload.
+func DecodeEnvelope(data []byte) (header EnvelopeHeader, payload []byte, ok bool) {
+	l := len(envelopeMagic)
+	if len(data) < l+4 || !bytes.Equal(data[:l], envelopeMagic) {
+		return header, data, false
+	}
+	size := int(binary.BigEndian.Uint32(data[l : l+4]))
+	if len(data) < l+4+size {
+		return header, data, false
+	}
+	if err := json.Unmarshal(data[l+4:l+4+size], &header); err != nil {
+		return header, data, false
+	}
+	return header, data[l+4+size:], true
+}
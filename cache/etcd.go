@@ -0,0 +1,361 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// EtcdOptions are etcd cache connection options.
+type EtcdOptions struct {
+	// Endpoints are etcd server addresses.
+	Endpoints []string
+	// Username to authenticate with.
+	Username string
+	// Password to authenticate with.
+	Password string
+	// DialTimeout is a timeout for establishing connection.
+	DialTimeout time.Duration
+	// TLS enables TLS connection to the servers.
+	TLS bool
+	// InsecureSkipVerify disables server certificate verification.
+	InsecureSkipVerify bool
+}
+
+// ParseEtcdURL parses etcd connection string in the format
+// etcd://[user:password@]host1:2379,host2:2379?dial_timeout=5s
+//
+// Use etcds scheme to connect using TLS. Server certificate verification can be
+// disabled with skip_verify query parameter. Port defaults to 2379.
+func ParseEtcdURL(v string) (*EtcdOptions, error) {
+	scheme, rest, ok := strings.Cut(v, "://")
+	if !ok {
+		return nil, fmt.Errorf("etcd: invalid URL: %s", v)
+	}
+	if scheme != "etcd" && scheme != "etcds" {
+		return nil, fmt.Errorf("etcd: invalid URL scheme: %s", scheme)
+	}
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	authority, tail := rest[:end], rest[end:]
+	var userinfo string
+	if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+		userinfo, authority = authority[:i+1], authority[i+1:]
+	}
+
+	u, err := url.Parse(scheme + "://" + userinfo + "etcd" + tail)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &EtcdOptions{
+		DialTimeout: 5 * time.Second,
+		TLS:         scheme == "etcds",
+	}
+	for _, h := range strings.Split(authority, ",") {
+		h = strings.TrimSpace(h)
+		if len(h) == 0 {
+			continue
+		}
+		if !strings.Contains(h, ":") {
+			h += ":2379"
+		}
+		o.Endpoints = append(o.Endpoints, h)
+	}
+	if len(o.Endpoints) == 0 {
+		return nil, errors.New("etcd: no endpoints specified")
+	}
+	if u.User != nil {
+		o.Username = u.User.Username()
+		o.Password, _ = u.User.Password()
+	}
+
+	q := u.Query()
+	if t := q.Get("dial_timeout"); len(t) != 0 {
+		if o.DialTimeout, err = time.ParseDuration(t); err != nil {
+			return nil, fmt.Errorf("etcd: invalid dial_timeout: %w", err)
+		}
+	}
+	if q.Has("skip_verify") {
+		o.InsecureSkipVerify = q.Get("skip_verify") != "false"
+	}
+	return o, nil
+}
+
+// etcdClient is a subset of etcd client used by the cache.
+type etcdClient interface {
+	clientv3.KV
+	clientv3.Lease
+}
+
+type etcdCache[T any] struct {
+	client       etcdClient
+	close        func() error
+	ping         func(ctx context.Context) error
+	closed       atomic.Bool
+	prefix       string
+	items        itemDefaults[T]
+	loader       func(ctx context.Context, key string) (T, error)
+	instrumenter instrumenter.Instrumenter
+	audit        *Audit
+	version      int
+	migrations   map[int]MigrationFunc
+	ttlGuard     *TTLGuard
+
+	backendState
+}
+
+func newEtcdCache[T any](name string, opts ...CacheOption) (CacheInstance[T], error) {
+	opt := newCacheOptions(opts...)
+
+	conf, err := ParseEtcdURL(opt.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	if len(opt.ConnectionPassword) != 0 {
+		// If password is provided override provided in connection string.
+		conf.Password = opt.ConnectionPassword
+	}
+
+	cfg := clientv3.Config{
+		Endpoints:   conf.Endpoints,
+		Username:    conf.Username,
+		Password:    conf.Password,
+		DialTimeout: conf.DialTimeout,
+		Logger:      zap.NewNop(),
+	}
+	if conf.TLS {
+		cfg.TLS = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			//nolint:gosec
+			InsecureSkipVerify: conf.InsecureSkipVerify,
+		}
+	}
+	client, err := clientv3.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newEtcdClientCache[T](client, name, opt)
+	c.close = client.Close
+	c.ping = func(ctx context.Context) error {
+		_, err := client.Status(ctx, conf.Endpoints[0])
+		return err
+	}
+	return c, nil
+}
+
+func newEtcdClientCache[T any](client etcdClient, name string, opt *cacheOptions) *etcdCache[T] {
+	keyPrefix := opt.KeyPrefix
+	if keyPrefix != "" {
+		keyPrefix += "/"
+	}
+
+	return &etcdCache[T]{
+		client:       client,
+		close:        func() error { return nil },
+		ping:         func(context.Context) error { return nil },
+		prefix:       keyPrefix + name + "/",
+		items:        newItemDefaults[T](opt),
+		loader:       newLoader[T](opt),
+		instrumenter: opt.Instrumenter,
+		audit:        opt.Audit,
+		version:      opt.SchemaVersion,
+		migrations:   opt.Migrations,
+		ttlGuard:     opt.TTLGuard,
+	}
+}
+
+// etcdLeaseTTL returns lease TTL in seconds for the item TTL.
+func etcdLeaseTTL(ttl time.Duration) int64 {
+	// Lease has second precision so round up to not expire early.
+	return int64((ttl + time.Second - 1) / time.Second)
+}
+
+// revoke releases lease of the removed or replaced value.
+func (c *etcdCache[T]) revoke(ctx context.Context, kv []*mvccpb.KeyValue) {
+	for _, v := range kv {
+		if v.Lease != 0 {
+			_, _ = c.client.Revoke(ctx, clientv3.LeaseID(v.Lease))
+		}
+	}
+}
+
+func (c *etcdCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, true, opts...)
+}
+
+// peek returns value from cache without calling the loader.
+func (c *etcdCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, false, opts...)
+}
+
+// read returns value from cache. Missing value is loaded and stored if load is
+// true, otherwise ErrKeyNotFound error is returned.
+func (c *etcdCache[T]) read(ctx context.Context, key string, load bool, opts ...ItemOption[T]) (T, error) {
+	val := new(T)
+	if c.closed.Load() {
+		return *val, ErrCacheClosed
+	}
+	opt := c.items.resolve(opts...)
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	resp, err := c.client.Get(ctx, c.prefix+key)
+	if err != nil {
+		finish(err)
+		return *val, err
+	}
+	if len(resp.Kvs) == 0 {
+		if !load {
+			finish(nil)
+			return *val, ErrKeyNotFound{Key: key}
+		}
+		v, err := loadAndStore(ctx, c.loader, c.Set, key, opt, opts...)
+		finish(err)
+		return v, err
+	}
+	if err := decodeValue(c.migrations, c.version, opt, resp.Kvs[0].Value, val); err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return *val, err
+	}
+	finish(nil)
+	return *val, nil
+}
+
+func (c *etcdCache[T]) Exists(ctx context.Context, key string) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	resp, err := c.client.Get(ctx, c.prefix+key, clientv3.WithCountOnly())
+	finish(err)
+	if err != nil {
+		return false, err
+	}
+	return resp.Count > 0, nil
+}
+
+func (c *etcdCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	val := new(T)
+	if c.closed.Load() {
+		return *val, ErrCacheClosed
+	}
+
+	finishG := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	finishD := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+key)
+
+	// Delete returns previous value atomically so only one of concurrent callers gets it.
+	resp, err := c.client.Delete(ctx, c.prefix+key, clientv3.WithPrevKV())
+	if err != nil {
+		finishD(err)
+		finishG(err)
+		return *val, err
+	}
+	if len(resp.PrevKvs) == 0 {
+		finishD(nil)
+		finishG(nil)
+		return *val, ErrKeyNotFound{Key: key}
+	}
+	c.revoke(ctx, resp.PrevKvs)
+	if err := decodeValue(c.migrations, c.version, c.items.resolve(), resp.PrevKvs[0].Value, val); err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finishD(err)
+		finishG(err)
+		return *val, err
+	}
+	finishD(nil)
+	finishG(nil)
+	return *val, nil
+}
+
+func (c *etcdCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	opt := c.items.resolve(opts...)
+	ttl, err := c.ttlGuard.expiration(ctx, key, opt.TTL)
+	if err != nil {
+		finish(err)
+		return err
+	}
+	buf, err := encodeValue(ctx, c.audit, c.version, opt, value)
+	if err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return err
+	}
+
+	popts := []clientv3.OpOption{clientv3.WithPrevKV()}
+	if ttl > 0 {
+		lease, err := c.client.Grant(ctx, etcdLeaseTTL(ttl))
+		if err != nil {
+			finish(err)
+			return err
+		}
+		popts = append(popts, clientv3.WithLease(lease.ID))
+	}
+	resp, err := c.client.Put(ctx, c.prefix+key, string(buf), popts...)
+	if err != nil {
+		finish(err)
+		return err
+	}
+	if resp.PrevKv != nil {
+		c.revoke(ctx, []*mvccpb.KeyValue{resp.PrevKv})
+	}
+	finish(nil)
+	return nil
+}
+
+func (c *etcdCache[T]) Delete(ctx context.Context, key string) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+key)
+
+	resp, err := c.client.Delete(ctx, c.prefix+key, clientv3.WithPrevKV())
+	if err != nil {
+		finish(err)
+		return err
+	}
+	c.revoke(ctx, resp.PrevKvs)
+	finish(nil)
+	return nil
+}
+
+func (c *etcdCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	return c.items.resolve(opts...)
+}
+
+func (c *etcdCache[T]) Ping(ctx context.Context) error {
+	if c.closed.Load() {
+		return nil
+	}
+	return c.ping(ctx)
+}
+
+func (c *etcdCache[T]) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	return c.close()
+}
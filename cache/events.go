@@ -0,0 +1,263 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/queue"
+	"azugo.io/core/scrub"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	InstrumentationCacheEvent = "cache-event"
+)
+
+// ErrEventQueueFull is reported when event is dropped because event queue is full.
+var ErrEventQueueFull = errors.New("cache event queue is full")
+
+// EventType is a type of the cache event.
+type EventType string
+
+const (
+	// EventSet is emitted when value is set.
+	EventSet EventType = "set"
+	// EventDelete is emitted when value is deleted.
+	EventDelete EventType = "delete"
+)
+
+// Event is a cache write event.
+type Event struct {
+	// Type of the event.
+	Type EventType `json:"type"`
+	// Instance is a name of the cache instance.
+	Instance string `json:"instance"`
+	// Key of the item.
+	Key string `json:"key"`
+	// Value is a JSON encoded item value if values are included in events.
+	Value json.RawMessage `json:"value,omitempty"`
+	// TTL of the item.
+	TTL time.Duration `json:"ttl,omitempty"`
+	// Component is a calling component name if available.
+	Component string `json:"component,omitempty"`
+	// Time when event has happened.
+	Time time.Time `json:"time"`
+}
+
+// DecodeEvent returns cache event from the queue message.
+func DecodeEvent(msg *queue.Message) (*Event, error) {
+	e := &Event{}
+	if err := json.Unmarshal(msg.Body, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// EventValue returns decoded value of the cache event.
+func EventValue[T any](e *Event) (T, error) {
+	var val T
+	if len(e.Value) == 0 {
+		return val, errors.New("event does not contain value")
+	}
+	err := json.Unmarshal(e.Value, &val)
+	return val, err
+}
+
+// Events publishes every Set and Delete of the cache instance as an Event message to the queue.
+//
+// Events are published asynchronously and dropped if event queue is full.
+type Events struct {
+	// Publisher to publish events to.
+	Publisher queue.Publisher
+	// Topic to publish events to. Defaults to "cache-events".
+	Topic string
+	// SampleRate is a fraction of events to publish. Zero publishes all events.
+	SampleRate float64
+	// IncludeValue includes JSON encoded value in set events.
+	IncludeValue bool
+	// QueueSize is a maximum number of events waiting to be published. Defaults to 1000.
+	QueueSize int
+}
+
+func (e Events) applyCache(c *cacheOptions) {
+	c.Events = &e
+}
+
+type eventCache[T any] struct {
+	CacheInstance[T]
+	name         string
+	conf         Events
+	instrumenter instrumenter.Instrumenter
+	scrubber     *scrub.Scrubber
+
+	lock   sync.RWMutex
+	closed bool
+	queue  chan *Event
+	done   chan struct{}
+}
+
+func newEventCache[T any](c CacheInstance[T], name string, opts ...CacheOption) (CacheInstance[T], error) {
+	opt := newCacheOptions(opts...)
+	conf := *opt.Events
+	if conf.Publisher == nil {
+		return nil, errors.New("cache events publisher is not set")
+	}
+	if len(conf.Topic) == 0 {
+		conf.Topic = "cache-events"
+	}
+	size := conf.QueueSize
+	if size <= 0 {
+		size = 1000
+	}
+
+	e := &eventCache[T]{
+		CacheInstance: c,
+		name:          name,
+		conf:          conf,
+		instrumenter:  opt.Instrumenter,
+		scrubber:      opt.Scrubber,
+		queue:         make(chan *Event, size),
+		done:          make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+func (c *eventCache[T]) emit(ctx context.Context, typ EventType, key string, value *T, opts ...ItemOption[T]) {
+	if c.conf.SampleRate > 0 && c.conf.SampleRate < 1 && rand.Float64() >= c.conf.SampleRate { //nolint:gosec
+		return
+	}
+
+	e := &Event{
+		Type:     typ,
+		Instance: c.name,
+		Key:      key,
+		Time:     time.Now().UTC(),
+	}
+	if comp := ComponentFromContext(ctx); len(comp) != 0 {
+		e.Component, _ = c.scrubber.String("component", comp)
+	}
+	if value != nil {
+		e.TTL = ResolveItemOptions[T](c.CacheInstance, opts...).TTL
+		if c.conf.IncludeValue {
+			buf, err := json.Marshal(value)
+			if err == nil {
+				buf, err = c.scrubber.JSON(buf)
+			}
+			if err != nil {
+				c.instrumenter.Observe(ctx, InstrumentationCacheEvent, key)(err)
+				return
+			}
+			e.Value = buf
+		}
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.closed {
+		return
+	}
+	select {
+	case c.queue <- e:
+	default:
+		c.instrumenter.Observe(ctx, InstrumentationCacheEvent, key)(ErrEventQueueFull)
+	}
+}
+
+func (c *eventCache[T]) run() {
+	defer close(c.done)
+
+	ctx := context.Background()
+	for e := range c.queue {
+		finish := c.instrumenter.Observe(ctx, InstrumentationCacheEvent, e.Key)
+		body, err := json.Marshal(e)
+		if err == nil {
+			err = c.conf.Publisher.Publish(ctx, &queue.Message{
+				Topic: c.conf.Topic,
+				Key:   c.name + ":" + e.Key,
+				Headers: map[string]string{
+					"type":     string(e.Type),
+					"instance": c.name,
+				},
+				Body: body,
+			})
+		}
+		finish(err)
+	}
+}
+
+func (c *eventCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	v, err := c.CacheInstance.Pop(ctx, key)
+	if err == nil {
+		c.emit(ctx, EventDelete, key, nil)
+	}
+	return v, err
+}
+
+func (c *eventCache[T]) PopWithMetadata(ctx context.Context, key string) (T, ItemMetadata, error) {
+	v, meta, err := PopWithMetadata(ctx, c.CacheInstance, key)
+	if err == nil {
+		c.emit(ctx, EventDelete, key, nil)
+	}
+	return v, meta, err
+}
+
+func (c *eventCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	if err := c.CacheInstance.Set(ctx, key, value, opts...); err != nil {
+		return err
+	}
+	c.emit(ctx, EventSet, key, &value, opts...)
+	return nil
+}
+
+func (c *eventCache[T]) Delete(ctx context.Context, key string) error {
+	if err := c.CacheInstance.Delete(ctx, key); err != nil {
+		return err
+	}
+	c.emit(ctx, EventDelete, key, nil)
+	return nil
+}
+
+// Close stops publishing after all queued events are published.
+func (c *eventCache[T]) Close() {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.queue)
+	c.lock.Unlock()
+
+	<-c.done
+
+	closeInstance(c.CacheInstance)
+}
+
+func (c *eventCache[T]) Ping(ctx context.Context) error {
+	if p, ok := c.CacheInstance.(CacheInstancePinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *eventCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	if r, ok := c.CacheInstance.(itemOptionsResolver[T]); ok {
+		return r.itemOptions(opts...)
+	}
+	return newItemOptions(opts...)
+}
+
+func (c *eventCache[T]) unwrap() CacheInstance[T] {
+	return c.CacheInstance
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"azugo.io/core/queue"
+	"azugo.io/core/scrub"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPublisher struct {
+	lock     sync.Mutex
+	messages []*queue.Message
+}
+
+func (p *testPublisher) Publish(_ context.Context, msg *queue.Message) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+func TestCacheEvents(t *testing.T) {
+	c := New(MemoryCache)
+	require.NoError(t, c.Start(context.TODO()))
+
+	pub := &testPublisher{}
+	i, err := Create[string](c, "test", Events{Publisher: pub, IncludeValue: true}, DefaultTTL(time.Minute))
+	require.NoError(t, err)
+
+	ctx := WithComponent(context.TODO(), "billing")
+	require.NoError(t, i.Set(ctx, "key", "value"))
+	require.NoError(t, i.Delete(ctx, "key"))
+
+	c.Close()
+
+	require.Len(t, pub.messages, 2)
+	assert.Equal(t, "cache-events", pub.messages[0].Topic)
+	assert.Equal(t, "test:key", pub.messages[0].Key)
+
+	e, err := DecodeEvent(pub.messages[0])
+	require.NoError(t, err)
+	assert.Equal(t, EventSet, e.Type)
+	assert.Equal(t, "test", e.Instance)
+	assert.Equal(t, "key", e.Key)
+	assert.Equal(t, "billing", e.Component)
+	assert.Equal(t, time.Minute, e.TTL)
+	v, err := EventValue[string](e)
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	e, err = DecodeEvent(pub.messages[1])
+	require.NoError(t, err)
+	assert.Equal(t, EventDelete, e.Type)
+	assert.Empty(t, e.Value)
+}
+
+func TestCacheEventsScrub(t *testing.T) {
+	type user struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	s := scrub.New(scrub.Rules{
+		{Field: "email", Action: scrub.Mask},
+		{Field: "password", Action: scrub.Drop},
+		{Field: "component", Action: scrub.Hash},
+	})
+	c := New(MemoryCache, MemorySyncWrites(true), Scrub{s})
+	require.NoError(t, c.Start(context.TODO()))
+
+	pub := &testPublisher{}
+	i, err := Create[user](c, "users", Events{Publisher: pub, IncludeValue: true})
+	require.NoError(t, err)
+
+	u := user{Name: "John", Email: "john@example.com", Password: "secret"}
+	require.NoError(t, i.Set(WithComponent(context.TODO(), "billing"), "1", u))
+
+	v, err := i.Get(context.TODO(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, u, v)
+
+	c.Close()
+
+	require.Len(t, pub.messages, 1)
+	e, err := DecodeEvent(pub.messages[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"John","email":"***"}`, string(e.Value))
+	assert.NotEqual(t, "billing", e.Component)
+	assert.Len(t, e.Component, 32)
+}
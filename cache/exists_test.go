@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// existsLoader returns cache option with the loader counting its calls.
+func existsLoader(calls *atomic.Int32) Loader[string] {
+	return func(_ context.Context, _ string) (string, error) {
+		calls.Add(1)
+		return "loaded", nil
+	}
+}
+
+func testExists(t *testing.T, i CacheInstance[string], calls *atomic.Int32) {
+	t.Helper()
+
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+
+	ok, err := i.Exists(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = i.Exists(context.TODO(), "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, i.Delete(context.TODO(), "key"))
+	ok, err = i.Exists(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, int32(0), calls.Load(), "loader must not be called")
+}
+
+func TestRedisCacheExists(t *testing.T) {
+	var calls atomic.Int32
+	c, s := newMiniRedisCache(t, existsLoader(&calls))
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testExists(t, i, &calls)
+
+	require.NoError(t, i.Set(context.TODO(), "ttl", "value", TTL[string](time.Second)))
+	s.FastForward(2 * time.Second)
+	ok, err := i.Exists(context.TODO(), "ttl")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestMemoryCacheExists(t *testing.T) {
+	var calls atomic.Int32
+	c := New(MemoryCache, MemorySyncWrites(true), existsLoader(&calls))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testExists(t, i, &calls)
+}
+
+func TestLRUCacheExists(t *testing.T) {
+	var calls atomic.Int32
+	i, err := newLRUCache[string](MemoryLimit{MaxItems: 2}, existsLoader(&calls))
+	require.NoError(t, err)
+	defer closeInstance(i)
+	testExists(t, i, &calls)
+
+	// Exists does not change eviction order.
+	c := i.(*lruCache[string])
+	require.NoError(t, c.Set(context.TODO(), "a", "1"))
+	require.NoError(t, c.Set(context.TODO(), "b", "2"))
+	ok, err := c.Exists(context.TODO(), "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, c.Set(context.TODO(), "c", "3"))
+	ok, err = c.Exists(context.TODO(), "a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestBoltCacheExists(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestBoltCache[string](t, filepath.Join(t.TempDir(), "cache.db"), existsLoader(&calls))
+	testExists(t, c, &calls)
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	require.NoError(t, c.Set(context.TODO(), "ttl", "value", TTL[string](time.Minute)))
+	now = now.Add(2 * time.Minute)
+	ok, err := c.Exists(context.TODO(), "ttl")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestMemcachedCacheExists(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newTestMemcachedCache(t, existsLoader(&calls))
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testExists(t, i, &calls)
+}
+
+func TestPostgresCacheExists(t *testing.T) {
+	var calls atomic.Int32
+	c, db := newTestPostgresCache[string](t, existsLoader(&calls))
+	testExists(t, c, &calls)
+
+	require.NoError(t, c.Set(context.TODO(), "ttl", "value", TTL[string](time.Minute)))
+	db.now = db.now.Add(2 * time.Minute)
+	ok, err := c.Exists(context.TODO(), "ttl")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestDynamoDBCacheExists(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newTestDynamoDBCache[string](t, "cache", existsLoader(&calls))
+	testExists(t, c, &calls)
+}
+
+func TestEtcdCacheExists(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newTestEtcdCache[string](t, existsLoader(&calls))
+	testExists(t, c, &calls)
+}
+
+func TestNATSCacheExists(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newTestNATSCache[string](t, existsLoader(&calls))
+	testExists(t, c, &calls)
+}
+
+func TestTieredCacheExists(t *testing.T) {
+	i1, i2, _ := newTieredTestCaches(t, Tiered{TTL: time.Hour})
+
+	require.NoError(t, i1.Set(context.TODO(), "key", "value"))
+	ok, err := i2.Exists(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, i1.Delete(context.TODO(), "key"))
+	assert.Eventually(t, func() bool {
+		ok, err := i2.Exists(context.TODO(), "key")
+		return err == nil && !ok
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,670 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// CacheInstanceTTL represents cache instance that can inspect and extend
+// lifetime of the stored values without rewriting them.
+type CacheInstanceTTL[T any] interface {
+	// TTL returns remaining lifetime of the value. Zero means that value does not expire.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Touch sets new lifetime of the value.
+	Touch(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// GetTTL returns remaining lifetime of the value in the cache instance. Zero
+// means that value is stored without expiration. If value is not found, it
+// returns ErrKeyNotFound error.
+//
+// Cache instances that can not inspect value lifetime return ErrNotSupported.
+func GetTTL[T any](ctx context.Context, instance CacheInstance[T], key string) (time.Duration, error) {
+	if s, ok := instance.(CacheInstanceTTL[T]); ok {
+		return s.TTL(ctx, key)
+	}
+	return 0, ErrNotSupported
+}
+
+// Touch sets new lifetime of the value in the cache instance without rewriting
+// the value. TTL is subject to the TTLGuard limits same as when value is stored
+// and NoExpiration removes expiration of the value. If value is not found, it
+// returns ErrKeyNotFound error.
+//
+// It can be used to cheaply refresh expiration of session-like values. Cache
+// instances that can not change value lifetime return ErrNotSupported.
+func Touch[T any](ctx context.Context, instance CacheInstance[T], key string, ttl time.Duration) error {
+	if s, ok := instance.(CacheInstanceTTL[T]); ok {
+		return s.Touch(ctx, key, ttl)
+	}
+	return ErrNotSupported
+}
+
+// redisTouchScript sets expiration of the value and extends expiration of the
+// deduplicated payload it references.
+//
+// KEYS[1] - key.
+// ARGV[1] - TTL in milliseconds, ARGV[2] - reference prefix or empty if
+// deduplication is disabled, ARGV[3] - payload key prefix.
+var redisTouchScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if not v then
+	return 0
+end
+local ttl = tonumber(ARGV[1])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+else
+	redis.call('PERSIST', KEYS[1])
+end
+if ARGV[2] ~= '' and string.sub(v, 1, 4) == ARGV[2] then
+	local blob = ARGV[3] .. string.sub(v, 5)
+	local bttl = redis.call('PTTL', blob)
+	if ttl <= 0 then
+		redis.call('PERSIST', blob)
+	elseif bttl >= 0 and bttl < ttl then
+		redis.call('PEXPIRE', blob, ttl)
+	end
+end
+return 1
+`)
+
+// TTL returns remaining lifetime of the value using PTTL command.
+func (c *redisCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if c.closed.Load() {
+		return 0, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	ttl, err := c.con.PTTL(ctx, c.prefix+key).Result()
+	if err != nil {
+		finish(err)
+		return 0, err
+	}
+	finish(nil)
+	switch ttl {
+	case -2:
+		return 0, ErrKeyNotFound{Key: key}
+	case -1:
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// Touch sets new lifetime of the value and deduplicated payload it references.
+func (c *redisCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	ttl, err := c.ttlGuard.expiration(ctx, key, ttl)
+	if err != nil {
+		finish(err)
+		return err
+	}
+	var magic string
+	if c.dedup != nil {
+		magic = refMagic
+	}
+	ok, err := redisTouchScript.Run(ctx, c.con, []string{c.prefix + key}, ttl.Milliseconds(), magic, c.prefix+blobKeyPrefix).Bool()
+	finish(err)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrKeyNotFound{Key: key}
+	}
+	return nil
+}
+
+func (c *memoryCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if c.cache == nil {
+		return 0, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, key)
+	ttl, found := c.cache.GetTTL(key)
+	finish(nil)
+	if !found {
+		return 0, ErrKeyNotFound{Key: key}
+	}
+	return ttl, nil
+}
+
+// Touch stores the same entry again with new lifetime.
+//
+// Only concurrent Touch and SetNX calls are mutually exclusive, concurrent Set
+// can still be overwritten with the previous value.
+func (c *memoryCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if c.cache == nil {
+		return ErrCacheClosed
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, key)
+
+	ttl, err := c.ttlGuard.expiration(ctx, key, ttl)
+	if err != nil {
+		finish(err)
+		return err
+	}
+	v, found := c.cache.Get(key)
+	if !found {
+		finish(nil)
+		return ErrKeyNotFound{Key: key}
+	}
+	e, ok := v.(memoryEntry[T])
+	if !ok {
+		err = fmt.Errorf("invalid cache value: %v", v)
+		finish(err)
+		return err
+	}
+	// Existing item is updated in place so there is no need to wait for the
+	// set buffer to be applied.
+	err = c.store(key, e, ttl)
+	finish(err)
+	return err
+}
+
+// TTL returns remaining lifetime of the value without changing its recency.
+func (c *lruCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, key)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		finish(ErrCacheClosed)
+		return 0, ErrCacheClosed
+	}
+	now := c.now()
+	el, ok := c.items[key]
+	if !ok {
+		finish(nil)
+		return 0, ErrKeyNotFound{Key: key}
+	}
+	e := el.Value.(*lruEntry[T])
+	if e.expired(now) {
+		c.remove(el)
+		finish(nil)
+		return 0, ErrKeyNotFound{Key: key}
+	}
+	finish(nil)
+	if e.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return e.expiresAt.Sub(now), nil
+}
+
+// Touch sets new lifetime of the value without changing its recency.
+func (c *lruCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, key)
+
+	ttl, err := c.ttlGuard.expiration(ctx, key, ttl)
+	if err != nil {
+		finish(err)
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		finish(ErrCacheClosed)
+		return ErrCacheClosed
+	}
+	now := c.now()
+	el, ok := c.items[key]
+	if !ok {
+		finish(nil)
+		return ErrKeyNotFound{Key: key}
+	}
+	e := el.Value.(*lruEntry[T])
+	if e.expired(now) {
+		c.remove(el)
+		finish(nil)
+		return ErrKeyNotFound{Key: key}
+	}
+	e.expiresAt = time.Time{}
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	}
+	finish(nil)
+	return nil
+}
+
+func (c *boltCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if c.closed.Load() {
+		return 0, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	var exp int64
+	found := false
+	now := c.now()
+	err := c.file.view(func(tx *bolt.Tx) error {
+		v := tx.Bucket(c.bucket).Get([]byte(key))
+		if v == nil || boltExpired(v, now) {
+			return nil
+		}
+		found = true
+		exp = int64(binary.BigEndian.Uint64(v))
+		return nil
+	})
+	finish(err)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, ErrKeyNotFound{Key: key}
+	}
+	if exp == 0 {
+		return 0, nil
+	}
+	return time.Unix(0, exp).Sub(now), nil
+}
+
+// Touch rewrites expiration time stored in front of the value.
+func (c *boltCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	ttl, err := c.ttlGuard.expiration(ctx, key, ttl)
+	if err != nil {
+		finish(err)
+		return err
+	}
+
+	now := c.now()
+	found := false
+	err = c.file.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(c.bucket)
+		old := b.Get([]byte(key))
+		if old == nil || boltExpired(old, now) {
+			return nil
+		}
+		found = true
+		// Value returned by bolt is only valid during transaction and can not be modified.
+		v := make([]byte, len(old))
+		copy(v, old)
+		var exp uint64
+		if ttl > 0 {
+			exp = uint64(now.Add(ttl).UnixNano())
+		}
+		binary.BigEndian.PutUint64(v, exp)
+		return b.Put([]byte(key), v)
+	})
+	finish(err)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrKeyNotFound{Key: key}
+	}
+	return nil
+}
+
+// TTL is not supported as memcached does not expose expiration of the values.
+func (c *memcachedCache[T]) TTL(_ context.Context, _ string) (time.Duration, error) {
+	return 0, ErrNotSupported
+}
+
+// Touch sets new lifetime of the value using touch command.
+func (c *memcachedCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	ttl, err := c.ttlGuard.expiration(ctx, key, ttl)
+	if err != nil {
+		finish(err)
+		return err
+	}
+	err = c.con.Touch(c.key(key), memcachedExpiration(ttl))
+	if err == memcache.ErrCacheMiss {
+		finish(nil)
+		return ErrKeyNotFound{Key: key}
+	}
+	finish(err)
+	return err
+}
+
+func (c *postgresCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if c.closed.Load() {
+		return 0, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+
+	var ms int64
+	err := c.db.QueryRow(ctx, `SELECT COALESCE(CEIL(EXTRACT(EPOCH FROM expires_at - now()) * 1000)::bigint, 0) FROM `+c.table+
+		` WHERE key = $1 AND (expires_at IS NULL OR expires_at > now())`,
+		c.prefix+key).Scan(&ms)
+	if errors.Is(err, pgx.ErrNoRows) {
+		finish(nil)
+		return 0, ErrKeyNotFound{Key: key}
+	}
+	finish(err)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func (c *postgresCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	ttl, err := c.ttlGuard.expiration(ctx, key, ttl)
+	if err != nil {
+		finish(err)
+		return err
+	}
+	tag, err := c.db.Exec(ctx, `UPDATE `+c.table+` SET expires_at = now() + $2::bigint * interval '1 millisecond'
+WHERE key = $1 AND (expires_at IS NULL OR expires_at > now())`, c.prefix+key, postgresTTL(ttl))
+	finish(err)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrKeyNotFound{Key: key}
+	}
+	return nil
+}
+
+// TTL returns remaining lifetime of the value reading only item key and
+// expiration attributes.
+//
+// Expiration attribute has second precision.
+func (c *dynamodbCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if c.closed.Load() {
+		return 0, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+
+	var out struct {
+		Item dynamodbItem `json:"Item"`
+	}
+	err := c.do(ctx, "GetItem", map[string]any{
+		"TableName":            c.conf.Table,
+		"Key":                  c.itemKey(key),
+		"ConsistentRead":       true,
+		"ProjectionExpression": "#k, #t",
+		"ExpressionAttributeNames": map[string]string{
+			"#k": c.conf.KeyAttribute,
+			"#t": c.conf.TTLAttribute,
+		},
+	}, &out)
+	finish(err)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := c.value(out.Item); !ok {
+		return 0, ErrKeyNotFound{Key: key}
+	}
+	attr, ok := out.Item[c.conf.TTLAttribute]
+	if !ok || len(attr.N) == 0 {
+		return 0, nil
+	}
+	exp, err := strconv.ParseInt(attr.N, 10, 64)
+	if err != nil || exp <= 0 {
+		return 0, nil
+	}
+	return time.Unix(exp, 0).Sub(c.now()), nil
+}
+
+// Touch sets new lifetime of the value using conditional UpdateItem request.
+func (c *dynamodbCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	ttl, err := c.ttlGuard.expiration(ctx, key, ttl)
+	if err != nil {
+		finish(err)
+		return err
+	}
+
+	now := c.now()
+	values := dynamodbItem{
+		":now": {N: strconv.FormatInt(now.Unix(), 10)},
+	}
+	update := "REMOVE #t"
+	if ttl > 0 {
+		// TTL attribute has second precision so round up to not expire early.
+		values[":exp"] = dynamodbAttr{N: strconv.FormatInt(now.Add(ttl+time.Second-1).Unix(), 10)}
+		update = "SET #t = :exp"
+	}
+	// Expired items that are not yet deleted by DynamoDB are treated as absent.
+	err = c.do(ctx, "UpdateItem", map[string]any{
+		"TableName":           c.conf.Table,
+		"Key":                 c.itemKey(key),
+		"UpdateExpression":    update,
+		"ConditionExpression": "attribute_exists(#k) AND (attribute_not_exists(#t) OR #t > :now)",
+		"ExpressionAttributeNames": map[string]string{
+			"#k": c.conf.KeyAttribute,
+			"#t": c.conf.TTLAttribute,
+		},
+		"ExpressionAttributeValues": values,
+	}, nil)
+	var derr *dynamodbError
+	if errors.As(err, &derr) && derr.is("ConditionalCheckFailedException") {
+		finish(nil)
+		return ErrKeyNotFound{Key: key}
+	}
+	finish(err)
+	return err
+}
+
+// TTL returns remaining lifetime of the lease attached to the value.
+//
+// Lease has second precision.
+func (c *etcdCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if c.closed.Load() {
+		return 0, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+
+	resp, err := c.client.Get(ctx, c.prefix+key, clientv3.WithKeysOnly())
+	if err != nil {
+		finish(err)
+		return 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		finish(nil)
+		return 0, ErrKeyNotFound{Key: key}
+	}
+	if resp.Kvs[0].Lease == 0 {
+		finish(nil)
+		return 0, nil
+	}
+	lease, err := c.client.TimeToLive(ctx, clientv3.LeaseID(resp.Kvs[0].Lease))
+	finish(err)
+	if err != nil {
+		return 0, err
+	}
+	if lease.TTL <= 0 {
+		return 0, ErrKeyNotFound{Key: key}
+	}
+	return time.Duration(lease.TTL) * time.Second, nil
+}
+
+// Touch attaches new lease to the value keeping the value intact and revokes
+// the previous lease.
+func (c *etcdCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	ttl, err := c.ttlGuard.expiration(ctx, key, ttl)
+	if err != nil {
+		finish(err)
+		return err
+	}
+
+	popts := []clientv3.OpOption{clientv3.WithIgnoreValue(), clientv3.WithPrevKV()}
+	var lease clientv3.LeaseID
+	if ttl > 0 {
+		resp, err := c.client.Grant(ctx, etcdLeaseTTL(ttl))
+		if err != nil {
+			finish(err)
+			return err
+		}
+		lease = resp.ID
+		popts = append(popts, clientv3.WithLease(lease))
+	}
+	resp, err := c.client.Put(ctx, c.prefix+key, "", popts...)
+	if err != nil {
+		if lease != 0 {
+			_, _ = c.client.Revoke(ctx, lease)
+		}
+		if errors.Is(err, rpctypes.ErrKeyNotFound) {
+			finish(nil)
+			return ErrKeyNotFound{Key: key}
+		}
+		finish(err)
+		return err
+	}
+	if resp.PrevKv != nil && resp.PrevKv.Lease != int64(lease) {
+		_, _ = c.client.Revoke(ctx, clientv3.LeaseID(resp.PrevKv.Lease))
+	}
+	finish(nil)
+	return nil
+}
+
+func (c *natsCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if c.closed.Load() {
+		return 0, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	entry, _, err := c.get(key)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		finish(nil)
+		return 0, ErrKeyNotFound{Key: key}
+	}
+	finish(err)
+	if err != nil {
+		return 0, err
+	}
+	exp := int64(binary.BigEndian.Uint64(entry.Value()))
+	if exp == 0 {
+		return 0, nil
+	}
+	return time.Unix(0, exp).Sub(c.now()), nil
+}
+
+// Touch stores the same payload with new expiration time. Concurrent updates
+// of the value are detected by the revision and touch is retried.
+func (c *natsCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	ttl, err := c.ttlGuard.expiration(ctx, key, ttl)
+	if err != nil {
+		finish(err)
+		return err
+	}
+	for {
+		entry, payload, err := c.get(key)
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			finish(nil)
+			return ErrKeyNotFound{Key: key}
+		}
+		if err != nil {
+			finish(err)
+			return err
+		}
+		var exp int64
+		if ttl > 0 {
+			exp = c.now().Add(ttl).UnixNano()
+		}
+		data := make([]byte, 8, 8+len(payload))
+		binary.BigEndian.PutUint64(data, uint64(exp))
+		_, err = c.kv.Update(c.key(key), append(data, payload...), entry.Revision())
+		if errors.Is(err, nats.ErrKeyExists) {
+			continue
+		}
+		finish(err)
+		return err
+	}
+}
+
+func (c *readOnlyCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return GetTTL(ctx, c.CacheInstance, key)
+}
+
+func (c *readOnlyCache[T]) Touch(ctx context.Context, key string, _ time.Duration) error {
+	return c.reject(ctx, InstrumentationCacheSet, key)
+}
+
+func (c *modeCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return GetTTL(ctx, c.CacheInstance, key)
+}
+
+func (c *modeCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if m, _ := c.current(); m == ModeReadOnly {
+		return ErrReadOnly
+	}
+	return Touch(ctx, c.CacheInstance, key, ttl)
+}
+
+func (c *slidingCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return GetTTL(ctx, c.CacheInstance, key)
+}
+
+func (c *slidingCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	return Touch(ctx, c.CacheInstance, key, ttl)
+}
+
+func (c *eventCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return GetTTL(ctx, c.CacheInstance, key)
+}
+
+func (c *eventCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	return Touch(ctx, c.CacheInstance, key, ttl)
+}
+
+func (c *replicatedCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return GetTTL(ctx, c.CacheInstance, key)
+}
+
+// Touch changes lifetime of the value only in the primary cache instance.
+func (c *replicatedCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	return Touch(ctx, c.CacheInstance, key, ttl)
+}
+
+// TTL returns remaining lifetime of the value in the remote cache instance.
+func (c *tieredCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return GetTTL(ctx, c.CacheInstance, key)
+}
+
+// Touch sets new lifetime of the value in the remote cache instance and drops
+// local copy so that it does not outlive the shortened remote value.
+func (c *tieredCache[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	_ = c.local.Delete(ctx, key)
+	return Touch(ctx, c.CacheInstance, key, ttl)
+}
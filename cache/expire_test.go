@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTouch checks TTL and Touch of the cache instance. If advance is set it
+// is used to move cache instance clock forward.
+func testTouch(t *testing.T, i CacheInstance[string], advance func(d time.Duration)) {
+	t.Helper()
+
+	ctx := context.TODO()
+	require.NoError(t, i.Set(ctx, "key", "value", TTL[string](time.Minute)))
+	require.NoError(t, i.Set(ctx, "persistent", "value"))
+
+	ttl, err := GetTTL(ctx, i, "key")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 50*time.Second)
+	assert.LessOrEqual(t, ttl, time.Minute+time.Second)
+
+	ttl, err = GetTTL(ctx, i, "persistent")
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+
+	_, err = GetTTL(ctx, i, "missing")
+	assert.Equal(t, ErrKeyNotFound{Key: "missing"}, err)
+
+	require.NoError(t, Touch(ctx, i, "key", 10*time.Minute))
+	ttl, err = GetTTL(ctx, i, "key")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 9*time.Minute)
+	v, err := i.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	require.NoError(t, Touch(ctx, i, "persistent", time.Minute))
+	ttl, err = GetTTL(ctx, i, "persistent")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 50*time.Second)
+
+	require.NoError(t, Touch(ctx, i, "key", NoExpiration))
+	ttl, err = GetTTL(ctx, i, "key")
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+
+	assert.Equal(t, ErrKeyNotFound{Key: "missing"}, Touch(ctx, i, "missing", time.Minute))
+
+	if advance == nil {
+		return
+	}
+	require.NoError(t, Touch(ctx, i, "key", time.Minute))
+	advance(30 * time.Second)
+	require.NoError(t, Touch(ctx, i, "key", time.Minute))
+	advance(45 * time.Second)
+	v, err = i.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v, "touched value must not expire")
+
+	advance(time.Minute)
+	assert.Equal(t, ErrKeyNotFound{Key: "key"}, Touch(ctx, i, "key", time.Minute))
+}
+
+func TestRedisCacheTouch(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testTouch(t, i, s.FastForward)
+}
+
+func TestRedisCacheTouchDeduplicated(t *testing.T) {
+	c, s := newMiniRedisCache(t, Deduplicate{MinSize: 1})
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testTouch(t, i, s.FastForward)
+
+	require.NoError(t, i.Set(context.TODO(), "a", "shared", TTL[string](time.Minute)))
+	require.NoError(t, i.Set(context.TODO(), "b", "shared", TTL[string](time.Minute)))
+	require.NoError(t, Touch(context.TODO(), i, "a", time.Hour))
+	s.FastForward(2 * time.Minute)
+
+	// Payload must outlive the touched reference.
+	v, err := i.Get(context.TODO(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, "shared", v)
+}
+
+func TestMemoryCacheTouch(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testTouch(t, i, nil)
+}
+
+func TestLRUCacheTouch(t *testing.T) {
+	i, err := newLRUCache[string]()
+	require.NoError(t, err)
+	defer closeInstance(i)
+
+	c := i.(*lruCache[string])
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	testTouch(t, c, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestBoltCacheTouch(t *testing.T) {
+	c := newTestBoltCache[string](t, filepath.Join(t.TempDir(), "cache.db"))
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	testTouch(t, c, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestMemcachedCacheTouch(t *testing.T) {
+	c, s := newTestMemcachedCache(t)
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(context.TODO(), "key", "value", TTL[string](time.Minute)))
+	require.NoError(t, Touch(context.TODO(), i, "key", 10*time.Minute))
+	item, ok := s.item("test:key")
+	require.True(t, ok)
+	assert.Equal(t, int64(600), item.exp)
+
+	assert.Equal(t, ErrKeyNotFound{Key: "missing"}, Touch(context.TODO(), i, "missing", time.Minute))
+
+	_, err = GetTTL(context.TODO(), i, "key")
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestPostgresCacheTouch(t *testing.T) {
+	c, db := newTestPostgresCache[string](t)
+	testTouch(t, c, func(d time.Duration) { db.now = db.now.Add(d) })
+}
+
+func TestDynamoDBCacheTouch(t *testing.T) {
+	c, _ := newTestDynamoDBCache[string](t, "cache")
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	testTouch(t, c, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestEtcdCacheTouch(t *testing.T) {
+	c, client := newTestEtcdCache[string](t)
+	testTouch(t, c, func(d time.Duration) {
+		client.lock.Lock()
+		defer client.lock.Unlock()
+		client.now = client.now.Add(d)
+	})
+
+	// Previous leases are revoked.
+	require.NoError(t, c.Set(context.TODO(), "lease", "value", TTL[string](time.Minute)))
+	require.NoError(t, c.Touch(context.TODO(), "lease", time.Hour))
+	require.NoError(t, c.Touch(context.TODO(), "lease", 2*time.Hour))
+	assert.Len(t, client.leases, 1)
+}
+
+func TestNATSCacheTouch(t *testing.T) {
+	c, _ := newTestNATSCache[string](t)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	testTouch(t, c, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestTouchTTLGuard(t *testing.T) {
+	i, err := newLRUCache[string](TTLGuard{Max: time.Hour, Strict: true})
+	require.NoError(t, err)
+	defer closeInstance(i)
+
+	require.NoError(t, i.Set(context.TODO(), "key", "value", TTL[string](time.Minute)))
+	require.NoError(t, Touch(context.TODO(), i, "key", 24*time.Hour))
+	ttl, err := GetTTL(context.TODO(), i, "key")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Hour)
+}
+
+func TestTouchWrappers(t *testing.T) {
+	i, err := newLRUCache[string]()
+	require.NoError(t, err)
+	defer closeInstance(i)
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+
+	ro := newReadOnlyCache(i, ReadOnly{})
+	assert.ErrorIs(t, Touch(context.TODO(), ro, "key", time.Minute), ErrReadOnly)
+	ttl, err := GetTTL(context.TODO(), ro, "key")
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+
+	_, err = GetTTL[string](context.TODO(), testMapCache[string]{}, "key")
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestTieredCacheTouch(t *testing.T) {
+	i1, i2, s := newTieredTestCaches(t, Tiered{TTL: time.Hour})
+
+	require.NoError(t, i1.Set(context.TODO(), "key", "value", TTL[string](time.Minute)))
+	_, err := i2.Get(context.TODO(), "key")
+	require.NoError(t, err)
+
+	require.NoError(t, Touch(context.TODO(), i2, "key", time.Hour))
+	ttl, err := GetTTL(context.TODO(), i1, "key")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute)
+
+	require.NoError(t, Touch(context.TODO(), i2, "key", time.Second))
+	s.FastForward(2 * time.Second)
+	_, err = i2.Pop(context.TODO(), "key")
+	assert.Equal(t, ErrKeyNotFound{Key: "key"}, err)
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+)
+
+// CacheInstanceDecoder represents cache instance that can decode value directly
+// into the caller provided destination.
+type CacheInstanceDecoder[T any] interface {
+	// GetInto decodes value into dst. If value is not found, dst is set to the
+	// loaded or default value.
+	GetInto(ctx context.Context, key string, dst *T, opts ...ItemOption[T]) error
+}
+
+// GetInto reads value from the cache instance into dst. If value is not found,
+// dst is set to the value returned by the loader or the default value.
+//
+// Stored value is decoded directly into dst avoiding allocation and copy of the
+// value on hot paths. Value is decoded the same way as by the serializer
+// Unmarshal, so existing map entries and struct fields not present in the
+// stored value are kept and dst should be reset before reuse if that matters.
+//
+// Cache instances that do not decode values, for example memory cache, return
+// value using Get.
+func GetInto[T any](ctx context.Context, instance CacheInstance[T], key string, dst *T, opts ...ItemOption[T]) error {
+	if d, ok := instance.(CacheInstanceDecoder[T]); ok {
+		return d.GetInto(ctx, key, dst, opts...)
+	}
+	v, err := instance.Get(ctx, key, opts...)
+	if err != nil {
+		return err
+	}
+	*dst = v
+	return nil
+}
+
+func (c *readOnlyCache[T]) GetInto(ctx context.Context, key string, dst *T, opts ...ItemOption[T]) error {
+	return GetInto(ctx, c.CacheInstance, key, dst, opts...)
+}
+
+func (c *eventCache[T]) GetInto(ctx context.Context, key string, dst *T, opts ...ItemOption[T]) error {
+	return GetInto(ctx, c.CacheInstance, key, dst, opts...)
+}
+
+func (c *modeCache[T]) GetInto(ctx context.Context, key string, dst *T, opts ...ItemOption[T]) error {
+	if m, _ := c.current(); m == ModeNormal {
+		return GetInto(ctx, c.CacheInstance, key, dst, opts...)
+	}
+	v, err := c.Get(ctx, key, opts...)
+	if err != nil {
+		return err
+	}
+	*dst = v
+	return nil
+}
+
+func (c *slidingCache[T]) GetInto(ctx context.Context, key string, dst *T, opts ...ItemOption[T]) error {
+	ttl := c.ttl(opts...)
+	if ttl == 0 {
+		return GetInto(ctx, c.CacheInstance, key, dst, opts...)
+	}
+	err := peekInto(ctx, c.CacheInstance, key, dst, opts...)
+	if isKeyNotFound(err) {
+		if !c.load {
+			*dst = c.itemOptions(opts...).DefaultValue
+			return nil
+		}
+		return GetInto(ctx, c.CacheInstance, key, dst, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	return c.slide(ctx, ttl, key)
+}
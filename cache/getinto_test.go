@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisGetInto(t *testing.T) {
+	c, _ := newMiniRedisCache(t)
+
+	i, err := Create[testProfile](c, "profiles", ModeSwitch{}, Loader[testProfile](func(_ context.Context, key string) (testProfile, error) {
+		return testProfile{Name: "loaded:" + key}, nil
+	}))
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "user/1", testProfile{Name: "John"}))
+
+	var p testProfile
+	require.NoError(t, GetInto(context.TODO(), i, "user/1", &p))
+	assert.Equal(t, "John", p.Name)
+
+	require.NoError(t, GetInto(context.TODO(), i, "user/2", &p))
+	assert.Equal(t, "loaded:user/2", p.Name)
+
+	// Value is decoded the same way when instance is not in normal mode.
+	require.NoError(t, c.SetMode("profiles", ModeFreeze))
+	require.NoError(t, GetInto(context.TODO(), i, "user/1", &p))
+	assert.Equal(t, "John", p.Name)
+}
+
+func TestMemoryGetInto(t *testing.T) {
+	c := New(CacheType(MemoryCache), MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "values")
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+
+	var v string
+	require.NoError(t, GetInto(context.TODO(), i, "key", &v))
+	assert.Equal(t, "value", v)
+
+	require.NoError(t, GetInto(context.TODO(), i, "missing", &v, DefaultValue[string]{Value: "default"}))
+	assert.Equal(t, "default", v)
+}
+
+func BenchmarkRedisGetInto(b *testing.B) {
+	s, err := miniredis.Run()
+	require.NoError(b, err)
+	defer s.Close()
+
+	c := New(CacheType(RedisCache), ConnectionString("redis://"+s.Addr()))
+	require.NoError(b, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[testProfile](c, "profiles")
+	require.NoError(b, err)
+	require.NoError(b, i.Set(context.TODO(), "user/1", testProfile{Name: "John"}))
+
+	var p testProfile
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := GetInto(context.TODO(), i, "user/1", &p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// computeTimesMaxEntries is a maximum number of tracked value computation times.
+const computeTimesMaxEntries = 10000
+
+// backendState is a state of the cache instance backend shared by all its
+// wrappers.
+type backendState struct {
+	// loader is true if backend loads missing values with the loader.
+	loader bool
+	// flights coalesces concurrent GetOrSet misses of the same key.
+	flights singleflight.Group
+
+	computeLock sync.Mutex
+	// computeTimes are last GetOrSet value computation times by the key.
+	computeTimes map[string]time.Duration
+}
+
+func (s *backendState) state() *backendState {
+	return s
+}
+
+// stateProvider represents cache instance backend with its state.
+type stateProvider interface {
+	state() *backendState
+}
+
+// instanceState returns state of the cache instance backend or nil if cache
+// instance is not implemented by this package.
+func instanceState[T any](instance CacheInstance[T]) *backendState {
+	if p, ok := backendInstance(instance).(stateProvider); ok {
+		return p.state()
+	}
+	return nil
+}
+
+// EarlyExpiration enables probabilistic early expiration (XFetch algorithm) in
+// GetOrSet as an alternative to waiting for the value to expire.
+//
+// Each read recomputes value before it expires with probability that grows as
+// the value approaches expiration and with the time it takes to compute the
+// value, so that hot keys are usually refreshed by a single reader in advance
+// without locks. Computation time is measured in this process so values are not
+// refreshed early until they have been computed by it at least once.
+//
+// Cache instance must support TTL lookups, otherwise option is ignored.
+type EarlyExpiration[T any] struct {
+	// Beta scales early expiration, values greater than 1 favor earlier
+	// refresh. Defaults to 1.
+	Beta float64
+}
+
+func (e EarlyExpiration[T]) applyItem(c *itemOptions[T]) {
+	c.EarlyBeta = e.Beta
+	if c.EarlyBeta <= 0 {
+		c.EarlyBeta = 1
+	}
+}
+
+// expireEarly returns true if value computed in d with remaining lifetime ttl
+// should be recomputed now.
+func expireEarly(d, ttl time.Duration, beta float64) bool {
+	if d <= 0 || ttl <= 0 {
+		return false
+	}
+	// 1-rand.Float64() is in (0, 1] so logarithm is never infinite.
+	return -float64(d)*beta*math.Log(1-rand.Float64()) >= float64(ttl) //nolint:gosec
+}
+
+// computeTime returns last computation time of the value.
+func (s *backendState) computeTime(key string) time.Duration {
+	s.computeLock.Lock()
+	defer s.computeLock.Unlock()
+
+	return s.computeTimes[key]
+}
+
+// compute returns new value computed using fn and records computation time.
+func compute[T any](ctx context.Context, s *backendState, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	start := time.Now()
+	v, err := fn(ctx)
+	if err != nil || s == nil {
+		return v, err
+	}
+	d := time.Since(start)
+
+	s.computeLock.Lock()
+	defer s.computeLock.Unlock()
+
+	if s.computeTimes == nil {
+		s.computeTimes = make(map[string]time.Duration)
+	}
+	if _, ok := s.computeTimes[key]; !ok && len(s.computeTimes) >= computeTimesMaxEntries {
+		for k := range s.computeTimes {
+			delete(s.computeTimes, k)
+			break
+		}
+	}
+	s.computeTimes[key] = d
+	return v, nil
+}
+
+// lookup returns value of the key and true if value was found in the cache
+// instance without calling its loader.
+func lookup[T any](ctx context.Context, instance CacheInstance[T], key string, opts ...ItemOption[T]) (T, bool, error) {
+	v, err := peek(ctx, instance, key, opts...)
+	if isKeyNotFound(err) {
+		return v, false, nil
+	}
+	if err != nil {
+		return v, false, err
+	}
+	return v, true, nil
+}
+
+// refreshEarly recomputes and stores value before it expires if early expiration
+// is enabled and returns false if value was not refreshed.
+//
+// Value is recomputed by the reader without waiting for other readers, on failure
+// current value is kept.
+func refreshEarly[T any](ctx context.Context, instance CacheInstance[T], s *backendState, key string, fn func(ctx context.Context) (T, error), opts ...ItemOption[T]) (T, bool) {
+	var v T
+	beta := resolveItemOptions(instance, opts...).EarlyBeta
+	if beta <= 0 || s == nil {
+		return v, false
+	}
+	d := s.computeTime(key)
+	if d <= 0 {
+		return v, false
+	}
+	ttl, err := GetTTL(ctx, instance, key)
+	if err != nil || !expireEarly(d, ttl, beta) {
+		return v, false
+	}
+	v, err = compute(ctx, s, key, fn)
+	if err != nil {
+		return v, false
+	}
+	return v, instance.Set(ctx, key, v, opts...) == nil
+}
+
+// GetOrSet returns value of the key from the cache instance or computes it
+// using fn and stores it in the cache instance when value is missing.
+//
+// Concurrent calls for the same missing key of the cache instance in this
+// process are coalesced so that fn is called and value is stored only once,
+// other calls wait for and share its result. Function is called with the
+// context of the first caller without its cancellation, so that other calls
+// do not fail if the first caller goes away. Calls are not coalesced for cache
+// instances not implemented by this package.
+//
+// Cache instance loader is not called, missing values are computed using fn.
+//
+// Use EarlyExpiration option to refresh values before they expire.
+func GetOrSet[T any](ctx context.Context, instance CacheInstance[T], key string, fn func(ctx context.Context) (T, error), opts ...ItemOption[T]) (T, error) {
+	s := instanceState(instance)
+
+	v, found, err := lookup(ctx, instance, key, opts...)
+	if err != nil {
+		return v, err
+	}
+	if found {
+		if nv, ok := refreshEarly(ctx, instance, s, key, fn, opts...); ok {
+			return nv, nil
+		}
+		return v, nil
+	}
+
+	set := func() (any, error) {
+		ctx := context.WithoutCancel(ctx)
+		// Value could have been stored by the call that has just completed.
+		v, found, err := lookup(ctx, instance, key, opts...)
+		if err != nil || found {
+			return v, err
+		}
+		if v, err = compute(ctx, s, key, fn); err != nil {
+			return v, err
+		}
+		return v, instance.Set(ctx, key, v, opts...)
+	}
+	var r any
+	if s != nil {
+		r, err, _ = s.flights.Do(key, set)
+	} else {
+		r, err = set()
+	}
+	v, _ = r.(T)
+	return v, err
+}
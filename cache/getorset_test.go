@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrSetStampede(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context) (string, error) {
+		calls.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 50)
+	for n := range results {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			v, err := GetOrSet(context.TODO(), i, "key", fn, TTL[string](time.Minute))
+			assert.NoError(t, err)
+			results[n] = v
+		}(n)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, v := range results {
+		assert.Equal(t, "value", v)
+	}
+	assert.True(t, s.Exists("test:key"))
+	assert.Greater(t, s.TTL("test:key"), time.Duration(0))
+
+	v, err := GetOrSet(context.TODO(), i, "key", fn)
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestGetOrSetError(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[int](c, "test")
+	require.NoError(t, err)
+
+	errLoad := errors.New("failed")
+	_, err = GetOrSet(context.TODO(), i, "key", func(context.Context) (int, error) {
+		return 0, errLoad
+	})
+	assert.ErrorIs(t, err, errLoad)
+
+	v, err := GetOrSet(context.TODO(), i, "key", func(context.Context) (int, error) {
+		return 42, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+
+	v, err = i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+}
+
+func TestGetOrSetZeroValue(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[int](c, "test", Loader[int](func(context.Context, string) (int, error) {
+		return 0, errors.New("loader called")
+	}))
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "zero", 0))
+
+	// Stored zero value is found and loader is not used for missing values.
+	v, err := GetOrSet(context.TODO(), i, "zero", func(context.Context) (int, error) {
+		return 42, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, v)
+
+	v, err = GetOrSet(context.TODO(), i, "missing", func(context.Context) (int, error) {
+		return 42, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+
+	// Computation is not canceled with the caller context.
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	v, err = GetOrSet(ctx, i, "canceled", func(ctx context.Context) (int, error) {
+		return 1, ctx.Err()
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
+
+func TestGetOrSetFallback(t *testing.T) {
+	i := testMapCache[string]{"hit": "cached"}
+
+	v, err := GetOrSet[string](context.TODO(), i, "hit", func(context.Context) (string, error) {
+		return "computed", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "cached", v)
+
+	v, err = GetOrSet[string](context.TODO(), i, "miss", func(context.Context) (string, error) {
+		return "computed", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "computed", v)
+	assert.Equal(t, "computed", i["miss"])
+}
+
+func TestGetOrSetEarlyExpiration(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[int32](c, "test")
+	require.NoError(t, err)
+
+	var calls atomic.Int32
+	fn := func(context.Context) (int32, error) {
+		time.Sleep(10 * time.Millisecond)
+		return calls.Add(1), nil
+	}
+
+	v, err := GetOrSet(context.TODO(), i, "key", fn, TTL[int32](time.Hour), EarlyExpiration[int32]{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), v)
+
+	// Value far from expiration is not refreshed.
+	v, err = GetOrSet(context.TODO(), i, "key", fn, TTL[int32](time.Hour), EarlyExpiration[int32]{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), v)
+
+	// Value close to expiration relative to its computation time is refreshed.
+	require.NoError(t, i.Set(context.TODO(), "key", 1, TTL[int32](time.Second)))
+	v, err = GetOrSet(context.TODO(), i, "key", fn, TTL[int32](time.Second), EarlyExpiration[int32]{Beta: 1e6})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), v)
+	v, err = i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), v)
+
+	// Without early expiration value is kept until it expires.
+	v, err = GetOrSet(context.TODO(), i, "key", fn, TTL[int32](time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), v)
+}
+
+func TestExpireEarly(t *testing.T) {
+	assert.False(t, expireEarly(0, time.Second, 1))
+	assert.False(t, expireEarly(time.Second, 0, 1))
+	assert.False(t, expireEarly(time.Millisecond, time.Hour, 1))
+	assert.True(t, expireEarly(time.Second, time.Nanosecond, 1e9))
+}
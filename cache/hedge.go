@@ -0,0 +1,98 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	InstrumentationCacheHedge = "cache-hedge"
+)
+
+// HedgedReads sends read to the Redis replica if primary has not responded within
+// the delay and uses the first response received.
+//
+// Misses returned by the replica are not used as value could have not been
+// replicated yet, in such case primary response is used.
+type HedgedReads struct {
+	// ConnectionString is a connection string of the Redis replica.
+	ConnectionString string
+	// ConnectionPassword is a password of the Redis replica.
+	ConnectionPassword string
+	// Delay is a latency budget of the primary after which read is sent to replica.
+	// Defaults to 10 milliseconds.
+	Delay time.Duration
+}
+
+func (h HedgedReads) applyCache(c *cacheOptions) {
+	c.HedgedReads = &h
+}
+
+type hedgedReader struct {
+	con   redis.Cmdable
+	delay time.Duration
+}
+
+func newHedgedReader(conf *HedgedReads) (*hedgedReader, error) {
+	con, err := newRedisClient(conf.ConnectionString, conf.ConnectionPassword)
+	if err != nil {
+		return nil, err
+	}
+	delay := conf.Delay
+	if delay <= 0 {
+		delay = 10 * time.Millisecond
+	}
+	return &hedgedReader{
+		con:   con,
+		delay: delay,
+	}, nil
+}
+
+// fetch returns value from the primary or replica, whichever responds first.
+func (c *redisCache[T]) fetch(ctx context.Context, key string) *redis.StringCmd {
+	if c.hedge == nil {
+		return c.con.Get(ctx, key)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	primary := make(chan *redis.StringCmd, 1)
+	go func() {
+		primary <- c.con.Get(ctx, key)
+	}()
+
+	timer := time.NewTimer(c.hedge.delay)
+	defer timer.Stop()
+
+	select {
+	case s := <-primary:
+		return s
+	case <-timer.C:
+	}
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheHedge, key)
+	replica := make(chan *redis.StringCmd, 1)
+	go func() {
+		replica <- c.hedge.con.Get(ctx, key)
+	}()
+
+	select {
+	case s := <-primary:
+		finish(nil)
+		return s
+	case s := <-replica:
+		if s.Err() == nil {
+			finish(nil)
+			return s
+		}
+		finish(s.Err())
+		return <-primary
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	bolt "go.etcd.io/bbolt"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// CacheInstanceKeys represents cache instance that can iterate its keys.
+type CacheInstanceKeys[T any] interface {
+	// ForEach calls fn for every key of the cache instance matching the pattern.
+	ForEach(ctx context.Context, pattern string, fn func(key string) error) error
+}
+
+// ForEach calls fn for every key of the cache instance matching the glob-style
+// pattern. Keys are passed without the instance prefix. Empty pattern matches
+// all keys.
+//
+// Pattern supports "*" matching any sequence of characters, "?" matching any
+// single character, "[abc]" and "[a-z]" matching character classes, "[^a]"
+// negating character class and "\" escaping special characters.
+//
+// If fn returns an error, iteration stops and the error is returned. Keys stored
+// or deleted concurrently may or may not be passed to fn and the same key can be
+// passed more than once. Cache instances that can not enumerate their keys
+// return ErrNotSupported.
+func ForEach[T any](ctx context.Context, instance CacheInstance[T], pattern string, fn func(key string) error) error {
+	if s, ok := instance.(CacheInstanceKeys[T]); ok {
+		return s.ForEach(ctx, pattern, fn)
+	}
+	return ErrNotSupported
+}
+
+// Keys returns all keys of the cache instance matching the glob-style pattern
+// sorted in ascending order. See ForEach for the pattern syntax.
+//
+// Keys loads all matching keys in memory so ForEach should be used to walk
+// large cache instances.
+func Keys[T any](ctx context.Context, instance CacheInstance[T], pattern string) ([]string, error) {
+	keys := make([]string, 0)
+	seen := make(map[string]struct{})
+	err := ForEach(ctx, instance, pattern, func(key string) error {
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// matchPattern reports whether key matches the glob-style pattern using the
+// same rules as Redis SCAN MATCH.
+func matchPattern(pattern, key string) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if matchPattern(pattern, key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				// Unterminated class matches literally.
+				if key[0] != '[' {
+					return false
+				}
+				pattern, key = pattern[1:], key[1:]
+				continue
+			}
+			class := pattern[1 : end+1]
+			negate := len(class) > 0 && class[0] == '^'
+			if negate {
+				class = class[1:]
+			}
+			if matchClass(class, key[0]) == negate {
+				return false
+			}
+			pattern, key = pattern[end+2:], key[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+		}
+	}
+	return len(key) == 0
+}
+
+// matchClass reports whether character matches character class with ranges.
+func matchClass(class string, c byte) bool {
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				return true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			return true
+		}
+	}
+	return false
+}
+
+// ForEach scans keys using SCAN command with the pattern so that Redis is not
+// blocked by the KEYS command. Deduplicated payloads and tag indexes are skipped.
+func (c *redisCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix)
+
+	err := scanKeys(ctx, c.con, c.prefix, func(_ redis.Cmdable, keys []string) error {
+		for _, k := range keys {
+			key := strings.TrimPrefix(k, c.prefix)
+			if internalKey(key) || !matchPattern(pattern, key) {
+				continue
+			}
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	finish(err)
+	return err
+}
+
+func (c *lruCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, "")
+
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		finish(ErrCacheClosed)
+		return ErrCacheClosed
+	}
+	// Keys are collected first so that fn can use the cache instance.
+	now := c.now()
+	keys := make([]string, 0, len(c.items))
+	for key, el := range c.items {
+		if !el.Value.(*lruEntry[T]).expired(now) && matchPattern(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	c.lock.Unlock()
+
+	err := forEachKey(ctx, keys, fn)
+	finish(err)
+	return err
+}
+
+func (c *boltCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix)
+
+	// Keys are collected first as fn can not write to the database during
+	// read transaction.
+	keys := make([]string, 0)
+	now := c.now()
+	err := c.file.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(c.bucket).ForEach(func(k, v []byte) error {
+			if !boltExpired(v, now) && matchPattern(pattern, string(k)) {
+				keys = append(keys, string(k))
+			}
+			return nil
+		})
+	})
+	if err == nil {
+		err = forEachKey(ctx, keys, fn)
+	}
+	finish(err)
+	return err
+}
+
+func (c *postgresCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix)
+
+	var all []string
+	err := c.db.QueryRow(ctx, `SELECT coalesce(array_agg(key), '{}') FROM `+c.table+` WHERE key LIKE $1 AND (expires_at IS NULL OR expires_at > now())`,
+		postgresLikeEscaper.Replace(c.prefix)+"%").Scan(&all)
+	if err != nil {
+		finish(err)
+		return err
+	}
+	keys := make([]string, 0, len(all))
+	for _, k := range all {
+		if key := strings.TrimPrefix(k, c.prefix); matchPattern(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	err = forEachKey(ctx, keys, fn)
+	finish(err)
+	return err
+}
+
+func (c *etcdCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix)
+
+	resp, err := c.client.Get(ctx, c.prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		finish(err)
+		return err
+	}
+	keys := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if key := strings.TrimPrefix(string(kv.Key), c.prefix); matchPattern(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	err = forEachKey(ctx, keys, fn)
+	finish(err)
+	return err
+}
+
+// ForEach lists keys of the instance bucket. Keys are checked for expiration
+// one by one so pattern should be used to limit number of keys.
+func (c *natsCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix)
+
+	all, err := c.kv.Keys(nats.Context(ctx))
+	if errors.Is(err, nats.ErrNoKeysFound) {
+		finish(nil)
+		return nil
+	}
+	if err != nil {
+		finish(err)
+		return err
+	}
+	keys := make([]string, 0, len(all))
+	for _, k := range all {
+		key := k
+		if strings.HasPrefix(k, natsEncodedKeyPrefix) {
+			buf, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(k, natsEncodedKeyPrefix))
+			if err != nil {
+				continue
+			}
+			key = string(buf)
+		}
+		if !matchPattern(pattern, key) {
+			continue
+		}
+		if _, _, err := c.get(key); err != nil {
+			if errors.Is(err, nats.ErrKeyNotFound) {
+				continue
+			}
+			finish(err)
+			return err
+		}
+		keys = append(keys, key)
+	}
+	err = forEachKey(ctx, keys, fn)
+	finish(err)
+	return err
+}
+
+// forEachKey calls fn for every key until context is done.
+func forEachKey(ctx context.Context, keys []string, fn func(key string) error) error {
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *readOnlyCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	return ForEach(ctx, c.CacheInstance, pattern, fn)
+}
+
+func (c *modeCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	return ForEach(ctx, c.CacheInstance, pattern, fn)
+}
+
+func (c *slidingCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	return ForEach(ctx, c.CacheInstance, pattern, fn)
+}
+
+func (c *eventCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	return ForEach(ctx, c.CacheInstance, pattern, fn)
+}
+
+// ForEach iterates keys of the primary cache instance.
+func (c *replicatedCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	return ForEach(ctx, c.CacheInstance, pattern, fn)
+}
+
+// ForEach iterates keys of the remote cache instance.
+func (c *tieredCache[T]) ForEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	return ForEach(ctx, c.CacheInstance, pattern, fn)
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		match   bool
+	}{
+		{"", "any", true},
+		{"*", "", true},
+		{"user:*", "user:1", true},
+		{"user:*", "user:1/profile", true},
+		{"user:*", "order:1", false},
+		{"*:profile", "user:1:profile", true},
+		{"user:?", "user:1", true},
+		{"user:?", "user:12", false},
+		{"user:[12]", "user:2", true},
+		{"user:[12]", "user:3", false},
+		{"user:[a-c]", "user:b", true},
+		{"user:[^a-c]", "user:b", false},
+		{"user:[^a-c]", "user:d", true},
+		{`user:\*`, "user:*", true},
+		{`user:\*`, "user:1", false},
+		{"user:[", "user:[", true},
+		{"user", "user:1", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, matchPattern(tt.pattern, tt.key), "%q %q", tt.pattern, tt.key)
+	}
+}
+
+// testForEach checks that ForEach walks keys matching the pattern without the
+// instance prefix and ignores keys of the other instance.
+func testForEach(t *testing.T, i, other CacheInstance[string]) {
+	t.Helper()
+
+	ctx := context.TODO()
+	for _, k := range []string{"user:1", "user:2", "user:2:profile", "order:1"} {
+		require.NoError(t, i.Set(ctx, k, "value"))
+	}
+	require.NoError(t, other.Set(ctx, "user:3", "other"))
+
+	keys, err := Keys(ctx, i, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"order:1", "user:1", "user:2", "user:2:profile"}, keys)
+
+	keys, err = Keys(ctx, i, "user:?")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:1", "user:2"}, keys)
+
+	// Matching keys can be purged while iterating.
+	require.NoError(t, ForEach(ctx, i, "user:*", func(key string) error {
+		return i.Delete(ctx, key)
+	}))
+	keys, err = Keys(ctx, i, "*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"order:1"}, keys)
+
+	errStop := errors.New("stop")
+	n := 0
+	err = ForEach(ctx, other, "", func(key string) error {
+		n++
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, 1, n)
+}
+
+func TestRedisCacheForEach(t *testing.T) {
+	c, _ := newMiniRedisCache(t, Deduplicate{MinSize: 1})
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	other, err := Create[string](c, "other")
+	require.NoError(t, err)
+	testForEach(t, i, other)
+}
+
+func TestLRUCacheForEach(t *testing.T) {
+	i, err := newLRUCache[string]()
+	require.NoError(t, err)
+	defer closeInstance(i)
+	other, err := newLRUCache[string]()
+	require.NoError(t, err)
+	defer closeInstance(other)
+
+	testForEach(t, i, other)
+}
+
+func TestBoltCacheForEach(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	c := newTestBoltCache[string](t, path)
+	other := newTestBoltCache[string](t, path, KeyPrefix("other"))
+	testForEach(t, c, other)
+}
+
+func TestPostgresCacheForEach(t *testing.T) {
+	c, db := newTestPostgresCache[string](t)
+	conf, err := ParsePostgresURL("postgres://localhost/test?table=cache.items")
+	require.NoError(t, err)
+	other := newPostgresDBCache[string](db, "other", conf, newCacheOptions())
+	testForEach(t, c, other)
+}
+
+func TestEtcdCacheForEach(t *testing.T) {
+	c, client := newTestEtcdCache[string](t)
+	other := newEtcdClientCache[string](client, "other", newCacheOptions())
+	testForEach(t, c, other)
+}
+
+func TestNATSCacheForEach(t *testing.T) {
+	c, _ := newTestNATSCache[string](t)
+	other, _ := newTestNATSCache[string](t)
+	testForEach(t, c, other)
+
+	// Encoded keys are decoded.
+	require.NoError(t, c.Set(context.TODO(), "user:1 name", "value"))
+	keys, err := Keys[string](context.TODO(), c, "user:*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:1 name"}, keys)
+}
+
+func TestForEachWrappers(t *testing.T) {
+	i, err := newLRUCache[string]()
+	require.NoError(t, err)
+	defer closeInstance(i)
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+
+	keys, err := Keys(context.TODO(), newReadOnlyCache(i, ReadOnly{}), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key"}, keys)
+
+	c := New(MemoryCache)
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+	mi, err := Create[string](c, "test")
+	require.NoError(t, err)
+	_, err = Keys(context.TODO(), mi, "")
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
@@ -0,0 +1,381 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"github.com/goccy/go-json"
+)
+
+// InstrumentationCacheEvict is an instrumentation operation for items evicted
+// from the memory cache to stay within its limits.
+const InstrumentationCacheEvict = "cache-evict"
+
+// MemoryLimit bounds memory cache instance by item count and size evicting
+// least recently used items when any of the limits is reached.
+type MemoryLimit struct {
+	// MaxItems is a maximum number of items in cache instance. Zero means no limit.
+	MaxItems int
+	// MaxBytes is a maximum total size of items in cache instance. Zero means no limit.
+	MaxBytes int64
+	// Size returns size of the value in bytes. Defaults to length of strings and
+	// byte slices and JSON encoded length of other values.
+	Size func(value any) int64
+}
+
+func (l MemoryLimit) applyCache(o *cacheOptions) {
+	o.MemoryLimit = &l
+}
+
+// estimateSize returns approximate size of the value in bytes.
+func estimateSize(value any) int64 {
+	switch v := value.(type) {
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	}
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return int64(len(buf))
+}
+
+type lruEntry[T any] struct {
+	key       string
+	value     T
+	size      int64
+	storedAt  time.Time
+	expiresAt time.Time
+	tags      []string
+}
+
+func (e *lruEntry[T]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+type lruCache[T any] struct {
+	lock         sync.Mutex
+	closed       bool
+	items        map[string]*list.Element
+	order        *list.List
+	bytes        int64
+	maxItems     int
+	maxBytes     int64
+	size         func(value any) int64
+	defaults     itemDefaults[T]
+	loader       func(ctx context.Context, key string) (T, error)
+	instrumenter instrumenter.Instrumenter
+	ttlGuard     *TTLGuard
+	persist      *MemorySnapshot
+	now          func() time.Time
+
+	backendState
+}
+
+func newLRUCache[T any](opts ...CacheOption) (CacheInstance[T], error) {
+	opt := newCacheOptions(opts...)
+
+	limit := opt.MemoryLimit
+	if limit == nil {
+		limit = &MemoryLimit{}
+	}
+	if limit.MaxItems < 0 || limit.MaxBytes < 0 {
+		return nil, fmt.Errorf("invalid memory cache limits: %d items, %d bytes", limit.MaxItems, limit.MaxBytes)
+	}
+	size := limit.Size
+	if size == nil {
+		size = estimateSize
+	}
+
+	c := &lruCache[T]{
+		items:        make(map[string]*list.Element),
+		order:        list.New(),
+		maxItems:     limit.MaxItems,
+		maxBytes:     limit.MaxBytes,
+		size:         size,
+		defaults:     newItemDefaults[T](opt),
+		loader:       newLoader[T](opt),
+		instrumenter: opt.Instrumenter,
+		ttlGuard:     opt.TTLGuard,
+		persist:      opt.MemorySnapshot,
+		now:          time.Now,
+	}
+	if c.persist != nil {
+		if err := c.loadSnapshot(context.Background()); err != nil {
+			return nil, fmt.Errorf("failed to restore memory cache snapshot: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// lookup returns live entry for the key marking it as recently used.
+//
+// Must be called with lock held.
+func (c *lruCache[T]) lookup(key string) (*lruEntry[T], bool) {
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry[T])
+	if e.expired(c.now()) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e, true
+}
+
+// remove entry from cache.
+//
+// Must be called with lock held.
+func (c *lruCache[T]) remove(el *list.Element) {
+	e := c.order.Remove(el).(*lruEntry[T])
+	delete(c.items, e.key)
+	c.bytes -= e.size
+}
+
+// evict removes least recently used entries until cache is within its limits.
+// Returns keys of evicted entries.
+//
+// Must be called with lock held.
+func (c *lruCache[T]) evict() []string {
+	var evicted []string
+	for c.overLimit() {
+		el := c.order.Back()
+		if el == nil {
+			break
+		}
+		evicted = append(evicted, el.Value.(*lruEntry[T]).key)
+		c.remove(el)
+	}
+	return evicted
+}
+
+func (c *lruCache[T]) overLimit() bool {
+	return (c.maxItems > 0 && c.order.Len() > c.maxItems) || (c.maxBytes > 0 && c.bytes > c.maxBytes)
+}
+
+func (c *lruCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, true, opts...)
+}
+
+// peek returns value from cache without calling the loader.
+func (c *lruCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, false, opts...)
+}
+
+// read returns value from cache. Missing value is loaded and stored if load is
+// true, otherwise ErrKeyNotFound error is returned.
+func (c *lruCache[T]) read(ctx context.Context, key string, load bool, opts ...ItemOption[T]) (T, error) {
+	var val T
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, key)
+
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		finish(ErrCacheClosed)
+		return val, ErrCacheClosed
+	}
+	e, ok := c.lookup(key)
+	if ok {
+		val = e.value
+	}
+	c.lock.Unlock()
+
+	if ok {
+		finish(nil)
+		return val, nil
+	}
+
+	opt := c.defaults.resolve(opts...)
+	if !load {
+		finish(nil)
+		return val, ErrKeyNotFound{Key: key}
+	}
+	if c.loader == nil {
+		finish(nil)
+		return opt.DefaultValue, nil
+	}
+	v, err := c.loader(ctx, key)
+	if err != nil {
+		finish(err)
+		return val, err
+	}
+	err = c.set(ctx, key, v, opt.TTL)
+	finish(err)
+	return v, err
+}
+
+// Exists checks if value exists in cache without changing its recency.
+func (c *lruCache[T]) Exists(ctx context.Context, key string) (bool, error) {
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, key)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		finish(ErrCacheClosed)
+		return false, ErrCacheClosed
+	}
+	el, ok := c.items[key]
+	if ok && el.Value.(*lruEntry[T]).expired(c.now()) {
+		c.remove(el)
+		ok = false
+	}
+	finish(nil)
+	return ok, nil
+}
+
+func (c *lruCache[T]) set(ctx context.Context, key string, value T, ttl time.Duration, tags ...string) error {
+	_, err := c.put(ctx, key, value, ttl, false, tags...)
+	return err
+}
+
+// put stores value in cache. If nx is true, value is stored only if key does
+// not exist. Returns true if value was stored.
+func (c *lruCache[T]) put(ctx context.Context, key string, value T, ttl time.Duration, nx bool, tags ...string) (bool, error) {
+	ttl, err := c.ttlGuard.expiration(ctx, key, ttl)
+	if err != nil {
+		return false, err
+	}
+
+	now := c.now()
+	e := &lruEntry[T]{
+		key:      key,
+		value:    value,
+		size:     c.size(value),
+		storedAt: now,
+		tags:     tags,
+	}
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	}
+
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return false, ErrCacheClosed
+	}
+	if nx {
+		if _, ok := c.lookup(key); ok {
+			c.lock.Unlock()
+			return false, nil
+		}
+	}
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	c.items[key] = c.order.PushFront(e)
+	c.bytes += e.size
+	evicted := c.evict()
+	c.lock.Unlock()
+
+	for _, k := range evicted {
+		c.instrumenter.Observe(ctx, InstrumentationCacheEvict, k)(nil)
+	}
+	return true, nil
+}
+
+func (c *lruCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	v, _, err := c.PopWithMetadata(ctx, key)
+	return v, err
+}
+
+func (c *lruCache[T]) PopWithMetadata(ctx context.Context, key string) (T, ItemMetadata, error) {
+	var val T
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, key)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		finish(ErrCacheClosed)
+		return val, ItemMetadata{}, ErrCacheClosed
+	}
+	e, ok := c.lookup(key)
+	if !ok {
+		finish(nil)
+		return val, ItemMetadata{}, ErrKeyNotFound{Key: key}
+	}
+	c.remove(c.items[key])
+	finish(nil)
+
+	meta := ItemMetadata{StoredAt: e.storedAt}
+	if !e.expiresAt.IsZero() {
+		meta.TTL = e.expiresAt.Sub(c.now())
+	}
+	return e.value, meta, nil
+}
+
+func (c *lruCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	opt := c.defaults.resolve(opts...)
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, key)
+
+	err := c.set(ctx, key, value, opt.TTL, opt.Tags...)
+	finish(err)
+	return err
+}
+
+func (c *lruCache[T]) Delete(ctx context.Context, key string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		return ErrCacheClosed
+	}
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, key)
+	defer finish(nil)
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	return nil
+}
+
+func (c *lruCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	return c.defaults.resolve(opts...)
+}
+
+// Len returns number of items and their total size in cache instance.
+func (c *lruCache[T]) Len() (int, int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.order.Len(), c.bytes
+}
+
+func (c *lruCache[T]) Close() {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return
+	}
+	var entries []lruEntry[T]
+	if c.persist != nil {
+		entries = c.entries()
+	}
+	c.closed = true
+	c.items = nil
+	c.order.Init()
+	c.bytes = 0
+	c.lock.Unlock()
+
+	if c.persist != nil {
+		// Errors are reported to the instrumenter.
+		_ = c.saveSnapshot(context.Background(), entries)
+	}
+}
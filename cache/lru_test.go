@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCacheInstanceSuite runs common cache instance behaviour tests against cache backend.
+func testCacheInstanceSuite(t *testing.T, newCache func(t *testing.T) *Cache) {
+	ctx := context.TODO()
+
+	t.Run("GetSet", func(t *testing.T) {
+		i, err := Create[string](newCache(t), "suite")
+		require.NoError(t, err)
+
+		val, err := i.Get(ctx, "key", DefaultValue[string]{Value: "default"})
+		require.NoError(t, err)
+		assert.Equal(t, "default", val)
+
+		require.NoError(t, i.Set(ctx, "key", "value"))
+		val, err = i.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", val)
+
+		require.NoError(t, i.Set(ctx, "key", "updated"))
+		val, err = i.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "updated", val)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		i, err := Create[string](newCache(t), "suite")
+		require.NoError(t, err)
+
+		require.NoError(t, i.Set(ctx, "key", "value"))
+		require.NoError(t, i.Delete(ctx, "key"))
+		require.NoError(t, i.Delete(ctx, "missing"))
+
+		val, err := i.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Empty(t, val)
+	})
+
+	t.Run("Pop", func(t *testing.T) {
+		i, err := Create[string](newCache(t), "suite")
+		require.NoError(t, err)
+
+		require.NoError(t, i.Set(ctx, "key", "value"))
+		val, err := i.Pop(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", val)
+
+		_, err = i.Pop(ctx, "key")
+		assert.ErrorIs(t, err, ErrKeyNotFound{Key: "key"})
+	})
+
+	t.Run("Loader", func(t *testing.T) {
+		calls := 0
+		i, err := Create[string](newCache(t), "suite", Loader[string](func(ctx context.Context, key string) (string, error) {
+			calls++
+			return "loaded-" + key, nil
+		}))
+		require.NoError(t, err)
+
+		for n := 0; n < 2; n++ {
+			val, err := i.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, "loaded-key", val)
+		}
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Struct", func(t *testing.T) {
+		i, err := Create[testUserV2](newCache(t), "suite")
+		require.NoError(t, err)
+
+		require.NoError(t, i.Set(ctx, "user", testUserV2{FullName: "John"}))
+		val, err := i.Get(ctx, "user")
+		require.NoError(t, err)
+		assert.Equal(t, "John", val.FullName)
+	})
+}
+
+func TestCacheInstanceSuite(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		testCacheInstanceSuite(t, func(t *testing.T) *Cache {
+			c := New(CacheType(MemoryCache), MemorySyncWrites(true))
+			require.NoError(t, c.Start(context.TODO()))
+			t.Cleanup(c.Close)
+			return c
+		})
+	})
+	t.Run("MemoryLRU", func(t *testing.T) {
+		testCacheInstanceSuite(t, func(t *testing.T) *Cache {
+			c := New(CacheType(MemoryCache), MemoryLimit{MaxItems: 100})
+			require.NoError(t, c.Start(context.TODO()))
+			t.Cleanup(c.Close)
+			return c
+		})
+	})
+	t.Run("Redis", func(t *testing.T) {
+		testCacheInstanceSuite(t, func(t *testing.T) *Cache {
+			c, _ := newMiniRedisCache(t)
+			return c
+		})
+	})
+	t.Run("Memcached", func(t *testing.T) {
+		testCacheInstanceSuite(t, func(t *testing.T) *Cache {
+			c, _ := newTestMemcachedCache(t)
+			return c
+		})
+	})
+}
+
+func TestLRUCacheMaxItems(t *testing.T) {
+	ctx := context.TODO()
+
+	evicted := make([]string, 0)
+	c := New(CacheType(MemoryCache), MemoryLimit{MaxItems: 2}, Instrumenter(func(ctx context.Context, op string, args ...any) func(err error) {
+		if op == InstrumentationCacheEvict {
+			evicted = append(evicted, args[0].(string))
+		}
+		return func(err error) {}
+	}))
+	require.NoError(t, c.Start(ctx))
+	defer c.Close()
+
+	i, err := Create[int](c, "test")
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(ctx, "a", 1))
+	require.NoError(t, i.Set(ctx, "b", 2))
+
+	// Access "a" so that "b" becomes least recently used.
+	v, err := i.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	require.NoError(t, i.Set(ctx, "c", 3))
+	assert.Equal(t, []string{"b"}, evicted)
+
+	_, err = i.Pop(ctx, "b")
+	assert.ErrorIs(t, err, ErrKeyNotFound{Key: "b"})
+
+	n, _ := i.(*lruCache[int]).Len()
+	assert.Equal(t, 2, n)
+}
+
+func TestLRUCacheMaxBytes(t *testing.T) {
+	ctx := context.TODO()
+
+	i, err := newLRUCache[string](MemoryLimit{MaxBytes: 10})
+	require.NoError(t, err)
+	lru := i.(*lruCache[string])
+
+	for n := 0; n < 5; n++ {
+		require.NoError(t, i.Set(ctx, fmt.Sprintf("key%d", n), "abcd"))
+	}
+	items, size := lru.Len()
+	assert.Equal(t, 2, items)
+	assert.Equal(t, int64(8), size)
+
+	v, err := i.Get(ctx, "key4")
+	require.NoError(t, err)
+	assert.Equal(t, "abcd", v)
+
+	v, err = i.Get(ctx, "key0")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+
+	// Item larger than the limit is not kept.
+	require.NoError(t, i.Set(ctx, "big", "0123456789abc"))
+	items, size = lru.Len()
+	assert.Equal(t, 0, items)
+	assert.Equal(t, int64(0), size)
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	ctx := context.TODO()
+
+	i, err := newLRUCache[string](MemoryLimit{MaxItems: 10}, DefaultTTL(time.Minute))
+	require.NoError(t, err)
+	lru := i.(*lruCache[string])
+
+	now := time.Now()
+	lru.now = func() time.Time { return now }
+
+	require.NoError(t, i.Set(ctx, "default", "value"))
+	require.NoError(t, i.Set(ctx, "short", "value", TTL[string](time.Second)))
+
+	now = now.Add(2 * time.Second)
+
+	v, err := i.Get(ctx, "short")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+
+	v, meta, err := PopWithMetadata(ctx, i, "default")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	assert.Equal(t, 58*time.Second, meta.TTL)
+
+	lru.Close()
+	assert.ErrorIs(t, i.Set(ctx, "key", "value"), ErrCacheClosed)
+}
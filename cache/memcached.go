@@ -0,0 +1,336 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// memcachedMaxRelativeTTL is a maximum expiration that memcached treats as relative
+// to the current time, longer expirations must be set as absolute Unix time.
+const memcachedMaxRelativeTTL = 30 * 24 * time.Hour
+
+// MemcachedOptions are memcached client connection options.
+type MemcachedOptions struct {
+	// Servers is a list of memcached server addresses.
+	Servers []string
+	// Timeout is a socket read/write timeout.
+	Timeout time.Duration
+	// MaxIdleConns is a maximum number of idle connections kept per server.
+	MaxIdleConns int
+}
+
+// ParseMemcachedURL parses memcached connection string in the format
+// memcached://host1:11211,host2:11211?timeout=500ms&max_idle_conns=10
+//
+// Scheme is optional and port defaults to 11211.
+func ParseMemcachedURL(v string) (*MemcachedOptions, error) {
+	v = strings.TrimPrefix(v, "memcached://")
+	hosts, query, _ := strings.Cut(v, "?")
+	hosts = strings.TrimSuffix(hosts, "/")
+
+	o := &MemcachedOptions{}
+	for _, h := range strings.Split(hosts, ",") {
+		h = strings.TrimSpace(h)
+		if len(h) == 0 {
+			continue
+		}
+		if !strings.Contains(h, ":") && !strings.HasPrefix(h, "/") {
+			h += ":11211"
+		}
+		o.Servers = append(o.Servers, h)
+	}
+	if len(o.Servers) == 0 {
+		return nil, errors.New("memcached servers not specified")
+	}
+
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if t := q.Get("timeout"); len(t) != 0 {
+		if o.Timeout, err = time.ParseDuration(t); err != nil {
+			return nil, fmt.Errorf("invalid memcached timeout: %w", err)
+		}
+	}
+	if n := q.Get("max_idle_conns"); len(n) != 0 {
+		if o.MaxIdleConns, err = strconv.Atoi(n); err != nil {
+			return nil, fmt.Errorf("invalid memcached max_idle_conns: %w", err)
+		}
+	}
+	return o, nil
+}
+
+func newMemcachedClient(constr string) (*memcache.Client, error) {
+	o, err := ParseMemcachedURL(constr)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := &memcache.ServerList{}
+	if err := ss.SetServers(o.Servers...); err != nil {
+		return nil, err
+	}
+	c := memcache.NewFromSelector(ss)
+	c.Timeout = o.Timeout
+	c.MaxIdleConns = o.MaxIdleConns
+	return c, nil
+}
+
+type memcachedCache[T any] struct {
+	con          *memcache.Client
+	closed       atomic.Bool
+	prefix       string
+	items        itemDefaults[T]
+	loader       func(ctx context.Context, key string) (T, error)
+	instrumenter instrumenter.Instrumenter
+	audit        *Audit
+	version      int
+	migrations   map[int]MigrationFunc
+	ttlGuard     *TTLGuard
+
+	backendState
+}
+
+func newMemcachedCache[T any](prefix string, opts ...CacheOption) (CacheInstance[T], error) {
+	opt := newCacheOptions(opts...)
+
+	con, err := newMemcachedClient(opt.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPrefix := opt.KeyPrefix
+	if keyPrefix != "" {
+		keyPrefix += ":"
+	}
+
+	return &memcachedCache[T]{
+		con:          con,
+		prefix:       keyPrefix + prefix + ":",
+		items:        newItemDefaults[T](opt),
+		loader:       newLoader[T](opt),
+		instrumenter: opt.Instrumenter,
+		audit:        opt.Audit,
+		version:      opt.SchemaVersion,
+		migrations:   opt.Migrations,
+		ttlGuard:     opt.TTLGuard,
+	}, nil
+}
+
+// key returns memcached key. Keys that are too long or contain characters not
+// allowed by memcached are hashed.
+func (c *memcachedCache[T]) key(key string) string {
+	k := c.prefix + key
+	if len(k) > 250 || strings.IndexFunc(k, func(r rune) bool { return r <= ' ' || r == 0x7f }) >= 0 {
+		h := sha256.Sum256([]byte(k))
+		return c.prefix + "#" + hex.EncodeToString(h[:])
+	}
+	return k
+}
+
+// expiration returns memcached item expiration for the TTL.
+func memcachedExpiration(ttl time.Duration) int32 {
+	if ttl <= 0 {
+		return 0
+	}
+	if ttl > memcachedMaxRelativeTTL {
+		return int32(time.Now().Add(ttl).Unix())
+	}
+	// Memcached has second precision so round up to not expire early.
+	return int32((ttl + time.Second - 1) / time.Second)
+}
+
+func (c *memcachedCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, true, opts...)
+}
+
+// peek returns value from cache without calling the loader.
+func (c *memcachedCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, false, opts...)
+}
+
+// read returns value from cache. Missing value is loaded and stored if load is
+// true, otherwise ErrKeyNotFound error is returned.
+func (c *memcachedCache[T]) read(ctx context.Context, key string, load bool, opts ...ItemOption[T]) (T, error) {
+	val := new(T)
+	if c.closed.Load() {
+		return *val, ErrCacheClosed
+	}
+	opt := c.items.resolve(opts...)
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	item, err := c.con.Get(c.key(key))
+	if err == memcache.ErrCacheMiss {
+		if !load {
+			finish(nil)
+			return *val, ErrKeyNotFound{Key: key}
+		}
+		v, err := loadAndStore(ctx, c.loader, c.Set, key, opt, opts...)
+		finish(err)
+		return v, err
+	}
+	if err != nil {
+		finish(err)
+		return *val, err
+	}
+	if err := decodeValue(c.migrations, c.version, opt, item.Value, val); err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return *val, err
+	}
+	finish(nil)
+	return *val, nil
+}
+
+// Exists checks if value exists in cache.
+//
+// Memcached protocol has no command to check key presence so value is fetched
+// but not decoded.
+func (c *memcachedCache[T]) Exists(ctx context.Context, key string) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	_, err := c.con.Get(c.key(key))
+	if err == memcache.ErrCacheMiss {
+		finish(nil)
+		return false, nil
+	}
+	finish(err)
+	return err == nil, err
+}
+
+func (c *memcachedCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	val := new(T)
+	if c.closed.Load() {
+		return *val, ErrCacheClosed
+	}
+
+	finishG := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	finishD := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+key)
+
+	item, err := c.con.Get(c.key(key))
+	if err == nil {
+		// Only one of concurrent callers succeeds to delete the value.
+		err = c.con.Delete(c.key(key))
+	}
+	if err == memcache.ErrCacheMiss {
+		finishD(nil)
+		finishG(nil)
+		return *val, ErrKeyNotFound{Key: key}
+	}
+	if err != nil {
+		finishD(err)
+		finishG(err)
+		return *val, err
+	}
+	if err := decodeValue(c.migrations, c.version, c.items.resolve(), item.Value, val); err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finishD(err)
+		finishG(err)
+		return *val, err
+	}
+	finishD(nil)
+	finishG(nil)
+	return *val, nil
+}
+
+// put stores value in memcached. If nx is true, value is stored using add
+// command only if key does not exist. Returns true if value was stored.
+func (c *memcachedCache[T]) put(ctx context.Context, key string, value T, nx bool, opts ...ItemOption[T]) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	opt := c.items.resolve(opts...)
+	ttl, err := c.ttlGuard.expiration(ctx, key, opt.TTL)
+	if err != nil {
+		finish(err)
+		return false, err
+	}
+	buf, err := encodeValue(ctx, c.audit, c.version, opt, value)
+	if err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return false, err
+	}
+	item := &memcache.Item{
+		Key:        c.key(key),
+		Value:      buf,
+		Expiration: memcachedExpiration(ttl),
+	}
+	if nx {
+		err = c.con.Add(item)
+		if err == memcache.ErrNotStored {
+			finish(nil)
+			return false, nil
+		}
+	} else {
+		err = c.con.Set(item)
+	}
+	if err != nil {
+		finish(err)
+		return false, err
+	}
+	finish(nil)
+	return true, nil
+}
+
+func (c *memcachedCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	_, err := c.put(ctx, key, value, false, opts...)
+	return err
+}
+
+// SetNX stores value only if key does not exist. Returns true if value was stored.
+func (c *memcachedCache[T]) SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error) {
+	return c.put(ctx, key, value, true, opts...)
+}
+
+func (c *memcachedCache[T]) Delete(ctx context.Context, key string) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+key)
+
+	if err := c.con.Delete(c.key(key)); err != nil && err != memcache.ErrCacheMiss {
+		finish(err)
+		return err
+	}
+	finish(nil)
+	return nil
+}
+
+func (c *memcachedCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	return c.items.resolve(opts...)
+}
+
+func (c *memcachedCache[T]) Ping(ctx context.Context) error {
+	if c.closed.Load() {
+		return nil
+	}
+	return c.con.Ping()
+}
+
+func (c *memcachedCache[T]) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	return c.con.Close()
+}
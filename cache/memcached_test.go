@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMemcachedItem struct {
+	value []byte
+	flags string
+	exp   int64
+}
+
+// fakeMemcached implements minimal subset of memcached text protocol.
+type fakeMemcached struct {
+	lock  sync.Mutex
+	l     net.Listener
+	items map[string]fakeMemcachedItem
+}
+
+func newFakeMemcached(t *testing.T) *fakeMemcached {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeMemcached{
+		l:     l,
+		items: make(map[string]fakeMemcachedItem),
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	t.Cleanup(func() { _ = l.Close() })
+	return s
+}
+
+func (s *fakeMemcached) Addr() string {
+	return s.l.Addr().String()
+}
+
+func (s *fakeMemcached) item(key string) (fakeMemcachedItem, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	i, ok := s.items[key]
+	return i, ok
+}
+
+func (s *fakeMemcached) serve(c net.Conn) {
+	defer c.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(c), bufio.NewWriter(c))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		s.lock.Lock()
+		switch f[0] {
+		case "get", "gets":
+			for _, k := range f[1:] {
+				if i, ok := s.items[k]; ok {
+					fmt.Fprintf(rw, "VALUE %s %s %d 1\r\n%s\r\n", k, i.flags, len(i.value), i.value)
+				}
+			}
+			fmt.Fprint(rw, "END\r\n")
+		case "set", "add":
+			n, _ := strconv.Atoi(f[4])
+			exp, _ := strconv.ParseInt(f[3], 10, 64)
+			buf := make([]byte, n+2)
+			if _, err := io.ReadFull(rw, buf); err != nil {
+				s.lock.Unlock()
+				return
+			}
+			if _, ok := s.items[f[1]]; ok && f[0] == "add" {
+				fmt.Fprint(rw, "NOT_STORED\r\n")
+				break
+			}
+			s.items[f[1]] = fakeMemcachedItem{value: buf[:n], flags: f[2], exp: exp}
+			fmt.Fprint(rw, "STORED\r\n")
+		case "delete":
+			if _, ok := s.items[f[1]]; ok {
+				delete(s.items, f[1])
+				fmt.Fprint(rw, "DELETED\r\n")
+			} else {
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+			}
+		case "touch":
+			if i, ok := s.items[f[1]]; ok {
+				i.exp, _ = strconv.ParseInt(f[2], 10, 64)
+				s.items[f[1]] = i
+				fmt.Fprint(rw, "TOUCHED\r\n")
+			} else {
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+			}
+		case "version":
+			fmt.Fprint(rw, "VERSION 1.6.0\r\n")
+		default:
+			fmt.Fprint(rw, "ERROR\r\n")
+		}
+		s.lock.Unlock()
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func newTestMemcachedCache(t *testing.T, opts ...CacheOption) (*Cache, *fakeMemcached) {
+	s := newFakeMemcached(t)
+	c := New(append([]CacheOption{CacheType(MemcachedCache), ConnectionString("memcached://" + s.Addr())}, opts...)...)
+	require.NoError(t, c.Start(context.TODO()))
+	t.Cleanup(c.Close)
+	return c, s
+}
+
+func TestParseMemcachedURL(t *testing.T) {
+	o, err := ParseMemcachedURL("memcached://host1,host2:11212?timeout=1s&max_idle_conns=5")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"host1:11211", "host2:11212"}, o.Servers)
+	assert.Equal(t, time.Second, o.Timeout)
+	assert.Equal(t, 5, o.MaxIdleConns)
+
+	_, err = ParseMemcachedURL("memcached://")
+	assert.Error(t, err)
+
+	_, err = ParseMemcachedURL("host1?timeout=abc")
+	assert.Error(t, err)
+
+	assert.Error(t, ValidateConnectionString(MemcachedCache, ""))
+	assert.NoError(t, ValidateConnectionString(MemcachedCache, "127.0.0.1:11211"))
+}
+
+func TestMemcachedCacheGetSet(t *testing.T) {
+	c, s := newTestMemcachedCache(t, KeyPrefix("prefix"))
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	val, err := i.Get(context.TODO(), "key1", DefaultValue[string]{Value: "none"})
+	require.NoError(t, err)
+	assert.Equal(t, "none", val)
+
+	require.NoError(t, i.Set(context.TODO(), "key1", "value"))
+
+	_, ok := s.item("prefix:test:key1")
+	assert.True(t, ok)
+
+	val, err = i.Get(context.TODO(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "value", val)
+
+	require.NoError(t, i.Delete(context.TODO(), "key1"))
+	require.NoError(t, i.Delete(context.TODO(), "key1"))
+
+	val, err = i.Get(context.TODO(), "key1")
+	require.NoError(t, err)
+	assert.Empty(t, val)
+}
+
+func TestMemcachedCachePop(t *testing.T) {
+	c, _ := newTestMemcachedCache(t)
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(context.TODO(), "key2", "value"))
+
+	val, err := i.Pop(context.TODO(), "key2")
+	require.NoError(t, err)
+	assert.Equal(t, "value", val)
+
+	_, err = i.Pop(context.TODO(), "key2")
+	assert.ErrorIs(t, err, ErrKeyNotFound{Key: "key2"})
+}
+
+func TestMemcachedCacheTTL(t *testing.T) {
+	c, s := newTestMemcachedCache(t)
+
+	i, err := Create[string](c, "test", DefaultTTL(1500*time.Millisecond))
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(context.TODO(), "short", "value"))
+	item, ok := s.item("test:short")
+	require.True(t, ok)
+	assert.Equal(t, int64(2), item.exp)
+
+	require.NoError(t, i.Set(context.TODO(), "long", "value", TTL[string](60*24*time.Hour)))
+	item, ok = s.item("test:long")
+	require.True(t, ok)
+	assert.Greater(t, item.exp, time.Now().Unix())
+}
+
+func TestMemcachedCacheLoader(t *testing.T) {
+	c, s := newTestMemcachedCache(t)
+
+	calls := 0
+	i, err := Create[string](c, "test", Loader[string](func(ctx context.Context, key string) (string, error) {
+		calls++
+		return "loaded-" + key, nil
+	}))
+	require.NoError(t, err)
+
+	val, err := i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded-key", val)
+
+	val, err = i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded-key", val)
+	assert.Equal(t, 1, calls)
+
+	_, ok := s.item("test:key")
+	assert.True(t, ok)
+}
+
+func TestMemcachedCacheLongKey(t *testing.T) {
+	c, _ := newTestMemcachedCache(t)
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	key := "key with spaces " + strings.Repeat("x", 300)
+	require.NoError(t, i.Set(context.TODO(), key, "value"))
+
+	val, err := i.Get(context.TODO(), key)
+	require.NoError(t, err)
+	assert.Equal(t, "value", val)
+}
@@ -6,8 +6,9 @@ package cache
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"azugo.io/core/instrumenter"
@@ -17,56 +18,64 @@ import (
 
 type memoryCache[T any] struct {
 	cache        *ristretto.Cache
-	ttl          time.Duration
+	closed       atomic.Bool
+	items        itemDefaults[T]
 	lock         sync.Mutex
-	loader       func(ctx context.Context, key string) (interface{}, error)
+	loader       func(ctx context.Context, key string) (T, error)
 	instrumenter instrumenter.Instrumenter
+	ttlGuard     *TTLGuard
+	cost         *MemoryCost
+	syncWrites   bool
+	tags         tagIndex
+
+	backendState
+}
+
+// memoryEntry is a value stored in memory cache.
+type memoryEntry[T any] struct {
+	key      string
+	value    T
+	storedAt time.Time
+	tags     []string
 }
 
 
 func newMemoryCache[T any](opts ...CacheOption) (CacheInstance[T], error) {
 	opt := newCacheOptions(opts...)
-	c, err := ristretto.NewCache(&ristretto.Config{
-		NumCounters: 1000, // number of keys to track frequency of (10k).
-		MaxCost:     1 << 30, // maximum cost of cache (1GB).
-		BufferItems: 64, // number of keys per Get buffer.
-	})
-	if err != nil {
-		return nil, err
-	}
 
-	loader := opt.Loader
-	if loader != nil {
-		loader = func(ctx context.Context, key string) (interface{}, error) {
-			finish := opt.Instrumenter.Observe(ctx, InstrumentationCacheLoader, key)
-			v, err := opt.Loader(ctx, key)
-			finish(err)
-			return v, err
-		}
-	}
-	return &memoryCache[T]{
-		cache:        c,
-		ttl:          opt.TTL,
+	loader := newLoader[T](opt)
+	mc := &memoryCache[T]{
+		items:        newItemDefaults[T](opt),
 		loader:       loader,
 		instrumenter: opt.Instrumenter,
-	}, nil
-}
+		ttlGuard:     opt.TTLGuard,
+		cost:         opt.MemoryCost,
+		syncWrites:   opt.MemorySyncWrites,
+	}
 
-func (c *memoryCache[T]) getLoader(ctx context.Context, opts ...ItemOption[T]) func(string) (interface{}, error) {
-	return func(key string) (interface{}, error) {
-		v, err := c.loader(ctx, key)
-		if err != nil {
-			return nil, err
-		}
-		vv, ok := v.(T)
-		if !ok {
-			return nil, fmt.Errorf("invalid value from loader: %v", v)
-		}
-		return vv, nil
+	conf := opt.MemoryCost.ristrettoConfig()
+	conf.OnEvict = mc.onEvict
+	c, err := ristretto.NewCache(conf)
+	if err != nil {
+		return nil, err
 	}
+	mc.cache = c
+
+	return mc, nil
 }
 
 func (c *memoryCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, true, opts...)
+}
+
+// peek returns value from cache without calling the loader.
+func (c *memoryCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, false, opts...)
+}
+
+// read returns value from cache. Missing value is loaded and stored if load is
+// true, otherwise ErrKeyNotFound error is returned.
+func (c *memoryCache[T]) read(ctx context.Context, key string, load bool, opts ...ItemOption[T]) (T, error) {
 	var val T
 	if c.cache == nil {
 		return val, ErrCacheClosed
@@ -77,51 +86,82 @@ func (c *memoryCache[T]) Get(ctx context.Context, key string, opts ...ItemOption
 	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, key)
 	if value, found = c.cache.Get(key); found {
 		finish(nil)
-		return value.(T), nil
+		return value.(memoryEntry[T]).value, nil
+	}
+	opt := c.items.resolve(opts...)
+	if !load {
+		finish(nil)
+		return val, ErrKeyNotFound{Key: key}
 	}
 	if c.loader != nil {
-		var err error
-		if value, err = c.getWithLoader(ctx, key, c.getLoader(ctx, opts...)); err != nil {
+		v, err := c.getWithLoader(ctx, key, opt.TTL)
+		if err != nil {
 			return val, err
 		}
 		finish(nil)
-		return value.(T), nil
+		return v, nil
 	}
 	finish(nil)
-	return val, nil
+	return opt.DefaultValue, nil
 }
 
-func (c *memoryCache[T]) set(key string, v interface{}, ttl time.Duration) error {
+func (c *memoryCache[T]) Exists(ctx context.Context, key string) (bool, error) {
+	if c.cache == nil {
+		return false, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, key)
+	_, found := c.cache.Get(key)
+	finish(nil)
+	return found, nil
+}
+
+func (c *memoryCache[T]) set(ctx context.Context, key string, value T, ttl time.Duration, tags ...string) error {
 	if c.cache == nil {
 		return ErrCacheClosed
 	}
-	if ttl == 0 {
-		success := c.cache.Set(key, v, 1)
-		if !success {
-			return ErrCacheClosed
-		}
-		return nil
+	ttl, err := c.ttlGuard.expiration(ctx, key, ttl)
+	if err != nil {
+		return err
 	}
-	success := c.cache.SetWithTTL(key, v, 1, ttl)
-	if !success {
-		return ErrCacheClosed
+	v := memoryEntry[T]{
+		key:      key,
+		value:    value,
+		storedAt: time.Now(),
+		tags:     tags,
 	}
+	if err := c.store(key, v, ttl); err != nil {
+		return err
+	}
+	if c.syncWrites {
+		// Sets of new keys are applied asynchronously from the set buffer.
+		c.cache.Wait()
+	}
+	c.tags.add(key, tags)
 	return nil
 }
 
-func (c *memoryCache[T]) getWithLoader(ctx context.Context, key string, loader func(string) (interface{}, error)) (interface{}, error) {
-	v, err := loader(key)
+func (c *memoryCache[T]) getWithLoader(ctx context.Context, key string, ttl time.Duration) (T, error) {
+	v, err := c.loader(ctx, key)
 	if err != nil {
-		return nil, err
+		return v, err
+	}
+	err = c.set(ctx, key, v, ttl)
+	if errors.Is(err, ErrNotAdmitted) {
+		// Loaded value is returned even if it is not cached.
+		err = nil
 	}
-	err = c.set(key, v, c.ttl)
 	return v, err
 }
 
 func (c *memoryCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	v, _, err := c.PopWithMetadata(ctx, key)
+	return v, err
+}
+
+func (c *memoryCache[T]) PopWithMetadata(ctx context.Context, key string) (T, ItemMetadata, error) {
 	var val T
 	if c.cache == nil {
-		return val, ErrCacheClosed
+		return val, ItemMetadata{}, ErrCacheClosed
 	}
 
 	c.lock.Lock()
@@ -132,23 +172,26 @@ func (c *memoryCache[T]) Pop(ctx context.Context, key string) (T, error) {
 	i, exists := c.cache.Get(key)
 	if !exists {
 		finish(nil)
-		return val, ErrKeyNotFound{Key: key}
+		return val, ItemMetadata{}, ErrKeyNotFound{Key: key}
 	}
+	ttl, _ := c.cache.GetTTL(key)
 	c.cache.Del(key)
 	finish(nil)
-	return i.(T), nil
+	e := i.(memoryEntry[T])
+	c.tags.remove(key, e.tags)
+	return e.value, ItemMetadata{
+		TTL:      ttl,
+		StoredAt: e.storedAt,
+	}, nil
 }
 
 func (c *memoryCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
-	opt := newItemOptions(opts...)
-	ttl := opt.TTL
-	if ttl == 0 {
-		ttl = c.ttl
-	}
+	opt := c.items.resolve(opts...)
 	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, key)
-	defer finish(nil)
 
-	return c.set(key, value, ttl)
+	err := c.set(ctx, key, value, opt.TTL, opt.Tags...)
+	finish(err)
+	return err
 }
 
 func (c *memoryCache[T]) Delete(ctx context.Context, key string) error {
@@ -159,14 +202,23 @@ func (c *memoryCache[T]) Delete(ctx context.Context, key string) error {
 	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, key)
 	defer finish(nil)
 
+	if v, ok := c.cache.Get(key); ok {
+		c.tags.remove(key, v.(memoryEntry[T]).tags)
+	}
 	c.cache.Del(key)
 	return nil
 }
 
+func (c *memoryCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	return c.items.resolve(opts...)
+}
+
 func (c *memoryCache[T]) Close() {
 	if c.cache == nil {
 		return
 	}
+	c.closed.Store(true)
 	c.cache.Clear()
 	c.cache = nil
+	c.tags.reset()
 }
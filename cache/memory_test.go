@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"strconv"
 	"testing"
 	"time"
 
@@ -29,6 +30,27 @@ func TestMemoryCacheGetSet(t *testing.T) {
 	assert.Equal(t, "value", val)
 }
 
+func TestMemoryCacheConsecutiveSets(t *testing.T) {
+	c := New(CacheType(MemoryCache), MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	// Value is readable right after Set and last write of a new key wins
+	// when writes are synchronous.
+	for n := 0; n < 100; n++ {
+		key := "key" + strconv.Itoa(n)
+		require.NoError(t, i.Set(context.TODO(), key, "first"))
+		require.NoError(t, i.Set(context.TODO(), key, "second"))
+
+		val, err := i.Get(context.TODO(), key)
+		require.NoError(t, err)
+		require.Equal(t, "second", val)
+	}
+}
+
 func TestMemoryCachePop(t *testing.T) {
 	c := New(CacheType(MemoryCache))
 	err := c.Start(context.TODO())
@@ -109,3 +131,96 @@ func TestMemoryCacheItemExpire(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, val)
 }
+
+func TestMemoryCacheItemDefaults(t *testing.T) {
+	c := New(CacheType(MemoryCache), DefaultTTL(time.Hour))
+	err := c.Start(context.TODO())
+	require.NoError(t, err)
+	defer c.Close()
+
+	i, err := Create[string](c, "test", ItemDefaults[string]{DefaultValue[string]{Value: "none"}}, ItemDefaults[int]{TTL[int](time.Second)})
+	require.NoError(t, err)
+
+	val, err := i.Get(context.TODO(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "none", val)
+
+	val, err = i.Get(context.TODO(), "key", DefaultValue[string]{Value: "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, "other", val)
+
+	opt := ResolveItemOptions(i)
+	assert.Equal(t, time.Hour, opt.TTL)
+	assert.Equal(t, "none", opt.DefaultValue)
+
+	opt = ResolveItemOptions[string](i, TTL[string](time.Minute))
+	assert.Equal(t, time.Minute, opt.TTL)
+}
+
+func TestMemoryCacheReadOnly(t *testing.T) {
+	c := New(CacheType(MemoryCache))
+	err := c.Start(context.TODO())
+	require.NoError(t, err)
+	defer c.Close()
+
+	i, err := Create[string](c, "test", WithReadOnly())
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, i.Set(context.TODO(), "key", "value"), ErrReadOnly)
+	assert.ErrorIs(t, i.Delete(context.TODO(), "key"), ErrReadOnly)
+	_, err = i.Pop(context.TODO(), "key")
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	var logged []string
+	i, err = Create[string](c, "silent", ReadOnly{Silent: true, Log: func(_ context.Context, op, key string) {
+		logged = append(logged, op+":"+key)
+	}})
+	require.NoError(t, err)
+
+	assert.NoError(t, i.Set(context.TODO(), "key", "value"))
+	assert.Equal(t, []string{InstrumentationCacheSet + ":key"}, logged)
+
+	_, err = Create[string](c, "loader", WithReadOnly(), Loader[string](func(ctx context.Context, key string) (string, error) {
+		return "", nil
+	}))
+	assert.Error(t, err)
+}
+
+func TestMemoryCachePopWithMetadata(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	before := time.Now()
+	require.NoError(t, i.Set(context.TODO(), "token", "value", TTL[string](time.Minute)))
+
+	v, meta, err := PopWithMetadata(context.TODO(), i, "token")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	assert.Greater(t, meta.TTL, 50*time.Second)
+	assert.LessOrEqual(t, meta.TTL, time.Minute)
+	assert.False(t, meta.StoredAt.Before(before))
+
+	_, _, err = PopWithMetadata(context.TODO(), i, "token")
+	assert.ErrorIs(t, err, ErrKeyNotFound{Key: "token"})
+}
+
+func TestLoaderTypeMismatch(t *testing.T) {
+	c := New(MemoryCache, Loader[int](func(ctx context.Context, key string) (int, error) {
+		return 42, nil
+	}))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	_, err := Create[string](c, "string")
+	assert.ErrorContains(t, err, "cache instance of type string")
+
+	i, err := Create[int](c, "int")
+	require.NoError(t, err)
+	v, err := i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+}
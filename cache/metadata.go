@@ -0,0 +1,46 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// ItemMetadata is a metadata of the cached item.
+type ItemMetadata struct {
+	// TTL is a remaining time the item had left in cache. Zero means no expiration.
+	TTL time.Duration
+	// StoredAt is a time when item was stored in cache. Zero if not known.
+	StoredAt time.Time
+}
+
+// ExpiresAt returns time when item would have expired or zero time if item has no expiration.
+func (m ItemMetadata) ExpiresAt(now time.Time) time.Time {
+	if m.TTL == 0 {
+		return time.Time{}
+	}
+	return now.Add(m.TTL)
+}
+
+// CacheInstanceMetadataPopper represents a cache instance method that pops value
+// together with its metadata.
+type CacheInstanceMetadataPopper[T any] interface {
+	// PopWithMetadata returns value with its metadata from the cache and deletes it.
+	// If value is not found, it will return ErrKeyNotFound error.
+	PopWithMetadata(ctx context.Context, key string) (T, ItemMetadata, error)
+}
+
+// PopWithMetadata returns value with its remaining TTL and time when it was stored from
+// the cache instance and deletes it. If value is not found, it will return ErrKeyNotFound error.
+//
+// If cache instance does not support metadata, value is popped with empty metadata.
+func PopWithMetadata[T any](ctx context.Context, instance CacheInstance[T], key string) (T, ItemMetadata, error) {
+	if p, ok := instance.(CacheInstanceMetadataPopper[T]); ok {
+		return p.PopWithMetadata(ctx, key)
+	}
+	v, err := instance.Pop(ctx, key)
+	return v, ItemMetadata{}, err
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"fmt"
+)
+
+// MigrationFunc upgrades encoded value payload from one schema version to the next one.
+type MigrationFunc func(data []byte) ([]byte, error)
+
+// SchemaVersion is a current schema version of values stored in the cache instance.
+//
+// Values are stored in remote cache backends together with their schema version and
+// older values are upgraded on read using registered migrations.
+// Values stored without envelope are considered to be version 0.
+type SchemaVersion int
+
+func (v SchemaVersion) applyCache(c *cacheOptions) {
+	c.SchemaVersion = int(v)
+}
+
+// Migration upgrades values stored with schema version From to version From+1.
+type Migration struct {
+	From    int
+	Migrate MigrationFunc
+}
+
+func (m Migration) applyCache(c *cacheOptions) {
+	if c.Migrations == nil {
+		c.Migrations = make(map[int]MigrationFunc)
+	}
+	c.Migrations[m.From] = m.Migrate
+}
+
+// WithMigration returns option that registers migration of values stored with schema
+// version from to the version from+1.
+func WithMigration(from int, fn MigrationFunc) Migration {
+	return Migration{
+		From:    from,
+		Migrate: fn,
+	}
+}
+
+// migrate upgrades payload from the version to the current schema version.
+func migrate(migrations map[int]MigrationFunc, version, current int, payload []byte) ([]byte, error) {
+	for v := version; v < current; v++ {
+		fn, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration from schema version %d", v)
+		}
+		var err error
+		if payload, err = fn(payload); err != nil {
+			return nil, fmt.Errorf("failed to migrate from schema version %d: %w", v, err)
+		}
+	}
+	return payload, nil
+}
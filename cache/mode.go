@@ -0,0 +1,343 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Mode is a runtime mode of the cache instance that can be switched while the
+// service is running, for example during incidents.
+type Mode string
+
+const (
+	// ModeNormal is a default mode of the cache instance.
+	ModeNormal Mode = "normal"
+	// ModeBypass always loads values using the loader without reading cached
+	// values. Cache instances without loader behave as if all keys are missing.
+	// Writes are still applied so that cache is up to date when switched back.
+	ModeBypass Mode = "bypass"
+	// ModeFreeze serves whatever is cached and never expires it. Values read
+	// while instance is frozen are kept in memory of the service and served
+	// even after they expire or are changed by other service replicas.
+	ModeFreeze Mode = "freeze"
+	// ModeReadOnly rejects all write operations with ErrReadOnly. Values loaded
+	// by the loader are returned without storing them in cache.
+	ModeReadOnly Mode = "read-only"
+)
+
+// frozenMaxEntries is a maximum number of values kept in memory of the frozen
+// cache instance.
+const frozenMaxEntries = 10000
+
+// ParseMode parses cache instance mode. Empty string is parsed as ModeNormal.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case "", ModeNormal:
+		return ModeNormal, nil
+	case ModeBypass, ModeFreeze, ModeReadOnly:
+		return m, nil
+	default:
+		return ModeNormal, fmt.Errorf("unknown cache mode %q", s)
+	}
+}
+
+// ModeSwitch allows to change mode of the cache instances with Cache.SetMode
+// while the service is running.
+type ModeSwitch struct {
+	// Mode is an initial mode of the cache instance if mode is not yet set.
+	Mode Mode
+}
+
+func (m ModeSwitch) applyCache(c *cacheOptions) {
+	c.ModeSwitch = &m
+}
+
+type modeState struct {
+	mode Mode
+}
+
+type modeSwitch struct {
+	state atomic.Pointer[modeState]
+}
+
+func (s *modeSwitch) mode() (Mode, *modeState) {
+	st := s.state.Load()
+	if st == nil {
+		return ModeNormal, nil
+	}
+	return st.mode, st
+}
+
+// modeSwitch returns mode switch of the cache instance with the name.
+func (c *Cache) modeSwitch(name string) *modeSwitch {
+	c.modeLock.Lock()
+	defer c.modeLock.Unlock()
+
+	if c.modes == nil {
+		c.modes = make(map[string]*modeSwitch)
+	}
+	s, ok := c.modes[name]
+	if !ok {
+		s = &modeSwitch{}
+		c.modes[name] = s
+	}
+	return s
+}
+
+// SetMode changes mode of the cache instance with the name in this service
+// replica. Mode can be set before the cache instance is created.
+//
+// Only cache instances created with ModeSwitch option are affected.
+func (c *Cache) SetMode(name string, mode Mode) error {
+	mode, err := ParseMode(string(mode))
+	if err != nil {
+		return err
+	}
+	c.modeSwitch(name).state.Store(&modeState{mode: mode})
+	return nil
+}
+
+// Mode returns mode of the cache instance with the name.
+func (c *Cache) Mode(name string) Mode {
+	c.modeLock.Lock()
+	s, ok := c.modes[name]
+	c.modeLock.Unlock()
+
+	if !ok {
+		return ModeNormal
+	}
+	m, _ := s.mode()
+	return m
+}
+
+// Modes returns modes of the cache instances that are not in normal mode.
+func (c *Cache) Modes() map[string]Mode {
+	c.modeLock.Lock()
+	defer c.modeLock.Unlock()
+
+	modes := make(map[string]Mode)
+	for name, s := range c.modes {
+		if m, _ := s.mode(); m != ModeNormal {
+			modes[name] = m
+		}
+	}
+	return modes
+}
+
+type modeCache[T any] struct {
+	CacheInstance[T]
+	mode   *modeSwitch
+	loader func(ctx context.Context, key string) (T, error)
+
+	lock     sync.Mutex
+	frozen   map[string]T
+	frozenIn atomic.Pointer[modeState]
+}
+
+func newModeCache[T any](cache *Cache, c CacheInstance[T], name string, opts ...CacheOption) CacheInstance[T] {
+	opt := newCacheOptions(opts...)
+
+	s := cache.modeSwitch(name)
+	if len(opt.ModeSwitch.Mode) != 0 {
+		s.state.CompareAndSwap(nil, &modeState{mode: opt.ModeSwitch.Mode})
+	}
+	return &modeCache[T]{
+		CacheInstance: c,
+		mode:          s,
+		loader:        newLoader[T](opt),
+	}
+}
+
+// current returns current mode of the cache instance and releases values
+// kept in memory if instance is no longer frozen.
+func (c *modeCache[T]) current() (Mode, *modeState) {
+	m, st := c.mode.mode()
+
+	if f := c.frozenIn.Load(); f != nil && f != st {
+		c.lock.Lock()
+		if c.frozenIn.CompareAndSwap(f, nil) {
+			c.frozen = nil
+		}
+		c.lock.Unlock()
+	}
+	return m, st
+}
+
+func (c *modeCache[T]) lookupFrozen(st *modeState, key string) (T, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.frozenIn.Load() != st {
+		var val T
+		return val, false
+	}
+	v, ok := c.frozen[key]
+	return v, ok
+}
+
+func (c *modeCache[T]) freeze(st *modeState, key string, value T) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.frozenIn.Load() != st {
+		c.frozen = make(map[string]T)
+		c.frozenIn.Store(st)
+	}
+	if _, ok := c.frozen[key]; !ok && len(c.frozen) >= frozenMaxEntries {
+		return
+	}
+	c.frozen[key] = value
+}
+
+func (c *modeCache[T]) forget(keys ...string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, key := range keys {
+		delete(c.frozen, key)
+	}
+}
+
+func (c *modeCache[T]) forgetAll() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.frozen != nil {
+		c.frozen = make(map[string]T)
+	}
+}
+
+func (c *modeCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	m, st := c.current()
+	switch m {
+	case ModeBypass:
+		if c.loader != nil {
+			return c.loader(ctx, key)
+		}
+		return c.itemOptions(opts...).DefaultValue, nil
+	case ModeFreeze:
+		if v, ok := c.lookupFrozen(st, key); ok {
+			return v, nil
+		}
+		v, err := c.CacheInstance.Get(ctx, key, opts...)
+		if err != nil {
+			return v, err
+		}
+		c.freeze(st, key, v)
+		return v, nil
+	case ModeReadOnly:
+		if c.loader == nil {
+			break
+		}
+		ok, err := c.CacheInstance.Exists(ctx, key)
+		if err != nil {
+			var val T
+			return val, err
+		}
+		if !ok {
+			return c.loader(ctx, key)
+		}
+	}
+	return c.CacheInstance.Get(ctx, key, opts...)
+}
+
+// peek returns value from cache without calling the loader. Values are not
+// read from cache in bypass mode.
+func (c *modeCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	m, st := c.current()
+	switch m {
+	case ModeBypass:
+		var val T
+		return val, ErrKeyNotFound{Key: key}
+	case ModeFreeze:
+		if v, ok := c.lookupFrozen(st, key); ok {
+			return v, nil
+		}
+		v, err := peek(ctx, c.CacheInstance, key, opts...)
+		if err != nil {
+			return v, err
+		}
+		c.freeze(st, key, v)
+		return v, nil
+	}
+	return peek(ctx, c.CacheInstance, key, opts...)
+}
+
+func (c *modeCache[T]) Exists(ctx context.Context, key string) (bool, error) {
+	m, st := c.current()
+	switch m {
+	case ModeBypass:
+		return false, nil
+	case ModeFreeze:
+		if _, ok := c.lookupFrozen(st, key); ok {
+			return true, nil
+		}
+	}
+	return c.CacheInstance.Exists(ctx, key)
+}
+
+func (c *modeCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	v, _, err := c.PopWithMetadata(ctx, key)
+	return v, err
+}
+
+func (c *modeCache[T]) PopWithMetadata(ctx context.Context, key string) (T, ItemMetadata, error) {
+	if m, _ := c.current(); m == ModeReadOnly {
+		var val T
+		return val, ItemMetadata{}, ErrReadOnly
+	}
+	v, meta, err := PopWithMetadata(ctx, c.CacheInstance, key)
+	c.forget(key)
+	return v, meta, err
+}
+
+func (c *modeCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	m, st := c.current()
+	if m == ModeReadOnly {
+		return ErrReadOnly
+	}
+	if err := c.CacheInstance.Set(ctx, key, value, opts...); err != nil {
+		return err
+	}
+	if m == ModeFreeze {
+		c.freeze(st, key, value)
+	}
+	return nil
+}
+
+func (c *modeCache[T]) Delete(ctx context.Context, key string) error {
+	if m, _ := c.current(); m == ModeReadOnly {
+		return ErrReadOnly
+	}
+	err := c.CacheInstance.Delete(ctx, key)
+	c.forget(key)
+	return err
+}
+
+func (c *modeCache[T]) Close() {
+	closeInstance(c.CacheInstance)
+}
+
+func (c *modeCache[T]) Ping(ctx context.Context) error {
+	if p, ok := c.CacheInstance.(CacheInstancePinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *modeCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	if r, ok := c.CacheInstance.(itemOptionsResolver[T]); ok {
+		return r.itemOptions(opts...)
+	}
+	return newItemOptions(opts...)
+}
+
+func (c *modeCache[T]) unwrap() CacheInstance[T] {
+	return c.CacheInstance
+}
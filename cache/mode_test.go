@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMode(t *testing.T) {
+	for s, want := range map[string]Mode{
+		"":          ModeNormal,
+		"normal":    ModeNormal,
+		"bypass":    ModeBypass,
+		"freeze":    ModeFreeze,
+		"read-only": ModeReadOnly,
+	} {
+		m, err := ParseMode(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, m, s)
+	}
+
+	_, err := ParseMode("disabled")
+	assert.Error(t, err)
+}
+
+func TestModeSwitch(t *testing.T) {
+	c := New(CacheType(MemoryCache), MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	var calls atomic.Int32
+	i, err := Create[string](c, "modes", ModeSwitch{}, Loader[string](func(_ context.Context, key string) (string, error) {
+		calls.Add(1)
+		return "loaded:" + key, nil
+	}))
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+
+	// Bypass always calls loader but still applies writes.
+	require.NoError(t, c.SetMode("modes", ModeBypass))
+	assert.Equal(t, ModeBypass, c.Mode("modes"))
+	assert.Equal(t, map[string]Mode{"modes": ModeBypass}, c.Modes())
+	v, err := i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded:key", v)
+	assert.Equal(t, int32(1), calls.Load())
+	require.NoError(t, i.Set(context.TODO(), "key", "updated"))
+
+	// Read-only rejects writes and does not store loaded values.
+	require.NoError(t, c.SetMode("modes", ModeReadOnly))
+	assert.ErrorIs(t, i.Set(context.TODO(), "key", "other"), ErrReadOnly)
+	assert.ErrorIs(t, i.Delete(context.TODO(), "key"), ErrReadOnly)
+	_, err = i.Pop(context.TODO(), "key")
+	assert.ErrorIs(t, err, ErrReadOnly)
+	_, err = SetNX(context.TODO(), i, "new", "value")
+	assert.ErrorIs(t, err, ErrReadOnly)
+	v, err = i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "updated", v)
+	v, err = i.Get(context.TODO(), "missing")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded:missing", v)
+	ok, err := i.Exists(context.TODO(), "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, c.SetMode("modes", ModeNormal))
+	assert.Empty(t, c.Modes())
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+
+	assert.Error(t, c.SetMode("modes", Mode("unknown")))
+}
+
+func testModeFreeze(t *testing.T, c *Cache) {
+	t.Helper()
+
+	i, err := Create[string](c, "frozen", ModeSwitch{Mode: ModeFreeze})
+	require.NoError(t, err)
+	assert.Equal(t, ModeFreeze, c.Mode("frozen"))
+
+	require.NoError(t, i.Set(context.TODO(), "key", "value", TTL[string](100*time.Millisecond)))
+	time.Sleep(150 * time.Millisecond)
+
+	// Value is served even after it has expired.
+	v, err := i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	ok, err := i.Exists(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, i.Delete(context.TODO(), "key"))
+	v, err = i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+
+	require.NoError(t, i.Set(context.TODO(), "key", "value", TTL[string](100*time.Millisecond)))
+	time.Sleep(150 * time.Millisecond)
+
+	// Values kept in memory are released when instance is no longer frozen.
+	require.NoError(t, c.SetMode("frozen", ModeNormal))
+	v, err = i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+}
+
+func TestMemoryModeFreeze(t *testing.T) {
+	c := New(CacheType(MemoryCache))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	testModeFreeze(t, c)
+}
+
+func TestRedisModeFreeze(t *testing.T) {
+	c, mr := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "frozen", ModeSwitch{Mode: ModeFreeze})
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(context.TODO(), "key", "value", TTL[string](time.Second)))
+	mr.FastForward(2 * time.Second)
+
+	v, err := i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	require.NoError(t, c.SetMode("frozen", ModeNormal))
+	v, err = i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+}
+
+func TestModeBypassWithoutLoader(t *testing.T) {
+	c := New(CacheType(MemoryCache), ModeSwitch{})
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "values")
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+
+	require.NoError(t, c.SetMode("values", ModeBypass))
+	v, err := i.Get(context.TODO(), "key", DefaultValue[string]{Value: "default"})
+	require.NoError(t, err)
+	assert.Equal(t, "default", v)
+	ok, err := i.Exists(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	// Keys are missing in bypass mode so they are not returned.
+	values, err := GetMulti(context.TODO(), i, []string{"key"})
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
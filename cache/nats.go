@@ -0,0 +1,420 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"github.com/nats-io/nats.go"
+)
+
+const natsEncodedKeyPrefix = "_b64."
+
+var (
+	natsKeyRe         = regexp.MustCompile(`^[-/_=a-zA-Z0-9]+(\.[-/_=a-zA-Z0-9]+)*$`)
+	natsBucketInvalid = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+)
+
+// NATSOptions are NATS JetStream key-value cache connection options.
+type NATSOptions struct {
+	// Servers are NATS server URLs.
+	Servers []string
+	// Username to authenticate with.
+	Username string
+	// Password to authenticate with.
+	Password string
+	// Token to authenticate with.
+	Token string
+	// Replicas is a number of bucket replicas when bucket is created.
+	Replicas int
+	// Storage is a bucket storage type when bucket is created.
+	Storage nats.StorageType
+}
+
+// ParseNATSURL parses NATS connection string.
+//
+// Multiple servers can be specified as comma separated list of hosts
+// (nats://host1:4222,host2:4222). Bucket options can be provided using
+// replicas and storage (file or memory) query parameters.
+func ParseNATSURL(v string) (*NATSOptions, error) {
+	scheme, rest, ok := strings.Cut(v, "://")
+	if !ok {
+		return nil, fmt.Errorf("nats: invalid URL: %s", v)
+	}
+	if scheme != "nats" && scheme != "tls" {
+		return nil, fmt.Errorf("nats: invalid URL scheme: %s", scheme)
+	}
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	authority, tail := rest[:end], rest[end:]
+	var userinfo string
+	if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+		userinfo, authority = authority[:i+1], authority[i+1:]
+	}
+
+	u, err := url.Parse(scheme + "://" + userinfo + "nats" + tail)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &NATSOptions{}
+	for _, h := range strings.Split(authority, ",") {
+		h = strings.TrimSpace(h)
+		if len(h) == 0 {
+			continue
+		}
+		o.Servers = append(o.Servers, scheme+"://"+h)
+	}
+	if len(o.Servers) == 0 {
+		return nil, errors.New("nats: no servers specified")
+	}
+	if u.User != nil {
+		if p, ok := u.User.Password(); ok {
+			o.Username, o.Password = u.User.Username(), p
+		} else {
+			o.Token = u.User.Username()
+		}
+	}
+
+	q := u.Query()
+	if r := q.Get("replicas"); len(r) != 0 {
+		if o.Replicas, err = strconv.Atoi(r); err != nil || o.Replicas < 0 {
+			return nil, fmt.Errorf("nats: invalid replicas value: %q", r)
+		}
+	}
+	switch s := q.Get("storage"); s {
+	case "", "file":
+		o.Storage = nats.FileStorage
+	case "memory":
+		o.Storage = nats.MemoryStorage
+	default:
+		return nil, fmt.Errorf("nats: invalid storage value: %q", s)
+	}
+	return o, nil
+}
+
+// natsBucketName returns valid key-value bucket name for the cache instance.
+func natsBucketName(keyPrefix, name string) string {
+	if len(keyPrefix) != 0 {
+		name = keyPrefix + "_" + name
+	}
+	return natsBucketInvalid.ReplaceAllString(name, "_")
+}
+
+type natsCache[T any] struct {
+	kv           nats.KeyValue
+	close        func()
+	ping         func() error
+	closed       atomic.Bool
+	prefix       string
+	items        itemDefaults[T]
+	loader       func(ctx context.Context, key string) (T, error)
+	instrumenter instrumenter.Instrumenter
+	audit        *Audit
+	version      int
+	migrations   map[int]MigrationFunc
+	ttlGuard     *TTLGuard
+	now          func() time.Time
+
+	backendState
+}
+
+func newNATSCache[T any](name string, opts ...CacheOption) (CacheInstance[T], error) {
+	opt := newCacheOptions(opts...)
+
+	conf, err := ParseNATSURL(opt.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	nopts := make([]nats.Option, 0, 2)
+	if len(opt.ConnectionPassword) != 0 {
+		// If password is provided override provided in connection string.
+		if len(conf.Username) != 0 {
+			conf.Password = opt.ConnectionPassword
+		} else {
+			conf.Token = opt.ConnectionPassword
+		}
+	}
+	if len(conf.Username) != 0 {
+		nopts = append(nopts, nats.UserInfo(conf.Username, conf.Password))
+	} else if len(conf.Token) != 0 {
+		nopts = append(nopts, nats.Token(conf.Token))
+	}
+
+	nc, err := nats.Connect(strings.Join(conf.Servers, ","), nopts...)
+	if err != nil {
+		return nil, err
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	bucket := natsBucketName(opt.KeyPrefix, name)
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		ttl := opt.TTL
+		if ttl < 0 {
+			ttl = 0
+		}
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:   bucket,
+			History:  1,
+			TTL:      ttl,
+			Storage:  conf.Storage,
+			Replicas: conf.Replicas,
+		})
+	}
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	c := newNATSKVCache[T](kv, opt)
+	c.close = nc.Close
+	c.ping = func() error {
+		_, err := nc.RTT()
+		return err
+	}
+	return c, nil
+}
+
+func newNATSKVCache[T any](kv nats.KeyValue, opt *cacheOptions) *natsCache[T] {
+	return &natsCache[T]{
+		kv:           kv,
+		close:        func() {},
+		ping:         func() error { return nil },
+		prefix:       kv.Bucket() + ":",
+		items:        newItemDefaults[T](opt),
+		loader:       newLoader[T](opt),
+		instrumenter: opt.Instrumenter,
+		audit:        opt.Audit,
+		version:      opt.SchemaVersion,
+		migrations:   opt.Migrations,
+		ttlGuard:     opt.TTLGuard,
+		now:          time.Now,
+	}
+}
+
+// key returns key-value store key. Keys containing characters not allowed
+// in key-value store are encoded.
+func (c *natsCache[T]) key(key string) string {
+	if natsKeyRe.MatchString(key) && !strings.HasPrefix(key, natsEncodedKeyPrefix) {
+		return key
+	}
+	return natsEncodedKeyPrefix + base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// encode prepends value with expiration time as bucket TTL applies to all keys.
+func (c *natsCache[T]) encode(ctx context.Context, opt *itemOptions[T], ttl time.Duration, value T) ([]byte, error) {
+	buf, err := encodeValue(ctx, c.audit, c.version, opt, value)
+	if err != nil {
+		return nil, err
+	}
+	var exp int64
+	if ttl > 0 {
+		exp = c.now().Add(ttl).UnixNano()
+	}
+	data := make([]byte, 8, 8+len(buf))
+	binary.BigEndian.PutUint64(data, uint64(exp))
+	return append(data, buf...), nil
+}
+
+// decode returns value payload or false if value has expired.
+func (c *natsCache[T]) decode(data []byte) ([]byte, bool, error) {
+	if len(data) < 8 {
+		return nil, false, errors.New("value too short")
+	}
+	if exp := int64(binary.BigEndian.Uint64(data)); exp != 0 && c.now().UnixNano() >= exp {
+		return nil, false, nil
+	}
+	return data[8:], true, nil
+}
+
+// get returns current entry payload. Returns nats.ErrKeyNotFound if key does not exist or has expired.
+func (c *natsCache[T]) get(key string) (nats.KeyValueEntry, []byte, error) {
+	entry, err := c.kv.Get(c.key(key))
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, ok, err := c.decode(entry.Value())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cache value: %w", err)
+	}
+	if !ok {
+		// Only remove expired revision to not delete concurrently updated value.
+		_ = c.kv.Delete(c.key(key), nats.LastRevision(entry.Revision()))
+		return nil, nil, nats.ErrKeyNotFound
+	}
+	return entry, payload, nil
+}
+
+func (c *natsCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, true, opts...)
+}
+
+// peek returns value from cache without calling the loader.
+func (c *natsCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, false, opts...)
+}
+
+// read returns value from cache. Missing value is loaded and stored if load is
+// true, otherwise ErrKeyNotFound error is returned.
+func (c *natsCache[T]) read(ctx context.Context, key string, load bool, opts ...ItemOption[T]) (T, error) {
+	val := new(T)
+	if c.closed.Load() {
+		return *val, ErrCacheClosed
+	}
+	opt := c.items.resolve(opts...)
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	_, payload, err := c.get(key)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		if !load {
+			finish(nil)
+			return *val, ErrKeyNotFound{Key: key}
+		}
+		v, err := loadAndStore(ctx, c.loader, c.Set, key, opt, opts...)
+		finish(err)
+		return v, err
+	}
+	if err != nil {
+		finish(err)
+		return *val, err
+	}
+	if err := decodeValue(c.migrations, c.version, opt, payload, val); err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return *val, err
+	}
+	finish(nil)
+	return *val, nil
+}
+
+func (c *natsCache[T]) Exists(ctx context.Context, key string) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	_, _, err := c.get(key)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		finish(nil)
+		return false, nil
+	}
+	finish(err)
+	return err == nil, err
+}
+
+func (c *natsCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	val := new(T)
+	if c.closed.Load() {
+		return *val, ErrCacheClosed
+	}
+
+	finishG := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	finishD := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+key)
+
+	entry, payload, err := c.get(key)
+	if err == nil {
+		// Only one of concurrent callers succeeds to delete the revision.
+		if derr := c.kv.Delete(c.key(key), nats.LastRevision(entry.Revision())); derr != nil {
+			err = nats.ErrKeyNotFound
+		}
+	}
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		finishD(nil)
+		finishG(nil)
+		return *val, ErrKeyNotFound{Key: key}
+	}
+	if err != nil {
+		finishD(err)
+		finishG(err)
+		return *val, err
+	}
+	if err := decodeValue(c.migrations, c.version, c.items.resolve(), payload, val); err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finishD(err)
+		finishG(err)
+		return *val, err
+	}
+	finishD(nil)
+	finishG(nil)
+	return *val, nil
+}
+
+func (c *natsCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
+
+	opt := c.items.resolve(opts...)
+	ttl, err := c.ttlGuard.expiration(ctx, key, opt.TTL)
+	if err != nil {
+		finish(err)
+		return err
+	}
+	buf, err := c.encode(ctx, opt, ttl, value)
+	if err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return err
+	}
+	if _, err := c.kv.Put(c.key(key), buf); err != nil {
+		finish(err)
+		return err
+	}
+	finish(nil)
+	return nil
+}
+
+func (c *natsCache[T]) Delete(ctx context.Context, key string) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+key)
+
+	if err := c.kv.Delete(c.key(key)); err != nil && !errors.Is(err, nats.ErrKeyNotFound) {
+		finish(err)
+		return err
+	}
+	finish(nil)
+	return nil
+}
+
+func (c *natsCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	return c.items.resolve(opts...)
+}
+
+func (c *natsCache[T]) Ping(ctx context.Context) error {
+	if c.closed.Load() {
+		return nil
+	}
+	return c.ping()
+}
+
+func (c *natsCache[T]) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	c.close()
+	return nil
+}
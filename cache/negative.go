@@ -0,0 +1,119 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+)
+
+const (
+	InstrumentationCacheNegative = "cache-negative"
+)
+
+// NegativeCache caches loader "not found" results of the cache instance.
+//
+// Loader reports missing value by returning ErrKeyNotFound. Until the not found
+// result expires, Get returns ErrKeyNotFound without calling the loader again.
+// Values set in the meantime are returned as usual as loader is called only for
+// missing keys.
+//
+// Not found results are kept in memory of the service so each service replica
+// calls the loader for the missing key at most once per TTL.
+type NegativeCache struct {
+	// TTL of the cached not found result. Defaults to 30 seconds.
+	TTL time.Duration
+	// MaxEntries is a maximum number of cached not found results. Defaults to 10000.
+	MaxEntries int
+}
+
+func (n NegativeCache) applyCache(c *cacheOptions) {
+	c.NegativeCache = &n
+}
+
+// isKeyNotFound returns true if err reports missing key.
+func isKeyNotFound(err error) bool {
+	var nf ErrKeyNotFound
+	return errors.As(err, &nf)
+}
+
+type negativeGuard struct {
+	conf         NegativeCache
+	instrumenter instrumenter.Instrumenter
+
+	lock    sync.Mutex
+	entries map[string]time.Time
+}
+
+func newNegativeGuard(conf NegativeCache, instr instrumenter.Instrumenter) *negativeGuard {
+	if conf.TTL <= 0 {
+		conf.TTL = 30 * time.Second
+	}
+	if conf.MaxEntries <= 0 {
+		conf.MaxEntries = 10000
+	}
+	return &negativeGuard{
+		conf:         conf,
+		instrumenter: instr,
+		entries:      make(map[string]time.Time),
+	}
+}
+
+// cached returns true if not found result for the key has not expired.
+func (g *negativeGuard) cached(key string, now time.Time) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	exp, ok := g.entries[key]
+	if ok && !now.Before(exp) {
+		delete(g.entries, key)
+		return false
+	}
+	return ok
+}
+
+// store caches not found result for the key.
+func (g *negativeGuard) store(key string, now time.Time) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if _, ok := g.entries[key]; !ok && len(g.entries) >= g.conf.MaxEntries {
+		for k, exp := range g.entries {
+			if !now.Before(exp) {
+				delete(g.entries, k)
+			}
+		}
+		// Evict any entry if all of them are still valid.
+		for k := range g.entries {
+			if len(g.entries) < g.conf.MaxEntries {
+				break
+			}
+			delete(g.entries, k)
+		}
+	}
+	g.entries[key] = now.Add(g.conf.TTL)
+}
+
+func wrapNegative[T any](g *negativeGuard, loader func(ctx context.Context, key string) (T, error)) func(ctx context.Context, key string) (T, error) {
+	return func(ctx context.Context, key string) (T, error) {
+		if g.cached(key, time.Now()) {
+			var val T
+			finish := g.instrumenter.Observe(ctx, InstrumentationCacheNegative, key)
+			finish(nil)
+			return val, ErrKeyNotFound{Key: key}
+		}
+
+		v, err := loader(ctx, key)
+		if isKeyNotFound(err) {
+			g.store(key, time.Now())
+			return v, ErrKeyNotFound{Key: key}
+		}
+		return v, err
+	}
+}
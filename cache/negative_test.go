@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testNegativeCache(t *testing.T, c *Cache) {
+	t.Helper()
+
+	var calls atomic.Int32
+	i, err := Create[string](c, "negative", NegativeCache{TTL: 100 * time.Millisecond}, Loader[string](func(_ context.Context, key string) (string, error) {
+		calls.Add(1)
+		if key == "missing" {
+			return "", ErrKeyNotFound{Key: key}
+		}
+		return "loaded:" + key, nil
+	}))
+	require.NoError(t, err)
+
+	for n := 0; n < 3; n++ {
+		_, err = i.Get(context.TODO(), "missing")
+		assert.ErrorAs(t, err, &ErrKeyNotFound{})
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	values, err := GetMulti(context.TODO(), i, []string{"missing", "key"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"key": "loaded:key"}, values)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Value set while not found result is cached is returned.
+	require.NoError(t, i.Set(context.TODO(), "missing", "value"))
+	v, err := i.Get(context.TODO(), "missing")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	require.NoError(t, i.Delete(context.TODO(), "missing"))
+
+	time.Sleep(150 * time.Millisecond)
+	_, err = i.Get(context.TODO(), "missing")
+	assert.ErrorAs(t, err, &ErrKeyNotFound{})
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestMemoryNegativeCache(t *testing.T) {
+	c := New(CacheType(MemoryCache), MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	testNegativeCache(t, c)
+}
+
+func TestRedisNegativeCache(t *testing.T) {
+	c, _ := newMiniRedisCache(t)
+
+	testNegativeCache(t, c)
+}
+
+func TestNegativeCacheWithoutOption(t *testing.T) {
+	c := New(CacheType(MemoryCache))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	var calls atomic.Int32
+	i, err := Create[string](c, "negative", Loader[string](func(_ context.Context, key string) (string, error) {
+		calls.Add(1)
+		return "", ErrKeyNotFound{Key: key}
+	}))
+	require.NoError(t, err)
+
+	for n := 0; n < 2; n++ {
+		_, err = i.Get(context.TODO(), "missing")
+		assert.ErrorAs(t, err, &ErrKeyNotFound{})
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestNegativeGuardMaxEntries(t *testing.T) {
+	g := newNegativeGuard(NegativeCache{TTL: time.Minute, MaxEntries: 2}, nil)
+	now := time.Now()
+
+	g.store("a", now)
+	g.store("b", now)
+	g.store("c", now)
+	assert.Len(t, g.entries, 2)
+	assert.True(t, g.cached("c", now))
+
+	assert.False(t, g.cached("c", now.Add(time.Minute)))
+	assert.Len(t, g.entries, 1)
+}
@@ -6,9 +6,16 @@ package cache
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"reflect"
 	"time"
 
 	"azugo.io/core/instrumenter"
+	"azugo.io/core/scrub"
+	"azugo.io/core/serializer"
+
+	"github.com/redis/go-redis/v9"
 )
 
 type cacheOptions struct {
@@ -17,8 +24,39 @@ type cacheOptions struct {
 	ConnectionString   string
 	ConnectionPassword string
 	KeyPrefix          string
-	Loader             func(ctx context.Context, key string) (interface{}, error)
+	Loader             any
 	Instrumenter       instrumenter.Instrumenter
+	Serializer         serializer.Serializer
+	Backpressure       *Backpressure
+	ItemDefaults       []any
+	Audit              *Audit
+	ReadOnly           *ReadOnly
+	SchemaVersion      int
+	Migrations         map[int]MigrationFunc
+	Quarantine         *Quarantine
+	Replication        *Replication
+	VirtualNodes       int
+	TTLGuard           *TTLGuard
+	InvalidationDelay  time.Duration
+	HedgedReads        *HedgedReads
+	Deduplicate        *Deduplicate
+	Events             *Events
+	TypeDefaults       map[reflect.Type][]CacheOption
+	InstanceDefaults   map[string][]CacheOption
+	Coalesce           *Coalesce
+	MemoryLimit        *MemoryLimit
+	MemorySnapshot     *MemorySnapshot
+	MemoryCost         *MemoryCost
+	MemorySyncWrites   bool
+	Tiered             *Tiered
+	RedisClient        redis.UniversalClient
+	Scrubber           *scrub.Scrubber
+	BoltStorage        *BoltStorage
+	HTTPClient         *http.Client
+	NegativeCache      *NegativeCache
+	TTLJitter          float64
+	ModeSwitch         *ModeSwitch
+	SlidingExpiration  *SlidingExpiration
 }
 
 // CacheOption is an option for the cache instance.
@@ -31,12 +69,92 @@ func newCacheOptions(opts ...CacheOption) *cacheOptions {
 	for _, o := range opts {
 		o.applyCache(opt)
 	}
+	if opt.RedisClient != nil && !opt.Type.isRedis() {
+		switch any(opt.RedisClient).(type) {
+		case *redis.ClusterClient:
+			opt.Type = RedisClusterCache
+		case *redis.Ring:
+			opt.Type = RedisRingCache
+		default:
+			opt.Type = RedisCache
+		}
+	}
+	if opt.Audit != nil && opt.Scrubber != nil {
+		a := *opt.Audit
+		a.scrubber = opt.Scrubber
+		opt.Audit = &a
+	}
+	if opt.Audit != nil {
+		opt.Instrumenter = auditInstrumenter(opt.Instrumenter, opt.Audit)
+	}
+	if opt.TTLJitter > 0 {
+		g := TTLGuard{}
+		if opt.TTLGuard != nil {
+			g = *opt.TTLGuard
+		}
+		g.jitter = opt.TTLJitter
+		opt.TTLGuard = &g
+	}
 	return opt
 }
 
+// newLoader returns instrumented loader function or nil if loader is not
+// configured or does not load values of type T.
+func newLoader[T any](opt *cacheOptions) func(ctx context.Context, key string) (T, error) {
+	load, ok := opt.Loader.(Loader[T])
+	if !ok || load == nil {
+		return nil
+	}
+	loader := func(ctx context.Context, key string) (T, error) {
+		finish := opt.Instrumenter.Observe(ctx, InstrumentationCacheLoader, key)
+		v, err := load(ctx, key)
+		finish(err)
+		return v, err
+	}
+	if opt.Backpressure != nil {
+		loader = newLoaderGuard[T](*opt.Backpressure, opt.Instrumenter).wrap(loader)
+	}
+	if opt.NegativeCache != nil {
+		loader = wrapNegative(newNegativeGuard(*opt.NegativeCache, opt.Instrumenter), loader)
+	}
+	return loader
+}
+
+// loadAndStore returns value from loader and stores it in cache instance using
+// set function or default value if loader is not set.
+func loadAndStore[T any](ctx context.Context, loader func(ctx context.Context, key string) (T, error), set func(ctx context.Context, key string, value T, opts ...ItemOption[T]) error, key string, opt *itemOptions[T], opts ...ItemOption[T]) (T, error) {
+	var val T
+	if loader == nil {
+		return opt.DefaultValue, nil
+	}
+	v, err := loader(ctx, key)
+	if err != nil {
+		return val, err
+	}
+	if err := set(ctx, key, v, opts...); err != nil {
+		return val, err
+	}
+	return v, nil
+}
+
+// validateLoader returns error if loader is configured but does not load values of type T.
+func validateLoader[T any](opt *cacheOptions) error {
+	if opt.Loader == nil {
+		return nil
+	}
+	if _, ok := opt.Loader.(Loader[T]); !ok {
+		return fmt.Errorf("loader %T can not be used with cache instance of type %s", opt.Loader, typeOf[T]())
+	}
+	return nil
+}
+
 type itemOptions[T any] struct {
 	TTL          time.Duration
+	DefaultTTL   time.Duration
 	DefaultValue T
+	Serializer   serializer.Serializer
+	Tags         []string
+	EarlyBeta    float64
 }
 
 // ItemOption is an option for the cached item.
@@ -52,6 +170,94 @@ func newItemOptions[T any](opts ...ItemOption[T]) *itemOptions[T] {
 	return opt
 }
 
+// itemDefaults are default item options of the cache instance.
+type itemDefaults[T any] []ItemOption[T]
+
+// newItemDefaults returns item option defaults for the cache instance
+// inherited from the cache instance options.
+func newItemDefaults[T any](opt *cacheOptions) itemDefaults[T] {
+	defs := make(itemDefaults[T], 0, len(opt.ItemDefaults)+2)
+	if opt.TTL != 0 {
+		defs = append(defs, TTL[T](opt.TTL))
+	}
+	if opt.Serializer != nil {
+		defs = append(defs, ItemSerializer[T]{opt.Serializer})
+	}
+	for _, o := range opt.ItemDefaults {
+		if io, ok := o.(ItemOption[T]); ok {
+			defs = append(defs, io)
+		}
+	}
+	return defs
+}
+
+// resolve returns item options with per-call options overriding instance defaults.
+func (d itemDefaults[T]) resolve(opts ...ItemOption[T]) *itemOptions[T] {
+	opt := &itemOptions[T]{}
+	for _, o := range d {
+		o.applyItem(opt)
+	}
+	opt.DefaultTTL = opt.TTL
+	for _, o := range opts {
+		o.applyItem(opt)
+	}
+	return opt
+}
+
+// ttlOverride returns per-call TTL that overrides the cache instance default TTL
+// or zero if default TTL is used.
+func (o *itemOptions[T]) ttlOverride() time.Duration {
+	if o.TTL == o.DefaultTTL {
+		return 0
+	}
+	return o.TTL
+}
+
+// ItemSettings are resolved cached item options.
+type ItemSettings[T any] struct {
+	// TTL is a time to keep item in cache. Zero means that TTL is not set
+	// and NoExpiration means no expiration.
+	TTL time.Duration
+	// DefaultValue is a value returned when item is not found.
+	DefaultValue T
+	// Serializer is a serializer used to encode item in remote cache backends.
+	Serializer serializer.Serializer
+}
+
+type itemOptionsResolver[T any] interface {
+	itemOptions(opts ...ItemOption[T]) *itemOptions[T]
+}
+
+// ResolveItemOptions returns item options resolved for the cache instance,
+// with per-call options overriding cache instance defaults.
+func ResolveItemOptions[T any](instance CacheInstance[T], opts ...ItemOption[T]) ItemSettings[T] {
+	opt := resolveItemOptions(instance, opts...)
+	return ItemSettings[T]{
+		TTL:          opt.TTL,
+		DefaultValue: opt.DefaultValue,
+		Serializer:   opt.Serializer,
+	}
+}
+
+// resolveItemOptions returns item options resolved for the cache instance.
+func resolveItemOptions[T any](instance CacheInstance[T], opts ...ItemOption[T]) *itemOptions[T] {
+	if r, ok := instance.(itemOptionsResolver[T]); ok {
+		return r.itemOptions(opts...)
+	}
+	return newItemOptions(opts...)
+}
+
+// ItemDefaults are default item options for all items in the cache instance.
+//
+// Options that do not match cache instance value type are ignored.
+type ItemDefaults[T any] []ItemOption[T]
+
+func (d ItemDefaults[T]) applyCache(c *cacheOptions) {
+	for _, o := range d {
+		c.ItemDefaults = append(c.ItemDefaults, o)
+	}
+}
+
 // CacheType represents a cache type.
 type CacheType string
 
@@ -62,13 +268,59 @@ const (
 	RedisCache CacheType = "redis"
 	// RedisClusterCache store data in Redis database cluster.
 	RedisClusterCache CacheType = "redis-cluster"
+	// RedisRingCache store data in multiple standalone Redis databases using consistent hashing.
+	RedisRingCache CacheType = "redis-ring"
+	// RedisSentinelCache store data in Redis database master discovered by Redis Sentinel.
+	RedisSentinelCache CacheType = "redis-sentinel"
+	// MemcachedCache store data in memcached servers.
+	MemcachedCache CacheType = "memcached"
+	// NATSCache store data in NATS JetStream key-value buckets.
+	//
+	// Each cache instance uses separate bucket that is created with cache default TTL
+	// if it does not exist. Item TTL longer than bucket TTL is limited by the bucket TTL.
+	NATSCache CacheType = "nats"
+	// EtcdCache store data in etcd cluster.
+	//
+	// Item TTL is implemented using etcd leases that have second precision.
+	// Suitable for small, strongly consistent values.
+	EtcdCache CacheType = "etcd"
+	// DynamoDBCache store data in Amazon DynamoDB table.
+	//
+	// Table must exist with string partition key and TTL enabled on the TTL attribute.
+	// DynamoDB deletes expired items with a delay so expiration is also checked on read.
+	// Credentials are taken from connection string or AWS environment variables.
+	// Use HTTPClient option to configure HTTP client used to call DynamoDB API.
+	DynamoDBCache CacheType = "dynamodb"
+	// PostgresCache store data in PostgreSQL unlogged table.
+	//
+	// Table is created if it does not exist. Expired items are deleted by a
+	// background sweeper and are never returned before that.
+	PostgresCache CacheType = "postgres"
+	// BoltCache store data in local bolt database file that persists across restarts.
+	//
+	// Expired items are never returned and are deleted periodically. Use BoltStorage
+	// option to configure deleting expired items and database file compaction.
+	BoltCache CacheType = "bolt"
+	// RemoteCache store data in cache instances of another service exposed using
+	// RemoteServer over HTTP.
+	//
+	// Connection string is the URL the remote cache server is mounted at. Use
+	// HTTPClient option to authenticate with client certificate.
+	RemoteCache CacheType = "remote"
 )
 
+// isRedis returns true if cache type stores data in Redis.
+func (t CacheType) isRedis() bool {
+	return t == RedisCache || t == RedisClusterCache || t == RedisRingCache || t == RedisSentinelCache
+}
+
 func (t CacheType) applyCache(c *cacheOptions) {
 	c.Type = t
 }
 
 // DefaultTTL is an default TTL for items in cache instance.
+//
+// Use NoExpiration to store items without expiration.
 type DefaultTTL time.Duration
 
 func (t DefaultTTL) applyCache(c *cacheOptions) {
@@ -76,6 +328,8 @@ func (t DefaultTTL) applyCache(c *cacheOptions) {
 }
 
 // TTL represents time to keep item in cache.
+//
+// Use NoExpiration to store item without expiration.
 type TTL[T any] time.Duration
 
 //nolint:unused
@@ -83,6 +337,24 @@ func (t TTL[T]) applyItem(c *itemOptions[T]) {
 	c.TTL = time.Duration(t)
 }
 
+// DefaultValue is a value returned when item is not found in cache.
+type DefaultValue[T any] struct {
+	Value T
+}
+
+func (d DefaultValue[T]) applyItem(c *itemOptions[T]) {
+	c.DefaultValue = d.Value
+}
+
+// ItemSerializer is a serializer used to encode item in remote cache backends.
+type ItemSerializer[T any] struct {
+	serializer.Serializer
+}
+
+func (s ItemSerializer[T]) applyItem(c *itemOptions[T]) {
+	c.Serializer = s.Serializer
+}
+
 // ConnectionString is a connection string for the cache instance.
 type ConnectionString string
 
@@ -106,10 +378,14 @@ func (kp KeyPrefix) applyCache(c *cacheOptions) {
 
 // Loader is a function that loads data when cache key is missing.
 //
+// Loader must return values of the cache instance type, otherwise cache instance
+// creation fails. Loader can return ErrKeyNotFound if value does not exist, use
+// NegativeCache option to cache such results.
+//
 // WARNING: it's not guaranteed that the function will be called only once.
-type Loader func(ctx context.Context, key string) (any, error)
+type Loader[T any] func(ctx context.Context, key string) (T, error)
 
-func (l Loader) applyCache(c *cacheOptions) {
+func (l Loader[T]) applyCache(c *cacheOptions) {
 	c.Loader = l
 }
 
@@ -119,3 +395,14 @@ type Instrumenter instrumenter.Instrumenter
 func (i Instrumenter) applyCache(c *cacheOptions) {
 	c.Instrumenter = instrumenter.Instrumenter(i)
 }
+
+// Serializer is a serializer used to encode values stored in remote cache backends.
+//
+// Defaults to JSON serializer.
+type Serializer struct {
+	serializer.Serializer
+}
+
+func (s Serializer) applyCache(c *cacheOptions) {
+	c.Serializer = s.Serializer
+}
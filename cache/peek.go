@@ -0,0 +1,85 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import "context"
+
+// peeker represents cache instance that can read values without calling the
+// loader or returning default value.
+type peeker[T any] interface {
+	// peek returns value of the key. If value is not found, it returns
+	// ErrKeyNotFound error.
+	peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error)
+}
+
+// intoPeeker represents cache instance that can decode values directly into the
+// caller provided destination without calling the loader.
+type intoPeeker[T any] interface {
+	// peekInto decodes value of the key into dst. If value is not found, it
+	// returns ErrKeyNotFound error.
+	peekInto(ctx context.Context, key string, dst *T, opts ...ItemOption[T]) error
+}
+
+// backendInstance returns cache instance backend with all wrappers removed.
+func backendInstance[T any](instance CacheInstance[T]) CacheInstance[T] {
+	for {
+		u, ok := instance.(instanceUnwrapper[T])
+		if !ok {
+			return instance
+		}
+		instance = u.unwrap()
+	}
+}
+
+// peekable returns the first cache instance or its wrapped instance that
+// implements peeker. If none does, instance with all wrappers removed is returned.
+func peekable[T any](instance CacheInstance[T]) CacheInstance[T] {
+	for {
+		if _, ok := instance.(peeker[T]); ok {
+			return instance
+		}
+		u, ok := instance.(instanceUnwrapper[T])
+		if !ok {
+			return instance
+		}
+		instance = u.unwrap()
+	}
+}
+
+// peek returns value of the key from the cache instance without calling the
+// loader. If value is not found, it returns ErrKeyNotFound error.
+//
+// Wrappers that do not implement peeker are skipped. Cache instances not
+// implemented by this package return default value for missing values, so
+// value presence is checked before reading it.
+func peek[T any](ctx context.Context, instance CacheInstance[T], key string, opts ...ItemOption[T]) (T, error) {
+	instance = peekable(instance)
+	if p, ok := instance.(peeker[T]); ok {
+		return p.peek(ctx, key, opts...)
+	}
+	var val T
+	ok, err := instance.Exists(ctx, key)
+	if err != nil {
+		return val, err
+	}
+	if !ok {
+		return val, ErrKeyNotFound{Key: key}
+	}
+	return instance.Get(ctx, key, opts...)
+}
+
+// peekInto decodes value of the key from the cache instance into dst without
+// calling the loader. If value is not found, it returns ErrKeyNotFound error.
+func peekInto[T any](ctx context.Context, instance CacheInstance[T], key string, dst *T, opts ...ItemOption[T]) error {
+	if p, ok := peekable(instance).(intoPeeker[T]); ok {
+		return p.peekInto(ctx, key, dst, opts...)
+	}
+	v, err := peek(ctx, instance, key, opts...)
+	if err != nil {
+		return err
+	}
+	*dst = v
+	return nil
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// foreignCache is a cache instance wrapper not implemented by this package.
+type foreignCache[T any] struct {
+	CacheInstance[T]
+}
+
+func TestPeek(t *testing.T) {
+	c := New(CacheType(MemoryCache), MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	var calls int
+	i, err := Create[string](c, "values", ModeSwitch{}, Loader[string](func(_ context.Context, key string) (string, error) {
+		calls++
+		return "loaded", nil
+	}))
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+
+	for _, instance := range []CacheInstance[string]{i, &foreignCache[string]{i}} {
+		v, err := peek(context.TODO(), instance, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", v)
+
+		_, err = peek(context.TODO(), instance, "missing")
+		assert.ErrorAs(t, err, &ErrKeyNotFound{})
+	}
+	assert.Equal(t, 0, calls)
+
+	// Values are not read from cache in bypass mode.
+	require.NoError(t, c.SetMode("values", ModeBypass))
+	_, err = peek(context.TODO(), i, "key")
+	assert.ErrorAs(t, err, &ErrKeyNotFound{})
+	v, err := peek(context.TODO(), backendInstance(i), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	assert.Equal(t, 0, calls)
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNoProvenance is returned when value is stored without envelope.
+var ErrNoProvenance = errors.New("cache value is stored without provenance")
+
+// rawReader represents cache instance that can read value as stored in the backend.
+type rawReader interface {
+	// readRaw returns stored value of the key. If value is not found, it will
+	// return ErrKeyNotFound error.
+	readRaw(ctx context.Context, key string) ([]byte, error)
+}
+
+// Provenance returns envelope header of the value stored in the cache instance so
+// that it can be attributed to the component and code location that has written it
+// and TTL it has requested.
+//
+// Value must be written with Audit envelope enabled, otherwise ErrNoProvenance is
+// returned. If value is not found, it will return ErrKeyNotFound error. Only remote
+// cache instances are supported, other instances return ErrNotSupported.
+func Provenance[T any](ctx context.Context, instance CacheInstance[T], key string) (EnvelopeHeader, error) {
+	r, ok := lookupInstance[T, rawReader](instance)
+	if !ok {
+		return EnvelopeHeader{}, ErrNotSupported
+	}
+	raw, err := r.readRaw(ctx, key)
+	if err != nil {
+		return EnvelopeHeader{}, err
+	}
+	h, _, ok := DecodeEnvelope(raw)
+	if !ok {
+		return EnvelopeHeader{}, ErrNoProvenance
+	}
+	return h, nil
+}
+
+func (c *redisCache[T]) readRaw(ctx context.Context, key string) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	raw, err := c.deref(ctx, c.con.Get(ctx, c.prefix+key))
+	if err == redis.Nil {
+		finish(nil)
+		return nil, ErrKeyNotFound{Key: key}
+	}
+	finish(err)
+	return []byte(raw), err
+}
+
+func (c *boltCache[T]) readRaw(ctx context.Context, key string) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	buf, ok, err := c.get(key, false)
+	if err == nil && !ok {
+		finish(nil)
+		return nil, ErrKeyNotFound{Key: key}
+	}
+	finish(err)
+	return buf, err
+}
+
+func (c *memcachedCache[T]) readRaw(ctx context.Context, key string) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	item, err := c.con.Get(c.key(key))
+	if err == memcache.ErrCacheMiss {
+		finish(nil)
+		return nil, ErrKeyNotFound{Key: key}
+	}
+	if err != nil {
+		finish(err)
+		return nil, err
+	}
+	finish(nil)
+	return item.Value, nil
+}
+
+func (c *postgresCache[T]) readRaw(ctx context.Context, key string) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+
+	var buf []byte
+	err := c.db.QueryRow(ctx, `SELECT value FROM `+c.table+` WHERE key = $1 AND (expires_at IS NULL OR expires_at > now())`,
+		c.prefix+key).Scan(&buf)
+	if errors.Is(err, pgx.ErrNoRows) {
+		finish(nil)
+		return nil, ErrKeyNotFound{Key: key}
+	}
+	finish(err)
+	return buf, err
+}
+
+func (c *dynamodbCache[T]) readRaw(ctx context.Context, key string) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+
+	var out struct {
+		Item dynamodbItem `json:"Item"`
+	}
+	if err := c.do(ctx, "GetItem", map[string]any{
+		"TableName":      c.conf.Table,
+		"Key":            c.itemKey(key),
+		"ConsistentRead": true,
+	}, &out); err != nil {
+		finish(err)
+		return nil, err
+	}
+	finish(nil)
+	buf, ok := c.value(out.Item)
+	if !ok {
+		return nil, ErrKeyNotFound{Key: key}
+	}
+	return buf, nil
+}
+
+func (c *etcdCache[T]) readRaw(ctx context.Context, key string) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	resp, err := c.client.Get(ctx, c.prefix+key)
+	finish(err)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, ErrKeyNotFound{Key: key}
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+func (c *natsCache[T]) readRaw(ctx context.Context, key string) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	_, payload, err := c.get(key)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		finish(nil)
+		return nil, ErrKeyNotFound{Key: key}
+	}
+	finish(err)
+	return payload, err
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testProvenance checks that provenance of the values written with audit
+// envelope records writer and TTL override.
+func testProvenance(t *testing.T, i CacheInstance[string]) {
+	t.Helper()
+
+	ctx := WithComponent(context.TODO(), "billing")
+	require.NoError(t, i.Set(ctx, "default", "value"))
+	require.NoError(t, i.Set(ctx, "override", "value", TTL[string](time.Minute)))
+
+	h, err := Provenance(context.TODO(), i, "default")
+	require.NoError(t, err)
+	assert.Equal(t, "billing", h.Component)
+	assert.Contains(t, h.Caller, "testProvenance")
+	assert.WithinDuration(t, time.Now(), h.WrittenAt, time.Minute)
+	assert.Zero(t, h.TTLOverride)
+
+	h, err = Provenance(context.TODO(), i, "override")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, h.TTLOverride)
+
+	_, err = Provenance(context.TODO(), i, "missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound{Key: "missing"})
+}
+
+var testProvenanceOptions = []CacheOption{
+	DefaultTTL(time.Hour),
+	Audit{Envelope: true, Caller: true},
+}
+
+func TestRedisCacheProvenance(t *testing.T) {
+	c, _ := newMiniRedisCache(t, append(testProvenanceOptions, Deduplicate{MinSize: 1})...)
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testProvenance(t, i)
+
+	plain, err := Create[string](c, "plain", Audit{})
+	require.NoError(t, err)
+	require.NoError(t, plain.Set(context.TODO(), "key", "value"))
+	_, err = Provenance(context.TODO(), plain, "key")
+	assert.ErrorIs(t, err, ErrNoProvenance)
+
+	// Provenance is read through wrappers.
+	ro := newReadOnlyCache(i, ReadOnly{})
+	h, err := Provenance(context.TODO(), ro, "override")
+	require.NoError(t, err)
+	assert.Equal(t, "billing", h.Component)
+}
+
+func TestBoltCacheProvenance(t *testing.T) {
+	testProvenance(t, newTestBoltCache[string](t, filepath.Join(t.TempDir(), "cache.db"), testProvenanceOptions...))
+}
+
+func TestMemcachedCacheProvenance(t *testing.T) {
+	c, _ := newTestMemcachedCache(t, testProvenanceOptions...)
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testProvenance(t, i)
+}
+
+func TestPostgresCacheProvenance(t *testing.T) {
+	c, _ := newTestPostgresCache[string](t, testProvenanceOptions...)
+	testProvenance(t, c)
+}
+
+func TestDynamoDBCacheProvenance(t *testing.T) {
+	c, _ := newTestDynamoDBCache[string](t, "cache", testProvenanceOptions...)
+	testProvenance(t, c)
+}
+
+func TestEtcdCacheProvenance(t *testing.T) {
+	c, _ := newTestEtcdCache[string](t, testProvenanceOptions...)
+	testProvenance(t, c)
+}
+
+func TestNATSCacheProvenance(t *testing.T) {
+	c, _ := newTestNATSCache[string](t, testProvenanceOptions...)
+	testProvenance(t, c)
+}
+
+func TestProvenanceNotSupported(t *testing.T) {
+	i, err := newLRUCache[string]()
+	require.NoError(t, err)
+	defer closeInstance(i)
+
+	_, err = Provenance(context.TODO(), i, "key")
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
@@ -0,0 +1,152 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	InstrumentationCachePublish = "cache-publish"
+)
+
+type redisPubSub interface {
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// memoryPubSub is an in-process publish/subscribe used by memory cache.
+type memoryPubSub struct {
+	lock   sync.RWMutex
+	nextID int
+	subs   map[string]map[int]func(string)
+}
+
+func (p *memoryPubSub) publish(channel, message string) {
+	p.lock.RLock()
+	subs := make([]func(string), 0, len(p.subs[channel]))
+	for _, fn := range p.subs[channel] {
+		subs = append(subs, fn)
+	}
+	p.lock.RUnlock()
+
+	for _, fn := range subs {
+		fn(message)
+	}
+}
+
+func (p *memoryPubSub) subscribe(channel string, fn func(string)) func() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.subs == nil {
+		p.subs = make(map[string]map[int]func(string))
+	}
+	if p.subs[channel] == nil {
+		p.subs[channel] = make(map[int]func(string))
+	}
+	id := p.nextID
+	p.nextID++
+	p.subs[channel][id] = fn
+
+	return func() {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		delete(p.subs[channel], id)
+	}
+}
+
+func (c *Cache) channelName(opt *cacheOptions, channel string) string {
+	if len(opt.KeyPrefix) != 0 {
+		return opt.KeyPrefix + ":" + channel
+	}
+	return channel
+}
+
+// Publish message to the channel.
+//
+// For memory cache message is delivered only to subscribers in the same process.
+func (c *Cache) Publish(ctx context.Context, channel, message string) error {
+	opt := newCacheOptions(c.options...)
+
+	finish := opt.Instrumenter.Observe(ctx, InstrumentationCachePublish, channel)
+
+	if opt.Type.isRedis() {
+		if c.redisCon == nil {
+			finish(ErrCacheClosed)
+			return ErrCacheClosed
+		}
+		if err := c.redisCon.Publish(ctx, c.channelName(opt, channel), message).Err(); err != nil {
+			finish(err)
+			return err
+		}
+		finish(nil)
+		return nil
+	}
+
+	c.pubsub.publish(channel, message)
+	finish(nil)
+	return nil
+}
+
+// Subscribe to messages published to the channel.
+//
+// Handler is called sequentially for each received message until context is canceled
+// or returned unsubscribe function is called.
+func (c *Cache) Subscribe(ctx context.Context, channel string, handler func(message string)) (func(), error) {
+	opt := newCacheOptions(c.options...)
+
+	if !opt.Type.isRedis() {
+		unsubscribe := c.pubsub.subscribe(channel, handler)
+		if done := ctx.Done(); done != nil {
+			go func() {
+				<-done
+				unsubscribe()
+			}()
+		}
+		return unsubscribe, nil
+	}
+
+	con, ok := c.redisCon.(redisPubSub)
+	if !ok {
+		return nil, ErrCacheClosed
+	}
+
+	ps := con.Subscribe(ctx, c.channelName(opt, channel))
+	// Wait for subscription to be confirmed.
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			_ = ps.Close()
+		})
+	}
+
+	go func() {
+		defer unsubscribe()
+
+		ch := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				handler(msg.Payload)
+			}
+		}
+	}()
+
+	return unsubscribe, nil
+}
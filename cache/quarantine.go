@@ -0,0 +1,54 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	InstrumentationCacheCorrupt = "cache-corrupt"
+)
+
+// Quarantine enables strict unmarshal mode for remote cache backends.
+//
+// When stored value can not be deserialized, corrupt entry is removed (and optionally
+// copied to a side key for later inspection), failure is reported to instrumenter
+// and value is loaded using the loader as if it was missing.
+type Quarantine struct {
+	// Suffix is appended to the key to store a copy of the corrupt entry.
+	// If empty, corrupt entry is only deleted.
+	Suffix string
+	// TTL is a time to keep quarantined entry. Defaults to 24 hours.
+	TTL time.Duration
+}
+
+func (q Quarantine) applyCache(c *cacheOptions) {
+	c.Quarantine = &q
+}
+
+// quarantineValue moves corrupt value out of the way.
+func (c *redisCache[T]) quarantineValue(ctx context.Context, key, raw string, cause error, remove bool) {
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheCorrupt, c.prefix+key, cause)
+
+	if len(c.quarantine.Suffix) != 0 {
+		ttl := c.quarantine.TTL
+		if ttl == 0 {
+			ttl = 24 * time.Hour
+		}
+		if err := c.con.Set(ctx, c.prefix+key+c.quarantine.Suffix, raw, ttl).Err(); err != nil {
+			finish(err)
+			return
+		}
+	}
+	if remove {
+		if err := c.del(ctx, key); err != nil {
+			finish(err)
+			return
+		}
+	}
+	finish(nil)
+}
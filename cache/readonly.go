@@ -0,0 +1,98 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrReadOnly is returned when write operation is called on read-only cache instance.
+var ErrReadOnly = errors.New("cache instance is read-only")
+
+// ReadOnly makes cache instance read-only so that Set, Delete and Pop operations
+// can not mutate shared cache namespace.
+type ReadOnly struct {
+	// Silent ignores write operations instead of returning ErrReadOnly.
+	// Pop returns value without deleting it.
+	Silent bool
+	// Log is called for every rejected or ignored write operation.
+	Log func(ctx context.Context, op, key string)
+}
+
+func (r ReadOnly) applyCache(c *cacheOptions) {
+	c.ReadOnly = &r
+}
+
+// WithReadOnly returns option that makes cache instance read-only with
+// write operations returning ErrReadOnly.
+func WithReadOnly() ReadOnly {
+	return ReadOnly{}
+}
+
+type readOnlyCache[T any] struct {
+	CacheInstance[T]
+	conf ReadOnly
+}
+
+func newReadOnlyCache[T any](c CacheInstance[T], conf ReadOnly) CacheInstance[T] {
+	return &readOnlyCache[T]{
+		CacheInstance: c,
+		conf:          conf,
+	}
+}
+
+func (c *readOnlyCache[T]) reject(ctx context.Context, op, key string) error {
+	if c.conf.Log != nil {
+		c.conf.Log(ctx, op, key)
+	}
+	if c.conf.Silent {
+		return nil
+	}
+	return ErrReadOnly
+}
+
+func (c *readOnlyCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	if err := c.reject(ctx, InstrumentationCacheDelete, key); err != nil {
+		var val T
+		return val, err
+	}
+	return c.CacheInstance.Get(ctx, key)
+}
+
+func (c *readOnlyCache[T]) PopWithMetadata(ctx context.Context, key string) (T, ItemMetadata, error) {
+	v, err := c.Pop(ctx, key)
+	return v, ItemMetadata{}, err
+}
+
+func (c *readOnlyCache[T]) Set(ctx context.Context, key string, _ T, _ ...ItemOption[T]) error {
+	return c.reject(ctx, InstrumentationCacheSet, key)
+}
+
+func (c *readOnlyCache[T]) Delete(ctx context.Context, key string) error {
+	return c.reject(ctx, InstrumentationCacheDelete, key)
+}
+
+func (c *readOnlyCache[T]) Close() {
+	closeInstance(c.CacheInstance)
+}
+
+func (c *readOnlyCache[T]) Ping(ctx context.Context) error {
+	if p, ok := c.CacheInstance.(CacheInstancePinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *readOnlyCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	if r, ok := c.CacheInstance.(itemOptionsResolver[T]); ok {
+		return r.itemOptions(opts...)
+	}
+	return newItemOptions(opts...)
+}
+
+func (c *readOnlyCache[T]) unwrap() CacheInstance[T] {
+	return c.CacheInstance
+}
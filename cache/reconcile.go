@@ -0,0 +1,332 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type reconcileOptions struct {
+	SampleSize int
+	Repair     bool
+	Equal      any
+	Interval   time.Duration
+	Schedule   Scheduler
+	Hook       func(report *ReconcileReport, err error)
+}
+
+// ReconcileOption is an option for the cache instance reconciliation.
+type ReconcileOption interface {
+	applyReconcile(*reconcileOptions)
+}
+
+func (s SampleSize) applyReconcile(o *reconcileOptions) {
+	o.SampleSize = int(s)
+}
+
+// ReconcileRepair repairs divergent entries by storing source value or
+// deleting entries missing in the source of truth.
+type ReconcileRepair bool
+
+func (r ReconcileRepair) applyReconcile(o *reconcileOptions) {
+	o.Repair = bool(r)
+}
+
+// ReconcileEqual compares cached and source values. Defaults to reflect.DeepEqual.
+type ReconcileEqual[T any] func(cached, source T) bool
+
+func (e ReconcileEqual[T]) applyReconcile(o *reconcileOptions) {
+	o.Equal = e
+}
+
+// ReconcileInterval is an interval to run scheduled reconciliation.
+type ReconcileInterval time.Duration
+
+func (i ReconcileInterval) applyReconcile(o *reconcileOptions) {
+	o.Interval = time.Duration(i)
+}
+
+// Scheduler returns next time to run after the given time, for example config.Cron.
+// Zero time stops scheduling.
+type Scheduler interface {
+	Next(t time.Time) time.Time
+}
+
+// ReconcileSchedule runs scheduled reconciliation at times returned by the
+// scheduler. Overrides ReconcileInterval.
+type ReconcileSchedule struct {
+	Scheduler
+}
+
+func (s ReconcileSchedule) applyReconcile(o *reconcileOptions) {
+	o.Schedule = s.Scheduler
+}
+
+// ReconcileHook is called with the result of every scheduled reconciliation.
+type ReconcileHook func(report *ReconcileReport, err error)
+
+func (h ReconcileHook) applyReconcile(o *reconcileOptions) {
+	o.Hook = h
+}
+
+// SourceFunc returns value of the key from the source of truth and false
+// if key does not exist in the source.
+type SourceFunc[T any] func(ctx context.Context, key string) (T, bool, error)
+
+// DivergenceKind is a kind of the cache entry divergence from the source of truth.
+type DivergenceKind string
+
+const (
+	// DivergenceStale is an entry stored in the cache that does not exist in the source.
+	DivergenceStale DivergenceKind = "stale"
+	// DivergenceMismatch is an entry with the value different from the source.
+	DivergenceMismatch DivergenceKind = "mismatch"
+)
+
+// Divergence is a cache entry that diverges from the source of truth.
+type Divergence struct {
+	// Key of the entry.
+	Key string
+	// Kind of the divergence.
+	Kind DivergenceKind
+	// Repaired is true if entry was repaired.
+	Repaired bool
+}
+
+// ReconcileReport is a result of the cache instance reconciliation.
+type ReconcileReport struct {
+	// Started is a time reconciliation was started.
+	Started time.Time
+	// Duration of the reconciliation.
+	Duration time.Duration
+	// Checked is a number of checked keys.
+	Checked int
+	// Consistent is a number of entries matching the source.
+	Consistent int
+	// Missing is a number of keys not stored in the cache.
+	Missing int
+	// Divergent are entries that diverge from the source.
+	Divergent []Divergence
+	// Repaired is a number of repaired entries.
+	Repaired int
+	// Failed is a number of keys that could not be checked or repaired.
+	Failed int
+}
+
+// Reconcile compares cache instance entries of the keys with the source of
+// truth and reports divergent entries.
+//
+// If SampleSize is set, only random sample of the keys is checked. Divergent
+// entries are repaired only when ReconcileRepair is set. Errors reading or
+// repairing single entries are counted as failed and do not stop reconciliation.
+func Reconcile[T any](ctx context.Context, instance CacheInstance[T], keys []string, source SourceFunc[T], opts ...ReconcileOption) (*ReconcileReport, error) {
+	opt := &reconcileOptions{}
+	for _, o := range opts {
+		o.applyReconcile(opt)
+	}
+	return reconcile(ctx, instance, keys, source, opt)
+}
+
+func reconcile[T any](ctx context.Context, instance CacheInstance[T], keys []string, source SourceFunc[T], opt *reconcileOptions) (*ReconcileReport, error) {
+	equal, _ := opt.Equal.(ReconcileEqual[T])
+	if equal == nil {
+		equal = func(cached, source T) bool {
+			return reflect.DeepEqual(cached, source)
+		}
+	}
+
+	if opt.SampleSize > 0 && len(keys) > opt.SampleSize {
+		sample := append([]string(nil), keys...)
+		rand.Shuffle(len(sample), func(i, j int) {
+			sample[i], sample[j] = sample[j], sample[i]
+		})
+		keys = sample[:opt.SampleSize]
+	}
+
+	report := &ReconcileReport{
+		Started: time.Now(),
+	}
+	defer func() {
+		report.Duration = time.Since(report.Started)
+	}()
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.Checked++
+
+		cached, found, err := lookup(ctx, instance, key)
+		if err != nil {
+			report.Failed++
+			continue
+		}
+		if !found {
+			report.Missing++
+			continue
+		}
+		value, exists, err := source(ctx, key)
+		if err != nil {
+			report.Failed++
+			continue
+		}
+
+		d := Divergence{Key: key}
+		switch {
+		case !exists:
+			d.Kind = DivergenceStale
+		case !equal(cached, value):
+			d.Kind = DivergenceMismatch
+		default:
+			report.Consistent++
+			continue
+		}
+
+		if opt.Repair {
+			if d.Kind == DivergenceStale {
+				err = instance.Delete(ctx, key)
+			} else {
+				err = instance.Set(ctx, key, value)
+			}
+			if err != nil {
+				report.Failed++
+			} else {
+				d.Repaired = true
+				report.Repaired++
+			}
+		}
+		report.Divergent = append(report.Divergent, d)
+	}
+	return report, nil
+}
+
+// Reconciler runs scheduled cache instance reconciliation.
+//
+// Reconciler implements core.Tasker interface.
+type Reconciler[T any] struct {
+	instance CacheInstance[T]
+	keys     func(ctx context.Context) ([]string, error)
+	source   SourceFunc[T]
+	opt      *reconcileOptions
+
+	lock sync.Mutex
+	last *ReconcileReport
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewReconciler creates new scheduled reconciler of the cache instance entries
+// of the keys returned by the keys function.
+func NewReconciler[T any](instance CacheInstance[T], keys func(ctx context.Context) ([]string, error), source SourceFunc[T], opts ...ReconcileOption) *Reconciler[T] {
+	opt := &reconcileOptions{}
+	for _, o := range opts {
+		o.applyReconcile(opt)
+	}
+	return &Reconciler[T]{
+		instance: instance,
+		keys:     keys,
+		source:   source,
+		opt:      opt,
+	}
+}
+
+// Reconcile runs reconciliation immediately.
+func (r *Reconciler[T]) Reconcile(ctx context.Context) (*ReconcileReport, error) {
+	keys, err := r.keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report, err := reconcile(ctx, r.instance, keys, r.source, r.opt)
+
+	r.lock.Lock()
+	r.last = report
+	r.lock.Unlock()
+
+	return report, err
+}
+
+// Last returns report of the last reconciliation or nil if it has not run yet.
+func (r *Reconciler[T]) Last() *ReconcileReport {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.last
+}
+
+// Name returns task name.
+func (r *Reconciler[T]) Name() string {
+	return "cache-reconcile"
+}
+
+// Start scheduled reconciliation if interval or schedule is set.
+func (r *Reconciler[T]) Start(ctx context.Context) error {
+	if r.opt.Interval <= 0 && r.opt.Schedule == nil {
+		return nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.stop != nil {
+		return nil
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+
+	go r.schedule(ctx, r.stop, r.done)
+	return nil
+}
+
+func (r *Reconciler[T]) schedule(ctx context.Context, stop, done chan struct{}) {
+	defer close(done)
+
+	for {
+		next := r.next()
+		if next.IsZero() {
+			return
+		}
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-stop:
+			t.Stop()
+			return
+		case <-t.C:
+			report, err := r.Reconcile(ctx)
+			if r.opt.Hook != nil {
+				r.opt.Hook(report, err)
+			}
+		}
+	}
+}
+
+// next returns time of the next scheduled reconciliation.
+func (r *Reconciler[T]) next() time.Time {
+	now := time.Now()
+	if r.opt.Schedule != nil {
+		return r.opt.Schedule.Next(now)
+	}
+	return now.Add(r.opt.Interval)
+}
+
+// Stop scheduled reconciliation.
+func (r *Reconciler[T]) Stop() {
+	r.lock.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.lock.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
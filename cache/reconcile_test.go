@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSource(values map[string]string) SourceFunc[string] {
+	return func(_ context.Context, key string) (string, bool, error) {
+		if key == "broken" {
+			return "", false, errors.New("source unavailable")
+		}
+		v, ok := values[key]
+		return v, ok, nil
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	for k, v := range map[string]string{"ok": "1", "changed": "old", "deleted": "x", "broken": "y"} {
+		require.NoError(t, i.Set(context.TODO(), k, v))
+	}
+	source := testSource(map[string]string{"ok": "1", "changed": "new", "uncached": "z"})
+	keys := []string{"ok", "changed", "deleted", "broken", "uncached"}
+
+	report, err := Reconcile(context.TODO(), i, keys, source)
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Checked)
+	assert.Equal(t, 1, report.Consistent)
+	assert.Equal(t, 1, report.Missing)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 0, report.Repaired)
+	assert.ElementsMatch(t, []Divergence{
+		{Key: "changed", Kind: DivergenceMismatch},
+		{Key: "deleted", Kind: DivergenceStale},
+	}, report.Divergent)
+
+	v, err := i.Get(context.TODO(), "changed")
+	require.NoError(t, err)
+	assert.Equal(t, "old", v)
+
+	report, err = Reconcile(context.TODO(), i, keys, source, ReconcileRepair(true))
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Repaired)
+	assert.ElementsMatch(t, []Divergence{
+		{Key: "changed", Kind: DivergenceMismatch, Repaired: true},
+		{Key: "deleted", Kind: DivergenceStale, Repaired: true},
+	}, report.Divergent)
+
+	v, err = i.Get(context.TODO(), "changed")
+	require.NoError(t, err)
+	assert.Equal(t, "new", v)
+	v, err = i.Get(context.TODO(), "deleted")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+
+	report, err = Reconcile(context.TODO(), i, keys, source, SampleSize(2))
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Checked)
+}
+
+func TestReconcileEqual(t *testing.T) {
+	i := testMapCache[string]{"a": "Value"}
+	source := testSource(map[string]string{"a": "value"})
+
+	report, err := Reconcile[string](context.TODO(), i, []string{"a"}, source, ReconcileEqual[string](strings.EqualFold))
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Consistent)
+	assert.Empty(t, report.Divergent)
+}
+
+func TestReconciler(t *testing.T) {
+	i := testMapCache[string]{"a": "old"}
+	source := testSource(map[string]string{"a": "new"})
+
+	reports := make(chan *ReconcileReport, 1)
+	r := NewReconciler[string](i, func(context.Context) ([]string, error) {
+		return []string{"a"}, nil
+	}, source,
+		ReconcileRepair(true),
+		ReconcileInterval(10*time.Millisecond),
+		ReconcileHook(func(report *ReconcileReport, err error) {
+			assert.NoError(t, err)
+			select {
+			case reports <- report:
+			default:
+			}
+		}),
+	)
+	assert.Nil(t, r.Last())
+
+	require.NoError(t, r.Start(context.TODO()))
+	select {
+	case report := <-reports:
+		assert.Equal(t, 1, report.Repaired)
+	case <-time.After(time.Second):
+		t.Fatal("reconciliation was not run")
+	}
+	r.Stop()
+
+	assert.NotNil(t, r.Last())
+	assert.Equal(t, "new", i["a"])
+}
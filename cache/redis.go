@@ -9,24 +9,35 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/url"
-	"reflect"
-	"time"
+	"sync/atomic"
 
 	"azugo.io/core/instrumenter"
+	"azugo.io/core/serializer"
 
-	"github.com/goccy/go-json"
 	"github.com/redis/go-redis/v9"
 )
 
 type redisCache[T any] struct {
 	con          redis.Cmdable
+	ref          *connRef
+	closed       atomic.Bool
 	prefix       string
-	ttl          time.Duration
-	loader       func(ctx context.Context, key string) (interface{}, error)
+	items        itemDefaults[T]
+	loader       func(ctx context.Context, key string) (T, error)
 	instrumenter instrumenter.Instrumenter
+	audit        *Audit
+	version      int
+	migrations   map[int]MigrationFunc
+	quarantine   *Quarantine
+	ttlGuard     *TTLGuard
+	hedge        *hedgedReader
+	dedup        *Deduplicate
+	coalesce     *coalescer
+
+	backendState
 }
 
-func newRedisCache[T any](prefix string, con redis.Cmdable, opts ...CacheOption) (CacheInstance[T], error) {
+func newRedisCache[T any](prefix string, ref *connRef, opts ...CacheOption) (CacheInstance[T], error) {
 	opt := newCacheOptions(opts...)
 
 	keyPrefix := opt.KeyPrefix
@@ -34,22 +45,35 @@ func newRedisCache[T any](prefix string, con redis.Cmdable, opts ...CacheOption)
 		keyPrefix += ":"
 	}
 
-	loader := opt.Loader
-	if loader != nil {
-		loader = func(ctx context.Context, key string) (interface{}, error) {
-			finish := opt.Instrumenter.Observe(ctx, InstrumentationCacheLoader, key)
-			v, err := opt.Loader(ctx, key)
-			finish(err)
-			return v, err
+	loader := newLoader[T](opt)
+
+	if err := checkClusterPrefix(ref.con, keyPrefix+prefix+":"); err != nil {
+		return nil, err
+	}
+
+	var hedge *hedgedReader
+	if opt.HedgedReads != nil {
+		var err error
+		if hedge, err = newHedgedReader(opt.HedgedReads); err != nil {
+			return nil, err
 		}
 	}
 
 	return &redisCache[T]{
-		con:          con,
+		con:          ref.con,
+		ref:          ref,
 		prefix:       keyPrefix + prefix + ":",
-		ttl:          opt.TTL,
+		items:        newItemDefaults[T](opt),
 		loader:       loader,
 		instrumenter: opt.Instrumenter,
+		audit:        opt.Audit,
+		version:      opt.SchemaVersion,
+		migrations:   opt.Migrations,
+		quarantine:   opt.Quarantine,
+		ttlGuard:     opt.TTLGuard,
+		hedge:        hedge,
+		dedup:        opt.Deduplicate,
+		coalesce:     newCoalescer(opt.Coalesce),
 	}, nil
 }
 
@@ -70,7 +94,15 @@ func parseCustomURLAttr(v string) (string, bool, error) {
 	return u.String(), insecureSkipVerify, nil
 }
 
+// ParseRedisClusterURL parses Redis Cluster connection string.
+//
+// Multiple cluster nodes can be specified either as comma separated list of hosts
+// (redis://host1:6379,host2:6379) or using addr query parameters.
 func ParseRedisClusterURL(v string) (*redis.ClusterOptions, error) {
+	v, err := expandClusterHosts(v)
+	if err != nil {
+		return nil, err
+	}
 	v, insecureSkipVerify, err := parseCustomURLAttr(v)
 	if err != nil {
 		return nil, err
@@ -126,146 +158,266 @@ func newRedisClusterClient(constr, password string) (redis.Cmdable, error) {
 }
 
 func (c *redisCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
-	val := new(T)
-	if c.con == nil {
-		return *val, ErrCacheClosed
+	var val T
+	err := c.GetInto(ctx, key, &val, opts...)
+	return val, err
+}
+
+// GetInto decodes value directly into dst.
+func (c *redisCache[T]) GetInto(ctx context.Context, key string, dst *T, opts ...ItemOption[T]) error {
+	return c.readInto(ctx, key, dst, true, opts...)
+}
+
+// peek returns value from cache without calling the loader.
+func (c *redisCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	var val T
+	err := c.readInto(ctx, key, &val, false, opts...)
+	return val, err
+}
+
+// peekInto decodes value directly into dst without calling the loader.
+func (c *redisCache[T]) peekInto(ctx context.Context, key string, dst *T, opts ...ItemOption[T]) error {
+	return c.readInto(ctx, key, dst, false, opts...)
+}
+
+// readInto decodes value directly into dst. Missing value is loaded and stored
+// if load is true, otherwise ErrKeyNotFound error is returned.
+func (c *redisCache[T]) readInto(ctx context.Context, key string, dst *T, load bool, opts ...ItemOption[T]) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
 	}
+	opt := c.items.resolve(opts...)
 	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
-	s := c.con.Get(ctx, c.prefix+key)
-	if s.Err() == redis.Nil {
-		if c.loader != nil {
-			v, err := c.loader(ctx, key)
-			if err != nil {
-				finish(err)
-				return *val, err
-			}
-			vv, ok := v.(T)
-			if !ok {
-				err = fmt.Errorf("invalid value from loader: %v", v)
-				finish(err)
-				return *val, err
-			}
-			if err := c.Set(ctx, key, vv, opts...); err != nil {
-				finish(err)
-				return *val, err
-			}
-			return vv, nil
-		}
-		return *val, nil
+	raw, err := c.deref(ctx, c.get(ctx, c.prefix+key))
+	if err == redis.Nil {
+		err = c.loadInto(ctx, key, dst, load, opt, opts...)
+		finish(err)
+		return err
 	}
-	if s.Err() != nil {
-		finish(s.Err())
-		return *val, s.Err()
+	if err != nil {
+		finish(err)
+		return err
 	}
-	if err := json.Unmarshal([]byte(s.Val()), val); err != nil {
+	if err := c.unmarshal(opt, []byte(raw), dst); err != nil {
 		err = fmt.Errorf("invalid cache value: %w", err)
+		if c.quarantine == nil {
+			finish(err)
+			return err
+		}
+		c.quarantineValue(ctx, key, raw, err, true)
+		// Discard partially decoded value.
+		var val T
+		*dst = val
+		err = c.loadInto(ctx, key, dst, load, opt, opts...)
 		finish(err)
-		return *val, err
+		return err
 	}
 	finish(nil)
-	return *val, nil
+	return nil
+}
+
+func (c *redisCache[T]) Exists(ctx context.Context, key string) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
+	n, err := c.con.Exists(ctx, c.prefix+key).Result()
+	finish(err)
+	return n > 0, err
+}
+
+// loadInto stores value from loader or default value in dst. If load is false,
+// it returns ErrKeyNotFound error.
+func (c *redisCache[T]) loadInto(ctx context.Context, key string, dst *T, load bool, opt *itemOptions[T], opts ...ItemOption[T]) error {
+	if !load {
+		return ErrKeyNotFound{Key: key}
+	}
+	v, err := loadAndStore(ctx, c.loader, c.Set, key, opt, opts...)
+	if err != nil {
+		return err
+	}
+	*dst = v
+	return nil
 }
 
 func (c *redisCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	v, _, err := c.PopWithMetadata(ctx, key)
+	return v, err
+}
+
+func (c *redisCache[T]) PopWithMetadata(ctx context.Context, key string) (T, ItemMetadata, error) {
 	val := new(T)
-	if c.con == nil {
-		return *val, ErrCacheClosed
+	var meta ItemMetadata
+	if c.closed.Load() {
+		return *val, meta, ErrCacheClosed
 	}
 
 	finishG := c.instrumenter.Observe(ctx, InstrumentationCacheGet, c.prefix+key)
 	finishD := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+key)
 
-	s := c.con.GetDel(ctx, c.prefix+key)
-	if s.Err() == redis.Nil {
+	defer c.coalesce.forget(c.prefix + key)
+
+	var ttl *redis.DurationCmd
+	var s *redis.StringCmd
+	_, err := c.con.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		ttl = p.PTTL(ctx, c.prefix+key)
+		s = p.GetDel(ctx, c.prefix+key)
+		return nil
+	})
+	var raw string
+	if err == nil || err == redis.Nil {
+		raw, err = c.release(ctx, s)
+	}
+	if err == redis.Nil {
 		finishD(nil)
 		finishG(nil)
-		return *val, ErrKeyNotFound{Key: key}
+		return *val, meta, ErrKeyNotFound{Key: key}
 	}
-	if s.Err() != nil {
-		finishD(s.Err())
-		finishG(s.Err())
-		return *val, s.Err()
+	if err != nil {
+		finishD(err)
+		finishG(err)
+		return *val, meta, err
+	}
+	// Negative values mean that key has no expiration.
+	if d := ttl.Val(); d > 0 {
+		meta.TTL = d
+	}
+	if h, _, ok := DecodeEnvelope([]byte(raw)); ok {
+		meta.StoredAt = h.WrittenAt
 	}
-	if err := json.Unmarshal([]byte(s.Val()), val); err != nil {
+	if err := c.unmarshal(c.items.resolve(), []byte(raw), val); err != nil {
 		err = fmt.Errorf("invalid cache value: %w", err)
+		if c.quarantine != nil {
+			// Value is already deleted so only keep a copy if requested.
+			c.quarantineValue(ctx, key, raw, err, false)
+			finishD(nil)
+			finishG(nil)
+			return *val, ItemMetadata{}, ErrKeyNotFound{Key: key}
+		}
 		finishD(err)
 		finishG(err)
-		return *val, err
+		return *val, meta, err
 	}
 	finishD(nil)
 	finishG(nil)
-	return *val, nil
+	return *val, meta, nil
 }
 
-func (c *redisCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
-	if c.con == nil {
-		return ErrCacheClosed
+func (c *redisCache[T]) set(ctx context.Context, key string, value T, nx bool, opts ...ItemOption[T]) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrCacheClosed
 	}
 	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
 
-	buf, err := json.Marshal(value)
+	opt := c.items.resolve(opts...)
+	ttl, err := c.ttlGuard.expiration(ctx, key, opt.TTL)
 	if err != nil {
-		err = fmt.Errorf("invalid cache value: %w", err)
 		finish(err)
-		return err
+		return false, err
 	}
-	opt := newItemOptions(opts...)
-	ttl := c.ttl
-	if opt.TTL != 0 {
-		ttl = opt.TTL
+	buf, err := c.marshal(ctx, opt, value)
+	if err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return false, err
 	}
-	s := c.con.Set(ctx, c.prefix+key, string(buf), ttl)
-	if s.Err() != nil {
-		finish(s.Err())
-		return s.Err()
+	stored, err := c.put(ctx, key, buf, ttl, nx)
+	if err == nil && stored {
+		err = c.tag(ctx, key, opt.Tags, ttl)
 	}
-	finish(nil)
-	return nil
+	finish(err)
+	return stored, err
+}
+
+func (c *redisCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	_, err := c.set(ctx, key, value, false, opts...)
+	return err
 }
 
 func (c *redisCache[T]) Delete(ctx context.Context, key string) error {
-	if c.con == nil {
+	if c.closed.Load() {
 		return ErrCacheClosed
 	}
 
 	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, c.prefix+key)
 
-	s := c.con.Del(ctx, c.prefix+key)
-	if s.Err() != nil {
-		finish(s.Err())
-		return s.Err()
+	if err := c.del(ctx, key); err != nil {
+		finish(err)
+		return err
 	}
 	finish(nil)
 	return nil
 }
 
+func (c *redisCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	return c.items.resolve(opts...)
+}
+
+// itemSerializer returns serializer for the item, defaults to JSON.
+func itemSerializer[T any](opt *itemOptions[T]) serializer.Serializer {
+	if opt.Serializer == nil {
+		return serializer.JSON
+	}
+	return opt.Serializer
+}
+
+func (c *redisCache[T]) marshal(ctx context.Context, opt *itemOptions[T], value T) ([]byte, error) {
+	return encodeValue(ctx, c.audit, c.version, opt, value)
+}
+
+func (c *redisCache[T]) unmarshal(opt *itemOptions[T], data []byte, val *T) error {
+	return decodeValue(c.migrations, c.version, opt, data, val)
+}
+
+// encodeValue returns value encoded by the item serializer wrapped in the envelope
+// if audit envelope or schema version is used. Audit envelope records TTL override
+// requested by the writer.
+func encodeValue[T any](ctx context.Context, audit *Audit, version int, opt *itemOptions[T], value T) ([]byte, error) {
+	buf, err := itemSerializer(opt).Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var h *EnvelopeHeader
+	if audit != nil && audit.Envelope {
+		h = audit.header(ctx)
+		h.TTLOverride = opt.ttlOverride()
+	}
+	if version != 0 {
+		if h == nil {
+			h = &EnvelopeHeader{}
+		}
+		h.Version = version
+	}
+	if h != nil {
+		return encodeEnvelope(h, buf)
+	}
+	return buf, nil
+}
+
+// decodeValue decodes value migrating it to the schema version if needed.
+func decodeValue[T any](migrations map[int]MigrationFunc, version int, opt *itemOptions[T], data []byte, val *T) error {
+	h, payload, _ := DecodeEnvelope(data)
+	payload, err := migrate(migrations, h.Version, version, payload)
+	if err != nil {
+		return err
+	}
+	return itemSerializer(opt).Unmarshal(payload, val)
+}
+
 func (c *redisCache[T]) Ping(ctx context.Context) error {
-	if c.con == nil {
+	if c.closed.Load() {
 		return nil
 	}
-	s := c.con.Ping(ctx)
-	if s.Err() != nil {
-		return s.Err()
-	}
-	return nil
+	return pingRedis(ctx, c.con)
 }
 
+// Close cache instance releasing its Redis client.
 func (c *redisCache[T]) Close() error {
-	if c.con == nil {
+	if c.closed.Swap(true) {
 		return nil
 	}
-	var err error
-	switch v := c.con.(type) {
-	case *redis.Client:
-		err = v.Close()
-	case *redis.ClusterClient:
-		err = v.Close()
-	case nil:
-		// do nothing
-	default:
-		// this will not happen anyway, unless we mishandle it on `Init`
-		panic(fmt.Sprintf("invalid redis client: %v", reflect.TypeOf(v)))
-	}
-	c.con = nil
-	return err
+	if c.hedge != nil {
+		_ = closeConnection(c.hedge.con)
+	}
+	return c.ref.Release()
 }
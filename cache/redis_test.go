@@ -2,10 +2,14 @@ package cache
 
 import (
 	"context"
+	"net"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -110,3 +114,175 @@ func TestRedisCacheExpire(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, val)
 }
+
+func newMiniRedisCache(t *testing.T, opts ...CacheOption) (*Cache, *miniredis.Miniredis) {
+	t.Helper()
+
+	s := miniredis.RunT(t)
+	c := New(append([]CacheOption{CacheType(RedisCache), ConnectionString("redis://" + s.Addr())}, opts...)...)
+	require.NoError(t, c.Start(context.TODO()))
+	t.Cleanup(c.Close)
+	return c, s
+}
+
+type testUserV2 struct {
+	FullName string `json:"full_name"`
+}
+
+func TestRedisCacheMigration(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+
+	require.NoError(t, s.Set("test:legacy", `{"name":"John"}`))
+
+	i, err := Create[testUserV2](c, "test", SchemaVersion(1), WithMigration(0, func(data []byte) ([]byte, error) {
+		return []byte(strings.Replace(string(data), `"name"`, `"full_name"`, 1)), nil
+	}))
+	require.NoError(t, err)
+
+	val, err := i.Get(context.TODO(), "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "John", val.FullName)
+
+	require.NoError(t, i.Set(context.TODO(), "new", testUserV2{FullName: "Jane"}))
+	raw, err := s.Get("test:new")
+	require.NoError(t, err)
+	h, _, ok := DecodeEnvelope([]byte(raw))
+	require.True(t, ok)
+	assert.Equal(t, 1, h.Version)
+
+	val, err = i.Get(context.TODO(), "new")
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", val.FullName)
+}
+
+func TestRedisCacheQuarantine(t *testing.T) {
+	ops := make([]string, 0)
+	c, s := newMiniRedisCache(t, Instrumenter(func(ctx context.Context, op string, args ...any) func(err error) {
+		ops = append(ops, op)
+		return func(err error) {}
+	}))
+
+	require.NoError(t, s.Set("test:key", `{corrupt`))
+
+	i, err := Create[string](c, "test", Quarantine{Suffix: ":corrupt"}, Loader[string](func(ctx context.Context, key string) (string, error) {
+		return "loaded", nil
+	}))
+	require.NoError(t, err)
+
+	val, err := i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", val)
+	assert.Contains(t, ops, InstrumentationCacheCorrupt)
+
+	raw, err := s.Get("test:key:corrupt")
+	require.NoError(t, err)
+	assert.Equal(t, `{corrupt`, raw)
+
+	raw, err = s.Get("test:key")
+	require.NoError(t, err)
+	assert.Equal(t, `"loaded"`, raw)
+}
+
+func TestRedisCachePopWithMetadata(t *testing.T) {
+	c, _ := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "test", Audit{Envelope: true})
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(context.TODO(), "token", "value", TTL[string](time.Minute)))
+
+	v, meta, err := PopWithMetadata(context.TODO(), i, "token")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	assert.Equal(t, time.Minute, meta.TTL)
+	assert.False(t, meta.StoredAt.IsZero())
+
+	_, _, err = PopWithMetadata(context.TODO(), i, "token")
+	assert.ErrorIs(t, err, ErrKeyNotFound{Key: "token"})
+}
+
+func TestRedisCacheHedgedReads(t *testing.T) {
+	// Primary that never responds.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	replica := miniredis.RunT(t)
+	require.NoError(t, replica.Set("test:key", `"replica"`))
+
+	c := New(CacheType(RedisCache), ConnectionString("redis://"+l.Addr().String()))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	hedges := 0
+	i, err := Create[string](c, "test", HedgedReads{
+		ConnectionString: "redis://" + replica.Addr(),
+		Delay:            time.Millisecond,
+	}, Instrumenter(func(ctx context.Context, op string, args ...any) func(err error) {
+		if op == InstrumentationCacheHedge {
+			hedges++
+		}
+		return func(err error) {}
+	}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	v, err := i.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "replica", v)
+	assert.Equal(t, 1, hedges)
+}
+
+func TestRedisCacheCoalesce(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+	ctx := context.TODO()
+
+	i, err := Create[string](c, "test", Coalesce{Window: time.Second})
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(ctx, "hot", "value"))
+
+	before := s.CommandCount()
+	var wg sync.WaitGroup
+	for n := 0; n < 50; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := i.Get(ctx, "hot")
+			assert.NoError(t, err)
+			assert.Equal(t, "value", v)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, s.CommandCount()-before)
+
+	require.NoError(t, i.Set(ctx, "hot", "new"))
+	v, err := i.Get(ctx, "hot")
+	require.NoError(t, err)
+	assert.Equal(t, "new", v)
+}
+
+func TestRedisCacheInfo(t *testing.T) {
+	c, _ := newMiniRedisCache(t)
+
+	info, err := c.Info(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, "1", info["connected_clients"])
+
+	m := New(MemoryCache)
+	require.NoError(t, m.Start(context.TODO()))
+	defer m.Close()
+	_, err = m.Info(context.TODO())
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
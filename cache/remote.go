@@ -0,0 +1,509 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/serializer"
+
+	"github.com/goccy/go-json"
+)
+
+// remoteMaxValueSize is a maximum size of the value accepted by the remote cache server.
+const remoteMaxValueSize = 32 << 20
+
+// ParseRemoteURL parses remote cache service connection string in the format
+// https://host[:port][/path] where path is a prefix the cache service is mounted at.
+func ParseRemoteURL(v string) (*url.URL, error) {
+	u, err := url.Parse(v)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("remote: invalid URL scheme: %s", u.Scheme)
+	}
+	if len(u.Host) == 0 {
+		return nil, errors.New("remote: host not specified")
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u, nil
+}
+
+// HTTPClient used by remote cache instances to call the cache service and by
+// DynamoDB cache instances to call DynamoDB API.
+//
+// Remote cache client should present client certificate, for example rotated by
+// cert.ClientCertManager, if cache service requires mTLS authentication.
+type HTTPClient struct {
+	*http.Client
+}
+
+func (c HTTPClient) applyCache(o *cacheOptions) {
+	o.HTTPClient = c.Client
+}
+
+// RemoteError is returned when remote cache service responds with an error.
+type RemoteError struct {
+	// StatusCode of the response.
+	StatusCode int
+	// Message returned by the cache service.
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("remote: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+type remoteCache[T any] struct {
+	endpoint     string
+	client       *http.Client
+	closed       atomic.Bool
+	items        itemDefaults[T]
+	loader       func(ctx context.Context, key string) (T, error)
+	instrumenter instrumenter.Instrumenter
+
+	backendState
+}
+
+func newRemoteCache[T any](name string, opts ...CacheOption) (CacheInstance[T], error) {
+	opt := newCacheOptions(opts...)
+
+	u, err := ParseRemoteURL(opt.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	client := opt.HTTPClient
+	if client == nil {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+		}
+	}
+
+	return &remoteCache[T]{
+		endpoint:     u.String() + "/" + url.PathEscape(name) + "/",
+		client:       client,
+		items:        newItemDefaults[T](opt),
+		loader:       newLoader[T](opt),
+		instrumenter: opt.Instrumenter,
+	}, nil
+}
+
+// do sends request to the cache service and returns response status code and body.
+// Error is returned for all responses except successful and not found.
+func (c *remoteCache[T]) do(ctx context.Context, method, key string, query url.Values, body []byte) (int, []byte, error) {
+	u := c.endpoint + url.PathEscape(key)
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", serializer.JSON.ContentType())
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, remoteMaxValueSize))
+	if err != nil {
+		return 0, nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode != http.StatusNotFound {
+		return resp.StatusCode, nil, &RemoteError{
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(buf)),
+		}
+	}
+	return resp.StatusCode, buf, nil
+}
+
+func (c *remoteCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, true, opts...)
+}
+
+// peek returns value from cache without calling the loader.
+func (c *remoteCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, false, opts...)
+}
+
+// read returns value from cache. Missing value is loaded and stored if load is
+// true, otherwise ErrKeyNotFound error is returned.
+func (c *remoteCache[T]) read(ctx context.Context, key string, load bool, opts ...ItemOption[T]) (T, error) {
+	var val T
+	if c.closed.Load() {
+		return val, ErrCacheClosed
+	}
+	opt := c.items.resolve(opts...)
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, key)
+	status, buf, err := c.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		finish(err)
+		return val, err
+	}
+	if status == http.StatusNotFound {
+		if !load {
+			finish(nil)
+			return val, ErrKeyNotFound{Key: key}
+		}
+		v, err := loadAndStore(ctx, c.loader, c.Set, key, opt, opts...)
+		finish(err)
+		return v, err
+	}
+	if err := serializer.JSON.Unmarshal(buf, &val); err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return val, err
+	}
+	finish(nil)
+	return val, nil
+}
+
+func (c *remoteCache[T]) Exists(ctx context.Context, key string) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheGet, key)
+	status, _, err := c.do(ctx, http.MethodHead, key, nil, nil)
+	finish(err)
+	return err == nil && status != http.StatusNotFound, err
+}
+
+func (c *remoteCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	var val T
+	if c.closed.Load() {
+		return val, ErrCacheClosed
+	}
+
+	finishG := c.instrumenter.Observe(ctx, InstrumentationCacheGet, key)
+	finishD := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, key)
+
+	status, buf, err := c.do(ctx, http.MethodDelete, key, url.Values{"pop": {"true"}}, nil)
+	if err == nil && status == http.StatusNotFound {
+		err = ErrKeyNotFound{Key: key}
+	} else if err == nil {
+		if err = serializer.JSON.Unmarshal(buf, &val); err != nil {
+			err = fmt.Errorf("invalid cache value: %w", err)
+		}
+	}
+	if _, ok := err.(ErrKeyNotFound); ok {
+		finishD(nil)
+		finishG(nil)
+		return val, err
+	}
+	finishD(err)
+	finishG(err)
+	return val, err
+}
+
+func (c *remoteCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, key)
+
+	opt := c.items.resolve(opts...)
+	buf, err := serializer.JSON.Marshal(value)
+	if err != nil {
+		err = fmt.Errorf("invalid cache value: %w", err)
+		finish(err)
+		return err
+	}
+	var query url.Values
+	if opt.TTL != 0 {
+		query = url.Values{"ttl": {opt.TTL.String()}}
+	}
+	_, _, err = c.do(ctx, http.MethodPut, key, query, buf)
+	finish(err)
+	return err
+}
+
+func (c *remoteCache[T]) Delete(ctx context.Context, key string) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, key)
+	_, _, err := c.do(ctx, http.MethodDelete, key, nil, nil)
+	finish(err)
+	return err
+}
+
+// Ping checks that cache instance is available in the cache service.
+func (c *remoteCache[T]) Ping(ctx context.Context) error {
+	if c.closed.Load() {
+		return nil
+	}
+	status, _, err := c.do(ctx, http.MethodHead, "", nil, nil)
+	if err == nil && status == http.StatusNotFound {
+		return &RemoteError{
+			StatusCode: status,
+			Message:    "cache instance not found",
+		}
+	}
+	return err
+}
+
+func (c *remoteCache[T]) Close() {
+	if c.closed.Swap(true) {
+		return
+	}
+	c.client.CloseIdleConnections()
+}
+
+// RemoteServerOptions are options of the remote cache server.
+type RemoteServerOptions struct {
+	// Instances that can be accessed. Empty allows access to all cache instances.
+	Instances []string
+	// ReadOnly rejects changes of the values.
+	ReadOnly bool
+	// Clients are names of the client certificates allowed to access cache
+	// instances. Client certificate subject common name, DNS and URI names are
+	// matched. Empty allows all clients.
+	//
+	// Client certificates must be verified by the TLS server configuration, for
+	// example with ClientCAs loaded using cert.LoadCertPoolFromFile.
+	Clients []string
+}
+
+// RemoteServer exposes cache instances over HTTP to remote cache instances of
+// other services.
+//
+// Values are exchanged as JSON so cache instances must use JSON serializer.
+// Memory cache instances can not be exposed as their values are not shared.
+type RemoteServer struct {
+	cache *Cache
+	conf  RemoteServerOptions
+
+	lock      sync.Mutex
+	instances map[string]CacheInstance[json.RawMessage]
+}
+
+// NewRemoteServer creates new remote cache server for the cache.
+//
+// Server must be mounted at the path of the remote cache connection string, for
+// example using http.StripPrefix.
+func NewRemoteServer(cache *Cache, conf RemoteServerOptions) *RemoteServer {
+	return &RemoteServer{
+		cache:     cache,
+		conf:      conf,
+		instances: make(map[string]CacheInstance[json.RawMessage]),
+	}
+}
+
+// authorized returns true if request is made by the allowed client.
+func (s *RemoteServer) authorized(r *http.Request) bool {
+	if len(s.conf.Clients) == 0 {
+		return true
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return false
+	}
+	crt := r.TLS.VerifiedChains[0][0]
+	names := append([]string{crt.Subject.CommonName}, crt.DNSNames...)
+	for _, u := range crt.URIs {
+		names = append(names, u.String())
+	}
+	for _, allowed := range s.conf.Clients {
+		for _, name := range names {
+			if len(name) != 0 && name == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// instance returns raw cache instance or nil if access to it is not allowed.
+func (s *RemoteServer) instance(name string) (CacheInstance[json.RawMessage], error) {
+	if len(s.conf.Instances) != 0 {
+		allowed := false
+		for _, i := range s.conf.Instances {
+			allowed = allowed || i == name
+		}
+		if !allowed {
+			return nil, nil
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if i, ok := s.instances[name]; ok {
+		return i, nil
+	}
+	if newCacheOptions(instanceOptions[json.RawMessage](s.cache, name)...).Type == MemoryCache {
+		return nil, errors.New("memory cache instances can not be shared")
+	}
+	i, err := create[json.RawMessage](s.cache, name)
+	if err != nil {
+		return nil, err
+	}
+	s.instances[name] = i
+	return i, nil
+}
+
+// ServeHTTP handles cache instance value requests at the path /{instance}/{key}.
+func (s *RemoteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "client is not allowed to access cache", http.StatusForbidden)
+		return
+	}
+
+	name, key, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	name, err := url.PathUnescape(name)
+	if err == nil {
+		key, err = url.PathUnescape(key)
+	}
+	if err != nil || len(name) == 0 {
+		http.Error(w, "invalid cache key", http.StatusBadRequest)
+		return
+	}
+	i, err := s.instance(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if i == nil {
+		http.Error(w, "cache instance not found", http.StatusNotFound)
+		return
+	}
+	if len(key) == 0 {
+		// Instance availability check.
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	write := r.Method == http.MethodPut || r.Method == http.MethodDelete
+	if write && s.conf.ReadOnly {
+		http.Error(w, ErrReadOnly.Error(), http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.get(w, r, i, key)
+	case http.MethodPut:
+		s.set(w, r, i, key)
+	case http.MethodDelete:
+		s.delete(w, r, i, key)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *RemoteServer) get(w http.ResponseWriter, r *http.Request, i CacheInstance[json.RawMessage], key string) {
+	if r.Method == http.MethodHead {
+		ok, err := i.Exists(r.Context(), key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	v, err := i.Get(r.Context(), key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeRemoteValue(w, key, v)
+}
+
+func (s *RemoteServer) set(w http.ResponseWriter, r *http.Request, i CacheInstance[json.RawMessage], key string) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, remoteMaxValueSize))
+	if err != nil || !json.Valid(body) {
+		http.Error(w, "invalid cache value", http.StatusBadRequest)
+		return
+	}
+	opts := make([]ItemOption[json.RawMessage], 0, 1)
+	if t := r.URL.Query().Get("ttl"); len(t) != 0 {
+		ttl, err := time.ParseDuration(t)
+		if err != nil {
+			http.Error(w, "invalid TTL", http.StatusBadRequest)
+			return
+		}
+		opts = append(opts, TTL[json.RawMessage](ttl))
+	}
+	if err := i.Set(r.Context(), key, json.RawMessage(body), opts...); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *RemoteServer) delete(w http.ResponseWriter, r *http.Request, i CacheInstance[json.RawMessage], key string) {
+	if r.URL.Query().Get("pop") != "true" {
+		if err := i.Delete(r.Context(), key); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	v, err := i.Pop(r.Context(), key)
+	if _, ok := err.(ErrKeyNotFound); ok {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeRemoteValue(w, key, v)
+}
+
+func writeRemoteValue(w http.ResponseWriter, key string, v json.RawMessage) {
+	// Missing value is returned as nil default value.
+	if v == nil {
+		http.Error(w, ErrKeyNotFound{Key: key}.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", serializer.JSON.ContentType())
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(v)
+}
+
+// Close cache instances opened by the server.
+func (s *RemoteServer) Close() {
+	s.lock.Lock()
+	instances := s.instances
+	s.instances = make(map[string]CacheInstance[json.RawMessage])
+	s.lock.Unlock()
+
+	for _, i := range instances {
+		closeInstance(i)
+	}
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRemoteCache(t *testing.T, conf RemoteServerOptions) (*Cache, *Cache) {
+	t.Helper()
+
+	shared, _ := newMiniRedisCache(t)
+	srv := NewRemoteServer(shared, conf)
+	t.Cleanup(srv.Close)
+
+	ts := httptest.NewServer(http.StripPrefix("/cache", srv))
+	t.Cleanup(ts.Close)
+
+	c := New(CacheType(RemoteCache), ConnectionString(ts.URL+"/cache/"))
+	require.NoError(t, c.Start(context.TODO()))
+	t.Cleanup(c.Close)
+	return shared, c
+}
+
+func TestParseRemoteURL(t *testing.T) {
+	u, err := ParseRemoteURL("https://cache.local:8443/cache/?a=b")
+	require.NoError(t, err)
+	assert.Equal(t, "https://cache.local:8443/cache", u.String())
+
+	_, err = ParseRemoteURL("redis://cache.local")
+	assert.Error(t, err)
+	_, err = ParseRemoteURL("https:///cache")
+	assert.Error(t, err)
+}
+
+func TestRemoteCache(t *testing.T) {
+	shared, c := newTestRemoteCache(t, RemoteServerOptions{})
+
+	i, err := Create[testProfile](c, "profiles")
+	require.NoError(t, err)
+	require.NoError(t, c.Ping(context.TODO()))
+
+	require.NoError(t, i.Set(context.TODO(), "user/1", testProfile{Name: "John"}, TTL[testProfile](time.Minute)))
+	v, err := i.Get(context.TODO(), "user/1")
+	require.NoError(t, err)
+	assert.Equal(t, "John", v.Name)
+
+	// Value is stored in the shared cache instance.
+	si, err := Create[testProfile](shared, "profiles")
+	require.NoError(t, err)
+	v, err = si.Get(context.TODO(), "user/1")
+	require.NoError(t, err)
+	assert.Equal(t, "John", v.Name)
+	ttl, err := GetTTL(context.TODO(), si, "user/1")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	ok, err := i.Exists(context.TODO(), "user/1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	v, err = i.Pop(context.TODO(), "user/1")
+	require.NoError(t, err)
+	assert.Equal(t, "John", v.Name)
+	_, err = i.Pop(context.TODO(), "user/1")
+	assert.ErrorAs(t, err, &ErrKeyNotFound{})
+
+	v, err = i.Get(context.TODO(), "user/1", DefaultValue[testProfile]{Value: testProfile{Name: "Default"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Default", v.Name)
+
+	require.NoError(t, i.Set(context.TODO(), "user/2", testProfile{Name: "Jane"}))
+	require.NoError(t, i.Delete(context.TODO(), "user/2"))
+	ok, err = i.Exists(context.TODO(), "user/2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRemoteCacheLoader(t *testing.T) {
+	_, c := newTestRemoteCache(t, RemoteServerOptions{})
+
+	i, err := Create[string](c, "values", Loader[string](func(_ context.Context, key string) (string, error) {
+		return "loaded:" + key, nil
+	}))
+	require.NoError(t, err)
+
+	v, err := i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded:key", v)
+	ok, err := i.Exists(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestRemoteCacheReadOnly(t *testing.T) {
+	shared, c := newTestRemoteCache(t, RemoteServerOptions{ReadOnly: true, Instances: []string{"values"}})
+
+	si, err := Create[string](shared, "values")
+	require.NoError(t, err)
+	require.NoError(t, si.Set(context.TODO(), "key", "value"))
+
+	i, err := Create[string](c, "values")
+	require.NoError(t, err)
+	v, err := i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	var rerr *RemoteError
+	require.ErrorAs(t, i.Set(context.TODO(), "key", "other"), &rerr)
+	assert.Equal(t, http.StatusForbidden, rerr.StatusCode)
+	assert.Error(t, i.Delete(context.TODO(), "key"))
+
+	// Instances not in the allow list are not exposed.
+	_, err = Create[string](c, "other")
+	require.NoError(t, err)
+	require.ErrorAs(t, c.Ping(context.TODO()), &rerr)
+	assert.Equal(t, http.StatusNotFound, rerr.StatusCode)
+}
+
+func TestRemoteServerMemoryInstance(t *testing.T) {
+	c := New(CacheType(MemoryCache))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	srv := NewRemoteServer(c, RemoteServerOptions{})
+	defer srv.Close()
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/values/key", nil))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestRemoteServerClients(t *testing.T) {
+	shared, _ := newMiniRedisCache(t)
+	srv := NewRemoteServer(shared, RemoteServerOptions{Clients: []string{"edge.svc.local"}})
+	defer srv.Close()
+
+	request := func(crt *x509.Certificate) int {
+		r := httptest.NewRequest(http.MethodGet, "/values/", nil)
+		if crt != nil {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{crt}}}
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, request(nil))
+	assert.Equal(t, http.StatusForbidden, request(&x509.Certificate{Subject: pkix.Name{CommonName: "other"}}))
+	assert.Equal(t, http.StatusOK, request(&x509.Certificate{Subject: pkix.Name{CommonName: "edge"}, DNSNames: []string{"edge.svc.local"}}))
+}
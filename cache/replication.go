@@ -0,0 +1,255 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	InstrumentationCacheReplicate = "cache-replicate"
+)
+
+// ErrReplicationQueueFull is reported when write can not be queued for replication.
+var ErrReplicationQueueFull = errors.New("cache replication queue is full")
+
+// ConflictPolicy defines how replicated writes are applied to the replica.
+type ConflictPolicy int
+
+const (
+	// ConflictOverwrite always overwrites value in the replica.
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictKeepNewer does not overwrite replica values that were written later than
+	// the replicated write. Values without write time are always overwritten.
+	ConflictKeepNewer
+	// ConflictKeepExisting does not overwrite values that already exist in the replica.
+	ConflictKeepExisting
+)
+
+// Replication mirrors writes of the Redis cache instance asynchronously to the
+// secondary Redis, for example in another region.
+//
+// Reads are always served from the primary Redis. Replication lag is reported to the
+// instrumenter as "lag" label of the cache-replicate operation.
+type Replication struct {
+	// ConnectionString is a connection string of the secondary Redis.
+	ConnectionString string
+	// ConnectionPassword is a password of the secondary Redis.
+	ConnectionPassword string
+	// QueueSize is a maximum number of writes waiting for replication. Defaults to 1000.
+	QueueSize int
+	// Conflict is a policy for writes conflicting with values in the replica.
+	Conflict ConflictPolicy
+}
+
+func (r Replication) applyCache(c *cacheOptions) {
+	c.Replication = &r
+}
+
+type replicationOp[T any] struct {
+	del   bool
+	key   string
+	value T
+	opts  []ItemOption[T]
+	at    time.Time
+}
+
+type replicatedCache[T any] struct {
+	CacheInstance[T]
+	replica      *redisCache[T]
+	conflict     ConflictPolicy
+	instrumenter instrumenter.Instrumenter
+
+	lock   sync.RWMutex
+	closed bool
+	queue  chan replicationOp[T]
+	done   chan struct{}
+}
+
+func newReplicatedCache[T any](c CacheInstance[T], typ CacheType, name string, opts ...CacheOption) (CacheInstance[T], error) {
+	opt := newCacheOptions(opts...)
+	conf := opt.Replication
+
+	if !typ.isRedis() {
+		return nil, errors.New("replication is supported only for redis cache instances")
+	}
+	con, err := newRedisConnection(typ, conf.ConnectionString, conf.ConnectionPassword, opt.VirtualNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	replica, err := newRedisCache[T](name, newOwnedConn(con), opts...)
+	if err != nil {
+		_ = closeConnection(con)
+		return nil, err
+	}
+
+	rc := replica.(*redisCache[T])
+	if rc.ttlGuard != nil {
+		// Writes are already checked when stored in primary.
+		g := *rc.ttlGuard
+		g.Strict, g.Warn = false, nil
+		rc.ttlGuard = &g
+	}
+
+	size := conf.QueueSize
+	if size <= 0 {
+		size = 1000
+	}
+
+	r := &replicatedCache[T]{
+		CacheInstance: c,
+		replica:       rc,
+		conflict:      conf.Conflict,
+		instrumenter:  opt.Instrumenter,
+		queue:         make(chan replicationOp[T], size),
+		done:          make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+func (c *replicatedCache[T]) enqueue(ctx context.Context, op replicationOp[T]) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.closed {
+		return
+	}
+	select {
+	case c.queue <- op:
+	default:
+		c.instrumenter.Observe(ctx, InstrumentationCacheReplicate, c.replica.prefix+op.key)(ErrReplicationQueueFull)
+	}
+}
+
+func (c *replicatedCache[T]) run() {
+	defer close(c.done)
+
+	ctx := context.Background()
+	for op := range c.queue {
+		finish := c.instrumenter.Observe(ctx, InstrumentationCacheReplicate, c.replica.prefix+op.key,
+			instrumenter.Label{Name: "lag", Value: time.Since(op.at).String()})
+		finish(c.apply(ctx, op))
+	}
+}
+
+func (c *replicatedCache[T]) apply(ctx context.Context, op replicationOp[T]) error {
+	key := c.replica.prefix + op.key
+	if op.del {
+		return c.replica.con.Del(ctx, key).Err()
+	}
+
+	opt := c.replica.items.resolve(op.opts...)
+
+	if c.conflict == ConflictKeepNewer {
+		s := c.replica.con.Get(ctx, key)
+		if s.Err() != nil && s.Err() != redis.Nil {
+			return s.Err()
+		}
+		if h, _, ok := DecodeEnvelope([]byte(s.Val())); ok && h.WrittenAt.After(op.at) {
+			return nil
+		}
+	}
+
+	ttl, err := c.replica.ttlGuard.expiration(ctx, op.key, opt.TTL)
+	if err != nil {
+		return err
+	}
+	buf, err := c.marshal(ctx, opt, op)
+	if err != nil {
+		return err
+	}
+
+	if c.conflict == ConflictKeepExisting {
+		return c.replica.con.SetNX(ctx, key, string(buf), ttl).Err()
+	}
+	return c.replica.con.Set(ctx, key, string(buf), ttl).Err()
+}
+
+// marshal returns value encoded for the replica. Write time of the value is always
+// stored when replica conflicts are resolved by the write time.
+func (c *replicatedCache[T]) marshal(ctx context.Context, opt *itemOptions[T], op replicationOp[T]) ([]byte, error) {
+	if c.conflict != ConflictKeepNewer {
+		return c.replica.marshal(ctx, opt, op.value)
+	}
+	payload, err := itemSerializer(opt).Marshal(op.value)
+	if err != nil {
+		return nil, err
+	}
+	return encodeEnvelope(&EnvelopeHeader{Version: c.replica.version, WrittenAt: op.at.UTC()}, payload)
+}
+
+func (c *replicatedCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	v, err := c.CacheInstance.Pop(ctx, key)
+	if err == nil {
+		c.enqueue(ctx, replicationOp[T]{del: true, key: key, at: time.Now()})
+	}
+	return v, err
+}
+
+func (c *replicatedCache[T]) PopWithMetadata(ctx context.Context, key string) (T, ItemMetadata, error) {
+	v, meta, err := PopWithMetadata(ctx, c.CacheInstance, key)
+	if err == nil {
+		c.enqueue(ctx, replicationOp[T]{del: true, key: key, at: time.Now()})
+	}
+	return v, meta, err
+}
+
+func (c *replicatedCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	at := time.Now()
+	if err := c.CacheInstance.Set(ctx, key, value, opts...); err != nil {
+		return err
+	}
+	c.enqueue(ctx, replicationOp[T]{key: key, value: value, opts: opts, at: at})
+	return nil
+}
+
+func (c *replicatedCache[T]) Delete(ctx context.Context, key string) error {
+	if err := c.CacheInstance.Delete(ctx, key); err != nil {
+		return err
+	}
+	c.enqueue(ctx, replicationOp[T]{del: true, key: key, at: time.Now()})
+	return nil
+}
+
+// Close stops replication after all queued writes are applied to the replica.
+func (c *replicatedCache[T]) Close() {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.queue)
+	c.lock.Unlock()
+
+	<-c.done
+	_ = c.replica.Close()
+
+	closeInstance(c.CacheInstance)
+}
+
+func (c *replicatedCache[T]) Ping(ctx context.Context) error {
+	if p, ok := c.CacheInstance.(CacheInstancePinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *replicatedCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	return c.replica.items.resolve(opts...)
+}
+
+func (c *replicatedCache[T]) unwrap() CacheInstance[T] {
+	return c.CacheInstance
+}
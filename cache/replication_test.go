@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisCacheReplication(t *testing.T) {
+	var lock sync.Mutex
+	lags := 0
+	c, _ := newMiniRedisCache(t, Instrumenter(func(ctx context.Context, op string, args ...any) func(err error) {
+		if op == InstrumentationCacheReplicate {
+			for _, a := range args {
+				if l, ok := a.(instrumenter.Label); ok && l.Name == "lag" {
+					lock.Lock()
+					lags++
+					lock.Unlock()
+				}
+			}
+		}
+		return func(err error) {
+			assert.NoError(t, err)
+		}
+	}))
+	replica := miniredis.RunT(t)
+
+	i, err := Create[string](c, "test", Replication{ConnectionString: "redis://" + replica.Addr()})
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(context.TODO(), "a", "value"))
+	require.NoError(t, i.Set(context.TODO(), "b", "value"))
+	require.NoError(t, i.Delete(context.TODO(), "b"))
+
+	i.(CacheInstanceCloser).Close()
+
+	v, err := replica.Get("test:a")
+	require.NoError(t, err)
+	assert.Equal(t, `"value"`, v)
+	assert.False(t, replica.Exists("test:b"))
+
+	lock.Lock()
+	assert.Equal(t, 3, lags)
+	lock.Unlock()
+}
+
+func TestRedisCacheReplicationKeepNewer(t *testing.T) {
+	c, _ := newMiniRedisCache(t)
+	replica := miniredis.RunT(t)
+
+	newer, err := encodeEnvelope(&EnvelopeHeader{WrittenAt: time.Now().Add(time.Minute)}, []byte(`"newer"`))
+	require.NoError(t, err)
+	require.NoError(t, replica.Set("test:a", string(newer)))
+
+	i, err := Create[string](c, "test", Replication{
+		ConnectionString: "redis://" + replica.Addr(),
+		Conflict:         ConflictKeepNewer,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(context.TODO(), "a", "older"))
+	require.NoError(t, i.Set(context.TODO(), "b", "value"))
+
+	i.(CacheInstanceCloser).Close()
+
+	v, err := replica.Get("test:a")
+	require.NoError(t, err)
+	assert.Equal(t, string(newer), v)
+
+	v, err = replica.Get("test:b")
+	require.NoError(t, err)
+	_, payload, ok := DecodeEnvelope([]byte(v))
+	assert.True(t, ok)
+	assert.Equal(t, `"value"`, string(payload))
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Repository is a data store of values identified by ID.
+type Repository[K comparable, V any] interface {
+	// Get returns value by ID.
+	Get(ctx context.Context, id K) (V, error)
+	// List returns found values by IDs.
+	List(ctx context.Context, ids ...K) (map[K]V, error)
+	// Update stores value with ID.
+	Update(ctx context.Context, id K, value V) error
+	// Delete removes value by ID.
+	Delete(ctx context.Context, id K) error
+}
+
+// InvalidationDelay is a delay after which cached value is deleted second time after
+// the write to evict stale values cached by concurrent reads. Defaults to 1 second,
+// negative value disables second delete.
+type InvalidationDelay time.Duration
+
+func (d InvalidationDelay) applyCache(c *cacheOptions) {
+	c.InvalidationDelay = time.Duration(d)
+}
+
+type cachedRepository[K comparable, V any] struct {
+	cache CacheInstance[*V]
+	repo  Repository[K, V]
+	delay time.Duration
+}
+
+// WrapRepository returns repository that caches values in the cache instance with
+// specified name using cache-aside pattern.
+//
+// Cached values are deleted after they are written to the repository and deleted
+// again after invalidation delay. Cache failures on read fall back to the repository.
+func WrapRepository[K comparable, V any](cache *Cache, name string, repo Repository[K, V], opts ...CacheOption) (Repository[K, V], error) {
+	// Cache instance stores pointers so use defaults registered for the value type
+	// keeping instance defaults to override them.
+	opts = append(append(typeDefaults[V](cache), instanceDefaults(cache, name)...), opts...)
+
+	i, err := Create[*V](cache, name, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	delay := newCacheOptions(instanceOptions[*V](cache, name, opts...)...).InvalidationDelay
+	if delay == 0 {
+		delay = time.Second
+	}
+
+	return &cachedRepository[K, V]{
+		cache: i,
+		repo:  repo,
+		delay: delay,
+	}, nil
+}
+
+func (r *cachedRepository[K, V]) key(id K) string {
+	return fmt.Sprint(id)
+}
+
+func (r *cachedRepository[K, V]) Get(ctx context.Context, id K) (V, error) {
+	if v, err := r.cache.Get(ctx, r.key(id)); err == nil && v != nil {
+		return *v, nil
+	}
+
+	v, err := r.repo.Get(ctx, id)
+	if err != nil {
+		return v, err
+	}
+	_ = r.cache.Set(ctx, r.key(id), &v)
+	return v, nil
+}
+
+func (r *cachedRepository[K, V]) List(ctx context.Context, ids ...K) (map[K]V, error) {
+	values := make(map[K]V, len(ids))
+	missing := make([]K, 0, len(ids))
+	for _, id := range ids {
+		if v, err := r.cache.Get(ctx, r.key(id)); err == nil && v != nil {
+			values[id] = *v
+			continue
+		}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	loaded, err := r.repo.List(ctx, missing...)
+	if err != nil {
+		return nil, err
+	}
+	for id, v := range loaded {
+		v := v
+		values[id] = v
+		_ = r.cache.Set(ctx, r.key(id), &v)
+	}
+	return values, nil
+}
+
+// invalidate deletes cached value now and again after the invalidation delay.
+func (r *cachedRepository[K, V]) invalidate(ctx context.Context, id K) error {
+	key := r.key(id)
+	err := r.cache.Delete(ctx, key)
+	if r.delay > 0 {
+		time.AfterFunc(r.delay, func() {
+			_ = r.cache.Delete(context.Background(), key)
+		})
+	}
+	return err
+}
+
+func (r *cachedRepository[K, V]) Update(ctx context.Context, id K, value V) error {
+	if err := r.repo.Update(ctx, id, value); err != nil {
+		return err
+	}
+	return r.invalidate(ctx, id)
+}
+
+func (r *cachedRepository[K, V]) Delete(ctx context.Context, id K) error {
+	if err := r.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	return r.invalidate(ctx, id)
+}
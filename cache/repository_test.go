@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestNotFound = errors.New("not found")
+
+type testRepository struct {
+	lock  sync.Mutex
+	data  map[int]string
+	reads int
+}
+
+func (r *testRepository) Get(_ context.Context, id int) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.reads++
+	v, ok := r.data[id]
+	if !ok {
+		return "", errTestNotFound
+	}
+	return v, nil
+}
+
+func (r *testRepository) List(_ context.Context, ids ...int) (map[int]string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.reads++
+	values := make(map[int]string)
+	for _, id := range ids {
+		if v, ok := r.data[id]; ok {
+			values[id] = v
+		}
+	}
+	return values, nil
+}
+
+func (r *testRepository) Update(_ context.Context, id int, value string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.data[id] = value
+	return nil
+}
+
+func (r *testRepository) Delete(_ context.Context, id int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.data, id)
+	return nil
+}
+
+func TestWrapRepository(t *testing.T) {
+	c, _ := newMiniRedisCache(t)
+	ctx := context.TODO()
+
+	repo := &testRepository{data: map[int]string{1: "one", 2: "two"}}
+	r, err := WrapRepository[int, string](c, "users", repo, InvalidationDelay(20*time.Millisecond))
+	require.NoError(t, err)
+
+	v, err := r.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "one", v)
+	_, err = r.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.reads)
+
+	_, err = r.Get(ctx, 3)
+	assert.ErrorIs(t, err, errTestNotFound)
+
+	values, err := r.List(ctx, 1, 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]string{1: "one", 2: "two"}, values)
+	assert.Equal(t, 3, repo.reads)
+
+	require.NoError(t, r.Update(ctx, 1, "uno"))
+	v, err = r.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "uno", v)
+
+	// Stale value cached by concurrent read is evicted by the second delete.
+	i, err := Get[*string](c, "users")
+	require.NoError(t, err)
+	stale := "stale"
+	require.NoError(t, i.Set(ctx, "1", &stale))
+	time.Sleep(50 * time.Millisecond)
+	v, err = r.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "uno", v)
+
+	require.NoError(t, r.Delete(ctx, 2))
+	_, err = r.Get(ctx, 2)
+	assert.ErrorIs(t, err, errTestNotFound)
+}
@@ -0,0 +1,216 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"azugo.io/core/serializer"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	InstrumentationCacheRewrite = "cache-rewrite"
+)
+
+type rewriteOptions struct {
+	Rate    int
+	Sources []serializer.Serializer
+	DryRun  bool
+}
+
+// RewriteOption is an option for the cache instance rewrite.
+type RewriteOption interface {
+	applyRewrite(*rewriteOptions)
+}
+
+// RewriteRate is a maximum number of entries rewritten per second. Zero means no limit.
+type RewriteRate int
+
+func (r RewriteRate) applyRewrite(o *rewriteOptions) {
+	o.Rate = int(r)
+}
+
+// RewriteFrom are serializers tried in order to decode existing entries.
+//
+// Defaults to the current cache instance serializer.
+type RewriteFrom []serializer.Serializer
+
+func (f RewriteFrom) applyRewrite(o *rewriteOptions) {
+	o.Sources = append(o.Sources, f...)
+}
+
+// RewriteDryRun only counts entries that would be rewritten.
+type RewriteDryRun bool
+
+func (d RewriteDryRun) applyRewrite(o *rewriteOptions) {
+	o.DryRun = bool(d)
+}
+
+// RewriteResult is a result of the cache instance rewrite.
+type RewriteResult struct {
+	// Scanned is a number of scanned entries.
+	Scanned int
+	// Rewritten is a number of rewritten entries.
+	Rewritten int
+	// Unchanged is a number of entries already stored in current format.
+	Unchanged int
+	// Failed is a number of entries that could not be decoded or written.
+	Failed int
+}
+
+// rewriter represents cache instance that can rewrite its entries.
+type rewriter interface {
+	rewrite(ctx context.Context, opt *rewriteOptions) (*RewriteResult, error)
+}
+
+// Rewrite scans all entries of the cache instance and rewrites them with the current
+// serializer, schema version and storage settings keeping their remaining TTL.
+//
+// Entries changed while being rewritten are left intact. Only Redis cache instances are
+// supported, other instances return ErrNotSupported.
+func Rewrite[T any](ctx context.Context, instance CacheInstance[T], opts ...RewriteOption) (*RewriteResult, error) {
+	opt := &rewriteOptions{}
+	for _, o := range opts {
+		o.applyRewrite(opt)
+	}
+
+	r, ok := lookupInstance[T, rewriter](instance)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return r.rewrite(ctx, opt)
+}
+
+// casScript sets value only if it has not been changed keeping TTL of the key.
+//
+// KEYS[1] - key, ARGV[1] - expected value, ARGV[2] - new value.
+var casScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'KEEPTTL')
+return 1
+`)
+
+func (c *redisCache[T]) rewrite(ctx context.Context, opt *rewriteOptions) (*RewriteResult, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheRewrite, c.prefix)
+
+	var limit <-chan time.Time
+	if opt.Rate > 0 {
+		t := time.NewTicker(time.Second / time.Duration(opt.Rate))
+		defer t.Stop()
+		limit = t.C
+	}
+
+	items := c.items.resolve()
+	sources := opt.Sources
+	if len(sources) == 0 {
+		sources = []serializer.Serializer{itemSerializer(items)}
+	}
+
+	res := &RewriteResult{}
+	err := scanKeys(ctx, c.con, c.prefix, func(_ redis.Cmdable, keys []string) error {
+		for _, k := range keys {
+			key := strings.TrimPrefix(k, c.prefix)
+			if internalKey(key) {
+				continue
+			}
+			if limit != nil {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-limit:
+				}
+			}
+			res.Scanned++
+
+			changed, err := c.rewriteKey(ctx, key, items, sources, opt.DryRun)
+			switch {
+			case err != nil:
+				res.Failed++
+			case changed:
+				res.Rewritten++
+			default:
+				res.Unchanged++
+			}
+		}
+		return nil
+	})
+	finish(err)
+	return res, err
+}
+
+func (c *redisCache[T]) rewriteKey(ctx context.Context, key string, items *itemOptions[T], sources []serializer.Serializer, dryRun bool) (bool, error) {
+	s := c.con.Get(ctx, c.prefix+key)
+	raw, err := c.deref(ctx, s)
+	if err != nil {
+		return false, err
+	}
+
+	val := new(T)
+	err = errors.New("no serializers to decode value")
+	for _, src := range sources {
+		opt := *items
+		opt.Serializer = src
+		if err = c.unmarshal(&opt, []byte(raw), val); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	buf, err := c.marshal(ctx, items, *val)
+	if err != nil {
+		return false, err
+	}
+	if !c.rewriteNeeded(s.Val(), raw, buf) {
+		return false, nil
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	if c.dedup != nil {
+		ttl, err := c.con.PTTL(ctx, c.prefix+key).Result()
+		if err != nil {
+			return false, err
+		}
+		if ttl < 0 {
+			ttl = NoExpiration
+		}
+		ttl, err = c.ttlGuard.expiration(ctx, key, ttl)
+		if err != nil {
+			return false, err
+		}
+		return true, c.store(ctx, key, buf, ttl)
+	}
+
+	ok, err := casScript.Run(ctx, c.con, []string{c.prefix + key}, s.Val(), string(buf)).Bool()
+	return ok, err
+}
+
+// rewriteNeeded returns true if stored value differs from the value in current format.
+func (c *redisCache[T]) rewriteNeeded(stored, raw string, buf []byte) bool {
+	if c.dedup != nil {
+		if strings.HasPrefix(stored, refMagic) != (len(buf) >= c.dedup.minSize()) {
+			return true
+		}
+	}
+	// Envelope write time always differs so compare only payloads and versions.
+	oh, op, ook := DecodeEnvelope([]byte(raw))
+	nh, np, nok := DecodeEnvelope(buf)
+	return ook != nok || oh.Version != nh.Version || !bytes.Equal(op, np)
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"azugo.io/core/serializer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewrite(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+	ctx := context.TODO()
+
+	require.NoError(t, s.Set("test:a", `"one"`))
+	s.SetTTL("test:a", time.Hour)
+	require.NoError(t, s.Set("test:b", `"two"`))
+	require.NoError(t, s.Set("test:bad", `{corrupt`))
+	require.NoError(t, s.Set("other:c", `"three"`))
+
+	i, err := Create[string](c, "test", Serializer{serializer.MsgPack}, SchemaVersion(1), WithMigration(0, func(data []byte) ([]byte, error) {
+		return data, nil
+	}))
+	require.NoError(t, err)
+
+	res, err := Rewrite(ctx, i, RewriteFrom{serializer.JSON}, RewriteDryRun(true))
+	require.NoError(t, err)
+	assert.Equal(t, &RewriteResult{Scanned: 3, Rewritten: 2, Failed: 1}, res)
+	raw, err := s.Get("test:a")
+	require.NoError(t, err)
+	assert.Equal(t, `"one"`, raw)
+
+	res, err = Rewrite(ctx, i, RewriteFrom{serializer.MsgPack, serializer.JSON}, RewriteRate(1000))
+	require.NoError(t, err)
+	assert.Equal(t, &RewriteResult{Scanned: 3, Rewritten: 2, Failed: 1}, res)
+	assert.Equal(t, time.Hour, s.TTL("test:a"))
+
+	raw, err = s.Get("test:a")
+	require.NoError(t, err)
+	h, _, ok := DecodeEnvelope([]byte(raw))
+	require.True(t, ok)
+	assert.Equal(t, 1, h.Version)
+
+	v, err := i.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "one", v)
+
+	res, err = Rewrite(ctx, i)
+	require.NoError(t, err)
+	assert.Equal(t, &RewriteResult{Scanned: 3, Unchanged: 2, Failed: 1}, res)
+}
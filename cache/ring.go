@@ -0,0 +1,121 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"errors"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultVirtualNodes is a default number of virtual nodes for each Redis node in the hash ring.
+const DefaultVirtualNodes = 160
+
+// VirtualNodes is a number of virtual nodes for each Redis node in the consistent hash ring.
+//
+// More virtual nodes provide more even key distribution between Redis nodes.
+type VirtualNodes int
+
+func (n VirtualNodes) applyCache(c *cacheOptions) {
+	c.VirtualNodes = int(n)
+}
+
+// ketama is a consistent hash with virtual nodes.
+//
+// Key distribution depends only on the node names, so all clients with the same
+// nodes map keys to the same node, and adding or removing a node only moves keys
+// that belong to that node.
+type ketama struct {
+	hashes []uint32
+	nodes  map[uint32]string
+}
+
+func newKetama(vnodes int) func(shards []string) redis.ConsistentHash {
+	if vnodes <= 0 {
+		vnodes = DefaultVirtualNodes
+	}
+	return func(shards []string) redis.ConsistentHash {
+		k := &ketama{
+			hashes: make([]uint32, 0, len(shards)*vnodes),
+			nodes:  make(map[uint32]string, len(shards)*vnodes),
+		}
+		// Sort shards so that hash collisions are always resolved in the same way.
+		shards = append([]string{}, shards...)
+		sort.Strings(shards)
+		for _, s := range shards {
+			for i := 0; i < vnodes; i++ {
+				h := crc32.ChecksumIEEE([]byte(s + "-" + strconv.Itoa(i)))
+				if _, ok := k.nodes[h]; ok {
+					continue
+				}
+				k.nodes[h] = s
+				k.hashes = append(k.hashes, h)
+			}
+		}
+		sort.Slice(k.hashes, func(i, j int) bool { return k.hashes[i] < k.hashes[j] })
+		return k
+	}
+}
+
+// Get returns node name for the key.
+func (k *ketama) Get(key string) string {
+	if len(k.hashes) == 0 {
+		return ""
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(k.hashes), func(i int) bool { return k.hashes[i] >= h })
+	if i == len(k.hashes) {
+		i = 0
+	}
+	return k.nodes[k.hashes[i]]
+}
+
+// ParseRedisRingURL parses comma separated list of standalone Redis node URLs.
+//
+// Connection options except address are taken from the first node URL.
+func ParseRedisRingURL(v string) (*redis.RingOptions, error) {
+	var o *redis.RingOptions
+	for _, u := range strings.Split(v, ",") {
+		u = strings.TrimSpace(u)
+		if len(u) == 0 {
+			continue
+		}
+		no, err := ParseRedisURL(u)
+		if err != nil {
+			return nil, err
+		}
+		if o == nil {
+			o = &redis.RingOptions{
+				Addrs:     make(map[string]string),
+				Username:  no.Username,
+				Password:  no.Password,
+				DB:        no.DB,
+				TLSConfig: no.TLSConfig,
+			}
+		}
+		o.Addrs[no.Addr] = no.Addr
+	}
+	if o == nil {
+		return nil, errors.New("no redis nodes specified")
+	}
+	return o, nil
+}
+
+func newRedisRingClient(constr, password string, vnodes int) (redis.Cmdable, error) {
+	redisOptions, err := ParseRedisRingURL(constr)
+	if err != nil {
+		return nil, err
+	}
+	// If password is provided override provided in connection string.
+	if len(password) != 0 {
+		redisOptions.Password = password
+	}
+	redisOptions.NewConsistentHash = newKetama(vnodes)
+	return redis.NewRing(redisOptions), nil
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKetamaDistribution(t *testing.T) {
+	h := newKetama(0)([]string{"a:6379", "b:6379", "c:6379"})
+	same := newKetama(0)([]string{"c:6379", "a:6379", "b:6379"})
+	grown := newKetama(0)([]string{"a:6379", "b:6379", "c:6379", "d:6379"})
+
+	counts := make(map[string]int)
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		key := "key:" + strconv.Itoa(i)
+		node := h.Get(key)
+		counts[node]++
+		assert.Equal(t, node, same.Get(key))
+		if n := grown.Get(key); n != node {
+			assert.Equal(t, "d:6379", n, "keys must move only to the new node")
+			moved++
+		}
+	}
+
+	assert.Len(t, counts, 3)
+	for _, c := range counts {
+		assert.Greater(t, c, 2000)
+	}
+	assert.Less(t, moved, 4000)
+}
+
+func TestRedisRingCache(t *testing.T) {
+	s1 := miniredis.RunT(t)
+	s2 := miniredis.RunT(t)
+
+	connStr := "redis://" + s1.Addr() + ",redis://" + s2.Addr()
+	require.NoError(t, ValidateConnectionString(RedisRingCache, connStr))
+
+	c := New(RedisRingCache, ConnectionString(connStr))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	for n := 0; n < 50; n++ {
+		require.NoError(t, i.Set(context.TODO(), strconv.Itoa(n), "value"))
+	}
+	for n := 0; n < 50; n++ {
+		v, err := i.Get(context.TODO(), strconv.Itoa(n))
+		require.NoError(t, err)
+		assert.Equal(t, "value", v)
+	}
+
+	assert.NotEmpty(t, s1.Keys())
+	assert.NotEmpty(t, s2.Keys())
+	assert.Equal(t, 50, len(s1.Keys())+len(s2.Keys()))
+}
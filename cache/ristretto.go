@@ -0,0 +1,217 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+)
+
+// ErrNotAdmitted is returned when memory cache instance can never admit the
+// value as its cost exceeds the maximum cost of the cache instance. Value is not
+// stored but the cache instance remains usable.
+//
+// Values dropped by the admission policy or under contention when the set buffer
+// is full are not reported as errors, they are counted in SetsRejected and
+// SetsDropped memory metrics.
+var ErrNotAdmitted = errors.New("cache value not admitted")
+
+const (
+	// DefaultMemoryMaxCost is a default maximum total cost of memory cache instance items.
+	DefaultMemoryMaxCost = 1 << 30
+	// DefaultMemoryCounters is a default number of keys to track access frequency of.
+	DefaultMemoryCounters = 1_000_000
+	// DefaultMemoryBufferItems is a default number of keys per access buffer.
+	DefaultMemoryBufferItems = 64
+)
+
+// MemoryCost configures cost based admission and eviction of the memory cache
+// instance for hot read paths.
+//
+// Items are admitted using TinyLFU policy and least valuable items are evicted
+// when total cost of items exceeds the MaxCost.
+type MemoryCost struct {
+	// MaxCost is a maximum total cost of items in cache instance.
+	// Defaults to 1 GiB.
+	MaxCost int64
+	// NumCounters is a number of keys to track access frequency of. It should be
+	// about 10 times the number of items expected in cache instance when full.
+	// Defaults to 1 000 000.
+	NumCounters int64
+	// BufferItems is a number of keys per access buffer. Defaults to 64.
+	BufferItems int64
+	// Cost returns cost of the value. Defaults to size of the value in bytes
+	// (length of strings and byte slices and JSON encoded length of other values).
+	Cost func(value any) int64
+	// Metrics enables collection of cache instance metrics returned by MemoryStats.
+	Metrics bool
+}
+
+func (m MemoryCost) applyCache(o *cacheOptions) {
+	o.MemoryCost = &m
+}
+
+// MemorySyncWrites makes memory cache instance wait until the value is applied
+// before Set returns, so that the value can be read right after it is set and
+// the last of consecutive sets of a new key wins.
+//
+// By default memory cache applies sets of new keys asynchronously, so the value
+// might not be readable right after Set returns. Waiting makes concurrent writers
+// of the cache instance queue behind each other, so it should only be enabled
+// when read-your-writes is required.
+type MemorySyncWrites bool
+
+func (w MemorySyncWrites) applyCache(o *cacheOptions) {
+	o.MemorySyncWrites = bool(w)
+}
+
+// ristrettoConfig returns ristretto configuration for the memory cache instance.
+func (m *MemoryCost) ristrettoConfig() *ristretto.Config {
+	if m == nil {
+		return &ristretto.Config{
+			NumCounters: 1000,    // number of keys to track frequency of (10k).
+			MaxCost:     1 << 30, // maximum cost of cache (1GB).
+			BufferItems: 64,      // number of keys per Get buffer.
+		}
+	}
+
+	conf := &ristretto.Config{
+		NumCounters:        m.NumCounters,
+		MaxCost:            m.MaxCost,
+		BufferItems:        m.BufferItems,
+		Metrics:            m.Metrics,
+		IgnoreInternalCost: true,
+	}
+	if conf.NumCounters <= 0 {
+		conf.NumCounters = DefaultMemoryCounters
+	}
+	if conf.MaxCost <= 0 {
+		conf.MaxCost = DefaultMemoryMaxCost
+	}
+	if conf.BufferItems <= 0 {
+		conf.BufferItems = DefaultMemoryBufferItems
+	}
+	return conf
+}
+
+// cost returns cost of the value.
+func (m *MemoryCost) cost(value any) int64 {
+	if m == nil {
+		return 1
+	}
+	var cost int64
+	if m.Cost != nil {
+		cost = m.Cost(value)
+	} else {
+		cost = estimateSize(value)
+	}
+	if cost < 1 {
+		cost = 1
+	}
+	return cost
+}
+
+// store queues entry for admission to the memory cache instance.
+//
+// Ristretto decides on admission asynchronously, so only values that cost more
+// than the maximum cost can be rejected right away.
+func (c *memoryCache[T]) store(key string, e memoryEntry[T], ttl time.Duration) error {
+	cost := c.cost.cost(e.value)
+	if cost > c.cache.MaxCost() {
+		return ErrNotAdmitted
+	}
+	// Set returns false when the set buffer is full under contention. Such drops
+	// are counted by ristretto metrics and treated like admission rejections.
+	if ttl == 0 {
+		c.cache.Set(key, e, cost)
+	} else {
+		c.cache.SetWithTTL(key, e, cost, ttl)
+	}
+	return nil
+}
+
+// MemoryMetrics are metrics of the memory cache instance.
+type MemoryMetrics struct {
+	// Hits is a number of reads that found the item.
+	Hits uint64
+	// Misses is a number of reads that did not find the item.
+	Misses uint64
+	// HitRatio is a ratio of hits to all reads.
+	HitRatio float64
+	// KeysAdded is a number of new items added.
+	KeysAdded uint64
+	// KeysUpdated is a number of existing items updated.
+	KeysUpdated uint64
+	// KeysEvicted is a number of items evicted or expired.
+	KeysEvicted uint64
+	// CostAdded is a total cost of items added.
+	CostAdded uint64
+	// CostEvicted is a total cost of items evicted or expired.
+	CostEvicted uint64
+	// SetsDropped is a number of writes dropped due to contention.
+	SetsDropped uint64
+	// SetsRejected is a number of writes rejected by admission policy.
+	SetsRejected uint64
+}
+
+// memoryStatser represents cache instance that can report memory cache metrics.
+type memoryStatser interface {
+	memoryStats() (*MemoryMetrics, error)
+}
+
+// MemoryStats returns metrics of the memory cache instance.
+//
+// Only memory cache instances configured with MemoryCost having metrics enabled
+// are supported, other instances return ErrNotSupported.
+func MemoryStats[T any](instance CacheInstance[T]) (*MemoryMetrics, error) {
+	s, ok := lookupInstance[T, memoryStatser](instance)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return s.memoryStats()
+}
+
+func (c *memoryCache[T]) memoryStats() (*MemoryMetrics, error) {
+	rc := c.cache
+	if rc == nil {
+		return nil, ErrCacheClosed
+	}
+	m := rc.Metrics
+	if m == nil {
+		return nil, ErrNotSupported
+	}
+	return &MemoryMetrics{
+		Hits:         m.Hits(),
+		Misses:       m.Misses(),
+		HitRatio:     m.Ratio(),
+		KeysAdded:    m.KeysAdded(),
+		KeysUpdated:  m.KeysUpdated(),
+		KeysEvicted:  m.KeysEvicted(),
+		CostAdded:    m.CostAdded(),
+		CostEvicted:  m.CostEvicted(),
+		SetsDropped:  m.SetsDropped(),
+		SetsRejected: m.SetsRejected(),
+	}, nil
+}
+
+// onEvict removes evicted and expired items from the tag index and reports items
+// evicted to stay within the maximum cost.
+func (c *memoryCache[T]) onEvict(item *ristretto.Item) {
+	if c.closed.Load() {
+		return
+	}
+	e, ok := item.Value.(memoryEntry[T])
+	if !ok {
+		return
+	}
+	c.tags.remove(e.key, e.tags)
+	if c.cost == nil || (!item.Expiration.IsZero() && !time.Now().Before(item.Expiration)) {
+		return
+	}
+	c.instrumenter.Observe(context.Background(), InstrumentationCacheEvict, e.key)(nil)
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCacheCost(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		evicted []string
+	)
+	instr := func(_ context.Context, op string, args ...any) func(err error) {
+		if op == InstrumentationCacheEvict {
+			lock.Lock()
+			evicted = append(evicted, args[0].(string))
+			lock.Unlock()
+		}
+		return func(error) {}
+	}
+
+	c := New(MemoryCache, MemorySyncWrites(true), Instrumenter(instr))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "hot", MemoryCost{
+		MaxCost:     10,
+		NumCounters: 100,
+		Cost:        func(any) int64 { return 1 },
+		Metrics:     true,
+	})
+	require.NoError(t, err)
+
+	for n := 0; n < 50; n++ {
+		require.NoError(t, i.Set(context.TODO(), strconv.Itoa(n), "value"))
+	}
+	_, _ = i.Get(context.TODO(), "49")
+	_, _ = i.Get(context.TODO(), "missing")
+
+	m, err := MemoryStats(i)
+	require.NoError(t, err)
+	assert.Positive(t, m.KeysEvicted)
+	assert.Equal(t, uint64(1), m.Misses)
+	assert.LessOrEqual(t, m.KeysAdded-m.KeysEvicted, uint64(10))
+	assert.Greater(t, m.HitRatio, 0.0)
+
+	lock.Lock()
+	assert.Len(t, evicted, int(m.KeysEvicted))
+	lock.Unlock()
+}
+
+func TestMemoryCacheCostDefaults(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "sized", MemoryCost{MaxCost: 1024, Metrics: true})
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "key", "12345678"))
+
+	m, err := MemoryStats(i)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(8), m.CostAdded)
+
+	v, err := i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "12345678", v)
+
+	plain, err := Create[string](c, "plain")
+	require.NoError(t, err)
+	_, err = MemoryStats(plain)
+	assert.ErrorIs(t, err, ErrNotSupported)
+
+	lru, err := Create[string](c, "lru", MemoryLimit{MaxItems: 10})
+	require.NoError(t, err)
+	_, err = MemoryStats(lru)
+	assert.ErrorIs(t, err, ErrNotSupported)
+
+	_, err = Create[string](c, "both", MemoryLimit{MaxItems: 10}, MemoryCost{})
+	assert.Error(t, err)
+}
+
+func TestMemoryCacheNotAdmitted(t *testing.T) {
+	c := New(CacheType(MemoryCache), MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test", MemoryCost{MaxCost: 4}, Loader[string](func(_ context.Context, key string) (string, error) {
+		return "loaded", nil
+	}))
+	require.NoError(t, err)
+
+	// Value costing more than maximum cost is never admitted.
+	assert.ErrorIs(t, i.Set(context.TODO(), "key", "value"), ErrNotAdmitted)
+	require.NoError(t, i.Set(context.TODO(), "key", "val"))
+
+	// Loaded value is returned even if it is not admitted.
+	v, err := i.Get(context.TODO(), "other")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", v)
+	ok, err := i.Exists(context.TODO(), "other")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// errStopScan stops key scanning without returning an error.
+var errStopScan = errors.New("stop scan")
+
+// scanBatchSize is a number of keys requested from Redis in single SCAN call.
+const scanBatchSize = 500
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// scanKeys calls fn for batches of keys with the prefix on all Redis nodes.
+//
+// Returning errStopScan from fn stops scanning without an error.
+func scanKeys(ctx context.Context, con redis.Cmdable, prefix string, fn func(node redis.Cmdable, keys []string) error) error {
+	match := globEscaper.Replace(prefix) + "*"
+
+	var lock sync.Mutex
+	scan := func(ctx context.Context, node redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := node.Scan(ctx, cursor, match, scanBatchSize).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) != 0 {
+				lock.Lock()
+				err = fn(node, keys)
+				lock.Unlock()
+				if err != nil {
+					return err
+				}
+			}
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	var err error
+	switch v := con.(type) {
+	case *redis.ClusterClient:
+		err = v.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scan(ctx, node)
+		})
+	case *redis.Ring:
+		err = v.ForEachShard(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scan(ctx, node)
+		})
+	default:
+		err = scan(ctx, con)
+	}
+	if errors.Is(err, errStopScan) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultSentinelPort = "26379"
+
+// Sentinel configures cache to connect to Redis master discovered through Redis Sentinel.
+//
+// Sets cache type to RedisSentinelCache and overrides connection string.
+// Master password can be provided using ConnectionPassword option.
+type Sentinel struct {
+	// MasterName is a name of the master monitored by sentinels.
+	MasterName string
+	// Addrs is a seed list of sentinel node addresses in host:port format.
+	Addrs []string
+	// Username is an ACL user name used to authenticate to the master.
+	Username string
+	// SentinelUsername is an ACL user name used to authenticate to sentinels.
+	SentinelUsername string
+	// SentinelPassword is a password used to authenticate to sentinels.
+	SentinelPassword string
+	// DB is a database to select after connecting to the master.
+	DB int
+	// TLS enables TLS connection to the master and sentinels.
+	TLS bool
+}
+
+// URL returns Redis Sentinel connection string.
+func (s Sentinel) URL() string {
+	u := &url.URL{
+		Scheme: "redis+sentinel",
+		Host:   strings.Join(s.Addrs, ","),
+		Path:   "/" + s.MasterName,
+	}
+	if s.TLS {
+		u.Scheme = "rediss+sentinel"
+	}
+	if len(s.Username) != 0 {
+		u.User = url.User(s.Username)
+	}
+	if s.DB != 0 {
+		u.Path += "/" + strconv.Itoa(s.DB)
+	}
+	q := url.Values{}
+	if len(s.SentinelUsername) != 0 {
+		q.Set("sentinel_username", s.SentinelUsername)
+	}
+	if len(s.SentinelPassword) != 0 {
+		q.Set("sentinel_password", s.SentinelPassword)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (s Sentinel) applyCache(c *cacheOptions) {
+	c.Type = RedisSentinelCache
+	c.ConnectionString = s.URL()
+}
+
+// ParseRedisSentinelURL parses Redis Sentinel connection string.
+//
+// Connection string format is
+// redis+sentinel://[user:password@]host1:26379[,host2:26379,...]/master[/db][?options].
+// Credentials in user info are used to authenticate to the master, sentinel credentials
+// can be provided using sentinel_username and sentinel_password query parameters.
+// Use rediss+sentinel scheme to enable TLS.
+func ParseRedisSentinelURL(v string) (*redis.FailoverOptions, error) {
+	scheme, rest, ok := strings.Cut(v, "://")
+	if !ok || (scheme != "redis+sentinel" && scheme != "rediss+sentinel") {
+		return nil, fmt.Errorf("redis: invalid sentinel URL scheme: %s", scheme)
+	}
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	authority, tail := rest[:end], rest[end:]
+	var userinfo string
+	if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+		userinfo, authority = authority[:i+1], authority[i+1:]
+	}
+
+	o := &redis.FailoverOptions{}
+	for _, h := range strings.Split(authority, ",") {
+		h = strings.TrimSpace(h)
+		if len(h) == 0 {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			h = net.JoinHostPort(h, defaultSentinelPort)
+		}
+		o.SentinelAddrs = append(o.SentinelAddrs, h)
+	}
+	if len(o.SentinelAddrs) == 0 {
+		return nil, errors.New("redis: no sentinel nodes specified")
+	}
+
+	u, err := url.Parse("redis://" + userinfo + "sentinel" + tail)
+	if err != nil {
+		return nil, err
+	}
+	if u.User != nil {
+		o.Username = u.User.Username()
+		o.Password, _ = u.User.Password()
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) == 0 || len(parts[0]) == 0 {
+		return nil, errors.New("redis: sentinel master name is not specified")
+	}
+	if len(parts) > 2 {
+		return nil, fmt.Errorf("redis: invalid sentinel URL path: %s", u.Path)
+	}
+	o.MasterName = parts[0]
+	if len(parts) == 2 {
+		if o.DB, err = strconv.Atoi(parts[1]); err != nil {
+			return nil, fmt.Errorf("redis: invalid database number: %q", parts[1])
+		}
+	}
+
+	q := u.Query()
+	o.SentinelUsername = q.Get("sentinel_username")
+	o.SentinelPassword = q.Get("sentinel_password")
+	o.ClientName = q.Get("client_name")
+	if r := q.Get("replica_only"); len(r) != 0 {
+		if o.ReplicaOnly, err = strconv.ParseBool(r); err != nil {
+			return nil, fmt.Errorf("redis: invalid replica_only value: %q", r)
+		}
+	}
+	if scheme == "rediss+sentinel" {
+		o.TLSConfig = &tls.Config{}
+	}
+	if q.Get("skip_verify") == "true" {
+		if o.TLSConfig == nil {
+			o.TLSConfig = &tls.Config{}
+		}
+		o.TLSConfig.InsecureSkipVerify = true
+	}
+	return o, nil
+}
+
+func newRedisSentinelClient(constr, password string) (redis.Cmdable, error) {
+	redisOptions, err := ParseRedisSentinelURL(constr)
+	if err != nil {
+		return nil, err
+	}
+	// If password is provided override provided in connection string.
+	if len(password) != 0 {
+		redisOptions.Password = password
+	}
+	return redis.NewFailoverClient(redisOptions), nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// CacheInstanceSetNX represents cache instance that can atomically store value
+// only if the key does not exist.
+type CacheInstanceSetNX[T any] interface {
+	// SetNX stores value only if the key does not exist. Returns true if value was stored.
+	SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error)
+}
+
+// SetNX atomically stores value in the cache instance only if the key does not
+// exist and returns true if value was stored.
+//
+// It can be used for idempotent initialization and simple mutual exclusion.
+// Cache instances that can not store value atomically return ErrNotSupported.
+func SetNX[T any](ctx context.Context, instance CacheInstance[T], key string, value T, opts ...ItemOption[T]) (bool, error) {
+	if s, ok := instance.(CacheInstanceSetNX[T]); ok {
+		return s.SetNX(ctx, key, value, opts...)
+	}
+	return false, ErrNotSupported
+}
+
+// SetNX stores value only if the key does not exist using SET NX command.
+func (c *redisCache[T]) SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error) {
+	return c.set(ctx, key, value, true, opts...)
+}
+
+// SetNX stores value only if the key does not exist.
+//
+// Only concurrent SetNX calls are mutually exclusive, concurrent Set can still
+// overwrite the value.
+func (c *memoryCache[T]) SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error) {
+	if c.cache == nil {
+		return false, ErrCacheClosed
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	opt := c.items.resolve(opts...)
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, key)
+
+	if _, found := c.cache.Get(key); found {
+		finish(nil)
+		return false, nil
+	}
+	err := c.set(ctx, key, value, opt.TTL, opt.Tags...)
+	finish(err)
+	return err == nil, err
+}
+
+// SetNX stores value only if the key does not exist or has expired.
+func (c *lruCache[T]) SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error) {
+	opt := c.defaults.resolve(opts...)
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSet, key)
+
+	stored, err := c.put(ctx, key, value, opt.TTL, true, opt.Tags...)
+	finish(err)
+	return stored, err
+}
+
+func (c *readOnlyCache[T]) SetNX(ctx context.Context, key string, _ T, _ ...ItemOption[T]) (bool, error) {
+	return false, c.reject(ctx, InstrumentationCacheSet, key)
+}
+
+func (c *modeCache[T]) SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error) {
+	m, st := c.current()
+	if m == ModeReadOnly {
+		return false, ErrReadOnly
+	}
+	stored, err := SetNX(ctx, c.CacheInstance, key, value, opts...)
+	if err == nil && stored && m == ModeFreeze {
+		c.freeze(st, key, value)
+	}
+	return stored, err
+}
+
+func (c *slidingCache[T]) SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error) {
+	return SetNX(ctx, c.CacheInstance, key, value, opts...)
+}
+
+func (c *eventCache[T]) SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error) {
+	stored, err := SetNX(ctx, c.CacheInstance, key, value, opts...)
+	if err != nil || !stored {
+		return stored, err
+	}
+	c.emit(ctx, EventSet, key, &value, opts...)
+	return true, nil
+}
+
+func (c *replicatedCache[T]) SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error) {
+	at := time.Now()
+	stored, err := SetNX(ctx, c.CacheInstance, key, value, opts...)
+	if err != nil || !stored {
+		return stored, err
+	}
+	c.enqueue(ctx, replicationOp[T]{key: key, value: value, opts: opts, at: at})
+	return true, nil
+}
+
+func (c *tieredCache[T]) SetNX(ctx context.Context, key string, value T, opts ...ItemOption[T]) (bool, error) {
+	stored, err := SetNX(ctx, c.CacheInstance, key, value, opts...)
+	if err != nil || !stored {
+		// Local copy could be stale if value is already stored by other instance.
+		_ = c.local.Delete(ctx, key)
+		return stored, err
+	}
+	if c.changed(key, value, false) {
+		_ = c.local.set(ctx, key, value, c.localTTL(opts...))
+	}
+	c.invalidate(ctx, key)
+	return true, nil
+}
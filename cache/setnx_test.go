@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSetNX(t *testing.T, i CacheInstance[string]) {
+	t.Helper()
+
+	ok, err := SetNX(context.TODO(), i, "lock", "first", TTL[string](time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = SetNX(context.TODO(), i, "lock", "second")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	v, err := i.Get(context.TODO(), "lock")
+	require.NoError(t, err)
+	assert.Equal(t, "first", v)
+
+	require.NoError(t, i.Delete(context.TODO(), "lock"))
+
+	var stored atomic.Int32
+	var wg sync.WaitGroup
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := SetNX(context.TODO(), i, "lock", "value")
+			assert.NoError(t, err)
+			if ok {
+				stored.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), stored.Load())
+}
+
+func TestRedisCacheSetNX(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testSetNX(t, i)
+
+	ok, err := SetNX(context.TODO(), i, "ttl", "value", TTL[string](time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, s.TTL("test:ttl"))
+}
+
+func TestRedisCacheSetNXDeduplicate(t *testing.T) {
+	c, _ := newMiniRedisCache(t, Deduplicate{MinSize: 1})
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testSetNX(t, i)
+
+	value := strings.Repeat("x", 100)
+	ok, err := SetNX(context.TODO(), i, "lock", value)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestMemoryCacheSetNX(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testSetNX(t, i)
+}
+
+func TestLRUCacheSetNX(t *testing.T) {
+	c := New(MemoryCache, MemoryLimit{MaxItems: 10})
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testSetNX(t, i)
+}
+
+func TestMemcachedCacheSetNX(t *testing.T) {
+	c, _ := newTestMemcachedCache(t)
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testSetNX(t, i)
+}
+
+func TestBoltCacheSetNX(t *testing.T) {
+	c := newTestBoltCache[string](t, filepath.Join(t.TempDir(), "cache.db"))
+	now := time.Unix(1700000000, 0)
+	c.now = func() time.Time { return now }
+
+	testSetNX(t, c)
+
+	now = now.Add(time.Hour)
+	ok, err := c.SetNX(context.TODO(), "lock", "expired")
+	require.NoError(t, err)
+	assert.False(t, ok, "key without TTL must not expire")
+
+	require.NoError(t, c.Set(context.TODO(), "ttl", "old", TTL[string](time.Minute)))
+	now = now.Add(time.Hour)
+	ok, err = c.SetNX(context.TODO(), "ttl", "new")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestSetNXReadOnly(t *testing.T) {
+	c := New(MemoryCache)
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test", ReadOnly{})
+	require.NoError(t, err)
+
+	ok, err := SetNX(context.TODO(), i, "lock", "value")
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.False(t, ok)
+}
+
+func TestSetNXNotSupported(t *testing.T) {
+	_, err := SetNX[string](context.TODO(), testMapCache[string]{}, "lock", "value")
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// SlidingExpiration resets lifetime of the item on every successful Get so
+// that items expire only after they have not been read for the TTL.
+//
+// Lifetime is reset using Touch so cache instance must support it, otherwise
+// cache instance creation fails. Only items found in cache are touched and
+// Get returns error if their lifetime can not be reset. Lifetime is not reset
+// while cache instance is read-only or frozen.
+type SlidingExpiration struct {
+	// TTL is a lifetime of the item after it was last read. Defaults to the
+	// item TTL resolved for Get, items without TTL are not touched.
+	TTL time.Duration
+}
+
+func (s SlidingExpiration) applyCache(c *cacheOptions) {
+	c.SlidingExpiration = &s
+}
+
+type slidingCache[T any] struct {
+	CacheInstance[T]
+	conf SlidingExpiration
+	load bool
+}
+
+func newSlidingCache[T any](c CacheInstance[T], conf SlidingExpiration, load bool) CacheInstance[T] {
+	return &slidingCache[T]{
+		CacheInstance: c,
+		conf:          conf,
+		load:          load,
+	}
+}
+
+// ttl returns lifetime to reset items to or zero if items are not touched.
+func (c *slidingCache[T]) ttl(opts ...ItemOption[T]) time.Duration {
+	ttl := c.conf.TTL
+	if ttl == 0 {
+		ttl = c.itemOptions(opts...).TTL
+	}
+	if ttl <= 0 {
+		return 0
+	}
+	return ttl
+}
+
+// slide resets lifetime of the items found in cache. Items deleted or expired
+// in the meantime are skipped.
+func (c *slidingCache[T]) slide(ctx context.Context, ttl time.Duration, keys ...string) error {
+	for _, key := range keys {
+		if ok, _ := checkWritable(ctx, c.CacheInstance, InstrumentationCacheSet, key); !ok {
+			return nil
+		}
+		if err := Touch(ctx, c.CacheInstance, key, ttl); err != nil && !isKeyNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *slidingCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	ttl := c.ttl(opts...)
+	if ttl == 0 {
+		return c.CacheInstance.Get(ctx, key, opts...)
+	}
+	// Value is read without loading it first so that only items found in
+	// cache are touched.
+	v, err := peek(ctx, c.CacheInstance, key, opts...)
+	if isKeyNotFound(err) {
+		if !c.load {
+			return c.itemOptions(opts...).DefaultValue, nil
+		}
+		return c.CacheInstance.Get(ctx, key, opts...)
+	}
+	if err != nil {
+		return v, err
+	}
+	return v, c.slide(ctx, ttl, key)
+}
+
+// peek returns value from cache without calling the loader and resets lifetime
+// of the value found in cache.
+func (c *slidingCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	v, err := peek(ctx, c.CacheInstance, key, opts...)
+	if err != nil {
+		return v, err
+	}
+	if ttl := c.ttl(opts...); ttl != 0 {
+		return v, c.slide(ctx, ttl, key)
+	}
+	return v, nil
+}
+
+func (c *slidingCache[T]) PopWithMetadata(ctx context.Context, key string) (T, ItemMetadata, error) {
+	return PopWithMetadata(ctx, c.CacheInstance, key)
+}
+
+func (c *slidingCache[T]) Close() {
+	closeInstance(c.CacheInstance)
+}
+
+func (c *slidingCache[T]) Ping(ctx context.Context) error {
+	if p, ok := c.CacheInstance.(CacheInstancePinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *slidingCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	if r, ok := c.CacheInstance.(itemOptionsResolver[T]); ok {
+		return r.itemOptions(opts...)
+	}
+	return newItemOptions(opts...)
+}
+
+func (c *slidingCache[T]) unwrap() CacheInstance[T] {
+	return c.CacheInstance
+}
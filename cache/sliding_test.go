@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisSlidingExpiration(t *testing.T) {
+	c, mr := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "sessions", DefaultTTL(time.Minute), SlidingExpiration{})
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "session", "value"))
+
+	for n := 0; n < 3; n++ {
+		mr.FastForward(45 * time.Second)
+		v, err := i.Get(context.TODO(), "session")
+		require.NoError(t, err)
+		assert.Equal(t, "value", v)
+	}
+	ttl, err := GetTTL(context.TODO(), i, "session")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	// Exists does not reset lifetime.
+	mr.FastForward(45 * time.Second)
+	ok, err := i.Exists(context.TODO(), "session")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	mr.FastForward(30 * time.Second)
+	ok, err = i.Exists(context.TODO(), "session")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Missing keys are not touched.
+	n := mr.CommandCount()
+	v, err := i.Get(context.TODO(), "missing")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+	assert.Equal(t, n+1, mr.CommandCount())
+}
+
+func TestRedisSlidingExpirationModes(t *testing.T) {
+	c, mr := newMiniRedisCache(t)
+
+	var calls int
+	i, err := Create[string](c, "sessions", DefaultTTL(time.Minute), SlidingExpiration{}, ModeSwitch{}, Loader[string](func(_ context.Context, key string) (string, error) {
+		calls++
+		return "loaded", nil
+	}))
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "session", "value"))
+
+	// Lifetime is not reset while instance is read-only.
+	require.NoError(t, c.SetMode("sessions", ModeReadOnly))
+	mr.FastForward(45 * time.Second)
+	v, err := i.Get(context.TODO(), "session")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	ttl, err := GetTTL(context.TODO(), i, "session")
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Second, ttl)
+
+	// Cached values are not read or touched in bypass mode.
+	require.NoError(t, c.SetMode("sessions", ModeBypass))
+	v, err = i.Get(context.TODO(), "session")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", v)
+	assert.Equal(t, 1, calls)
+
+	// Missing value is loaded and stored with its TTL.
+	require.NoError(t, c.SetMode("sessions", ModeNormal))
+	v, err = i.Get(context.TODO(), "missing")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", v)
+	assert.Equal(t, 2, calls)
+	ttl, err = GetTTL(context.TODO(), i, "missing")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+}
+
+func TestMemorySlidingExpiration(t *testing.T) {
+	c := New(CacheType(MemoryCache), MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "sessions", SlidingExpiration{TTL: 300 * time.Millisecond})
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "session", "value", TTL[string](time.Hour)))
+
+	_, err = i.Get(context.TODO(), "session")
+	require.NoError(t, err)
+	ttl, err := GetTTL(context.TODO(), i, "session")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, ttl, 300*time.Millisecond)
+
+	values, err := GetMulti(context.TODO(), i, []string{"session"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"session": "value"}, values)
+}
+
+func TestSlidingExpirationNotSupported(t *testing.T) {
+	c := New(CacheType(MemoryCache))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	_, err := Create[string](c, "sessions", ReadOnly{}, SlidingExpiration{})
+	assert.Error(t, err)
+
+	_, err = Create[string](c, "remote", CacheType(RemoteCache), ConnectionString("https://cache.local/cache"), SlidingExpiration{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,395 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"azugo.io/core/snapshot"
+
+	"github.com/redis/go-redis/v9"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	InstrumentationCacheSnapshot = "cache-snapshot"
+)
+
+type dumpOptions struct {
+	Compression snapshot.Compression
+	Metadata    map[string]string
+}
+
+// DumpOption is an option for the cache instance dump.
+type DumpOption interface {
+	applyDump(*dumpOptions)
+}
+
+// DumpCompression is a compression of the dump. Defaults to no compression.
+type DumpCompression snapshot.Compression
+
+func (c DumpCompression) applyDump(o *dumpOptions) {
+	o.Compression = snapshot.Compression(c)
+}
+
+// DumpMetadata are additional properties stored in the dump header.
+type DumpMetadata map[string]string
+
+func (m DumpMetadata) applyDump(o *dumpOptions) {
+	o.Metadata = m
+}
+
+// snapshotter represents cache instance that can dump and restore its entries.
+type snapshotter interface {
+	// snapshotHeader returns header describing dumped entries.
+	snapshotHeader() snapshot.Header
+	// dump calls fn for every entry of the cache instance.
+	dump(ctx context.Context, fn func(e snapshot.Entry) error) error
+	// restore stores entry in the cache instance.
+	restore(ctx context.Context, e snapshot.Entry) error
+}
+
+// Dump writes all entries of the cache instance to the writer in the snapshot format.
+//
+// Only Redis, bolt and memory cache instances with limits or persistence are
+// supported, other instances return ErrNotSupported.
+func Dump[T any](ctx context.Context, instance CacheInstance[T], w io.Writer, opts ...DumpOption) error {
+	opt := &dumpOptions{}
+	for _, o := range opts {
+		o.applyDump(opt)
+	}
+
+	s, ok := lookupInstance[T, snapshotter](instance)
+	if !ok {
+		return ErrNotSupported
+	}
+	h := s.snapshotHeader()
+	h.Compression = opt.Compression
+	h.Metadata = opt.Metadata
+	return writeSnapshot(ctx, s, h, w)
+}
+
+func writeSnapshot(ctx context.Context, s snapshotter, h snapshot.Header, w io.Writer) error {
+	sw, err := snapshot.NewWriter(w, h)
+	if err != nil {
+		return err
+	}
+	if err := s.dump(ctx, sw.Write); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+// Restore reads entries from the snapshot and stores them in the cache instance
+// keeping their remaining TTL. Returns number of restored entries.
+//
+// Entries that have already expired are skipped and existing entries not
+// present in the snapshot are kept. Snapshot must be created from the cache
+// instance with the same serializer. Only Redis, bolt and memory cache instances
+// with limits or persistence are supported, other instances return ErrNotSupported.
+func Restore[T any](ctx context.Context, instance CacheInstance[T], r io.Reader) (int, error) {
+	if ro, ok := lookupInstance[T, *readOnlyCache[T]](instance); ok {
+		return 0, ro.reject(ctx, InstrumentationCacheSet, "")
+	}
+	s, ok := lookupInstance[T, snapshotter](instance)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return readSnapshot(ctx, s, r)
+}
+
+func readSnapshot(ctx context.Context, s snapshotter, r io.Reader) (int, error) {
+	sr, err := snapshot.NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+	if h, expected := sr.Header(), s.snapshotHeader(); len(h.Serializer) != 0 && h.Serializer != expected.Serializer {
+		return 0, fmt.Errorf("snapshot serializer %q does not match cache instance serializer %q", h.Serializer, expected.Serializer)
+	}
+
+	now := time.Now()
+	var n int
+	for {
+		e, err := sr.Next()
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if e.Expired(now) {
+			continue
+		}
+		if err := s.restore(ctx, e); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// SnapshotHandler backs up and restores cache instance entries in the snapshot format.
+//
+// SnapshotHandler implements backup.Handler interface.
+type SnapshotHandler[T any] struct {
+	Instance CacheInstance[T]
+	Options  []DumpOption
+}
+
+// Backup writes all entries of the cache instance to the writer.
+func (h SnapshotHandler[T]) Backup(ctx context.Context, w io.Writer) error {
+	return Dump(ctx, h.Instance, w, h.Options...)
+}
+
+// Restore stores entries from the backup in the cache instance.
+func (h SnapshotHandler[T]) Restore(ctx context.Context, r io.Reader) error {
+	_, err := Restore(ctx, h.Instance, r)
+	return err
+}
+
+// MemorySnapshot persists memory cache instance entries to the file in the
+// snapshot format when it is closed and restores them when it is created.
+//
+// Memory cache instances with persistence use least recently used eviction and
+// can be combined with MemoryLimit.
+type MemorySnapshot struct {
+	// Path to the snapshot file.
+	Path string
+	// Compression of the snapshot. Defaults to no compression.
+	Compression snapshot.Compression
+}
+
+func (s MemorySnapshot) applyCache(o *cacheOptions) {
+	o.MemorySnapshot = &s
+}
+
+func (c *redisCache[T]) snapshotHeader() snapshot.Header {
+	return snapshot.Header{
+		Source:     strings.TrimSuffix(c.prefix, ":"),
+		Serializer: itemSerializer(c.items.resolve()).ContentType(),
+	}
+}
+
+func (c *redisCache[T]) dump(ctx context.Context, fn func(e snapshot.Entry) error) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	return scanKeys(ctx, c.con, c.prefix, func(node redis.Cmdable, keys []string) error {
+		// Deduplicated payloads are dumped as part of the entries referencing them
+		// and tag indexes are not dumped.
+		filtered := keys[:0]
+		for _, k := range keys {
+			if !internalKey(strings.TrimPrefix(k, c.prefix)) {
+				filtered = append(filtered, k)
+			}
+		}
+		keys = filtered
+		if len(keys) == 0 {
+			return nil
+		}
+
+		values := make([]*redis.StringCmd, 0, len(keys))
+		ttls := make([]*redis.DurationCmd, 0, len(keys))
+		if _, err := node.Pipelined(ctx, func(p redis.Pipeliner) error {
+			for _, k := range keys {
+				values = append(values, p.Get(ctx, k))
+				ttls = append(ttls, p.PTTL(ctx, k))
+			}
+			return nil
+		}); err != nil && err != redis.Nil {
+			return err
+		}
+
+		now := time.Now()
+		for i, k := range keys {
+			key := strings.TrimPrefix(k, c.prefix)
+			v, err := c.deref(ctx, values[i])
+			if err == redis.Nil {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			e := snapshot.Entry{Key: key, Value: []byte(v)}
+			if ttl := ttls[i].Val(); ttl > 0 {
+				e.ExpiresAt = now.Add(ttl)
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *redisCache[T]) restore(ctx context.Context, e snapshot.Entry) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	_, err := c.put(ctx, e.Key, e.Value, e.TTL(time.Now()), false)
+	return err
+}
+
+func (c *boltCache[T]) snapshotHeader() snapshot.Header {
+	return snapshot.Header{
+		Source:     c.prefix,
+		Serializer: itemSerializer(c.items.resolve()).ContentType(),
+	}
+}
+
+func (c *boltCache[T]) dump(ctx context.Context, fn func(e snapshot.Entry) error) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	now := c.now()
+	return c.file.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(c.bucket).ForEach(func(k, v []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if boltExpired(v, now) {
+				return nil
+			}
+			e := snapshot.Entry{
+				Key:   string(k),
+				Value: append([]byte{}, v[boltExpiresSize:]...),
+			}
+			if exp := int64(binary.BigEndian.Uint64(v)); exp != 0 {
+				e.ExpiresAt = time.Unix(0, exp)
+			}
+			return fn(e)
+		})
+	})
+}
+
+func (c *boltCache[T]) restore(_ context.Context, e snapshot.Entry) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	v := make([]byte, boltExpiresSize+len(e.Value))
+	if !e.ExpiresAt.IsZero() {
+		binary.BigEndian.PutUint64(v, uint64(e.ExpiresAt.UnixNano()))
+	}
+	copy(v[boltExpiresSize:], e.Value)
+
+	return c.file.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(c.bucket).Put([]byte(e.Key), v)
+	})
+}
+
+func (c *lruCache[T]) snapshotHeader() snapshot.Header {
+	return snapshot.Header{
+		Serializer: itemSerializer(c.defaults.resolve()).ContentType(),
+	}
+}
+
+func (c *lruCache[T]) dump(ctx context.Context, fn func(e snapshot.Entry) error) error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return ErrCacheClosed
+	}
+	entries := c.entries()
+	c.lock.Unlock()
+
+	return c.encodeEntries(ctx, entries, fn)
+}
+
+// entries returns live entries from least to most recently used so that
+// restored cache keeps the same eviction order.
+//
+// Must be called with lock held.
+func (c *lruCache[T]) entries() []lruEntry[T] {
+	now := c.now()
+	entries := make([]lruEntry[T], 0, len(c.items))
+	for el := c.order.Back(); el != nil; el = el.Prev() {
+		if e := el.Value.(*lruEntry[T]); !e.expired(now) {
+			entries = append(entries, *e)
+		}
+	}
+	return entries
+}
+
+func (c *lruCache[T]) encodeEntries(ctx context.Context, entries []lruEntry[T], fn func(e snapshot.Entry) error) error {
+	opt := c.defaults.resolve()
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		buf, err := encodeValue(ctx, nil, 0, opt, e.value)
+		if err != nil {
+			return fmt.Errorf("invalid cache value: %w", err)
+		}
+		if err := fn(snapshot.Entry{Key: e.key, Value: buf, ExpiresAt: e.expiresAt}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *lruCache[T]) restore(ctx context.Context, e snapshot.Entry) error {
+	var value T
+	if err := decodeValue(nil, 0, c.defaults.resolve(), e.Value, &value); err != nil {
+		return fmt.Errorf("invalid snapshot entry %q: %w", e.Key, err)
+	}
+	_, err := c.put(ctx, e.Key, value, e.TTL(c.now()), false)
+	return err
+}
+
+// loadSnapshot restores entries from the snapshot file if it exists.
+func (c *lruCache[T]) loadSnapshot(ctx context.Context) error {
+	f, err := os.Open(c.persist.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSnapshot, c.persist.Path)
+	_, err = readSnapshot(ctx, c, f)
+	finish(err)
+	return err
+}
+
+// saveSnapshot atomically writes entries to the snapshot file.
+func (c *lruCache[T]) saveSnapshot(ctx context.Context, entries []lruEntry[T]) error {
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheSnapshot, c.persist.Path)
+
+	f, err := os.CreateTemp(filepath.Dir(c.persist.Path), filepath.Base(c.persist.Path)+".*")
+	if err != nil {
+		finish(err)
+		return err
+	}
+	tmp := f.Name()
+
+	h := c.snapshotHeader()
+	h.Compression = c.persist.Compression
+	sw, err := snapshot.NewWriter(f, h)
+	if err == nil {
+		if err = c.encodeEntries(ctx, entries, sw.Write); err == nil {
+			err = sw.Close()
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, c.persist.Path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	finish(err)
+	return err
+}
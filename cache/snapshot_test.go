@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"azugo.io/core/serializer"
+	"azugo.io/core/snapshot"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisDumpRestore(t *testing.T) {
+	c, _ := newMiniRedisCache(t, Deduplicate{MinSize: 4})
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(context.TODO(), "a", "first"))
+	require.NoError(t, i.Set(context.TODO(), "b", "second", TTL[string](time.Hour)))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Dump(context.TODO(), i, buf, DumpCompression(snapshot.Gzip), DumpMetadata{"env": "test"}))
+
+	info, err := snapshot.Validate(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Entries)
+	assert.Equal(t, "test", info.Source)
+	assert.Equal(t, "test", info.Metadata["env"])
+
+	// Snapshot can be restored to the cache instance of other type.
+	m := New(MemoryCache)
+	require.NoError(t, m.Start(context.TODO()))
+	defer m.Close()
+
+	mi, err := Create[string](m, "test", MemoryLimit{MaxItems: 10})
+	require.NoError(t, err)
+
+	n, err := Restore(context.TODO(), mi, bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	v, err := mi.Get(context.TODO(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, "first", v)
+
+	// Dump of the memory cache instance restores to Redis.
+	buf.Reset()
+	require.NoError(t, Dump(context.TODO(), mi, buf))
+	require.NoError(t, i.Delete(context.TODO(), "a"))
+	require.NoError(t, i.Delete(context.TODO(), "b"))
+	n, err = Restore(context.TODO(), i, bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	v, meta, err := PopWithMetadata(context.TODO(), i, "b")
+	require.NoError(t, err)
+	assert.Equal(t, "second", v)
+	assert.InDelta(t, time.Hour.Seconds(), meta.TTL.Seconds(), 5)
+
+	v, err = i.Get(context.TODO(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, "first", v)
+}
+
+func TestBoltDumpRestore(t *testing.T) {
+	src := newTestBoltCache[string](t, filepath.Join(t.TempDir(), "src.db"))
+	dst := newTestBoltCache[string](t, filepath.Join(t.TempDir(), "dst.db"))
+
+	require.NoError(t, src.Set(context.TODO(), "a", "1"))
+	require.NoError(t, src.Set(context.TODO(), "b", "2", TTL[string](time.Hour)))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, SnapshotHandler[string]{Instance: src}.Backup(context.TODO(), buf))
+	require.NoError(t, SnapshotHandler[string]{Instance: dst}.Restore(context.TODO(), buf))
+
+	v, err := dst.Get(context.TODO(), "b")
+	require.NoError(t, err)
+	assert.Equal(t, "2", v)
+}
+
+func TestMemorySnapshotPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+
+	c := New(MemoryCache)
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[int](c, "test", MemorySnapshot{Path: path, Compression: snapshot.Gzip})
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(context.TODO(), "a", 1))
+	require.NoError(t, i.Set(context.TODO(), "b", 2, TTL[int](time.Hour)))
+	closeInstance(i)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	info, err := snapshot.Validate(f)
+	f.Close()
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Entries)
+
+	i, err = Create[int](c, "test", MemorySnapshot{Path: path})
+	require.NoError(t, err)
+	defer closeInstance(i)
+
+	v, err := i.Get(context.TODO(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+	v, err = i.Get(context.TODO(), "b")
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+
+	// Snapshot of the other serializer is rejected.
+	_, err = Create[int](c, "test", MemorySnapshot{Path: path}, Serializer{serializer.MsgPack})
+	assert.Error(t, err)
+
+	_, err = Create[int](c, "test", MemorySnapshot{Path: path}, MemoryCost{})
+	assert.Error(t, err)
+}
+
+func TestRestoreReadOnly(t *testing.T) {
+	c := New(MemoryCache)
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test", MemoryLimit{MaxItems: 10})
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "a", "1"))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Dump(context.TODO(), i, buf))
+
+	ro, err := Create[string](c, "test", MemoryLimit{MaxItems: 10}, ReadOnly{})
+	require.NoError(t, err)
+	_, err = Restore(context.TODO(), ro, bytes.NewReader(buf.Bytes()))
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	_, err = Restore[string](context.TODO(), testMapCache[string]{}, bytes.NewReader(buf.Bytes()))
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
@@ -0,0 +1,271 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tagKeyPrefix is a key prefix of tag indexes in the cache instance namespace.
+const tagKeyPrefix = "~tag:"
+
+// internalKey reports whether key without the instance prefix is used to store
+// deduplicated payloads, tag indexes or soft deleted values.
+func internalKey(key string) bool {
+	return strings.HasPrefix(key, blobKeyPrefix) || strings.HasPrefix(key, tagKeyPrefix) || strings.HasPrefix(key, tombstoneKeyPrefix)
+}
+
+// Tags attached to the item so that it can be deleted with InvalidateTag when
+// entity it is derived from changes.
+//
+// Tags are ignored by cache instances that do not support tag invalidation.
+type Tags[T any] []string
+
+//nolint:unused
+func (t Tags[T]) applyItem(c *itemOptions[T]) {
+	c.Tags = append(c.Tags, t...)
+}
+
+// WithTags returns option to attach tags to the item.
+func WithTags[T any](tags ...string) Tags[T] {
+	return Tags[T](tags)
+}
+
+// CacheInstanceTagInvalidator represents cache instance that can delete items by tag.
+type CacheInstanceTagInvalidator[T any] interface {
+	// InvalidateTag deletes all items with the tag.
+	InvalidateTag(ctx context.Context, tag string) error
+}
+
+// InvalidateTag deletes all items of the cache instance stored with the tag.
+//
+// Items tagged concurrently with the invalidation may be left in the cache.
+// Remote cache instances do not track items stored again without the tag so such
+// items can be deleted as well. Cache instances that do not support tags return ErrNotSupported.
+func InvalidateTag[T any](ctx context.Context, instance CacheInstance[T], tag string) error {
+	if s, ok := instance.(CacheInstanceTagInvalidator[T]); ok {
+		return s.InvalidateTag(ctx, tag)
+	}
+	return ErrNotSupported
+}
+
+// tagIndex maps tags to keys of the in-memory cache instance items.
+type tagIndex struct {
+	lock sync.Mutex
+	keys map[string]map[string]struct{}
+}
+
+// add key to the tags.
+func (x *tagIndex) add(key string, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	if x.keys == nil {
+		x.keys = make(map[string]map[string]struct{})
+	}
+	for _, tag := range tags {
+		keys, ok := x.keys[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			x.keys[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// remove key from the tags.
+func (x *tagIndex) remove(key string, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	for _, tag := range tags {
+		if keys, ok := x.keys[tag]; ok {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(x.keys, tag)
+			}
+		}
+	}
+}
+
+// take returns keys with the tag and removes the tag.
+func (x *tagIndex) take(tag string) []string {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	keys := make([]string, 0, len(x.keys[tag]))
+	for key := range x.keys[tag] {
+		keys = append(keys, key)
+	}
+	delete(x.keys, tag)
+	return keys
+}
+
+// reset removes all tags.
+func (x *tagIndex) reset() {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	x.keys = nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// redisTagScript adds key to the tag index extending its expiration to
+// outlive all tagged items.
+//
+// KEYS[1] - tag index key, ARGV[1] - item TTL in milliseconds or 0 for no
+// expiration, ARGV[2] - item key.
+var redisTagScript = redis.NewScript(`
+local existed = redis.call('EXISTS', KEYS[1])
+redis.call('SADD', KEYS[1], ARGV[2])
+local ttl = tonumber(ARGV[1])
+if ttl == 0 then
+	redis.call('PERSIST', KEYS[1])
+	return 1
+end
+local current = redis.call('PTTL', KEYS[1])
+if existed == 0 or (current >= 0 and current < ttl) then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`)
+
+// redisTakeTagScript returns keys of the tag index and deletes it.
+//
+// KEYS[1] - tag index key.
+var redisTakeTagScript = redis.NewScript(`
+local keys = redis.call('SMEMBERS', KEYS[1])
+redis.call('DEL', KEYS[1])
+return keys
+`)
+
+// tag adds key to the tag indexes.
+func (c *redisCache[T]) tag(ctx context.Context, key string, tags []string, ttl time.Duration) error {
+	for _, tag := range tags {
+		if err := redisTagScript.Run(ctx, c.con, []string{c.prefix + tagKeyPrefix + tag}, ttl.Milliseconds(), key).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *redisCache[T]) InvalidateTag(ctx context.Context, tag string) error {
+	if c.closed.Load() {
+		return ErrCacheClosed
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, c.prefix+tagKeyPrefix+tag)
+
+	keys, err := redisTakeTagScript.Run(ctx, c.con, []string{c.prefix + tagKeyPrefix + tag}).StringSlice()
+	if err != nil && err != redis.Nil {
+		finish(err)
+		return err
+	}
+	for _, key := range keys {
+		if err := c.del(ctx, key); err != nil {
+			finish(err)
+			return err
+		}
+	}
+	finish(nil)
+	return nil
+}
+
+func (c *memoryCache[T]) InvalidateTag(ctx context.Context, tag string) error {
+	if c.cache == nil {
+		return ErrCacheClosed
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, tagKeyPrefix+tag)
+	for _, key := range c.tags.take(tag) {
+		// Key could have been stored again without the tag.
+		if v, ok := c.cache.Get(key); ok && hasTag(v.(memoryEntry[T]).tags, tag) {
+			c.cache.Del(key)
+		}
+	}
+	finish(nil)
+	return nil
+}
+
+// InvalidateTag scans all items as the number of items is limited.
+func (c *lruCache[T]) InvalidateTag(ctx context.Context, tag string) error {
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheDelete, tagKeyPrefix+tag)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		finish(ErrCacheClosed)
+		return ErrCacheClosed
+	}
+	for _, el := range c.items {
+		if hasTag(el.Value.(*lruEntry[T]).tags, tag) {
+			c.remove(el)
+		}
+	}
+	finish(nil)
+	return nil
+}
+
+func (c *readOnlyCache[T]) InvalidateTag(ctx context.Context, tag string) error {
+	return c.reject(ctx, InstrumentationCacheDelete, tagKeyPrefix+tag)
+}
+
+func (c *modeCache[T]) InvalidateTag(ctx context.Context, tag string) error {
+	if m, _ := c.current(); m == ModeReadOnly {
+		return ErrReadOnly
+	}
+	err := InvalidateTag(ctx, c.CacheInstance, tag)
+	c.forgetAll()
+	return err
+}
+
+func (c *slidingCache[T]) InvalidateTag(ctx context.Context, tag string) error {
+	return InvalidateTag(ctx, c.CacheInstance, tag)
+}
+
+func (c *eventCache[T]) InvalidateTag(ctx context.Context, tag string) error {
+	return InvalidateTag(ctx, c.CacheInstance, tag)
+}
+
+// InvalidateTag deletes items only in the primary cache instance.
+func (c *replicatedCache[T]) InvalidateTag(ctx context.Context, tag string) error {
+	return InvalidateTag(ctx, c.CacheInstance, tag)
+}
+
+// InvalidateTag deletes items in the remote cache instance and clears local
+// tiers of all application instances as local tiers do not keep tags.
+func (c *tieredCache[T]) InvalidateTag(ctx context.Context, tag string) error {
+	if err := InvalidateTag(ctx, c.CacheInstance, tag); err != nil {
+		return err
+	}
+	c.forgetAll()
+	_ = c.local.Clear(ctx)
+	c.invalidateAll(ctx)
+	return nil
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testInvalidateTag checks that InvalidateTag deletes only items with the tag.
+func testInvalidateTag(t *testing.T, i CacheInstance[string]) {
+	t.Helper()
+
+	ctx := context.TODO()
+	require.NoError(t, i.Set(ctx, "user:42", "user", WithTags[string]("user:42", "org:7")))
+	require.NoError(t, i.Set(ctx, "user:43", "user", WithTags[string]("user:43", "org:7")))
+	require.NoError(t, i.Set(ctx, "org:7", "org", WithTags[string]("org:7"), TTL[string](time.Hour)))
+	require.NoError(t, i.Set(ctx, "org:8", "org", WithTags[string]("org:8")))
+	require.NoError(t, i.Set(ctx, "settings", "value"))
+
+	require.NoError(t, InvalidateTag(ctx, i, "user:42"))
+	ok, err := i.Exists(ctx, "user:42")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = i.Exists(ctx, "user:43")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, InvalidateTag(ctx, i, "org:7"))
+	for key, exists := range map[string]bool{"user:43": false, "org:7": false, "org:8": true, "settings": true} {
+		ok, err := i.Exists(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, exists, ok, key)
+	}
+
+	// Unknown tag is ignored.
+	require.NoError(t, InvalidateTag(ctx, i, "unknown"))
+}
+
+func TestRedisCacheInvalidateTag(t *testing.T) {
+	c, s := newMiniRedisCache(t, Deduplicate{MinSize: 1})
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testInvalidateTag(t, i)
+
+	// Tag index outlives tagged items and is not listed as item key.
+	require.NoError(t, i.Set(context.TODO(), "key", "value", WithTags[string]("tag"), TTL[string](time.Minute)))
+	assert.Greater(t, s.TTL("test:"+tagKeyPrefix+"tag"), 59*time.Second)
+	require.NoError(t, i.Set(context.TODO(), "other", "value", WithTags[string]("tag")))
+	assert.Zero(t, s.TTL("test:"+tagKeyPrefix+"tag"))
+
+	keys, err := Keys(context.TODO(), i, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key", "org:8", "other", "settings"}, keys)
+
+	require.NoError(t, SetMulti(context.TODO(), i, map[string]string{"a": "1", "b": "2"}, WithTags[string]("multi")))
+	require.NoError(t, InvalidateTag(context.TODO(), i, "multi"))
+	values, err := GetMulti(context.TODO(), i, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Empty(t, values)
+	assert.False(t, s.Exists("test:"+tagKeyPrefix+"multi"))
+}
+
+func TestMemoryCacheInvalidateTag(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test")
+	require.NoError(t, err)
+	testInvalidateTag(t, i)
+
+	// Item stored again without the tag is not invalidated.
+	require.NoError(t, i.Set(context.TODO(), "key", "value", WithTags[string]("tag")))
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+	require.NoError(t, InvalidateTag(context.TODO(), i, "tag"))
+	ok, err := i.Exists(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Deleted items are removed from the tag index.
+	mc := i.(*memoryCache[string])
+	require.NoError(t, i.Delete(context.TODO(), "org:8"))
+	mc.tags.lock.Lock()
+	assert.NotContains(t, mc.tags.keys, "org:8")
+	mc.tags.lock.Unlock()
+}
+
+func TestLRUCacheInvalidateTag(t *testing.T) {
+	i, err := newLRUCache[string]()
+	require.NoError(t, err)
+	defer closeInstance(i)
+
+	testInvalidateTag(t, i)
+}
+
+func TestTieredCacheInvalidateTag(t *testing.T) {
+	i1, i2, _ := newTieredTestCaches(t, Tiered{TTL: time.Hour})
+
+	require.NoError(t, i1.Set(context.TODO(), "key", "value", WithTags[string]("tag")))
+	_, err := i2.Get(context.TODO(), "key")
+	require.NoError(t, err)
+
+	require.NoError(t, InvalidateTag(context.TODO(), i1, "tag"))
+	assert.Eventually(t, func() bool {
+		ok, err := i2.Exists(context.TODO(), "key")
+		return err == nil && !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestInvalidateTagWrappers(t *testing.T) {
+	i, err := newLRUCache[string]()
+	require.NoError(t, err)
+	defer closeInstance(i)
+
+	assert.ErrorIs(t, InvalidateTag(context.TODO(), newReadOnlyCache(i, ReadOnly{}), "tag"), ErrReadOnly)
+
+	bc := newTestBoltCache[string](t, filepath.Join(t.TempDir(), "cache.db"))
+	assert.ErrorIs(t, InvalidateTag(context.TODO(), bc, "tag"), ErrNotSupported)
+}
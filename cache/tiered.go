@@ -0,0 +1,408 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	InstrumentationCacheTierHit        = "cache-tier-hit"
+	InstrumentationCacheTierInvalidate = "cache-tier-invalidate"
+)
+
+// TierInvalidation is a strategy to invalidate local tier values changed by other
+// application instances.
+type TierInvalidation int
+
+const (
+	// TierInvalidatePubSub publishes changed keys to other application instances
+	// that remove them from their local tier.
+	TierInvalidatePubSub TierInvalidation = iota
+	// TierInvalidateTTL relies only on the local tier TTL, values changed by other
+	// application instances can be stale for up to the local tier TTL.
+	TierInvalidateTTL
+)
+
+// Tiered adds local in-memory tier in front of the remote cache instance.
+//
+// Reads are served from local tier first falling back to remote cache, writes go
+// through to both tiers.
+type Tiered struct {
+	// TTL is a maximum time to keep value in local tier. Defaults to 1 minute.
+	TTL time.Duration
+	// MaxItems is a maximum number of items in local tier. Defaults to 10000.
+	MaxItems int
+	// MaxBytes is a maximum total size of items in local tier. Zero means no limit.
+	MaxBytes int64
+	// Invalidation is a strategy to invalidate local tier values.
+	Invalidation TierInvalidation
+	// ReadYourWrites is a time window after the key is changed by this application
+	// instance during which reads of the key bypass local tier and are served from
+	// the remote cache. Zero disables read-your-writes consistency.
+	ReadYourWrites time.Duration
+	// PinWrites returns the value written by this application instance for the
+	// ReadYourWrites window instead of reading it from the remote cache.
+	PinWrites bool
+}
+
+func (t Tiered) applyCache(c *cacheOptions) {
+	c.Tiered = &t
+}
+
+type tierInvalidation struct {
+	Origin string `json:"origin"`
+	Key    string `json:"key"`
+	// All invalidates all keys of the local tier.
+	All bool `json:"all,omitempty"`
+}
+
+// tierWrite is a change of the key made by this application instance.
+type tierWrite[T any] struct {
+	seq     uint64
+	until   time.Time
+	value   T
+	deleted bool
+}
+
+type tieredCache[T any] struct {
+	CacheInstance[T]
+	local        *lruCache[T]
+	ttl          time.Duration
+	cache        *Cache
+	channel      string
+	id           string
+	instrumenter instrumenter.Instrumenter
+	unsubscribe  func()
+
+	window    time.Duration
+	pin       bool
+	writelock sync.Mutex
+	seq       uint64
+	writes    map[string]tierWrite[T]
+	pruned    time.Time
+}
+
+func newTieredCache[T any](cache *Cache, c CacheInstance[T], typ CacheType, name string, opts ...CacheOption) (CacheInstance[T], error) {
+	opt := newCacheOptions(opts...)
+	conf := *opt.Tiered
+
+	if typ == MemoryCache {
+		return nil, errors.New("tiered cache is not supported for memory cache instances")
+	}
+	if conf.TTL <= 0 {
+		conf.TTL = time.Minute
+	}
+	if conf.MaxItems <= 0 {
+		conf.MaxItems = 10000
+	}
+
+	local, err := newLRUCache[T](MemoryLimit{MaxItems: conf.MaxItems, MaxBytes: conf.MaxBytes})
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	t := &tieredCache[T]{
+		CacheInstance: c,
+		local:         local.(*lruCache[T]),
+		ttl:           conf.TTL,
+		cache:         cache,
+		channel:       name + ":invalidate",
+		id:            hex.EncodeToString(buf),
+		instrumenter:  opt.Instrumenter,
+		window:        conf.ReadYourWrites,
+		pin:           conf.PinWrites,
+		writes:        make(map[string]tierWrite[T]),
+	}
+	if conf.Invalidation == TierInvalidatePubSub {
+		t.unsubscribe, err = cache.Subscribe(context.Background(), t.channel, t.invalidated)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// invalidated removes key changed by other application instance from local tier.
+func (c *tieredCache[T]) invalidated(message string) {
+	var m tierInvalidation
+	if err := json.Unmarshal([]byte(message), &m); err != nil || m.Origin == c.id {
+		return
+	}
+	if m.All {
+		c.forgetAll()
+		_ = c.local.Clear(context.Background())
+		return
+	}
+	// Value written by other application instance is newer than pinned value.
+	c.forget(m.Key)
+	_ = c.local.Delete(context.Background(), m.Key)
+}
+
+// wrote records change of the key made by this application instance.
+func (c *tieredCache[T]) wrote(key string, value T, deleted bool) {
+	c.writelock.Lock()
+	defer c.writelock.Unlock()
+
+	c.seq++
+	now := time.Now()
+	if now.Sub(c.pruned) > c.window {
+		for k, w := range c.writes {
+			if !now.Before(w.until) {
+				delete(c.writes, k)
+			}
+		}
+		c.pruned = now
+	}
+	c.writes[key] = tierWrite[T]{
+		seq:     c.seq,
+		until:   now.Add(c.window),
+		value:   value,
+		deleted: deleted,
+	}
+}
+
+// written returns last change of the key made by this application instance
+// within read-your-writes window and current write sequence number.
+func (c *tieredCache[T]) written(key string) (tierWrite[T], bool, uint64) {
+	c.writelock.Lock()
+	defer c.writelock.Unlock()
+
+	w, ok := c.writes[key]
+	if ok && !time.Now().Before(w.until) {
+		delete(c.writes, key)
+		ok = false
+	}
+	return w, ok, c.seq
+}
+
+// changedSince returns true if key was changed by this application instance
+// after the write sequence number.
+func (c *tieredCache[T]) changedSince(key string, seq uint64) bool {
+	c.writelock.Lock()
+	defer c.writelock.Unlock()
+
+	w, ok := c.writes[key]
+	return ok && w.seq > seq
+}
+
+func (c *tieredCache[T]) forget(key string) {
+	if c.window <= 0 {
+		return
+	}
+	c.writelock.Lock()
+	defer c.writelock.Unlock()
+
+	delete(c.writes, key)
+}
+
+func (c *tieredCache[T]) forgetAll() {
+	if c.window <= 0 {
+		return
+	}
+	c.writelock.Lock()
+	defer c.writelock.Unlock()
+
+	c.writes = make(map[string]tierWrite[T])
+}
+
+// changed records change of the key for read-your-writes consistency and
+// returns true if written value can be kept in local tier.
+func (c *tieredCache[T]) changed(key string, value T, deleted bool) bool {
+	if c.window <= 0 {
+		return true
+	}
+	c.wrote(key, value, deleted)
+	// Reads bypass local tier within the window.
+	if !c.pin {
+		_ = c.local.Delete(context.Background(), key)
+		return false
+	}
+	return true
+}
+
+// invalidate key in local tiers of other application instances.
+func (c *tieredCache[T]) invalidate(ctx context.Context, key string) {
+	c.publish(ctx, tierInvalidation{Origin: c.id, Key: key})
+}
+
+// invalidateAll keys in local tiers of other application instances.
+func (c *tieredCache[T]) invalidateAll(ctx context.Context) {
+	c.publish(ctx, tierInvalidation{Origin: c.id, All: true})
+}
+
+func (c *tieredCache[T]) publish(ctx context.Context, m tierInvalidation) {
+	if c.unsubscribe == nil {
+		return
+	}
+	finish := c.instrumenter.Observe(ctx, InstrumentationCacheTierInvalidate, m.Key)
+	buf, err := json.Marshal(m)
+	if err == nil {
+		err = c.cache.Publish(ctx, c.channel, string(buf))
+	}
+	finish(err)
+}
+
+// deleted records deletion of the key for read-your-writes consistency.
+func (c *tieredCache[T]) deleted(key string) {
+	var zero T
+	c.changed(key, zero, true)
+}
+
+// localTTL returns TTL for the value in local tier not exceeding value TTL.
+func (c *tieredCache[T]) localTTL(opts ...ItemOption[T]) time.Duration {
+	ttl := ResolveItemOptions[T](c.CacheInstance, opts...).TTL
+	if ttl > 0 && ttl < c.ttl {
+		return ttl
+	}
+	return c.ttl
+}
+
+func (c *tieredCache[T]) Get(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, c.CacheInstance.Get, opts...)
+}
+
+// peek returns value from local tier falling back to remote cache without
+// calling the loader.
+func (c *tieredCache[T]) peek(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+	return c.read(ctx, key, func(ctx context.Context, key string, opts ...ItemOption[T]) (T, error) {
+		return peek(ctx, c.CacheInstance, key, opts...)
+	}, opts...)
+}
+
+// read returns value from local tier falling back to reading it from remote
+// cache with the remote function.
+func (c *tieredCache[T]) read(ctx context.Context, key string, remote func(ctx context.Context, key string, opts ...ItemOption[T]) (T, error), opts ...ItemOption[T]) (T, error) {
+	if c.window > 0 {
+		w, ok, seq := c.written(key)
+		if ok && c.pin && !w.deleted {
+			c.instrumenter.Observe(ctx, InstrumentationCacheTierHit, key)(nil)
+			return w.value, nil
+		}
+		if ok {
+			return remote(ctx, key, opts...)
+		}
+		return c.get(ctx, key, seq, remote, opts...)
+	}
+	return c.get(ctx, key, 0, remote, opts...)
+}
+
+// get returns value from local tier falling back to remote cache. Value read
+// from remote cache is not kept in local tier if the key was changed by this
+// application instance after write sequence number seq in the meantime.
+func (c *tieredCache[T]) get(ctx context.Context, key string, seq uint64, remote func(ctx context.Context, key string, opts ...ItemOption[T]) (T, error), opts ...ItemOption[T]) (T, error) {
+	c.local.lock.Lock()
+	e, ok := c.local.lookup(key)
+	c.local.lock.Unlock()
+	if ok {
+		c.instrumenter.Observe(ctx, InstrumentationCacheTierHit, key)(nil)
+		return e.value, nil
+	}
+
+	v, err := remote(ctx, key, opts...)
+	if err != nil {
+		return v, err
+	}
+	if c.window > 0 && c.changedSince(key, seq) {
+		return v, nil
+	}
+	// Missing value can not be distinguished from default value, so default
+	// values are not kept in local tier.
+	if !reflect.DeepEqual(v, ResolveItemOptions[T](c.CacheInstance, opts...).DefaultValue) {
+		_ = c.local.set(ctx, key, v, c.localTTL(opts...))
+	}
+	return v, nil
+}
+
+func (c *tieredCache[T]) Exists(ctx context.Context, key string) (bool, error) {
+	if ok, _ := c.local.Exists(ctx, key); ok {
+		c.instrumenter.Observe(ctx, InstrumentationCacheTierHit, key)(nil)
+		return true, nil
+	}
+	return c.CacheInstance.Exists(ctx, key)
+}
+
+func (c *tieredCache[T]) Pop(ctx context.Context, key string) (T, error) {
+	_ = c.local.Delete(ctx, key)
+	v, err := c.CacheInstance.Pop(ctx, key)
+	if err == nil {
+		c.deleted(key)
+		c.invalidate(ctx, key)
+	}
+	return v, err
+}
+
+func (c *tieredCache[T]) PopWithMetadata(ctx context.Context, key string) (T, ItemMetadata, error) {
+	_ = c.local.Delete(ctx, key)
+	v, meta, err := PopWithMetadata(ctx, c.CacheInstance, key)
+	if err == nil {
+		c.deleted(key)
+		c.invalidate(ctx, key)
+	}
+	return v, meta, err
+}
+
+func (c *tieredCache[T]) Set(ctx context.Context, key string, value T, opts ...ItemOption[T]) error {
+	if err := c.CacheInstance.Set(ctx, key, value, opts...); err != nil {
+		_ = c.local.Delete(ctx, key)
+		return err
+	}
+	if c.changed(key, value, false) {
+		_ = c.local.set(ctx, key, value, c.localTTL(opts...))
+	}
+	c.invalidate(ctx, key)
+	return nil
+}
+
+func (c *tieredCache[T]) Delete(ctx context.Context, key string) error {
+	_ = c.local.Delete(ctx, key)
+	if err := c.CacheInstance.Delete(ctx, key); err != nil {
+		return err
+	}
+	c.deleted(key)
+	c.invalidate(ctx, key)
+	return nil
+}
+
+func (c *tieredCache[T]) Close() {
+	if c.unsubscribe != nil {
+		c.unsubscribe()
+	}
+	c.local.Close()
+	closeInstance(c.CacheInstance)
+}
+
+func (c *tieredCache[T]) Ping(ctx context.Context) error {
+	if p, ok := c.CacheInstance.(CacheInstancePinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tieredCache[T]) itemOptions(opts ...ItemOption[T]) *itemOptions[T] {
+	if r, ok := c.CacheInstance.(itemOptionsResolver[T]); ok {
+		return r.itemOptions(opts...)
+	}
+	return newItemOptions(opts...)
+}
+
+func (c *tieredCache[T]) unwrap() CacheInstance[T] {
+	return c.CacheInstance
+}
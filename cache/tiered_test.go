@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTieredTestCaches(t *testing.T, conf Tiered) (CacheInstance[string], CacheInstance[string], *miniredis.Miniredis) {
+	t.Helper()
+
+	c1, s := newMiniRedisCache(t)
+	c2 := New(CacheType(RedisCache), ConnectionString("redis://"+s.Addr()))
+	require.NoError(t, c2.Start(context.TODO()))
+	t.Cleanup(c2.Close)
+
+	i1, err := Create[string](c1, "test", conf)
+	require.NoError(t, err)
+	i2, err := Create[string](c2, "test", conf)
+	require.NoError(t, err)
+	return i1, i2, s
+}
+
+func TestTieredCacheLocalHits(t *testing.T) {
+	ctx := context.TODO()
+	i, _, s := newTieredTestCaches(t, Tiered{Invalidation: TierInvalidateTTL})
+
+	require.NoError(t, i.Set(ctx, "hot", "value"))
+
+	before := s.CommandCount()
+	for n := 0; n < 10; n++ {
+		v, err := i.Get(ctx, "hot")
+		require.NoError(t, err)
+		assert.Equal(t, "value", v)
+	}
+	assert.Equal(t, before, s.CommandCount())
+
+	// Missing values are not cached locally.
+	v, err := i.Get(ctx, "missing", DefaultValue[string]{Value: "default"})
+	require.NoError(t, err)
+	assert.Equal(t, "default", v)
+	require.NoError(t, s.Set("test:missing", `"remote"`))
+	v, err = i.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.Equal(t, "remote", v)
+
+	require.NoError(t, i.Delete(ctx, "hot"))
+	v, err = i.Get(ctx, "hot")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+}
+
+func TestTieredCachePubSubInvalidation(t *testing.T) {
+	ctx := context.TODO()
+	i1, i2, _ := newTieredTestCaches(t, Tiered{TTL: time.Hour})
+
+	require.NoError(t, i1.Set(ctx, "key", "v1"))
+	v, err := i2.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", v)
+
+	require.NoError(t, i1.Set(ctx, "key", "v2"))
+	assert.Eventually(t, func() bool {
+		v, err := i2.Get(ctx, "key")
+		return err == nil && v == "v2"
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = i1.Pop(ctx, "key")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		v, err := i2.Get(ctx, "key")
+		return err == nil && v == ""
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTieredCacheTTLInvalidation(t *testing.T) {
+	ctx := context.TODO()
+	i1, i2, _ := newTieredTestCaches(t, Tiered{TTL: 50 * time.Millisecond, Invalidation: TierInvalidateTTL})
+
+	require.NoError(t, i1.Set(ctx, "key", "v1"))
+	v, err := i2.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", v)
+
+	require.NoError(t, i1.Set(ctx, "key", "v2"))
+	v, err = i2.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", v)
+
+	time.Sleep(60 * time.Millisecond)
+	v, err = i2.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", v)
+}
+
+func TestTieredCacheMemoryUnsupported(t *testing.T) {
+	c := New(CacheType(MemoryCache))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	_, err := Create[string](c, "test", Tiered{})
+	assert.Error(t, err)
+}
+
+func TestTieredCacheReadYourWrites(t *testing.T) {
+	ctx := context.TODO()
+	i, _, s := newTieredTestCaches(t, Tiered{TTL: time.Hour, Invalidation: TierInvalidateTTL, ReadYourWrites: 50 * time.Millisecond})
+
+	// Value changed by other writer is read from remote cache within the window.
+	require.NoError(t, i.Set(ctx, "key", "v1"))
+	require.NoError(t, s.Set("test:key", `"v2"`))
+	v, err := i.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", v)
+
+	time.Sleep(60 * time.Millisecond)
+	v, err = i.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", v)
+
+	// After the window value is served from local tier again.
+	before := s.CommandCount()
+	v, err = i.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", v)
+	assert.Equal(t, before, s.CommandCount())
+
+	require.NoError(t, i.Delete(ctx, "key"))
+	v, err = i.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+
+	// Read started before the write does not keep old value in local tier.
+	tc := i.(*tieredCache[string])
+	_, _, seq := tc.written("other")
+	require.NoError(t, i.Set(ctx, "other", "v1"))
+	_, err = tc.get(ctx, "other", seq, tc.CacheInstance.Get)
+	require.NoError(t, err)
+	ok, err := tc.local.Exists(ctx, "other")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestTieredCachePinWrites(t *testing.T) {
+	ctx := context.TODO()
+	i1, i2, s := newTieredTestCaches(t, Tiered{TTL: time.Hour, ReadYourWrites: time.Minute, PinWrites: true})
+
+	require.NoError(t, i1.Set(ctx, "key", "v1"))
+	require.NoError(t, s.Set("test:key", `"stale"`))
+	before := s.CommandCount()
+	v, err := i1.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", v)
+	assert.Equal(t, before, s.CommandCount())
+
+	// Value written by other application instance replaces pinned value.
+	require.NoError(t, i2.Set(ctx, "key", "v2"))
+	assert.Eventually(t, func() bool {
+		v, err := i1.Get(ctx, "key")
+		return err == nil && v == "v2"
+	}, time.Second, 10*time.Millisecond)
+}
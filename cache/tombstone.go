@@ -0,0 +1,123 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// tombstoneKeyPrefix is a key prefix of soft deleted values in the cache instance namespace.
+const tombstoneKeyPrefix = "~tombstone:"
+
+// ErrFrozen is returned when soft deleted values are moved in the frozen cache instance.
+var ErrFrozen = errors.New("cache instance is frozen")
+
+// writeChecker represents cache instance wrapper that can reject changing values.
+type writeChecker interface {
+	// writable returns false if values can not be changed. Error is nil if
+	// changes are silently ignored.
+	writable(ctx context.Context, op, key string) (bool, error)
+}
+
+// checkWritable returns false if value of the key can not be changed by any
+// of the cache instance wrappers.
+func checkWritable[T any](ctx context.Context, instance CacheInstance[T], op, key string) (bool, error) {
+	for {
+		if c, ok := instance.(writeChecker); ok {
+			if ok, err := c.writable(ctx, op, key); !ok {
+				return false, err
+			}
+		}
+		u, ok := instance.(instanceUnwrapper[T])
+		if !ok {
+			return true, nil
+		}
+		instance = u.unwrap()
+	}
+}
+
+// SoftDelete deletes value from the cache instance keeping it in the tombstone
+// keyspace of the instance for the retention time, so that it can be inspected
+// with GetDeleted or brought back with RestoreDeleted. If value is not found, it
+// returns ErrKeyNotFound error.
+//
+// Tombstones are stored directly in the cache instance backend with exactly
+// the retention TTL. Value is moved with separate operations so concurrent
+// writes of the same key are not isolated. If value can not be moved, it is
+// put back.
+func SoftDelete[T any](ctx context.Context, instance CacheInstance[T], key string, retention time.Duration) error {
+	if retention <= 0 {
+		return errors.New("soft delete retention must be positive")
+	}
+	if ok, err := checkWritable(ctx, instance, InstrumentationCacheDelete, key); !ok {
+		return err
+	}
+	v, meta, err := PopWithMetadata(ctx, instance, key)
+	if err != nil {
+		return err
+	}
+	if err := backendInstance(instance).Set(ctx, tombstoneKeyPrefix+key, v, TTL[T](retention)); err != nil {
+		// Original TTL is kept only if it is known, otherwise instance default TTL is used.
+		var opts []ItemOption[T]
+		if meta.TTL > 0 {
+			opts = append(opts, TTL[T](meta.TTL))
+		}
+		_ = instance.Set(ctx, key, v, opts...)
+		return err
+	}
+	return nil
+}
+
+// GetDeleted returns soft deleted value from the cache instance without
+// restoring it. If value is not found or retention has expired, it returns
+// ErrKeyNotFound error.
+func GetDeleted[T any](ctx context.Context, instance CacheInstance[T], key string) (T, error) {
+	v, err := peek(ctx, backendInstance(instance), tombstoneKeyPrefix+key)
+	if isKeyNotFound(err) {
+		return v, ErrKeyNotFound{Key: key}
+	}
+	return v, err
+}
+
+// RestoreDeleted brings soft deleted value back to the cache instance overwriting
+// current value of the key. Value is stored with the instance default TTL
+// unless TTL is provided in options. If value is not found or retention has
+// expired, it returns ErrKeyNotFound error.
+func RestoreDeleted[T any](ctx context.Context, instance CacheInstance[T], key string, opts ...ItemOption[T]) error {
+	if ok, err := checkWritable(ctx, instance, InstrumentationCacheSet, key); !ok {
+		return err
+	}
+	backend := backendInstance(instance)
+	v, meta, err := PopWithMetadata(ctx, backend, tombstoneKeyPrefix+key)
+	if isKeyNotFound(err) {
+		return ErrKeyNotFound{Key: key}
+	}
+	if err != nil {
+		return err
+	}
+	if err := instance.Set(ctx, key, v, opts...); err != nil {
+		if meta.TTL > 0 {
+			_ = backend.Set(ctx, tombstoneKeyPrefix+key, v, TTL[T](meta.TTL))
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *readOnlyCache[T]) writable(ctx context.Context, op, key string) (bool, error) {
+	return false, c.reject(ctx, op, key)
+}
+
+func (c *modeCache[T]) writable(_ context.Context, _, _ string) (bool, error) {
+	switch m, _ := c.current(); m {
+	case ModeReadOnly:
+		return false, ErrReadOnly
+	case ModeFreeze:
+		return false, ErrFrozen
+	}
+	return true, nil
+}
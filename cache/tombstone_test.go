@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSoftDelete(t *testing.T, c *Cache) {
+	t.Helper()
+
+	var calls int
+	i, err := Create[string](c, "soft", Loader[string](func(_ context.Context, key string) (string, error) {
+		calls++
+		return "", ErrKeyNotFound{Key: key}
+	}))
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+
+	require.NoError(t, SoftDelete(context.TODO(), i, "key", time.Minute))
+	ok, err := i.Exists(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	v, err := GetDeleted(context.TODO(), i, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	require.NoError(t, RestoreDeleted(context.TODO(), i, "key"))
+	v, err = i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	_, err = GetDeleted(context.TODO(), i, "key")
+	assert.ErrorAs(t, err, &ErrKeyNotFound{})
+	assert.ErrorAs(t, RestoreDeleted(context.TODO(), i, "key"), &ErrKeyNotFound{})
+	assert.ErrorAs(t, SoftDelete(context.TODO(), i, "missing", time.Minute), &ErrKeyNotFound{})
+	assert.Error(t, SoftDelete(context.TODO(), i, "key", 0))
+	// Loader is not called for tombstone keys.
+	assert.Equal(t, 0, calls)
+}
+
+func TestMemorySoftDelete(t *testing.T) {
+	c := New(CacheType(MemoryCache), MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	testSoftDelete(t, c)
+}
+
+func TestRedisSoftDelete(t *testing.T) {
+	c, mr := newMiniRedisCache(t)
+
+	testSoftDelete(t, c)
+
+	i, err := Create[string](c, "retention")
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+	require.NoError(t, SoftDelete(context.TODO(), i, "key", time.Minute))
+
+	// Soft deleted values are not listed.
+	var keys []string
+	require.NoError(t, ForEach(context.TODO(), i, "*", func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Empty(t, keys)
+
+	mr.FastForward(2 * time.Minute)
+	assert.ErrorAs(t, RestoreDeleted(context.TODO(), i, "key"), &ErrKeyNotFound{})
+}
+
+func TestRedisSoftDeleteGuards(t *testing.T) {
+	c, _ := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "guarded", ModeSwitch{}, TTLGuard{Max: time.Minute}, WithTTLJitter(50))
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+
+	// Value is not moved if instance can not be changed.
+	require.NoError(t, c.SetMode("guarded", ModeReadOnly))
+	assert.ErrorIs(t, SoftDelete(context.TODO(), i, "key", time.Hour), ErrReadOnly)
+	require.NoError(t, c.SetMode("guarded", ModeFreeze))
+	assert.ErrorIs(t, SoftDelete(context.TODO(), i, "key", time.Hour), ErrFrozen)
+	require.NoError(t, c.SetMode("guarded", ModeNormal))
+	v, err := i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	// Retention is not clamped or jittered by TTL guard.
+	require.NoError(t, SoftDelete(context.TODO(), i, "key", time.Hour))
+	ttl, err := GetTTL(context.TODO(), backendInstance(i), tombstoneKeyPrefix+"key")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, ttl)
+
+	require.NoError(t, c.SetMode("guarded", ModeReadOnly))
+	assert.ErrorIs(t, RestoreDeleted(context.TODO(), i, "key"), ErrReadOnly)
+	require.NoError(t, c.SetMode("guarded", ModeNormal))
+	require.NoError(t, RestoreDeleted(context.TODO(), i, "key"))
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// NoExpiration is a TTL for items that are intentionally stored without expiration.
+//
+// Zero TTL means that TTL was not set and is checked by TTLGuard.
+const NoExpiration time.Duration = -1
+
+// ErrNoExpiration is returned when item would be unintentionally stored without expiration.
+var ErrNoExpiration = errors.New("cache item would be stored without expiration")
+
+// TTLGuard enforces TTL limits for items stored in the cache instance.
+type TTLGuard struct {
+	// Min is a minimum TTL. Shorter TTLs are increased to the minimum.
+	Min time.Duration
+	// Max is a maximum TTL. Longer TTLs are decreased to the maximum and
+	// items without TTL are stored with maximum TTL.
+	Max time.Duration
+	// Strict returns ErrNoExpiration when item without TTL would be stored
+	// without expiration. Use NoExpiration TTL to store item without expiration.
+	Strict bool
+	// Warn is called when item without TTL would be stored without expiration.
+	Warn func(ctx context.Context, key string)
+
+	jitter float64
+}
+
+func (g TTLGuard) applyCache(c *cacheOptions) {
+	c.TTLGuard = &g
+}
+
+// TTLJitter is a maximum percentage (0..100) by which TTL of each stored item is
+// randomly shortened, so that items written together do not expire at the same
+// time.
+type TTLJitter float64
+
+func (j TTLJitter) applyCache(c *cacheOptions) {
+	c.TTLJitter = float64(j)
+}
+
+// WithTTLJitter returns option that randomly shortens TTL of each stored item by
+// up to percent of it.
+func WithTTLJitter(percent float64) TTLJitter {
+	return TTLJitter(percent)
+}
+
+// expiration returns TTL for the item to be stored with. Zero means no expiration.
+func (g *TTLGuard) expiration(ctx context.Context, key string, ttl time.Duration) (time.Duration, error) {
+	if ttl == NoExpiration {
+		return 0, nil
+	}
+	// Soft deleted values are kept for exactly the requested retention.
+	if g == nil || strings.HasPrefix(key, tombstoneKeyPrefix) {
+		if ttl < 0 {
+			return 0, nil
+		}
+		return ttl, nil
+	}
+	if ttl <= 0 {
+		if g.Max > 0 {
+			return g.jittered(g.Max), nil
+		}
+		if g.Warn != nil {
+			g.Warn(ctx, key)
+		}
+		if g.Strict {
+			return 0, ErrNoExpiration
+		}
+		return 0, nil
+	}
+	if g.Min > 0 && ttl < g.Min {
+		ttl = g.Min
+	}
+	if g.Max > 0 && ttl > g.Max {
+		ttl = g.Max
+	}
+	return g.jittered(ttl), nil
+}
+
+// jittered returns TTL randomly shortened by the jitter but not below minimum TTL.
+func (g *TTLGuard) jittered(ttl time.Duration) time.Duration {
+	if g.jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	ttl -= time.Duration(rand.Float64() * g.jitter / 100 * float64(ttl)) //nolint:gosec
+	if ttl < g.Min {
+		ttl = g.Min
+	}
+	if ttl <= 0 {
+		ttl = 1
+	}
+	return ttl
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLGuardExpiration(t *testing.T) {
+	ctx := context.TODO()
+	warned := 0
+	g := &TTLGuard{
+		Min:    time.Second,
+		Max:    time.Hour,
+		Strict: true,
+		Warn: func(ctx context.Context, key string) {
+			warned++
+		},
+	}
+
+	tests := []struct {
+		name  string
+		guard *TTLGuard
+		ttl   time.Duration
+		want  time.Duration
+		err   error
+	}{
+		{name: "no guard", ttl: time.Minute, want: time.Minute},
+		{name: "no guard no ttl", ttl: 0, want: 0},
+		{name: "no guard no expiration", ttl: NoExpiration, want: 0},
+		{name: "min", guard: g, ttl: time.Millisecond, want: time.Second},
+		{name: "max", guard: g, ttl: 2 * time.Hour, want: time.Hour},
+		{name: "max no ttl", guard: g, ttl: 0, want: time.Hour},
+		{name: "no expiration", guard: g, ttl: NoExpiration, want: 0},
+		{name: "strict", guard: &TTLGuard{Strict: true, Warn: g.Warn}, ttl: 0, err: ErrNoExpiration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttl, err := tt.guard.expiration(ctx, "key", tt.ttl)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ttl)
+		})
+	}
+	assert.Equal(t, 1, warned)
+}
+
+func TestRedisCacheTTLGuard(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "test", TTLGuard{Min: time.Minute, Strict: true})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, i.Set(context.TODO(), "a", "value"), ErrNoExpiration)
+	assert.False(t, s.Exists("test:a"))
+
+	require.NoError(t, i.Set(context.TODO(), "b", "value", TTL[string](time.Second)))
+	assert.Equal(t, time.Minute, s.TTL("test:b"))
+
+	require.NoError(t, i.Set(context.TODO(), "c", "value", TTL[string](NoExpiration)))
+	assert.True(t, s.Exists("test:c"))
+	assert.Zero(t, s.TTL("test:c"))
+}
+
+func TestMemoryCacheNoExpiration(t *testing.T) {
+	c := New(MemoryCache, MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	i, err := Create[string](c, "test", DefaultTTL(NoExpiration))
+	require.NoError(t, err)
+
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+	v, err := i.Get(context.TODO(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+}
+
+func TestTTLJitter(t *testing.T) {
+	c, s := newMiniRedisCache(t)
+
+	i, err := Create[string](c, "test", DefaultTTL(time.Hour), WithTTLJitter(20), TTLGuard{Strict: true})
+	require.NoError(t, err)
+
+	ttls := make(map[time.Duration]struct{})
+	for n := 0; n < 20; n++ {
+		key := fmt.Sprintf("key%d", n)
+		require.NoError(t, i.Set(context.TODO(), key, "value"))
+		ttl := s.TTL("test:" + key)
+		assert.LessOrEqual(t, ttl, time.Hour)
+		assert.GreaterOrEqual(t, ttl, 48*time.Minute)
+		ttls[ttl] = struct{}{}
+	}
+	assert.Greater(t, len(ttls), 1)
+
+	// TTL guard options are kept.
+	assert.ErrorIs(t, i.Set(context.TODO(), "a", "value", TTL[string](0)), ErrNoExpiration)
+	require.NoError(t, i.Set(context.TODO(), "b", "value", TTL[string](NoExpiration)))
+	assert.Zero(t, s.TTL("test:b"))
+
+	_, err = Create[string](c, "invalid", WithTTLJitter(150))
+	assert.Error(t, err)
+}
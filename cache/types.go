@@ -0,0 +1,88 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"reflect"
+	"sort"
+)
+
+// TypeDefaults are default options for all cache instances of the value type T,
+// for example TTL or serializer.
+//
+// Type defaults override cache options and are overridden by the cache instance options.
+type TypeDefaults[T any] []CacheOption
+
+func (d TypeDefaults[T]) applyCache(c *cacheOptions) {
+	if c.TypeDefaults == nil {
+		c.TypeDefaults = make(map[reflect.Type][]CacheOption)
+	}
+	t := typeOf[T]()
+	c.TypeDefaults[t] = append(c.TypeDefaults[t], d...)
+}
+
+// RegisterTypeDefaults registers default options for all cache instances of the value type T
+// created afterwards.
+func RegisterTypeDefaults[T any](cache *Cache, opts ...CacheOption) {
+	cache.options = append(cache.options, TypeDefaults[T](opts))
+}
+
+// InstanceDefaults are default options for the cache instance with the name,
+// for example backend, TTL, serializer or tiering declared in the configuration.
+//
+// Instance defaults override type defaults and are overridden by the options
+// passed when cache instance is created.
+type InstanceDefaults struct {
+	// Name of the cache instance.
+	Name string
+	// Options of the cache instance.
+	Options []CacheOption
+}
+
+func (d InstanceDefaults) applyCache(c *cacheOptions) {
+	if c.InstanceDefaults == nil {
+		c.InstanceDefaults = make(map[string][]CacheOption)
+	}
+	c.InstanceDefaults[d.Name] = append(c.InstanceDefaults[d.Name], d.Options...)
+}
+
+// Define declares cache instance with the name and options so that it is created
+// by Get on first use without creating it in code.
+func Define(cache *Cache, name string, opts ...CacheOption) {
+	cache.options = append(cache.options, InstanceDefaults{Name: name, Options: opts})
+}
+
+// Defined returns sorted names of the declared cache instances.
+func (c *Cache) Defined() []string {
+	defs := newCacheOptions(c.options...).InstanceDefaults
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// typeDefaults returns default options registered for the value type T.
+func typeDefaults[T any](cache *Cache) []CacheOption {
+	return newCacheOptions(cache.options...).TypeDefaults[typeOf[T]()]
+}
+
+// instanceDefaults returns default options declared for the cache instance name.
+func instanceDefaults(cache *Cache, name string) []CacheOption {
+	return newCacheOptions(cache.options...).InstanceDefaults[name]
+}
+
+// instanceOptions returns options for the cache instance of the value type T with the name.
+func instanceOptions[T any](cache *Cache, name string, opts ...CacheOption) []CacheOption {
+	opt := append([]CacheOption{}, cache.options...)
+	opt = append(opt, typeDefaults[T](cache)...)
+	opt = append(opt, instanceDefaults(cache, name)...)
+	return append(opt, opts...)
+}
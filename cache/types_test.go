@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"azugo.io/core/serializer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testProfile struct {
+	Name string `json:"name"`
+}
+
+func TestTypeDefaults(t *testing.T) {
+	c, s := newMiniRedisCache(t, DefaultTTL(time.Hour), TypeDefaults[testProfile]{
+		DefaultTTL(10 * time.Minute),
+		Serializer{serializer.Gzip(serializer.JSON)},
+	})
+	RegisterTypeDefaults[string](c, DefaultTTL(time.Minute))
+
+	profiles, err := Create[testProfile](c, "profiles")
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, ResolveItemOptions[testProfile](profiles).TTL)
+
+	require.NoError(t, profiles.Set(context.TODO(), "john", testProfile{Name: "John"}))
+	assert.Equal(t, 10*time.Minute, s.TTL("profiles:john"))
+	raw, err := s.Get("profiles:john")
+	require.NoError(t, err)
+	assert.Equal(t, "\x1f\x8b", raw[:2], "value must be gzip compressed")
+
+	v, err := profiles.Get(context.TODO(), "john")
+	require.NoError(t, err)
+	assert.Equal(t, "John", v.Name)
+
+	strs, err := Create[string](c, "strings")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ResolveItemOptions[string](strs).TTL)
+
+	strs, err = Create[string](c, "override", DefaultTTL(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, ResolveItemOptions[string](strs).TTL)
+
+	ints, err := Create[int](c, "ints")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, ResolveItemOptions[int](ints).TTL)
+}
+
+func TestInstanceDefaults(t *testing.T) {
+	c, s := newMiniRedisCache(t, DefaultTTL(time.Hour), TypeDefaults[string]{DefaultTTL(time.Minute)})
+	Define(c, "sessions", DefaultTTL(10*time.Minute), Serializer{serializer.Gzip(serializer.JSON)})
+	Define(c, "local", MemoryCache, MemoryLimit{MaxItems: 10})
+	assert.Equal(t, []string{"local", "sessions"}, c.Defined())
+
+	// Declared instance is created on first use.
+	sessions, err := Get[string](c, "sessions")
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, ResolveItemOptions[string](sessions).TTL)
+	require.NoError(t, sessions.Set(context.TODO(), "john", "token"))
+	raw, err := s.Get("sessions:john")
+	require.NoError(t, err)
+	assert.Equal(t, "\x1f\x8b", raw[:2], "value must be gzip compressed")
+
+	again, err := Get[string](c, "sessions")
+	require.NoError(t, err)
+	assert.Same(t, sessions, again)
+
+	local, err := Get[int](c, "local")
+	require.NoError(t, err)
+	assert.IsType(t, &lruCache[int]{}, local)
+
+	// Options passed to create override declared options.
+	sessions, err = Create[string](c, "sessions", DefaultTTL(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, ResolveItemOptions[string](sessions).TTL)
+
+	_, err = Get[string](c, "unknown")
+	assert.Error(t, err)
+}
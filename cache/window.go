@@ -0,0 +1,211 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	InstrumentationCacheWindowAdd   = "cache-window-add"
+	InstrumentationCacheWindowCount = "cache-window-count"
+)
+
+// Window counts events per key over sliding time windows.
+//
+// Events are kept for the retention period, so counts can be queried for any
+// window up to the retention. Redis backends store events in sorted sets so that
+// counts are shared between all service instances.
+type Window struct {
+	retention    time.Duration
+	prefix       string
+	con          redis.Cmdable
+	instrumenter instrumenter.Instrumenter
+	seq          uint32
+
+	lock   sync.Mutex
+	events map[string][]int64
+}
+
+// NewWindow creates sliding window event counter with specified name that keeps
+// events for the retention period.
+func NewWindow(cache *Cache, name string, retention time.Duration, opts ...CacheOption) (*Window, error) {
+	if retention <= 0 {
+		return nil, errors.New("window retention must be positive")
+	}
+
+	opt := newCacheOptions(append(append([]CacheOption{}, cache.options...), opts...)...)
+
+	w := &Window{
+		retention:    retention,
+		instrumenter: opt.Instrumenter,
+	}
+
+	switch opt.Type {
+	case MemoryCache:
+		w.events = make(map[string][]int64)
+	case RedisCache, RedisClusterCache, RedisRingCache, RedisSentinelCache:
+		if cache.redisCon == nil {
+			return nil, ErrCacheClosed
+		}
+		w.con = cache.redisCon
+		w.prefix = name + ":"
+		if len(opt.KeyPrefix) != 0 {
+			w.prefix = opt.KeyPrefix + ":" + w.prefix
+		}
+	default:
+		return nil, errors.New("unsupported cache type")
+	}
+
+	return w, nil
+}
+
+func (w *Window) window(window time.Duration) time.Duration {
+	if window <= 0 || window > w.retention {
+		return w.retention
+	}
+	return window
+}
+
+// prune removes expired events of the key. Lock must be held by the caller.
+func (w *Window) prune(key string, now int64) []int64 {
+	events := w.events[key]
+	cutoff := now - int64(w.retention)
+	i := sort.Search(len(events), func(i int) bool { return events[i] > cutoff })
+	events = events[i:]
+	if len(events) == 0 {
+		delete(w.events, key)
+	} else {
+		w.events[key] = events
+	}
+	return events
+}
+
+func count(events []int64, now int64, window time.Duration) int64 {
+	cutoff := now - int64(window)
+	i := sort.Search(len(events), func(i int) bool { return events[i] > cutoff })
+	return int64(len(events) - i)
+}
+
+// member returns unique sorted set member for the event.
+func (w *Window) member(now int64) string {
+	return strconv.FormatInt(now, 36) + "-" +
+		strconv.FormatUint(uint64(atomic.AddUint32(&w.seq, 1)), 36) + "-" +
+		strconv.FormatUint(uint64(rand.Uint32()), 36) //nolint:gosec
+}
+
+// Add records event for the key.
+func (w *Window) Add(ctx context.Context, key string) error {
+	_, err := w.Incr(ctx, key, w.retention)
+	return err
+}
+
+// Incr records event for the key and returns count of events in the window including
+// recorded one. Window larger than retention is limited to the retention.
+func (w *Window) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	window = w.window(window)
+	now := time.Now().UnixNano()
+
+	finish := w.instrumenter.Observe(ctx, InstrumentationCacheWindowAdd, w.prefix+key)
+
+	if w.con == nil {
+		w.lock.Lock()
+		events := append(w.prune(key, now), now)
+		// Keep events sorted as clock can move backwards.
+		sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+		w.events[key] = events
+		n := count(events, now, window)
+		w.lock.Unlock()
+
+		finish(nil)
+		return n, nil
+	}
+
+	var cnt *redis.IntCmd
+	_, err := w.con.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		k := w.prefix + key
+		p.ZRemRangeByScore(ctx, k, "-inf", strconv.FormatInt(now-int64(w.retention), 10))
+		p.ZAdd(ctx, k, redis.Z{Score: float64(now), Member: w.member(now)})
+		p.PExpire(ctx, k, w.retention)
+		cnt = p.ZCount(ctx, k, "("+strconv.FormatInt(now-int64(window), 10), "+inf")
+		return nil
+	})
+	if err != nil {
+		finish(err)
+		return 0, err
+	}
+	finish(nil)
+	return cnt.Val(), nil
+}
+
+// Count returns count of events for the key in the window. Window larger than
+// retention is limited to the retention.
+func (w *Window) Count(ctx context.Context, key string, window time.Duration) (int64, error) {
+	counts, err := w.Counts(ctx, key, window)
+	if err != nil {
+		return 0, err
+	}
+	return counts[0], nil
+}
+
+// Counts returns counts of events for the key in each of the windows.
+func (w *Window) Counts(ctx context.Context, key string, windows ...time.Duration) ([]int64, error) {
+	now := time.Now().UnixNano()
+
+	finish := w.instrumenter.Observe(ctx, InstrumentationCacheWindowCount, w.prefix+key)
+
+	counts := make([]int64, len(windows))
+
+	if w.con == nil {
+		w.lock.Lock()
+		events := w.prune(key, now)
+		for i, window := range windows {
+			counts[i] = count(events, now, w.window(window))
+		}
+		w.lock.Unlock()
+
+		finish(nil)
+		return counts, nil
+	}
+
+	cmds := make([]*redis.IntCmd, len(windows))
+	_, err := w.con.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, window := range windows {
+			cmds[i] = p.ZCount(ctx, w.prefix+key, "("+strconv.FormatInt(now-int64(w.window(window)), 10), "+inf")
+		}
+		return nil
+	})
+	if err != nil {
+		finish(err)
+		return nil, err
+	}
+	for i, cmd := range cmds {
+		counts[i] = cmd.Val()
+	}
+	finish(nil)
+	return counts, nil
+}
+
+// Reset removes all events of the key.
+func (w *Window) Reset(ctx context.Context, key string) error {
+	if w.con == nil {
+		w.lock.Lock()
+		delete(w.events, key)
+		w.lock.Unlock()
+		return nil
+	}
+	return w.con.Del(ctx, w.prefix+key).Err()
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testWindow(t *testing.T, w *Window) {
+	ctx := context.TODO()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, w.Add(ctx, "ip"))
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	n, err := w.Incr(ctx, "ip", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	counts, err := w.Counts(ctx, "ip", 50*time.Millisecond, time.Second, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 4, 4}, counts)
+
+	n, err = w.Count(ctx, "other", time.Second)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	require.NoError(t, w.Reset(ctx, "ip"))
+	n, err = w.Count(ctx, "ip", time.Second)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestMemoryWindow(t *testing.T) {
+	c := New(MemoryCache)
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	w, err := NewWindow(c, "login", time.Second)
+	require.NoError(t, err)
+
+	testWindow(t, w)
+}
+
+func TestRedisWindow(t *testing.T) {
+	c, _ := newMiniRedisCache(t)
+
+	w, err := NewWindow(c, "login", time.Second)
+	require.NoError(t, err)
+
+	testWindow(t, w)
+}
@@ -2,10 +2,14 @@ package core
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"azugo.io/core/cache"
+	"azugo.io/core/config"
+	"azugo.io/core/serializer"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -27,7 +31,7 @@ func TestCacheInstrumentation(t *testing.T) {
 
 	require.NoError(t, a.Start())
 
-	c, err := cache.Create[string](a.Cache(), "test", cache.Loader(func(_ context.Context, key string) (any, error) {
+	c, err := cache.Create[string](a.Cache(), "test", cache.Loader[string](func(_ context.Context, key string) (string, error) {
 		return "loaded", nil
 	}))
 	require.NoError(t, err)
@@ -73,3 +77,46 @@ func TestCacheInstrumentation(t *testing.T) {
 		cache.InstrumentationCacheClose + ":end",
 	}, actions)
 }
+
+func TestCacheInstancesConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+cache:
+  ttl: 1h
+  instances:
+    sessions:
+      ttl: 10m
+      serializer: msgpack
+      compress: true
+    profiles:
+      max_items: 100
+`), 0o600))
+
+	a := New()
+	conf := config.New()
+	conf.SetConfigFile(path)
+	require.NoError(t, conf.Load(nil, conf, ""))
+	require.NoError(t, conf.Validate(a.Validate()))
+	a.SetConfig(nil, conf)
+	require.NoError(t, a.Start())
+	t.Cleanup(a.Stop)
+
+	assert.Equal(t, []string{"profiles", "sessions"}, a.Cache().Defined())
+
+	sessions, err := cache.Get[string](a.Cache(), "sessions")
+	require.NoError(t, err)
+	settings := cache.ResolveItemOptions(sessions)
+	assert.Equal(t, 10*time.Minute, settings.TTL)
+	assert.Equal(t, serializer.Gzip(serializer.MsgPack).ContentType(), settings.Serializer.ContentType())
+
+	profiles, err := cache.Get[int](a.Cache(), "profiles")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, cache.ResolveItemOptions(profiles).TTL)
+	require.NoError(t, profiles.Set(context.TODO(), "john", 1))
+	v, err := profiles.Get(context.TODO(), "john")
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	conf.Cache.Instances["profiles"].Tiered = &config.CacheTiered{TTL: config.Duration(time.Minute)}
+	assert.Error(t, conf.Validate(a.Validate()))
+}
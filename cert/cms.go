@@ -0,0 +1,97 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/digitorus/pkcs7"
+)
+
+// PEMBlockPKCS7 is a PEM block type of PKCS #7 structure.
+const PEMBlockPKCS7 = "PKCS7"
+
+func cmsDigestAlgorithm(hash crypto.Hash) (asn1.ObjectIdentifier, error) {
+	switch hash {
+	case crypto.SHA256:
+		return pkcs7.OIDDigestAlgorithmSHA256, nil
+	case crypto.SHA384:
+		return pkcs7.OIDDigestAlgorithmSHA384, nil
+	case crypto.SHA512:
+		return pkcs7.OIDDigestAlgorithmSHA512, nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %s", hash)
+	}
+}
+
+// SignCMS creates DER encoded detached CMS (PKCS #7) signature of the data.
+//
+// Chain must start with the signer certificate matching the private key followed by
+// its issuers that are embedded in the signature. Digest algorithm is selected
+// based on the key type and size.
+func SignCMS(data []byte, chain []*x509.Certificate, key crypto.Signer) ([]byte, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("signer certificate is required")
+	}
+	if err := KeyMatchesCertificate(key, chain[0]); err != nil {
+		return nil, err
+	}
+	if err := ValidateChainOrder(chain); err != nil {
+		return nil, err
+	}
+
+	sd, err := pkcs7.NewSignedData(data)
+	if err != nil {
+		return nil, err
+	}
+	hash := defaultHash(key.Public())
+	if hash == 0 {
+		// Ed25519 signatures in CMS use SHA-512 message digest (RFC 8419).
+		hash = crypto.SHA512
+	}
+	oid, err := cmsDigestAlgorithm(hash)
+	if err != nil {
+		return nil, err
+	}
+	sd.SetDigestAlgorithm(oid)
+	if err := sd.AddSignerChain(chain[0], key, chain[1:], pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("unable to sign data: %w", err)
+	}
+	sd.Detach()
+	return sd.Finish()
+}
+
+// VerifyCMS verifies detached CMS (PKCS #7) signature of the data and returns
+// the signer certificate.
+//
+// Signature can be DER or PEM encoded. If roots is not nil signer certificate must
+// chain to one of the roots using certificates embedded in the signature.
+// Returned error wraps ErrInvalidSignature if signature does not match.
+func VerifyCMS(data, sig []byte, roots *x509.CertPool) (*x509.Certificate, error) {
+	if block, _ := pem.Decode(sig); block != nil {
+		if block.Type != PEMBlockPKCS7 && block.Type != "CMS" {
+			return nil, fmt.Errorf("unexpected PEM block type %q", block.Type)
+		}
+		sig = block.Bytes
+	}
+
+	p7, err := pkcs7.Parse(sig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse signature: %w", err)
+	}
+	if len(p7.Signers) != 1 {
+		return nil, fmt.Errorf("%w: expected single signer, got %d", ErrInvalidSignature, len(p7.Signers))
+	}
+	p7.Content = data
+	if err := p7.VerifyWithChain(roots); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return p7.GetOnlySigner(), nil
+}
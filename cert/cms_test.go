@@ -0,0 +1,46 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerifyCMS(t *testing.T) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	leafKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	root := testCertificate(t, "root", rootKey, nil, nil)
+	leaf := testCertificate(t, "signer", leafKey, root, rootKey)
+
+	data := []byte("document")
+
+	sig, err := SignCMS(data, []*x509.Certificate{leaf, root}, leafKey)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	signer, err := VerifyCMS(data, sig, roots)
+	require.NoError(t, err)
+	assert.Equal(t, "signer", signer.Subject.CommonName)
+
+	signer, err = VerifyCMS(data, pem.EncodeToMemory(&pem.Block{Type: PEMBlockPKCS7, Bytes: sig}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "signer", signer.Subject.CommonName)
+
+	_, err = VerifyCMS([]byte("tampered"), sig, roots)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = SignCMS(data, []*x509.Certificate{leaf}, rootKey)
+	assert.ErrorIs(t, err, ErrKeyMismatch)
+}
@@ -0,0 +1,175 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	stdlog "log"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "1.0",
+	tls.VersionTLS11: "1.1",
+	tls.VersionTLS12: "1.2",
+	tls.VersionTLS13: "1.3",
+}
+
+func tlsVersionName(v uint16) string {
+	if n, ok := tlsVersions[v]; ok {
+		return n
+	}
+	return "unknown"
+}
+
+func tlsVersionNames(vs []uint16) []string {
+	names := make([]string, 0, len(vs))
+	for _, v := range vs {
+		if n, ok := tlsVersions[v]; ok {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+func peerFields(certs []*x509.Certificate) []zap.Field {
+	if len(certs) == 0 {
+		return nil
+	}
+	c := certs[0]
+	return []zap.Field{
+		zap.String("tls.peer.x509.subject.distinguished_name", c.Subject.String()),
+		zap.String("tls.peer.x509.issuer.distinguished_name", c.Issuer.String()),
+		zap.String("tls.peer.x509.serial_number", strings.ToUpper(c.SerialNumber.Text(16))),
+		zap.Time("tls.peer.x509.not_after", c.NotAfter),
+	}
+}
+
+type handshakeLogger struct {
+	log *zap.Logger
+}
+
+func (h *handshakeLogger) clientHello(hello *tls.ClientHelloInfo) {
+	fields := []zap.Field{
+		zap.String("tls.client.server_name", hello.ServerName),
+		zap.Strings("tls.client.supported_versions", tlsVersionNames(hello.SupportedVersions)),
+		zap.Strings("tls.client.supported_protocols", hello.SupportedProtos),
+	}
+	if hello.Conn != nil {
+		fields = append(fields, zap.String("client.address", hello.Conn.RemoteAddr().String()))
+	}
+	h.log.Debug("TLS client hello", fields...)
+}
+
+func (h *handshakeLogger) failed(msg string, err error, fields ...zap.Field) {
+	h.log.Debug(msg, append(fields, zap.Error(err))...)
+}
+
+func (h *handshakeLogger) established(cs tls.ConnectionState) {
+	fields := []zap.Field{
+		zap.String("tls.version", tlsVersionName(cs.Version)),
+		zap.String("tls.cipher", tls.CipherSuiteName(cs.CipherSuite)),
+		zap.String("tls.client.server_name", cs.ServerName),
+		zap.String("tls.next_protocol", cs.NegotiatedProtocol),
+		zap.Bool("tls.resumed", cs.DidResume),
+		zap.Bool("tls.peer.verified", len(cs.VerifiedChains) > 0),
+	}
+	h.log.Debug("TLS handshake established", append(fields, peerFields(cs.PeerCertificates)...)...)
+}
+
+func (h *handshakeLogger) wrap(conf *tls.Config) {
+	if getCert := conf.GetCertificate; getCert != nil {
+		conf.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			c, err := getCert(hello)
+			if err != nil {
+				h.failed("TLS handshake failed: no certificate", err,
+					zap.String("tls.client.server_name", hello.ServerName))
+			}
+			return c, err
+		}
+	}
+
+	verify := conf.VerifyConnection
+	conf.VerifyConnection = func(cs tls.ConnectionState) error {
+		if verify != nil {
+			if err := verify(cs); err != nil {
+				h.failed("TLS handshake failed: connection verification", err,
+					append([]zap.Field{zap.String("tls.client.server_name", cs.ServerName)}, peerFields(cs.PeerCertificates)...)...)
+				return err
+			}
+		}
+		h.established(cs)
+		return nil
+	}
+}
+
+// LogHandshakes returns copy of TLS configuration that logs TLS handshakes with
+// negotiated version, cipher suite, SNI and peer certificate details.
+//
+// Handshakes are logged only if debug level is enabled in the logger, otherwise
+// configuration is returned unchanged. Failures returned by certificate selection
+// and connection verification callbacks are logged with the failure reason. Use
+// HandshakeErrorLog for HTTP servers to log failures that occur in the TLS stack
+// itself, such as untrusted client certificates.
+func LogHandshakes(conf *tls.Config, log *zap.Logger) *tls.Config {
+	if conf == nil || log == nil || !log.Core().Enabled(zapcore.DebugLevel) {
+		return conf
+	}
+
+	h := &handshakeLogger{log: log}
+
+	c := conf.Clone()
+	h.wrap(c)
+
+	getConfig := c.GetConfigForClient
+	c.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		h.clientHello(hello)
+		if getConfig == nil {
+			return nil, nil
+		}
+		cc, err := getConfig(hello)
+		if err != nil {
+			h.failed("TLS handshake failed: client configuration", err,
+				zap.String("tls.client.server_name", hello.ServerName))
+			return nil, err
+		}
+		if cc != nil {
+			cc = cc.Clone()
+			h.wrap(cc)
+		}
+		return cc, nil
+	}
+	return c
+}
+
+type handshakeErrorWriter struct {
+	log *zap.Logger
+}
+
+func (w *handshakeErrorWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+	if i := strings.Index(msg, "TLS handshake error from "); i >= 0 {
+		msg = msg[i+len("TLS handshake error from "):]
+		addr, reason, _ := strings.Cut(msg, ": ")
+		w.log.Debug("TLS handshake failed",
+			zap.String("client.address", addr),
+			zap.String("error.message", reason),
+		)
+		return len(p), nil
+	}
+	w.log.Warn(msg)
+	return len(p), nil
+}
+
+// HandshakeErrorLog returns logger to use as http.Server ErrorLog that logs TLS
+// handshake failures with the failure reason at debug level. Other server errors
+// are logged at warn level.
+func HandshakeErrorLog(log *zap.Logger) *stdlog.Logger {
+	return stdlog.New(&handshakeErrorWriter{log: log}, "", 0)
+}
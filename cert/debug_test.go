@@ -0,0 +1,121 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func testTLSPair(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	c := testCertificate(t, "server.local", key, nil, nil)
+
+	server := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{c.Raw}, PrivateKey: key, Leaf: c}},
+		MinVersion:   tls.VersionTLS12,
+	}
+	client := &tls.Config{
+		InsecureSkipVerify: true, //nolint:gosec
+		MinVersion:         tls.VersionTLS12,
+	}
+	return server, client
+}
+
+func handshake(t *testing.T, server, client *tls.Config) (error, error) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer conn.Close()
+		errc <- tls.Server(conn, server).Handshake()
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	cerr := tls.Client(conn, client).Handshake()
+	return <-errc, cerr
+}
+
+func TestLogHandshakes(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(core)
+
+	server, client := testTLSPair(t)
+	client.ServerName = "server.local"
+
+	serr, cerr := handshake(t, LogHandshakes(server, log), client)
+	require.NoError(t, serr)
+	require.NoError(t, cerr)
+
+	hello := logs.FilterMessage("TLS client hello").All()
+	require.Len(t, hello, 1)
+	assert.Equal(t, "server.local", hello[0].ContextMap()["tls.client.server_name"])
+
+	est := logs.FilterMessage("TLS handshake established").All()
+	require.Len(t, est, 1)
+	assert.Equal(t, "1.3", est[0].ContextMap()["tls.version"])
+	assert.NotEmpty(t, est[0].ContextMap()["tls.cipher"])
+
+	// Original configuration is not modified.
+	assert.Nil(t, server.GetConfigForClient)
+	assert.Nil(t, server.VerifyConnection)
+}
+
+func TestLogHandshakesFailure(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(core)
+
+	server, client := testTLSPair(t)
+	server.VerifyConnection = func(cs tls.ConnectionState) error {
+		return errors.New("client certificate required")
+	}
+
+	serr, _ := handshake(t, LogHandshakes(server, log), client)
+	require.Error(t, serr)
+
+	failed := logs.FilterMessage("TLS handshake failed: connection verification").All()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "client certificate required", failed[0].ContextMap()["error"])
+}
+
+func TestLogHandshakesDisabled(t *testing.T) {
+	core, _ := observer.New(zapcore.InfoLevel)
+
+	server, _ := testTLSPair(t)
+	assert.Same(t, server, LogHandshakes(server, zap.New(core)))
+}
+
+func TestHandshakeErrorLog(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	HandshakeErrorLog(zap.New(core)).Printf("http: TLS handshake error from 10.0.0.1:5555: remote error: tls: bad certificate")
+
+	entries := logs.FilterMessage("TLS handshake failed").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "10.0.0.1:5555", entries[0].ContextMap()["client.address"])
+	assert.Equal(t, "remote error: tls: bad certificate", entries[0].ContextMap()["error.message"])
+}
@@ -0,0 +1,371 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"azugo.io/core/queue"
+	"azugo.io/core/webhook"
+
+	"github.com/goccy/go-json"
+)
+
+// EventCertificateExpiring is an event type of certificate expiry alerts.
+const EventCertificateExpiring = "certificate.expiring"
+
+// DefaultAlertThresholds are remaining validity durations at which expiry alerts are sent.
+var DefaultAlertThresholds = []time.Duration{
+	30 * 24 * time.Hour,
+	14 * 24 * time.Hour,
+	7 * 24 * time.Hour,
+	24 * time.Hour,
+}
+
+// ExpiryAlert is a notification about expiring certificate.
+type ExpiryAlert struct {
+	// Name of the monitored certificate.
+	Name string `json:"name"`
+	// Subject of the certificate.
+	Subject string `json:"subject"`
+	// Issuer of the certificate.
+	Issuer string `json:"issuer"`
+	// SerialNumber of the certificate in hex encoding.
+	SerialNumber string `json:"serial_number"`
+	// NotAfter is a time when certificate expires.
+	NotAfter time.Time `json:"not_after"`
+	// Remaining validity of the certificate at the time of the check.
+	// Negative value means that certificate has already expired.
+	Remaining time.Duration `json:"remaining"`
+	// Threshold that was crossed.
+	Threshold time.Duration `json:"threshold"`
+}
+
+// Expired returns true if certificate has already expired.
+func (a ExpiryAlert) Expired() bool {
+	return a.Remaining <= 0
+}
+
+// Notifier sends certificate expiry alerts.
+type Notifier interface {
+	MonitorOption
+	// Notify about expiring certificate.
+	Notify(ctx context.Context, alert ExpiryAlert) error
+}
+
+// NotifierFunc is a callback notifier.
+type NotifierFunc func(ctx context.Context, alert ExpiryAlert) error
+
+func (f NotifierFunc) Notify(ctx context.Context, alert ExpiryAlert) error {
+	return f(ctx, alert)
+}
+
+func (f NotifierFunc) applyMonitor(o *monitorOptions) {
+	o.Notifiers = append(o.Notifiers, f)
+}
+
+// WebhookNotifier dispatches expiry alerts as webhook events to the endpoint.
+type WebhookNotifier struct {
+	Dispatcher *webhook.Dispatcher
+	Endpoint   webhook.Endpoint
+}
+
+func (n WebhookNotifier) Notify(_ context.Context, alert ExpiryAlert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	return n.Dispatcher.Dispatch(n.Endpoint, webhook.Event{
+		ID:      alert.id(),
+		Type:    EventCertificateExpiring,
+		Payload: payload,
+	})
+}
+
+func (n WebhookNotifier) applyMonitor(o *monitorOptions) {
+	o.Notifiers = append(o.Notifiers, n)
+}
+
+// QueueNotifier publishes expiry alerts as JSON messages to the queue topic.
+type QueueNotifier struct {
+	Publisher queue.Publisher
+	// Topic to publish alerts to. Defaults to "certificate-expiry".
+	Topic string
+}
+
+func (n QueueNotifier) Notify(ctx context.Context, alert ExpiryAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	topic := n.Topic
+	if len(topic) == 0 {
+		topic = "certificate-expiry"
+	}
+	return n.Publisher.Publish(ctx, &queue.Message{
+		Topic: topic,
+		Key:   alert.Name,
+		Headers: map[string]string{
+			"event": EventCertificateExpiring,
+		},
+		Body: body,
+	})
+}
+
+func (n QueueNotifier) applyMonitor(o *monitorOptions) {
+	o.Notifiers = append(o.Notifiers, n)
+}
+
+func (a ExpiryAlert) id() string {
+	return a.Name + ":" + a.SerialNumber + ":" + strconv.FormatInt(int64(a.Threshold/time.Second), 10)
+}
+
+type monitorOptions struct {
+	Thresholds []time.Duration
+	Interval   time.Duration
+	Notifiers  []Notifier
+}
+
+// MonitorOption for the certificate expiry monitor.
+type MonitorOption interface {
+	applyMonitor(*monitorOptions)
+}
+
+// AlertThresholds are remaining validity durations at which alerts are sent.
+// Defaults to DefaultAlertThresholds.
+type AlertThresholds []time.Duration
+
+func (t AlertThresholds) applyMonitor(o *monitorOptions) {
+	o.Thresholds = append([]time.Duration{}, t...)
+}
+
+// CheckInterval is an interval between certificate expiry checks. Defaults to 1 hour.
+type CheckInterval time.Duration
+
+func (i CheckInterval) applyMonitor(o *monitorOptions) {
+	o.Interval = time.Duration(i)
+}
+
+// CertificateSource returns current certificate to monitor.
+type CertificateSource func(ctx context.Context) (*x509.Certificate, error)
+
+// StaticCertificate returns source for the certificate.
+func StaticCertificate(c *x509.Certificate) CertificateSource {
+	return func(context.Context) (*x509.Certificate, error) {
+		return c, nil
+	}
+}
+
+// FileCertificate returns source that reads the first certificate from PEM encoded file
+// on every check.
+func FileCertificate(path string, opt ...Option) CertificateSource {
+	return func(context.Context) (*x509.Certificate, error) {
+		crt, _, err := LoadPEMFromFile(path, opt...)
+		if err != nil {
+			return nil, err
+		}
+		certs, err := ParseCertificates(crt)
+		if err != nil {
+			return nil, err
+		}
+		return certs[0], nil
+	}
+}
+
+// ExpiryMonitor periodically checks certificates and sends alerts when remaining
+// validity crosses configured thresholds.
+//
+// Every threshold is alerted once per certificate and notifier. Renewed certificate
+// with a new serial number is alerted again. Failed notifications are retried on
+// the next check.
+type ExpiryMonitor struct {
+	opts *monitorOptions
+	now  func() time.Time
+
+	lock    sync.Mutex
+	sources map[string]CertificateSource
+	sent    map[string]struct{}
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewExpiryMonitor creates new certificate expiry monitor.
+func NewExpiryMonitor(opts ...MonitorOption) *ExpiryMonitor {
+	opt := &monitorOptions{
+		Interval: time.Hour,
+	}
+	for _, o := range opts {
+		o.applyMonitor(opt)
+	}
+	if len(opt.Thresholds) == 0 {
+		opt.Thresholds = append([]time.Duration{}, DefaultAlertThresholds...)
+	}
+	sort.Slice(opt.Thresholds, func(i, j int) bool {
+		return opt.Thresholds[i] < opt.Thresholds[j]
+	})
+	return &ExpiryMonitor{
+		opts:    opt,
+		now:     time.Now,
+		sources: make(map[string]CertificateSource),
+		sent:    make(map[string]struct{}),
+	}
+}
+
+// Add certificate source to monitor.
+func (m *ExpiryMonitor) Add(name string, source CertificateSource) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.sources[name] = source
+}
+
+// Remove certificate from monitoring.
+func (m *ExpiryMonitor) Remove(name string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.sources, name)
+}
+
+// Name returns task name.
+func (m *ExpiryMonitor) Name() string {
+	return "certificate-expiry-monitor"
+}
+
+// Start checking certificates in the background.
+func (m *ExpiryMonitor) Start(ctx context.Context) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.cancel != nil {
+		return nil
+	}
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+	go m.run(ctx, m.done)
+	return nil
+}
+
+// Stop checking certificates.
+func (m *ExpiryMonitor) Stop() {
+	m.lock.Lock()
+	if m.cancel == nil {
+		m.lock.Unlock()
+		return
+	}
+	m.cancel()
+	m.cancel = nil
+	done := m.done
+	m.lock.Unlock()
+
+	<-done
+}
+
+func (m *ExpiryMonitor) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	t := time.NewTicker(m.opts.Interval)
+	defer t.Stop()
+
+	for {
+		_ = m.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// threshold returns smallest threshold crossed by remaining validity.
+func (m *ExpiryMonitor) threshold(remaining time.Duration) (time.Duration, bool) {
+	for _, t := range m.opts.Thresholds {
+		if remaining <= t {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// Check all monitored certificates and send alerts for crossed thresholds.
+//
+// Returns first error that occurred while loading certificates or sending alerts.
+func (m *ExpiryMonitor) Check(ctx context.Context) error {
+	m.lock.Lock()
+	sources := make(map[string]CertificateSource, len(m.sources))
+	for name, s := range m.sources {
+		sources[name] = s
+	}
+	m.lock.Unlock()
+
+	var err error
+	now := m.now()
+	for name, source := range sources {
+		c, serr := source(ctx)
+		if serr != nil {
+			if err == nil {
+				err = fmt.Errorf("certificate %q: %w", name, serr)
+			}
+			continue
+		}
+		if c == nil {
+			continue
+		}
+
+		remaining := c.NotAfter.Sub(now)
+		threshold, ok := m.threshold(remaining)
+		if !ok {
+			continue
+		}
+		alert := ExpiryAlert{
+			Name:         name,
+			Subject:      c.Subject.String(),
+			Issuer:       c.Issuer.String(),
+			SerialNumber: hex.EncodeToString(c.SerialNumber.Bytes()),
+			NotAfter:     c.NotAfter,
+			Remaining:    remaining,
+			Threshold:    threshold,
+		}
+		if nerr := m.notify(ctx, alert); nerr != nil && err == nil {
+			err = fmt.Errorf("certificate %q: %w", name, nerr)
+		}
+	}
+	return err
+}
+
+func (m *ExpiryMonitor) notify(ctx context.Context, alert ExpiryAlert) error {
+	var err error
+	for i, n := range m.opts.Notifiers {
+		id := alert.id() + ":" + strconv.Itoa(i)
+
+		m.lock.Lock()
+		_, sent := m.sent[id]
+		m.lock.Unlock()
+		if sent {
+			continue
+		}
+
+		if nerr := n.Notify(ctx, alert); nerr != nil {
+			if err == nil {
+				err = nerr
+			}
+			continue
+		}
+
+		m.lock.Lock()
+		m.sent[id] = struct{}{}
+		m.lock.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package cert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"azugo.io/core/queue"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func expiringCertificate(t *testing.T, serial int64, notAfter time.Time) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	c, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return c
+}
+
+func TestExpiryMonitorThresholds(t *testing.T) {
+	now := time.Now()
+	alerts := make([]ExpiryAlert, 0)
+	fail := true
+
+	m := NewExpiryMonitor(NotifierFunc(func(ctx context.Context, alert ExpiryAlert) error {
+		alerts = append(alerts, alert)
+		return nil
+	}), NotifierFunc(func(ctx context.Context, alert ExpiryAlert) error {
+		if fail {
+			return errors.New("unavailable")
+		}
+		return nil
+	}))
+	m.now = func() time.Time { return now }
+
+	crt := expiringCertificate(t, 1, now.Add(10*24*time.Hour))
+	m.Add("api", StaticCertificate(crt))
+	m.Add("valid", StaticCertificate(expiringCertificate(t, 2, now.Add(60*24*time.Hour))))
+
+	err := m.Check(context.TODO())
+	assert.ErrorContains(t, err, `certificate "api": unavailable`)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "api", alerts[0].Name)
+	assert.Equal(t, 14*24*time.Hour, alerts[0].Threshold)
+	assert.False(t, alerts[0].Expired())
+
+	// Successful notifiers are not repeated.
+	fail = false
+	require.NoError(t, m.Check(context.TODO()))
+	assert.Len(t, alerts, 1)
+
+	now = now.Add(4 * 24 * time.Hour)
+	require.NoError(t, m.Check(context.TODO()))
+	require.Len(t, alerts, 2)
+	assert.Equal(t, 7*24*time.Hour, alerts[1].Threshold)
+
+	// Renewed certificate is alerted again.
+	m.Add("api", StaticCertificate(expiringCertificate(t, 3, now.Add(20*24*time.Hour))))
+	require.NoError(t, m.Check(context.TODO()))
+	require.Len(t, alerts, 3)
+	assert.Equal(t, 30*24*time.Hour, alerts[2].Threshold)
+}
+
+func TestExpiryMonitorQueueNotifier(t *testing.T) {
+	q := queue.NewMemory(10)
+	defer q.Close()
+
+	msgs := make(chan *queue.Message, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, q.Subscribe(ctx, "certificate-expiry", func(ctx context.Context, msg *queue.Message) error {
+		msgs <- msg
+		return nil
+	}))
+
+	m := NewExpiryMonitor(QueueNotifier{Publisher: q}, AlertThresholds{48 * time.Hour})
+	m.Add("api", StaticCertificate(expiringCertificate(t, 1, time.Now().Add(-time.Hour))))
+	require.NoError(t, m.Check(context.TODO()))
+
+	select {
+	case msg := <-msgs:
+		assert.Equal(t, "api", msg.Key)
+		var alert ExpiryAlert
+		require.NoError(t, json.Unmarshal(msg.Body, &alert))
+		assert.True(t, alert.Expired())
+		assert.Equal(t, 48*time.Hour, alert.Threshold)
+	case <-time.After(time.Second):
+		t.Fatal("alert not published")
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/lafriks/pkcs8"
+)
+
+var (
+	// ErrKeyMismatch is returned when private key does not match certificate public key.
+	ErrKeyMismatch = errors.New("private key does not match certificate")
+	// ErrChainOrder is returned when certificate chain is not ordered from leaf to root.
+	ErrChainOrder = errors.New("invalid certificate chain order")
+)
+
+func keyTypeName(k any) string {
+	switch k.(type) {
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return "RSA"
+	case *ecdsa.PublicKey, *ecdsa.PrivateKey:
+		return "ECDSA"
+	case ed25519.PublicKey, ed25519.PrivateKey, *ed25519.PrivateKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", k)
+	}
+}
+
+// KeyMatchesCertificate checks that private key matches certificate public key.
+//
+// Supports RSA, ECDSA and Ed25519 keys. Returned error wraps ErrKeyMismatch with
+// the reason of the mismatch.
+func KeyMatchesCertificate(key any, cert *x509.Certificate) error {
+	if cert == nil {
+		return fmt.Errorf("%w: certificate is empty", ErrKeyMismatch)
+	}
+	if k, ok := key.(*ed25519.PrivateKey); ok {
+		key = *k
+	}
+	var pub crypto.PublicKey
+	if s, ok := key.(crypto.Signer); ok {
+		pub = s.Public()
+	} else {
+		return fmt.Errorf("%w: unsupported private key type %T", ErrKeyMismatch, key)
+	}
+
+	switch cp := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		kp, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %s private key for RSA certificate", ErrKeyMismatch, keyTypeName(pub))
+		}
+		if kp.E != cp.E {
+			return fmt.Errorf("%w: RSA public exponent differs", ErrKeyMismatch)
+		}
+		if kp.N.BitLen() != cp.N.BitLen() {
+			return fmt.Errorf("%w: RSA key size %d does not match certificate key size %d", ErrKeyMismatch, kp.N.BitLen(), cp.N.BitLen())
+		}
+		if kp.N.Cmp(cp.N) != 0 {
+			return fmt.Errorf("%w: RSA modulus differs", ErrKeyMismatch)
+		}
+	case *ecdsa.PublicKey:
+		kp, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %s private key for ECDSA certificate", ErrKeyMismatch, keyTypeName(pub))
+		}
+		if kp.Curve != cp.Curve {
+			return fmt.Errorf("%w: ECDSA curve %s does not match certificate curve %s", ErrKeyMismatch, kp.Curve.Params().Name, cp.Curve.Params().Name)
+		}
+		if kp.X.Cmp(cp.X) != 0 || kp.Y.Cmp(cp.Y) != 0 {
+			return fmt.Errorf("%w: ECDSA public point differs", ErrKeyMismatch)
+		}
+	case ed25519.PublicKey:
+		kp, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %s private key for Ed25519 certificate", ErrKeyMismatch, keyTypeName(pub))
+		}
+		if !bytes.Equal(kp, cp) {
+			return fmt.Errorf("%w: Ed25519 public key differs", ErrKeyMismatch)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported certificate public key type %T", ErrKeyMismatch, cert.PublicKey)
+	}
+	return nil
+}
+
+func certName(c *x509.Certificate) string {
+	if len(c.Subject.CommonName) != 0 {
+		return c.Subject.CommonName
+	}
+	return c.Subject.String()
+}
+
+// ValidateChainOrder checks that certificates are ordered from leaf to root with
+// each certificate issued by the next one in the chain.
+//
+// Returned error wraps ErrChainOrder with the reason.
+func ValidateChainOrder(chain []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("%w: certificate chain is empty", ErrChainOrder)
+	}
+	for i := 0; i < len(chain)-1; i++ {
+		child, parent := chain[i], chain[i+1]
+		if !bytes.Equal(child.RawIssuer, parent.RawSubject) {
+			return fmt.Errorf("%w: certificate %d %q is issued by %q, not by next certificate %q",
+				ErrChainOrder, i, certName(child), child.Issuer.String(), parent.Subject.String())
+		}
+		if err := child.CheckSignatureFrom(parent); err != nil {
+			return fmt.Errorf("%w: certificate %d %q signature is not valid for issuer %q: %v",
+				ErrChainOrder, i, certName(child), certName(parent), err)
+		}
+	}
+	return nil
+}
+
+// ParseCertificates parses all PEM encoded certificates in the order they appear.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type == PEMBlockCertificate {
+			c, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			certs = append(certs, c)
+		}
+		data = rest
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+// ParsePrivateKey parses first PEM encoded private key.
+func ParsePrivateKey(data []byte, opt ...Option) (any, error) {
+	opts := opts(opt...)
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case PEMBlockRSAPrivateKey:
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case PEMBlockECPrivateKey:
+			if k, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+				return k, nil
+			}
+			// Some tools store PKCS #8 keys with EC block type.
+			return x509.ParsePKCS8PrivateKey(block.Bytes)
+		case PEMBlockPrivateKey:
+			return x509.ParsePKCS8PrivateKey(block.Bytes)
+		case PEMBlockEncryptedPrivateKey:
+			if len(opts.Password) == 0 {
+				return nil, errors.New("password required to decrypt private key")
+			}
+			return pkcs8.ParsePKCS8PrivateKey(block.Bytes, opts.Password)
+		}
+		data = rest
+	}
+	return nil, errors.New("no private key found")
+}
+
+// ValidateKeyPair checks that PEM encoded certificate chain is ordered from leaf
+// to root and private key matches the leaf certificate.
+func ValidateKeyPair(certPEM, keyPEM []byte, opt ...Option) error {
+	chain, err := ParseCertificates(certPEM)
+	if err != nil {
+		return err
+	}
+	key, err := ParsePrivateKey(keyPEM, opt...)
+	if err != nil {
+		return err
+	}
+	if err := KeyMatchesCertificate(key, chain[0]); err != nil {
+		return err
+	}
+	return ValidateChainOrder(chain)
+}
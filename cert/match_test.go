@@ -0,0 +1,104 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCertificate(t *testing.T, name string, priv any, parent *x509.Certificate, parentKey any) *x509.Certificate {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, priv
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey(priv), parentKey)
+	require.NoError(t, err)
+	c, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return c
+}
+
+func TestKeyMatchesCertificate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecKey384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, edKey2, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaCert := testCertificate(t, "rsa", rsaKey, nil, nil)
+	ecCert := testCertificate(t, "ec", ecKey, nil, nil)
+	edCert := testCertificate(t, "ed", edKey, nil, nil)
+
+	assert.NoError(t, KeyMatchesCertificate(rsaKey, rsaCert))
+	assert.NoError(t, KeyMatchesCertificate(ecKey, ecCert))
+	assert.NoError(t, KeyMatchesCertificate(edKey, edCert))
+
+	err = KeyMatchesCertificate(ecKey, rsaCert)
+	assert.ErrorIs(t, err, ErrKeyMismatch)
+	assert.ErrorContains(t, err, "ECDSA private key for RSA certificate")
+
+	err = KeyMatchesCertificate(ecKey384, ecCert)
+	assert.ErrorContains(t, err, "ECDSA curve P-384 does not match certificate curve P-256")
+
+	err = KeyMatchesCertificate(edKey2, edCert)
+	assert.ErrorContains(t, err, "Ed25519 public key differs")
+}
+
+func TestValidateChainOrder(t *testing.T) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	interKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	root := testCertificate(t, "root", rootKey, nil, nil)
+	inter := testCertificate(t, "intermediate", interKey, root, rootKey)
+	leaf := testCertificate(t, "leaf", leafKey, inter, interKey)
+
+	assert.NoError(t, ValidateChainOrder([]*x509.Certificate{leaf, inter, root}))
+	assert.NoError(t, ValidateChainOrder([]*x509.Certificate{leaf, inter}))
+
+	err = ValidateChainOrder([]*x509.Certificate{leaf, root, inter})
+	assert.ErrorIs(t, err, ErrChainOrder)
+	assert.ErrorContains(t, err, `certificate 0 "leaf" is issued by "CN=intermediate"`)
+
+	certPEM := make([]byte, 0)
+	for _, c := range []*x509.Certificate{leaf, inter} {
+		p, _, err := DERBytesToPEMBlocks(c.Raw, nil)
+		require.NoError(t, err)
+		certPEM = append(certPEM, p...)
+	}
+	_, keyPEM, err := DERBytesToPEMBlocks(leaf.Raw, leafKey)
+	require.NoError(t, err)
+	assert.NoError(t, ValidateKeyPair(certPEM, keyPEM))
+
+	_, otherPEM, err := DERBytesToPEMBlocks(leaf.Raw, interKey)
+	require.NoError(t, err)
+	assert.ErrorIs(t, ValidateKeyPair(certPEM, otherPEM), ErrKeyMismatch)
+}
@@ -1,7 +1,8 @@
 package cert
 
 type options struct {
-	Password []byte
+	Password       []byte
+	ExportPassword []byte
 }
 
 func opts(opt ...Option) *options {
@@ -23,3 +24,12 @@ type Password []byte
 func (p Password) apply(o *options) {
 	o.Password = p[:]
 }
+
+// ExportPassword to encrypt the private key when converting between formats.
+//
+// If not set, Password is used.
+type ExportPassword []byte
+
+func (p ExportPassword) apply(o *options) {
+	o.ExportPassword = p[:]
+}
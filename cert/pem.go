@@ -7,6 +7,7 @@ package cert
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"encoding/pem"
 	"fmt"
@@ -28,6 +29,8 @@ func publicKey(priv any) any {
 		return &k.PublicKey
 	case *ecdsa.PrivateKey:
 		return &k.PublicKey
+	case ed25519.PrivateKey:
+		return k.Public()
 	default:
 		return nil
 	}
@@ -49,6 +52,8 @@ func pemBlockForKey(priv any, opt ...Option) (*pem.Block, error) {
 // DERBytesToPEMBlocks converts certificate DER bytes and optional private key
 // to PEM blocks.
 // Returns certificate PEM block and private key PEM block.
+//
+// Private key export is subject to the export policy set by SetExportPolicy.
 func DERBytesToPEMBlocks(der []byte, priv any, opt ...Option) ([]byte, []byte, error) {
 	out := &bytes.Buffer{}
 	if err := pem.Encode(out, &pem.Block{Type: PEMBlockCertificate, Bytes: der}); err != nil {
@@ -58,6 +63,9 @@ func DERBytesToPEMBlocks(der []byte, priv any, opt ...Option) ([]byte, []byte, e
 
 	var key []byte
 	if priv != nil {
+		if err := checkExport(len(opts(opt...).Password) > 0); err != nil {
+			return nil, nil, err
+		}
 		out.Reset()
 		block, err := pemBlockForKey(priv, opt...)
 		if err != nil {
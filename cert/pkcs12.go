@@ -0,0 +1,76 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// EncodePKCS12 encodes certificate chain and private key to PKCS #12 archive
+// protected with the password.
+//
+// Archive is encrypted using AES-256 and PBKDF2. If password is empty archive
+// is not encrypted. Private key export is subject to the export policy set by
+// SetExportPolicy.
+func EncodePKCS12(crt *tls.Certificate, password string) ([]byte, error) {
+	if crt == nil || len(crt.Certificate) == 0 {
+		return nil, errors.New("certificate is empty")
+	}
+	if crt.PrivateKey == nil {
+		return nil, errors.New("private key is empty")
+	}
+	if err := checkExport(len(password) > 0); err != nil {
+		return nil, err
+	}
+
+	chain := make([]*x509.Certificate, 0, len(crt.Certificate))
+	for _, der := range crt.Certificate {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, c)
+	}
+
+	enc := pkcs12.Modern
+	if len(password) == 0 {
+		enc = pkcs12.Passwordless
+	}
+	return enc.Encode(crt.PrivateKey, chain[0], chain[1:], password)
+}
+
+// EncodePKCS12TrustStore encodes certificates to PKCS #12 trust store without
+// private keys.
+func EncodePKCS12TrustStore(certs []*x509.Certificate, password string) ([]byte, error) {
+	enc := pkcs12.Modern
+	if len(password) == 0 {
+		enc = pkcs12.Passwordless
+	}
+	return enc.EncodeTrustStore(certs, password)
+}
+
+// DecodePKCS12 decodes certificate chain and private key from PKCS #12 archive.
+func DecodePKCS12(data []byte, password string) (*tls.Certificate, error) {
+	key, leaf, ca, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := KeyMatchesCertificate(key, leaf); err != nil {
+		return nil, err
+	}
+	crt := &tls.Certificate{
+		Certificate: [][]byte{leaf.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+	for _, c := range ca {
+		crt.Certificate = append(crt.Certificate, c.Raw)
+	}
+	return crt, nil
+}
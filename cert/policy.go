@@ -0,0 +1,86 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrKeyExportDenied is returned when private key export is not allowed by the export policy.
+var ErrKeyExportDenied = errors.New("private key export denied by policy")
+
+// ExportPolicy controls whether private keys may be exported.
+type ExportPolicy int32
+
+const (
+	// ExportAlways allows private keys to be exported in plain and encrypted form.
+	ExportAlways ExportPolicy = iota
+	// ExportEncryptedOnly allows private keys to be exported only encrypted with a password.
+	ExportEncryptedOnly
+	// ExportNever does not allow private keys to be exported.
+	ExportNever
+)
+
+func (p ExportPolicy) String() string {
+	switch p {
+	case ExportAlways:
+		return "always"
+	case ExportEncryptedOnly:
+		return "encrypted-only"
+	case ExportNever:
+		return "never"
+	default:
+		return fmt.Sprintf("ExportPolicy(%d)", int32(p))
+	}
+}
+
+// ParseExportPolicy parses export policy name.
+func ParseExportPolicy(name string) (ExportPolicy, error) {
+	switch name {
+	case "", "always":
+		return ExportAlways, nil
+	case "encrypted-only":
+		return ExportEncryptedOnly, nil
+	case "never":
+		return ExportNever, nil
+	default:
+		return ExportAlways, fmt.Errorf("invalid key export policy %q", name)
+	}
+}
+
+var exportPolicy atomic.Int32
+
+// SetExportPolicy sets private key export policy enforced by all PEM and PKCS #12
+// export functions of the package.
+//
+// Policy can only be made more strict, attempts to relax already set policy are ignored.
+func SetExportPolicy(p ExportPolicy) {
+	for {
+		cur := exportPolicy.Load()
+		if int32(p) <= cur || exportPolicy.CompareAndSwap(cur, int32(p)) {
+			return
+		}
+	}
+}
+
+// CurrentExportPolicy returns private key export policy.
+func CurrentExportPolicy() ExportPolicy {
+	return ExportPolicy(exportPolicy.Load())
+}
+
+// checkExport returns error if private key export is not allowed by the policy.
+func checkExport(encrypted bool) error {
+	switch CurrentExportPolicy() {
+	case ExportNever:
+		return ErrKeyExportDenied
+	case ExportEncryptedOnly:
+		if !encrypted {
+			return fmt.Errorf("%w: password is required", ErrKeyExportDenied)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportPolicy(t *testing.T) {
+	defer exportPolicy.Store(int32(ExportAlways))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	c := testCertificate(t, "export", key, nil, nil)
+	crt := &tls.Certificate{Certificate: [][]byte{c.Raw}, PrivateKey: key}
+
+	p12, err := EncodePKCS12(crt, "")
+	require.NoError(t, err)
+	decoded, err := DecodePKCS12(p12, "")
+	require.NoError(t, err)
+	assert.Equal(t, "export", decoded.Leaf.Subject.CommonName)
+
+	SetExportPolicy(ExportEncryptedOnly)
+	assert.Equal(t, ExportEncryptedOnly, CurrentExportPolicy())
+
+	_, _, err = DERBytesToPEMBlocks(c.Raw, key)
+	assert.ErrorIs(t, err, ErrKeyExportDenied)
+	_, err = EncodePKCS12(crt, "")
+	assert.ErrorIs(t, err, ErrKeyExportDenied)
+
+	certPEM, _, err := DERBytesToPEMBlocks(c.Raw, nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, certPEM)
+
+	p12, err = EncodePKCS12(crt, "secret")
+	require.NoError(t, err)
+	decoded, err = DecodePKCS12(p12, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "export", decoded.Leaf.Subject.CommonName)
+
+	// Policy can not be relaxed.
+	SetExportPolicy(ExportAlways)
+	assert.Equal(t, ExportEncryptedOnly, CurrentExportPolicy())
+
+	SetExportPolicy(ExportNever)
+	_, err = EncodePKCS12(crt, "secret")
+	assert.ErrorIs(t, err, ErrKeyExportDenied)
+}
+
+func TestParseExportPolicy(t *testing.T) {
+	for _, p := range []ExportPolicy{ExportAlways, ExportEncryptedOnly, ExportNever} {
+		parsed, err := ParseExportPolicy(p.String())
+		require.NoError(t, err)
+		assert.Equal(t, p, parsed)
+	}
+	_, err := ParseExportPolicy("sometimes")
+	assert.Error(t, err)
+}
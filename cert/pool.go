@@ -0,0 +1,39 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"crypto/x509"
+	"errors"
+	"os"
+)
+
+// NewCertPool returns system certificate pool with additional PEM encoded CA certificates.
+func NewCertPool(pemCerts ...[]byte) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, c := range pemCerts {
+		if !pool.AppendCertsFromPEM(c) {
+			return nil, errors.New("no valid certificates found in CA bundle")
+		}
+	}
+	return pool, nil
+}
+
+// LoadCertPoolFromFile returns system certificate pool with additional CA certificates
+// loaded from PEM encoded bundle files.
+func LoadCertPoolFromFile(paths ...string) (*x509.CertPool, error) {
+	pemCerts := make([][]byte, 0, len(paths))
+	for _, p := range paths {
+		buf, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		pemCerts = append(pemCerts, buf)
+	}
+	return NewCertPool(pemCerts...)
+}
@@ -0,0 +1,321 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Issuer issues new client certificates, for example using Vault PKI, ACME or
+// internal certificate authority.
+type Issuer interface {
+	// Issue new certificate with the private key.
+	Issue(ctx context.Context) (*tls.Certificate, error)
+}
+
+// IssuerFunc is a function that issues new client certificate.
+type IssuerFunc func(ctx context.Context) (*tls.Certificate, error)
+
+func (f IssuerFunc) Issue(ctx context.Context) (*tls.Certificate, error) {
+	return f(ctx)
+}
+
+// CAIssuer issues client certificates signed by the internal certificate authority.
+type CAIssuer struct {
+	// CA certificate chain starting with the issuing certificate.
+	CA []*x509.Certificate
+	// Key of the issuing CA certificate.
+	Key crypto.Signer
+	// Subject of the issued certificates.
+	Subject pkix.Name
+	// DNSNames of the issued certificates.
+	DNSNames []string
+	// Validity of the issued certificates. Defaults to 24 hours.
+	Validity time.Duration
+}
+
+func (i CAIssuer) Issue(_ context.Context) (*tls.Certificate, error) {
+	if len(i.CA) == 0 || i.Key == nil {
+		return nil, errors.New("issuing CA certificate and key are required")
+	}
+	validity := i.Validity
+	if validity <= 0 {
+		validity = 24 * time.Hour
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      i.Subject,
+		DNSNames:     i.DNSNames,
+		// Allow small clock skew between services.
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, i.CA[0], &priv.PublicKey, i.Key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	crt := &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  priv,
+		Leaf:        leaf,
+	}
+	for _, c := range i.CA {
+		crt.Certificate = append(crt.Certificate, c.Raw)
+	}
+	return crt, nil
+}
+
+type renewOptions struct {
+	RenewBefore   float64
+	CheckInterval time.Duration
+	OnRenew       func(crt *tls.Certificate, err error)
+}
+
+// RenewOption for the client certificate manager.
+type RenewOption interface {
+	applyRenew(*renewOptions)
+}
+
+// RenewBefore is a fraction of the certificate lifetime before expiry at which
+// certificate is renewed. Defaults to 1/3 of the lifetime.
+type RenewBefore float64
+
+func (r RenewBefore) applyRenew(o *renewOptions) {
+	o.RenewBefore = float64(r)
+}
+
+// RenewCheckInterval is an interval at which certificate expiry is checked.
+// Defaults to 1 minute.
+type RenewCheckInterval time.Duration
+
+func (i RenewCheckInterval) applyRenew(o *renewOptions) {
+	o.CheckInterval = time.Duration(i)
+}
+
+// OnRenew is called after every renewal attempt with the new certificate or error.
+type OnRenew func(crt *tls.Certificate, err error)
+
+func (f OnRenew) applyRenew(o *renewOptions) {
+	o.OnRenew = f
+}
+
+// ClientCertManager keeps outbound mTLS client certificate renewed.
+//
+// Certificate is renewed using the issuer when its remaining lifetime drops below
+// the configured fraction. Renewed certificate is used for all new TLS handshakes
+// while existing connections are kept open. Previous certificate is kept if the
+// renewal fails.
+type ClientCertManager struct {
+	issuer Issuer
+	opts   *renewOptions
+	now    func() time.Time
+
+	lock sync.RWMutex
+	cert *tls.Certificate
+
+	rlock  sync.Mutex
+	tlock  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewClientCertManager creates new client certificate manager.
+func NewClientCertManager(issuer Issuer, opts ...RenewOption) *ClientCertManager {
+	opt := &renewOptions{
+		RenewBefore:   1.0 / 3,
+		CheckInterval: time.Minute,
+	}
+	for _, o := range opts {
+		o.applyRenew(opt)
+	}
+	if opt.RenewBefore <= 0 || opt.RenewBefore >= 1 {
+		opt.RenewBefore = 1.0 / 3
+	}
+	return &ClientCertManager{
+		issuer: issuer,
+		opts:   opt,
+		now:    time.Now,
+	}
+}
+
+// Certificate returns current client certificate or nil if not yet issued.
+func (m *ClientCertManager) Certificate() *tls.Certificate {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.cert
+}
+
+// GetClientCertificate returns current client certificate.
+//
+// It can be used as tls.Config GetClientCertificate function.
+func (m *ClientCertManager) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if c := m.Certificate(); c != nil {
+		return c, nil
+	}
+	// Continue handshake without client certificate.
+	return &tls.Certificate{}, nil
+}
+
+// TLSConfig returns copy of the TLS configuration that uses managed client certificate.
+func (m *ClientCertManager) TLSConfig(conf *tls.Config) *tls.Config {
+	if conf == nil {
+		conf = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	} else {
+		conf = conf.Clone()
+	}
+	conf.Certificates = nil
+	conf.GetClientCertificate = m.GetClientCertificate
+	return conf
+}
+
+// renewAt returns time when certificate should be renewed.
+func (m *ClientCertManager) renewAt(c *tls.Certificate) time.Time {
+	lifetime := c.Leaf.NotAfter.Sub(c.Leaf.NotBefore)
+	return c.Leaf.NotAfter.Add(-time.Duration(float64(lifetime) * m.opts.RenewBefore))
+}
+
+// NeedsRenewal returns true if certificate is not issued or should be renewed.
+func (m *ClientCertManager) NeedsRenewal() bool {
+	c := m.Certificate()
+	return c == nil || !m.now().Before(m.renewAt(c))
+}
+
+// Renew issues new client certificate and replaces the current one.
+func (m *ClientCertManager) Renew(ctx context.Context) error {
+	m.rlock.Lock()
+	defer m.rlock.Unlock()
+
+	crt, err := m.issue(ctx)
+	if m.opts.OnRenew != nil {
+		m.opts.OnRenew(crt, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	m.cert = crt
+	m.lock.Unlock()
+	return nil
+}
+
+func (m *ClientCertManager) issue(ctx context.Context) (*tls.Certificate, error) {
+	crt, err := m.issuer.Issue(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to issue client certificate: %w", err)
+	}
+	if crt == nil || len(crt.Certificate) == 0 {
+		return nil, errors.New("issuer returned empty client certificate")
+	}
+	chain := make([]*x509.Certificate, 0, len(crt.Certificate))
+	for _, der := range crt.Certificate {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, c)
+	}
+	if err := KeyMatchesCertificate(crt.PrivateKey, chain[0]); err != nil {
+		return nil, err
+	}
+	if err := ValidateChainOrder(chain); err != nil {
+		return nil, err
+	}
+	c := *crt
+	c.Leaf = chain[0]
+	return &c, nil
+}
+
+// Check renews certificate if it is not issued or should be renewed.
+func (m *ClientCertManager) Check(ctx context.Context) error {
+	if !m.NeedsRenewal() {
+		return nil
+	}
+	return m.Renew(ctx)
+}
+
+// Name returns task name.
+func (m *ClientCertManager) Name() string {
+	return "client-certificate-manager"
+}
+
+// Start issues client certificate if not yet issued and renews it in the background.
+func (m *ClientCertManager) Start(ctx context.Context) error {
+	m.tlock.Lock()
+	defer m.tlock.Unlock()
+
+	if m.cancel != nil {
+		return nil
+	}
+	if err := m.Check(ctx); err != nil {
+		return err
+	}
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+
+		t := time.NewTicker(m.opts.CheckInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				_ = m.Check(ctx)
+			}
+		}
+	}(m.done)
+	return nil
+}
+
+// Stop renewing client certificate.
+func (m *ClientCertManager) Stop() {
+	m.tlock.Lock()
+	if m.cancel == nil {
+		m.tlock.Unlock()
+		return
+	}
+	m.cancel()
+	m.cancel = nil
+	done := m.done
+	m.tlock.Unlock()
+
+	<-done
+}
@@ -0,0 +1,103 @@
+package cert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCertManagerRenew(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ca := testCertificate(t, "ca", caKey, nil, nil)
+
+	calls := 0
+	var fail error
+	issuer := CAIssuer{CA: []*x509.Certificate{ca}, Key: caKey, Subject: pkix.Name{CommonName: "client"}, Validity: time.Hour}
+	m := NewClientCertManager(IssuerFunc(func(ctx context.Context) (*tls.Certificate, error) {
+		calls++
+		if fail != nil {
+			return nil, fail
+		}
+		return issuer.Issue(ctx)
+	}))
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	require.NoError(t, m.Start(context.TODO()))
+	defer m.Stop()
+	first := m.Certificate()
+	require.NotNil(t, first)
+	assert.Equal(t, "client", first.Leaf.Subject.CommonName)
+	assert.Equal(t, 1, calls)
+
+	require.NoError(t, m.Check(context.TODO()))
+	assert.Equal(t, 1, calls)
+
+	// Failed renewal keeps current certificate.
+	now = now.Add(45 * time.Minute)
+	fail = errors.New("issuer unavailable")
+	assert.ErrorIs(t, m.Check(context.TODO()), fail)
+	assert.Same(t, first, m.Certificate())
+
+	fail = nil
+	require.NoError(t, m.Check(context.TODO()))
+	assert.NotSame(t, first, m.Certificate())
+	assert.Equal(t, 3, calls)
+}
+
+func TestClientCertManagerTLS(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ca := testCertificate(t, "ca", caKey, nil, nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	subjects := make(chan string, 2)
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subjects <- r.TLS.PeerCertificates[0].Subject.CommonName
+	}))
+	s.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	s.StartTLS()
+	defer s.Close()
+
+	name := "v1"
+	m := NewClientCertManager(IssuerFunc(func(ctx context.Context) (*tls.Certificate, error) {
+		return CAIssuer{CA: []*x509.Certificate{ca}, Key: caKey, Subject: pkix.Name{CommonName: name}}.Issue(ctx)
+	}))
+	require.NoError(t, m.Renew(context.TODO()))
+
+	tr := s.Client().Transport.(*http.Transport).Clone()
+	tr.TLSClientConfig = m.TLSConfig(tr.TLSClientConfig)
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(s.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "v1", <-subjects)
+
+	name = "v2"
+	require.NoError(t, m.Renew(context.TODO()))
+	tr.CloseIdleConnections()
+
+	resp, err = client.Get(s.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "v2", <-subjects)
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	// Register hash implementations used for signatures.
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// ErrInvalidSignature is returned when signature verification fails.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+var signatureHashes = []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512}
+
+// defaultHash returns hash algorithm matching the key strength.
+func defaultHash(pub crypto.PublicKey) crypto.Hash {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P384():
+			return crypto.SHA384
+		case elliptic.P521():
+			return crypto.SHA512
+		}
+	case ed25519.PublicKey:
+		return 0
+	}
+	return crypto.SHA256
+}
+
+func digest(data []byte, hash crypto.Hash) ([]byte, error) {
+	if !hash.Available() {
+		return nil, fmt.Errorf("unsupported hash algorithm %s", hash)
+	}
+	h := hash.New()
+	_, _ = h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// Sign creates detached signature of the data using private key.
+//
+// RSA keys are signed using PKCS #1 v1.5 and ECDSA keys using ASN.1 encoded
+// signatures. If hash is zero it is selected based on key type and size
+// (SHA-256 for RSA and P-256, SHA-384 for P-384 and SHA-512 for P-521).
+// Ed25519 keys sign data directly and hash is ignored.
+func Sign(data []byte, key crypto.Signer, hash crypto.Hash) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("private key is empty")
+	}
+
+	pub := key.Public()
+	switch pub.(type) {
+	case ed25519.PublicKey:
+		return key.Sign(rand.Reader, data, crypto.Hash(0))
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	if hash == 0 {
+		hash = defaultHash(pub)
+	}
+	d, err := digest(data, hash)
+	if err != nil {
+		return nil, err
+	}
+	return key.Sign(rand.Reader, d, hash)
+}
+
+// Verify checks detached signature of the data created by Sign.
+//
+// Public key can be provided as public key, private key or *x509.Certificate.
+// Hash algorithm is negotiated from the key type, trying the default hash for
+// the key first and then other supported SHA-2 hashes.
+// Returned error wraps ErrInvalidSignature if signature does not match.
+func Verify(data, sig []byte, pub any) error {
+	switch k := pub.(type) {
+	case *x509.Certificate:
+		pub = k.PublicKey
+	case crypto.Signer:
+		pub = k.Public()
+	case *ed25519.PrivateKey:
+		pub = k.Public()
+	}
+
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, sig) {
+			return fmt.Errorf("%w: Ed25519 signature does not match", ErrInvalidSignature)
+		}
+		return nil
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+
+	def := defaultHash(pub)
+	hashes := append([]crypto.Hash{def}, signatureHashes...)
+	for i, hash := range hashes {
+		if i > 0 && hash == def {
+			continue
+		}
+		d, err := digest(data, hash)
+		if err != nil {
+			return err
+		}
+		switch k := pub.(type) {
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, hash, d, sig) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, d, sig) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: %s signature does not match", ErrInvalidSignature, keyTypeName(pub))
+}
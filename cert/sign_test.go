@@ -0,0 +1,65 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	data := []byte(`{"event":"created"}`)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		hash crypto.Hash
+	}{
+		{"RSA", rsaKey, 0},
+		{"RSA-SHA512", rsaKey, crypto.SHA512},
+		{"ECDSA", ecKey, 0},
+		{"ECDSA-SHA256", ecKey, crypto.SHA256},
+		{"Ed25519", edKey, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sig, err := Sign(data, tt.key, tt.hash)
+			require.NoError(t, err)
+
+			assert.NoError(t, Verify(data, sig, tt.key.Public()))
+			assert.NoError(t, Verify(data, sig, tt.key))
+
+			err = Verify([]byte("tampered"), sig, tt.key.Public())
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	c := testCertificate(t, "signer", key, nil, nil)
+
+	sig, err := Sign([]byte("manifest"), key, 0)
+	require.NoError(t, err)
+	assert.NoError(t, Verify([]byte("manifest"), sig, c))
+
+	sig, err = Sign([]byte("manifest"), other, 0)
+	require.NoError(t, err)
+	assert.ErrorIs(t, Verify([]byte("manifest"), sig, c), ErrInvalidSignature)
+}
@@ -0,0 +1,427 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoCertificate is returned when there is no certificate for the requested server name.
+var ErrNoCertificate = errors.New("no certificate for server name")
+
+// keyPair returns TLS certificate from PEM encoded certificate chain and private key
+// after validating that key matches the certificate and chain is ordered.
+func keyPair(certPEM, keyPEM []byte, opt ...Option) (*tls.Certificate, error) {
+	chain, err := ParseCertificates(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePrivateKey(keyPEM, opt...)
+	if err != nil {
+		return nil, err
+	}
+	if err := KeyMatchesCertificate(key, chain[0]); err != nil {
+		return nil, err
+	}
+	if err := ValidateChainOrder(chain); err != nil {
+		return nil, err
+	}
+	crt := &tls.Certificate{
+		PrivateKey: key,
+		Leaf:       chain[0],
+	}
+	for _, c := range chain {
+		crt.Certificate = append(crt.Certificate, c.Raw)
+	}
+	return crt, nil
+}
+
+type sniFile struct {
+	certPath string
+	keyPath  string
+	opts     []Option
+	modTime  time.Time
+}
+
+func fileModTime(paths ...string) (time.Time, error) {
+	var t time.Time
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return t, err
+		}
+		if fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+	return t, nil
+}
+
+func (f *sniFile) paths() []string {
+	if len(f.keyPath) != 0 && f.keyPath != f.certPath {
+		return []string{f.certPath, f.keyPath}
+	}
+	return []string{f.certPath}
+}
+
+func (f *sniFile) load() (*tls.Certificate, time.Time, error) {
+	paths := f.paths()
+	mod, err := fileModTime(paths...)
+	if err != nil {
+		return nil, mod, err
+	}
+
+	certPEM, err := os.ReadFile(f.certPath)
+	if err != nil {
+		return nil, mod, err
+	}
+	keyPEM := certPEM
+	if len(paths) > 1 {
+		if keyPEM, err = os.ReadFile(f.keyPath); err != nil {
+			return nil, mod, err
+		}
+	}
+	crt, err := keyPair(certPEM, keyPEM, f.opts...)
+	return crt, mod, err
+}
+
+type sniEntry struct {
+	name  string
+	hosts []string
+	file  *sniFile
+
+	lock sync.RWMutex
+	cert *tls.Certificate
+}
+
+func (e *sniEntry) certificate() *tls.Certificate {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.cert
+}
+
+// reload certificate from files if they have been modified.
+//
+// Previous certificate is kept if the new one fails to load.
+func (e *sniEntry) reload() error {
+	if e.file == nil {
+		return nil
+	}
+	mod, err := fileModTime(e.file.paths()...)
+	if err != nil {
+		return err
+	}
+	e.lock.RLock()
+	changed := mod.After(e.file.modTime)
+	e.lock.RUnlock()
+	if !changed {
+		return nil
+	}
+	crt, mod, err := e.file.load()
+	if err != nil {
+		return err
+	}
+
+	e.lock.Lock()
+	e.cert = crt
+	e.file.modTime = mod
+	e.lock.Unlock()
+	return nil
+}
+
+// SNIProvider selects TLS certificate based on the server name requested by the client.
+//
+// Host names are matched exactly or by wildcard pattern "*.example.com" that matches
+// single label subdomains. Host name "*" sets the default certificate used when no
+// other certificate matches. Certificates loaded from files are reloaded when files
+// are modified, either by calling Reload or periodically when provider is started
+// as a task.
+type SNIProvider struct {
+	interval time.Duration
+
+	lock     sync.RWMutex
+	entries  map[string]*sniEntry
+	hosts    map[string]*sniEntry
+	fallback *sniEntry
+
+	tlock  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewSNIProvider creates new SNI based certificate provider.
+//
+// Files are checked for modifications at reload interval when provider is started.
+// If interval is zero it defaults to 1 minute.
+func NewSNIProvider(interval time.Duration) *SNIProvider {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &SNIProvider{
+		interval: interval,
+		entries:  make(map[string]*sniEntry),
+		hosts:    make(map[string]*sniEntry),
+	}
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+func (p *SNIProvider) set(e *sniEntry) error {
+	if len(e.hosts) == 0 {
+		return errors.New("at least one host name is required")
+	}
+	for i, h := range e.hosts {
+		e.hosts[i] = normalizeHost(h)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if old, ok := p.entries[e.name]; ok {
+		p.remove(old)
+	}
+	p.entries[e.name] = e
+	for _, h := range e.hosts {
+		if h == "*" {
+			p.fallback = e
+			continue
+		}
+		p.hosts[h] = e
+	}
+	return nil
+}
+
+func (p *SNIProvider) remove(e *sniEntry) {
+	for _, h := range e.hosts {
+		if h == "*" && p.fallback == e {
+			p.fallback = nil
+			continue
+		}
+		if p.hosts[h] == e {
+			delete(p.hosts, h)
+		}
+	}
+	delete(p.entries, e.name)
+}
+
+// Set certificate entry for the host names.
+//
+// Entry with the same name is replaced. Certificate chain and private key are
+// validated before the entry is installed.
+func (p *SNIProvider) Set(name string, hosts []string, crt *tls.Certificate) error {
+	if crt == nil || len(crt.Certificate) == 0 {
+		return errors.New("certificate is empty")
+	}
+	chain := make([]*x509.Certificate, 0, len(crt.Certificate))
+	for _, der := range crt.Certificate {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("certificate %q: %w", name, err)
+		}
+		chain = append(chain, c)
+	}
+	if err := KeyMatchesCertificate(crt.PrivateKey, chain[0]); err != nil {
+		return fmt.Errorf("certificate %q: %w", name, err)
+	}
+	if err := ValidateChainOrder(chain); err != nil {
+		return fmt.Errorf("certificate %q: %w", name, err)
+	}
+	c := *crt
+	c.Leaf = chain[0]
+	return p.set(&sniEntry{
+		name:  name,
+		hosts: append([]string{}, hosts...),
+		cert:  &c,
+	})
+}
+
+// SetPEM sets certificate entry for the host names from PEM encoded certificate
+// chain and private key.
+func (p *SNIProvider) SetPEM(name string, hosts []string, certPEM, keyPEM []byte, opt ...Option) error {
+	crt, err := keyPair(certPEM, keyPEM, opt...)
+	if err != nil {
+		return fmt.Errorf("certificate %q: %w", name, err)
+	}
+	return p.set(&sniEntry{
+		name:  name,
+		hosts: append([]string{}, hosts...),
+		cert:  crt,
+	})
+}
+
+// SetFile sets certificate entry for the host names loaded from PEM encoded files.
+//
+// If key path is empty private key is loaded from the certificate file.
+func (p *SNIProvider) SetFile(name string, hosts []string, certPath, keyPath string, opt ...Option) error {
+	f := &sniFile{
+		certPath: certPath,
+		keyPath:  keyPath,
+		opts:     opt,
+	}
+	crt, mod, err := f.load()
+	if err != nil {
+		return fmt.Errorf("certificate %q: %w", name, err)
+	}
+	f.modTime = mod
+	return p.set(&sniEntry{
+		name:  name,
+		hosts: append([]string{}, hosts...),
+		file:  f,
+		cert:  crt,
+	})
+}
+
+// Remove certificate entry.
+func (p *SNIProvider) Remove(name string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if e, ok := p.entries[name]; ok {
+		p.remove(e)
+	}
+}
+
+func (p *SNIProvider) lookup(host string) *sniEntry {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	host = normalizeHost(host)
+	if e, ok := p.hosts[host]; ok {
+		return e
+	}
+	if i := strings.IndexByte(host, '.'); i > 0 {
+		if e, ok := p.hosts["*"+host[i:]]; ok {
+			return e
+		}
+	}
+	return p.fallback
+}
+
+// GetCertificate returns certificate for the server name requested by the client.
+//
+// It can be used as tls.Config GetCertificate function.
+func (p *SNIProvider) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	e := p.lookup(hello.ServerName)
+	if e == nil {
+		return nil, fmt.Errorf("%w %q", ErrNoCertificate, hello.ServerName)
+	}
+	return e.certificate(), nil
+}
+
+// Chains returns certificate chains by entry name starting with the leaf certificate.
+//
+// Certificates that can not be parsed are omitted from the chain.
+func (p *SNIProvider) Chains() map[string][]*x509.Certificate {
+	p.lock.RLock()
+	entries := make([]*sniEntry, 0, len(p.entries))
+	for _, e := range p.entries {
+		entries = append(entries, e)
+	}
+	p.lock.RUnlock()
+
+	chains := make(map[string][]*x509.Certificate, len(entries))
+	for _, e := range entries {
+		crt := e.certificate()
+		if crt == nil {
+			continue
+		}
+		chain := make([]*x509.Certificate, 0, len(crt.Certificate))
+		for _, der := range crt.Certificate {
+			if c, err := x509.ParseCertificate(der); err == nil {
+				chain = append(chain, c)
+			}
+		}
+		chains[e.name] = chain
+	}
+	return chains
+}
+
+// TLSConfig returns server TLS configuration that uses provider certificates.
+func (p *SNIProvider) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: p.GetCertificate,
+	}
+}
+
+// Reload certificates of file entries that have been modified.
+//
+// Entries that fail to reload keep serving previous certificate. Returns first
+// error that occurred.
+func (p *SNIProvider) Reload() error {
+	p.lock.RLock()
+	entries := make([]*sniEntry, 0, len(p.entries))
+	for _, e := range p.entries {
+		entries = append(entries, e)
+	}
+	p.lock.RUnlock()
+
+	var err error
+	for _, e := range entries {
+		if rerr := e.reload(); rerr != nil && err == nil {
+			err = fmt.Errorf("certificate %q: %w", e.name, rerr)
+		}
+	}
+	return err
+}
+
+// Name returns task name.
+func (p *SNIProvider) Name() string {
+	return "sni-certificate-provider"
+}
+
+// Start reloading modified certificate files in the background.
+func (p *SNIProvider) Start(ctx context.Context) error {
+	p.tlock.Lock()
+	defer p.tlock.Unlock()
+
+	if p.cancel != nil {
+		return nil
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+
+		t := time.NewTicker(p.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				_ = p.Reload()
+			}
+		}
+	}(p.done)
+	return nil
+}
+
+// Stop reloading certificate files.
+func (p *SNIProvider) Stop() {
+	p.tlock.Lock()
+	if p.cancel == nil {
+		p.tlock.Unlock()
+		return
+	}
+	p.cancel()
+	p.cancel = nil
+	done := p.done
+	p.tlock.Unlock()
+
+	<-done
+}
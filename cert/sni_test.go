@@ -0,0 +1,102 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPEM(t *testing.T, name string) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	c := testCertificate(t, name, key, nil, nil)
+	certPEM, keyPEM, err := DERBytesToPEMBlocks(c.Raw, key)
+	require.NoError(t, err)
+	return certPEM, keyPEM
+}
+
+func commonName(t *testing.T, p *SNIProvider, host string) string {
+	t.Helper()
+
+	c, err := p.GetCertificate(&tls.ClientHelloInfo{ServerName: host})
+	if err != nil {
+		return ""
+	}
+	return c.Leaf.Subject.CommonName
+}
+
+func TestSNIProviderMatch(t *testing.T) {
+	p := NewSNIProvider(0)
+
+	certPEM, keyPEM := testPEM(t, "api")
+	require.NoError(t, p.SetPEM("api", []string{"api.example.com"}, certPEM, keyPEM))
+	certPEM, keyPEM = testPEM(t, "wildcard")
+	require.NoError(t, p.SetPEM("wildcard", []string{"*.example.com"}, certPEM, keyPEM))
+
+	assert.Equal(t, "api", commonName(t, p, "API.example.com."))
+	assert.Equal(t, "wildcard", commonName(t, p, "www.example.com"))
+	assert.Equal(t, "", commonName(t, p, "a.b.example.com"))
+
+	_, err := p.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.org"})
+	assert.ErrorIs(t, err, ErrNoCertificate)
+
+	certPEM, keyPEM = testPEM(t, "default")
+	require.NoError(t, p.SetPEM("default", []string{"*"}, certPEM, keyPEM))
+	assert.Equal(t, "default", commonName(t, p, "other.org"))
+
+	p.Remove("api")
+	assert.Equal(t, "wildcard", commonName(t, p, "api.example.com"))
+
+	_, otherKey := testPEM(t, "other")
+	err = p.SetPEM("invalid", []string{"invalid.example.com"}, certPEM, otherKey)
+	assert.ErrorIs(t, err, ErrKeyMismatch)
+}
+
+func TestSNIProviderChains(t *testing.T) {
+	p := NewSNIProvider(0)
+
+	certPEM, keyPEM := testPEM(t, "api")
+	require.NoError(t, p.SetPEM("api", []string{"api.example.com"}, certPEM, keyPEM))
+
+	chains := p.Chains()
+	require.Len(t, chains["api"], 1)
+	assert.Equal(t, "api", chains["api"][0].Subject.CommonName)
+}
+
+func TestSNIProviderReload(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+
+	certPEM, keyPEM := testPEM(t, "v1")
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyPath, keyPEM, 0o600))
+
+	p := NewSNIProvider(0)
+	require.NoError(t, p.SetFile("site", []string{"example.com"}, certPath, keyPath))
+	assert.Equal(t, "v1", commonName(t, p, "example.com"))
+
+	// Mismatched files keep previous certificate.
+	certPEM, keyPEM = testPEM(t, "v2")
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0o600))
+	mod := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(certPath, mod, mod))
+	assert.ErrorIs(t, p.Reload(), ErrKeyMismatch)
+	assert.Equal(t, "v1", commonName(t, p, "example.com"))
+
+	require.NoError(t, os.WriteFile(keyPath, keyPEM, 0o600))
+	mod = mod.Add(time.Second)
+	require.NoError(t, os.Chtimes(keyPath, mod, mod))
+	require.NoError(t, p.Reload())
+	assert.Equal(t, "v2", commonName(t, p, "example.com"))
+}
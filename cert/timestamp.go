@@ -0,0 +1,201 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/digitorus/timestamp"
+)
+
+const (
+	contentTypeTimestampQuery = "application/timestamp-query"
+	contentTypeTimestampReply = "application/timestamp-reply"
+
+	maxTimestampResponseSize = 1 << 20
+)
+
+// ErrInvalidTimestamp is returned when timestamp token does not match the data or
+// can not be trusted.
+var ErrInvalidTimestamp = errors.New("invalid timestamp")
+
+// Timestamp is a RFC 3161 timestamp token.
+type Timestamp struct {
+	// Token is a DER encoded timestamp token that should be stored alongside
+	// the timestamped data.
+	Token []byte
+	// Time when the data was timestamped.
+	Time time.Time
+	// Accuracy of the time.
+	Accuracy time.Duration
+	// SerialNumber of the token assigned by the TSA.
+	SerialNumber *big.Int
+	// Policy under which the token was issued.
+	Policy asn1.ObjectIdentifier
+	// Hash algorithm used for the data imprint.
+	Hash crypto.Hash
+	// Certificates embedded in the token.
+	Certificates []*x509.Certificate
+}
+
+// TimestampClient requests RFC 3161 timestamps from the timestamping authority (TSA).
+//
+// To extend validity of a signature timestamp signature bytes, for example
+// created with SignCMS, and store the token together with the signature.
+type TimestampClient struct {
+	// URL of the TSA.
+	URL string
+	// Client is a HTTP client to use. Defaults to client with 30 second timeout.
+	Client *http.Client
+	// Hash algorithm to use for the data imprint. Defaults to SHA-256.
+	Hash crypto.Hash
+	// Policy to request from the TSA. Defaults to TSA default policy.
+	Policy asn1.ObjectIdentifier
+	// Roots to verify the TSA certificate against. If nil the TSA certificate
+	// chain is not verified.
+	Roots *x509.CertPool
+}
+
+// NewTimestampClient creates new RFC 3161 timestamping client.
+func NewTimestampClient(url string) *TimestampClient {
+	return &TimestampClient{
+		URL: url,
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		Hash: crypto.SHA256,
+	}
+}
+
+func (c *TimestampClient) hash() crypto.Hash {
+	if c.Hash == 0 {
+		return crypto.SHA256
+	}
+	return c.Hash
+}
+
+func (c *TimestampClient) client() *http.Client {
+	if c.Client == nil {
+		return http.DefaultClient
+	}
+	return c.Client
+}
+
+// Timestamp requests timestamp token for the data.
+//
+// Returned token is verified to match the data and the request nonce.
+func (c *TimestampClient) Timestamp(ctx context.Context, data []byte) (*Timestamp, error) {
+	hash := c.hash()
+	imprint, err := digest(data, hash)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	tsq, err := (&timestamp.Request{
+		HashAlgorithm: hash,
+		HashedMessage: imprint,
+		Certificates:  true,
+		TSAPolicyOID:  c.Policy,
+		Nonce:         nonce,
+	}).Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create timestamp request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(tsq))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentTypeTimestampQuery)
+	req.Header.Set("Accept", contentTypeTimestampReply)
+
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("timestamp authority returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTimestampResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := timestamp.ParseResponse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp response: %w", err)
+	}
+	if ts.Nonce == nil || ts.Nonce.Cmp(nonce) != 0 {
+		return nil, fmt.Errorf("%w: nonce does not match request", ErrInvalidTimestamp)
+	}
+	return verifyTimestamp(ts, data, c.Roots)
+}
+
+// VerifyTimestamp verifies DER encoded timestamp token for the data.
+//
+// If roots is not nil TSA certificate embedded in the token must chain to one of
+// the roots and must be valid for timestamping at the time of the token.
+// Returned error wraps ErrInvalidTimestamp if token does not match the data.
+func VerifyTimestamp(token, data []byte, roots *x509.CertPool) (*Timestamp, error) {
+	ts, err := timestamp.Parse(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTimestamp, err)
+	}
+	return verifyTimestamp(ts, data, roots)
+}
+
+func verifyTimestamp(ts *timestamp.Timestamp, data []byte, roots *x509.CertPool) (*Timestamp, error) {
+	imprint, err := digest(data, ts.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(imprint, ts.HashedMessage) {
+		return nil, fmt.Errorf("%w: message imprint does not match data", ErrInvalidTimestamp)
+	}
+
+	if roots != nil {
+		if len(ts.Certificates) == 0 {
+			return nil, fmt.Errorf("%w: token does not contain TSA certificate", ErrInvalidTimestamp)
+		}
+		intermediates := x509.NewCertPool()
+		for _, c := range ts.Certificates[1:] {
+			intermediates.AddCert(c)
+		}
+		if _, err := ts.Certificates[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   ts.Time,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		}); err != nil {
+			return nil, fmt.Errorf("%w: untrusted TSA certificate: %v", ErrInvalidTimestamp, err)
+		}
+	}
+
+	return &Timestamp{
+		Token:        ts.RawToken,
+		Time:         ts.Time,
+		Accuracy:     ts.Accuracy,
+		SerialNumber: ts.SerialNumber,
+		Policy:       ts.Policy,
+		Hash:         ts.HashAlgorithm,
+		Certificates: ts.Certificates,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package cert
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/digitorus/timestamp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTSA(t *testing.T) (*httptest.Server, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tsa"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		BasicConstraintsValid: true,
+	}, &x509.Certificate{Subject: pkix.Name{CommonName: "tsa"}}, &key.PublicKey, key)
+	require.NoError(t, err)
+	tsaCert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/timestamp-query", r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		req, err := timestamp.ParseRequest(body)
+		require.NoError(t, err)
+
+		resp, err := (&timestamp.Timestamp{
+			HashAlgorithm:     req.HashAlgorithm,
+			HashedMessage:     req.HashedMessage,
+			Time:              time.Now(),
+			Nonce:             req.Nonce,
+			Policy:            asn1.ObjectIdentifier{1, 2, 3, 4},
+			AddTSACertificate: req.Certificates,
+		}).CreateResponseWithOpts(tsaCert, key, crypto.SHA256)
+		require.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		_, _ = w.Write(resp)
+	}))
+	t.Cleanup(s.Close)
+	return s, tsaCert
+}
+
+func TestTimestampClient(t *testing.T) {
+	s, tsaCert := newTestTSA(t)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(tsaCert)
+
+	c := NewTimestampClient(s.URL)
+	c.Roots = roots
+
+	data := []byte("signature")
+	ts, err := c.Timestamp(context.TODO(), data)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), ts.Time, time.Minute)
+	assert.Equal(t, crypto.SHA256, ts.Hash)
+	assert.NotEmpty(t, ts.Token)
+
+	vts, err := VerifyTimestamp(ts.Token, data, roots)
+	require.NoError(t, err)
+	assert.Equal(t, ts.SerialNumber, vts.SerialNumber)
+
+	_, err = VerifyTimestamp(ts.Token, []byte("other"), roots)
+	assert.ErrorIs(t, err, ErrInvalidTimestamp)
+
+	_, err = VerifyTimestamp(ts.Token, data, x509.NewCertPool())
+	assert.ErrorIs(t, err, ErrInvalidTimestamp)
+}
@@ -0,0 +1,423 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+)
+
+// Format is an encoding format of certificates and keys.
+type Format string
+
+const (
+	// FormatPEM is a PEM encoded certificates and private key.
+	FormatPEM Format = "pem"
+	// FormatDER is a DER encoded certificates without private key.
+	FormatDER Format = "der"
+	// FormatPKCS12 is a PKCS #12 archive.
+	FormatPKCS12 Format = "pkcs12"
+)
+
+// KeyType is a type of generated private key.
+type KeyType string
+
+const (
+	KeyECDSAP256 KeyType = "ecdsa-p256"
+	KeyECDSAP384 KeyType = "ecdsa-p384"
+	KeyRSA2048   KeyType = "rsa-2048"
+	KeyRSA4096   KeyType = "rsa-4096"
+	KeyEd25519   KeyType = "ed25519"
+)
+
+// CertificateInfo is a summary of the certificate.
+type CertificateInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serial_number"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	DNSNames           []string  `json:"dns_names,omitempty"`
+	IPAddresses        []string  `json:"ip_addresses,omitempty"`
+	EmailAddresses     []string  `json:"email_addresses,omitempty"`
+	KeyAlgorithm       string    `json:"key_algorithm"`
+	KeySize            int       `json:"key_size,omitempty"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	IsCA               bool      `json:"is_ca"`
+	ExtKeyUsage        []string  `json:"ext_key_usage,omitempty"`
+	FingerprintSHA256  string    `json:"fingerprint_sha256"`
+}
+
+// Expired returns true if certificate is not valid at the time.
+func (i CertificateInfo) Expired(now time.Time) bool {
+	return now.Before(i.NotBefore) || now.After(i.NotAfter)
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "any",
+	x509.ExtKeyUsageServerAuth:      "server_auth",
+	x509.ExtKeyUsageClientAuth:      "client_auth",
+	x509.ExtKeyUsageCodeSigning:     "code_signing",
+	x509.ExtKeyUsageEmailProtection: "email_protection",
+	x509.ExtKeyUsageTimeStamping:    "time_stamping",
+	x509.ExtKeyUsageOCSPSigning:     "ocsp_signing",
+}
+
+func keySize(pub any) int {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	default:
+		return 0
+	}
+}
+
+// NewCertificateInfo returns summary of the certificate.
+func NewCertificateInfo(c *x509.Certificate) CertificateInfo {
+	fp := sha256.Sum256(c.Raw)
+	info := CertificateInfo{
+		Subject:            c.Subject.String(),
+		Issuer:             c.Issuer.String(),
+		SerialNumber:       strings.ToUpper(c.SerialNumber.Text(16)),
+		NotBefore:          c.NotBefore,
+		NotAfter:           c.NotAfter,
+		DNSNames:           c.DNSNames,
+		EmailAddresses:     c.EmailAddresses,
+		KeyAlgorithm:       c.PublicKeyAlgorithm.String(),
+		KeySize:            keySize(c.PublicKey),
+		SignatureAlgorithm: c.SignatureAlgorithm.String(),
+		IsCA:               c.IsCA,
+		FingerprintSHA256:  strings.ToUpper(hex.EncodeToString(fp[:])),
+	}
+	for _, ip := range c.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	for _, u := range c.ExtKeyUsage {
+		if n, ok := extKeyUsageNames[u]; ok {
+			info.ExtKeyUsage = append(info.ExtKeyUsage, n)
+		}
+	}
+	return info
+}
+
+// Inspection is a result of the certificate inspection.
+type Inspection struct {
+	// Format of the inspected data.
+	Format Format `json:"format"`
+	// Certificates in the order they appear in the data.
+	Certificates []CertificateInfo `json:"certificates"`
+	// HasPrivateKey is true if data contains private key.
+	HasPrivateKey bool `json:"has_private_key"`
+	// KeyError describes why private key does not match the first certificate.
+	KeyError string `json:"key_error,omitempty"`
+	// ChainError describes why certificates are not ordered as a chain.
+	ChainError string `json:"chain_error,omitempty"`
+}
+
+// Valid returns true if private key, if present, matches the certificate and
+// certificates form a chain.
+func (i *Inspection) Valid() bool {
+	return len(i.KeyError) == 0 && len(i.ChainError) == 0
+}
+
+type bundle struct {
+	format Format
+	chain  []*x509.Certificate
+	key    any
+}
+
+// readBundle reads certificates and private key in PEM, DER or PKCS #12 format.
+func readBundle(r io.Reader, opt ...Option) (*bundle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		b := &bundle{format: FormatPEM}
+		if b.chain, err = ParseCertificates(data); err != nil {
+			return nil, err
+		}
+		if bytes.Contains(data, []byte("PRIVATE KEY-----")) {
+			if b.key, err = ParsePrivateKey(data, opt...); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+
+	if certs, err := x509.ParseCertificates(data); err == nil && len(certs) > 0 {
+		return &bundle{format: FormatDER, chain: certs}, nil
+	}
+
+	crt, err := DecodePKCS12(data, string(opts(opt...).Password))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported certificate format: %w", err)
+	}
+	b := &bundle{format: FormatPKCS12, key: crt.PrivateKey}
+	for _, der := range crt.Certificate {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		b.chain = append(b.chain, c)
+	}
+	return b, nil
+}
+
+// Inspect reads certificates and optional private key in PEM, DER or PKCS #12
+// format and returns their summary.
+//
+// Password option is used to decrypt private key or PKCS #12 archive.
+func Inspect(r io.Reader, opt ...Option) (*Inspection, error) {
+	b, err := readBundle(r, opt...)
+	if err != nil {
+		return nil, err
+	}
+	res := &Inspection{
+		Format:        b.format,
+		Certificates:  make([]CertificateInfo, 0, len(b.chain)),
+		HasPrivateKey: b.key != nil,
+	}
+	for _, c := range b.chain {
+		res.Certificates = append(res.Certificates, NewCertificateInfo(c))
+	}
+	if b.key != nil {
+		if err := KeyMatchesCertificate(b.key, b.chain[0]); err != nil {
+			res.KeyError = err.Error()
+		}
+	}
+	if err := ValidateChainOrder(b.chain); err != nil {
+		res.ChainError = err.Error()
+	}
+	return res, nil
+}
+
+func (b *bundle) write(w io.Writer, format Format, password []byte) error {
+	switch format {
+	case FormatPEM:
+		var o []Option
+		if len(password) > 0 {
+			o = append(o, Password(password))
+		}
+		var key []byte
+		for i, c := range b.chain {
+			var priv any
+			if i == 0 {
+				priv = b.key
+			}
+			crt, k, err := DERBytesToPEMBlocks(c.Raw, priv, o...)
+			if err != nil {
+				return err
+			}
+			if k != nil {
+				key = k
+			}
+			if _, err := w.Write(crt); err != nil {
+				return err
+			}
+		}
+		// Private key is written after the certificate chain.
+		if key != nil {
+			if _, err := w.Write(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatDER:
+		for _, c := range b.chain {
+			if _, err := w.Write(c.Raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatPKCS12:
+		crt := &tls.Certificate{PrivateKey: b.key}
+		for _, c := range b.chain {
+			crt.Certificate = append(crt.Certificate, c.Raw)
+		}
+		var data []byte
+		var err error
+		if b.key == nil {
+			data, err = EncodePKCS12TrustStore(b.chain, string(password))
+		} else {
+			data, err = EncodePKCS12(crt, string(password))
+		}
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		return fmt.Errorf("unsupported certificate format %q", format)
+	}
+}
+
+// Convert reads certificates and optional private key in PEM, DER or PKCS #12
+// format and writes them in the requested format.
+//
+// Password option is used to decrypt the input and ExportPassword option to
+// encrypt the private key in the output. DER format contains only certificates.
+// Private key export is subject to the export policy set by SetExportPolicy.
+func Convert(w io.Writer, r io.Reader, format Format, opt ...Option) error {
+	b, err := readBundle(r, opt...)
+	if err != nil {
+		return err
+	}
+	o := opts(opt...)
+	password := o.ExportPassword
+	if password == nil {
+		password = o.Password
+	}
+	return b.write(w, format, password)
+}
+
+// IssueRequest describes certificate to issue.
+type IssueRequest struct {
+	// Subject of the certificate.
+	Subject pkix.Name
+	// DNSNames and IP addresses of the certificate.
+	Hosts []string
+	// Validity of the certificate. Defaults to 1 year.
+	Validity time.Duration
+	// KeyType of the generated private key. Defaults to ECDSA P-256.
+	KeyType KeyType
+	// IsCA issues certificate authority certificate.
+	IsCA bool
+	// ExtKeyUsage of the certificate. Defaults to server authentication for
+	// non CA certificates.
+	ExtKeyUsage []x509.ExtKeyUsage
+	// Issuer certificate and private key. Certificate is self-signed if not set.
+	Issuer *tls.Certificate
+	// Format of the output. Defaults to PEM.
+	Format Format
+	// Password to encrypt the private key in the output.
+	Password []byte
+}
+
+func generateKey(t KeyType) (crypto.Signer, error) {
+	switch t {
+	case "", KeyECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case KeyRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case KeyEd25519:
+		_, k, err := ed25519.GenerateKey(rand.Reader)
+		return k, err
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", t)
+	}
+}
+
+// Issue generates private key and certificate and writes them in the requested format.
+//
+// Returns summary of the issued certificate. Private key export is subject to the
+// export policy set by SetExportPolicy.
+func Issue(w io.Writer, req IssueRequest) (*CertificateInfo, error) {
+	key, err := generateKey(req.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	validity := req.Validity
+	if validity <= 0 {
+		validity = 365 * 24 * time.Hour
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               req.Subject,
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           req.ExtKeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  req.IsCA,
+	}
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	if req.IsCA {
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	} else if len(template.ExtKeyUsage) == 0 {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+	for _, h := range req.Hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	parent, signer := template, any(key)
+	var chain []*x509.Certificate
+	if req.Issuer != nil {
+		if len(req.Issuer.Certificate) == 0 || req.Issuer.PrivateKey == nil {
+			return nil, errors.New("issuer certificate and private key are required")
+		}
+		for _, der := range req.Issuer.Certificate {
+			c, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, c)
+		}
+		if err := KeyMatchesCertificate(req.Issuer.PrivateKey, chain[0]); err != nil {
+			return nil, err
+		}
+		parent, signer = chain[0], req.Issuer.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	format := req.Format
+	if len(format) == 0 {
+		format = FormatPEM
+	}
+	b := &bundle{chain: append([]*x509.Certificate{leaf}, chain...), key: key}
+	if err := b.write(w, format, req.Password); err != nil {
+		return nil, err
+	}
+	info := NewCertificateInfo(leaf)
+	return &info, nil
+}
@@ -0,0 +1,101 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueSelfSigned(t *testing.T) {
+	out := &bytes.Buffer{}
+	info, err := Issue(out, IssueRequest{
+		Subject:  pkix.Name{CommonName: "localhost"},
+		Hosts:    []string{"localhost", "127.0.0.1"},
+		Validity: time.Hour,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "CN=localhost", info.Subject)
+	assert.Equal(t, []string{"localhost"}, info.DNSNames)
+	assert.Equal(t, []string{"127.0.0.1"}, info.IPAddresses)
+	assert.Equal(t, []string{"server_auth"}, info.ExtKeyUsage)
+	assert.Equal(t, "ECDSA", info.KeyAlgorithm)
+	assert.Equal(t, 256, info.KeySize)
+
+	res, err := Inspect(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, FormatPEM, res.Format)
+	assert.True(t, res.HasPrivateKey)
+	assert.True(t, res.Valid())
+	require.Len(t, res.Certificates, 1)
+	assert.Equal(t, info.FingerprintSHA256, res.Certificates[0].FingerprintSHA256)
+}
+
+func TestIssueWithCA(t *testing.T) {
+	ca := &bytes.Buffer{}
+	_, err := Issue(ca, IssueRequest{
+		Subject: pkix.Name{CommonName: "Test CA"},
+		KeyType: KeyEd25519,
+		IsCA:    true,
+	})
+	require.NoError(t, err)
+
+	caCert, err := tls.X509KeyPair(ca.Bytes(), ca.Bytes())
+	require.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	info, err := Issue(out, IssueRequest{
+		Subject:     pkix.Name{CommonName: "client"},
+		KeyType:     KeyRSA2048,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		Issuer:      &caCert,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "CN=Test CA", info.Issuer)
+	assert.Equal(t, 2048, info.KeySize)
+
+	res, err := Inspect(out)
+	require.NoError(t, err)
+	assert.True(t, res.Valid())
+	require.Len(t, res.Certificates, 2)
+	assert.True(t, res.Certificates[1].IsCA)
+}
+
+func TestConvert(t *testing.T) {
+	issued := &bytes.Buffer{}
+	_, err := Issue(issued, IssueRequest{Subject: pkix.Name{CommonName: "localhost"}})
+	require.NoError(t, err)
+
+	p12 := &bytes.Buffer{}
+	require.NoError(t, Convert(p12, bytes.NewReader(issued.Bytes()), FormatPKCS12, ExportPassword("secret")))
+
+	res, err := Inspect(bytes.NewReader(p12.Bytes()), Password("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, FormatPKCS12, res.Format)
+	assert.True(t, res.HasPrivateKey)
+
+	der := &bytes.Buffer{}
+	require.NoError(t, Convert(der, bytes.NewReader(p12.Bytes()), FormatDER, Password("secret")))
+
+	res, err = Inspect(der)
+	require.NoError(t, err)
+	assert.Equal(t, FormatDER, res.Format)
+	assert.False(t, res.HasPrivateKey)
+
+	pemOut := &bytes.Buffer{}
+	require.NoError(t, Convert(pemOut, bytes.NewReader(p12.Bytes()), FormatPEM, Password("secret"), ExportPassword("")))
+	_, err = tls.X509KeyPair(pemOut.Bytes(), pemOut.Bytes())
+	require.NoError(t, err)
+
+	assert.Error(t, Convert(&bytes.Buffer{}, bytes.NewReader(issued.Bytes()), Format("jks")))
+}
+
+func TestInspectUnsupported(t *testing.T) {
+	_, err := Inspect(bytes.NewReader([]byte("not a certificate")))
+	assert.Error(t, err)
+}
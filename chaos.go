@@ -0,0 +1,47 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"azugo.io/core/chaos"
+)
+
+func (a *App) initChaos() {
+	a.chaoslock.Lock()
+	defer a.chaoslock.Unlock()
+
+	if a.chaos != nil {
+		return
+	}
+
+	conf := a.Config().Chaos
+	rules := make(chaos.Rules, 0, len(conf.Rules))
+	for _, r := range conf.Rules {
+		rules = append(rules, chaos.Rule{
+			Target:      r.Target,
+			ErrorRate:   r.ErrorRate,
+			StatusCode:  r.StatusCode,
+			Latency:     r.Latency,
+			LatencyRate: r.LatencyRate,
+		})
+	}
+	a.chaos = chaos.New(
+		chaos.Enabled(conf.Enabled),
+		chaos.Seed(conf.Seed),
+		rules,
+		chaos.Instrumenter(a.Instrumenter()),
+		chaos.Logger{Logger: a.Log().Named("chaos")},
+	)
+}
+
+// Chaos returns fault injector for game-day testing.
+//
+// Faults are injected only when enabled in the configuration. HTTP clients
+// returned by HTTPClient are wrapped automatically, queues and scheduled tasks
+// must be wrapped using the injector Queue, Handler and Run methods.
+func (a *App) Chaos() *chaos.Injector {
+	a.initChaos()
+	return a.chaos
+}
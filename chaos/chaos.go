@@ -0,0 +1,216 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"go.uber.org/zap"
+)
+
+// InstrumentationFault is an instrumentation operation for injected faults.
+const InstrumentationFault = "chaos-fault"
+
+// Target kinds used as a prefix for the fault injection target.
+const (
+	// TargetHTTP is a target kind for outbound HTTP client calls, target name is destination host.
+	TargetHTTP = "http"
+	// TargetQueue is a target kind for queue operations, target name is topic.
+	TargetQueue = "queue"
+	// TargetScheduler is a target kind for scheduled task runs, target name is task name.
+	TargetScheduler = "scheduler"
+)
+
+// ErrInjected is an error returned by injected faults when rule does not specify one.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Rule describes faults to inject into matching targets.
+type Rule struct {
+	// Target is a pattern in the format "kind:name" matched against targets.
+	// Name can contain shell file name patterns (for example "http:*.example.com"
+	// or "scheduler:*").
+	Target string
+	// ErrorRate is a probability in range 0 to 1 to fail the operation.
+	ErrorRate float64
+	// Error to return for failed operations. Defaults to ErrInjected.
+	Error error
+	// StatusCode is an HTTP status code to respond with instead of returning
+	// an error for failed HTTP client calls.
+	StatusCode int
+	// Latency to add to the operation.
+	Latency time.Duration
+	// LatencyRate is a probability in range 0 to 1 to add latency to the operation.
+	LatencyRate float64
+}
+
+// Fault is a fault decided for the single operation.
+type Fault struct {
+	// Target of the operation.
+	Target string
+	// Latency added to the operation.
+	Latency time.Duration
+	// Err is an error to fail the operation with, nil if operation should not fail.
+	Err error
+	// StatusCode is an HTTP status code to respond with for failed HTTP client calls.
+	StatusCode int
+}
+
+// Injector injects latency and errors into core subsystems for game-day testing.
+type Injector struct {
+	enabled      atomic.Bool
+	instrumenter instrumenter.Instrumenter
+	logger       *zap.Logger
+
+	lock  sync.RWMutex
+	rules []Rule
+
+	rndlock sync.Mutex
+	rnd     *rand.Rand
+}
+
+// New creates new fault injector.
+func New(opts ...Option) *Injector {
+	opt := newOptions(opts...)
+
+	seed := opt.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	i := &Injector{
+		instrumenter: opt.Instrumenter,
+		logger:       opt.Logger,
+		rules:        opt.Rules,
+		//nolint:gosec
+		rnd: rand.New(rand.NewSource(seed)),
+	}
+	i.enabled.Store(opt.Enabled)
+	return i
+}
+
+// Enabled returns true if fault injection is enabled.
+func (i *Injector) Enabled() bool {
+	return i != nil && i.enabled.Load()
+}
+
+// SetEnabled enables or disables fault injection.
+func (i *Injector) SetEnabled(enabled bool) {
+	i.enabled.Store(enabled)
+}
+
+// Rules returns current fault injection rules.
+func (i *Injector) Rules() []Rule {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return append([]Rule(nil), i.rules...)
+}
+
+// SetRules replaces fault injection rules.
+func (i *Injector) SetRules(rules ...Rule) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.rules = append([]Rule(nil), rules...)
+}
+
+// rule returns first rule matching target.
+func (i *Injector) rule(target string) (Rule, bool) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	for _, r := range i.rules {
+		if ok, _ := path.Match(r.Target, target); ok {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+func (i *Injector) chance(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	if p >= 1 {
+		return true
+	}
+
+	i.rndlock.Lock()
+	defer i.rndlock.Unlock()
+
+	return i.rnd.Float64() < p
+}
+
+// Decide returns fault to inject into operation for the target of the kind.
+// Returns nil if fault injection is disabled or no fault should be injected.
+func (i *Injector) Decide(kind, name string) *Fault {
+	if !i.Enabled() {
+		return nil
+	}
+	target := kind + ":" + name
+	r, ok := i.rule(target)
+	if !ok {
+		return nil
+	}
+
+	f := &Fault{Target: target}
+	if r.Latency > 0 && i.chance(r.LatencyRate) {
+		f.Latency = r.Latency
+	}
+	if i.chance(r.ErrorRate) {
+		f.Err = r.Error
+		if f.Err == nil {
+			f.Err = ErrInjected
+		}
+		f.StatusCode = r.StatusCode
+	}
+	if f.Latency == 0 && f.Err == nil {
+		return nil
+	}
+	return f
+}
+
+// apply waits for the fault latency and returns fault error.
+func (i *Injector) apply(ctx context.Context, f *Fault) error {
+	if f == nil {
+		return nil
+	}
+
+	finish := i.instrumenter.Observe(ctx, InstrumentationFault, f.Target, f.Latency)
+
+	i.logger.Warn("chaos fault injected",
+		zap.String("chaos.target", f.Target),
+		zap.Duration("chaos.latency", f.Latency),
+		zap.Error(f.Err),
+	)
+
+	if f.Latency > 0 {
+		t := time.NewTimer(f.Latency)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			finish(ctx.Err())
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	finish(f.Err)
+	return f.Err
+}
+
+// Inject latency and error into the operation for the target of the kind.
+//
+// Returns error if operation should fail.
+func (i *Injector) Inject(ctx context.Context, kind, name string) error {
+	return i.apply(ctx, i.Decide(kind, name))
+}
@@ -0,0 +1,146 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"azugo.io/core/queue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testQueue struct {
+	published []*queue.Message
+	handler   queue.Handler
+}
+
+func (q *testQueue) Publish(_ context.Context, msg *queue.Message) error {
+	q.published = append(q.published, msg)
+	return nil
+}
+
+func (q *testQueue) Subscribe(_ context.Context, _ string, handler queue.Handler) error {
+	q.handler = handler
+	return nil
+}
+
+func TestInjectorDisabled(t *testing.T) {
+	i := New(Rules{{Target: "scheduler:*", ErrorRate: 1}})
+
+	assert.False(t, i.Enabled())
+	assert.NoError(t, i.Inject(context.Background(), TargetScheduler, "cleanup"))
+
+	var nilInjector *Injector
+	assert.False(t, nilInjector.Enabled())
+	assert.Nil(t, nilInjector.Decide(TargetScheduler, "cleanup"))
+
+	i.SetEnabled(true)
+	assert.ErrorIs(t, i.Inject(context.Background(), TargetScheduler, "cleanup"), ErrInjected)
+}
+
+func TestInjectorRules(t *testing.T) {
+	errCustom := errors.New("custom")
+	i := New(Enabled(true), Seed(1), Rules{
+		{Target: "queue:orders", ErrorRate: 1, Error: errCustom},
+		{Target: "queue:*", ErrorRate: 0},
+		{Target: "http:*.example.com", Latency: 10 * time.Millisecond, LatencyRate: 1},
+	})
+
+	assert.ErrorIs(t, i.Inject(context.Background(), TargetQueue, "orders"), errCustom)
+	assert.NoError(t, i.Inject(context.Background(), TargetQueue, "invoices"))
+	assert.Nil(t, i.Decide(TargetScheduler, "cleanup"))
+
+	f := i.Decide(TargetHTTP, "api.example.com")
+	require.NotNil(t, f)
+	assert.Equal(t, 10*time.Millisecond, f.Latency)
+	assert.NoError(t, f.Err)
+
+	start := time.Now()
+	assert.NoError(t, i.Inject(context.Background(), TargetHTTP, "api.example.com"))
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, i.Inject(ctx, TargetHTTP, "api.example.com"), context.Canceled)
+
+	i.SetRules()
+	assert.Empty(t, i.Rules())
+	assert.NoError(t, i.Inject(context.Background(), TargetQueue, "orders"))
+}
+
+func TestInjectorProbability(t *testing.T) {
+	i := New(Enabled(true), Seed(42), Rules{{Target: "scheduler:*", ErrorRate: 0.5}})
+
+	failed := 0
+	for n := 0; n < 1000; n++ {
+		if i.Decide(TargetScheduler, "task") != nil {
+			failed++
+		}
+	}
+	assert.InDelta(t, 500, failed, 100)
+}
+
+func TestInjectorTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	i := New(Enabled(true), Rules{{Target: "http:127.0.0.1", ErrorRate: 1, StatusCode: http.StatusServiceUnavailable}})
+	c := i.Client(srv.Client())
+
+	resp, err := c.Get(srv.URL)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, http.StatusText(http.StatusServiceUnavailable), string(body))
+
+	i.SetRules(Rule{Target: "http:127.0.0.1", ErrorRate: 1})
+	_, err = c.Get(srv.URL)
+	assert.ErrorIs(t, err, ErrInjected)
+
+	i.SetEnabled(false)
+	resp, err = c.Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestInjectorQueueAndRun(t *testing.T) {
+	i := New(Enabled(true), Rules{
+		{Target: "queue:faulty", ErrorRate: 1},
+		{Target: "scheduler:faulty", ErrorRate: 1},
+	})
+
+	tq := &testQueue{}
+	q := i.Queue(tq)
+
+	assert.ErrorIs(t, q.Publish(context.Background(), &queue.Message{Topic: "faulty"}), ErrInjected)
+	assert.NoError(t, q.Publish(context.Background(), &queue.Message{Topic: "ok"}))
+	assert.Len(t, tq.published, 1)
+
+	handled := 0
+	require.NoError(t, q.Subscribe(context.Background(), "faulty", func(ctx context.Context, msg *queue.Message) error {
+		handled++
+		return nil
+	}))
+	assert.ErrorIs(t, tq.handler(context.Background(), &queue.Message{Topic: "faulty"}), ErrInjected)
+	assert.NoError(t, tq.handler(context.Background(), &queue.Message{Topic: "ok"}))
+	assert.Equal(t, 1, handled)
+
+	run := 0
+	fn := func(ctx context.Context) error {
+		run++
+		return nil
+	}
+	assert.ErrorIs(t, i.Run("faulty", fn)(context.Background()), ErrInjected)
+	assert.NoError(t, i.Run("ok", fn)(context.Background()))
+	assert.Equal(t, 1, run)
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"azugo.io/core/queue"
+)
+
+type transport struct {
+	injector *Injector
+	next     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f := t.injector.Decide(TargetHTTP, req.URL.Hostname())
+	if f == nil {
+		return t.next.RoundTrip(req)
+	}
+	if err := t.injector.apply(req.Context(), f); err != nil {
+		if f.StatusCode == 0 || err != f.Err {
+			return nil, err
+		}
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		body := http.StatusText(f.StatusCode)
+		return &http.Response{
+			Status:        body,
+			StatusCode:    f.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}},
+			Body:          io.NopCloser(bytes.NewBufferString(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+// Transport wraps HTTP client transport to inject faults into outbound calls.
+//
+// If next is nil, http.DefaultTransport is used.
+func (i *Injector) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{
+		injector: i,
+		next:     next,
+	}
+}
+
+// Client wraps HTTP client transport to inject faults into outbound calls.
+func (i *Injector) Client(c *http.Client) *http.Client {
+	cc := *c
+	cc.Transport = i.Transport(c.Transport)
+	return &cc
+}
+
+type faultyQueue struct {
+	injector *Injector
+	queue.Queue
+}
+
+func (q *faultyQueue) Publish(ctx context.Context, msg *queue.Message) error {
+	if err := q.injector.Inject(ctx, TargetQueue, msg.Topic); err != nil {
+		return err
+	}
+	return q.Queue.Publish(ctx, msg)
+}
+
+func (q *faultyQueue) Subscribe(ctx context.Context, topic string, handler queue.Handler) error {
+	return q.Queue.Subscribe(ctx, topic, q.injector.Handler(handler))
+}
+
+// Queue wraps queue to inject faults into publishing and message handling.
+func (i *Injector) Queue(q queue.Queue) queue.Queue {
+	return &faultyQueue{
+		injector: i,
+		Queue:    q,
+	}
+}
+
+// Handler wraps queue message handler to inject faults before message is handled.
+func (i *Injector) Handler(next queue.Handler) queue.Handler {
+	return func(ctx context.Context, msg *queue.Message) error {
+		if err := i.Inject(ctx, TargetQueue, msg.Topic); err != nil {
+			return err
+		}
+		return next(ctx, msg)
+	}
+}
+
+// Run wraps scheduled task function with specified name to inject faults
+// before the task is run.
+func (i *Injector) Run(name string, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if err := i.Inject(ctx, TargetScheduler, name); err != nil {
+			return err
+		}
+		return fn(ctx)
+	}
+}
@@ -0,0 +1,70 @@
+package chaos
+
+import (
+	"azugo.io/core/instrumenter"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	Enabled      bool
+	Rules        []Rule
+	Seed         int64
+	Instrumenter instrumenter.Instrumenter
+	Logger       *zap.Logger
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return opt
+}
+
+// Option for the fault injector.
+type Option interface {
+	apply(*options)
+}
+
+// Enabled enables fault injection. Injector is disabled by default.
+type Enabled bool
+
+func (e Enabled) apply(o *options) {
+	o.Enabled = bool(e)
+}
+
+// Rules are fault injection rules. First rule matching target is applied.
+type Rules []Rule
+
+func (r Rules) apply(o *options) {
+	o.Rules = append(o.Rules, r...)
+}
+
+// Seed for the random number generator to make fault injection reproducible.
+//
+// Zero uses random seed.
+type Seed int64
+
+func (s Seed) apply(o *options) {
+	o.Seed = int64(s)
+}
+
+// Instrumenter to observe injected faults.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
+
+// Logger to log injected faults.
+type Logger struct {
+	*zap.Logger
+}
+
+func (l Logger) apply(o *options) {
+	o.Logger = l.Logger
+}
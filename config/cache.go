@@ -5,20 +5,54 @@
 package config
 
 import (
+	"errors"
 	"time"
 
 	"azugo.io/core/cache"
+	"azugo.io/core/serializer"
 	"azugo.io/core/validation"
 
 	"github.com/spf13/viper"
 )
 
 type Cache struct {
-	Type             cache.CacheType `mapstructure:"type" validate:"required,oneof=memory redis redis-cluster"`
-	TTL              time.Duration   `mapstructure:"ttl" validate:"omitempty,min=0"`
+	Type              cache.CacheType           `mapstructure:"type" validate:"required,oneof=memory redis redis-cluster redis-ring redis-sentinel memcached nats etcd dynamodb postgres bolt remote"`
+	TTL               Duration                  `mapstructure:"ttl" validate:"omitempty,min=0"`
+	TTLJitter         float64                   `mapstructure:"ttl_jitter" validate:"omitempty,min=0,max=100"`
+	ConnectionString  string                    `mapstructure:"connection" validate:"omitempty"`
+	Password          string                    `mapstructure:"password" validate:"omitempty"`
+	KeyPrefix         string                    `mapstructure:"key_prefix" validate:"omitempty"`
+	MaxItems          int                       `mapstructure:"max_items" validate:"omitempty,min=0"`
+	MaxSize           Size                      `mapstructure:"max_size" validate:"omitempty,min=0"`
+	Instances         map[string]*CacheInstance `mapstructure:"instances" validate:"omitempty,dive"`
+	ClientCertificate string                    `mapstructure:"client_certificate" validate:"omitempty,file"`
+}
+
+// CacheInstance is a declared named cache instance. Unset fields are inherited
+// from the cache configuration section.
+type CacheInstance struct {
+	Type             cache.CacheType `mapstructure:"type" validate:"omitempty,oneof=memory redis redis-cluster redis-ring redis-sentinel memcached nats etcd dynamodb postgres bolt remote"`
+	TTL              Duration        `mapstructure:"ttl" validate:"omitempty,min=0"`
 	ConnectionString string          `mapstructure:"connection" validate:"omitempty"`
 	Password         string          `mapstructure:"password" validate:"omitempty"`
 	KeyPrefix        string          `mapstructure:"key_prefix" validate:"omitempty"`
+	MaxItems         int             `mapstructure:"max_items" validate:"omitempty,min=0"`
+	MaxSize          Size            `mapstructure:"max_size" validate:"omitempty,min=0"`
+	Serializer       string          `mapstructure:"serializer" validate:"omitempty,oneof=json msgpack xml"`
+	Compress         bool            `mapstructure:"compress"`
+	Tiered           *CacheTiered    `mapstructure:"tiered" validate:"omitempty"`
+	Mode             cache.Mode      `mapstructure:"mode" validate:"omitempty,oneof=normal bypass freeze read-only"`
+	Sliding          bool            `mapstructure:"sliding"`
+}
+
+// CacheTiered is a local tier configuration of the cache instance.
+type CacheTiered struct {
+	TTL            Duration `mapstructure:"ttl" validate:"omitempty,min=0"`
+	MaxItems       int      `mapstructure:"max_items" validate:"omitempty,min=0"`
+	MaxSize        Size     `mapstructure:"max_size" validate:"omitempty,min=0"`
+	Invalidation   string   `mapstructure:"invalidation" validate:"omitempty,oneof=pubsub ttl"`
+	ReadYourWrites Duration `mapstructure:"read_your_writes" validate:"omitempty,min=0"`
+	PinWrites      bool     `mapstructure:"pin_writes"`
 }
 
 // Validate cache configuration section.
@@ -27,11 +61,125 @@ func (c *Cache) Validate(valid *validation.Validate) error {
 		return err
 	}
 	if err := cache.ValidateConnectionString(c.Type, c.ConnectionString); err != nil {
-		return err
+		return &FieldError{Path: "connection", Err: err}
+	}
+	for name, i := range c.Instances {
+		if i == nil {
+			continue
+		}
+		typ := i.Type
+		if len(typ) == 0 {
+			typ = c.Type
+		}
+		if i.Tiered != nil && typ == cache.MemoryCache {
+			return &FieldError{
+				Path: "instances." + name + ".tiered",
+				Err:  errors.New("tiered cache is not supported for memory cache instances"),
+			}
+		}
+		// Instance of the other type can not inherit connection string.
+		if len(i.ConnectionString) == 0 && typ == c.Type {
+			continue
+		}
+		if err := cache.ValidateConnectionString(typ, i.ConnectionString); err != nil {
+			return &FieldError{Path: "instances." + name + ".connection", Err: err}
+		}
 	}
 	return nil
 }
 
+// Options returns cache options from the cache configuration section.
+//
+// Declared cache instances are not included, use Options of each instance to
+// define them in the cache.
+func (c *Cache) Options() []cache.CacheOption {
+	opts := []cache.CacheOption{c.Type}
+	if c.TTL > 0 {
+		opts = append(opts, cache.DefaultTTL(c.TTL))
+	}
+	if c.TTLJitter > 0 {
+		opts = append(opts, cache.WithTTLJitter(c.TTLJitter))
+	}
+	if len(c.ConnectionString) != 0 {
+		opts = append(opts, cache.ConnectionString(c.ConnectionString))
+	}
+	if len(c.Password) != 0 {
+		opts = append(opts, cache.ConnectionPassword(c.Password))
+	}
+	if len(c.KeyPrefix) != 0 {
+		opts = append(opts, cache.KeyPrefix(c.KeyPrefix))
+	}
+	if c.MaxItems > 0 || c.MaxSize > 0 {
+		opts = append(opts, cache.MemoryLimit{
+			MaxItems: c.MaxItems,
+			MaxBytes: int64(c.MaxSize),
+		})
+	}
+	return opts
+}
+
+// Options returns options of the cache instance declared in the configuration.
+func (c *CacheInstance) Options() []cache.CacheOption {
+	opts := make([]cache.CacheOption, 0)
+	if c == nil {
+		return opts
+	}
+	if len(c.Type) != 0 {
+		opts = append(opts, c.Type)
+	}
+	if c.TTL > 0 {
+		opts = append(opts, cache.DefaultTTL(c.TTL))
+	}
+	if len(c.ConnectionString) != 0 {
+		// Password of the cache configuration does not apply to other connection.
+		opts = append(opts, cache.ConnectionString(c.ConnectionString), cache.ConnectionPassword(c.Password))
+	} else if len(c.Password) != 0 {
+		opts = append(opts, cache.ConnectionPassword(c.Password))
+	}
+	if len(c.KeyPrefix) != 0 {
+		opts = append(opts, cache.KeyPrefix(c.KeyPrefix))
+	}
+	if c.MaxItems > 0 || c.MaxSize > 0 {
+		opts = append(opts, cache.MemoryLimit{
+			MaxItems: c.MaxItems,
+			MaxBytes: int64(c.MaxSize),
+		})
+	}
+	if len(c.Serializer) != 0 || c.Compress {
+		s := serializer.JSON
+		switch c.Serializer {
+		case "msgpack":
+			s = serializer.MsgPack
+		case "xml":
+			s = serializer.XML
+		}
+		if c.Compress {
+			s = serializer.Gzip(s)
+		}
+		opts = append(opts, cache.Serializer{Serializer: s})
+	}
+	if len(c.Mode) != 0 {
+		opts = append(opts, cache.ModeSwitch{Mode: c.Mode})
+	}
+	if c.Sliding {
+		opts = append(opts, cache.SlidingExpiration{})
+	}
+	if t := c.Tiered; t != nil {
+		tiered := cache.Tiered{
+			TTL:            time.Duration(t.TTL),
+			MaxItems:       t.MaxItems,
+			MaxBytes:       int64(t.MaxSize),
+			ReadYourWrites: time.Duration(t.ReadYourWrites),
+			PinWrites:      t.PinWrites,
+		}
+		if t.Invalidation == "ttl" {
+			tiered.Invalidation = cache.TierInvalidateTTL
+		}
+		opts = append(opts, tiered)
+	}
+	return opts
+}
+
 // Bind cache configuration section.
 func (c *Cache) Bind(prefix string, v *viper.Viper) {
 	psw, _ := LoadRemoteSecret("CACHE_PASSWORD")
@@ -41,6 +189,10 @@ func (c *Cache) Bind(prefix string, v *viper.Viper) {
 
 	_ = v.BindEnv(prefix+".type", "CACHE_TYPE")
 	_ = v.BindEnv(prefix+".ttl", "CACHE_TTL")
+	_ = v.BindEnv(prefix+".ttl_jitter", "CACHE_TTL_JITTER")
 	_ = v.BindEnv(prefix+".connection", "CACHE_CONNECTION")
 	_ = v.BindEnv(prefix+".key_prefix", "CACHE_KEY_PREFIX")
+	_ = v.BindEnv(prefix+".max_items", "CACHE_MAX_ITEMS")
+	_ = v.BindEnv(prefix+".max_size", "CACHE_MAX_SIZE")
+	_ = v.BindEnv(prefix+".client_certificate", "CACHE_CLIENT_CERTIFICATE")
 }
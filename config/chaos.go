@@ -0,0 +1,42 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"time"
+
+	"azugo.io/core/validation"
+
+	"github.com/spf13/viper"
+)
+
+// ChaosRule is a fault injection rule for matching targets.
+type ChaosRule struct {
+	Target      string        `mapstructure:"target" validate:"required"`
+	ErrorRate   float64       `mapstructure:"error_rate" validate:"min=0,max=1"`
+	StatusCode  int           `mapstructure:"status_code" validate:"omitempty,min=100,max=599"`
+	Latency     time.Duration `mapstructure:"latency" validate:"omitempty,min=0"`
+	LatencyRate float64       `mapstructure:"latency_rate" validate:"min=0,max=1"`
+}
+
+// Chaos is a fault injection configuration section.
+type Chaos struct {
+	Enabled bool        `mapstructure:"enabled"`
+	Seed    int64       `mapstructure:"seed"`
+	Rules   []ChaosRule `mapstructure:"rules" validate:"omitempty,dive"`
+}
+
+// Validate chaos configuration section.
+func (c *Chaos) Validate(valid *validation.Validate) error {
+	return valid.Struct(c)
+}
+
+// Bind chaos configuration section.
+func (c *Chaos) Bind(prefix string, v *viper.Viper) {
+	v.SetDefault(prefix+".enabled", false)
+
+	_ = v.BindEnv(prefix+".enabled", "CHAOS_ENABLED")
+	_ = v.BindEnv(prefix+".seed", "CHAOS_SEED")
+}
@@ -10,10 +10,19 @@ import (
 
 	"azugo.io/core/validation"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+// decodeHook extends default viper decode hooks to decode configuration values
+// implementing encoding.TextUnmarshaler, such as Duration, Size and Cron.
+var decodeHook = mapstructure.ComposeDecodeHookFunc(
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+	mapstructure.TextUnmarshallerHookFunc(),
+)
+
 // Configuration for the application.
 type Configuration struct {
 	v          *viper.Viper
@@ -23,6 +32,20 @@ type Configuration struct {
 
 	// Cache configuration section.
 	Cache *Cache
+	// Network configuration section.
+	Network *Network
+	// TLS certificates configuration section.
+	TLS *TLS
+	// Warmup phase configuration section.
+	Warmup *Warmup
+	// Graceful draining configuration section.
+	Drain *Drain
+	// Self-diagnostics configuration section.
+	Diagnostics *Diagnostics
+	// Fault injection configuration section.
+	Chaos *Chaos
+	// Template rendering configuration section.
+	Templates *Templates
 }
 
 // New returns a new configuration.
@@ -77,6 +100,13 @@ func Bind[T any](c *T, prefix string, v *viper.Viper) *T {
 // Bind binds configuration section to viper.
 func (c *Configuration) Bind(_ string, v *viper.Viper) {
 	c.Cache = Bind(c.Cache, "cache", v)
+	c.Network = Bind(c.Network, "network", v)
+	c.TLS = Bind(c.TLS, "tls", v)
+	c.Warmup = Bind(c.Warmup, "warmup", v)
+	c.Drain = Bind(c.Drain, "drain", v)
+	c.Diagnostics = Bind(c.Diagnostics, "diagnostics", v)
+	c.Chaos = Bind(c.Chaos, "chaos", v)
+	c.Templates = Bind(c.Templates, "templates", v)
 }
 
 // Core returns the core configuration.
@@ -152,7 +182,7 @@ func (c *Configuration) Load(cmd *cobra.Command, config any, environment string)
 		}
 	}
 
-	if err := c.v.Unmarshal(config); err != nil {
+	if err := c.v.Unmarshal(config, viper.DecodeHook(decodeHook)); err != nil {
 		return fmt.Errorf("failed to unmarshal configuration: %w", err)
 	}
 
@@ -175,7 +205,28 @@ func (c *Configuration) Load(cmd *cobra.Command, config any, environment string)
 // Validate the configuration.
 func (c *Configuration) Validate(validate *validation.Validate) error {
 	if err := c.Cache.Validate(validate); err != nil {
-		return err
+		return WithPath("cache", c.Cache, err)
+	}
+	if err := c.Network.Validate(validate); err != nil {
+		return WithPath("network", c.Network, err)
+	}
+	if err := c.TLS.Validate(validate); err != nil {
+		return WithPath("tls", c.TLS, err)
+	}
+	if err := c.Warmup.Validate(validate); err != nil {
+		return WithPath("warmup", c.Warmup, err)
+	}
+	if err := c.Drain.Validate(validate); err != nil {
+		return WithPath("drain", c.Drain, err)
+	}
+	if err := c.Diagnostics.Validate(validate); err != nil {
+		return WithPath("diagnostics", c.Diagnostics, err)
+	}
+	if err := c.Chaos.Validate(validate); err != nil {
+		return WithPath("chaos", c.Chaos, err)
+	}
+	if err := c.Templates.Validate(validate); err != nil {
+		return WithPath("templates", c.Templates, err)
 	}
 	return nil
 }
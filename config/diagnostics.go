@@ -0,0 +1,45 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"time"
+
+	"azugo.io/core/validation"
+
+	"github.com/spf13/viper"
+)
+
+// Diagnostics is a self-diagnostics configuration section.
+type Diagnostics struct {
+	// Timeout is a maximum duration of a single diagnostic check.
+	Timeout Duration `mapstructure:"timeout" validate:"omitempty,min=0"`
+	// MaxGoroutines is a number of goroutines above which runtime check reports warning.
+	MaxGoroutines int `mapstructure:"max_goroutines" validate:"omitempty,min=0"`
+	// CacheLatency is a 99th percentile cache operation latency above which
+	// cache latency check reports warning.
+	CacheLatency Duration `mapstructure:"cache_latency" validate:"omitempty,min=0"`
+	// CertificateExpiry is a duration before certificate expiration when
+	// certificate check starts to report warning.
+	CertificateExpiry Duration `mapstructure:"certificate_expiry" validate:"omitempty,min=0"`
+}
+
+// Validate diagnostics configuration section.
+func (c *Diagnostics) Validate(valid *validation.Validate) error {
+	return valid.Struct(c)
+}
+
+// Bind diagnostics configuration section.
+func (c *Diagnostics) Bind(prefix string, v *viper.Viper) {
+	v.SetDefault(prefix+".timeout", 5*time.Second)
+	v.SetDefault(prefix+".max_goroutines", 10000)
+	v.SetDefault(prefix+".cache_latency", 100*time.Millisecond)
+	v.SetDefault(prefix+".certificate_expiry", 14*24*time.Hour)
+
+	_ = v.BindEnv(prefix+".timeout", "DIAGNOSTICS_TIMEOUT")
+	_ = v.BindEnv(prefix+".max_goroutines", "DIAGNOSTICS_MAX_GOROUTINES")
+	_ = v.BindEnv(prefix+".cache_latency", "DIAGNOSTICS_CACHE_LATENCY")
+	_ = v.BindEnv(prefix+".certificate_expiry", "DIAGNOSTICS_CERTIFICATE_EXPIRY")
+}
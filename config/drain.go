@@ -0,0 +1,32 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"time"
+
+	"azugo.io/core/validation"
+
+	"github.com/spf13/viper"
+)
+
+// Drain is a graceful draining configuration section.
+type Drain struct {
+	// StageTimeout is a maximum time to wait for in-flight work of a single
+	// priority to finish during shutdown.
+	StageTimeout Duration `mapstructure:"stage_timeout" validate:"omitempty,min=0"`
+}
+
+// Validate drain configuration section.
+func (c *Drain) Validate(valid *validation.Validate) error {
+	return valid.Struct(c)
+}
+
+// Bind drain configuration section.
+func (c *Drain) Bind(prefix string, v *viper.Viper) {
+	v.SetDefault(prefix+".stage_timeout", 10*time.Second)
+
+	_ = v.BindEnv(prefix+".stage_timeout", "DRAIN_STAGE_TIMEOUT")
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"azugo.io/core/scrub"
+
+	"github.com/goccy/go-json"
+)
+
+// RedactRules are rules used to redact secret values of the configuration dump.
+var RedactRules = scrub.Rules{
+	{Field: "*password*", Action: scrub.Mask},
+	{Field: "*passphrase*", Action: scrub.Mask},
+	{Field: "*secret*", Action: scrub.Mask},
+	{Field: "*token*", Action: scrub.Mask},
+	{Field: "*credential*", Action: scrub.Mask},
+	{Field: "*api_key*", Action: scrub.Mask},
+	{Field: "*apikey*", Action: scrub.Mask},
+	{Field: "*access_key*", Action: scrub.Mask},
+	{Field: "*private_key*", Action: scrub.Mask},
+	{Field: "authorization", Action: scrub.Mask},
+}
+
+// secretParam matches secret parameters in the connection strings, for example
+// "password=secret" in the key/value format or the URL query.
+var secretParam = regexp.MustCompile(`(?i)\b([a-z_]*(?:password|passwd|pwd|secret|token)[a-z_]*)=([^\s&;]+)`)
+
+// redactString redacts passwords of the connection strings embedded in the value.
+func redactString(v string) string {
+	if strings.Contains(v, "://") {
+		if u, err := url.Parse(v); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				// Masked value would be escaped in the URL user info.
+				v = strings.Replace(u.Redacted(), ":xxxxx@", ":"+scrub.Masked+"@", 1)
+			}
+		}
+	}
+	return secretParam.ReplaceAllString(v, "${1}="+scrub.Masked)
+}
+
+// redactValues returns copy of the configuration values with connection
+// string passwords redacted.
+func redactValues(v any) any {
+	switch vv := v.(type) {
+	case string:
+		return redactString(vv)
+	case []string:
+		a := make([]any, 0, len(vv))
+		for _, s := range vv {
+			a = append(a, redactString(s))
+		}
+		return a
+	case []any:
+		a := make([]any, 0, len(vv))
+		for _, val := range vv {
+			a = append(a, redactValues(val))
+		}
+		return a
+	case map[string]any:
+		m := make(map[string]any, len(vv))
+		for k, val := range vv {
+			m[k] = redactValues(val)
+		}
+		return m
+	}
+	return v
+}
+
+// Dump returns fully resolved effective configuration after merging defaults,
+// configuration files, environment variables, command line flags and remote
+// secrets, with secret values redacted.
+//
+// Values of the keys matching RedactRules and additional rules are masked and
+// passwords in the connection strings are always redacted.
+func (c *Configuration) Dump(rules ...scrub.Rule) map[string]any {
+	s := scrub.New(append(append(scrub.Rules{}, RedactRules...), rules...))
+	v, _ := s.Any("", redactValues(c.v.AllSettings()))
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// Handler returns admin HTTP handler that responds with the effective
+// configuration dump with secret values redacted.
+func (c *Configuration) Handler(rules ...scrub.Rule) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		default:
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c.Dump(rules...))
+	})
+}
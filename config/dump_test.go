@@ -0,0 +1,68 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"azugo.io/core/scrub"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactString(t *testing.T) {
+	for v, want := range map[string]string{
+		"redis://:s3cr3t@localhost:6379/0":               "redis://:***@localhost:6379/0",
+		"postgres://app:pa55@db/cache?sslmode=disable":   "postgres://app:***@db/cache?sslmode=disable",
+		"redis://localhost:6379":                         "redis://localhost:6379",
+		"nats://host:4222?token=abc&bucket=cache":        "nats://host:4222?token=***&bucket=cache",
+		"host=db user=app password=pa55 dbname=cache":    "host=db user=app password=*** dbname=cache",
+		"Server=db;User Id=app;Password=pa55;Encrypt=no": "Server=db;User Id=app;Password=***;Encrypt=no",
+		"memory": "memory",
+	} {
+		assert.Equal(t, want, redactString(v), v)
+	}
+}
+
+func TestDump(t *testing.T) {
+	t.Setenv("CACHE_TYPE", "redis")
+	t.Setenv("CACHE_CONNECTION", "redis://:s3cr3t@localhost:6379/0")
+	t.Setenv("CACHE_KEY_PREFIX", "app")
+
+	c := New()
+	require.NoError(t, c.Load(nil, c, ""))
+	c.v.Set("cache.password", "hunter2")
+	c.v.Set("billing.api_key", "key")
+	c.v.Set("billing.account", "acme")
+
+	d := c.Dump(scrub.Rule{Field: "billing.account", Action: scrub.Drop})
+
+	conf, ok := d["cache"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "redis", conf["type"])
+	assert.Equal(t, "app", conf["key_prefix"])
+	assert.Equal(t, "redis://:***@localhost:6379/0", conf["connection"])
+	assert.Equal(t, scrub.Masked, conf["password"])
+
+	billing, ok := d["billing"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, scrub.Masked, billing["api_key"])
+	assert.NotContains(t, billing, "account")
+
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.NotContains(t, rec.Body.String(), "s3cr3t")
+	assert.NotContains(t, rec.Body.String(), "hunter2")
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Contains(t, body, "cache")
+
+	rec = httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/config", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,155 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is a configuration validation error of the field.
+type FieldError struct {
+	// Path of the field in the configuration, for example "cache.instances.users.ttl".
+	Path string
+	// Err is a validation error.
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// FieldErrors are validation errors of multiple configuration fields.
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// WithPath returns configuration section validation error with field paths
+// prefixed by the path of the section in the configuration.
+//
+// Struct validation errors are mapped to configuration paths using mapstructure
+// tags of the section fields, other errors are reported at the section path.
+func WithPath(path string, section any, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		t := reflect.TypeOf(section)
+		res := make(FieldErrors, 0, len(verrs))
+		for _, fe := range verrs {
+			res = append(res, &FieldError{
+				Path: joinPath(path, fieldPath(t, fe.StructNamespace())),
+				Err:  tagError(fe),
+			})
+		}
+		return res
+	}
+
+	var fes FieldErrors
+	if errors.As(err, &fes) {
+		res := make(FieldErrors, 0, len(fes))
+		for _, fe := range fes {
+			res = append(res, &FieldError{Path: joinPath(path, fe.Path), Err: fe.Err})
+		}
+		return res
+	}
+
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		return &FieldError{Path: joinPath(path, fe.Path), Err: fe.Err}
+	}
+	return &FieldError{Path: path, Err: err}
+}
+
+// tagError returns error describing failed validation tag.
+func tagError(fe validator.FieldError) error {
+	if len(fe.Param()) != 0 {
+		return fmt.Errorf("failed %q validation", fe.Tag()+"="+fe.Param())
+	}
+	return fmt.Errorf("failed %q validation", fe.Tag())
+}
+
+func joinPath(prefix, path string) string {
+	if len(prefix) == 0 {
+		return path
+	}
+	if len(path) == 0 {
+		return prefix
+	}
+	if strings.HasPrefix(path, "[") {
+		return prefix + path
+	}
+	return prefix + "." + path
+}
+
+// fieldPath returns configuration path of the field from struct namespace
+// reported by the validator, for example "Cache.Instances[users].TTL" is
+// returned as "instances.users.ttl".
+func fieldPath(t reflect.Type, ns string) string {
+	// First element is a name of the validated struct.
+	_, ns, _ = strings.Cut(ns, ".")
+
+	var path string
+	for _, elem := range strings.Split(ns, ".") {
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		name, index, _ := strings.Cut(elem, "[")
+
+		key := name
+		if t != nil && t.Kind() == reflect.Struct {
+			if f, ok := t.FieldByName(name); ok {
+				if tag, _, _ := strings.Cut(f.Tag.Get("mapstructure"), ","); len(tag) != 0 && tag != "-" {
+					key = tag
+				}
+				t = f.Type
+			} else {
+				t = nil
+			}
+		} else {
+			t = nil
+		}
+		path = joinPath(path, key)
+
+		for len(index) != 0 {
+			var i string
+			i, index, _ = strings.Cut(index, "]")
+			index = strings.TrimPrefix(index, "[")
+			for t != nil && t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			}
+			if t != nil && t.Kind() == reflect.Map {
+				path += "." + i
+			} else {
+				path += "[" + i + "]"
+			}
+			if t != nil && (t.Kind() == reflect.Map || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+				t = t.Elem()
+			} else {
+				t = nil
+			}
+		}
+	}
+	return path
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPath(t *testing.T) {
+	valid := validation.New()
+
+	c := &Cache{
+		Type: cache.MemoryCache,
+		Instances: map[string]*CacheInstance{
+			"users": {TTL: Duration(-time.Second)},
+		},
+	}
+	err := WithPath("cache", c, valid.Struct(c))
+	var fes FieldErrors
+	require.ErrorAs(t, err, &fes)
+	require.Len(t, fes, 1)
+	assert.Equal(t, "cache.instances.users.ttl", fes[0].Path)
+	assert.Equal(t, `cache.instances.users.ttl: failed "min=0" validation`, err.Error())
+
+	ch := &Chaos{Rules: []ChaosRule{{Target: "cache"}, {Target: "http", LatencyRate: 2}}}
+	err = WithPath("chaos", ch, valid.Struct(ch))
+	require.ErrorAs(t, err, &fes)
+	require.Len(t, fes, 1)
+	assert.Equal(t, "chaos.rules[1].latency_rate", fes[0].Path)
+
+	err = WithPath("cache", c, &FieldError{Path: "instances.users.tiered", Err: errors.New("invalid")})
+	assert.Equal(t, "cache.instances.users.tiered: invalid", err.Error())
+
+	err = WithPath("tls", nil, errors.New("invalid"))
+	var fe *FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "tls", fe.Path)
+
+	assert.NoError(t, WithPath("cache", c, nil))
+}
+
+func TestCacheOptions(t *testing.T) {
+	c := &Cache{
+		Type:      cache.MemoryCache,
+		TTL:       Duration(time.Minute),
+		TTLJitter: 10,
+		KeyPrefix: "app",
+		Instances: map[string]*CacheInstance{
+			"users": {TTL: Duration(time.Second)},
+		},
+	}
+
+	cc := cache.New(append(c.Options(), cache.MemorySyncWrites(true))...)
+	require.NoError(t, cc.Start(context.TODO()))
+	defer cc.Close()
+
+	i, err := cache.Create[string](cc, "users", c.Instances["users"].Options()...)
+	require.NoError(t, err)
+	require.NoError(t, i.Set(context.TODO(), "key", "value"))
+	ttl, err := cache.GetTTL(context.TODO(), i, "key")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Second)
+}
+
+func TestTLSProfile(t *testing.T) {
+	valid := validation.New()
+
+	p := &TLSProfile{
+		MinVersion:   "1.2",
+		CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_RC4_128_SHA", "UNKNOWN"},
+		ClientAuth:   "require-and-verify",
+	}
+	err := WithPath("grpc.tls", p, p.Validate(valid))
+	var fe *FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "grpc.tls.cipher_suites[1]", fe.Path)
+
+	p.CipherSuites = p.CipherSuites[:1]
+	err = WithPath("grpc.tls", p, p.Validate(valid))
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "grpc.tls.client_ca_bundle", fe.Path)
+
+	p = &TLSProfile{
+		MinVersion:   "1.3",
+		CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+		ClientAuth:   "request",
+		ServerName:   "svc.local",
+	}
+	require.NoError(t, p.Validate(valid))
+	conf, err := p.Config()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), conf.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, conf.CipherSuites)
+	assert.Equal(t, tls.RequestClientCert, conf.ClientAuth)
+	assert.Equal(t, "svc.local", conf.ServerName)
+
+	p = &TLSProfile{Certificate: "missing.pem"}
+	_, err = p.Config()
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "certificate", fe.Path)
+}
+
+func TestPolicies(t *testing.T) {
+	valid := validation.New()
+
+	r := &Retry{MaxAttempts: 3, MinBackoff: Duration(time.Second), MaxBackoff: Duration(time.Millisecond)}
+	err := WithPath("webhook.retry", r, r.Validate(valid))
+	var fes FieldErrors
+	require.ErrorAs(t, err, &fes)
+	assert.Equal(t, "webhook.retry.max_backoff", fes[0].Path)
+
+	r.MaxBackoff = Duration(time.Minute)
+	require.NoError(t, r.Validate(valid))
+	assert.Len(t, r.WebhookOptions(), 2)
+	assert.Empty(t, (&Retry{}).WebhookOptions())
+
+	l := &RateLimit{Rate: -1}
+	err = WithPath("grpc.rate_limit", l, l.Validate(valid))
+	require.ErrorAs(t, err, &fes)
+	assert.Equal(t, "grpc.rate_limit.rate", fes[0].Path)
+
+	l = &RateLimit{Rate: 10, Burst: 20}
+	assert.Equal(t, 10.0, l.GRPCOption().Rate)
+	assert.Equal(t, 20, l.GRPCOption().Burst)
+}
@@ -0,0 +1,48 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"time"
+
+	"azugo.io/core/validation"
+
+	"github.com/spf13/viper"
+)
+
+// NetworkOverride is an outbound connectivity override for a destination host.
+type NetworkOverride struct {
+	Host               string `mapstructure:"host" validate:"required"`
+	Proxy              string `mapstructure:"proxy" validate:"omitempty,url"`
+	NoProxy            bool   `mapstructure:"no_proxy"`
+	CABundle           string `mapstructure:"ca_bundle" validate:"omitempty,file"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+}
+
+// Network is an outbound connectivity configuration section.
+type Network struct {
+	HTTPProxy  string            `mapstructure:"http_proxy" validate:"omitempty,url"`
+	HTTPSProxy string            `mapstructure:"https_proxy" validate:"omitempty,url"`
+	NoProxy    string            `mapstructure:"no_proxy"`
+	CABundle   string            `mapstructure:"ca_bundle" validate:"omitempty,file"`
+	Timeout    time.Duration     `mapstructure:"timeout" validate:"omitempty,min=0"`
+	TLSDebug   bool              `mapstructure:"tls_debug"`
+	Overrides  []NetworkOverride `mapstructure:"overrides" validate:"omitempty,dive"`
+}
+
+// Validate network configuration section.
+func (c *Network) Validate(valid *validation.Validate) error {
+	return valid.Struct(c)
+}
+
+// Bind network configuration section.
+func (c *Network) Bind(prefix string, v *viper.Viper) {
+	_ = v.BindEnv(prefix+".http_proxy", "HTTP_PROXY", "http_proxy")
+	_ = v.BindEnv(prefix+".https_proxy", "HTTPS_PROXY", "https_proxy")
+	_ = v.BindEnv(prefix+".no_proxy", "NO_PROXY", "no_proxy")
+	_ = v.BindEnv(prefix+".ca_bundle", "CA_BUNDLE")
+	_ = v.BindEnv(prefix+".timeout", "HTTP_CLIENT_TIMEOUT")
+	_ = v.BindEnv(prefix+".tls_debug", "TLS_DEBUG")
+}
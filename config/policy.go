@@ -0,0 +1,62 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"time"
+
+	"azugo.io/core/grpc"
+	"azugo.io/core/validation"
+	"azugo.io/core/webhook"
+)
+
+// Retry is a retry policy configuration that can be used in application
+// configuration sections.
+type Retry struct {
+	MaxAttempts int      `mapstructure:"max_attempts" validate:"omitempty,min=1"`
+	MinBackoff  Duration `mapstructure:"min_backoff" validate:"omitempty,min=0"`
+	MaxBackoff  Duration `mapstructure:"max_backoff" validate:"omitempty,min=0,gtefield=MinBackoff"`
+}
+
+// Validate retry policy.
+func (c *Retry) Validate(valid *validation.Validate) error {
+	return valid.Struct(c)
+}
+
+// WebhookOptions returns webhook dispatcher delivery retry options. Unset
+// values are left to webhook dispatcher defaults.
+func (c *Retry) WebhookOptions() []webhook.Option {
+	opts := make([]webhook.Option, 0, 2)
+	if c.MaxAttempts > 0 {
+		opts = append(opts, webhook.MaxAttempts(c.MaxAttempts))
+	}
+	if c.MinBackoff > 0 || c.MaxBackoff > 0 {
+		opts = append(opts, webhook.Backoff{
+			Min: time.Duration(c.MinBackoff),
+			Max: time.Duration(c.MaxBackoff),
+		})
+	}
+	return opts
+}
+
+// RateLimit is a rate limit configuration that can be used in application
+// configuration sections.
+type RateLimit struct {
+	Rate  float64 `mapstructure:"rate" validate:"omitempty,min=0"`
+	Burst int     `mapstructure:"burst" validate:"omitempty,min=0"`
+}
+
+// Validate rate limit.
+func (c *RateLimit) Validate(valid *validation.Validate) error {
+	return valid.Struct(c)
+}
+
+// GRPCOption returns gRPC server rate limit option.
+func (c *RateLimit) GRPCOption() grpc.RateLimit {
+	return grpc.RateLimit{
+		Rate:  c.Rate,
+		Burst: c.Burst,
+	}
+}
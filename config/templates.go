@@ -0,0 +1,36 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"azugo.io/core/validation"
+
+	"github.com/spf13/viper"
+)
+
+// Templates is a template rendering configuration section.
+type Templates struct {
+	// Path is a directory to load templates from.
+	Path string `mapstructure:"path"`
+	// DefaultLayout is a layout used to render pages if no layout is specified.
+	DefaultLayout string `mapstructure:"default_layout"`
+	// Reload enables template hot reload.
+	Reload bool `mapstructure:"reload"`
+}
+
+// Validate templates configuration section.
+func (c *Templates) Validate(valid *validation.Validate) error {
+	return valid.Struct(c)
+}
+
+// Bind templates configuration section.
+func (c *Templates) Bind(prefix string, v *viper.Viper) {
+	v.SetDefault(prefix+".path", "templates")
+	v.SetDefault(prefix+".reload", false)
+
+	_ = v.BindEnv(prefix+".path", "TEMPLATES_PATH")
+	_ = v.BindEnv(prefix+".default_layout", "TEMPLATES_DEFAULT_LAYOUT")
+	_ = v.BindEnv(prefix+".reload", "TEMPLATES_RELOAD")
+}
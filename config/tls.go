@@ -0,0 +1,172 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"azugo.io/core/cert"
+	"azugo.io/core/validation"
+
+	"github.com/spf13/viper"
+)
+
+// TLSCertificate is a certificate served for the host names.
+type TLSCertificate struct {
+	Name        string   `mapstructure:"name" validate:"required"`
+	Hosts       []string `mapstructure:"hosts" validate:"required,min=1"`
+	Certificate string   `mapstructure:"certificate" validate:"required,file"`
+	Key         string   `mapstructure:"key" validate:"omitempty,file"`
+	Password    string   `mapstructure:"password"`
+}
+
+// TLS is a TLS certificates configuration section.
+type TLS struct {
+	ReloadInterval  time.Duration    `mapstructure:"reload_interval" validate:"omitempty,min=0"`
+	KeyExportPolicy string           `mapstructure:"key_export_policy" validate:"omitempty,oneof=always encrypted-only never"`
+	Certificates    []TLSCertificate `mapstructure:"certificates" validate:"omitempty,dive"`
+}
+
+// Validate TLS configuration section.
+func (c *TLS) Validate(valid *validation.Validate) error {
+	return valid.Struct(c)
+}
+
+// Bind TLS configuration section.
+func (c *TLS) Bind(prefix string, v *viper.Viper) {
+	v.SetDefault(prefix+".reload_interval", time.Minute)
+
+	_ = v.BindEnv(prefix+".reload_interval", "TLS_RELOAD_INTERVAL")
+	_ = v.BindEnv(prefix+".key_export_policy", "TLS_KEY_EXPORT_POLICY")
+}
+
+// TLSProfile is a TLS connection configuration that can be used in application
+// configuration sections for servers and clients.
+type TLSProfile struct {
+	MinVersion         string   `mapstructure:"min_version" validate:"omitempty,oneof=1.2 1.3"`
+	CipherSuites       []string `mapstructure:"cipher_suites" validate:"omitempty,dive,required"`
+	Certificate        string   `mapstructure:"certificate" validate:"omitempty,file"`
+	Password           string   `mapstructure:"password"`
+	CABundle           string   `mapstructure:"ca_bundle" validate:"omitempty,file"`
+	ClientAuth         string   `mapstructure:"client_auth" validate:"omitempty,oneof=none request require verify-if-given require-and-verify"`
+	ClientCABundle     string   `mapstructure:"client_ca_bundle" validate:"omitempty,file"`
+	ServerName         string   `mapstructure:"server_name"`
+	InsecureSkipVerify bool     `mapstructure:"insecure_skip_verify"`
+}
+
+// tlsVersions are TLS versions allowed in TLS profile.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsClientAuth are client authentication policies allowed in TLS profile.
+var tlsClientAuth = map[string]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify-if-given":    tls.VerifyClientCertIfGiven,
+	"require-and-verify": tls.RequireAndVerifyClientCert,
+}
+
+// cipherSuite returns ID of the secure cipher suite by its name.
+func cipherSuite(name string) (uint16, bool) {
+	for _, s := range tls.CipherSuites() {
+		if s.Name == name {
+			return s.ID, true
+		}
+	}
+	return 0, false
+}
+
+// Validate TLS profile.
+func (c *TLSProfile) Validate(valid *validation.Validate) error {
+	if err := valid.Struct(c); err != nil {
+		return err
+	}
+	for i, name := range c.CipherSuites {
+		if _, ok := cipherSuite(name); !ok {
+			return &FieldError{
+				Path: fmt.Sprintf("cipher_suites[%d]", i),
+				Err:  fmt.Errorf("unsupported or insecure cipher suite %q", name),
+			}
+		}
+	}
+	if len(c.ClientCABundle) == 0 && tlsClientAuth[c.ClientAuth] >= tls.VerifyClientCertIfGiven {
+		return &FieldError{
+			Path: "client_ca_bundle",
+			Err:  errors.New("client CA bundle is required to verify client certificates"),
+		}
+	}
+	return nil
+}
+
+// Config returns TLS configuration from the TLS profile.
+//
+// Certificate file must contain PEM encoded certificate and private key. It is
+// presented by servers and used as client certificate by clients.
+func (c *TLSProfile) Config() (*tls.Config, error) {
+	//nolint:gosec
+	conf := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		ClientAuth:         tlsClientAuth[c.ClientAuth],
+	}
+	if len(c.MinVersion) != 0 {
+		v, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, &FieldError{Path: "min_version", Err: fmt.Errorf("unsupported TLS version %q", c.MinVersion)}
+		}
+		conf.MinVersion = v
+	}
+	for i, name := range c.CipherSuites {
+		id, ok := cipherSuite(name)
+		if !ok {
+			return nil, &FieldError{
+				Path: fmt.Sprintf("cipher_suites[%d]", i),
+				Err:  fmt.Errorf("unsupported or insecure cipher suite %q", name),
+			}
+		}
+		conf.CipherSuites = append(conf.CipherSuites, id)
+	}
+	if len(c.Certificate) != 0 {
+		var opts []cert.Option
+		if len(c.Password) != 0 {
+			opts = append(opts, cert.Password(c.Password))
+		}
+		crt, err := cert.ParseTLSCertificateFromFile(c.Certificate, opts...)
+		if err != nil {
+			return nil, &FieldError{Path: "certificate", Err: err}
+		}
+		conf.Certificates = []tls.Certificate{*crt}
+	}
+	if len(c.CABundle) != 0 {
+		pool, err := cert.LoadCertPoolFromFile(c.CABundle)
+		if err != nil {
+			return nil, &FieldError{Path: "ca_bundle", Err: err}
+		}
+		conf.RootCAs = pool
+	}
+	if len(c.ClientCABundle) != 0 {
+		// Client certificates are verified only against configured authorities.
+		buf, err := os.ReadFile(c.ClientCABundle)
+		if err != nil {
+			return nil, &FieldError{Path: "client_ca_bundle", Err: err}
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return nil, &FieldError{Path: "client_ca_bundle", Err: errors.New("no valid certificates found in CA bundle")}
+		}
+		conf.ClientCAs = pool
+	}
+	return conf, nil
+}
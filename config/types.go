@@ -0,0 +1,483 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time duration configuration value.
+//
+// In addition to units supported by time.ParseDuration, days ("d") and weeks ("w")
+// can be used, for example "1d12h" or "1h30m".
+type Duration time.Duration
+
+// ParseDuration parses human friendly duration string.
+func ParseDuration(s string) (Duration, error) {
+	s = strings.TrimSpace(s)
+	orig := s
+	neg := false
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	if len(s) == 0 {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+
+	var d time.Duration
+	for len(s) > 0 {
+		i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		j := strings.IndexFunc(s[i:], func(r rune) bool { return (r >= '0' && r <= '9') || r == '.' })
+		if j < 0 {
+			j = len(s) - i
+		}
+		num, unit := s[:i], s[i:i+j]
+		s = s[i+j:]
+
+		var part time.Duration
+		switch unit {
+		case "d", "w":
+			f, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+			mul := 24 * time.Hour
+			if unit == "w" {
+				mul *= 7
+			}
+			if f*float64(mul) > math.MaxInt64 {
+				return 0, fmt.Errorf("invalid duration %q: overflow", orig)
+			}
+			part = time.Duration(f * float64(mul))
+		default:
+			var err error
+			if part, err = time.ParseDuration(num + unit); err != nil {
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+		}
+		if d > math.MaxInt64-part {
+			return 0, fmt.Errorf("invalid duration %q: overflow", orig)
+		}
+		d += part
+	}
+	if neg {
+		d = -d
+	}
+	return Duration(d), nil
+}
+
+// String returns duration formatted without trailing zero units, for example "1h30m".
+func (d Duration) String() string {
+	s := time.Duration(d).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// UnmarshalJSON accepts duration string or number of nanoseconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		v, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid duration %s", data)
+		}
+		*d = Duration(v)
+		return nil
+	}
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return err
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// Size is a size in bytes configuration value.
+//
+// Decimal units (KB, MB, GB, TB, PB) are powers of 1000 and binary units
+// (KiB, MiB, GiB, TiB, PiB) are powers of 1024, for example "256MB" or "1.5GiB".
+// Units are case-insensitive and "B" suffix can be omitted.
+type Size int64
+
+// Size units.
+const (
+	Byte Size = 1
+
+	KB Size = 1000 * Byte
+	MB Size = 1000 * KB
+	GB Size = 1000 * MB
+	TB Size = 1000 * GB
+	PB Size = 1000 * TB
+
+	KiB Size = 1024 * Byte
+	MiB Size = 1024 * KiB
+	GiB Size = 1024 * MiB
+	TiB Size = 1024 * GiB
+	PiB Size = 1024 * TiB
+)
+
+var sizeUnits = map[string]Size{
+	"":    Byte,
+	"b":   Byte,
+	"k":   KB,
+	"kb":  KB,
+	"m":   MB,
+	"mb":  MB,
+	"g":   GB,
+	"gb":  GB,
+	"t":   TB,
+	"tb":  TB,
+	"p":   PB,
+	"pb":  PB,
+	"ki":  KiB,
+	"kib": KiB,
+	"mi":  MiB,
+	"mib": MiB,
+	"gi":  GiB,
+	"gib": GiB,
+	"ti":  TiB,
+	"tib": TiB,
+	"pi":  PiB,
+	"pib": PiB,
+}
+
+// ParseSize parses human friendly size string.
+func ParseSize(s string) (Size, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("invalid size %q", orig)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit", orig)
+	}
+	if n, err := strconv.ParseInt(s[:i], 10, 64); err == nil {
+		if n > math.MaxInt64/int64(unit) {
+			return 0, fmt.Errorf("invalid size %q: overflow", orig)
+		}
+		return Size(n) * unit, nil
+	}
+	f, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", orig)
+	}
+	if f*float64(unit) >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: overflow", orig)
+	}
+	return Size(f * float64(unit)), nil
+}
+
+// String returns size formatted using the largest unit that represents it exactly.
+func (s Size) String() string {
+	if s == 0 {
+		return "0B"
+	}
+	for _, u := range []struct {
+		name string
+		size Size
+	}{
+		{"PiB", PiB}, {"PB", PB}, {"TiB", TiB}, {"TB", TB}, {"GiB", GiB},
+		{"GB", GB}, {"MiB", MiB}, {"MB", MB}, {"KiB", KiB}, {"KB", KB},
+	} {
+		if s%u.size == 0 {
+			return strconv.FormatInt(int64(s/u.size), 10) + u.name
+		}
+	}
+	return strconv.FormatInt(int64(s), 10) + "B"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Size) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Size) UnmarshalText(text []byte) error {
+	v, err := ParseSize(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// UnmarshalJSON accepts size string or number of bytes.
+func (s *Size) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		return s.UnmarshalText(data)
+	}
+	str, err := strconv.Unquote(string(data))
+	if err != nil {
+		return err
+	}
+	return s.UnmarshalText([]byte(str))
+}
+
+// cronField is a bit set of allowed values of the cron expression field.
+type cronField uint64
+
+func (f cronField) has(v int) bool {
+	return f&(1<<uint(v)) != 0
+}
+
+type cronBounds struct {
+	min, max int
+	names    []string
+}
+
+var (
+	cronMinutes = cronBounds{min: 0, max: 59}
+	cronHours   = cronBounds{min: 0, max: 23}
+	cronDays    = cronBounds{min: 1, max: 31}
+	cronMonths  = cronBounds{min: 1, max: 12, names: []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	cronWeek    = cronBounds{min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+func (b cronBounds) value(s string) (int, error) {
+	for i, n := range b.names {
+		if len(n) != 0 && strings.EqualFold(s, n) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < b.min || v > b.max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, b.min, b.max)
+	}
+	return v, nil
+}
+
+// parse parses cron field. Returns true if field matches all values.
+func (b cronBounds) parse(s string) (cronField, bool, error) {
+	var f cronField
+	all := false
+	for _, part := range strings.Split(s, ",") {
+		rng, step, hasStep := strings.Cut(part, "/")
+		n := 1
+		if hasStep {
+			var err error
+			if n, err = strconv.Atoi(step); err != nil || n <= 0 {
+				return 0, false, fmt.Errorf("invalid step %q", step)
+			}
+		}
+
+		lo, hi := b.min, b.max
+		switch {
+		case rng == "*" || rng == "?":
+			all = all || !hasStep
+		case strings.Contains(rng, "-"):
+			l, h, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = b.value(l); err != nil {
+				return 0, false, err
+			}
+			if hi, err = b.value(h); err != nil {
+				return 0, false, err
+			}
+			if lo > hi {
+				return 0, false, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			var err error
+			if lo, err = b.value(rng); err != nil {
+				return 0, false, err
+			}
+			if !hasStep {
+				hi = lo
+			}
+		}
+		for v := lo; v <= hi; v += n {
+			f |= 1 << uint(v)
+		}
+	}
+	return f, all, nil
+}
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow cronField
+	domAll, dowAll                bool
+}
+
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	if m, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields", expr)
+	}
+
+	s := &cronSchedule{}
+	var err error
+	for i, p := range []struct {
+		field  *cronField
+		all    *bool
+		bounds cronBounds
+	}{
+		{&s.minute, nil, cronMinutes},
+		{&s.hour, nil, cronHours},
+		{&s.dom, &s.domAll, cronDays},
+		{&s.month, nil, cronMonths},
+		{&s.dow, &s.dowAll, cronWeek},
+	} {
+		var all bool
+		if *p.field, all, err = p.bounds.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		if p.all != nil {
+			*p.all = all
+		}
+	}
+	// Sunday can be specified both as 0 and 7.
+	if s.dow.has(7) {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom, dow := s.dom.has(t.Day()), s.dow.has(int(t.Weekday()))
+	// If both day fields are restricted, day matches if either of them matches.
+	if !s.domAll && !s.dowAll {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Schedules that never match, for example 30th of February, are limited.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !s.month.has(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hour.has(t.Hour()) {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if !s.minute.has(t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Cron is a cron expression configuration value.
+//
+// Standard five field expressions (minute, hour, day of month, month, day of week)
+// with lists, ranges, steps and month and weekday names are supported, as well as
+// @yearly, @monthly, @weekly, @daily and @hourly macros.
+type Cron struct {
+	expr     string
+	schedule *cronSchedule
+}
+
+// ErrEmptyCron is returned when cron expression is empty.
+var ErrEmptyCron = errors.New("empty cron expression")
+
+// ParseCron parses cron expression.
+func ParseCron(expr string) (Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if len(expr) == 0 {
+		return Cron{}, ErrEmptyCron
+	}
+	s, err := parseCronSchedule(expr)
+	if err != nil {
+		return Cron{}, err
+	}
+	return Cron{expr: expr, schedule: s}, nil
+}
+
+// IsZero returns true if cron expression is not set.
+func (c Cron) IsZero() bool {
+	return c.schedule == nil
+}
+
+// Next returns next activation time after the given time in its location.
+//
+// Returns zero time if expression is not set or never matches.
+func (c Cron) Next(t time.Time) time.Time {
+	if c.schedule == nil {
+		return time.Time{}
+	}
+	return c.schedule.next(t)
+}
+
+// String returns cron expression.
+func (c Cron) String() string {
+	return c.expr
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Cron) MarshalText() ([]byte, error) {
+	return []byte(c.expr), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Empty expression unsets the value.
+func (c *Cron) UnmarshalText(text []byte) error {
+	if len(bytes.TrimSpace(text)) == 0 {
+		*c = Cron{}
+		return nil
+	}
+	v, err := ParseCron(string(text))
+	if err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	for s, want := range map[string]time.Duration{
+		"0":        0,
+		"1h30m":    90 * time.Minute,
+		"1d12h":    36 * time.Hour,
+		"2w":       14 * 24 * time.Hour,
+		"1.5d":     36 * time.Hour,
+		"-5m":      -5 * time.Minute,
+		" 250ms ":  250 * time.Millisecond,
+		"1h0m0.5s": time.Hour + 500*time.Millisecond,
+	} {
+		d, err := ParseDuration(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, Duration(want), d, s)
+	}
+	for _, s := range []string{"", "1", "h", "1y", "1d2", "-"} {
+		_, err := ParseDuration(s)
+		assert.Error(t, err, s)
+	}
+
+	assert.Equal(t, "1h30m", Duration(90*time.Minute).String())
+	assert.Equal(t, "36h", Duration(36*time.Hour).String())
+	assert.Equal(t, "1m5s", Duration(65*time.Second).String())
+	assert.Equal(t, "0s", Duration(0).String())
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]Size{
+		"0":       0,
+		"512":     512,
+		"1B":      1,
+		"256MB":   256 * MB,
+		"256 mib": 256 * MiB,
+		"1.5GiB":  GiB + 512*MiB,
+		"64k":     64 * KB,
+		"2Ti":     2 * TiB,
+	} {
+		v, err := ParseSize(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, v, s)
+	}
+	for _, s := range []string{"", "MB", "-1", "1XB", "10000PB"} {
+		_, err := ParseSize(s)
+		assert.Error(t, err, s)
+	}
+
+	assert.Equal(t, "256MiB", (256 * MiB).String())
+	assert.Equal(t, "3MB", (3 * MB).String())
+	assert.Equal(t, "1001B", Size(1001).String())
+	assert.Equal(t, "0B", Size(0).String())
+}
+
+func TestParseCron(t *testing.T) {
+	loc := time.UTC
+	from := time.Date(2024, time.January, 31, 10, 17, 30, 0, loc)
+
+	for expr, want := range map[string]time.Time{
+		"*/15 * * * *":       time.Date(2024, time.January, 31, 10, 30, 0, 0, loc),
+		"0 3 * * *":          time.Date(2024, time.February, 1, 3, 0, 0, 0, loc),
+		"30 9 * * mon-fri":   time.Date(2024, time.February, 1, 9, 30, 0, 0, loc),
+		"0 0 29 feb *":       time.Date(2024, time.February, 29, 0, 0, 0, 0, loc),
+		"0 12 1 * 7":         time.Date(2024, time.February, 1, 12, 0, 0, 0, loc),
+		"@hourly":            time.Date(2024, time.January, 31, 11, 0, 0, 0, loc),
+		"@weekly":            time.Date(2024, time.February, 4, 0, 0, 0, 0, loc),
+		"5,10 22-23/1 * * ?": time.Date(2024, time.January, 31, 22, 5, 0, 0, loc),
+		"0 0 1 1 *":          time.Date(2025, time.January, 1, 0, 0, 0, 0, loc),
+		"18-20 10 31 1 *":    time.Date(2024, time.January, 31, 10, 18, 0, 0, loc),
+	} {
+		c, err := ParseCron(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, want, c.Next(from), expr)
+		assert.Equal(t, expr, c.String())
+	}
+
+	c, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, c.Next(from).IsZero())
+	assert.True(t, Cron{}.Next(from).IsZero())
+	assert.True(t, Cron{}.IsZero())
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestTypesMarshaling(t *testing.T) {
+	type conf struct {
+		TTL      Duration `json:"ttl"`
+		MaxSize  Size     `json:"max_size"`
+		Schedule Cron     `json:"schedule"`
+	}
+
+	var c conf
+	require.NoError(t, json.Unmarshal([]byte(`{"ttl":"1d","max_size":"1GiB","schedule":"@daily"}`), &c))
+	assert.Equal(t, Duration(24*time.Hour), c.TTL)
+	assert.Equal(t, GiB, c.MaxSize)
+	assert.Equal(t, "@daily", c.Schedule.String())
+
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ttl":"24h","max_size":"1GiB","schedule":"@daily"}`, string(data))
+
+	require.NoError(t, json.Unmarshal([]byte(`{"ttl":1000000000,"max_size":1024,"schedule":""}`), &c))
+	assert.Equal(t, Duration(time.Second), c.TTL)
+	assert.Equal(t, KiB, c.MaxSize)
+	assert.True(t, c.Schedule.IsZero())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"schedule":"every day"}`), &c))
+}
+
+func TestLoadTypes(t *testing.T) {
+	t.Setenv("CACHE_TTL", "1d")
+	t.Setenv("CACHE_MAX_SIZE", "256MiB")
+
+	c := New()
+	require.NoError(t, c.Load(nil, c, ""))
+	assert.Equal(t, Duration(24*time.Hour), c.Cache.TTL)
+	assert.Equal(t, 256*MiB, c.Cache.MaxSize)
+	assert.Equal(t, Duration(30*time.Second), c.Warmup.Timeout)
+	assert.Equal(t, Duration(10*time.Second), c.Drain.StageTimeout)
+	assert.Equal(t, Duration(5*time.Second), c.Diagnostics.Timeout)
+	assert.Equal(t, Duration(14*24*time.Hour), c.Diagnostics.CertificateExpiry)
+}
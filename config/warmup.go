@@ -0,0 +1,31 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"time"
+
+	"azugo.io/core/validation"
+
+	"github.com/spf13/viper"
+)
+
+// Warmup is an application warmup phase configuration section.
+type Warmup struct {
+	// Timeout is a total time budget for all warmers.
+	Timeout Duration `mapstructure:"timeout" validate:"omitempty,min=0"`
+}
+
+// Validate warmup configuration section.
+func (c *Warmup) Validate(valid *validation.Validate) error {
+	return valid.Struct(c)
+}
+
+// Bind warmup configuration section.
+func (c *Warmup) Bind(prefix string, v *viper.Viper) {
+	v.SetDefault(prefix+".timeout", 30*time.Second)
+
+	_ = v.BindEnv(prefix+".timeout", "WARMUP_TIMEOUT")
+}
@@ -0,0 +1,293 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/instrumenter"
+)
+
+const (
+	InstrumentationDataLoaderBatch = "dataloader-batch"
+)
+
+// ErrNotFound is returned when batch function does not return value for the key.
+type ErrNotFound struct {
+	Key any
+}
+
+func (e ErrNotFound) Error() string {
+	return fmt.Sprintf("value for key %v not found", e.Key)
+}
+
+// BatchFunc loads values for the keys. Keys missing in the returned map are
+// reported as not found.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Source defines how values are loaded in batches and holds the second level
+// cache shared between requests.
+type Source[K comparable, V any] struct {
+	name  string
+	batch BatchFunc[K, V]
+	opts  *options
+	cache cache.CacheInstance[*V]
+}
+
+// New creates new data source with the batch function.
+//
+// If Cache option is set, values are cached in the cache instance with the
+// specified name.
+func New[K comparable, V any](name string, batch BatchFunc[K, V], opts ...Option) (*Source[K, V], error) {
+	opt := newOptions(opts...)
+	if opt.MaxBatch <= 0 {
+		opt.MaxBatch = 1
+	}
+
+	s := &Source[K, V]{
+		name:  name,
+		batch: batch,
+		opts:  opt,
+	}
+	if opt.Cache != nil {
+		i, err := cache.Create[*V](opt.Cache, name, opt.CacheOptions...)
+		if err != nil {
+			return nil, err
+		}
+		s.cache = i
+	}
+	return s, nil
+}
+
+// Loader returns loader for the request scope. If context has no request scope,
+// new loader is returned on each call.
+func (s *Source[K, V]) Loader(ctx context.Context) *Loader[K, V] {
+	sc := scopeFromContext(ctx)
+	if sc == nil {
+		return newLoader(s)
+	}
+	return sc.loader(s, func() any {
+		return newLoader(s)
+	}).(*Loader[K, V])
+}
+
+// Load returns value by the key using request scoped loader.
+func (s *Source[K, V]) Load(ctx context.Context, key K) (V, error) {
+	return s.Loader(ctx).Load(ctx, key)
+}
+
+// LoadMany returns found values by the keys using request scoped loader.
+func (s *Source[K, V]) LoadMany(ctx context.Context, keys ...K) (map[K]V, error) {
+	return s.Loader(ctx).LoadMany(ctx, keys...)
+}
+
+// Invalidate removes value from the second level cache and the request scoped loader.
+func (s *Source[K, V]) Invalidate(ctx context.Context, key K) error {
+	if sc := scopeFromContext(ctx); sc != nil {
+		if l, ok := sc.get(s).(*Loader[K, V]); ok {
+			l.Clear(key)
+		}
+	}
+	if s.cache == nil {
+		return nil
+	}
+	return s.cache.Delete(ctx, s.key(key))
+}
+
+func (s *Source[K, V]) key(key K) string {
+	return fmt.Sprint(key)
+}
+
+type result[V any] struct {
+	value V
+	err   error
+	done  chan struct{}
+}
+
+func (r *result[V]) wait(ctx context.Context) (V, error) {
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var v V
+		return v, ctx.Err()
+	}
+}
+
+type batch[K comparable, V any] struct {
+	ctx     context.Context
+	keys    []K
+	results map[K]*result[V]
+}
+
+// Loader batches and memoizes loads of the values for a single request.
+type Loader[K comparable, V any] struct {
+	source *Source[K, V]
+
+	lock  sync.Mutex
+	memo  map[K]*result[V]
+	batch *batch[K, V]
+}
+
+func newLoader[K comparable, V any](s *Source[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		source: s,
+		memo:   make(map[K]*result[V]),
+	}
+}
+
+// enqueue returns memoized result for the key or adds key to the pending batch.
+func (l *Loader[K, V]) enqueue(ctx context.Context, key K) *result[V] {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if r, ok := l.memo[key]; ok {
+		return r
+	}
+	r := &result[V]{done: make(chan struct{})}
+	l.memo[key] = r
+
+	if l.batch == nil {
+		b := &batch[K, V]{ctx: ctx, results: make(map[K]*result[V])}
+		l.batch = b
+		time.AfterFunc(l.source.opts.Wait, func() {
+			l.dispatch(b)
+		})
+	}
+	b := l.batch
+	b.keys = append(b.keys, key)
+	b.results[key] = r
+	if len(b.keys) >= l.source.opts.MaxBatch {
+		l.batch = nil
+		go l.run(b)
+	}
+	return r
+}
+
+// dispatch loads the batch if it is still pending.
+func (l *Loader[K, V]) dispatch(b *batch[K, V]) {
+	l.lock.Lock()
+	if l.batch != b {
+		l.lock.Unlock()
+		return
+	}
+	l.batch = nil
+	l.lock.Unlock()
+
+	l.run(b)
+}
+
+func (l *Loader[K, V]) run(b *batch[K, V]) {
+	ctx := b.ctx
+	s := l.source
+
+	keys := b.keys
+	if s.cache != nil {
+		keys = make([]K, 0, len(b.keys))
+		for _, k := range b.keys {
+			if v, err := s.cache.Get(ctx, s.key(k)); err == nil && v != nil {
+				r := b.results[k]
+				r.value = *v
+				close(r.done)
+				continue
+			}
+			keys = append(keys, k)
+		}
+		if len(keys) == 0 {
+			return
+		}
+	}
+
+	finish := s.opts.Instrumenter.Observe(ctx, InstrumentationDataLoaderBatch, s.name,
+		instrumenter.Label{Name: "size", Value: fmt.Sprint(len(keys))})
+	values, err := l.call(ctx, keys)
+	finish(err)
+
+	if err != nil {
+		// Errors are not memoized so that the load can be retried.
+		l.lock.Lock()
+		for _, k := range keys {
+			if l.memo[k] == b.results[k] {
+				delete(l.memo, k)
+			}
+		}
+		l.lock.Unlock()
+	}
+
+	for _, k := range keys {
+		r := b.results[k]
+		switch v, ok := values[k]; {
+		case err != nil:
+			r.err = err
+		case !ok:
+			r.err = ErrNotFound{Key: k}
+		default:
+			r.value = v
+			if s.cache != nil {
+				_ = s.cache.Set(ctx, s.key(k), &v)
+			}
+		}
+		close(r.done)
+	}
+}
+
+func (l *Loader[K, V]) call(ctx context.Context, keys []K) (values map[K]V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("data loader %s panic: %v", l.source.name, r)
+		}
+	}()
+	return l.source.batch(ctx, keys)
+}
+
+// Load returns value by the key. Loads of the keys requested at the same time
+// are batched and results are memoized for the loader lifetime.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	return l.enqueue(ctx, key).wait(ctx)
+}
+
+// LoadMany returns found values by the keys in a single batch. Keys that are
+// not found are omitted from the result.
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys ...K) (map[K]V, error) {
+	results := make(map[K]*result[V], len(keys))
+	for _, k := range keys {
+		results[k] = l.enqueue(ctx, k)
+	}
+
+	values := make(map[K]V, len(keys))
+	for k, r := range results {
+		v, err := r.wait(ctx)
+		if _, ok := err.(ErrNotFound); ok {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[k] = v
+	}
+	return values, nil
+}
+
+// Prime stores value for the key in the loader without loading it.
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	r := &result[V]{value: value, done: make(chan struct{})}
+	close(r.done)
+	l.memo[key] = r
+}
+
+// Clear removes memoized value for the key from the loader.
+func (l *Loader[K, V]) Clear(key K) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	delete(l.memo, key)
+}
@@ -0,0 +1,141 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"azugo.io/core/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUser struct {
+	ID   int
+	Name string
+}
+
+type testBatch struct {
+	lock    sync.Mutex
+	batches [][]int
+	fail    bool
+}
+
+func (b *testBatch) load(ctx context.Context, keys []int) (map[int]testUser, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	sorted := append([]int{}, keys...)
+	sort.Ints(sorted)
+	b.batches = append(b.batches, sorted)
+	if b.fail {
+		return nil, errors.New("database unavailable")
+	}
+	users := make(map[int]testUser, len(keys))
+	for _, k := range keys {
+		if k > 0 {
+			users[k] = testUser{ID: k, Name: "user"}
+		}
+	}
+	return users, nil
+}
+
+func TestLoaderBatching(t *testing.T) {
+	b := &testBatch{}
+	s, err := New("users", b.load)
+	require.NoError(t, err)
+
+	ctx := WithScope(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			u, err := s.Load(ctx, id%5+1)
+			assert.NoError(t, err)
+			assert.Equal(t, id%5+1, u.ID)
+		}(i)
+	}
+	wg.Wait()
+
+	require.Len(t, b.batches, 1)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, b.batches[0])
+
+	// Values are memoized in the request scope.
+	_, err = s.Load(ctx, 3)
+	require.NoError(t, err)
+	assert.Len(t, b.batches, 1)
+
+	_, err = s.Load(ctx, -1)
+	assert.ErrorIs(t, err, ErrNotFound{Key: -1})
+
+	// New scope loads values again.
+	_, err = s.Load(WithScope(context.Background()), 3)
+	require.NoError(t, err)
+	assert.Len(t, b.batches, 3)
+}
+
+func TestLoaderMaxBatch(t *testing.T) {
+	b := &testBatch{}
+	s, err := New("users", b.load, MaxBatch(2))
+	require.NoError(t, err)
+
+	users, err := s.LoadMany(WithScope(context.Background()), 1, 2, 3, 0)
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+	assert.Len(t, b.batches, 2)
+}
+
+func TestLoaderErrorNotMemoized(t *testing.T) {
+	b := &testBatch{fail: true}
+	s, err := New("users", b.load)
+	require.NoError(t, err)
+
+	l := s.Loader(context.Background())
+	_, err = l.Load(context.Background(), 1)
+	assert.EqualError(t, err, "database unavailable")
+
+	b.fail = false
+	u, err := l.Load(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, u.ID)
+
+	l.Prime(2, testUser{ID: 2, Name: "primed"})
+	u, err = l.Load(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, "primed", u.Name)
+	assert.Len(t, b.batches, 2)
+}
+
+func TestLoaderSecondLevelCache(t *testing.T) {
+	c := cache.New(cache.MemoryCache)
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(c.Close)
+
+	b := &testBatch{}
+	s, err := New("users", b.load, Cache{c})
+	require.NoError(t, err)
+
+	var loaded int
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		users, err := s.LoadMany(r.Context(), 1, 2)
+		assert.NoError(t, err)
+		loaded += len(users)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, 4, loaded)
+	assert.Len(t, b.batches, 1, "second request must be served from cache")
+
+	require.NoError(t, s.Invalidate(context.Background(), 1))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Len(t, b.batches, 2)
+	assert.Equal(t, []int{1}, b.batches[1])
+}
@@ -0,0 +1,69 @@
+package dataloader
+
+import (
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/instrumenter"
+)
+
+type options struct {
+	Wait         time.Duration
+	MaxBatch     int
+	Cache        *cache.Cache
+	CacheOptions []cache.CacheOption
+	Instrumenter instrumenter.Instrumenter
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Wait:     time.Millisecond,
+		MaxBatch: 100,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	return opt
+}
+
+// Option for the data loader.
+type Option interface {
+	apply(*options)
+}
+
+// Wait is a time to collect keys before the batch is loaded. Defaults to 1 millisecond.
+type Wait time.Duration
+
+func (w Wait) apply(o *options) {
+	o.Wait = time.Duration(w)
+}
+
+// MaxBatch is a maximum number of keys loaded in a single batch. Defaults to 100.
+type MaxBatch int
+
+func (m MaxBatch) apply(o *options) {
+	o.MaxBatch = int(m)
+}
+
+// Cache to use as a second level cache shared between requests.
+type Cache struct {
+	*cache.Cache
+}
+
+func (c Cache) apply(o *options) {
+	o.Cache = c.Cache
+}
+
+// CacheOptions are options for the second level cache instance.
+type CacheOptions []cache.CacheOption
+
+func (c CacheOptions) apply(o *options) {
+	o.CacheOptions = append(o.CacheOptions, c...)
+}
+
+// Instrumenter to observe batch loads.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
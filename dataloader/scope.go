@@ -0,0 +1,57 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dataloader
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type scopeKey struct{}
+
+type scope struct {
+	lock    sync.Mutex
+	loaders map[any]any
+}
+
+func scopeFromContext(ctx context.Context) *scope {
+	s, _ := ctx.Value(scopeKey{}).(*scope)
+	return s
+}
+
+func (s *scope) get(source any) any {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.loaders[source]
+}
+
+func (s *scope) loader(source any, create func() any) any {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	l, ok := s.loaders[source]
+	if !ok {
+		l = create()
+		s.loaders[source] = l
+	}
+	return l
+}
+
+// WithScope returns context with new loader scope. Loaders of all sources
+// are created once per scope and memoize loaded values for the scope lifetime.
+func WithScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{
+		loaders: make(map[any]any),
+	})
+}
+
+// Middleware creates new loader scope for each HTTP request.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithScope(r.Context())))
+	})
+}
@@ -0,0 +1,38 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"azugo.io/core/degrade"
+)
+
+func (a *App) initDegradation() {
+	a.degradelock.Lock()
+	defer a.degradelock.Unlock()
+
+	if a.degrade != nil {
+		return
+	}
+
+	// Engine without policies can not fail to be created.
+	a.degrade, _ = degrade.New(
+		degrade.Instrumenter(a.Instrumenter()),
+		degrade.Logger{Logger: a.Log().Named("degradation")},
+	)
+	if a.cache != nil {
+		a.degrade.Watch("cache", degrade.PingCheck(a.cache), 0)
+	}
+	_ = a.AddTask(a.degrade)
+}
+
+// Degradation returns graceful degradation policy engine.
+//
+// Subsystems report their level to the engine and fallback policies declared
+// by the application are toggled automatically. Application cache is watched
+// under the "cache" subsystem name. Engine is added to the application tasks.
+func (a *App) Degradation() *degrade.Engine {
+	a.initDegradation()
+	return a.degrade
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package degrade
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"azugo.io/core/cert"
+)
+
+// Check returns current subsystem level and reason of the degradation.
+type Check func(ctx context.Context) (Level, string)
+
+// Pinger is a subsystem that can be pinged to check availability.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingCheck reports subsystem as down while ping fails.
+func PingCheck(p Pinger) Check {
+	return func(ctx context.Context) (Level, string) {
+		if err := p.Ping(ctx); err != nil {
+			return Down, err.Error()
+		}
+		return Healthy, ""
+	}
+}
+
+// CertificateCheck reports certificate as degraded when it expires in less than
+// the threshold and as down when it has expired or can not be loaded.
+func CertificateCheck(source cert.CertificateSource, threshold time.Duration) Check {
+	return func(ctx context.Context) (Level, string) {
+		c, err := source(ctx)
+		if err != nil {
+			return Down, err.Error()
+		}
+		left := time.Until(c.NotAfter)
+		if left <= 0 {
+			return Down, "certificate has expired"
+		}
+		if left < threshold {
+			return Degraded, fmt.Sprintf("certificate expires in %s", left.Truncate(time.Second))
+		}
+		return Healthy, ""
+	}
+}
+
+// LagCheck reports subsystem as degraded or down when measured lag (for example
+// queue consumer lag) exceeds the thresholds. Zero threshold is not checked.
+func LagCheck(measure func(ctx context.Context) (time.Duration, error), degraded, down time.Duration) Check {
+	return func(ctx context.Context) (Level, string) {
+		lag, err := measure(ctx)
+		if err != nil {
+			return Down, err.Error()
+		}
+		if down > 0 && lag >= down {
+			return Down, fmt.Sprintf("lagging by %s", lag)
+		}
+		if degraded > 0 && lag >= degraded {
+			return Degraded, fmt.Sprintf("lagging by %s", lag)
+		}
+		return Healthy, ""
+	}
+}
+
+// Watch runs subsystem check periodically and reports its level.
+//
+// Zero interval uses engine default interval. Checks are run only while
+// engine is started.
+func (e *Engine) Watch(name string, check Check, interval time.Duration) {
+	if interval <= 0 {
+		interval = e.interval
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.seq++
+	w := watch{id: e.seq, check: check, interval: interval}
+	e.watches[name] = w
+	if e.ctx != nil {
+		e.run(name, w)
+	}
+}
+
+// run starts check loop for the watched subsystem.
+//
+// Must be called with write lock held.
+func (e *Engine) run(name string, w watch) {
+	ctx := e.ctx
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		t := time.NewTicker(w.interval)
+		defer t.Stop()
+
+		for {
+			e.probe(ctx, name, w)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			// Stop if watch was replaced.
+			e.lock.RLock()
+			cur, ok := e.watches[name]
+			e.lock.RUnlock()
+			if !ok || cur.id != w.id {
+				return
+			}
+		}
+	}()
+}
+
+func (e *Engine) probe(ctx context.Context, name string, w watch) {
+	cctx, cancel := context.WithTimeout(ctx, w.interval)
+	defer cancel()
+
+	level, reason := w.check(cctx)
+	if ctx.Err() != nil {
+		return
+	}
+	e.Report(ctx, name, level, reason)
+}
+
+// Name returns task name.
+func (e *Engine) Name() string {
+	return "degradation"
+}
+
+// Start running watched subsystem checks.
+func (e *Engine) Start(ctx context.Context) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.ctx != nil {
+		return nil
+	}
+	e.ctx, e.stop = context.WithCancel(ctx)
+	for name, w := range e.watches {
+		e.run(name, w)
+	}
+	return nil
+}
+
+// Stop running watched subsystem checks.
+func (e *Engine) Stop() {
+	e.lock.Lock()
+	if e.ctx == nil {
+		e.lock.Unlock()
+		return
+	}
+	e.stop()
+	e.ctx, e.stop = nil, nil
+	e.lock.Unlock()
+
+	e.wg.Wait()
+}
@@ -0,0 +1,545 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package degrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// InstrumentationDegradation is an instrumentation operation for subsystem level changes.
+	InstrumentationDegradation = "degradation"
+	// InstrumentationFallback is an instrumentation operation for fallback policy activation changes.
+	InstrumentationFallback = "degradation-fallback"
+	// InstrumentationShed is an instrumentation operation for requests rejected by load shedding.
+	InstrumentationShed = "degradation-shed"
+)
+
+const (
+	// DefaultInterval is a default interval to run watched subsystem checks.
+	DefaultInterval = 15 * time.Second
+	// DefaultRetryAfter is a default duration returned in Retry-After header of shed requests.
+	DefaultRetryAfter = 30 * time.Second
+)
+
+// ErrInvalidPolicy is returned when declared fallback policy is not valid.
+var ErrInvalidPolicy = errors.New("degrade: invalid policy")
+
+// Level of the subsystem health.
+type Level int
+
+const (
+	// Healthy subsystem works as expected.
+	Healthy Level = iota
+	// Degraded subsystem works with reduced capacity or is about to fail.
+	Degraded
+	// Down subsystem is not available.
+	Down
+)
+
+// String returns level name.
+func (l Level) String() string {
+	switch l {
+	case Healthy:
+		return "healthy"
+	case Degraded:
+		return "degraded"
+	case Down:
+		return "down"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler interface.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface.
+func (l *Level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "healthy":
+		*l = Healthy
+	case "degraded":
+		*l = Degraded
+	case "down":
+		*l = Down
+	default:
+		return fmt.Errorf("degrade: unknown level %q", string(text))
+	}
+	return nil
+}
+
+// Signal is a last reported state of the subsystem.
+type Signal struct {
+	// Name of the subsystem (for example "cache" or "queue:orders").
+	Name string `json:"name"`
+	// Level of the subsystem health.
+	Level Level `json:"level"`
+	// Reason of the degradation.
+	Reason string `json:"reason,omitempty"`
+	// Since is a time when subsystem changed to the current level.
+	Since time.Time `json:"since"`
+}
+
+// Action is a fallback behavior applied while policy is active.
+type Action string
+
+const (
+	// ServeStale allows serving stale data for the feature instead of failing.
+	ServeStale Action = "serve-stale"
+	// DisableFeature turns off the feature.
+	DisableFeature Action = "disable-feature"
+	// ShedLoad rejects a ratio of incoming requests.
+	ShedLoad Action = "shed-load"
+)
+
+// Policy declares fallback behavior to apply while subsystems are degraded.
+type Policy struct {
+	// Name of the policy.
+	Name string
+	// Signals are subsystem names that activate the policy. Names can contain
+	// shell file name patterns (for example "queue:*").
+	Signals []string
+	// Level is a minimal subsystem level to activate the policy. Defaults to Degraded.
+	Level Level
+	// Action to apply while policy is active.
+	Action Action
+	// Feature name for ServeStale and DisableFeature actions.
+	Feature string
+	// ShedRatio is a ratio in range 0 to 1 of requests to reject for
+	// ShedLoad action. Defaults to 1.
+	ShedRatio float64
+}
+
+func (p Policy) validate() error {
+	if len(p.Name) == 0 {
+		return fmt.Errorf("%w: name is required", ErrInvalidPolicy)
+	}
+	if len(p.Signals) == 0 {
+		return fmt.Errorf("%w %q: signals are required", ErrInvalidPolicy, p.Name)
+	}
+	for _, s := range p.Signals {
+		if _, err := path.Match(s, ""); err != nil {
+			return fmt.Errorf("%w %q: signal %q: %v", ErrInvalidPolicy, p.Name, s, err)
+		}
+	}
+	switch p.Action {
+	case ServeStale, DisableFeature:
+		if len(p.Feature) == 0 {
+			return fmt.Errorf("%w %q: feature is required", ErrInvalidPolicy, p.Name)
+		}
+	case ShedLoad:
+		if p.ShedRatio < 0 || p.ShedRatio > 1 {
+			return fmt.Errorf("%w %q: shed ratio must be in range 0 to 1", ErrInvalidPolicy, p.Name)
+		}
+	default:
+		return fmt.Errorf("%w %q: unknown action %q", ErrInvalidPolicy, p.Name, p.Action)
+	}
+	if p.Level < Healthy || p.Level > Down {
+		return fmt.Errorf("%w %q: unknown level %d", ErrInvalidPolicy, p.Name, int(p.Level))
+	}
+	return nil
+}
+
+func (p Policy) matches(s Signal) bool {
+	level := p.Level
+	if level == Healthy {
+		level = Degraded
+	}
+	if s.Level < level {
+		return false
+	}
+	for _, pattern := range p.Signals {
+		if ok, _ := path.Match(pattern, s.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is emitted when fallback policy is activated or deactivated.
+type Event struct {
+	// Policy that changed.
+	Policy Policy
+	// Active is true if policy was activated.
+	Active bool
+	// Signals that activated the policy.
+	Signals []Signal
+	// Time of the change.
+	Time time.Time
+}
+
+type watch struct {
+	id       uint64
+	check    Check
+	interval time.Duration
+}
+
+// Engine tracks subsystem degradation signals and toggles declared
+// fallback policies automatically.
+//
+// Engine implements core.Tasker interface to run watched subsystem checks.
+type Engine struct {
+	instrumenter instrumenter.Instrumenter
+	logger       *zap.Logger
+	interval     time.Duration
+	retryAfter   time.Duration
+
+	lock     sync.RWMutex
+	policies []Policy
+	signals  map[string]Signal
+	active   map[string][]Signal
+	hooks    []func(Event)
+	watches  map[string]watch
+	seq      uint64
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+
+	rndlock sync.Mutex
+	rnd     *rand.Rand
+}
+
+// New creates new degradation policy engine.
+func New(opts ...Option) (*Engine, error) {
+	opt := newOptions(opts...)
+
+	seed := opt.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	e := &Engine{
+		instrumenter: opt.Instrumenter,
+		logger:       opt.Logger,
+		interval:     opt.Interval,
+		retryAfter:   opt.RetryAfter,
+		signals:      make(map[string]Signal),
+		active:       make(map[string][]Signal),
+		watches:      make(map[string]watch),
+		//nolint:gosec
+		rnd: rand.New(rand.NewSource(seed)),
+	}
+	if err := e.Declare(opt.Policies...); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Declare fallback policies.
+//
+// Policy with the same name as already declared one replaces it.
+func (e *Engine) Declare(policies ...Policy) error {
+	for _, p := range policies {
+		if err := p.validate(); err != nil {
+			return err
+		}
+	}
+
+	e.lock.Lock()
+	for _, p := range policies {
+		replaced := false
+		for i := range e.policies {
+			if e.policies[i].Name == p.Name {
+				e.policies[i] = p
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			e.policies = append(e.policies, p)
+		}
+	}
+	events := e.evaluate()
+	e.lock.Unlock()
+
+	e.emit(context.Background(), events)
+	return nil
+}
+
+// Policies returns declared fallback policies.
+func (e *Engine) Policies() []Policy {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return append([]Policy(nil), e.policies...)
+}
+
+// OnChange registers hook that is called when fallback policy is activated or deactivated.
+func (e *Engine) OnChange(fn func(Event)) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.hooks = append(e.hooks, fn)
+}
+
+// Report subsystem level.
+//
+// Fallback policies matching the subsystem are toggled accordingly.
+func (e *Engine) Report(ctx context.Context, name string, level Level, reason string) {
+	now := time.Now().UTC()
+
+	e.lock.Lock()
+	prev, ok := e.signals[name]
+	if ok && prev.Level == level && prev.Reason == reason {
+		e.lock.Unlock()
+		return
+	}
+	s := Signal{
+		Name:   name,
+		Level:  level,
+		Reason: reason,
+		Since:  now,
+	}
+	if ok && prev.Level == level {
+		s.Since = prev.Since
+	}
+	e.signals[name] = s
+	events := e.evaluate()
+	e.lock.Unlock()
+
+	if !ok && level == Healthy {
+		e.emit(ctx, events)
+		return
+	}
+
+	if !ok || prev.Level != level {
+		e.instrumenter.Observe(ctx, InstrumentationDegradation,
+			instrumenter.Label{Name: "subsystem", Value: name},
+			instrumenter.Label{Name: "level", Value: level.String()},
+		)(nil)
+
+		if level == Healthy {
+			e.logger.Info("subsystem recovered", zap.String("degrade.subsystem", name))
+		} else {
+			e.logger.Warn("subsystem degraded",
+				zap.String("degrade.subsystem", name),
+				zap.Stringer("degrade.level", level),
+				zap.String("degrade.reason", reason),
+			)
+		}
+	}
+
+	e.emit(ctx, events)
+}
+
+// Recover reports subsystem as healthy.
+func (e *Engine) Recover(ctx context.Context, name string) {
+	e.Report(ctx, name, Healthy, "")
+}
+
+// Level returns last reported subsystem level.
+//
+// Subsystems that have not reported are considered healthy.
+func (e *Engine) Level(name string) Level {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.signals[name].Level
+}
+
+// Signals returns last reported states of all subsystems ordered by name.
+func (e *Engine) Signals() []Signal {
+	e.lock.RLock()
+	signals := make([]Signal, 0, len(e.signals))
+	for _, s := range e.signals {
+		signals = append(signals, s)
+	}
+	e.lock.RUnlock()
+
+	sort.Slice(signals, func(i, j int) bool {
+		return signals[i].Name < signals[j].Name
+	})
+	return signals
+}
+
+// evaluate updates active policies and returns events for the changed ones.
+//
+// Must be called with write lock held.
+func (e *Engine) evaluate() []Event {
+	var events []Event
+
+	now := time.Now().UTC()
+	for _, p := range e.policies {
+		var matched []Signal
+		for _, s := range e.signals {
+			if p.matches(s) {
+				matched = append(matched, s)
+			}
+		}
+		sort.Slice(matched, func(i, j int) bool {
+			return matched[i].Name < matched[j].Name
+		})
+
+		_, wasActive := e.active[p.Name]
+		active := len(matched) > 0
+		if active {
+			e.active[p.Name] = matched
+		} else {
+			delete(e.active, p.Name)
+		}
+		if active != wasActive {
+			events = append(events, Event{
+				Policy:  p,
+				Active:  active,
+				Signals: matched,
+				Time:    now,
+			})
+		}
+	}
+
+	// Forget removed policies.
+	for name := range e.active {
+		found := false
+		for _, p := range e.policies {
+			if p.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			delete(e.active, name)
+		}
+	}
+
+	return events
+}
+
+func (e *Engine) emit(ctx context.Context, events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	e.lock.RLock()
+	hooks := append([]func(Event){}, e.hooks...)
+	e.lock.RUnlock()
+
+	for _, ev := range events {
+		e.instrumenter.Observe(ctx, InstrumentationFallback,
+			instrumenter.Label{Name: "policy", Value: ev.Policy.Name},
+			instrumenter.Label{Name: "action", Value: string(ev.Policy.Action)},
+			instrumenter.Label{Name: "active", Value: fmt.Sprint(ev.Active)},
+		)(nil)
+
+		if ev.Active {
+			e.logger.Warn("fallback policy activated",
+				zap.String("degrade.policy", ev.Policy.Name),
+				zap.String("degrade.action", string(ev.Policy.Action)),
+			)
+		} else {
+			e.logger.Info("fallback policy deactivated",
+				zap.String("degrade.policy", ev.Policy.Name),
+				zap.String("degrade.action", string(ev.Policy.Action)),
+			)
+		}
+
+		for _, fn := range hooks {
+			fn(ev)
+		}
+	}
+}
+
+// Active returns true if policy with the name is active.
+func (e *Engine) Active(policy string) bool {
+	if e == nil {
+		return false
+	}
+
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	_, ok := e.active[policy]
+	return ok
+}
+
+// action returns true if any active policy with the action applies to the feature.
+func (e *Engine) action(action Action, feature string) bool {
+	if e == nil {
+		return false
+	}
+
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	for _, p := range e.policies {
+		if p.Action != action || p.Feature != feature {
+			continue
+		}
+		if _, ok := e.active[p.Name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Stale returns true if serving stale data for the feature is allowed.
+func (e *Engine) Stale(feature string) bool {
+	return e.action(ServeStale, feature)
+}
+
+// FeatureEnabled returns false if feature is disabled by an active policy.
+func (e *Engine) FeatureEnabled(feature string) bool {
+	return !e.action(DisableFeature, feature)
+}
+
+// ShedRatio returns ratio of requests to reject.
+//
+// If multiple load shedding policies are active, the highest ratio is returned.
+func (e *Engine) ShedRatio() float64 {
+	if e == nil {
+		return 0
+	}
+
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	ratio := 0.0
+	for _, p := range e.policies {
+		if p.Action != ShedLoad {
+			continue
+		}
+		if _, ok := e.active[p.Name]; !ok {
+			continue
+		}
+		r := p.ShedRatio
+		if r == 0 {
+			r = 1
+		}
+		if r > ratio {
+			ratio = r
+		}
+	}
+	return ratio
+}
+
+// Shed returns true if the request should be rejected.
+func (e *Engine) Shed() bool {
+	p := e.ShedRatio()
+	if p <= 0 {
+		return false
+	}
+	if p >= 1 {
+		return true
+	}
+
+	e.rndlock.Lock()
+	defer e.rndlock.Unlock()
+
+	return e.rnd.Float64() < p
+}
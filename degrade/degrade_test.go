@@ -0,0 +1,250 @@
+package degrade
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pinger func(ctx context.Context) error
+
+func (p pinger) Ping(ctx context.Context) error {
+	return p(ctx)
+}
+
+func TestEngineDeclareValidation(t *testing.T) {
+	e, err := New()
+	require.NoError(t, err)
+
+	for _, p := range []Policy{
+		{Signals: []string{"cache"}, Action: ShedLoad},
+		{Name: "p", Action: ShedLoad},
+		{Name: "p", Signals: []string{"["}, Action: ShedLoad},
+		{Name: "p", Signals: []string{"cache"}, Action: ServeStale},
+		{Name: "p", Signals: []string{"cache"}, Action: ShedLoad, ShedRatio: 2},
+		{Name: "p", Signals: []string{"cache"}, Action: "retry"},
+		{Name: "p", Signals: []string{"cache"}, Action: ShedLoad, Level: 5},
+	} {
+		assert.ErrorIs(t, e.Declare(p), ErrInvalidPolicy, p)
+	}
+	assert.Empty(t, e.Policies())
+
+	_, err = New(Policies{{Name: "p"}})
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+}
+
+func TestEnginePolicies(t *testing.T) {
+	var (
+		lock   sync.Mutex
+		ops    []string
+		events []Event
+	)
+	instr := func(_ context.Context, op string, _ ...any) func(err error) {
+		lock.Lock()
+		ops = append(ops, op)
+		lock.Unlock()
+		return func(error) {}
+	}
+
+	e, err := New(Instrumenter(instr), Policies{
+		{Name: "stale-catalog", Signals: []string{"cache"}, Action: ServeStale, Feature: "catalog"},
+		{Name: "no-reports", Signals: []string{"queue:*"}, Level: Down, Action: DisableFeature, Feature: "reports"},
+		{Name: "shed", Signals: []string{"cache", "queue:*"}, Level: Down, Action: ShedLoad, ShedRatio: 0.5},
+	})
+	require.NoError(t, err)
+	e.OnChange(func(ev Event) {
+		events = append(events, ev)
+	})
+
+	assert.False(t, e.Stale("catalog"))
+	assert.True(t, e.FeatureEnabled("reports"))
+	assert.Zero(t, e.ShedRatio())
+
+	e.Report(context.Background(), "cache", Degraded, "slow")
+	assert.True(t, e.Active("stale-catalog"))
+	assert.True(t, e.Stale("catalog"))
+	assert.False(t, e.Active("shed"))
+	require.Len(t, events, 1)
+	assert.Equal(t, "stale-catalog", events[0].Policy.Name)
+	assert.True(t, events[0].Active)
+	require.Len(t, events[0].Signals, 1)
+	assert.Equal(t, "slow", events[0].Signals[0].Reason)
+
+	e.Report(context.Background(), "queue:orders", Degraded, "lag")
+	assert.True(t, e.FeatureEnabled("reports"))
+	e.Report(context.Background(), "queue:orders", Down, "unreachable")
+	assert.False(t, e.FeatureEnabled("reports"))
+	assert.Equal(t, 0.5, e.ShedRatio())
+	assert.Equal(t, Down, e.Level("queue:orders"))
+	assert.Len(t, events, 3)
+
+	e.Recover(context.Background(), "queue:orders")
+	assert.True(t, e.FeatureEnabled("reports"))
+	assert.Zero(t, e.ShedRatio())
+	assert.True(t, e.Stale("catalog"))
+	assert.Len(t, events, 5)
+	assert.False(t, events[4].Active)
+
+	e.Recover(context.Background(), "cache")
+	assert.False(t, e.Stale("catalog"))
+
+	signals := e.Signals()
+	require.Len(t, signals, 2)
+	assert.Equal(t, "cache", signals[0].Name)
+	assert.Equal(t, Healthy, signals[1].Level)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Contains(t, ops, InstrumentationDegradation)
+	assert.Contains(t, ops, InstrumentationFallback)
+}
+
+func TestEngineDeclareActivates(t *testing.T) {
+	e, err := New()
+	require.NoError(t, err)
+
+	e.Report(context.Background(), "search", Down, "timeout")
+	require.NoError(t, e.Declare(Policy{Name: "no-search", Signals: []string{"search"}, Action: DisableFeature, Feature: "search"}))
+	assert.False(t, e.FeatureEnabled("search"))
+
+	var nilEngine *Engine
+	assert.True(t, nilEngine.FeatureEnabled("search"))
+	assert.False(t, nilEngine.Stale("search"))
+	assert.False(t, nilEngine.Shed())
+}
+
+func TestEngineMiddleware(t *testing.T) {
+	e, err := New(Seed(1), RetryAfter(10*time.Second), Policies{
+		{Name: "shed", Signals: []string{"db"}, Action: ShedLoad},
+	})
+	require.NoError(t, err)
+
+	h := e.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), "/health")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	e.Report(context.Background(), "db", Degraded, "")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "10", rec.Header().Get("Retry-After"))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	require.NoError(t, e.Declare(Policy{Name: "shed", Signals: []string{"db"}, Action: ShedLoad, ShedRatio: 0.5}))
+	shed := 0
+	for i := 0; i < 1000; i++ {
+		if e.Shed() {
+			shed++
+		}
+	}
+	assert.InDelta(t, 500, shed, 100)
+}
+
+func TestEngineWatch(t *testing.T) {
+	var healthy sync.Mutex
+	fail := true
+
+	e, err := New(Instrumenter(instrumenter.NullInstrumenter), Policies{
+		{Name: "stale", Signals: []string{"cache"}, Action: ServeStale, Feature: "catalog"},
+	})
+	require.NoError(t, err)
+
+	changed := make(chan Event, 2)
+	e.OnChange(func(ev Event) {
+		changed <- ev
+	})
+	e.Watch("cache", PingCheck(pinger(func(context.Context) error {
+		healthy.Lock()
+		defer healthy.Unlock()
+		if fail {
+			return errors.New("connection refused")
+		}
+		return nil
+	})), 10*time.Millisecond)
+
+	require.NoError(t, e.Start(context.Background()))
+	defer e.Stop()
+
+	select {
+	case ev := <-changed:
+		assert.True(t, ev.Active)
+		assert.Equal(t, "connection refused", ev.Signals[0].Reason)
+	case <-time.After(time.Second):
+		t.Fatal("policy was not activated")
+	}
+
+	healthy.Lock()
+	fail = false
+	healthy.Unlock()
+
+	select {
+	case ev := <-changed:
+		assert.False(t, ev.Active)
+	case <-time.After(time.Second):
+		t.Fatal("policy was not deactivated")
+	}
+
+	e.Stop()
+	assert.Equal(t, Healthy, e.Level("cache"))
+}
+
+func TestChecks(t *testing.T) {
+	ctx := context.Background()
+
+	level, _ := CertificateCheck(func(context.Context) (*x509.Certificate, error) {
+		return &x509.Certificate{NotAfter: time.Now().Add(90 * 24 * time.Hour)}, nil
+	}, 14*24*time.Hour)(ctx)
+	assert.Equal(t, Healthy, level)
+
+	level, reason := CertificateCheck(func(context.Context) (*x509.Certificate, error) {
+		return &x509.Certificate{NotAfter: time.Now().Add(24 * time.Hour)}, nil
+	}, 14*24*time.Hour)(ctx)
+	assert.Equal(t, Degraded, level)
+	assert.Contains(t, reason, "certificate expires in")
+
+	level, _ = CertificateCheck(func(context.Context) (*x509.Certificate, error) {
+		return &x509.Certificate{NotAfter: time.Now().Add(-time.Hour)}, nil
+	}, time.Hour)(ctx)
+	assert.Equal(t, Down, level)
+
+	lag := func(d time.Duration) func(context.Context) (time.Duration, error) {
+		return func(context.Context) (time.Duration, error) {
+			return d, nil
+		}
+	}
+	level, _ = LagCheck(lag(time.Second), time.Minute, time.Hour)(ctx)
+	assert.Equal(t, Healthy, level)
+	level, reason = LagCheck(lag(2*time.Minute), time.Minute, time.Hour)(ctx)
+	assert.Equal(t, Degraded, level)
+	assert.Equal(t, "lagging by 2m0s", reason)
+	level, _ = LagCheck(lag(2*time.Hour), time.Minute, time.Hour)(ctx)
+	assert.Equal(t, Down, level)
+}
+
+func TestLevelText(t *testing.T) {
+	var l Level
+	require.NoError(t, l.UnmarshalText([]byte("down")))
+	assert.Equal(t, Down, l)
+	b, err := Degraded.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "degraded", string(b))
+	assert.Error(t, l.UnmarshalText([]byte("broken")))
+}
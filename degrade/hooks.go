@@ -0,0 +1,53 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package degrade
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"azugo.io/core/instrumenter"
+)
+
+// Middleware responds with 503 Service Unavailable to a ratio of requests while
+// load shedding policy is active, except for requests to the paths with allowed
+// prefixes (for example health checks).
+func (e *Engine) Middleware(next http.Handler, allowed ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range allowed {
+			if strings.HasPrefix(r.URL.Path, p) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if !e.Shed() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		e.instrumenter.Observe(r.Context(), InstrumentationShed,
+			instrumenter.Label{Name: "path", Value: r.URL.Path},
+		)(nil)
+
+		if e.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(e.retryAfter.Seconds()))))
+		}
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	})
+}
+
+// Run wraps task function to skip execution while feature is disabled by
+// an active policy.
+func (e *Engine) Run(feature string, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if !e.FeatureEnabled(feature) {
+			return nil
+		}
+		return fn(ctx)
+	}
+}
@@ -0,0 +1,83 @@
+package degrade
+
+import (
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	Policies     []Policy
+	Interval     time.Duration
+	RetryAfter   time.Duration
+	Seed         int64
+	Instrumenter instrumenter.Instrumenter
+	Logger       *zap.Logger
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Interval:   DefaultInterval,
+		RetryAfter: DefaultRetryAfter,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return opt
+}
+
+// Option for the degradation policy engine.
+type Option interface {
+	apply(*options)
+}
+
+// Policies are fallback policies to declare.
+type Policies []Policy
+
+func (p Policies) apply(o *options) {
+	o.Policies = append(o.Policies, p...)
+}
+
+// Interval is a default interval to run watched subsystem checks.
+type Interval time.Duration
+
+func (i Interval) apply(o *options) {
+	o.Interval = time.Duration(i)
+}
+
+// RetryAfter is a duration returned in Retry-After header of shed requests.
+type RetryAfter time.Duration
+
+func (r RetryAfter) apply(o *options) {
+	o.RetryAfter = time.Duration(r)
+}
+
+// Seed for the random number generator used to shed load.
+//
+// Zero uses random seed.
+type Seed int64
+
+func (s Seed) apply(o *options) {
+	o.Seed = int64(s)
+}
+
+// Instrumenter to observe subsystem level and fallback changes.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
+
+// Logger to log subsystem level and fallback changes.
+type Logger struct {
+	*zap.Logger
+}
+
+func (l Logger) apply(o *options) {
+	o.Logger = l.Logger
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/diagnostics"
+	"azugo.io/core/slo"
+)
+
+func (a *App) initDiagnostics() {
+	a.tlslock.Lock()
+	certs := a.certificates
+	a.tlslock.Unlock()
+
+	a.slolock.Lock()
+	tracker := a.slo
+	a.slolock.Unlock()
+
+	a.diaglock.Lock()
+	defer a.diaglock.Unlock()
+
+	if a.diagnostics != nil {
+		return
+	}
+
+	conf := a.Config().Diagnostics
+	a.diagnostics = diagnostics.New(
+		diagnostics.Timeout(time.Duration(conf.Timeout)),
+		diagnostics.Instrumenter(a.Instrumenter()),
+	)
+	a.diagnostics.Register("runtime", diagnostics.RuntimeCheck(conf.MaxGoroutines))
+	if a.cache != nil {
+		a.diagnostics.Register("cache", cacheCheck(a.cache))
+		a.diagnostics.Register("cache-latency", diagnostics.LatencyCheck(a.cacheLatency, time.Duration(conf.CacheLatency)))
+	}
+	if certs != nil {
+		a.diagnostics.Register("certificates", diagnostics.CertificateCheck(certs, time.Duration(conf.CertificateExpiry)))
+	}
+	if tracker != nil {
+		a.diagnostics.Register("slo", slo.Check(tracker))
+	}
+}
+
+// registerDiagnostic registers check if diagnostics are used.
+func (a *App) registerDiagnostic(name string, check diagnostics.Check) {
+	a.diaglock.Lock()
+	defer a.diaglock.Unlock()
+
+	if a.diagnostics != nil {
+		a.diagnostics.Register(name, check)
+	}
+}
+
+// cacheCheck reports cache availability and Redis server statistics.
+func cacheCheck(c *cache.Cache) diagnostics.Check {
+	ping := diagnostics.PingCheck(c)
+	return func(ctx context.Context) diagnostics.Result {
+		res := ping(ctx)
+		if res.Status != diagnostics.OK {
+			return res
+		}
+		info, err := c.Info(ctx)
+		switch {
+		case errors.Is(err, cache.ErrNotSupported):
+		case err != nil:
+			res.Status = diagnostics.Warning
+			res.Message = "failed to get Redis info: " + err.Error()
+		default:
+			res.Details["redis"] = info
+		}
+		return res
+	}
+}
+
+// AddDiagnostic adds self-check to the application diagnostics report.
+//
+// Check with the same name replaces the previous one.
+func (a *App) AddDiagnostic(name string, check diagnostics.Check) {
+	a.initDiagnostics()
+	a.diagnostics.Register(name, check)
+}
+
+// Diagnostics runs self-checks of all application components and returns
+// aggregated report for the incident triage.
+//
+// Go runtime statistics are always reported. Application cache availability,
+// Redis server statistics and cache operation latency percentiles are reported
+// under the "cache" and "cache-latency" names and TLS certificate chains under
+// the "certificates" name and dependency service level objectives under the
+// "slo" name when these components are used.
+func (a *App) Diagnostics(ctx context.Context) diagnostics.Report {
+	a.initDiagnostics()
+	return a.diagnostics.Run(ctx)
+}
+
+// DiagnosticsHandler returns HTTP handler that responds with the application
+// diagnostics report in JSON format.
+//
+// Report exposes internal details of the application so handler must only be
+// served on the administrative endpoint.
+func (a *App) DiagnosticsHandler() http.Handler {
+	a.initDiagnostics()
+	return diagnostics.Handler(a.diagnostics)
+}
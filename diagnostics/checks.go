@@ -0,0 +1,206 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package diagnostics
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// DefaultExpiryWarning is a default duration before certificate expiration to
+// report warning.
+const DefaultExpiryWarning = 14 * 24 * time.Hour
+
+// Pinger is a component that can be pinged to check availability.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingCheck reports component as critical while ping fails and includes ping
+// latency in the details.
+func PingCheck(p Pinger) Check {
+	return func(ctx context.Context) Result {
+		start := time.Now()
+		err := p.Ping(ctx)
+		details := map[string]any{
+			"latency": time.Since(start).String(),
+		}
+		if err != nil {
+			return Result{Status: Critical, Message: err.Error(), Details: details}
+		}
+		return Result{Status: OK, Details: details}
+	}
+}
+
+// RuntimeCheck reports Go runtime statistics. Warning is reported when number
+// of goroutines exceeds the limit. Zero limit is not checked.
+func RuntimeCheck(maxGoroutines int) Check {
+	return func(_ context.Context) Result {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		n := runtime.NumGoroutine()
+		res := Result{
+			Status: OK,
+			Details: map[string]any{
+				"goroutines":     n,
+				"go_version":     runtime.Version(),
+				"gomaxprocs":     runtime.GOMAXPROCS(0),
+				"heap_alloc":     mem.HeapAlloc,
+				"heap_objects":   mem.HeapObjects,
+				"sys":            mem.Sys,
+				"gc_count":       mem.NumGC,
+				"gc_pause_total": time.Duration(mem.PauseTotalNs).String(),
+			},
+		}
+		if maxGoroutines > 0 && n > maxGoroutines {
+			res.Status = Warning
+			res.Message = fmt.Sprintf("%d goroutines exceed limit of %d", n, maxGoroutines)
+		}
+		return res
+	}
+}
+
+// LatencyCheck reports latency percentiles of the recorded operations. Warning
+// is reported when 99th percentile of any operation exceeds the threshold. Zero
+// threshold is not checked.
+func LatencyCheck(l *Latency, threshold time.Duration) Check {
+	return func(_ context.Context) Result {
+		snapshot := l.Snapshot()
+		res := Result{
+			Status:  OK,
+			Details: make(map[string]any, len(snapshot)),
+		}
+		slow := make([]string, 0)
+		for op, p := range snapshot {
+			res.Details[op] = p
+			if threshold > 0 && p.P99 > threshold {
+				slow = append(slow, op)
+			}
+		}
+		if len(slow) > 0 {
+			sort.Strings(slow)
+			res.Status = Warning
+			res.Message = fmt.Sprintf("99th percentile latency exceeds %s: %v", threshold, slow)
+		}
+		return res
+	}
+}
+
+// ChainProvider provides certificate chains by name.
+type ChainProvider interface {
+	// Chains returns certificate chains starting with the leaf certificate.
+	Chains() map[string][]*x509.Certificate
+}
+
+// CertificateCheck reports status of the certificate chains. Critical is
+// reported when any certificate in the chain has expired or is not signed by
+// the next certificate in the chain, warning when any certificate expires in
+// less than the threshold.
+func CertificateCheck(p ChainProvider, threshold time.Duration) Check {
+	return func(_ context.Context) Result {
+		now := time.Now()
+		res := Result{
+			Status:  OK,
+			Details: make(map[string]any),
+		}
+		problems := make([]string, 0)
+		for name, chain := range p.Chains() {
+			status, detail := chainStatus(chain, now, threshold)
+			res.Details[name] = detail
+			if status > res.Status {
+				res.Status = status
+			}
+			if status != OK {
+				problems = append(problems, name)
+			}
+		}
+		if len(problems) > 0 {
+			sort.Strings(problems)
+			res.Message = fmt.Sprintf("certificates need attention: %v", problems)
+		}
+		return res
+	}
+}
+
+func chainStatus(chain []*x509.Certificate, now time.Time, threshold time.Duration) (Status, map[string]any) {
+	if len(chain) == 0 {
+		return Critical, map[string]any{"issues": []string{"no certificate"}}
+	}
+	status := OK
+	issues := make([]string, 0)
+	expires := chain[0].NotAfter
+	for i, c := range chain {
+		if c.NotAfter.Before(expires) {
+			expires = c.NotAfter
+		}
+		switch {
+		case now.After(c.NotAfter):
+			status = Critical
+			issues = append(issues, fmt.Sprintf("%q has expired", c.Subject.CommonName))
+		case now.Before(c.NotBefore):
+			status = Critical
+			issues = append(issues, fmt.Sprintf("%q is not yet valid", c.Subject.CommonName))
+		case threshold > 0 && c.NotAfter.Sub(now) < threshold:
+			if status < Warning {
+				status = Warning
+			}
+			issues = append(issues, fmt.Sprintf("%q expires in %s", c.Subject.CommonName, c.NotAfter.Sub(now).Truncate(time.Second)))
+		}
+		if i+1 < len(chain) {
+			if err := c.CheckSignatureFrom(chain[i+1]); err != nil {
+				status = Critical
+				issues = append(issues, fmt.Sprintf("%q is not signed by %q", c.Subject.CommonName, chain[i+1].Subject.CommonName))
+			}
+		}
+	}
+	detail := map[string]any{
+		"subject":   chain[0].Subject.String(),
+		"issuer":    chain[0].Issuer.String(),
+		"dns_names": chain[0].DNSNames,
+		"not_after": chain[0].NotAfter,
+		"expires":   expires,
+		"length":    len(chain),
+	}
+	if len(issues) > 0 {
+		detail["issues"] = issues
+	}
+	return status, detail
+}
+
+// DepthReporter reports number of pending messages by queue topic.
+type DepthReporter interface {
+	// Depths returns number of pending messages by topic.
+	Depths() map[string]int
+}
+
+// QueueCheck reports queue depths. Warning is reported when number of pending
+// messages of any topic reaches the limit. Zero limit is not checked.
+func QueueCheck(q DepthReporter, limit int) Check {
+	return func(_ context.Context) Result {
+		depths := q.Depths()
+		res := Result{
+			Status:  OK,
+			Details: make(map[string]any, len(depths)),
+		}
+		full := make([]string, 0)
+		for topic, n := range depths {
+			res.Details[topic] = n
+			if limit > 0 && n >= limit {
+				full = append(full, topic)
+			}
+		}
+		if len(full) > 0 {
+			sort.Strings(full)
+			res.Status = Warning
+			res.Message = fmt.Sprintf("queue depth reached %d: %v", limit, full)
+		}
+		return res
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package diagnostics aggregates structured self-checks of the application
+// components into a single report used for incident triage.
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	// InstrumentationDiagnostics is an instrumentation operation for a single diagnostic check.
+	InstrumentationDiagnostics = "diagnostics"
+)
+
+const (
+	// DefaultTimeout is a default maximum duration of a single check.
+	DefaultTimeout = 5 * time.Second
+)
+
+// Status of the diagnostic check.
+type Status int
+
+const (
+	// OK status means component works as expected.
+	OK Status = iota
+	// Warning status means component needs attention.
+	Warning
+	// Critical status means component is failing.
+	Critical
+)
+
+// String returns status name.
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "ok"
+	case Warning:
+		return "warning"
+	case Critical:
+		return "critical"
+	default:
+		return fmt.Sprintf("status(%d)", int(s))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler interface.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface.
+func (s *Status) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "ok":
+		*s = OK
+	case "warning":
+		*s = Warning
+	case "critical":
+		*s = Critical
+	default:
+		return fmt.Errorf("diagnostics: unknown status %q", string(text))
+	}
+	return nil
+}
+
+// Result of the diagnostic check.
+type Result struct {
+	// Name of the check.
+	Name string `json:"name"`
+	// Status of the check.
+	Status Status `json:"status"`
+	// Message is a short human readable summary.
+	Message string `json:"message,omitempty"`
+	// Details contains structured check data.
+	Details map[string]any `json:"details,omitempty"`
+	// Duration of the check.
+	Duration time.Duration `json:"duration"`
+}
+
+// Report of all diagnostic checks.
+type Report struct {
+	// Time when diagnostics were started.
+	Time time.Time `json:"time"`
+	// Duration of all checks.
+	Duration time.Duration `json:"duration"`
+	// Status is the worst status of all checks.
+	Status Status `json:"status"`
+	// Results of the checks sorted by name.
+	Results []Result `json:"results"`
+}
+
+// Result returns result of the check by name.
+func (r Report) Result(name string) (Result, bool) {
+	for _, res := range r.Results {
+		if res.Name == name {
+			return res, true
+		}
+	}
+	return Result{}, false
+}
+
+// Check runs diagnostic check of the component.
+//
+// Result name and duration are set by the registry.
+type Check func(ctx context.Context) Result
+
+// Registry of the diagnostic checks.
+type Registry struct {
+	opts *options
+
+	lock   sync.RWMutex
+	checks map[string]Check
+}
+
+// New creates new diagnostics registry.
+func New(opts ...Option) *Registry {
+	return &Registry{
+		opts:   newOptions(opts...),
+		checks: make(map[string]Check),
+	}
+}
+
+// Register diagnostic check with the name. Check with the same name is replaced.
+func (r *Registry) Register(name string, check Check) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.checks[name] = check
+}
+
+// Unregister diagnostic check.
+func (r *Registry) Unregister(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.checks, name)
+}
+
+// Names returns sorted names of the registered checks.
+func (r *Registry) Names() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run diagnostic checks concurrently and returns report. If names are provided
+// only checks with these names are run.
+//
+// Each check is limited by the timeout. Checks that time out or panic are
+// reported as critical.
+func (r *Registry) Run(ctx context.Context, names ...string) Report {
+	r.lock.RLock()
+	checks := make(map[string]Check, len(r.checks))
+	if len(names) == 0 {
+		for name, c := range r.checks {
+			checks[name] = c
+		}
+	} else {
+		for _, name := range names {
+			if c, ok := r.checks[name]; ok {
+				checks[name] = c
+			}
+		}
+	}
+	r.lock.RUnlock()
+
+	report := Report{
+		Time:    time.Now(),
+		Results: make([]Result, 0, len(checks)),
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for name, c := range checks {
+		wg.Add(1)
+		go func(name string, c Check) {
+			defer wg.Done()
+
+			res := r.run(ctx, name, c)
+
+			lock.Lock()
+			report.Results = append(report.Results, res)
+			lock.Unlock()
+		}(name, c)
+	}
+	wg.Wait()
+
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].Name < report.Results[j].Name
+	})
+	for _, res := range report.Results {
+		if res.Status > report.Status {
+			report.Status = res.Status
+		}
+	}
+	report.Duration = time.Since(report.Time)
+	return report
+}
+
+// run single check within the timeout.
+func (r *Registry) run(ctx context.Context, name string, c Check) Result {
+	if r.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.opts.Timeout)
+		defer cancel()
+	}
+
+	finish := r.opts.Instrumenter.Observe(ctx, InstrumentationDiagnostics, name)
+	start := time.Now()
+
+	// Check is run in separate goroutine so that checks ignoring context
+	// do not block the whole report.
+	ch := make(chan Result, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				ch <- Result{Status: Critical, Message: fmt.Sprintf("check panicked: %v", p)}
+			}
+		}()
+		ch <- c(ctx)
+	}()
+
+	var res Result
+	select {
+	case res = <-ch:
+	case <-ctx.Done():
+		res = Result{Status: Critical, Message: fmt.Sprintf("check did not complete: %v", ctx.Err())}
+	}
+	res.Name = name
+	res.Duration = time.Since(start)
+
+	var err error
+	if res.Status == Critical {
+		err = fmt.Errorf("diagnostics: %s", res.Message)
+	}
+	finish(err)
+	return res
+}
+
+// Handler returns HTTP handler that runs diagnostic checks and responds with
+// the report in JSON format.
+//
+// Checks can be limited by the comma separated "check" query parameter. Handler
+// always responds with 200 OK as report status is part of the response and must
+// be protected as it exposes internal details of the application.
+func Handler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var names []string
+		if q := req.URL.Query().Get("check"); len(q) != 0 {
+			names = strings.Split(q, ",")
+		}
+		report := r.Run(req.Context(), names...)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,220 @@
+package diagnostics
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"azugo.io/core/queue"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryRun(t *testing.T) {
+	r := New(Timeout(50 * time.Millisecond))
+	r.Register("ok", func(context.Context) Result {
+		return Result{Status: OK, Details: map[string]any{"value": 1}}
+	})
+	r.Register("warning", func(context.Context) Result {
+		return Result{Status: Warning, Message: "needs attention"}
+	})
+	r.Register("slow", func(ctx context.Context) Result {
+		<-ctx.Done()
+		time.Sleep(100 * time.Millisecond)
+		return Result{Status: OK}
+	})
+	r.Register("panic", func(context.Context) Result {
+		panic("boom")
+	})
+	assert.Equal(t, []string{"ok", "panic", "slow", "warning"}, r.Names())
+
+	report := r.Run(context.TODO())
+	assert.Equal(t, Critical, report.Status)
+	require.Len(t, report.Results, 4)
+	assert.Equal(t, "ok", report.Results[0].Name)
+
+	res, ok := report.Result("slow")
+	require.True(t, ok)
+	assert.Equal(t, Critical, res.Status)
+	assert.Less(t, res.Duration, 100*time.Millisecond)
+
+	res, ok = report.Result("panic")
+	require.True(t, ok)
+	assert.Equal(t, Critical, res.Status)
+	assert.Contains(t, res.Message, "boom")
+
+	report = r.Run(context.TODO(), "ok", "warning", "missing")
+	assert.Equal(t, Warning, report.Status)
+	assert.Len(t, report.Results, 2)
+
+	r.Unregister("warning")
+	report = r.Run(context.TODO(), "ok", "warning")
+	assert.Equal(t, OK, report.Status)
+	assert.Len(t, report.Results, 1)
+}
+
+func TestHandler(t *testing.T) {
+	r := New()
+	r.Register("ok", func(context.Context) Result {
+		return Result{Status: OK}
+	})
+	r.Register("failing", PingCheck(pinger(func(context.Context) error {
+		return errors.New("connection refused")
+	})))
+
+	rec := httptest.NewRecorder()
+	Handler(r).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/diagnostics?check=failing", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var report Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, Critical, report.Status)
+	require.Len(t, report.Results, 1)
+	assert.Equal(t, "connection refused", report.Results[0].Message)
+	assert.Contains(t, report.Results[0].Details, "latency")
+}
+
+type pinger func(ctx context.Context) error
+
+func (p pinger) Ping(ctx context.Context) error {
+	return p(ctx)
+}
+
+func TestLatency(t *testing.T) {
+	l := NewLatency(100)
+	for i := 1; i <= 200; i++ {
+		var err error
+		if i%50 == 0 {
+			err = errors.New("failed")
+		}
+		l.Record("op", time.Duration(i)*time.Millisecond, err)
+	}
+
+	p, ok := l.Percentiles("op")
+	require.True(t, ok)
+	assert.Equal(t, uint64(200), p.Count)
+	assert.Equal(t, uint64(4), p.Errors)
+	// Only the latest 100 samples are kept.
+	assert.Equal(t, 150*time.Millisecond, p.P50)
+	assert.Equal(t, 199*time.Millisecond, p.P99)
+	assert.Equal(t, 200*time.Millisecond, p.Max)
+
+	_, ok = l.Percentiles("other")
+	assert.False(t, ok)
+
+	instr := l.Instrumenter("cache-")
+	instr.Observe(context.TODO(), "cache-get")(nil)
+	instr.Observe(context.TODO(), "queue-publish")(nil)
+	assert.Contains(t, l.Snapshot(), "cache-get")
+	assert.NotContains(t, l.Snapshot(), "queue-publish")
+
+	res := LatencyCheck(l, 100*time.Millisecond)(context.TODO())
+	assert.Equal(t, Warning, res.Status)
+	assert.Contains(t, res.Message, "op")
+	assert.NotContains(t, res.Message, "cache-get")
+}
+
+func TestRuntimeCheck(t *testing.T) {
+	res := RuntimeCheck(0)(context.TODO())
+	assert.Equal(t, OK, res.Status)
+	assert.Greater(t, res.Details["goroutines"], 0)
+
+	res = RuntimeCheck(1)(context.TODO())
+	assert.Equal(t, Warning, res.Status)
+}
+
+type chains map[string][]*x509.Certificate
+
+func (c chains) Chains() map[string][]*x509.Certificate {
+	return c
+}
+
+func testCertificate(t *testing.T, name string, notAfter time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	c, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return c, key
+}
+
+func TestCertificateCheck(t *testing.T) {
+	year := time.Now().Add(365 * 24 * time.Hour)
+	ca, caKey := testCertificate(t, "ca", year, nil, nil)
+	leaf, _ := testCertificate(t, "leaf", year, ca, caKey)
+	expiring, _ := testCertificate(t, "expiring", time.Now().Add(24*time.Hour), ca, caKey)
+	expired, _ := testCertificate(t, "expired", time.Now().Add(-time.Hour), ca, caKey)
+	other, _ := testCertificate(t, "other", year, nil, nil)
+
+	res := CertificateCheck(chains{"api": {leaf, ca}}, DefaultExpiryWarning)(context.TODO())
+	assert.Equal(t, OK, res.Status)
+	assert.Equal(t, 2, res.Details["api"].(map[string]any)["length"])
+
+	res = CertificateCheck(chains{"api": {leaf, ca}, "soon": {expiring, ca}}, DefaultExpiryWarning)(context.TODO())
+	assert.Equal(t, Warning, res.Status)
+	assert.Contains(t, res.Message, "soon")
+
+	res = CertificateCheck(chains{"old": {expired, ca}}, DefaultExpiryWarning)(context.TODO())
+	assert.Equal(t, Critical, res.Status)
+
+	res = CertificateCheck(chains{"broken": {leaf, other}}, DefaultExpiryWarning)(context.TODO())
+	assert.Equal(t, Critical, res.Status)
+	assert.Contains(t, res.Details["broken"].(map[string]any)["issues"], `"leaf" is not signed by "other"`)
+}
+
+func TestQueueCheck(t *testing.T) {
+	q := queue.NewMemory(10)
+	defer q.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	block := make(chan struct{})
+	defer close(block)
+	require.NoError(t, q.Subscribe(ctx, "orders", func(context.Context, *queue.Message) error {
+		<-block
+		return nil
+	}))
+	for i := 0; i < 6; i++ {
+		require.NoError(t, q.Publish(context.TODO(), &queue.Message{Topic: "orders"}))
+	}
+
+	// One message is being handled.
+	require.Eventually(t, func() bool {
+		return q.Depths()["orders"] == 5
+	}, time.Second, 10*time.Millisecond)
+
+	res := QueueCheck(q, 0)(context.TODO())
+	assert.Equal(t, OK, res.Status)
+	assert.Equal(t, 5, res.Details["orders"])
+
+	res = QueueCheck(q, 5)(context.TODO())
+	assert.Equal(t, Warning, res.Status)
+}
@@ -0,0 +1,141 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package diagnostics
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+)
+
+// DefaultLatencyWindow is a default number of latest samples kept for every operation.
+const DefaultLatencyWindow = 1024
+
+// Percentiles of the operation latency.
+type Percentiles struct {
+	// Count is a total number of observed operations.
+	Count uint64 `json:"count"`
+	// Errors is a total number of failed operations.
+	Errors uint64 `json:"errors"`
+	// P50 is a median latency of the latest operations.
+	P50 time.Duration `json:"p50"`
+	// P90 is a 90th percentile latency of the latest operations.
+	P90 time.Duration `json:"p90"`
+	// P99 is a 99th percentile latency of the latest operations.
+	P99 time.Duration `json:"p99"`
+	// Max is a maximum latency of the latest operations.
+	Max time.Duration `json:"max"`
+}
+
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+	count   uint64
+	errors  uint64
+}
+
+func (w *latencyWindow) percentiles() Percentiles {
+	p := Percentiles{
+		Count:  w.count,
+		Errors: w.errors,
+	}
+	if len(w.samples) == 0 {
+		return p
+	}
+	sorted := append([]time.Duration{}, w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(q float64) time.Duration {
+		return sorted[int(q*float64(len(sorted)-1))]
+	}
+	p.P50 = at(0.5)
+	p.P90 = at(0.9)
+	p.P99 = at(0.99)
+	p.Max = sorted[len(sorted)-1]
+	return p
+}
+
+// Latency records latencies of the latest operations to calculate percentiles.
+type Latency struct {
+	size int
+
+	lock sync.Mutex
+	ops  map[string]*latencyWindow
+}
+
+// NewLatency creates latency recorder keeping size latest samples for every
+// operation. If size is zero it defaults to DefaultLatencyWindow.
+func NewLatency(size int) *Latency {
+	if size <= 0 {
+		size = DefaultLatencyWindow
+	}
+	return &Latency{
+		size: size,
+		ops:  make(map[string]*latencyWindow),
+	}
+}
+
+// Record operation latency.
+func (l *Latency) Record(op string, d time.Duration, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	w, ok := l.ops[op]
+	if !ok {
+		w = &latencyWindow{samples: make([]time.Duration, 0, l.size)}
+		l.ops[op] = w
+	}
+	w.count++
+	if err != nil {
+		w.errors++
+	}
+	if len(w.samples) < l.size {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % l.size
+}
+
+// Instrumenter returns instrumenter that records latencies of the operations
+// with the name prefix. Empty prefix records all operations.
+func (l *Latency) Instrumenter(prefix string) instrumenter.Instrumenter {
+	return func(_ context.Context, op string, _ ...any) func(err error) {
+		if !strings.HasPrefix(op, prefix) {
+			return func(error) {}
+		}
+		start := time.Now()
+		return func(err error) {
+			l.Record(op, time.Since(start), err)
+		}
+	}
+}
+
+// Percentiles returns latency percentiles of the operation.
+func (l *Latency) Percentiles(op string) (Percentiles, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	w, ok := l.ops[op]
+	if !ok {
+		return Percentiles{}, false
+	}
+	return w.percentiles(), true
+}
+
+// Snapshot returns latency percentiles of all recorded operations.
+func (l *Latency) Snapshot() map[string]Percentiles {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	res := make(map[string]Percentiles, len(l.ops))
+	for op, w := range l.ops {
+		res[op] = w.percentiles()
+	}
+	return res
+}
@@ -0,0 +1,41 @@
+package diagnostics
+
+import (
+	"time"
+
+	"azugo.io/core/instrumenter"
+)
+
+type options struct {
+	Timeout      time.Duration
+	Instrumenter instrumenter.Instrumenter
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Timeout: DefaultTimeout,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	return opt
+}
+
+// Option for the diagnostics registry.
+type Option interface {
+	apply(*options)
+}
+
+// Timeout is a maximum duration of a single check.
+type Timeout time.Duration
+
+func (t Timeout) apply(o *options) {
+	o.Timeout = time.Duration(t)
+}
+
+// Instrumenter to observe diagnostic checks.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"azugo.io/core/diagnostics"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnostics(t *testing.T) {
+	a, cleanup, _, err := newTestApp()
+	require.NoError(t, err)
+	t.Cleanup(cleanup)
+
+	require.NoError(t, a.Start())
+
+	require.NoError(t, a.Cache().Ping(context.TODO()))
+
+	a.AddDiagnostic("custom", func(context.Context) diagnostics.Result {
+		return diagnostics.Result{Status: diagnostics.Warning, Message: "custom warning"}
+	})
+
+	report := a.Diagnostics(context.TODO())
+	assert.Equal(t, diagnostics.Warning, report.Status)
+	for _, name := range []string{"runtime", "cache", "cache-latency", "custom"} {
+		res, ok := report.Result(name)
+		if assert.True(t, ok, name) && name != "custom" {
+			assert.Equal(t, diagnostics.OK, res.Status, name)
+		}
+	}
+
+	res, _ := report.Result("cache-latency")
+	assert.Contains(t, res.Details, "cache-ping")
+
+	rec := httptest.NewRecorder()
+	a.DiagnosticsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?check=runtime", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var r diagnostics.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &r))
+	require.Len(t, r.Results, 1)
+	assert.Equal(t, "runtime", r.Results[0].Name)
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Balancer selects service endpoint using round-robin between endpoints
+// with the highest priority.
+type Balancer struct {
+	discovery *Discovery
+
+	lock sync.Mutex
+	next map[string]uint64
+}
+
+// NewBalancer creates new client-side load balancer.
+func NewBalancer(d *Discovery) *Balancer {
+	return &Balancer{
+		discovery: d,
+		next:      make(map[string]uint64),
+	}
+}
+
+// Next returns next service endpoint to use.
+func (b *Balancer) Next(ctx context.Context, service string) (Endpoint, error) {
+	endpoints, err := b.discovery.Lookup(ctx, service)
+	if err != nil {
+		return Endpoint{}, err
+	}
+
+	// Endpoints are ordered by priority so only use ones with the highest priority.
+	n := 1
+	for n < len(endpoints) && endpoints[n].Priority == endpoints[0].Priority {
+		n++
+	}
+
+	b.lock.Lock()
+	i := b.next[service]
+	b.next[service] = i + 1
+	b.lock.Unlock()
+
+	return endpoints[i%uint64(n)], nil
+}
+
+// Transport is an HTTP round tripper that resolves request host as a service name
+// and sends request to the selected service endpoint.
+type Transport struct {
+	// Balancer to select service endpoints.
+	Balancer *Balancer
+	// Base round tripper. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ep, err := t.Balancer.Next(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	r := req.Clone(req.Context())
+	r.URL.Host = ep.Addr()
+	if len(r.Host) == 0 {
+		r.Host = req.URL.Host
+	}
+	return base.RoundTrip(r)
+}
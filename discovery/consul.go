@@ -0,0 +1,123 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// ConsulResolver resolves service endpoints using Consul health HTTP API.
+type ConsulResolver struct {
+	opts *consulOptions
+}
+
+// NewConsulResolver creates new Consul resolver.
+func NewConsulResolver(opts ...ConsulOption) *ConsulResolver {
+	opt := &consulOptions{
+		Address:     "http://127.0.0.1:8500",
+		PassingOnly: true,
+	}
+	for _, o := range opts {
+		o.applyConsul(opt)
+	}
+	if opt.Client == nil {
+		opt.Client = &http.Client{
+			Timeout: 10 * time.Second,
+		}
+	}
+	return &ConsulResolver{
+		opts: opt,
+	}
+}
+
+type consulServiceEntry struct {
+	Node struct {
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		Address string            `json:"Address"`
+		Port    int               `json:"Port"`
+		Meta    map[string]string `json:"Meta"`
+		Weights struct {
+			Passing int `json:"Passing"`
+		} `json:"Weights"`
+	} `json:"Service"`
+	Checks []struct {
+		Status string `json:"Status"`
+	} `json:"Checks"`
+}
+
+// Resolve returns service endpoints registered in Consul.
+//
+// Endpoint is considered healthy only if all its checks are passing.
+func (r *ConsulResolver) Resolve(ctx context.Context, service string) ([]Endpoint, error) {
+	q := url.Values{}
+	if r.opts.PassingOnly {
+		q.Set("passing", "true")
+	}
+	if len(r.opts.Datacenter) != 0 {
+		q.Set("dc", r.opts.Datacenter)
+	}
+	if len(r.opts.Tag) != 0 {
+		q.Set("tag", r.opts.Tag)
+	}
+	u := strings.TrimSuffix(r.opts.Address, "/") + "/v1/health/service/" + url.PathEscape(service)
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.opts.Token) != 0 {
+		req.Header.Set("X-Consul-Token", r.opts.Token)
+	}
+
+	resp, err := r.opts.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul returned unexpected status code: %d", resp.StatusCode)
+	}
+
+	var entries []consulServiceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("invalid consul response: %w", err)
+	}
+
+	endpoints := make([]Endpoint, 0, len(entries))
+	for _, e := range entries {
+		host := e.Service.Address
+		if len(host) == 0 {
+			host = e.Node.Address
+		}
+		healthy := true
+		for _, c := range e.Checks {
+			if c.Status != "passing" {
+				healthy = false
+				break
+			}
+		}
+		endpoints = append(endpoints, Endpoint{
+			Host:    host,
+			Port:    e.Service.Port,
+			Weight:  e.Service.Weights.Passing,
+			Healthy: healthy,
+			Meta:    e.Service.Meta,
+		})
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,264 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrNoEndpoints is returned when service has no healthy endpoints.
+var ErrNoEndpoints = errors.New("no service endpoints available")
+
+// Endpoint is a single service instance address.
+type Endpoint struct {
+	Host     string
+	Port     int
+	Priority int
+	Weight   int
+	Healthy  bool
+	Meta     map[string]string
+}
+
+// Addr returns endpoint address in host:port format.
+func (e Endpoint) Addr() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+}
+
+// Resolver resolves service endpoints.
+type Resolver interface {
+	// Resolve returns all known endpoints of the service.
+	Resolve(ctx context.Context, service string) ([]Endpoint, error)
+}
+
+// ResolverFunc is a function that implements Resolver interface.
+type ResolverFunc func(ctx context.Context, service string) ([]Endpoint, error)
+
+// Resolve returns all known endpoints of the service.
+func (f ResolverFunc) Resolve(ctx context.Context, service string) ([]Endpoint, error) {
+	return f(ctx, service)
+}
+
+type entry struct {
+	endpoints []Endpoint
+	expires   time.Time
+	subs      map[int]func([]Endpoint)
+}
+
+// Discovery resolves service endpoints with caching, health filtering and
+// change subscriptions.
+//
+// Discovery implements core.Tasker interface to refresh subscribed services in the background.
+type Discovery struct {
+	resolver Resolver
+	opts     *options
+
+	lock    sync.Mutex
+	entries map[string]*entry
+	nextID  int
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates new service discovery using the resolver.
+func New(resolver Resolver, opts ...Option) *Discovery {
+	return &Discovery{
+		resolver: resolver,
+		opts:     newOptions(opts...),
+		entries:  make(map[string]*entry),
+	}
+}
+
+func filterHealthy(endpoints []Endpoint) []Endpoint {
+	healthy := make([]Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		if e.Healthy {
+			healthy = append(healthy, e)
+		}
+	}
+	sort.SliceStable(healthy, func(i, j int) bool {
+		if healthy[i].Priority != healthy[j].Priority {
+			return healthy[i].Priority < healthy[j].Priority
+		}
+		return healthy[i].Addr() < healthy[j].Addr()
+	})
+	return healthy
+}
+
+func (d *Discovery) resolve(ctx context.Context, service string) ([]Endpoint, error) {
+	endpoints, err := d.resolver.Resolve(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	return filterHealthy(endpoints), nil
+}
+
+// Lookup returns healthy service endpoints ordered by priority.
+//
+// Results are cached for the configured TTL.
+func (d *Discovery) Lookup(ctx context.Context, service string) ([]Endpoint, error) {
+	d.lock.Lock()
+	e, ok := d.entries[service]
+	if ok && e.endpoints != nil && time.Now().Before(e.expires) {
+		endpoints := e.endpoints
+		d.lock.Unlock()
+		return endpoints, nil
+	}
+	d.lock.Unlock()
+
+	endpoints, err := d.resolve(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	d.update(service, endpoints)
+
+	if len(endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	return endpoints, nil
+}
+
+func equalEndpoints(a, b []Endpoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Addr() != b[i].Addr() || a[i].Priority != b[i].Priority || a[i].Weight != b[i].Weight {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *Discovery) update(service string, endpoints []Endpoint) {
+	d.lock.Lock()
+	e, ok := d.entries[service]
+	if !ok {
+		e = &entry{}
+		d.entries[service] = e
+	}
+	changed := e.endpoints != nil && !equalEndpoints(e.endpoints, endpoints)
+	e.endpoints = endpoints
+	e.expires = time.Now().Add(d.opts.TTL)
+	subs := make([]func([]Endpoint), 0, len(e.subs))
+	if changed {
+		for _, fn := range e.subs {
+			subs = append(subs, fn)
+		}
+	}
+	d.lock.Unlock()
+
+	for _, fn := range subs {
+		fn(endpoints)
+	}
+}
+
+// Subscribe registers callback that is called when service endpoints change.
+//
+// Subscribed services are refreshed in the background while discovery is started.
+// Returned function removes subscription.
+func (d *Discovery) Subscribe(service string, fn func(endpoints []Endpoint)) func() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	e, ok := d.entries[service]
+	if !ok {
+		e = &entry{}
+		d.entries[service] = e
+	}
+	if e.subs == nil {
+		e.subs = make(map[int]func([]Endpoint))
+	}
+	id := d.nextID
+	d.nextID++
+	e.subs[id] = fn
+
+	return func() {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+
+		delete(e.subs, id)
+	}
+}
+
+// Refresh resolves all subscribed services and notifies subscribers about changes.
+func (d *Discovery) Refresh(ctx context.Context) {
+	d.lock.Lock()
+	services := make([]string, 0, len(d.entries))
+	for name, e := range d.entries {
+		if len(e.subs) > 0 {
+			services = append(services, name)
+		}
+	}
+	d.lock.Unlock()
+
+	for _, service := range services {
+		endpoints, err := d.resolve(ctx, service)
+		if err != nil {
+			d.opts.Logger.Warn("failed to resolve service endpoints", zap.String("service.name", service), zap.Error(err))
+			continue
+		}
+		d.update(service, endpoints)
+	}
+}
+
+// Name returns task name.
+func (d *Discovery) Name() string {
+	return "service-discovery"
+}
+
+// Start refreshing subscribed services in the background.
+func (d *Discovery) Start(ctx context.Context) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.stop != nil {
+		return nil
+	}
+	d.stop = make(chan struct{})
+
+	d.wg.Add(1)
+	go func(stop chan struct{}) {
+		defer d.wg.Done()
+
+		t := time.NewTicker(d.opts.RefreshInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-t.C:
+				d.Refresh(ctx)
+			}
+		}
+	}(d.stop)
+
+	return nil
+}
+
+// Stop background refresh.
+func (d *Discovery) Stop() {
+	d.lock.Lock()
+	if d.stop == nil {
+		d.lock.Unlock()
+		return
+	}
+	close(d.stop)
+	d.stop = nil
+	d.lock.Unlock()
+
+	d.wg.Wait()
+}
@@ -0,0 +1,137 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testResolver struct {
+	lock      sync.Mutex
+	calls     int
+	endpoints []Endpoint
+}
+
+func (r *testResolver) set(endpoints ...Endpoint) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.endpoints = endpoints
+}
+
+func (r *testResolver) Resolve(_ context.Context, _ string) ([]Endpoint, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.calls++
+	return r.endpoints, nil
+}
+
+func TestDiscoveryLookup(t *testing.T) {
+	r := &testResolver{}
+	r.set(
+		Endpoint{Host: "b", Port: 80, Priority: 1, Healthy: true},
+		Endpoint{Host: "a", Port: 80, Priority: 1, Healthy: true},
+		Endpoint{Host: "c", Port: 80, Priority: 0, Healthy: false},
+	)
+	d := New(r)
+
+	endpoints, err := d.Lookup(context.Background(), "svc")
+	require.NoError(t, err)
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, "a:80", endpoints[0].Addr())
+
+	_, err = d.Lookup(context.Background(), "svc")
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.calls, "lookup must be cached")
+
+	r.set(Endpoint{Host: "c", Port: 80})
+	d = New(r)
+	_, err = d.Lookup(context.Background(), "svc")
+	assert.ErrorIs(t, err, ErrNoEndpoints)
+}
+
+func TestDiscoverySubscribe(t *testing.T) {
+	r := &testResolver{}
+	r.set(Endpoint{Host: "a", Port: 80, Healthy: true})
+	d := New(r)
+
+	var changes [][]Endpoint
+	unsubscribe := d.Subscribe("svc", func(endpoints []Endpoint) {
+		changes = append(changes, endpoints)
+	})
+
+	_, err := d.Lookup(context.Background(), "svc")
+	require.NoError(t, err)
+
+	d.Refresh(context.Background())
+	assert.Empty(t, changes)
+
+	r.set(Endpoint{Host: "a", Port: 80, Healthy: true}, Endpoint{Host: "b", Port: 80, Healthy: true})
+	d.Refresh(context.Background())
+	require.Len(t, changes, 1)
+	assert.Len(t, changes[0], 2)
+
+	unsubscribe()
+	r.set(Endpoint{Host: "b", Port: 80, Healthy: true})
+	d.Refresh(context.Background())
+	assert.Len(t, changes, 1)
+}
+
+func TestConsulResolver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health/service/api", r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("passing"))
+		assert.Equal(t, "secret", r.Header.Get("X-Consul-Token"))
+		_, _ = w.Write([]byte(`[
+			{"Node":{"Address":"10.0.0.1"},"Service":{"Address":"","Port":8080},"Checks":[{"Status":"passing"}]},
+			{"Node":{"Address":"10.0.0.2"},"Service":{"Address":"10.0.1.2","Port":8081},"Checks":[{"Status":"critical"}]}
+		]`))
+	}))
+	defer srv.Close()
+
+	r := NewConsulResolver(ConsulAddress(srv.URL), ConsulToken("secret"))
+	endpoints, err := r.Resolve(context.Background(), "api")
+	require.NoError(t, err)
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, "10.0.0.1:8080", endpoints[0].Addr())
+	assert.True(t, endpoints[0].Healthy)
+	assert.Equal(t, "10.0.1.2:8081", endpoints[1].Addr())
+	assert.False(t, endpoints[1].Healthy)
+}
+
+func TestTransport(t *testing.T) {
+	var hits [2]int
+	srvs := make([]*httptest.Server, 2)
+	endpoints := make([]Endpoint, 2)
+	for i := range srvs {
+		i := i
+		srvs[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "api", r.Host)
+			hits[i]++
+		}))
+		defer srvs[i].Close()
+
+		u, err := url.Parse(srvs[i].URL)
+		require.NoError(t, err)
+		port, err := strconv.Atoi(u.Port())
+		require.NoError(t, err)
+		endpoints[i] = Endpoint{Host: u.Hostname(), Port: port, Healthy: true}
+	}
+
+	r := &testResolver{}
+	r.set(endpoints...)
+
+	client := &http.Client{Transport: &Transport{Balancer: NewBalancer(New(r))}}
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get("http://api/test")
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, [2]int{2, 2}, hits)
+}
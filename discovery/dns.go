@@ -0,0 +1,51 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package discovery
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// DNSResolver resolves service endpoints using DNS SRV records.
+//
+// Service name must be a full SRV record name, for example "_http._tcp.example.com".
+type DNSResolver struct {
+	// Resolver to use for DNS lookups. If nil, default resolver is used.
+	Resolver *net.Resolver
+}
+
+// NewDNSResolver creates new DNS SRV resolver using default system resolver.
+func NewDNSResolver() *DNSResolver {
+	return &DNSResolver{}
+}
+
+// Resolve returns endpoints from service SRV records.
+//
+// All returned endpoints are considered healthy.
+func (r *DNSResolver) Resolve(ctx context.Context, service string) ([]Endpoint, error) {
+	res := r.Resolver
+	if res == nil {
+		res = net.DefaultResolver
+	}
+
+	_, srvs, err := res.LookupSRV(ctx, "", "", service)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]Endpoint, 0, len(srvs))
+	for _, srv := range srvs {
+		endpoints = append(endpoints, Endpoint{
+			Host:     strings.TrimSuffix(srv.Target, "."),
+			Port:     int(srv.Port),
+			Priority: int(srv.Priority),
+			Weight:   int(srv.Weight),
+			Healthy:  true,
+		})
+	}
+	return endpoints, nil
+}
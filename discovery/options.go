@@ -0,0 +1,118 @@
+package discovery
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	TTL             time.Duration
+	RefreshInterval time.Duration
+	Logger          *zap.Logger
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		TTL:             30 * time.Second,
+		RefreshInterval: 10 * time.Second,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return opt
+}
+
+// Option for the service discovery.
+type Option interface {
+	apply(*options)
+}
+
+// TTL is a time to cache resolved service endpoints.
+type TTL time.Duration
+
+func (t TTL) apply(o *options) {
+	o.TTL = time.Duration(t)
+}
+
+// RefreshInterval is an interval to refresh subscribed services.
+type RefreshInterval time.Duration
+
+func (r RefreshInterval) apply(o *options) {
+	o.RefreshInterval = time.Duration(r)
+}
+
+// Logger to log resolve errors.
+type Logger struct {
+	*zap.Logger
+}
+
+func (l Logger) apply(o *options) {
+	o.Logger = l.Logger
+}
+
+type consulOptions struct {
+	Address     string
+	Token       string
+	Datacenter  string
+	Tag         string
+	PassingOnly bool
+	Client      *http.Client
+}
+
+// ConsulOption for the Consul resolver.
+type ConsulOption interface {
+	applyConsul(*consulOptions)
+}
+
+// ConsulAddress is an address of the Consul agent HTTP API.
+//
+// Defaults to http://127.0.0.1:8500.
+type ConsulAddress string
+
+func (a ConsulAddress) applyConsul(o *consulOptions) {
+	o.Address = string(a)
+}
+
+// ConsulToken is an ACL token to access Consul HTTP API.
+type ConsulToken string
+
+func (t ConsulToken) applyConsul(o *consulOptions) {
+	o.Token = string(t)
+}
+
+// ConsulDatacenter to resolve services in.
+type ConsulDatacenter string
+
+func (d ConsulDatacenter) applyConsul(o *consulOptions) {
+	o.Datacenter = string(d)
+}
+
+// ConsulTag filters service instances by the tag.
+type ConsulTag string
+
+func (t ConsulTag) applyConsul(o *consulOptions) {
+	o.Tag = string(t)
+}
+
+// ConsulPassingOnly requests only instances with passing health checks from Consul.
+//
+// Instances with failing health checks are always filtered out by the discovery.
+type ConsulPassingOnly bool
+
+func (p ConsulPassingOnly) applyConsul(o *consulOptions) {
+	o.PassingOnly = bool(p)
+}
+
+// HTTPClient to use for resolver requests.
+type HTTPClient struct {
+	*http.Client
+}
+
+func (c HTTPClient) applyConsul(o *consulOptions) {
+	o.Client = c.Client
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"azugo.io/core/drain"
+
+	"go.uber.org/zap"
+)
+
+func (a *App) initDrainer() {
+	a.drainlock.Lock()
+	defer a.drainlock.Unlock()
+
+	if a.drainer != nil {
+		return
+	}
+
+	a.drainer = drain.New(
+		drain.StageTimeout(time.Duration(a.Config().Drain.StageTimeout)),
+		drain.Instrumenter(a.Instrumenter()),
+		drain.Logger{Logger: a.Log().Named("drain")},
+	)
+}
+
+// Drainer returns priority aware graceful drainer of the application work.
+//
+// When application is stopped, new background work (cache warmers, queue
+// consumption and tasks wrapped with the drainer) is stopped first, then new
+// requests are rejected while in-flight requests finish, before application
+// services are closed.
+func (a *App) Drainer() *drain.Drainer {
+	a.initDrainer()
+	return a.drainer
+}
+
+// drain drains application work if drainer is used.
+func (a *App) drain() {
+	a.drainlock.Lock()
+	d := a.drainer
+	a.drainlock.Unlock()
+
+	if d == nil {
+		return
+	}
+	if err := d.Drain(context.Background()); err != nil {
+		a.Log().Warn("Draining failed", zap.Error(err))
+	}
+}
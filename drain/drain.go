@@ -0,0 +1,304 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package drain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"go.uber.org/zap"
+)
+
+// InstrumentationDrain is an instrumentation operation for every drain stage.
+const InstrumentationDrain = "drain"
+
+var (
+	// ErrDraining is returned when work of the priority is no longer admitted.
+	ErrDraining = errors.New("drain: draining")
+	// ErrStageTimeout is returned when in-flight work did not finish in time.
+	ErrStageTimeout = errors.New("drain: in-flight work did not finish in time")
+)
+
+// Priority of the work. Lower priority work is drained first.
+type Priority int
+
+const (
+	// Background is low priority work, for example cache warmers, background
+	// refresh and queue consumption.
+	Background Priority = iota
+	// Request is user request handling.
+	Request
+	// Critical is work that must be available until the very end, for
+	// example health checks and admin endpoints.
+	Critical
+)
+
+// priorities in drain order.
+var priorities = [...]Priority{Background, Request, Critical}
+
+// String returns priority name.
+func (p Priority) String() string {
+	switch p {
+	case Background:
+		return "background"
+	case Request:
+		return "request"
+	case Critical:
+		return "critical"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+type stage struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	inflight int
+	idle     chan struct{}
+	hooks    []func(ctx context.Context)
+}
+
+// Drainer coordinates priority aware graceful draining of the work.
+//
+// When draining starts, admission of new work stops priority by priority
+// starting with the lowest one. Each stage waits for in-flight work of its
+// priority to finish before draining the next priority, so background work is
+// stopped first while in-flight user requests finish.
+type Drainer struct {
+	stageTimeout time.Duration
+	retryAfter   time.Duration
+	instrumenter instrumenter.Instrumenter
+	logger       *zap.Logger
+
+	lock     sync.Mutex
+	draining bool
+	stopped  int
+	stages   [len(priorities)]*stage
+	done     chan struct{}
+}
+
+// New creates new drainer.
+func New(opts ...Option) *Drainer {
+	opt := newOptions(opts...)
+
+	d := &Drainer{
+		stageTimeout: opt.StageTimeout,
+		retryAfter:   opt.RetryAfter,
+		instrumenter: opt.Instrumenter,
+		logger:       opt.Logger,
+		done:         make(chan struct{}),
+	}
+	for i := range d.stages {
+		ctx, cancel := context.WithCancel(context.Background())
+		d.stages[i] = &stage{
+			ctx:    ctx,
+			cancel: cancel,
+		}
+	}
+	return d
+}
+
+func (d *Drainer) stage(p Priority) *stage {
+	if p < Background {
+		p = Background
+	} else if p > Critical {
+		p = Critical
+	}
+	return d.stages[p]
+}
+
+// admitted returns true if new work of the priority is still admitted.
+//
+// Must be called with lock held.
+func (d *Drainer) admitted(p Priority) bool {
+	return !d.draining || int(p) >= d.stopped
+}
+
+// Acquire registers start of the work with the priority. Returned function
+// must be called when work is finished.
+//
+// Returns ErrDraining if work of the priority is no longer admitted.
+func (d *Drainer) Acquire(p Priority) (func(), error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if !d.admitted(p) {
+		return nil, ErrDraining
+	}
+	s := d.stage(p)
+	s.inflight++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.lock.Lock()
+			defer d.lock.Unlock()
+
+			s.inflight--
+			if s.inflight == 0 && s.idle != nil {
+				close(s.idle)
+				s.idle = nil
+			}
+		})
+	}, nil
+}
+
+// Admitted returns true if new work of the priority is still admitted.
+func (d *Drainer) Admitted(p Priority) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.admitted(p)
+}
+
+// Draining returns true if draining has started.
+func (d *Drainer) Draining() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.draining
+}
+
+// InFlight returns number of in-flight work of the priority.
+func (d *Drainer) InFlight(p Priority) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.stage(p).inflight
+}
+
+// Context returns context that is canceled when work of the priority must stop.
+//
+// Background context is canceled as soon as its stage starts so that
+// long running background loops stop, while contexts of higher priorities are
+// canceled only after their in-flight work did not finish within the stage timeout.
+func (d *Drainer) Context(p Priority) context.Context {
+	return d.stage(p).ctx
+}
+
+// Done returns channel that is closed when draining has completed.
+func (d *Drainer) Done() <-chan struct{} {
+	return d.done
+}
+
+// OnDrain registers function to be called when draining of the priority starts,
+// for example to stop queue subscriptions or close listeners.
+//
+// If draining of the priority has already started, function is called immediately.
+func (d *Drainer) OnDrain(p Priority, fn func(ctx context.Context)) {
+	d.lock.Lock()
+	if d.admitted(p) {
+		s := d.stage(p)
+		s.hooks = append(s.hooks, fn)
+		d.lock.Unlock()
+		return
+	}
+	d.lock.Unlock()
+
+	fn(context.Background())
+}
+
+// Drain stops admission of new work priority by priority, starting with the
+// lowest, and waits for in-flight work of each priority to finish.
+//
+// Each stage waits at most the stage timeout and draining continues with the
+// next priority even if in-flight work did not finish in time. Returns
+// ErrStageTimeout if any stage timed out. Draining is started only once,
+// subsequent calls wait for it to complete.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.lock.Lock()
+	if d.draining {
+		d.lock.Unlock()
+		select {
+		case <-d.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	d.draining = true
+	d.lock.Unlock()
+
+	defer close(d.done)
+
+	d.logger.Info("Draining started")
+
+	var errs []error
+	for _, p := range priorities {
+		if err := d.drain(ctx, p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	d.logger.Info("Draining completed")
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// drain stops admission of the work with the priority and waits for
+// in-flight work to finish.
+func (d *Drainer) drain(ctx context.Context, p Priority) error {
+	s := d.stage(p)
+
+	d.lock.Lock()
+	d.stopped = int(p) + 1
+	hooks := s.hooks
+	s.hooks = nil
+	var idle chan struct{}
+	if s.inflight > 0 {
+		idle = make(chan struct{})
+		s.idle = idle
+	}
+	d.lock.Unlock()
+
+	finish := d.instrumenter.Observe(ctx, InstrumentationDrain,
+		instrumenter.Label{Name: "priority", Value: p.String()},
+	)
+
+	if p == Background {
+		s.cancel()
+	}
+	defer s.cancel()
+
+	for _, fn := range hooks {
+		fn(ctx)
+	}
+
+	if idle == nil {
+		finish(nil)
+		return nil
+	}
+
+	wait := ctx
+	if d.stageTimeout > 0 {
+		var cancel context.CancelFunc
+		wait, cancel = context.WithTimeout(ctx, d.stageTimeout)
+		defer cancel()
+	}
+
+	select {
+	case <-idle:
+		finish(nil)
+		return nil
+	case <-wait.Done():
+	}
+
+	n := d.InFlight(p)
+	err := fmt.Errorf("%w: %d %s tasks still running", ErrStageTimeout, n, p)
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	d.logger.Warn("Draining stage did not complete", zap.Stringer("priority", p), zap.Int("inflight", n), zap.Error(err))
+	finish(err)
+	return err
+}
@@ -0,0 +1,174 @@
+package drain
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"azugo.io/core/queue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainOrder(t *testing.T) {
+	d := New(StageTimeout(time.Second))
+
+	var order []string
+	d.OnDrain(Background, func(context.Context) { order = append(order, "background") })
+	d.OnDrain(Request, func(context.Context) { order = append(order, "request") })
+
+	releaseRequest, err := d.Acquire(Request)
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- d.Drain(context.TODO())
+	}()
+
+	select {
+	case <-d.Context(Background).Done():
+	case <-time.After(time.Second):
+		t.Fatal("background context was not canceled")
+	}
+
+	require.Eventually(t, func() bool {
+		return !d.Admitted(Request)
+	}, time.Second, 5*time.Millisecond)
+
+	// Background work is rejected, in-flight request is still running and
+	// critical work is still admitted.
+	_, err = d.Acquire(Background)
+	assert.ErrorIs(t, err, ErrDraining)
+	_, err = d.Acquire(Request)
+	assert.ErrorIs(t, err, ErrDraining)
+	assert.Equal(t, 1, d.InFlight(Request))
+	assert.NoError(t, d.Context(Request).Err())
+	releaseCritical, err := d.Acquire(Critical)
+	require.NoError(t, err)
+	releaseCritical()
+
+	releaseRequest()
+	releaseRequest()
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"background", "request"}, order)
+	assert.True(t, d.Draining())
+	assert.Error(t, d.Context(Critical).Err())
+
+	_, err = d.Acquire(Critical)
+	assert.ErrorIs(t, err, ErrDraining)
+
+	// Hook registered after draining is called immediately.
+	called := false
+	d.OnDrain(Request, func(context.Context) { called = true })
+	assert.True(t, called)
+
+	require.NoError(t, d.Drain(context.TODO()))
+}
+
+func TestDrainStageTimeout(t *testing.T) {
+	d := New(StageTimeout(20 * time.Millisecond))
+
+	_, err := d.Acquire(Request)
+	require.NoError(t, err)
+
+	err = d.Drain(context.TODO())
+	assert.ErrorIs(t, err, ErrStageTimeout)
+	assert.Error(t, d.Context(Request).Err())
+	select {
+	case <-d.Done():
+	default:
+		t.Fatal("draining was not completed")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	d := New(RetryAfter(5 * time.Second))
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	h := d.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-finish
+		}
+		w.WriteHeader(http.StatusOK)
+	}), "/health")
+
+	slow := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		h.ServeHTTP(slow, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-started
+
+	drained := make(chan error)
+	go func() {
+		drained <- d.Drain(context.TODO())
+	}()
+	require.Eventually(t, func() bool {
+		return !d.Admitted(Request)
+	}, time.Second, 5*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "close", rec.Header().Get("Connection"))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	close(finish)
+	<-served
+	assert.Equal(t, http.StatusOK, slow.Code)
+	require.NoError(t, <-drained)
+}
+
+func TestBackgroundHooks(t *testing.T) {
+	d := New()
+
+	handled := 0
+	handler := d.Handler(func(context.Context, *queue.Message) error {
+		handled++
+		return nil
+	})
+
+	ran := 0
+	task := d.Run(func(ctx context.Context) error {
+		ran++
+		return nil
+	})
+
+	require.NoError(t, handler(context.TODO(), &queue.Message{}))
+	require.NoError(t, task(context.TODO()))
+
+	require.NoError(t, d.Drain(context.TODO()))
+
+	assert.ErrorIs(t, handler(context.TODO(), &queue.Message{}), ErrDraining)
+	require.NoError(t, task(context.TODO()))
+	assert.Equal(t, 1, handled)
+	assert.Equal(t, 1, ran)
+}
+
+func TestRunCanceled(t *testing.T) {
+	d := New()
+
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- d.Run(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})(context.TODO())
+	}()
+	<-started
+
+	require.NoError(t, d.Drain(context.TODO()))
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package drain
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"azugo.io/core/queue"
+)
+
+// Middleware tracks in-flight requests and responds with 503 Service Unavailable
+// to new requests once request draining has started.
+//
+// Requests to the paths with allowed prefixes (for example health checks) are
+// tracked with critical priority and are served until draining completes.
+func (d *Drainer) Middleware(next http.Handler, allowed ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := Request
+		for _, prefix := range allowed {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				p = Critical
+				break
+			}
+		}
+
+		release, err := d.Acquire(p)
+		if err != nil {
+			w.Header().Set("Connection", "close")
+			if d.retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.retryAfter.Seconds()))))
+			}
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Handler wraps queue message handler to track message processing as
+// background work. Messages received after draining has started are rejected
+// with ErrDraining so that they can be redelivered to other instances.
+func (d *Drainer) Handler(next queue.Handler) queue.Handler {
+	return func(ctx context.Context, msg *queue.Message) error {
+		release, err := d.Acquire(Background)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return next(ctx, msg)
+	}
+}
+
+// Run wraps background task function, for example cache refresh, to skip
+// execution once draining has started. Context of the running function is
+// canceled when background work must stop.
+func (d *Drainer) Run(fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		release, err := d.Acquire(Background)
+		if err != nil {
+			return nil
+		}
+		defer release()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stop := d.Context(Background)
+		go func() {
+			select {
+			case <-stop.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		return fn(ctx)
+	}
+}
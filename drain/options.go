@@ -0,0 +1,72 @@
+package drain
+
+import (
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"go.uber.org/zap"
+)
+
+// DefaultStageTimeout is a default maximum time to wait for in-flight work of
+// a single priority to finish.
+const DefaultStageTimeout = 10 * time.Second
+
+type options struct {
+	StageTimeout time.Duration
+	RetryAfter   time.Duration
+	Instrumenter instrumenter.Instrumenter
+	Logger       *zap.Logger
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		StageTimeout: DefaultStageTimeout,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Instrumenter == nil {
+		opt.Instrumenter = instrumenter.NullInstrumenter
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return opt
+}
+
+// Option for the drainer.
+type Option interface {
+	apply(*options)
+}
+
+// StageTimeout is a maximum time to wait for in-flight work of a single
+// priority to finish before draining next priority. Defaults to 10 seconds.
+type StageTimeout time.Duration
+
+func (t StageTimeout) apply(o *options) {
+	o.StageTimeout = time.Duration(t)
+}
+
+// RetryAfter is a value of the Retry-After header of rejected requests.
+type RetryAfter time.Duration
+
+func (r RetryAfter) apply(o *options) {
+	o.RetryAfter = time.Duration(r)
+}
+
+// Instrumenter to observe drain stages.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
+
+// Logger to log drain progress.
+type Logger struct {
+	*zap.Logger
+}
+
+func (l Logger) apply(o *options) {
+	o.Logger = l.Logger
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"azugo.io/core/drain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainOnStop(t *testing.T) {
+	a, cleanup, _, err := newTestApp()
+	require.NoError(t, err)
+	t.Cleanup(cleanup)
+
+	d := a.Drainer()
+	require.NoError(t, a.Start())
+
+	var drained []drain.Priority
+	d.OnDrain(drain.Background, func(context.Context) { drained = append(drained, drain.Background) })
+	d.OnDrain(drain.Request, func(context.Context) { drained = append(drained, drain.Request) })
+
+	a.Stop()
+
+	assert.True(t, d.Draining())
+	assert.Equal(t, []drain.Priority{drain.Background, drain.Request}, drained)
+}
+
+func TestDrainSkipsWarmers(t *testing.T) {
+	a, cleanup, _, err := newTestApp()
+	require.NoError(t, err)
+	t.Cleanup(cleanup)
+
+	require.NoError(t, a.Start())
+	defer a.Stop()
+
+	require.NoError(t, a.Drainer().Drain(context.TODO()))
+
+	var warmed bool
+	assert.ErrorIs(t, a.AddWarmer(WarmerFunc("late", func(ctx context.Context) error {
+		warmed = true
+		return nil
+	}), WarmerRequired(true)), drain.ErrDraining)
+	assert.False(t, warmed)
+}
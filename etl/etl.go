@@ -0,0 +1,361 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBatchSize is a default maximum number of records passed to the writer at once.
+	DefaultBatchSize = 100
+	// DefaultReportInterval is a default minimum interval between progress reports.
+	DefaultReportInterval = time.Second
+
+	// InstrumentationETL is an instrumentation operation for the pipeline run.
+	InstrumentationETL = "etl"
+
+	// maxRecordErrors is a maximum number of record errors kept in the Error.
+	maxRecordErrors = 100
+)
+
+// ErrSkip can be returned by the transform to skip the record without failing it.
+var ErrSkip = errors.New("skip record")
+
+// Reader reads records one by one. Read must return io.EOF when there are no more records.
+//
+// Reader is called only from a single goroutine.
+type Reader[T any] interface {
+	Read(ctx context.Context) (T, error)
+}
+
+// ReaderFunc is a function that implements Reader.
+type ReaderFunc[T any] func(ctx context.Context) (T, error)
+
+// Read calls the function.
+func (f ReaderFunc[T]) Read(ctx context.Context) (T, error) {
+	return f(ctx)
+}
+
+// Transform converts input record to the output record.
+//
+// Transform can be called concurrently depending on the Concurrency option.
+type Transform[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// Identity is a transform that returns record as is.
+func Identity[T any](_ context.Context, v T) (T, error) {
+	return v, nil
+}
+
+// Writer writes batches of records.
+//
+// Writer is called only from a single goroutine and must not retain the batch after return.
+type Writer[T any] interface {
+	Write(ctx context.Context, batch []T) error
+}
+
+// WriterFunc is a function that implements Writer.
+type WriterFunc[T any] func(ctx context.Context, batch []T) error
+
+// Write calls the function.
+func (f WriterFunc[T]) Write(ctx context.Context, batch []T) error {
+	return f(ctx, batch)
+}
+
+// Reporter receives pipeline progress. It is implemented by *job.Job so that
+// pipeline progress is tracked by the job-tracking subsystem.
+//
+// Returning error from Progress, for example job.ErrJobCanceled, aborts the pipeline.
+type Reporter interface {
+	Progress(ctx context.Context, current, total int64, message string) error
+}
+
+// Stage of the pipeline where record has failed.
+type Stage string
+
+const (
+	// StageTransform is a stage where records are transformed.
+	StageTransform Stage = "transform"
+	// StageWrite is a stage where batches of records are written.
+	StageWrite Stage = "write"
+)
+
+// RecordError is an error of the single record or batch of records.
+type RecordError struct {
+	// Stage where record has failed.
+	Stage Stage
+	// Index of the record in the input. For failed batches it is an index of
+	// the first record in the batch.
+	Index int64
+	// Count is a number of failed records.
+	Count int
+	// Err is an error returned by the transform or writer.
+	Err error
+}
+
+func (e *RecordError) Error() string {
+	if e.Count > 1 {
+		return fmt.Sprintf("%s of %d records starting at %d failed: %v", e.Stage, e.Count, e.Index, e.Err)
+	}
+	return fmt.Sprintf("%s of record %d failed: %v", e.Stage, e.Index, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Error is returned when some of the records have failed.
+type Error struct {
+	// Errors contains errors of the first failed records.
+	Errors []*RecordError
+	// Failed is a total number of failed records.
+	Failed int64
+	// Aborted is true if pipeline was stopped as MaxErrors was exceeded.
+	Aborted bool
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%d records failed", e.Failed)
+	if e.Aborted {
+		msg += ", pipeline aborted"
+	}
+	if len(e.Errors) > 0 {
+		msg += ": " + e.Errors[0].Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e.Errors[0]
+}
+
+// Stats of the pipeline run.
+type Stats struct {
+	// Read is a number of records read.
+	Read int64
+	// Written is a number of records written.
+	Written int64
+	// Skipped is a number of records skipped by the transform.
+	Skipped int64
+	// Failed is a number of failed records.
+	Failed int64
+	// Duration of the pipeline run.
+	Duration time.Duration
+}
+
+type record[T any] struct {
+	index int64
+	value T
+}
+
+type pipeline struct {
+	opts   *options
+	cancel context.CancelFunc
+
+	lock       sync.Mutex
+	stats      Stats
+	errors     []*RecordError
+	aborted    bool
+	fatal      error
+	lastReport time.Time
+}
+
+func (p *pipeline) abort(err error) {
+	p.lock.Lock()
+	if p.fatal == nil {
+		p.fatal = err
+	}
+	p.lock.Unlock()
+	p.cancel()
+}
+
+func (p *pipeline) fail(e *RecordError) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.stats.Failed += int64(e.Count)
+	if len(p.errors) < maxRecordErrors {
+		p.errors = append(p.errors, e)
+	}
+	if p.opts.MaxErrors >= 0 && p.stats.Failed > int64(p.opts.MaxErrors) && !p.aborted {
+		p.aborted = true
+		p.cancel()
+	}
+}
+
+func (p *pipeline) count(fn func(s *Stats)) {
+	p.lock.Lock()
+	fn(&p.stats)
+	p.lock.Unlock()
+}
+
+func (p *pipeline) report(ctx context.Context, final bool) error {
+	if p.opts.Reporter == nil {
+		return nil
+	}
+
+	p.lock.Lock()
+	now := time.Now()
+	if !final && now.Sub(p.lastReport) < p.opts.ReportInterval {
+		p.lock.Unlock()
+		return nil
+	}
+	p.lastReport = now
+	s := p.stats
+	p.lock.Unlock()
+
+	return p.opts.Reporter.Progress(ctx, s.Written+s.Skipped+s.Failed, p.opts.Total,
+		fmt.Sprintf("%d written, %d skipped, %d failed", s.Written, s.Skipped, s.Failed))
+}
+
+// Copy reads records and writes them in batches without transforming.
+func Copy[T any](ctx context.Context, r Reader[T], w Writer[T], opts ...Option) (Stats, error) {
+	return Run(ctx, r, Identity[T], w, opts...)
+}
+
+// Run streams records from the reader through the transform to the writer in batches.
+//
+// Failed records are aggregated into the *Error that is returned after all records
+// are processed or pipeline is aborted as MaxErrors was exceeded. Reader and
+// progress reporter errors abort the pipeline immediately.
+func Run[In, Out any](ctx context.Context, r Reader[In], t Transform[In, Out], w Writer[Out], opts ...Option) (Stats, error) {
+	p := &pipeline{
+		opts: newOptions(opts...),
+	}
+	start := time.Now()
+	finish := p.opts.Instrumenter.Observe(ctx, InstrumentationETL, p.opts.Name)
+
+	rctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	p.cancel = cancel
+
+	in := make(chan record[In], p.opts.Concurrency)
+	out := make(chan record[Out], p.opts.BatchSize)
+
+	go func() {
+		defer close(in)
+
+		for i := int64(0); ; i++ {
+			v, err := r.Read(rctx)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				if rctx.Err() == nil {
+					p.abort(fmt.Errorf("read record %d: %w", i, err))
+				}
+				return
+			}
+			p.count(func(s *Stats) { s.Read++ })
+
+			select {
+			case in <- record[In]{index: i, value: v}:
+			case <-rctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for rec := range in {
+				if rctx.Err() != nil {
+					return
+				}
+				v, err := t(rctx, rec.value)
+				if errors.Is(err, ErrSkip) {
+					p.count(func(s *Stats) { s.Skipped++ })
+					continue
+				}
+				if err != nil {
+					p.fail(&RecordError{Stage: StageTransform, Index: rec.index, Count: 1, Err: err})
+					continue
+				}
+
+				select {
+				case out <- record[Out]{index: rec.index, value: v}:
+				case <-rctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	var first int64
+	batch := make([]Out, 0, p.opts.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.Write(rctx, batch); err != nil {
+			p.fail(&RecordError{Stage: StageWrite, Index: first, Count: len(batch), Err: err})
+		} else {
+			p.count(func(s *Stats) { s.Written += int64(len(batch)) })
+		}
+		batch = make([]Out, 0, p.opts.BatchSize)
+
+		if err := p.report(rctx, false); err != nil {
+			p.abort(err)
+		}
+	}
+
+	for rec := range out {
+		if rctx.Err() != nil {
+			// Drain remaining records so that workers can exit.
+			continue
+		}
+		if len(batch) == 0 {
+			first = rec.index
+		}
+		batch = append(batch, rec.value)
+		if len(batch) >= p.opts.BatchSize {
+			flush()
+		}
+	}
+	if rctx.Err() == nil {
+		flush()
+	}
+
+	p.lock.Lock()
+	p.stats.Duration = time.Since(start)
+	stats := p.stats
+	var err error
+	switch {
+	case p.fatal != nil:
+		err = p.fatal
+	case ctx.Err() != nil:
+		err = ctx.Err()
+	case p.aborted || stats.Failed > 0:
+		err = &Error{
+			Errors:  p.errors,
+			Failed:  stats.Failed,
+			Aborted: p.aborted,
+		}
+	}
+	p.lock.Unlock()
+
+	if err == nil {
+		err = p.report(ctx, true)
+	}
+
+	finish(err)
+	return stats, err
+}
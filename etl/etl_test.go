@@ -0,0 +1,177 @@
+package etl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/job"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func numbers(n int) []int {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+	return items
+}
+
+type collector[T any] struct {
+	lock    sync.Mutex
+	items   []T
+	batches int
+}
+
+func (c *collector[T]) Write(_ context.Context, batch []T) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.items = append(c.items, batch...)
+	c.batches++
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	w := &collector[string]{}
+	stats, err := Run(context.TODO(), SliceReader(numbers(100)), func(_ context.Context, v int) (string, error) {
+		if v%10 == 0 {
+			return "", ErrSkip
+		}
+		return strconv.Itoa(v), nil
+	}, w, Concurrency(4), BatchSize(7))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(100), stats.Read)
+	assert.Equal(t, int64(90), stats.Written)
+	assert.Equal(t, int64(10), stats.Skipped)
+	assert.Zero(t, stats.Failed)
+	assert.Len(t, w.items, 90)
+	assert.Equal(t, 13, w.batches)
+}
+
+func TestRunErrors(t *testing.T) {
+	failing := func(_ context.Context, v int) (int, error) {
+		if v%2 == 1 {
+			return 0, errors.New("odd")
+		}
+		return v, nil
+	}
+
+	w := &collector[int]{}
+	stats, err := Run(context.TODO(), SliceReader(numbers(10)), failing, w, MaxErrors(-1))
+	var rerr *Error
+	require.ErrorAs(t, err, &rerr)
+	assert.False(t, rerr.Aborted)
+	assert.Equal(t, int64(5), rerr.Failed)
+	assert.Len(t, rerr.Errors, 5)
+	assert.Equal(t, StageTransform, rerr.Errors[0].Stage)
+	assert.Equal(t, int64(1), rerr.Errors[0].Index)
+	assert.Equal(t, "5 records failed: transform of record 1 failed: odd", err.Error())
+	assert.Equal(t, int64(5), stats.Written)
+
+	_, err = Run(context.TODO(), SliceReader(numbers(1000)), failing, &collector[int]{}, MaxErrors(2))
+	require.ErrorAs(t, err, &rerr)
+	assert.True(t, rerr.Aborted)
+	assert.Equal(t, int64(3), rerr.Failed)
+
+	_, err = Copy(context.TODO(), SliceReader(numbers(10)), WriterFunc[int](func(_ context.Context, batch []int) error {
+		return errors.New("write failed")
+	}), BatchSize(4), MaxErrors(-1))
+	require.ErrorAs(t, err, &rerr)
+	assert.Equal(t, int64(10), rerr.Failed)
+	assert.Equal(t, &RecordError{Stage: StageWrite, Index: 4, Count: 4, Err: errors.New("write failed")}, rerr.Errors[1])
+}
+
+func TestRunReadError(t *testing.T) {
+	i := 0
+	r := ReaderFunc[int](func(ctx context.Context) (int, error) {
+		if i == 3 {
+			return 0, errors.New("broken")
+		}
+		i++
+		return i, nil
+	})
+
+	stats, err := Copy[int](context.TODO(), r, &collector[int]{})
+	assert.EqualError(t, err, "read record 3: broken")
+	assert.Equal(t, int64(3), stats.Read)
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan int)
+	go func() {
+		ch <- 1
+		cancel()
+	}()
+
+	_, err := Copy(ctx, ChanReader(ch), &collector[int]{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRunJobProgress(t *testing.T) {
+	c := cache.New(cache.MemoryCache)
+	require.NoError(t, c.Start(context.TODO()))
+	t.Cleanup(c.Close)
+
+	tr, err := job.New[Stats](c, "imports")
+	require.NoError(t, err)
+
+	w := &collector[int]{}
+	j, err := tr.Start(context.Background(), func(ctx context.Context, j *job.Job[Stats]) (Stats, error) {
+		return Copy(ctx, SliceReader(numbers(50)), w, Total(50), BatchSize(10), Report{j}, ReportInterval(0))
+	})
+	require.NoError(t, err)
+
+	var s *job.Status[Stats]
+	require.Eventually(t, func() bool {
+		s, err = tr.Get(context.TODO(), j.ID())
+		return err == nil && s.State == job.StateCompleted
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(50), s.Result.Written)
+	assert.Equal(t, int64(50), s.Current)
+	assert.Equal(t, "50 written, 0 skipped, 0 failed", s.Message)
+
+	reports := 0
+	_, err = Copy(context.TODO(), SliceReader(numbers(50)), &collector[int]{}, BatchSize(10), ReportFunc(func(ctx context.Context, current, total int64, message string) error {
+		reports++
+		if current >= 20 {
+			return job.ErrJobCanceled
+		}
+		return nil
+	}), ReportInterval(0))
+	assert.ErrorIs(t, err, job.ErrJobCanceled)
+	assert.Equal(t, 2, reports)
+}
+
+func TestJSONLinesCache(t *testing.T) {
+	var buf bytes.Buffer
+	entries := []Entry[int]{{Key: "a", Value: 1}, {Key: "b", Value: 2}}
+	_, err := Copy(context.TODO(), SliceReader(entries), JSONLinesWriter[Entry[int]](&buf))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"key\":\"a\",\"value\":1}\n{\"key\":\"b\",\"value\":2}\n", buf.String())
+
+	c := cache.New(cache.MemoryCache, cache.MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	t.Cleanup(c.Close)
+	inst, err := cache.Create[int](c, "import")
+	require.NoError(t, err)
+
+	stats, err := Copy(context.TODO(), JSONLinesReader[Entry[int]](&buf), CacheWriter(inst), Concurrency(2))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Written)
+
+	for k, want := range map[string]int{"a": 1, "b": 2} {
+		v, err := inst.Get(context.TODO(), k)
+		require.NoError(t, err)
+		assert.Equal(t, want, v)
+	}
+}
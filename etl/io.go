@@ -0,0 +1,87 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package etl
+
+import (
+	"bufio"
+	"context"
+	"io"
+
+	"azugo.io/core/cache"
+
+	"github.com/goccy/go-json"
+)
+
+// SliceReader returns reader that reads records from the slice.
+func SliceReader[T any](items []T) Reader[T] {
+	i := 0
+	return ReaderFunc[T](func(ctx context.Context) (T, error) {
+		var v T
+		if i >= len(items) {
+			return v, io.EOF
+		}
+		v = items[i]
+		i++
+		return v, nil
+	})
+}
+
+// ChanReader returns reader that reads records from the channel until it is closed.
+func ChanReader[T any](ch <-chan T) Reader[T] {
+	return ReaderFunc[T](func(ctx context.Context) (T, error) {
+		var v T
+		select {
+		case <-ctx.Done():
+			return v, ctx.Err()
+		case v, ok := <-ch:
+			if !ok {
+				return v, io.EOF
+			}
+			return v, nil
+		}
+	})
+}
+
+// JSONLinesReader returns reader that decodes records from the newline delimited JSON stream.
+func JSONLinesReader[T any](r io.Reader) Reader[T] {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	return ReaderFunc[T](func(ctx context.Context) (T, error) {
+		var v T
+		err := dec.DecodeContext(ctx, &v)
+		return v, err
+	})
+}
+
+// JSONLinesWriter returns writer that encodes records to the newline delimited JSON stream.
+func JSONLinesWriter[T any](w io.Writer) Writer[T] {
+	enc := json.NewEncoder(w)
+	return WriterFunc[T](func(ctx context.Context, batch []T) error {
+		for _, v := range batch {
+			if err := enc.EncodeContext(ctx, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Entry is a cache entry record.
+type Entry[T any] struct {
+	Key   string `json:"key"`
+	Value T      `json:"value"`
+}
+
+// CacheWriter returns writer that stores entries in the cache instance, for
+// example to warm up the cache or to import exported entries.
+func CacheWriter[T any](c cache.CacheInstance[T], opts ...cache.ItemOption[T]) Writer[Entry[T]] {
+	return WriterFunc[Entry[T]](func(ctx context.Context, batch []Entry[T]) error {
+		for _, e := range batch {
+			if err := c.Set(ctx, e.Key, e.Value, opts...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,115 @@
+package etl
+
+import (
+	"context"
+	"time"
+
+	"azugo.io/core/instrumenter"
+)
+
+type options struct {
+	Name           string
+	Concurrency    int
+	BatchSize      int
+	MaxErrors      int
+	Total          int64
+	Reporter       Reporter
+	ReportInterval time.Duration
+	Instrumenter   instrumenter.Instrumenter
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Concurrency:    1,
+		BatchSize:      DefaultBatchSize,
+		ReportInterval: DefaultReportInterval,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Concurrency < 1 {
+		opt.Concurrency = 1
+	}
+	if opt.BatchSize < 1 {
+		opt.BatchSize = 1
+	}
+	return opt
+}
+
+// Option for the pipeline.
+type Option interface {
+	apply(*options)
+}
+
+// Name of the pipeline used for instrumentation.
+type Name string
+
+func (n Name) apply(o *options) {
+	o.Name = string(n)
+}
+
+// Concurrency is a number of records transformed concurrently. Defaults to 1.
+//
+// With concurrency greater than 1 records can be written in a different order than read.
+type Concurrency int
+
+func (c Concurrency) apply(o *options) {
+	o.Concurrency = int(c)
+}
+
+// BatchSize is a maximum number of records passed to the writer at once.
+type BatchSize int
+
+func (b BatchSize) apply(o *options) {
+	o.BatchSize = int(b)
+}
+
+// MaxErrors is a number of failed records tolerated before pipeline is aborted.
+// Zero aborts on first failure, negative value never aborts.
+type MaxErrors int
+
+func (m MaxErrors) apply(o *options) {
+	o.MaxErrors = int(m)
+}
+
+// Total is an expected number of records used to report progress.
+type Total int64
+
+func (t Total) apply(o *options) {
+	o.Total = int64(t)
+}
+
+// Report progress to the reporter, for example tracked job.
+type Report struct {
+	Reporter
+}
+
+func (r Report) apply(o *options) {
+	o.Reporter = r.Reporter
+}
+
+// ReportFunc reports progress by calling the function.
+type ReportFunc func(ctx context.Context, current, total int64, message string) error
+
+func (f ReportFunc) Progress(ctx context.Context, current, total int64, message string) error {
+	return f(ctx, current, total, message)
+}
+
+func (f ReportFunc) apply(o *options) {
+	o.Reporter = f
+}
+
+// ReportInterval is a minimum interval between progress reports. Final progress
+// is always reported.
+type ReportInterval time.Duration
+
+func (r ReportInterval) apply(o *options) {
+	o.ReportInterval = time.Duration(r)
+}
+
+// Instrumenter to observe pipeline runs.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
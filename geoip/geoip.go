@@ -0,0 +1,214 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package geoip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/instrumenter"
+)
+
+const (
+	// DefaultName is a default cache instance name for the lookup results.
+	DefaultName = "geoip"
+
+	// InstrumentationLookup is an instrumentation operation for provider lookups.
+	InstrumentationLookup = "geoip-lookup"
+)
+
+// ErrInvalidIP is returned when IP address can not be parsed.
+var ErrInvalidIP = errors.New("invalid IP address")
+
+// Info is an information about the IP address.
+type Info struct {
+	// CountryCode is an ISO 3166-1 alpha-2 country code.
+	CountryCode string `json:"country_code,omitempty"`
+	// CountryName is an English name of the country.
+	CountryName string `json:"country_name,omitempty"`
+	// ContinentCode is a continent code (for example "EU").
+	ContinentCode string `json:"continent_code,omitempty"`
+	// ASN is an autonomous system number.
+	ASN uint `json:"asn,omitempty"`
+	// Organization is an autonomous system organization.
+	Organization string `json:"organization,omitempty"`
+	// Private is true for loopback, private and link-local addresses.
+	Private bool `json:"private,omitempty"`
+}
+
+// Known returns true if country or autonomous system of the IP address is known.
+func (i *Info) Known() bool {
+	return len(i.CountryCode) != 0 || i.ASN != 0
+}
+
+// InCountry returns true if IP address is located in any of the countries.
+func (i *Info) InCountry(codes ...string) bool {
+	for _, c := range codes {
+		if strings.EqualFold(i.CountryCode, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// InASN returns true if IP address belongs to any of the autonomous systems.
+func (i *Info) InASN(asns ...uint) bool {
+	for _, a := range asns {
+		if i.ASN == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Provider resolves IP address information.
+type Provider interface {
+	// Lookup returns information about the IP address. Unknown addresses
+	// return empty information without an error.
+	Lookup(ctx context.Context, ip net.IP) (*Info, error)
+}
+
+// ProviderFunc is a function that implements Provider interface.
+type ProviderFunc func(ctx context.Context, ip net.IP) (*Info, error)
+
+// Lookup calls the function.
+func (f ProviderFunc) Lookup(ctx context.Context, ip net.IP) (*Info, error) {
+	return f(ctx, ip)
+}
+
+// Resolver resolves IP address information with results cached.
+type Resolver struct {
+	provider     Provider
+	cache        cache.CacheInstance[*Info]
+	ttl          cache.TTL[*Info]
+	instrumenter instrumenter.Instrumenter
+}
+
+// New creates new IP address resolver using provider.
+func New(provider Provider, opts ...Option) (*Resolver, error) {
+	opt := newOptions(opts...)
+
+	r := &Resolver{
+		provider:     provider,
+		ttl:          cache.TTL[*Info](opt.TTL),
+		instrumenter: opt.Instrumenter,
+	}
+	if opt.Cache != nil {
+		i, err := cache.Create[*Info](opt.Cache, opt.Name, opt.CacheOptions...)
+		if err != nil {
+			return nil, err
+		}
+		r.cache = i
+	}
+	return r, nil
+}
+
+// ParseIP parses IP address from the string that can also contain port.
+func ParseIP(addr string) (net.IP, error) {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip := net.ParseIP(strings.Trim(addr, "[]"))
+	if ip == nil {
+		return nil, ErrInvalidIP
+	}
+	return ip, nil
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// Lookup returns information about the IP address.
+//
+// Private addresses are not looked up and return information with Private flag set.
+func (r *Resolver) Lookup(ctx context.Context, ip net.IP) (*Info, error) {
+	if ip == nil {
+		return nil, ErrInvalidIP
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	if isPrivate(ip) {
+		return &Info{Private: true}, nil
+	}
+
+	key := ip.String()
+	if r.cache != nil {
+		info, err := r.cache.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if info != nil {
+			return info, nil
+		}
+	}
+
+	finish := r.instrumenter.Observe(ctx, InstrumentationLookup, key)
+	info, err := r.provider.Lookup(ctx, ip)
+	finish(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup IP address %s: %w", key, err)
+	}
+	if info == nil {
+		info = &Info{}
+	}
+	if r.cache != nil {
+		if err := r.cache.Set(ctx, key, info, r.ttl); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// LookupAddr returns information about the IP address in string form that can also contain port.
+func (r *Resolver) LookupAddr(ctx context.Context, addr string) (*Info, error) {
+	ip, err := ParseIP(addr)
+	if err != nil {
+		return nil, err
+	}
+	return r.Lookup(ctx, ip)
+}
+
+// Country returns ISO country code of the IP address or empty string if unknown.
+func (r *Resolver) Country(ctx context.Context, ip net.IP) (string, error) {
+	info, err := r.Lookup(ctx, ip)
+	if err != nil {
+		return "", err
+	}
+	return info.CountryCode, nil
+}
+
+// ASN returns autonomous system number of the IP address or zero if unknown.
+func (r *Resolver) ASN(ctx context.Context, ip net.IP) (uint, error) {
+	info, err := r.Lookup(ctx, ip)
+	if err != nil {
+		return 0, err
+	}
+	return info.ASN, nil
+}
+
+// RateKey returns key to group IP addresses for rate limiting by country
+// ("country:LV") or autonomous system ("asn:1234"). If group is not known,
+// IP address itself is used ("ip:192.0.2.1").
+func (r *Resolver) RateKey(ctx context.Context, ip net.IP, byASN bool) (string, error) {
+	info, err := r.Lookup(ctx, ip)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case byASN && info.ASN != 0:
+		return "asn:" + strconv.FormatUint(uint64(info.ASN), 10), nil
+	case !byASN && len(info.CountryCode) != 0:
+		return "country:" + info.CountryCode, nil
+	}
+	return "ip:" + ip.String(), nil
+}
@@ -0,0 +1,271 @@
+package geoip
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"azugo.io/core/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mmdbEncode encodes value in MaxMind DB data section format.
+func mmdbEncode(buf *bytes.Buffer, v any) {
+	ctrl := func(typ, size int) {
+		var ext []byte
+		if size >= 29 {
+			ext = []byte{byte(size - 29)}
+			size = 29
+		}
+		if typ > 7 {
+			buf.WriteByte(byte(size))
+			buf.WriteByte(byte(typ - 7))
+		} else {
+			buf.WriteByte(byte(typ<<5 | size))
+		}
+		buf.Write(ext)
+	}
+	switch vv := v.(type) {
+	case string:
+		ctrl(2, len(vv))
+		buf.WriteString(vv)
+	case uint32:
+		b := []byte{byte(vv >> 24), byte(vv >> 16), byte(vv >> 8), byte(vv)}
+		for len(b) > 0 && b[0] == 0 {
+			b = b[1:]
+		}
+		ctrl(6, len(b))
+		buf.Write(b)
+	case []string:
+		ctrl(11, len(vv))
+		for _, s := range vv {
+			mmdbEncode(buf, s)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		ctrl(7, len(keys))
+		for _, k := range keys {
+			mmdbEncode(buf, k)
+			mmdbEncode(buf, vv[k])
+		}
+	default:
+		panic("unsupported type")
+	}
+}
+
+// buildMMDB returns IPv4 MaxMind DB with 24 bit records for the networks.
+func buildMMDB(t *testing.T, networks map[string]map[string]any) []byte {
+	t.Helper()
+
+	const empty = -1
+	type node [2]int
+	nodes := []node{{empty, empty}}
+	data := &bytes.Buffer{}
+	dataRefs := make(map[int]int)
+
+	cidrs := make([]string, 0, len(networks))
+	for c := range networks {
+		cidrs = append(cidrs, c)
+	}
+	sort.Strings(cidrs)
+	for n, c := range cidrs {
+		_, ipnet, err := net.ParseCIDR(c)
+		require.NoError(t, err)
+		ones, _ := ipnet.Mask.Size()
+		ip := ipnet.IP.To4()
+
+		dataRefs[n] = data.Len()
+		mmdbEncode(data, networks[c])
+
+		cur := 0
+		for i := 0; i < ones; i++ {
+			bit := int(ip[i/8]>>(7-i%8)) & 1
+			if i == ones-1 {
+				nodes[cur][bit] = -2 - n
+				break
+			}
+			if nodes[cur][bit] == empty {
+				nodes = append(nodes, node{empty, empty})
+				nodes[cur][bit] = len(nodes) - 1
+			}
+			cur = nodes[cur][bit]
+		}
+	}
+
+	count := len(nodes)
+	out := &bytes.Buffer{}
+	for _, nd := range nodes {
+		for _, r := range nd {
+			v := r
+			switch {
+			case r == empty:
+				v = count
+			case r <= -2:
+				v = count + 16 + dataRefs[-2-r]
+			}
+			out.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	out.Write(make([]byte, 16))
+	out.Write(data.Bytes())
+	out.WriteString("\xab\xcd\xefMaxMind.com")
+	mmdbEncode(out, map[string]any{
+		"binary_format_major_version": uint32(2),
+		"binary_format_minor_version": uint32(0),
+		"build_epoch":                 uint32(1),
+		"database_type":               "Test",
+		"ip_version":                  uint32(4),
+		"languages":                   []string{"en"},
+		"node_count":                  uint32(count),
+		"record_size":                 uint32(24),
+	})
+	return out.Bytes()
+}
+
+func newTestMaxMind(t *testing.T) *MaxMind {
+	t.Helper()
+
+	country := buildMMDB(t, map[string]map[string]any{
+		"81.198.0.0/16": {
+			"country":   map[string]any{"iso_code": "LV", "names": map[string]any{"en": "Latvia"}},
+			"continent": map[string]any{"code": "EU"},
+		},
+		"8.8.8.0/24": {
+			"country":   map[string]any{"iso_code": "US", "names": map[string]any{"en": "United States"}},
+			"continent": map[string]any{"code": "NA"},
+		},
+	})
+	asn := buildMMDB(t, map[string]map[string]any{
+		"81.198.0.0/16": {"autonomous_system_number": uint32(12578), "autonomous_system_organization": "Tet"},
+	})
+	m, err := MaxMindFromBytes(country, asn)
+	require.NoError(t, err)
+	return m
+}
+
+func TestMaxMindLookup(t *testing.T) {
+	m := newTestMaxMind(t)
+
+	info, err := m.Lookup(context.Background(), net.ParseIP("81.198.1.2"))
+	require.NoError(t, err)
+	assert.Equal(t, &Info{
+		CountryCode:   "LV",
+		CountryName:   "Latvia",
+		ContinentCode: "EU",
+		ASN:           12578,
+		Organization:  "Tet",
+	}, info)
+
+	info, err = m.Lookup(context.Background(), net.ParseIP("1.1.1.1"))
+	require.NoError(t, err)
+	assert.False(t, info.Known())
+
+	info, err = m.Lookup(context.Background(), net.ParseIP("2001:db8::1"))
+	require.NoError(t, err)
+	assert.False(t, info.Known())
+}
+
+func TestResolverCache(t *testing.T) {
+	c := cache.New(cache.MemoryCache, cache.MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.Background()))
+	defer c.Close()
+
+	calls := 0
+	m := newTestMaxMind(t)
+	r, err := New(ProviderFunc(func(ctx context.Context, ip net.IP) (*Info, error) {
+		calls++
+		return m.Lookup(ctx, ip)
+	}), Cache{c})
+	require.NoError(t, err)
+
+	for n := 0; n < 3; n++ {
+		code, err := r.Country(context.Background(), net.ParseIP("81.198.1.2"))
+		require.NoError(t, err)
+		assert.Equal(t, "LV", code)
+	}
+	assert.Equal(t, 1, calls)
+
+	info, err := r.LookupAddr(context.Background(), "10.0.0.1:8080")
+	require.NoError(t, err)
+	assert.True(t, info.Private)
+	assert.Equal(t, 1, calls)
+
+	_, err = r.LookupAddr(context.Background(), "invalid")
+	assert.ErrorIs(t, err, ErrInvalidIP)
+
+	key, err := r.RateKey(context.Background(), net.ParseIP("81.198.1.2"), true)
+	require.NoError(t, err)
+	assert.Equal(t, "asn:12578", key)
+	key, err = r.RateKey(context.Background(), net.ParseIP("8.8.8.8"), true)
+	require.NoError(t, err)
+	assert.Equal(t, "ip:8.8.8.8", key)
+	key, err = r.RateKey(context.Background(), net.ParseIP("8.8.8.8"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "country:US", key)
+}
+
+func TestResolverPolicy(t *testing.T) {
+	r, err := New(newTestMaxMind(t))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	lv, us, unknown, private := net.ParseIP("81.198.1.2"), net.ParseIP("8.8.8.8"), net.ParseIP("1.1.1.1"), net.ParseIP("127.0.0.1")
+
+	p := Policy{AllowCountries: []string{"lv", "LT", "EE"}}
+	assert.NoError(t, r.Check(ctx, lv, p))
+	assert.ErrorAs(t, r.Check(ctx, us, p), &ErrBlocked{})
+	assert.Error(t, r.Check(ctx, unknown, p))
+	assert.NoError(t, r.Check(ctx, private, p))
+
+	p.AllowUnknown = true
+	p.DenyPrivate = true
+	assert.NoError(t, r.Check(ctx, unknown, p))
+	assert.Error(t, r.Check(ctx, private, p))
+
+	p = Policy{DenyCountries: []string{"US"}, DenyASN: []uint{12578}}
+	assert.Error(t, r.Check(ctx, us, p))
+	assert.Error(t, r.Check(ctx, lv, p))
+	assert.NoError(t, r.Check(ctx, unknown, p))
+}
+
+func TestRemoteAPI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/ip/81.198.1.2" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"country_code":"LV","asn":12578}`))
+	}))
+	defer srv.Close()
+
+	api := &RemoteAPI{
+		URL:    srv.URL + "/ip/{ip}",
+		Header: http.Header{"X-Api-Key": []string{"secret"}},
+	}
+	info, err := api.Lookup(context.Background(), net.ParseIP("81.198.1.2"))
+	require.NoError(t, err)
+	assert.Equal(t, "LV", info.CountryCode)
+	assert.Equal(t, uint(12578), info.ASN)
+
+	info, err = api.Lookup(context.Background(), net.ParseIP("8.8.8.8"))
+	require.NoError(t, err)
+	assert.False(t, info.Known())
+
+	api.Header = nil
+	_, err = api.Lookup(context.Background(), net.ParseIP("8.8.8.8"))
+	assert.Error(t, err)
+}
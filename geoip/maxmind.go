@@ -0,0 +1,100 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package geoip
+
+import (
+	"context"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+type mmdbRecord struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+	ASN          uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// MaxMind is a provider that resolves IP addresses using MaxMind DB files,
+// for example GeoLite2 Country and GeoLite2 ASN databases.
+type MaxMind struct {
+	readers []*maxminddb.Reader
+}
+
+// OpenMaxMind opens MaxMind DB files. Information from all databases is merged
+// with earlier databases taking precedence.
+func OpenMaxMind(paths ...string) (*MaxMind, error) {
+	m := &MaxMind{
+		readers: make([]*maxminddb.Reader, 0, len(paths)),
+	}
+	for _, p := range paths {
+		r, err := maxminddb.Open(p)
+		if err != nil {
+			_ = m.Close()
+			return nil, err
+		}
+		m.readers = append(m.readers, r)
+	}
+	return m, nil
+}
+
+// MaxMindFromBytes creates provider from MaxMind DB contents.
+func MaxMindFromBytes(dbs ...[]byte) (*MaxMind, error) {
+	m := &MaxMind{
+		readers: make([]*maxminddb.Reader, 0, len(dbs)),
+	}
+	for _, db := range dbs {
+		r, err := maxminddb.FromBytes(db)
+		if err != nil {
+			return nil, err
+		}
+		m.readers = append(m.readers, r)
+	}
+	return m, nil
+}
+
+// Lookup returns information about the IP address from the databases.
+func (m *MaxMind) Lookup(_ context.Context, ip net.IP) (*Info, error) {
+	info := &Info{}
+	for _, r := range m.readers {
+		if ip.To4() == nil && r.Metadata.IPVersion == 4 {
+			continue
+		}
+		var rec mmdbRecord
+		if err := r.Lookup(ip, &rec); err != nil {
+			return nil, err
+		}
+		if len(info.CountryCode) == 0 && len(rec.Country.ISOCode) != 0 {
+			info.CountryCode = rec.Country.ISOCode
+			info.CountryName = rec.Country.Names["en"]
+		}
+		if len(info.ContinentCode) == 0 {
+			info.ContinentCode = rec.Continent.Code
+		}
+		if info.ASN == 0 && rec.ASN != 0 {
+			info.ASN = rec.ASN
+			info.Organization = rec.Organization
+		}
+	}
+	return info, nil
+}
+
+// Close databases.
+func (m *MaxMind) Close() error {
+	var err error
+	for _, r := range m.readers {
+		if e := r.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	m.readers = nil
+	return err
+}
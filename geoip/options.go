@@ -0,0 +1,69 @@
+package geoip
+
+import (
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/instrumenter"
+)
+
+type options struct {
+	Name         string
+	TTL          time.Duration
+	Cache        *cache.Cache
+	CacheOptions []cache.CacheOption
+	Instrumenter instrumenter.Instrumenter
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Name: DefaultName,
+		TTL:  24 * time.Hour,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	return opt
+}
+
+// Option for the IP resolver.
+type Option interface {
+	apply(*options)
+}
+
+// Name is a cache instance name for the lookup results.
+type Name string
+
+func (n Name) apply(o *options) {
+	o.Name = string(n)
+}
+
+// TTL is a time to keep lookup results in the cache. Defaults to 24 hours.
+type TTL time.Duration
+
+func (t TTL) apply(o *options) {
+	o.TTL = time.Duration(t)
+}
+
+// Cache to store lookup results in.
+type Cache struct {
+	*cache.Cache
+}
+
+func (c Cache) apply(o *options) {
+	o.Cache = c.Cache
+}
+
+// CacheOptions are options for the lookup results cache instance.
+type CacheOptions []cache.CacheOption
+
+func (c CacheOptions) apply(o *options) {
+	o.CacheOptions = append(o.CacheOptions, c...)
+}
+
+// Instrumenter to observe provider lookups.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
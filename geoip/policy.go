@@ -0,0 +1,76 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// ErrBlocked is returned when IP address is not allowed by the policy.
+type ErrBlocked struct {
+	IP     string
+	Reason string
+}
+
+func (e ErrBlocked) Error() string {
+	return fmt.Sprintf("IP address %s is blocked: %s", e.IP, e.Reason)
+}
+
+// Policy is a geo restriction policy for compliance checks.
+type Policy struct {
+	// AllowCountries is a list of allowed country codes. Empty list allows all countries.
+	AllowCountries []string
+	// DenyCountries is a list of denied country codes.
+	DenyCountries []string
+	// DenyASN is a list of denied autonomous system numbers.
+	DenyASN []uint
+	// AllowUnknown allows addresses with unknown country when allowed countries are set.
+	AllowUnknown bool
+	// DenyPrivate denies private network addresses. Private addresses are allowed by default.
+	DenyPrivate bool
+}
+
+// Check returns ErrBlocked if information does not satisfy the policy.
+func (p Policy) Check(ip net.IP, info *Info) error {
+	blocked := func(reason string) error {
+		return ErrBlocked{IP: ip.String(), Reason: reason}
+	}
+	if info.Private {
+		if p.DenyPrivate {
+			return blocked("private network address")
+		}
+		return nil
+	}
+	if info.InASN(p.DenyASN...) {
+		return blocked(fmt.Sprintf("autonomous system %d denied", info.ASN))
+	}
+	if len(info.CountryCode) != 0 && info.InCountry(p.DenyCountries...) {
+		return blocked(fmt.Sprintf("country %s denied", info.CountryCode))
+	}
+	if len(p.AllowCountries) == 0 {
+		return nil
+	}
+	if len(info.CountryCode) == 0 {
+		if p.AllowUnknown {
+			return nil
+		}
+		return blocked("country unknown")
+	}
+	if !info.InCountry(p.AllowCountries...) {
+		return blocked(fmt.Sprintf("country %s not allowed", info.CountryCode))
+	}
+	return nil
+}
+
+// Check IP address against the policy. Returns ErrBlocked if address is not allowed.
+func (r *Resolver) Check(ctx context.Context, ip net.IP, policy Policy) error {
+	info, err := r.Lookup(ctx, ip)
+	if err != nil {
+		return err
+	}
+	return policy.Check(ip, info)
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// RemoteAPI is a provider that resolves IP addresses using remote HTTP API.
+type RemoteAPI struct {
+	// URL of the API with "{ip}" placeholder for the IP address
+	// (for example "https://geo.example.com/v1/{ip}").
+	URL string
+	// Header is added to every request, for example for API key.
+	Header http.Header
+	// Client is an HTTP client to use. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Decode decodes API response. By default response is decoded as JSON
+	// encoded Info.
+	Decode func(body []byte) (*Info, error)
+}
+
+// Lookup returns information about the IP address from the remote API.
+//
+// Not found response returns empty information.
+func (a *RemoteAPI) Lookup(ctx context.Context, ip net.IP) (*Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(a.URL, "{ip}", ip.String()), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range a.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &Info{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if a.Decode != nil {
+		return a.Decode(body)
+	}
+	info := &Info{}
+	if err := json.Unmarshal(body, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
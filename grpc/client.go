@@ -0,0 +1,35 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package grpc
+
+import (
+	"context"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Dial creates gRPC client connection to the target with instrumentation interceptors.
+//
+// If TLS configuration is not provided, connection is not encrypted.
+func Dial(ctx context.Context, target string, opts ...Option) (*grpclib.ClientConn, error) {
+	opt := newOptions(opts...)
+
+	creds := insecure.NewCredentials()
+	if opt.TLSConfig != nil {
+		creds = credentials.NewTLS(opt.TLSConfig)
+	}
+
+	do := make([]grpclib.DialOption, 0, len(opt.DialOptions)+3)
+	do = append(do,
+		grpclib.WithTransportCredentials(creds),
+		grpclib.WithChainUnaryInterceptor(InstrumentationUnaryClientInterceptor(opt.Instrumenter)),
+		grpclib.WithChainStreamInterceptor(InstrumentationStreamClientInterceptor(opt.Instrumenter)),
+	)
+	do = append(do, opt.DialOptions...)
+
+	return grpclib.DialContext(ctx, target, do...)
+}
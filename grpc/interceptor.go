@@ -0,0 +1,173 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"azugo.io/core/instrumenter"
+
+	"go.uber.org/zap"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	InstrumentationServerCall = "grpc-server-call"
+	InstrumentationClientCall = "grpc-client-call"
+)
+
+// LoggingUnaryServerInterceptor logs unary calls with their duration and status code.
+func LoggingUnaryServerInterceptor(log *zap.Logger) grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(log, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// LoggingStreamServerInterceptor logs stream calls with their duration and status code.
+func LoggingStreamServerInterceptor(log *zap.Logger) grpclib.StreamServerInterceptor {
+	return func(srv any, ss grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(log, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(log *zap.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	fields := []zap.Field{
+		zap.String("rpc.system", "grpc"),
+		zap.String("rpc.method", method),
+		zap.String("rpc.grpc.status_code", code.String()),
+		zap.Duration("event.duration", time.Since(start)),
+	}
+	switch code {
+	case codes.OK:
+		log.Debug("gRPC call", fields...)
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		log.Error("gRPC call failed", append(fields, zap.Error(err))...)
+	default:
+		log.Info("gRPC call failed", append(fields, zap.Error(err))...)
+	}
+}
+
+// InstrumentationUnaryServerInterceptor observes unary calls using instrumenter.
+func InstrumentationUnaryServerInterceptor(instr instrumenter.Instrumenter) grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (any, error) {
+		finish := instr.Observe(ctx, InstrumentationServerCall, info.FullMethod)
+		resp, err := handler(ctx, req)
+		finish(err)
+		return resp, err
+	}
+}
+
+// InstrumentationStreamServerInterceptor observes stream calls using instrumenter.
+func InstrumentationStreamServerInterceptor(instr instrumenter.Instrumenter) grpclib.StreamServerInterceptor {
+	return func(srv any, ss grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+		finish := instr.Observe(ss.Context(), InstrumentationServerCall, info.FullMethod)
+		err := handler(srv, ss)
+		finish(err)
+		return err
+	}
+}
+
+// InstrumentationUnaryClientInterceptor observes outgoing unary calls using instrumenter.
+func InstrumentationUnaryClientInterceptor(instr instrumenter.Instrumenter) grpclib.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpclib.ClientConn, invoker grpclib.UnaryInvoker, opts ...grpclib.CallOption) error {
+		finish := instr.Observe(ctx, InstrumentationClientCall, method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		finish(err)
+		return err
+	}
+}
+
+// InstrumentationStreamClientInterceptor observes outgoing stream creation using instrumenter.
+func InstrumentationStreamClientInterceptor(instr instrumenter.Instrumenter) grpclib.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpclib.StreamDesc, cc *grpclib.ClientConn, method string, streamer grpclib.Streamer, opts ...grpclib.CallOption) (grpclib.ClientStream, error) {
+		finish := instr.Observe(ctx, InstrumentationClientCall, method)
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		finish(err)
+		return s, err
+	}
+}
+
+type tokenBucket struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := float64(burst)
+	if b <= 0 {
+		b = rate
+	}
+	if b < 1 {
+		b = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) allow() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// RateLimitUnaryServerInterceptor rejects unary calls with ResourceExhausted status
+// when rate limit is exceeded.
+func RateLimitUnaryServerInterceptor(rate float64, burst int) grpclib.UnaryServerInterceptor {
+	return rateLimitUnary(newTokenBucket(rate, burst))
+}
+
+func rateLimitUnary(b *tokenBucket) grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (any, error) {
+		if !b.allow() {
+			return nil, status.Errorf(codes.ResourceExhausted, "%s rate limit exceeded", info.FullMethod)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamServerInterceptor rejects stream calls with ResourceExhausted status
+// when rate limit is exceeded.
+func RateLimitStreamServerInterceptor(rate float64, burst int) grpclib.StreamServerInterceptor {
+	return rateLimitStream(newTokenBucket(rate, burst))
+}
+
+func rateLimitStream(b *tokenBucket) grpclib.StreamServerInterceptor {
+	return func(srv any, ss grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+		if !b.allow() {
+			return status.Errorf(codes.ResourceExhausted, "%s rate limit exceeded", info.FullMethod)
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,180 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+
+	"azugo.io/core/discovery"
+	"azugo.io/core/instrumenter"
+
+	"go.uber.org/zap"
+	grpclib "google.golang.org/grpc"
+)
+
+type options struct {
+	TLSConfig          *tls.Config
+	Logger             *zap.Logger
+	Instrumenter       instrumenter.Instrumenter
+	RateLimit          float64
+	RateBurst          int
+	Listener           net.Listener
+	ServerOptions      []grpclib.ServerOption
+	DialOptions        []grpclib.DialOption
+	UnaryInterceptors  []grpclib.UnaryServerInterceptor
+	StreamInterceptors []grpclib.StreamServerInterceptor
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return opt
+}
+
+// Option for the gRPC server or client.
+type Option interface {
+	apply(*options)
+}
+
+// TLSConfig to use for the server or client connection.
+type TLSConfig struct {
+	*tls.Config
+}
+
+func (c TLSConfig) apply(o *options) {
+	o.TLSConfig = c.Config
+}
+
+// Certificate to use for the server or as a client certificate.
+//
+// Certificate can be loaded using the cert package.
+type Certificate struct {
+	*tls.Certificate
+}
+
+func (c Certificate) apply(o *options) {
+	if o.TLSConfig == nil {
+		o.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	o.TLSConfig.Certificates = append(o.TLSConfig.Certificates, *c.Certificate)
+}
+
+// ClientCertificateFunc returns client certificate for every new connection.
+//
+// Use cert.ClientCertManager GetClientCertificate to rotate client certificates
+// without closing established connections.
+type ClientCertificateFunc func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+
+func (f ClientCertificateFunc) apply(o *options) {
+	if o.TLSConfig == nil {
+		o.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	o.TLSConfig.GetClientCertificate = f
+}
+
+// RootCAs to use to verify server certificate for client connections
+// or client certificates for the server.
+type RootCAs struct {
+	*x509.CertPool
+}
+
+func (c RootCAs) apply(o *options) {
+	if o.TLSConfig == nil {
+		o.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	o.TLSConfig.RootCAs = c.CertPool
+	o.TLSConfig.ClientCAs = c.CertPool
+}
+
+// Logger to use for request logging.
+type Logger struct {
+	*zap.Logger
+}
+
+func (l Logger) apply(o *options) {
+	o.Logger = l.Logger
+}
+
+// Instrumenter to use for metrics and tracing of the calls.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
+
+// RateLimit limits number of server calls per second.
+type RateLimit struct {
+	// Rate is a number of calls allowed per second.
+	Rate float64
+	// Burst is a maximum number of calls allowed at once.
+	// Defaults to the rate.
+	Burst int
+}
+
+func (r RateLimit) apply(o *options) {
+	o.RateLimit = r.Rate
+	o.RateBurst = r.Burst
+}
+
+// Listener to use for the server instead of listening on the address.
+type Listener struct {
+	net.Listener
+}
+
+func (l Listener) apply(o *options) {
+	o.Listener = l.Listener
+}
+
+// ServerOptions are additional gRPC server options.
+type ServerOptions []grpclib.ServerOption
+
+func (s ServerOptions) apply(o *options) {
+	o.ServerOptions = append(o.ServerOptions, s...)
+}
+
+// DialOptions are additional gRPC client dial options.
+type DialOptions []grpclib.DialOption
+
+func (d DialOptions) apply(o *options) {
+	o.DialOptions = append(o.DialOptions, d...)
+}
+
+// UnaryInterceptors are additional unary server interceptors that are called
+// after the built-in ones.
+type UnaryInterceptors []grpclib.UnaryServerInterceptor
+
+func (u UnaryInterceptors) apply(o *options) {
+	o.UnaryInterceptors = append(o.UnaryInterceptors, u...)
+}
+
+// StreamInterceptors are additional stream server interceptors that are called
+// after the built-in ones.
+type StreamInterceptors []grpclib.StreamServerInterceptor
+
+func (s StreamInterceptors) apply(o *options) {
+	o.StreamInterceptors = append(o.StreamInterceptors, s...)
+}
+
+// Discovery to resolve targets with DiscoveryScheme using service discovery
+// with client-side round-robin load balancing.
+type Discovery struct {
+	*discovery.Discovery
+}
+
+func (d Discovery) apply(o *options) {
+	o.DialOptions = append(o.DialOptions,
+		grpclib.WithResolvers(&discoveryBuilder{discovery: d.Discovery}),
+		grpclib.WithDefaultServiceConfig(`{"loadBalancingConfig":[{"round_robin":{}}]}`),
+	)
+}
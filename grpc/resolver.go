@@ -0,0 +1,65 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"azugo.io/core/discovery"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// DiscoveryScheme is a target scheme to resolve service using service discovery,
+// for example "discovery:///my-service".
+const DiscoveryScheme = "discovery"
+
+type discoveryBuilder struct {
+	discovery *discovery.Discovery
+}
+
+func (b *discoveryBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	r := &discoveryResolver{
+		discovery: b.discovery,
+		service:   strings.TrimPrefix(target.URL.Path, "/"),
+		cc:        cc,
+	}
+	r.unsubscribe = b.discovery.Subscribe(r.service, r.update)
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	return r, nil
+}
+
+func (b *discoveryBuilder) Scheme() string {
+	return DiscoveryScheme
+}
+
+type discoveryResolver struct {
+	discovery   *discovery.Discovery
+	service     string
+	cc          resolver.ClientConn
+	unsubscribe func()
+}
+
+func (r *discoveryResolver) update(endpoints []discovery.Endpoint) {
+	addrs := make([]resolver.Address, 0, len(endpoints))
+	for _, e := range endpoints {
+		addrs = append(addrs, resolver.Address{Addr: e.Addr()})
+	}
+	_ = r.cc.UpdateState(resolver.State{Addresses: addrs})
+}
+
+func (r *discoveryResolver) ResolveNow(resolver.ResolveNowOptions) {
+	endpoints, err := r.discovery.Lookup(context.Background(), r.service)
+	if err != nil {
+		r.cc.ReportError(err)
+		return
+	}
+	r.update(endpoints)
+}
+
+func (r *discoveryResolver) Close() {
+	r.unsubscribe()
+}
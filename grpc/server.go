@@ -0,0 +1,150 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"go.uber.org/zap"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Server is a gRPC server with logging, instrumentation, rate limiting and
+// health service preconfigured.
+//
+// Server implements core.Tasker interface so it can be added as an application task.
+type Server struct {
+	addr   string
+	opts   *options
+	server *grpclib.Server
+	health *health.Server
+
+	lock     sync.Mutex
+	listener net.Listener
+	done     chan struct{}
+}
+
+// NewServer creates new gRPC server that will listen on the address.
+func NewServer(addr string, opts ...Option) *Server {
+	opt := newOptions(opts...)
+
+	unary := []grpclib.UnaryServerInterceptor{
+		InstrumentationUnaryServerInterceptor(opt.Instrumenter),
+		LoggingUnaryServerInterceptor(opt.Logger),
+	}
+	stream := []grpclib.StreamServerInterceptor{
+		InstrumentationStreamServerInterceptor(opt.Instrumenter),
+		LoggingStreamServerInterceptor(opt.Logger),
+	}
+	if opt.RateLimit > 0 {
+		// Share the same limit between unary and stream calls.
+		b := newTokenBucket(opt.RateLimit, opt.RateBurst)
+		unary = append(unary, rateLimitUnary(b))
+		stream = append(stream, rateLimitStream(b))
+	}
+	unary = append(unary, opt.UnaryInterceptors...)
+	stream = append(stream, opt.StreamInterceptors...)
+
+	so := make([]grpclib.ServerOption, 0, len(opt.ServerOptions)+3)
+	so = append(so,
+		grpclib.ChainUnaryInterceptor(unary...),
+		grpclib.ChainStreamInterceptor(stream...),
+	)
+	if opt.TLSConfig != nil {
+		so = append(so, grpclib.Creds(credentials.NewTLS(opt.TLSConfig)))
+	}
+	so = append(so, opt.ServerOptions...)
+
+	s := &Server{
+		addr:   addr,
+		opts:   opt,
+		server: grpclib.NewServer(so...),
+		health: health.NewServer(),
+	}
+	healthpb.RegisterHealthServer(s.server, s.health)
+	// Report not serving until server is started.
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	return s
+}
+
+// Name returns task name.
+func (s *Server) Name() string {
+	return "grpc-server"
+}
+
+// GRPC returns underlying gRPC server to register services.
+func (s *Server) GRPC() *grpclib.Server {
+	return s.server
+}
+
+// Health returns health service to update serving status of the services.
+func (s *Server) Health() *health.Server {
+	return s.health
+}
+
+// Addr returns address server is listening on or nil if server is not started.
+func (s *Server) Addr() net.Addr {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Start listening and serving gRPC requests in the background.
+func (s *Server) Start(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.listener != nil {
+		return nil
+	}
+
+	l := s.opts.Listener
+	if l == nil {
+		var err error
+		var lc net.ListenConfig
+		if l, err = lc.Listen(ctx, "tcp", s.addr); err != nil {
+			return err
+		}
+	}
+	s.listener = l
+	s.done = make(chan struct{})
+
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+
+	go func(done chan struct{}) {
+		defer close(done)
+		if err := s.server.Serve(l); err != nil && !errors.Is(err, grpclib.ErrServerStopped) {
+			s.opts.Logger.Error("gRPC server failed", zap.Error(err))
+		}
+	}(s.done)
+
+	return nil
+}
+
+// Stop gracefully stops the server waiting for pending calls to complete.
+func (s *Server) Stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.listener == nil {
+		return
+	}
+
+	s.health.Shutdown()
+	s.server.GracefulStop()
+	<-s.done
+	s.listener = nil
+}
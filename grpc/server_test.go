@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"azugo.io/core/discovery"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func startTestServer(t *testing.T, opts ...Option) (*Server, *grpclib.ClientConn) {
+	t.Helper()
+
+	return startTestServerTarget(t, "passthrough:///bufnet", opts...)
+}
+
+func startTestServerTarget(t *testing.T, target string, opts ...Option) (*Server, *grpclib.ClientConn) {
+	t.Helper()
+
+	l := bufconn.Listen(1024 * 1024)
+	s := NewServer("", append(opts, Listener{l})...)
+	require.NoError(t, s.Start(context.Background()))
+	t.Cleanup(s.Stop)
+
+	conn, err := Dial(context.Background(), target, append(opts, DialOptions{
+		grpclib.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+	})...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return s, conn
+}
+
+func TestServerHealth(t *testing.T) {
+	var lock sync.Mutex
+	ops := make([]string, 0)
+	instr := Instrumenter(func(ctx context.Context, op string, args ...any) func(err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		ops = append(ops, op)
+		return func(err error) {}
+	})
+
+	_, conn := startTestServer(t, instr)
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{InstrumentationClientCall, InstrumentationServerCall}, ops)
+}
+
+func TestServerRateLimit(t *testing.T) {
+	_, conn := startTestServer(t, RateLimit{Rate: 0.001, Burst: 1})
+
+	client := healthpb.NewHealthClient(conn)
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestDialDiscovery(t *testing.T) {
+	d := discovery.New(discovery.ResolverFunc(func(ctx context.Context, service string) ([]discovery.Endpoint, error) {
+		assert.Equal(t, "health", service)
+		return []discovery.Endpoint{{Host: "127.0.0.1", Port: 1, Healthy: true}}, nil
+	}))
+
+	_, conn := startTestServerTarget(t, "discovery:///health", Discovery{d})
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
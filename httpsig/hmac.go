@@ -0,0 +1,121 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpsig
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"azugo.io/core/keyring"
+)
+
+// HMAC signs and verifies requests using HMAC-SHA256 with keys from the key ring.
+//
+// Signature header has format: t=<unix timestamp>,k=<key id>,v1=<hex encoded HMAC-SHA256>
+//
+// Signed payload consists of timestamp, request method, request URI and hex encoded
+// SHA-256 digest of the body separated by new lines.
+type HMAC struct {
+	keys *keyring.KeyRing
+	opts *options
+	now  func() time.Time
+}
+
+// NewHMAC creates new HMAC-SHA256 request signer and verifier.
+func NewHMAC(keys *keyring.KeyRing, opts ...Option) *HMAC {
+	return &HMAC{
+		keys: keys,
+		opts: newOptions(opts...),
+		now:  time.Now,
+	}
+}
+
+func hmacPayload(ts int64, r *http.Request, body []byte) []byte {
+	var b strings.Builder
+	b.WriteString(strconv.FormatInt(ts, 10))
+	b.WriteByte('\n')
+	b.WriteString(strings.ToUpper(r.Method))
+	b.WriteByte('\n')
+	b.WriteString(requestURI(r))
+	b.WriteByte('\n')
+	b.WriteString(hex.EncodeToString(sha256Sum(body)))
+	return []byte(b.String())
+}
+
+// SignRequest adds signature header to the request.
+func (h *HMAC) SignRequest(req *http.Request) error {
+	body, err := readBody(req, 0)
+	if err != nil {
+		return err
+	}
+	ts := h.now().Unix()
+	id, sig, err := h.keys.Sign(hmacPayload(ts, req, body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(h.opts.Header, fmt.Sprintf("t=%d,k=%s,v1=%s", ts, id, hex.EncodeToString(sig)))
+	return nil
+}
+
+// VerifyRequest verifies request signature header and returns ID of the key
+// the request was signed with.
+func (h *HMAC) VerifyRequest(r *http.Request) (string, error) {
+	header := r.Header.Get(h.opts.Header)
+	if len(header) == 0 {
+		return "", ErrMissingSignature
+	}
+
+	var ts int64
+	var id string
+	var sig []byte
+	for _, p := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok {
+			continue
+		}
+		var err error
+		switch k {
+		case "t":
+			if ts, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return "", ErrInvalidSignature
+			}
+		case "k":
+			id = v
+		case "v1":
+			if sig, err = hex.DecodeString(v); err != nil {
+				return "", ErrInvalidSignature
+			}
+		}
+	}
+	if ts == 0 || len(id) == 0 || len(sig) == 0 {
+		return "", ErrInvalidSignature
+	}
+	if err := checkAge(h.now(), time.Unix(ts, 0), h.opts.Tolerance); err != nil {
+		return "", err
+	}
+
+	body, err := readBody(r, h.opts.MaxBodySize)
+	if err != nil {
+		return "", err
+	}
+	if !h.keys.Verify(id, hmacPayload(ts, r, body), sig) {
+		return "", ErrInvalidSignature
+	}
+	return id, nil
+}
+
+func checkAge(now, created time.Time, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		return nil
+	}
+	if d := now.Sub(created); d > tolerance || d < -tolerance {
+		return fmt.Errorf("%w: signature expired", ErrInvalidSignature)
+	}
+	return nil
+}
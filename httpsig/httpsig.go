@@ -0,0 +1,155 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpsig
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+)
+
+var (
+	// ErrMissingSignature is returned when request is not signed.
+	ErrMissingSignature = errors.New("missing request signature")
+	// ErrInvalidSignature is returned when request signature is invalid.
+	ErrInvalidSignature = errors.New("invalid request signature")
+	// ErrBodyTooLarge is returned when request body exceeds maximum size for verification.
+	ErrBodyTooLarge = errors.New("request body too large")
+)
+
+// Signer signs outbound HTTP requests.
+type Signer interface {
+	// SignRequest adds signature headers to the request.
+	SignRequest(req *http.Request) error
+}
+
+// Verifier verifies signatures of inbound HTTP requests.
+type Verifier interface {
+	// VerifyRequest verifies request signature and returns ID of the key
+	// the request was signed with.
+	VerifyRequest(r *http.Request) (string, error)
+}
+
+type transport struct {
+	signer Signer
+	next   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Round tripper must not modify original request.
+	r := req.Clone(req.Context())
+	if err := t.signer.SignRequest(r); err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.next.RoundTrip(r)
+}
+
+// Transport wraps HTTP client transport to sign outbound requests.
+//
+// If next is nil, http.DefaultTransport is used.
+func Transport(next http.RoundTripper, signer Signer) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{
+		signer: signer,
+		next:   next,
+	}
+}
+
+// Client wraps HTTP client transport to sign outbound requests.
+func Client(c *http.Client, signer Signer) *http.Client {
+	cc := *c
+	cc.Transport = Transport(c.Transport, signer)
+	return &cc
+}
+
+type keyIDContextKey struct{}
+
+// KeyID returns ID of the key the request was signed with or empty
+// string if request was not verified by the Middleware.
+func KeyID(ctx context.Context) string {
+	id, _ := ctx.Value(keyIDContextKey{}).(string)
+	return id
+}
+
+// Middleware verifies signature of every request and attaches ID of the key
+// the request was signed with to the request context.
+//
+// Requests without valid signature are rejected.
+func Middleware(verifier Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := verifier.VerifyRequest(r)
+			if errors.Is(err, ErrBodyTooLarge) {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(w, "invalid request signature", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), keyIDContextKey{}, id)))
+		})
+	}
+}
+
+// readBody reads request body and replaces it so that it can be read again.
+func readBody(r *http.Request, limit int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	if r.GetBody != nil {
+		if rc, err := r.GetBody(); err == nil {
+			defer rc.Close()
+			return readAll(rc, limit)
+		}
+	}
+
+	body, err := readAll(r.Body, limit)
+	_ = r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+func readAll(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, ErrBodyTooLarge
+	}
+	return body, nil
+}
+
+func sha256Sum(data []byte) []byte {
+	h := sha256.Sum256(data)
+	return h[:]
+}
+
+// requestURI returns request path with query.
+func requestURI(r *http.Request) string {
+	if len(r.RequestURI) != 0 && r.RequestURI[0] == '/' {
+		return r.RequestURI
+	}
+	return r.URL.RequestURI()
+}
@@ -0,0 +1,88 @@
+package httpsig
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"azugo.io/core/keyring"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMAC(t *testing.T) {
+	keys := keyring.New(keyring.Key{ID: "k1", Secret: []byte("secret")})
+	h := NewHMAC(keys)
+	now := time.Unix(1700000000, 0)
+	h.now = func() time.Time { return now }
+
+	req := httptest.NewRequest(http.MethodPut, "/items/1?force=true", strings.NewReader("payload"))
+	require.NoError(t, h.SignRequest(req))
+	assert.True(t, strings.HasPrefix(req.Header.Get("X-Signature"), "t=1700000000,k=k1,v1="))
+
+	id, err := h.VerifyRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, "k1", id)
+
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(body))
+
+	// Key rotation keeps old signatures valid.
+	keys.Rotate(keyring.Key{ID: "k2", Secret: []byte("new secret")})
+	req.Body = io.NopCloser(strings.NewReader("payload"))
+	req.GetBody = nil
+	_, err = h.VerifyRequest(req)
+	require.NoError(t, err)
+
+	req.Body = io.NopCloser(strings.NewReader("tampered"))
+	req.GetBody = nil
+	_, err = h.VerifyRequest(req)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	now = now.Add(10 * time.Minute)
+	req.Body = io.NopCloser(strings.NewReader("payload"))
+	req.GetBody = nil
+	_, err = h.VerifyRequest(req)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	req.Header.Del("X-Signature")
+	_, err = h.VerifyRequest(req)
+	assert.ErrorIs(t, err, ErrMissingSignature)
+}
+
+func TestClientMiddleware(t *testing.T) {
+	keys := KeyRing{keyring.New(keyring.Key{ID: "partner", Secret: []byte("secret")})}
+
+	var received string
+	srv := httptest.NewServer(Middleware(NewMessageVerifier(keys, MaxBodySize(16)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = KeyID(r.Context()) + ":" + string(body)
+	})))
+	defer srv.Close()
+
+	c := Client(srv.Client(), NewMessageSigner(keys))
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/hook?x=1", strings.NewReader("hello"))
+	require.NoError(t, err)
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "partner:hello", received)
+	assert.Empty(t, req.Header.Get(HeaderSignature))
+
+	resp, err = c.Post(srv.URL+"/hook", "text/plain", strings.NewReader(strings.Repeat("a", 17)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp, err = srv.Client().Get(srv.URL + "/hook")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
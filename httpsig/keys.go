@@ -0,0 +1,282 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpsig
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"azugo.io/core/keyring"
+)
+
+// HTTP message signature algorithms.
+const (
+	AlgorithmHMACSHA256      = "hmac-sha256"
+	AlgorithmRSAv15SHA256    = "rsa-v1_5-sha256"
+	AlgorithmECDSAP256SHA256 = "ecdsa-p256-sha256"
+	AlgorithmECDSAP384SHA384 = "ecdsa-p384-sha384"
+	AlgorithmEd25519         = "ed25519"
+)
+
+// ErrUnknownKey is returned when key with specified ID is not known.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key to sign or verify HTTP message signatures.
+type Key struct {
+	// ID of the key sent as keyid signature parameter.
+	ID string
+	// Secret is a shared secret for HMAC-SHA256 signatures.
+	Secret []byte
+	// Signer is a private key for asymmetric signatures.
+	Signer crypto.Signer
+	// PublicKey to verify asymmetric signatures. If not set public key of the
+	// Signer is used.
+	PublicKey crypto.PublicKey
+}
+
+func (k *Key) publicKey() crypto.PublicKey {
+	if k.PublicKey == nil && k.Signer != nil {
+		return k.Signer.Public()
+	}
+	return k.PublicKey
+}
+
+// Algorithm returns signature algorithm for the key.
+func (k *Key) Algorithm() (string, error) {
+	if len(k.Secret) != 0 {
+		return AlgorithmHMACSHA256, nil
+	}
+	switch pub := k.publicKey().(type) {
+	case *rsa.PublicKey:
+		return AlgorithmRSAv15SHA256, nil
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return AlgorithmECDSAP256SHA256, nil
+		case elliptic.P384():
+			return AlgorithmECDSAP384SHA384, nil
+		}
+		return "", fmt.Errorf("unsupported ECDSA curve %s", pub.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return AlgorithmEd25519, nil
+	case nil:
+		return "", errors.New("signing key is empty")
+	default:
+		return "", fmt.Errorf("unsupported key type %T", pub)
+	}
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+func ecdsaDigest(alg string, data []byte) ([]byte, crypto.Hash, int) {
+	if alg == AlgorithmECDSAP384SHA384 {
+		d := sha512.Sum384(data)
+		return d[:], crypto.SHA384, 48
+	}
+	d := sha256.Sum256(data)
+	return d[:], crypto.SHA256, 32
+}
+
+func (k *Key) sign(data []byte) (string, []byte, error) {
+	alg, err := k.Algorithm()
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch alg {
+	case AlgorithmHMACSHA256:
+		h := hmac.New(sha256.New, k.Secret)
+		_, _ = h.Write(data)
+		return alg, h.Sum(nil), nil
+	case AlgorithmEd25519:
+		if k.Signer == nil {
+			return "", nil, errors.New("private key is empty")
+		}
+		sig, err := k.Signer.Sign(rand.Reader, data, crypto.Hash(0))
+		return alg, sig, err
+	}
+
+	if k.Signer == nil {
+		return "", nil, errors.New("private key is empty")
+	}
+	if alg == AlgorithmRSAv15SHA256 {
+		d := sha256.Sum256(data)
+		sig, err := k.Signer.Sign(rand.Reader, d[:], crypto.SHA256)
+		return alg, sig, err
+	}
+
+	// ECDSA signatures are encoded as concatenated R and S values.
+	d, hash, size := ecdsaDigest(alg, data)
+	der, err := k.Signer.Sign(rand.Reader, d, hash)
+	if err != nil {
+		return "", nil, err
+	}
+	var es ecdsaSignature
+	if _, err := asn1.Unmarshal(der, &es); err != nil {
+		return "", nil, err
+	}
+	sig := make([]byte, 2*size)
+	es.R.FillBytes(sig[:size])
+	es.S.FillBytes(sig[size:])
+	return alg, sig, nil
+}
+
+func (k *Key) verify(alg string, data, sig []byte) error {
+	kalg, err := k.Algorithm()
+	if err != nil {
+		return err
+	}
+	if len(alg) != 0 && alg != kalg {
+		return fmt.Errorf("%w: algorithm %s does not match key", ErrInvalidSignature, alg)
+	}
+
+	ok := false
+	switch pub := k.publicKey().(type) {
+	case *rsa.PublicKey:
+		d := sha256.Sum256(data)
+		ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, d[:], sig) == nil
+	case *ecdsa.PublicKey:
+		d, _, size := ecdsaDigest(kalg, data)
+		if len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			ok = ecdsa.Verify(pub, d, r, s)
+		}
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, data, sig)
+	default:
+		h := hmac.New(sha256.New, k.Secret)
+		_, _ = h.Write(data)
+		ok = hmac.Equal(h.Sum(nil), sig)
+	}
+	if !ok {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// KeySource provides key to sign requests.
+type KeySource interface {
+	// SigningKey returns current key to sign requests.
+	SigningKey() (*Key, error)
+}
+
+// KeyResolver resolves key to verify request signature.
+type KeyResolver interface {
+	// ResolveKey returns key with specified ID or ErrUnknownKey.
+	ResolveKey(ctx context.Context, id string) (*Key, error)
+}
+
+// KeyResolverFunc is a function that resolves key to verify request signature.
+type KeyResolverFunc func(ctx context.Context, id string) (*Key, error)
+
+// ResolveKey returns key with specified ID.
+func (f KeyResolverFunc) ResolveKey(ctx context.Context, id string) (*Key, error) {
+	return f(ctx, id)
+}
+
+// StaticKey is a key that is used to sign requests.
+type StaticKey Key
+
+// SigningKey returns the key.
+func (k StaticKey) SigningKey() (*Key, error) {
+	key := Key(k)
+	return &key, nil
+}
+
+// KeyRing provides HMAC-SHA256 keys from the key ring.
+//
+// Primary key is used to sign requests and all keys are used for verification.
+type KeyRing struct {
+	*keyring.KeyRing
+}
+
+// SigningKey returns primary key from the key ring.
+func (k KeyRing) SigningKey() (*Key, error) {
+	key, err := k.Primary()
+	if err != nil {
+		return nil, err
+	}
+	return &Key{ID: key.ID, Secret: key.Secret}, nil
+}
+
+// ResolveKey returns key with specified ID from the key ring.
+func (k KeyRing) ResolveKey(_ context.Context, id string) (*Key, error) {
+	key, err := k.Key(id)
+	if errors.Is(err, keyring.ErrKeyNotFound) {
+		return nil, ErrUnknownKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Key{ID: key.ID, Secret: key.Secret}, nil
+}
+
+// CertificateKeyID returns key ID for the certificate that is hex encoded
+// SHA-256 fingerprint of the certificate.
+func CertificateKeyID(c *x509.Certificate) string {
+	return hex.EncodeToString(sha256Sum(c.Raw))
+}
+
+// Certificate provides private key of the current certificate to sign requests.
+//
+// Use cert.ClientCertManager Certificate method to sign requests with the
+// managed client certificate that is renewed automatically. Key ID is the
+// certificate fingerprint returned by CertificateKeyID.
+type Certificate func() *tls.Certificate
+
+// SigningKey returns private key of the current certificate.
+func (f Certificate) SigningKey() (*Key, error) {
+	c := f()
+	if c == nil || len(c.Certificate) == 0 {
+		return nil, errors.New("certificate is not available")
+	}
+	signer, ok := c.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", c.PrivateKey)
+	}
+	leaf := c.Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(c.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	return &Key{
+		ID:     CertificateKeyID(leaf),
+		Signer: signer,
+	}, nil
+}
+
+// Certificates resolves public keys of trusted certificates by certificate
+// fingerprint returned by CertificateKeyID.
+type Certificates []*x509.Certificate
+
+// ResolveKey returns public key of the certificate with the fingerprint.
+func (c Certificates) ResolveKey(_ context.Context, id string) (*Key, error) {
+	for _, crt := range c {
+		if CertificateKeyID(crt) == id {
+			return &Key{ID: id, PublicKey: crt.PublicKey}, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
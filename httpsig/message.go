@@ -0,0 +1,369 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpsig
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderSignature is a header containing HTTP message signature.
+	HeaderSignature = "Signature"
+	// HeaderSignatureInput is a header containing HTTP message signature parameters.
+	HeaderSignatureInput = "Signature-Input"
+	// HeaderContentDigest is a header containing digest of the request body.
+	HeaderContentDigest = "Content-Digest"
+)
+
+var defaultComponents = []string{"@method", "@authority", "@path", "@query"}
+
+// MessageSigner signs requests using HTTP Message Signatures (RFC 9421).
+//
+// Requests with body have Content-Digest header (RFC 9530) added and covered
+// by the signature.
+type MessageSigner struct {
+	keys KeySource
+	opts *options
+	now  func() time.Time
+}
+
+// NewMessageSigner creates new HTTP message signer with keys from the source.
+func NewMessageSigner(keys KeySource, opts ...Option) *MessageSigner {
+	opt := newOptions(opts...)
+	if len(opt.Components) == 0 {
+		opt.Components = defaultComponents
+	}
+	return &MessageSigner{
+		keys: keys,
+		opts: opt,
+		now:  time.Now,
+	}
+}
+
+// SignRequest adds Signature-Input and Signature headers to the request.
+func (s *MessageSigner) SignRequest(req *http.Request) error {
+	key, err := s.keys.SigningKey()
+	if err != nil {
+		return err
+	}
+	alg, err := key.Algorithm()
+	if err != nil {
+		return err
+	}
+
+	body, err := readBody(req, 0)
+	if err != nil {
+		return err
+	}
+	components := make([]string, 0, len(s.opts.Components)+1)
+	for _, c := range s.opts.Components {
+		name := strings.ToLower(c)
+		if name[0] != '@' && len(req.Header.Values(name)) == 0 {
+			continue
+		}
+		components = append(components, name)
+	}
+	if len(body) != 0 {
+		req.Header.Set(HeaderContentDigest, contentDigest(body))
+		if !contains(components, "content-digest") {
+			components = append(components, "content-digest")
+		}
+	}
+
+	var params strings.Builder
+	params.WriteByte('(')
+	for i, c := range components {
+		if i > 0 {
+			params.WriteByte(' ')
+		}
+		params.WriteString(strconv.Quote(c))
+	}
+	params.WriteString(");created=")
+	params.WriteString(strconv.FormatInt(s.now().Unix(), 10))
+	params.WriteString(";keyid=")
+	params.WriteString(strconv.Quote(key.ID))
+	params.WriteString(";alg=")
+	params.WriteString(strconv.Quote(alg))
+
+	base, err := signatureBase(req, components, params.String())
+	if err != nil {
+		return err
+	}
+	_, sig, err := key.sign(base)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(HeaderSignatureInput, s.opts.Label+"="+params.String())
+	req.Header.Set(HeaderSignature, s.opts.Label+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+// MessageVerifier verifies HTTP Message Signatures (RFC 9421) of requests.
+type MessageVerifier struct {
+	keys KeyResolver
+	opts *options
+	now  func() time.Time
+}
+
+// NewMessageVerifier creates new HTTP message signature verifier that resolves
+// keys using the resolver.
+func NewMessageVerifier(keys KeyResolver, opts ...Option) *MessageVerifier {
+	opt := newOptions(opts...)
+	if len(opt.Components) == 0 {
+		opt.Components = defaultComponents
+	}
+	return &MessageVerifier{
+		keys: keys,
+		opts: opt,
+		now:  time.Now,
+	}
+}
+
+// VerifyRequest verifies request signature with the configured label and
+// returns ID of the key the request was signed with.
+func (v *MessageVerifier) VerifyRequest(r *http.Request) (string, error) {
+	input, ok := parseDictionary(strings.Join(r.Header.Values(HeaderSignatureInput), ","))[v.opts.Label]
+	if !ok {
+		return "", ErrMissingSignature
+	}
+	raw, ok := parseDictionary(strings.Join(r.Header.Values(HeaderSignature), ","))[v.opts.Label]
+	if !ok || len(raw) < 2 || raw[0] != ':' || raw[len(raw)-1] != ':' {
+		return "", ErrInvalidSignature
+	}
+	sig, err := base64.StdEncoding.DecodeString(raw[1 : len(raw)-1])
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	components, params, err := parseSignatureInput(input)
+	if err != nil {
+		return "", err
+	}
+
+	required := v.opts.Components
+	body, err := readBody(r, v.opts.MaxBodySize)
+	if err != nil {
+		return "", err
+	}
+	if len(body) != 0 {
+		required = append(append([]string{}, required...), "content-digest")
+	}
+	for _, c := range required {
+		if !contains(components, strings.ToLower(c)) {
+			return "", fmt.Errorf("%w: component %s is not covered", ErrInvalidSignature, c)
+		}
+	}
+	if contains(components, "content-digest") && !verifyContentDigest(r.Header.Get(HeaderContentDigest), body) {
+		return "", fmt.Errorf("%w: content digest does not match", ErrInvalidSignature)
+	}
+
+	now := v.now()
+	if created, ok := params["created"]; ok {
+		ts, err := strconv.ParseInt(created, 10, 64)
+		if err != nil {
+			return "", ErrInvalidSignature
+		}
+		if err := checkAge(now, time.Unix(ts, 0), v.opts.Tolerance); err != nil {
+			return "", err
+		}
+	} else if v.opts.Tolerance > 0 {
+		return "", fmt.Errorf("%w: created parameter is required", ErrInvalidSignature)
+	}
+	if expires, ok := params["expires"]; ok {
+		ts, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil || now.After(time.Unix(ts, 0)) {
+			return "", fmt.Errorf("%w: signature expired", ErrInvalidSignature)
+		}
+	}
+
+	id := params["keyid"]
+	key, err := v.keys.ResolveKey(r.Context(), id)
+	if err != nil {
+		return "", err
+	}
+	base, err := signatureBase(r, components, input)
+	if err != nil {
+		return "", err
+	}
+	if err := key.verify(params["alg"], base, sig); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func contentDigest(body []byte) string {
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sha256Sum(body)) + ":"
+}
+
+func verifyContentDigest(header string, body []byte) bool {
+	want := contentDigest(body)
+	for _, d := range splitList(header, ',') {
+		if strings.HasPrefix(d, "sha-256=") {
+			return subtle.ConstantTimeCompare([]byte(d), []byte(want)) == 1
+		}
+	}
+	return false
+}
+
+// authority returns normalized host of the request target.
+func authority(r *http.Request) string {
+	host := r.Host
+	if len(host) == 0 {
+		host = r.URL.Host
+	}
+	host = strings.ToLower(host)
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if (port == "443" && (r.TLS != nil || r.URL.Scheme == "https")) || (port == "80" && r.TLS == nil && r.URL.Scheme != "https") {
+			return h
+		}
+	}
+	return host
+}
+
+func scheme(r *http.Request) string {
+	if len(r.URL.Scheme) != 0 {
+		return strings.ToLower(r.URL.Scheme)
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+func componentValue(r *http.Request, name string) (string, error) {
+	switch name {
+	case "@method":
+		return strings.ToUpper(r.Method), nil
+	case "@authority":
+		return authority(r), nil
+	case "@scheme":
+		return scheme(r), nil
+	case "@target-uri":
+		return scheme(r) + "://" + authority(r) + requestURI(r), nil
+	case "@request-target":
+		return requestURI(r), nil
+	case "@path":
+		if p := r.URL.EscapedPath(); len(p) != 0 {
+			return p, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + r.URL.RawQuery, nil
+	}
+	if strings.HasPrefix(name, "@") {
+		return "", fmt.Errorf("unsupported component %s", name)
+	}
+
+	values := r.Header.Values(name)
+	if len(values) == 0 {
+		return "", fmt.Errorf("%w: header %s is missing", ErrInvalidSignature, name)
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(values, ", "), nil
+}
+
+// signatureBase creates signature base as defined in RFC 9421 section 2.5.
+func signatureBase(r *http.Request, components []string, params string) ([]byte, error) {
+	var b strings.Builder
+	for _, c := range components {
+		v, err := componentValue(r, c)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(strconv.Quote(c))
+		b.WriteString(": ")
+		b.WriteString(v)
+		b.WriteByte('\n')
+	}
+	b.WriteString(`"@signature-params": `)
+	b.WriteString(params)
+	return []byte(b.String()), nil
+}
+
+// splitList splits structured field list by separator outside of quoted
+// strings and inner lists.
+func splitList(s string, sep byte) []string {
+	var parts []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if p := strings.TrimSpace(s[start:]); len(p) != 0 {
+		parts = append(parts, p)
+	}
+	return parts
+}
+
+// parseDictionary parses structured field dictionary to raw member values.
+func parseDictionary(s string) map[string]string {
+	dict := make(map[string]string)
+	for _, m := range splitList(s, ',') {
+		k, v, ok := strings.Cut(m, "=")
+		if !ok {
+			continue
+		}
+		dict[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return dict
+}
+
+// parseSignatureInput parses covered components and signature parameters.
+func parseSignatureInput(input string) ([]string, map[string]string, error) {
+	end := strings.IndexByte(input, ')')
+	if len(input) == 0 || input[0] != '(' || end < 0 {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	components := make([]string, 0, 4)
+	for _, c := range strings.Fields(input[1:end]) {
+		name, err := strconv.Unquote(c)
+		if err != nil || len(name) == 0 {
+			return nil, nil, fmt.Errorf("%w: unsupported component %s", ErrInvalidSignature, c)
+		}
+		components = append(components, name)
+	}
+
+	params := make(map[string]string)
+	for _, p := range splitList(input[end+1:], ';') {
+		k, v, _ := strings.Cut(p, "=")
+		if uv, err := strconv.Unquote(v); err == nil {
+			v = uv
+		}
+		params[k] = v
+	}
+	return components, params, nil
+}
@@ -0,0 +1,147 @@
+package httpsig
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"azugo.io/core/keyring"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageVerifierRFC9421HMAC(t *testing.T) {
+	// Test vector from RFC 9421 appendix B.2.5.
+	secret, err := base64.StdEncoding.DecodeString("uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ==")
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/foo?param=Value&Pet=dog", nil)
+	r.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(HeaderSignatureInput, `sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`)
+	r.Header.Set(HeaderSignature, `sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:`)
+
+	v := NewMessageVerifier(KeyRing{keyring.New(keyring.Key{ID: "test-shared-secret", Secret: secret})},
+		Label("sig-b25"), Components{"@authority", "date"}, Tolerance(0))
+
+	id, err := v.VerifyRequest(r)
+	require.NoError(t, err)
+	assert.Equal(t, "test-shared-secret", id)
+
+	r.Header.Set("Content-Type", "text/plain")
+	_, err = v.VerifyRequest(r)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	v = NewMessageVerifier(v.keys, Label("sig-b25"), Components{"@authority", "date"})
+	_, err = v.VerifyRequest(r)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func newTestCertificate(t *testing.T, key crypto.Signer) *tls.Certificate {
+	t.Helper()
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "partner"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestMessageSignerCertificate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		key crypto.Signer
+		alg string
+	}{
+		{rsaKey, AlgorithmRSAv15SHA256},
+		{p256Key, AlgorithmECDSAP256SHA256},
+		{p384Key, AlgorithmECDSAP384SHA384},
+		{edKey, AlgorithmEd25519},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			c := newTestCertificate(t, tt.key)
+			leaf, err := x509.ParseCertificate(c.Certificate[0])
+			require.NoError(t, err)
+
+			s := NewMessageSigner(Certificate(func() *tls.Certificate { return c }))
+			req, err := http.NewRequest(http.MethodPost, "https://api.example.com:443/orders?id=1", strings.NewReader(`{"id":1}`))
+			require.NoError(t, err)
+			require.NoError(t, s.SignRequest(req))
+
+			assert.Contains(t, req.Header.Get(HeaderSignatureInput), `alg="`+tt.alg+`"`)
+			assert.Contains(t, req.Header.Get(HeaderSignatureInput), `keyid="`+CertificateKeyID(leaf)+`"`)
+			assert.Equal(t, "sha-256=:"+base64.StdEncoding.EncodeToString(sha256Sum([]byte(`{"id":1}`)))+":", req.Header.Get(HeaderContentDigest))
+
+			// Simulate inbound request received by the server.
+			r := httptest.NewRequest(http.MethodPost, "/orders?id=1", strings.NewReader(`{"id":1}`))
+			r.Host = "api.example.com"
+			r.TLS = &tls.ConnectionState{}
+			r.Header = req.Header.Clone()
+
+			v := NewMessageVerifier(Certificates{leaf})
+			id, err := v.VerifyRequest(r)
+			require.NoError(t, err)
+			assert.Equal(t, CertificateKeyID(leaf), id)
+
+			r.Body = http.NoBody
+			r.Header.Set(HeaderContentDigest, "sha-256=:"+base64.StdEncoding.EncodeToString(sha256Sum(nil))+":")
+			_, err = v.VerifyRequest(r)
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+
+			_, err = NewMessageVerifier(Certificates{}).VerifyRequest(r)
+			assert.ErrorIs(t, err, ErrUnknownKey)
+		})
+	}
+}
+
+func TestMessageVerifierRequiredComponents(t *testing.T) {
+	keys := KeyRing{keyring.New(keyring.Key{ID: "k1", Secret: []byte("secret")})}
+
+	s := NewMessageSigner(keys, Components{"@method", "x-request-id"})
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("data"))
+	require.NoError(t, s.SignRequest(req))
+	assert.True(t, strings.HasPrefix(req.Header.Get(HeaderSignatureInput), `sig1=("@method" "content-digest");created=`))
+
+	_, err := NewMessageVerifier(keys).VerifyRequest(req)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	id, err := NewMessageVerifier(keys, Components{"@method"}).VerifyRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, "k1", id)
+
+	_, err = NewMessageVerifier(keys, Label("other")).VerifyRequest(req)
+	assert.ErrorIs(t, err, ErrMissingSignature)
+
+	_, err = NewMessageVerifier(KeyResolverFunc(func(ctx context.Context, id string) (*Key, error) {
+		return &Key{ID: id, Secret: []byte("other")}, nil
+	}), Components{"@method"}).VerifyRequest(req)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
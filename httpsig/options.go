@@ -0,0 +1,74 @@
+package httpsig
+
+import (
+	"time"
+)
+
+type options struct {
+	Label       string
+	Components  []string
+	Header      string
+	Tolerance   time.Duration
+	MaxBodySize int64
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Label:       "sig1",
+		Header:      "X-Signature",
+		Tolerance:   5 * time.Minute,
+		MaxBodySize: 10 << 20,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	return opt
+}
+
+// Option for request signing and verification.
+type Option interface {
+	apply(*options)
+}
+
+// Label is a HTTP message signature label. Defaults to "sig1".
+type Label string
+
+func (l Label) apply(o *options) {
+	o.Label = string(l)
+}
+
+// Components are HTTP message components covered by the signature.
+//
+// For signer components are included in the signature if present in the request.
+// For verifier components must be covered by the signature. Defaults to
+// "@method", "@authority", "@path" and "@query". Content digest is always
+// covered for requests with body.
+type Components []string
+
+func (c Components) apply(o *options) {
+	o.Components = append(o.Components, c...)
+}
+
+// Header is a header name for HMAC signature. Defaults to "X-Signature".
+type Header string
+
+func (h Header) apply(o *options) {
+	o.Header = string(h)
+}
+
+// Tolerance is a maximum allowed age of the signature. Defaults to 5 minutes.
+//
+// Zero tolerance disables the check.
+type Tolerance time.Duration
+
+func (t Tolerance) apply(o *options) {
+	o.Tolerance = time.Duration(t)
+}
+
+// MaxBodySize is a maximum size of the request body in bytes to read for
+// digest verification. Defaults to 10 MiB.
+type MaxBodySize int64
+
+func (s MaxBodySize) apply(o *options) {
+	o.MaxBodySize = int64(s)
+}
@@ -16,6 +16,13 @@ func (i Instrumenter) Observe(ctx context.Context, op string, args ...any) func(
 	return func(err error) {}
 }
 
+// Label is a named value that can be passed as an instrumentation argument,
+// for example to be used as a metrics label.
+type Label struct {
+	Name  string
+	Value string
+}
+
 // NullInstrumenter is a no-op instrumenter.
 func NullInstrumenter(ctx context.Context, op string, args ...any) func(err error) {
 	return func(err error) {}
@@ -0,0 +1,30 @@
+package awsauth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "ENVKEY")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "envsecret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	assert.Equal(t, Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}.Resolve())
+	assert.Equal(t, Credentials{AccessKeyID: "ENVKEY", SecretAccessKey: "envsecret"}, Credentials{}.Resolve())
+}
+
+func TestSign(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://dynamodb.eu-west-1.amazonaws.com/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+
+	Sign(req, []byte("{}"), Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"}, "eu-west-1", "dynamodb", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	assert.Equal(t, "20240102T030405Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "token", req.Header.Get("X-Amz-Security-Token"))
+	assert.Regexp(t, `^AWS4-HMAC-SHA256 Credential=AKID/20240102/eu-west-1/dynamodb/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token, Signature=[0-9a-f]{64}$`, req.Header.Get("Authorization"))
+}
@@ -0,0 +1,29 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package cachetest provides cache fixtures for tests.
+package cachetest
+
+import (
+	"context"
+	"testing"
+
+	"azugo.io/core/cache"
+
+	"github.com/stretchr/testify/require"
+)
+
+// New returns started memory cache that is closed when the test finishes.
+//
+// Values are readable right after they are set. Options are applied after
+// the defaults.
+func New(t testing.TB, opts ...cache.CacheOption) *cache.Cache {
+	t.Helper()
+
+	opts = append([]cache.CacheOption{cache.MemoryCache, cache.MemorySyncWrites(true)}, opts...)
+	c := cache.New(opts...)
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(c.Close)
+	return c
+}
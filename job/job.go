@@ -0,0 +1,328 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package job
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"azugo.io/core/cache"
+
+	"go.uber.org/zap"
+)
+
+// DefaultTTL is a default time to keep job status after last update.
+const DefaultTTL = 24 * time.Hour
+
+// finishTimeout is a maximum time to save final status of the job running in
+// the background.
+const finishTimeout = 10 * time.Second
+
+// ErrJobNotFound is returned when job status is not found.
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobCanceled is returned when job has been canceled.
+var ErrJobCanceled = errors.New("job canceled")
+
+// State of the job.
+type State string
+
+const (
+	// StatePending job is created but not yet started.
+	StatePending State = "pending"
+	// StateRunning job is running.
+	StateRunning State = "running"
+	// StateCompleted job has finished successfully.
+	StateCompleted State = "completed"
+	// StateFailed job has finished with an error.
+	StateFailed State = "failed"
+	// StateCanceled job has been canceled.
+	StateCanceled State = "canceled"
+)
+
+// IsFinished returns true if job is in final state.
+func (s State) IsFinished() bool {
+	return s == StateCompleted || s == StateFailed || s == StateCanceled
+}
+
+// Status of the job.
+type Status[T any] struct {
+	ID        string    `json:"id"`
+	State     State     `json:"state"`
+	Current   int64     `json:"current"`
+	Total     int64     `json:"total,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Result    T         `json:"result,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Progress returns job progress as percentage.
+//
+// If total is unknown returns -1.
+func (s *Status[T]) Progress() float64 {
+	if s.Total <= 0 {
+		return -1
+	}
+	return float64(s.Current) * 100 / float64(s.Total)
+}
+
+// Func is a function that is executed as a job.
+type Func[T any] func(ctx context.Context, j *Job[T]) (T, error)
+
+// Tracker stores and tracks job statuses in the cache.
+type Tracker[T any] struct {
+	status cache.CacheInstance[Status[T]]
+	cancel cache.CacheInstance[bool]
+	ttl    time.Duration
+	logger *zap.Logger
+
+	lock    sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+// New creates new job tracker that stores job statuses in the cache instance with specified name.
+func New[T any](c *cache.Cache, name string, opts ...Option) (*Tracker[T], error) {
+	opt := newOptions(opts...)
+
+	status, err := cache.Create[Status[T]](c, name, opt.CacheOptions...)
+	if err != nil {
+		return nil, err
+	}
+	cancel, err := cache.Create[bool](c, name+"-cancel", opt.CacheOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tracker[T]{
+		status:  status,
+		cancel:  cancel,
+		ttl:     opt.TTL,
+		logger:  opt.Logger,
+		running: make(map[string]context.CancelFunc),
+	}, nil
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (t *Tracker[T]) save(ctx context.Context, s *Status[T]) error {
+	s.UpdatedAt = time.Now().UTC()
+	return t.status.Set(ctx, s.ID, *s, cache.TTL[Status[T]](t.ttl))
+}
+
+// Create new job in pending state.
+func (t *Tracker[T]) Create(ctx context.Context) (*Job[T], error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	s := &Status[T]{
+		ID:        id,
+		State:     StatePending,
+		CreatedAt: now,
+	}
+	if err := t.save(ctx, s); err != nil {
+		return nil, err
+	}
+	return &Job[T]{
+		tracker: t,
+		status:  s,
+	}, nil
+}
+
+// Start creates new job and runs it in the background.
+//
+// Provided context is used as a parent context for the job and must not be request context.
+func (t *Tracker[T]) Start(ctx context.Context, fn Func[T]) (*Job[T], error) {
+	j, err := t.Create(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jctx, cancel := context.WithCancel(ctx)
+	j.ctx = jctx
+
+	t.lock.Lock()
+	t.running[j.ID()] = cancel
+	t.lock.Unlock()
+
+	if err := j.update(jctx, func(s *Status[T]) {
+		s.State = StateRunning
+	}); err != nil {
+		t.finished(j.ID())
+		return nil, err
+	}
+
+	go func() {
+		defer t.finished(j.ID())
+
+		res, err := fn(jctx, j)
+		// Job context is canceled and parent context can also be canceled on
+		// shutdown, so final status is saved without parent cancellation.
+		fctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finishTimeout)
+		defer cancel()
+		if err := j.finish(fctx, res, err); err != nil {
+			t.logger.Error("failed to save job status", zap.String("job.id", j.ID()), zap.Error(err))
+		}
+	}()
+
+	return j, nil
+}
+
+func (t *Tracker[T]) finished(id string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if cancel, ok := t.running[id]; ok {
+		cancel()
+		delete(t.running, id)
+	}
+}
+
+// Get returns job status.
+func (t *Tracker[T]) Get(ctx context.Context, id string) (*Status[T], error) {
+	s, err := t.status.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.ID) == 0 {
+		return nil, ErrJobNotFound
+	}
+	return &s, nil
+}
+
+// Cancel requests job cancellation.
+//
+// If job is running in current process its context is canceled immediately,
+// otherwise it will be canceled on next job status update.
+func (t *Tracker[T]) Cancel(ctx context.Context, id string) error {
+	s, err := t.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if s.State.IsFinished() {
+		return nil
+	}
+	if err := t.cancel.Set(ctx, id, true, cache.TTL[bool](t.ttl)); err != nil {
+		return err
+	}
+	t.finished(id)
+	return nil
+}
+
+func (t *Tracker[T]) canceled(ctx context.Context, id string) bool {
+	v, _ := t.cancel.Get(ctx, id)
+	return v
+}
+
+// Job represents a tracked job.
+type Job[T any] struct {
+	tracker *Tracker[T]
+	ctx     context.Context
+
+	lock   sync.Mutex
+	status *Status[T]
+}
+
+// ID returns job identifier.
+func (j *Job[T]) ID() string {
+	return j.status.ID
+}
+
+// Status returns copy of the current job status.
+func (j *Job[T]) Status() Status[T] {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return *j.status
+}
+
+// Context returns job context that is canceled when job is canceled.
+func (j *Job[T]) Context() context.Context {
+	if j.ctx == nil {
+		return context.Background()
+	}
+	return j.ctx
+}
+
+func (j *Job[T]) update(ctx context.Context, fn func(s *Status[T])) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.tracker.canceled(ctx, j.status.ID) {
+		j.tracker.finished(j.status.ID)
+		return ErrJobCanceled
+	}
+	fn(j.status)
+	return j.tracker.save(ctx, j.status)
+}
+
+// Progress updates job progress.
+//
+// Returns ErrJobCanceled if job cancellation has been requested.
+func (j *Job[T]) Progress(ctx context.Context, current, total int64, message string) error {
+	return j.update(ctx, func(s *Status[T]) {
+		s.State = StateRunning
+		s.Current = current
+		s.Total = total
+		s.Message = message
+	})
+}
+
+func (j *Job[T]) finish(ctx context.Context, res T, err error) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	switch {
+	case errors.Is(err, ErrJobCanceled) || errors.Is(err, context.Canceled) || j.tracker.canceled(ctx, j.status.ID):
+		j.status.State = StateCanceled
+	case err != nil:
+		j.status.State = StateFailed
+		j.status.Error = err.Error()
+	default:
+		j.status.State = StateCompleted
+		j.status.Result = res
+		if j.status.Total > 0 {
+			j.status.Current = j.status.Total
+		}
+	}
+	return j.tracker.save(ctx, j.status)
+}
+
+// Complete marks job as completed with the result.
+func (j *Job[T]) Complete(ctx context.Context, res T) error {
+	return j.finish(ctx, res, nil)
+}
+
+// Fail marks job as failed with the error.
+func (j *Job[T]) Fail(ctx context.Context, err error) error {
+	var res T
+	if err == nil {
+		err = errors.New("unknown error")
+	}
+	return j.finish(ctx, res, err)
+}
+
+// StatusURL returns job status URL relative to the base URL.
+func StatusURL(base *url.URL, id string) *url.URL {
+	u := *base
+	u.Path = path.Join(u.Path, url.PathEscape(id))
+	u.RawPath = ""
+	return &u
+}
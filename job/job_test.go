@@ -0,0 +1,115 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/internal/cachetest"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waitState[T any](t *testing.T, tr *Tracker[T], id string, state State) *Status[T] {
+	var s *Status[T]
+	require.Eventually(t, func() bool {
+		var err error
+		s, err = tr.Get(context.TODO(), id)
+		return err == nil && s.State == state
+	}, time.Second, 10*time.Millisecond)
+	return s
+}
+
+func TestJobComplete(t *testing.T) {
+	tr, err := New[string](cachetest.New(t), "jobs")
+	require.NoError(t, err)
+
+	j, err := tr.Start(context.Background(), func(ctx context.Context, j *Job[string]) (string, error) {
+		if err := j.Progress(ctx, 1, 2, "half way"); err != nil {
+			return "", err
+		}
+		return "done", nil
+	})
+	require.NoError(t, err)
+
+	s := waitState(t, tr, j.ID(), StateCompleted)
+	assert.Equal(t, "done", s.Result)
+	assert.Equal(t, float64(100), s.Progress())
+}
+
+func TestJobFail(t *testing.T) {
+	tr, err := New[string](cachetest.New(t), "jobs")
+	require.NoError(t, err)
+
+	j, err := tr.Start(context.Background(), func(ctx context.Context, j *Job[string]) (string, error) {
+		return "", errors.New("test error")
+	})
+	require.NoError(t, err)
+
+	s := waitState(t, tr, j.ID(), StateFailed)
+	assert.Equal(t, "test error", s.Error)
+}
+
+func TestJobCancel(t *testing.T) {
+	tr, err := New[string](cachetest.New(t), "jobs")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	j, err := tr.Start(context.Background(), func(ctx context.Context, j *Job[string]) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	require.NoError(t, err)
+	<-started
+
+	// Memory cache is eventually consistent.
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, tr.Cancel(context.TODO(), j.ID()))
+
+	waitState(t, tr, j.ID(), StateCanceled)
+}
+
+func TestJobParentCanceled(t *testing.T) {
+	srv := miniredis.RunT(t)
+	c := cache.New(cache.CacheType(cache.RedisCache), cache.ConnectionString("redis://"+srv.Addr()))
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(c.Close)
+
+	tr, err := New[string](c, "jobs")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	j, err := tr.Start(ctx, func(ctx context.Context, j *Job[string]) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	require.NoError(t, err)
+	<-started
+
+	// Final status is saved when parent context is canceled on shutdown.
+	cancel()
+	waitState(t, tr, j.ID(), StateCanceled)
+}
+
+func TestJobNotFound(t *testing.T) {
+	tr, err := New[string](cachetest.New(t), "jobs")
+	require.NoError(t, err)
+
+	_, err = tr.Get(context.TODO(), "missing")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestStatusURL(t *testing.T) {
+	base, err := url.Parse("https://localhost/api/jobs?x=1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://localhost/api/jobs/abc?x=1", StatusURL(base, "abc").String())
+}
@@ -0,0 +1,56 @@
+package job
+
+import (
+	"time"
+
+	"azugo.io/core/cache"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	TTL          time.Duration
+	CacheOptions []cache.CacheOption
+	Logger       *zap.Logger
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		TTL: DefaultTTL,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return opt
+}
+
+// Option for the job tracker.
+type Option interface {
+	apply(*options)
+}
+
+// TTL is a time to keep job status after last update.
+type TTL time.Duration
+
+func (t TTL) apply(o *options) {
+	o.TTL = time.Duration(t)
+}
+
+// CacheOptions are options for the cache instance used to store job statuses.
+type CacheOptions []cache.CacheOption
+
+func (c CacheOptions) apply(o *options) {
+	o.CacheOptions = append(o.CacheOptions, c...)
+}
+
+// Logger to log errors of jobs running in the background.
+type Logger struct {
+	*zap.Logger
+}
+
+func (l Logger) apply(o *options) {
+	o.Logger = l.Logger
+}
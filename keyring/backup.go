@@ -0,0 +1,52 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package keyring
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/goccy/go-json"
+)
+
+type backupKey struct {
+	ID     string `json:"id"`
+	Secret []byte `json:"secret"`
+}
+
+// Backup writes all keys to the writer. Backup contains secret key material
+// and must be stored encrypted.
+//
+// KeyRing implements backup.Handler interface.
+func (k *KeyRing) Backup(_ context.Context, w io.Writer) error {
+	keys := k.Keys()
+	out := make([]backupKey, 0, len(keys))
+	for _, kk := range keys {
+		out = append(out, backupKey{ID: kk.ID, Secret: kk.Secret})
+	}
+	return json.NewEncoder(w).Encode(out)
+}
+
+// Restore replaces all keys in the key ring with keys from the backup.
+func (k *KeyRing) Restore(_ context.Context, r io.Reader) error {
+	var in []backupKey
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return err
+	}
+	keys := make([]Key, 0, len(in))
+	for _, kk := range in {
+		if len(kk.ID) == 0 || len(kk.Secret) == 0 {
+			return errors.New("invalid key in backup")
+		}
+		keys = append(keys, Key{ID: kk.ID, Secret: kk.Secret})
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.keys = keys
+	return nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"sync"
+)
+
+var (
+	// ErrKeyNotFound is returned when key with specified ID is not in the key ring.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrDecrypt is returned when encrypted data can not be decrypted.
+	ErrDecrypt = errors.New("unable to decrypt data")
+)
+
+// Key is a secret key with identifier.
+type Key struct {
+	// ID of the key.
+	ID string
+	// Secret key material.
+	Secret []byte
+}
+
+// KeyRing holds a set of secret keys with support for key rotation.
+//
+// The newest key is the primary key and is used to sign data, all other
+// keys are only used to verify signatures.
+type KeyRing struct {
+	lock sync.RWMutex
+	keys []Key
+}
+
+// New creates new key ring. First key is used as a primary key.
+func New(keys ...Key) *KeyRing {
+	return &KeyRing{
+		keys: append([]Key{}, keys...),
+	}
+}
+
+// Rotate adds new key as a primary key.
+//
+// Previous keys are kept for verification until removed.
+func (k *KeyRing) Rotate(key Key) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	keys := make([]Key, 0, len(k.keys)+1)
+	keys = append(keys, key)
+	for _, kk := range k.keys {
+		if kk.ID != key.ID {
+			keys = append(keys, kk)
+		}
+	}
+	k.keys = keys
+}
+
+// Remove key with specified ID from the key ring.
+func (k *KeyRing) Remove(id string) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	for i, kk := range k.keys {
+		if kk.ID == id {
+			k.keys = append(k.keys[:i:i], k.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// Primary returns primary key.
+func (k *KeyRing) Primary() (Key, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	if len(k.keys) == 0 {
+		return Key{}, ErrKeyNotFound
+	}
+	return k.keys[0], nil
+}
+
+// Key returns key with specified ID.
+func (k *KeyRing) Key(id string) (Key, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	for _, kk := range k.keys {
+		if kk.ID == id {
+			return kk, nil
+		}
+	}
+	return Key{}, ErrKeyNotFound
+}
+
+// Keys returns all keys in the key ring starting with primary key.
+func (k *KeyRing) Keys() []Key {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return append([]Key{}, k.keys...)
+}
+
+func sign(secret, data []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	_, _ = h.Write(data)
+	return h.Sum(nil)
+}
+
+// Sign data using HMAC-SHA256 with the primary key.
+//
+// Returns ID of the key used and signature.
+func (k *KeyRing) Sign(data []byte) (string, []byte, error) {
+	key, err := k.Primary()
+	if err != nil {
+		return "", nil, err
+	}
+	return key.ID, sign(key.Secret, data), nil
+}
+
+// Verify HMAC-SHA256 signature using key with specified ID.
+//
+// If key ID is empty all keys in the key ring are tried.
+func (k *KeyRing) Verify(id string, data, signature []byte) bool {
+	if len(id) != 0 {
+		key, err := k.Key(id)
+		if err != nil {
+			return false
+		}
+		return hmac.Equal(sign(key.Secret, data), signature)
+	}
+	for _, key := range k.Keys() {
+		if hmac.Equal(sign(key.Secret, data), signature) {
+			return true
+		}
+	}
+	return false
+}
+
+func aead(secret []byte) (cipher.AEAD, error) {
+	// Derive encryption key so that secret can be of any length
+	// and is not used directly for both signing and encryption.
+	h := sha256.New()
+	_, _ = h.Write([]byte("azugo-keyring-encryption"))
+	_, _ = h.Write(secret)
+
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt data using AES-256-GCM with the primary key.
+//
+// Encrypted data contains ID of the key used so it can be decrypted after key rotation.
+func (k *KeyRing) Encrypt(data []byte) ([]byte, error) {
+	key, err := k.Primary()
+	if err != nil {
+		return nil, err
+	}
+	if len(key.ID) > 255 {
+		return nil, errors.New("key ID too long")
+	}
+	c, err := aead(key.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1+len(key.ID)+c.NonceSize(), 1+len(key.ID)+c.NonceSize()+len(data)+c.Overhead())
+	buf[0] = byte(len(key.ID))
+	copy(buf[1:], key.ID)
+	nonce := buf[1+len(key.ID):]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return c.Seal(buf, nonce, data, buf[:1+len(key.ID)]), nil
+}
+
+// Decrypt data encrypted with Encrypt.
+func (k *KeyRing) Decrypt(data []byte) ([]byte, error) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return nil, ErrDecrypt
+	}
+	l := 1 + int(data[0])
+	key, err := k.Key(string(data[1:l]))
+	if err != nil {
+		return nil, err
+	}
+	c, err := aead(key.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < l+c.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	out, err := c.Open(nil, data[l:l+c.NonceSize()], data[l+c.NonceSize():], data[:l])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return out, nil
+}
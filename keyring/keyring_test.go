@@ -0,0 +1,65 @@
+package keyring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRingSignVerify(t *testing.T) {
+	k := New(Key{ID: "k1", Secret: []byte("secret1")})
+
+	id, sig, err := k.Sign([]byte("data"))
+	require.NoError(t, err)
+	assert.Equal(t, "k1", id)
+	assert.True(t, k.Verify(id, []byte("data"), sig))
+	assert.True(t, k.Verify("", []byte("data"), sig))
+	assert.False(t, k.Verify(id, []byte("other"), sig))
+}
+
+func TestKeyRingRotate(t *testing.T) {
+	k := New(Key{ID: "k1", Secret: []byte("secret1")})
+
+	_, sig, err := k.Sign([]byte("data"))
+	require.NoError(t, err)
+
+	k.Rotate(Key{ID: "k2", Secret: []byte("secret2")})
+
+	id, _, err := k.Sign([]byte("data"))
+	require.NoError(t, err)
+	assert.Equal(t, "k2", id)
+	assert.True(t, k.Verify("k1", []byte("data"), sig), "old signatures must be valid after rotation")
+
+	k.Remove("k1")
+	assert.False(t, k.Verify("k1", []byte("data"), sig))
+	assert.Len(t, k.Keys(), 1)
+}
+
+func TestKeyRingEmpty(t *testing.T) {
+	k := New()
+
+	_, _, err := k.Sign([]byte("data"))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestKeyRingEncryptDecrypt(t *testing.T) {
+	k := New(Key{ID: "k1", Secret: []byte("secret1")})
+
+	enc, err := k.Encrypt([]byte("data"))
+	require.NoError(t, err)
+
+	k.Rotate(Key{ID: "k2", Secret: []byte("secret2")})
+
+	dec, err := k.Decrypt(enc)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), dec)
+
+	enc[len(enc)-1] ^= 0xff
+	_, err = k.Decrypt(enc)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	k.Remove("k1")
+	_, err = k.Decrypt(enc)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
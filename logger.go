@@ -8,6 +8,8 @@ import (
 	"os"
 	"strings"
 
+	"azugo.io/core/logging"
+	"azugo.io/core/scrub"
 	"azugo.io/core/system"
 
 	"github.com/mattn/go-colorable"
@@ -63,16 +65,29 @@ func (a *App) initLogger() {
 
 	info := system.CollectInfo()
 
+	defaultLevel := zap.InfoLevel
+	if a.Env().IsDevelopment() && !info.IsContainer() {
+		defaultLevel = zap.DebugLevel
+	}
+	a.logLevels = logging.NewController(parseLogLevel(os.Getenv("LOG_LEVEL"), defaultLevel))
+	if levels, err := logging.ParseLevels(os.Getenv("LOG_LEVELS")); err == nil {
+		for name, level := range levels {
+			a.logLevels.SetLevel(name, level)
+		}
+	}
+
+	a.scrubber = newScrubber()
+
 	if a.Env().IsDevelopment() && !info.IsContainer() {
 		conf := zap.NewDevelopmentEncoderConfig()
 		conf.EncodeLevel = zapcore.CapitalColorLevelEncoder
 
 		a.logger = zap.New(
-			zapcore.NewCore(
+			a.logLevels.Core(a.scrubber.Core(zapcore.NewCore(
 				zapcore.NewConsoleEncoder(conf),
 				zapcore.AddSync(colorable.NewColorableStdout()),
-				parseLogLevel(os.Getenv("LOG_LEVEL"), zap.DebugLevel),
-			),
+				zap.DebugLevel,
+			))),
 			zap.AddCaller(),
 			zap.AddStacktrace(zap.ErrorLevel),
 		).With(a.loggerFields(info)...)
@@ -80,11 +95,11 @@ func (a *App) initLogger() {
 		return
 	}
 
-	core := ecszap.NewCore(
+	core := a.logLevels.Core(a.scrubber.Core(ecszap.NewCore(
 		ecszap.NewDefaultEncoderConfig(),
 		os.Stdout,
-		parseLogLevel(os.Getenv("LOG_LEVEL"), zap.InfoLevel),
-	)
+		zap.DebugLevel,
+	)))
 
 	a.logger = zap.New(core, zap.AddCaller()).With(a.loggerFields(info)...)
 }
@@ -97,6 +112,39 @@ func (a *App) ReplaceLogger(logger *zap.Logger) error {
 	return nil
 }
 
+// LogLevels returns controller to change log levels of the named loggers and
+// log sampling at runtime.
+//
+// Controller has no effect on the logger set by ReplaceLogger.
+func (a *App) LogLevels() *logging.Controller {
+	if a.logger == nil {
+		a.initLogger()
+	}
+	a.loglock.Lock()
+	defer a.loglock.Unlock()
+	if a.logLevels == nil {
+		a.logLevels = logging.NewController(parseLogLevel(os.Getenv("LOG_LEVEL"), zap.InfoLevel))
+	}
+	return a.logLevels
+}
+
+// Scrubber returns data scrubber that masks, hashes or drops sensitive fields
+// in application logs, cache audit information and cache events.
+//
+// Rules are loaded from SCRUB_RULES environment variable and can be changed at runtime.
+// Scrubber has no effect on the logger set by ReplaceLogger.
+func (a *App) Scrubber() *scrub.Scrubber {
+	if a.logger == nil {
+		a.initLogger()
+	}
+	a.loglock.Lock()
+	defer a.loglock.Unlock()
+	if a.scrubber == nil {
+		a.scrubber = newScrubber()
+	}
+	return a.scrubber
+}
+
 // Log returns application logger.
 func (a *App) Log() *zap.Logger {
 	if a.logger == nil {
@@ -112,3 +160,14 @@ func parseLogLevel(level string, defaultLevel zapcore.Level) zapcore.Level {
 	}
 	return l
 }
+
+func newScrubber() *scrub.Scrubber {
+	opts := make([]scrub.Option, 0, 2)
+	if rules, err := scrub.ParseRules(os.Getenv("SCRUB_RULES")); err == nil {
+		opts = append(opts, rules)
+	}
+	if key := os.Getenv("SCRUB_HASH_KEY"); len(key) != 0 {
+		opts = append(opts, scrub.HashKey(key))
+	}
+	return scrub.New(opts...)
+}
@@ -0,0 +1,47 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/goccy/go-json"
+)
+
+// configHandler returns HTTP handler that responds with current configuration to
+// GET requests and replaces configuration with request body on PUT requests.
+func configHandler(get func() Config, set func(ctx context.Context, conf Config) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut:
+			var conf Config
+			if err := json.NewDecoder(r.Body).Decode(&conf); err != nil {
+				http.Error(w, "invalid log level configuration: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := set(r.Context(), conf); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(get())
+	})
+}
+
+// Handler returns admin HTTP handler to view and change log level configuration
+// of this application instance.
+func (c *Controller) Handler() http.Handler {
+	return configHandler(c.Config, func(_ context.Context, conf Config) error {
+		return c.Apply(conf)
+	})
+}
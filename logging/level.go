@@ -0,0 +1,340 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sampling limits number of log entries with the same level and message logged
+// per tick. First entries are logged and after that only every Thereafter entry.
+//
+// Error and higher level entries are never sampled.
+type Sampling struct {
+	// Tick is a sampling interval.
+	Tick time.Duration
+	// First is a number of entries logged per tick.
+	First int
+	// Thereafter is an interval of entries logged after First entries. Zero
+	// drops all entries after First entries.
+	Thereafter int
+}
+
+type samplingJSON struct {
+	Tick       string `json:"tick"`
+	First      int    `json:"first"`
+	Thereafter int    `json:"thereafter"`
+}
+
+// MarshalJSON encodes sampling with tick as duration string.
+func (s Sampling) MarshalJSON() ([]byte, error) {
+	return json.Marshal(samplingJSON{
+		Tick:       s.Tick.String(),
+		First:      s.First,
+		Thereafter: s.Thereafter,
+	})
+}
+
+// UnmarshalJSON decodes sampling with tick as duration string.
+func (s *Sampling) UnmarshalJSON(data []byte) error {
+	v := samplingJSON{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	tick, err := time.ParseDuration(v.Tick)
+	if err != nil {
+		return fmt.Errorf("invalid sampling tick: %w", err)
+	}
+	*s = Sampling{Tick: tick, First: v.First, Thereafter: v.Thereafter}
+	return nil
+}
+
+// Config of log levels and sampling.
+type Config struct {
+	// Level is a default log level.
+	Level string `json:"level,omitempty"`
+	// Components are log levels of named loggers.
+	Components map[string]string `json:"components,omitempty"`
+	// Sampling of log entries. Nil disables sampling.
+	Sampling *Sampling `json:"sampling,omitempty"`
+	// UpdatedAt is a time when configuration was changed.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// ParseLevels parses component log levels in the format "component=level,...".
+func ParseLevels(s string) (map[string]zapcore.Level, error) {
+	levels := make(map[string]zapcore.Level)
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if len(p) == 0 {
+			continue
+		}
+		name, level, ok := strings.Cut(p, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid component log level %q", p)
+		}
+		l, err := zapcore.ParseLevel(strings.TrimSpace(level))
+		if err != nil {
+			return nil, err
+		}
+		levels[strings.TrimSpace(name)] = l
+	}
+	return levels, nil
+}
+
+type levels struct {
+	def        zapcore.Level
+	components map[string]zapcore.Level
+	min        zapcore.Level
+}
+
+// level returns log level for the logger name. Logger names are matched by the
+// longest dot separated prefix, for example "cache.redis" is matched by "cache".
+func (l *levels) level(name string) zapcore.Level {
+	if len(l.components) == 0 {
+		return l.def
+	}
+	for len(name) > 0 {
+		if lvl, ok := l.components[name]; ok {
+			return lvl
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return l.def
+}
+
+const samplerBuckets = 4096
+
+type samplerCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+func (c *samplerCounter) inc(now time.Time, tick time.Duration) uint64 {
+	tn := now.UnixNano()
+	resetAt := c.resetAt.Load()
+	if resetAt > tn {
+		return c.count.Add(1)
+	}
+	c.count.Store(1)
+	if !c.resetAt.CompareAndSwap(resetAt, tn+tick.Nanoseconds()) {
+		return c.count.Add(1)
+	}
+	return 1
+}
+
+type sampler struct {
+	Sampling
+	counters [zapcore.ErrorLevel - zapcore.DebugLevel][samplerBuckets]samplerCounter
+}
+
+func (s *sampler) sample(ent zapcore.Entry) bool {
+	if ent.Level >= zapcore.ErrorLevel || ent.Level < zapcore.DebugLevel {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(ent.Message))
+	n := s.counters[ent.Level-zapcore.DebugLevel][h.Sum32()%samplerBuckets].inc(ent.Time, s.Tick)
+	if n <= uint64(s.First) {
+		return true
+	}
+	return s.Thereafter > 0 && (n-uint64(s.First))%uint64(s.Thereafter) == 0
+}
+
+// Controller changes log levels of the named loggers and log sampling at runtime.
+type Controller struct {
+	lock    sync.Mutex
+	initial Config
+	levels  atomic.Pointer[levels]
+	sampler atomic.Pointer[sampler]
+	hooks   []func(Config)
+}
+
+// NewController creates new log level controller with the default log level.
+func NewController(level zapcore.Level) *Controller {
+	c := &Controller{
+		initial: Config{Level: level.String()},
+	}
+	c.levels.Store(&levels{def: level, min: level})
+	return c
+}
+
+func (c *Controller) store(def zapcore.Level, components map[string]zapcore.Level) {
+	l := &levels{def: def, components: components, min: def}
+	for _, lvl := range components {
+		if lvl < l.min {
+			l.min = lvl
+		}
+	}
+	c.levels.Store(l)
+}
+
+// Level returns log level of the named logger.
+func (c *Controller) Level(name string) zapcore.Level {
+	return c.levels.Load().level(name)
+}
+
+// SetLevel sets log level of the named logger and its children. Empty name sets
+// the default log level.
+func (c *Controller) SetLevel(name string, level zapcore.Level) {
+	c.lock.Lock()
+	cur := c.levels.Load()
+	if len(name) == 0 {
+		c.store(level, cur.components)
+	} else {
+		components := make(map[string]zapcore.Level, len(cur.components)+1)
+		for k, v := range cur.components {
+			components[k] = v
+		}
+		components[name] = level
+		c.store(cur.def, components)
+	}
+	c.lock.Unlock()
+
+	c.notify()
+}
+
+// ResetLevel removes log level of the named logger so it inherits level from
+// its parent or the default log level.
+func (c *Controller) ResetLevel(name string) {
+	c.lock.Lock()
+	cur := c.levels.Load()
+	components := make(map[string]zapcore.Level, len(cur.components))
+	for k, v := range cur.components {
+		if k != name {
+			components[k] = v
+		}
+	}
+	c.store(cur.def, components)
+	c.lock.Unlock()
+
+	c.notify()
+}
+
+// SetSampling sets log sampling. Nil disables sampling.
+func (c *Controller) SetSampling(s *Sampling) {
+	c.lock.Lock()
+	c.setSampling(s)
+	c.lock.Unlock()
+
+	c.notify()
+}
+
+func (c *Controller) setSampling(s *Sampling) {
+	if s == nil || s.Tick <= 0 {
+		c.sampler.Store(nil)
+		return
+	}
+	c.sampler.Store(&sampler{Sampling: *s})
+}
+
+// Config returns current log levels and sampling configuration.
+func (c *Controller) Config() Config {
+	l := c.levels.Load()
+	conf := Config{Level: l.def.String()}
+	if len(l.components) > 0 {
+		conf.Components = make(map[string]string, len(l.components))
+		for k, v := range l.components {
+			conf.Components[k] = v.String()
+		}
+	}
+	if s := c.sampler.Load(); s != nil {
+		sampling := s.Sampling
+		conf.Sampling = &sampling
+	}
+	return conf
+}
+
+// Apply replaces log levels and sampling with configuration. Empty level
+// resets to the initial default log level.
+func (c *Controller) Apply(conf Config) error {
+	def := c.initial.Level
+	if len(conf.Level) > 0 {
+		def = conf.Level
+	}
+	lvl, err := zapcore.ParseLevel(def)
+	if err != nil {
+		return err
+	}
+	components := make(map[string]zapcore.Level, len(conf.Components))
+	for k, v := range conf.Components {
+		l, err := zapcore.ParseLevel(v)
+		if err != nil {
+			return fmt.Errorf("invalid log level of %q: %w", k, err)
+		}
+		components[k] = l
+	}
+
+	c.lock.Lock()
+	c.store(lvl, components)
+	c.setSampling(conf.Sampling)
+	c.lock.Unlock()
+
+	c.notify()
+	return nil
+}
+
+// OnChange registers hook that is called when configuration changes.
+func (c *Controller) OnChange(fn func(Config)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.hooks = append(c.hooks, fn)
+}
+
+func (c *Controller) notify() {
+	c.lock.Lock()
+	hooks := append([]func(Config){}, c.hooks...)
+	c.lock.Unlock()
+
+	if len(hooks) == 0 {
+		return
+	}
+	conf := c.Config()
+	for _, fn := range hooks {
+		fn(conf)
+	}
+}
+
+// Core wraps zap core to filter log entries by the controller log levels and
+// sampling. Wrapped core should be enabled for all levels.
+func (c *Controller) Core(core zapcore.Core) zapcore.Core {
+	return &controlledCore{Core: core, c: c}
+}
+
+type controlledCore struct {
+	zapcore.Core
+	c *Controller
+}
+
+func (cc *controlledCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= cc.c.levels.Load().min && cc.Core.Enabled(lvl)
+}
+
+func (cc *controlledCore) With(fields []zapcore.Field) zapcore.Core {
+	return &controlledCore{Core: cc.Core.With(fields), c: cc.c}
+}
+
+func (cc *controlledCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < cc.c.Level(ent.LoggerName) {
+		return ce
+	}
+	if s := cc.c.sampler.Load(); s != nil && !s.sample(ent) {
+		return ce
+	}
+	return cc.Core.Check(ent, ce)
+}
@@ -0,0 +1,141 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"azugo.io/core/cache"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestLogger(c *Controller) (*zap.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	return zap.New(c.Core(core)), logs
+}
+
+func TestControllerLevels(t *testing.T) {
+	c := NewController(zapcore.InfoLevel)
+	log, logs := newTestLogger(c)
+
+	log.Named("cache").Debug("hidden")
+	c.SetLevel("cache", zapcore.DebugLevel)
+	log.Named("cache").Named("redis").Debug("visible")
+	log.Named("tls").Debug("hidden")
+	log.Debug("hidden")
+
+	c.SetLevel("", zapcore.WarnLevel)
+	log.Info("hidden")
+	log.Named("cache").Info("visible")
+
+	c.ResetLevel("cache")
+	log.Named("cache").Info("hidden")
+
+	require.Equal(t, 2, logs.Len())
+	for _, e := range logs.All() {
+		assert.Equal(t, "visible", e.Message)
+	}
+}
+
+func TestControllerSampling(t *testing.T) {
+	c := NewController(zapcore.DebugLevel)
+	log, logs := newTestLogger(c)
+
+	c.SetSampling(&Sampling{Tick: time.Hour, First: 2, Thereafter: 3})
+	for i := 0; i < 10; i++ {
+		log.Info("repeated")
+		log.Error("failure")
+	}
+	// 2 first entries and every 3rd after that.
+	assert.Equal(t, 4, logs.FilterMessage("repeated").Len())
+	assert.Equal(t, 10, logs.FilterMessage("failure").Len())
+
+	c.SetSampling(nil)
+	log.Info("repeated")
+	assert.Equal(t, 5, logs.FilterMessage("repeated").Len())
+}
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels("tls=debug, cache=warn")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]zapcore.Level{"tls": zapcore.DebugLevel, "cache": zapcore.WarnLevel}, levels)
+
+	_, err = ParseLevels("tls")
+	assert.Error(t, err)
+	_, err = ParseLevels("tls=verbose")
+	assert.Error(t, err)
+}
+
+func TestControllerHandler(t *testing.T) {
+	c := NewController(zapcore.InfoLevel)
+	h := c.Handler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(
+		`{"level":"warn","components":{"tls":"debug"},"sampling":{"tick":"1s","first":10,"thereafter":100}}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, zapcore.WarnLevel, c.Level("cache"))
+	assert.Equal(t, zapcore.DebugLevel, c.Level("tls.handshake"))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	conf := Config{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &conf))
+	assert.Equal(t, "warn", conf.Level)
+	require.NotNil(t, conf.Sampling)
+	assert.Equal(t, time.Second, conf.Sampling.Tick)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"verbose"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestSyncPropagation(t *testing.T) {
+	cc := cache.New(cache.MemoryCache)
+	require.NoError(t, cc.Start(context.Background()))
+	t.Cleanup(cc.Close)
+
+	c1 := NewController(zapcore.InfoLevel)
+	s1, err := NewSync(cc, c1, RefreshInterval(0))
+	require.NoError(t, err)
+	require.NoError(t, s1.Start(context.Background()))
+	defer s1.Stop()
+
+	c2 := NewController(zapcore.InfoLevel)
+	s2, err := NewSync(cc, c2, RefreshInterval(0))
+	require.NoError(t, err)
+	require.NoError(t, s2.Start(context.Background()))
+	defer s2.Stop()
+
+	changes := make(chan Config, 1)
+	c2.OnChange(func(conf Config) {
+		changes <- conf
+	})
+
+	require.NoError(t, s1.Set(context.Background(), Config{Components: map[string]string{"cache": "debug"}}))
+	assert.Equal(t, zapcore.DebugLevel, c1.Level("cache"))
+
+	select {
+	case conf := <-changes:
+		assert.Equal(t, "debug", conf.Components["cache"])
+	case <-time.After(time.Second):
+		require.Fail(t, "log level change was not propagated")
+	}
+	assert.Equal(t, zapcore.DebugLevel, c2.Level("cache"))
+	assert.Equal(t, zapcore.InfoLevel, c2.Level(""))
+}
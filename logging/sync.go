@@ -0,0 +1,195 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"azugo.io/core/cache"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+// DefaultName is a default cache key and channel name for the log level configuration.
+const DefaultName = "log-levels"
+
+// Sync shares log level configuration between all application instances using
+// cache storage and publish/subscribe for change propagation.
+//
+// Sync implements core.Tasker interface.
+type Sync struct {
+	name  string
+	ctrl  *Controller
+	cache *cache.Cache
+	opts  *options
+	store cache.CacheInstance[Config]
+
+	lock        sync.Mutex
+	updatedAt   time.Time
+	unsubscribe func()
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewSync creates new log level configuration synchronization for the controller.
+func NewSync(c *cache.Cache, ctrl *Controller, opts ...Option) (*Sync, error) {
+	opt := newOptions(opts...)
+
+	store, err := cache.Create[Config](c, opt.Name, opt.CacheOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sync{
+		name:  opt.Name,
+		ctrl:  ctrl,
+		cache: c,
+		opts:  opt,
+		store: store,
+	}, nil
+}
+
+// Name returns task name.
+func (s *Sync) Name() string {
+	return "log-level-sync"
+}
+
+// Start loads current log level configuration and subscribes to its changes.
+func (s *Sync) Start(ctx context.Context) error {
+	s.lock.Lock()
+	if s.stop != nil {
+		s.lock.Unlock()
+		return nil
+	}
+	s.stop = make(chan struct{})
+	stop := s.stop
+	s.lock.Unlock()
+
+	if err := s.Refresh(ctx); err != nil {
+		return err
+	}
+
+	unsubscribe, err := s.cache.Subscribe(ctx, s.name, func(message string) {
+		var conf Config
+		if err := json.Unmarshal([]byte(message), &conf); err != nil {
+			s.opts.Logger.Warn("invalid log level configuration message", zap.Error(err))
+			return
+		}
+		s.apply(conf)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	s.unsubscribe = unsubscribe
+	s.lock.Unlock()
+
+	if s.opts.RefreshInterval > 0 {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			t := time.NewTicker(s.opts.RefreshInterval)
+			defer t.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-stop:
+					return
+				case <-t.C:
+					if err := s.Refresh(ctx); err != nil {
+						s.opts.Logger.Warn("failed to refresh log level configuration", zap.Error(err))
+					}
+				}
+			}
+		}()
+	}
+
+	return nil
+}
+
+// Stop listening for log level configuration changes.
+func (s *Sync) Stop() {
+	s.lock.Lock()
+	if s.stop == nil {
+		s.lock.Unlock()
+		return
+	}
+	close(s.stop)
+	s.stop = nil
+	unsubscribe := s.unsubscribe
+	s.unsubscribe = nil
+	s.lock.Unlock()
+
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+	s.wg.Wait()
+}
+
+// Refresh reloads log level configuration from the cache.
+func (s *Sync) Refresh(ctx context.Context) error {
+	conf, err := s.store.Get(ctx, s.name)
+	if err != nil {
+		return err
+	}
+	s.apply(conf)
+	return nil
+}
+
+// apply configuration to the controller if it is newer than the current one.
+func (s *Sync) apply(conf Config) {
+	// Configuration was never stored.
+	if conf.UpdatedAt.IsZero() {
+		return
+	}
+
+	s.lock.Lock()
+	if !conf.UpdatedAt.After(s.updatedAt) {
+		s.lock.Unlock()
+		return
+	}
+	s.updatedAt = conf.UpdatedAt
+	s.lock.Unlock()
+
+	if err := s.ctrl.Apply(conf); err != nil {
+		s.opts.Logger.Warn("invalid log level configuration", zap.Error(err))
+	}
+}
+
+// Set log level configuration for all application instances.
+func (s *Sync) Set(ctx context.Context, conf Config) error {
+	if err := s.ctrl.Apply(conf); err != nil {
+		return err
+	}
+	conf = s.ctrl.Config()
+	conf.UpdatedAt = time.Now().UTC()
+
+	if err := s.store.Set(ctx, s.name, conf); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	s.updatedAt = conf.UpdatedAt
+	s.lock.Unlock()
+
+	buf, err := json.Marshal(conf)
+	if err != nil {
+		return err
+	}
+	return s.cache.Publish(ctx, s.name, string(buf))
+}
+
+// Handler returns admin HTTP handler to view and change log level configuration
+// of all application instances.
+func (s *Sync) Handler() http.Handler {
+	return configHandler(s.ctrl.Config, s.Set)
+}
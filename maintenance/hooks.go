@@ -0,0 +1,67 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package maintenance
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"azugo.io/core/queue"
+)
+
+// Middleware responds with 503 Service Unavailable to all requests while maintenance
+// mode is enabled, except for requests to the paths with allowed prefixes (for example health checks).
+func (m *Mode) Middleware(next http.Handler, allowed ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := m.State()
+		if !s.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, p := range allowed {
+			if strings.HasPrefix(r.URL.Path, p) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if !s.Until.IsZero() {
+			if d := time.Until(s.Until); d > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+			}
+		}
+		msg := s.Message
+		if len(msg) == 0 {
+			msg = http.StatusText(http.StatusServiceUnavailable)
+		}
+		http.Error(w, msg, http.StatusServiceUnavailable)
+	})
+}
+
+// Handler wraps queue message handler to pause message processing while
+// maintenance mode is enabled.
+func (m *Mode) Handler(next queue.Handler) queue.Handler {
+	return func(ctx context.Context, msg *queue.Message) error {
+		if err := m.Wait(ctx); err != nil {
+			return err
+		}
+		return next(ctx, msg)
+	}
+}
+
+// Run wraps scheduled task function to skip execution while maintenance
+// mode is enabled.
+func (m *Mode) Run(fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if m.Enabled() {
+			return nil
+		}
+		return fn(ctx)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package maintenance
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"azugo.io/core/cache"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+// DefaultName is a default cache key and channel name for the maintenance state.
+const DefaultName = "maintenance"
+
+// State of the maintenance mode.
+type State struct {
+	// Enabled is true if maintenance mode is enabled.
+	Enabled bool `json:"enabled"`
+	// Message is a message to show to the users.
+	Message string `json:"message,omitempty"`
+	// Since is a time when maintenance mode was enabled.
+	Since time.Time `json:"since,omitempty"`
+	// Until is an estimated end of the maintenance window.
+	Until time.Time `json:"until,omitempty"`
+}
+
+// Mode is a maintenance mode switch that is shared between all application instances
+// using cache storage and publish/subscribe for change propagation.
+//
+// Mode implements core.Tasker interface.
+type Mode struct {
+	name  string
+	cache *cache.Cache
+	opts  *options
+	store cache.CacheInstance[State]
+
+	lock    sync.RWMutex
+	state   State
+	changed chan struct{}
+	hooks   []func(State)
+
+	unsubscribe func()
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// New creates new maintenance mode switch.
+func New(c *cache.Cache, opts ...Option) (*Mode, error) {
+	opt := newOptions(opts...)
+
+	store, err := cache.Create[State](c, opt.Name, opt.CacheOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mode{
+		name:    opt.Name,
+		cache:   c,
+		opts:    opt,
+		store:   store,
+		changed: make(chan struct{}),
+	}, nil
+}
+
+// Name returns task name.
+func (m *Mode) Name() string {
+	return "maintenance-mode"
+}
+
+// Start loads current maintenance state and subscribes to its changes.
+func (m *Mode) Start(ctx context.Context) error {
+	m.lock.Lock()
+	if m.stop != nil {
+		m.lock.Unlock()
+		return nil
+	}
+	m.stop = make(chan struct{})
+	stop := m.stop
+	m.lock.Unlock()
+
+	if err := m.Refresh(ctx); err != nil {
+		return err
+	}
+
+	unsubscribe, err := m.cache.Subscribe(ctx, m.name, func(message string) {
+		var s State
+		if err := json.Unmarshal([]byte(message), &s); err != nil {
+			m.opts.Logger.Warn("invalid maintenance state message", zap.Error(err))
+			return
+		}
+		m.update(s)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	m.unsubscribe = unsubscribe
+	m.lock.Unlock()
+
+	if m.opts.RefreshInterval > 0 {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+
+			t := time.NewTicker(m.opts.RefreshInterval)
+			defer t.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-stop:
+					return
+				case <-t.C:
+					if err := m.Refresh(ctx); err != nil {
+						m.opts.Logger.Warn("failed to refresh maintenance state", zap.Error(err))
+					}
+				}
+			}
+		}()
+	}
+
+	return nil
+}
+
+// Stop listening for maintenance state changes.
+func (m *Mode) Stop() {
+	m.lock.Lock()
+	if m.stop == nil {
+		m.lock.Unlock()
+		return
+	}
+	close(m.stop)
+	m.stop = nil
+	unsubscribe := m.unsubscribe
+	m.unsubscribe = nil
+	m.lock.Unlock()
+
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+	m.wg.Wait()
+}
+
+// Refresh reloads maintenance state from the cache.
+func (m *Mode) Refresh(ctx context.Context) error {
+	s, err := m.store.Get(ctx, m.name)
+	if err != nil {
+		return err
+	}
+	m.update(s)
+	return nil
+}
+
+func (m *Mode) update(s State) {
+	m.lock.Lock()
+	prev := m.state
+	m.state = s
+	var hooks []func(State)
+	if prev.Enabled != s.Enabled || prev.Message != s.Message || !prev.Until.Equal(s.Until) {
+		// Wake up all waiters.
+		close(m.changed)
+		m.changed = make(chan struct{})
+		hooks = append(hooks, m.hooks...)
+	}
+	m.lock.Unlock()
+
+	for _, fn := range hooks {
+		fn(s)
+	}
+}
+
+// State returns current maintenance state.
+func (m *Mode) State() State {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.state
+}
+
+// Enabled returns true if maintenance mode is enabled.
+func (m *Mode) Enabled() bool {
+	return m.State().Enabled
+}
+
+// OnChange registers hook that is called when maintenance state changes.
+func (m *Mode) OnChange(fn func(State)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.hooks = append(m.hooks, fn)
+}
+
+func (m *Mode) set(ctx context.Context, s State) error {
+	if err := m.store.Set(ctx, m.name, s); err != nil {
+		return err
+	}
+	m.update(s)
+
+	buf, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return m.cache.Publish(ctx, m.name, string(buf))
+}
+
+// Enable maintenance mode for all application instances.
+//
+// Until is an optional estimated end of the maintenance window.
+func (m *Mode) Enable(ctx context.Context, message string, until time.Time) error {
+	return m.set(ctx, State{
+		Enabled: true,
+		Message: message,
+		Since:   time.Now().UTC(),
+		Until:   until,
+	})
+}
+
+// Disable maintenance mode for all application instances.
+func (m *Mode) Disable(ctx context.Context) error {
+	return m.set(ctx, State{})
+}
+
+// Wait blocks while maintenance mode is enabled.
+//
+// It can be used by schedulers and queue consumers to pause processing gracefully.
+func (m *Mode) Wait(ctx context.Context) error {
+	for {
+		m.lock.RLock()
+		enabled := m.state.Enabled
+		changed := m.changed
+		m.lock.RUnlock()
+
+		if !enabled {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package maintenance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"azugo.io/core/internal/cachetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModePropagation(t *testing.T) {
+	c := cachetest.New(t)
+
+	m1, err := New(c, RefreshInterval(0))
+	require.NoError(t, err)
+	require.NoError(t, m1.Start(context.Background()))
+	defer m1.Stop()
+
+	m2, err := New(c, RefreshInterval(0))
+	require.NoError(t, err)
+	require.NoError(t, m2.Start(context.Background()))
+	defer m2.Stop()
+
+	changes := make(chan State, 1)
+	m2.OnChange(func(s State) {
+		changes <- s
+	})
+
+	require.NoError(t, m1.Enable(context.Background(), "upgrade", time.Time{}))
+	assert.True(t, m1.Enabled())
+
+	select {
+	case s := <-changes:
+		assert.True(t, s.Enabled)
+		assert.Equal(t, "upgrade", s.Message)
+	case <-time.After(time.Second):
+		t.Fatal("maintenance state change not propagated")
+	}
+	assert.True(t, m2.Enabled())
+}
+
+func TestModeWait(t *testing.T) {
+	m, err := New(cachetest.New(t), RefreshInterval(0))
+	require.NoError(t, err)
+	require.NoError(t, m.Start(context.Background()))
+	defer m.Stop()
+
+	require.NoError(t, m.Enable(context.Background(), "", time.Time{}))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Wait(context.Background())
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("wait must block while maintenance mode is enabled")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	require.NoError(t, m.Disable(context.Background()))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("wait must return after maintenance mode is disabled")
+	}
+}
+
+func TestModeMiddleware(t *testing.T) {
+	m, err := New(cachetest.New(t), RefreshInterval(0))
+	require.NoError(t, err)
+
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), "/health")
+
+	require.NoError(t, m.Enable(context.Background(), "back soon", time.Now().Add(time.Minute)))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,66 @@
+package maintenance
+
+import (
+	"time"
+
+	"azugo.io/core/cache"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	Name            string
+	RefreshInterval time.Duration
+	CacheOptions    []cache.CacheOption
+	Logger          *zap.Logger
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Name:            DefaultName,
+		RefreshInterval: time.Minute,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return opt
+}
+
+// Option for the maintenance mode.
+type Option interface {
+	apply(*options)
+}
+
+// Name is a cache key and channel name for the maintenance state.
+type Name string
+
+func (n Name) apply(o *options) {
+	o.Name = string(n)
+}
+
+// RefreshInterval is an interval to reload maintenance state from the cache
+// in case change notification was missed. Zero disables periodic refresh.
+type RefreshInterval time.Duration
+
+func (r RefreshInterval) apply(o *options) {
+	o.RefreshInterval = time.Duration(r)
+}
+
+// CacheOptions are options for the maintenance state cache instance.
+type CacheOptions []cache.CacheOption
+
+func (c CacheOptions) apply(o *options) {
+	o.CacheOptions = append(o.CacheOptions, c...)
+}
+
+// Logger to log state change errors.
+type Logger struct {
+	*zap.Logger
+}
+
+func (l Logger) apply(o *options) {
+	o.Logger = l.Logger
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package materialize
+
+import (
+	"context"
+	"sync"
+
+	"azugo.io/core/cache"
+)
+
+// CheckpointStore stores offsets of the last applied messages by materializer name.
+type CheckpointStore interface {
+	// Load returns offset of the last applied message or zero if checkpoint is not saved.
+	Load(ctx context.Context, name string) (uint64, error)
+	// Save offset of the last applied message.
+	Save(ctx context.Context, name string, offset uint64) error
+}
+
+type memoryCheckpoints struct {
+	lock    sync.Mutex
+	offsets map[string]uint64
+}
+
+// NewMemoryCheckpoints creates new in-memory checkpoint storage.
+func NewMemoryCheckpoints() CheckpointStore {
+	return &memoryCheckpoints{
+		offsets: make(map[string]uint64),
+	}
+}
+
+func (s *memoryCheckpoints) Load(_ context.Context, name string) (uint64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.offsets[name], nil
+}
+
+func (s *memoryCheckpoints) Save(_ context.Context, name string, offset uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.offsets[name] = offset
+	return nil
+}
+
+type cacheCheckpoints struct {
+	instance cache.CacheInstance[uint64]
+}
+
+// CacheCheckpoints stores checkpoints in the cache instance.
+//
+// Checkpoints should be stored in the same cache backend as the projection so
+// that projection and its checkpoint are lost together.
+func CacheCheckpoints(instance cache.CacheInstance[uint64]) CheckpointStore {
+	return &cacheCheckpoints{
+		instance: instance,
+	}
+}
+
+func (s *cacheCheckpoints) Load(ctx context.Context, name string) (uint64, error) {
+	return s.instance.Get(ctx, name)
+}
+
+func (s *cacheCheckpoints) Save(ctx context.Context, name string, offset uint64) error {
+	return s.instance.Set(ctx, name, offset)
+}
@@ -0,0 +1,434 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package materialize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/diagnostics"
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/queue"
+)
+
+const (
+	// DefaultCheckpointEvery is a default number of applied messages after which checkpoint is saved.
+	DefaultCheckpointEvery = 100
+	// DefaultMaxLag is a default number of messages projection can lag behind the topic.
+	DefaultMaxLag = 1000
+	// DefaultReportInterval is a default interval of reporting lag.
+	DefaultReportInterval = 10 * time.Second
+
+	// InstrumentationApply is an instrumentation operation for the message applied to the projection.
+	InstrumentationApply = "materialize-apply"
+	// InstrumentationLag is an instrumentation operation for the projection lag report.
+	InstrumentationLag = "materialize-lag"
+)
+
+var (
+	// ErrSkip can be returned by the decoder to ignore the message.
+	ErrSkip = errors.New("skip message")
+	// ErrReplayNotSupported is returned when replaying topic from queue that does not retain messages.
+	ErrReplayNotSupported = errors.New("queue does not support replay")
+)
+
+// Update of the projection.
+type Update[T any] struct {
+	// Key of the projection item.
+	Key string
+	// Value of the projection item.
+	Value T
+	// Delete item from the projection instead of storing the value.
+	Delete bool
+}
+
+// Decoder converts queue message to the projection update.
+type Decoder[T any] func(ctx context.Context, msg *queue.Message) (*Update[T], error)
+
+// Stats of the materializer.
+type Stats struct {
+	// Offset of the last applied message.
+	Offset uint64
+	// Checkpoint is an offset of the last saved checkpoint.
+	Checkpoint uint64
+	// Head is an offset of the last message published to the topic. Zero if queue
+	// does not support offsets.
+	Head uint64
+	// Lag is a number of published messages not yet applied to the projection.
+	Lag uint64
+	// Applied is a number of messages applied to the projection.
+	Applied uint64
+	// Skipped is a number of ignored and already applied messages.
+	Skipped uint64
+	// Failed is a number of messages that failed to decode or apply.
+	Failed uint64
+	// Running is true while materializer is subscribed to the topic.
+	Running bool
+}
+
+// Materializer consumes queue topic and maintains materialized key/value
+// projection of it in the cache instance.
+//
+// Messages are applied one by one in order they are received. Message offset is
+// checkpointed so that after restart topic is consumed from the last checkpoint
+// if queue supports replay. Messages that fail to decode or apply are counted as
+// failed and skipped.
+type Materializer[T any] struct {
+	opts     *options
+	source   queue.Subscriber
+	topic    string
+	instance cache.CacheInstance[T]
+	decode   Decoder[T]
+
+	// applylock serializes applying messages and saving checkpoints. It must be
+	// acquired before the lock.
+	applylock sync.Mutex
+
+	// lock protects subscription state and stats.
+	lock   sync.Mutex
+	ctx    context.Context
+	stop   context.CancelFunc
+	cancel context.CancelFunc
+	gen    uint64
+	stats  Stats
+	wg     sync.WaitGroup
+}
+
+// New creates new materializer of the topic to the cache instance.
+//
+// If decoder is nil, message key is used as the item key and message body is
+// unmarshaled as the value using the serializer. Message with empty body deletes
+// the item.
+func New[T any](source queue.Subscriber, topic string, instance cache.CacheInstance[T], decoder Decoder[T], opts ...Option) *Materializer[T] {
+	opt := newOptions(topic, opts...)
+	m := &Materializer[T]{
+		opts:     opt,
+		source:   source,
+		topic:    topic,
+		instance: instance,
+		decode:   decoder,
+	}
+	if m.decode == nil {
+		m.decode = m.decodeDefault
+	}
+	return m
+}
+
+func (m *Materializer[T]) decodeDefault(_ context.Context, msg *queue.Message) (*Update[T], error) {
+	if len(msg.Key) == 0 {
+		return nil, errors.New("message key is empty")
+	}
+	u := &Update[T]{Key: msg.Key}
+	if len(msg.Body) == 0 {
+		u.Delete = true
+		return u, nil
+	}
+	if err := m.opts.Serializer.Unmarshal(msg.Body, &u.Value); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Name returns task name.
+func (m *Materializer[T]) Name() string {
+	return m.opts.Name
+}
+
+// Start consuming the topic from the last checkpoint.
+func (m *Materializer[T]) Start(ctx context.Context) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stop != nil {
+		return nil
+	}
+
+	offset, err := m.opts.Checkpoints.Load(ctx, m.opts.Name)
+	if err != nil {
+		return fmt.Errorf("failed to load materializer checkpoint: %w", err)
+	}
+	m.stats.Offset, m.stats.Checkpoint = offset, offset
+
+	m.ctx, m.stop = context.WithCancel(ctx)
+	if err := m.subscribe(offset); err != nil {
+		m.stop()
+		m.stop = nil
+		return err
+	}
+
+	if m.opts.ReportInterval > 0 {
+		ctx := m.ctx
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+
+			tick := time.NewTicker(m.opts.ReportInterval)
+			defer tick.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-tick.C:
+					m.Report(ctx)
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// subscribe to the topic from the offset. Must be called with lock held.
+func (m *Materializer[T]) subscribe(offset uint64) error {
+	ctx, cancel := context.WithCancel(m.ctx)
+
+	m.gen++
+	gen := m.gen
+	handler := func(ctx context.Context, msg *queue.Message) error {
+		return m.apply(ctx, gen, msg)
+	}
+
+	var err error
+	if r, ok := m.source.(queue.Replayer); ok {
+		err = r.SubscribeFrom(ctx, m.topic, offset, handler)
+	} else {
+		err = m.source.Subscribe(ctx, m.topic, handler)
+	}
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to subscribe to topic %s: %w", m.topic, err)
+	}
+	m.cancel = cancel
+	m.stats.Running = true
+	return nil
+}
+
+// unsubscribe from the topic. Must be called with lock held.
+func (m *Materializer[T]) unsubscribe() {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+	m.gen++
+	m.stats.Running = false
+}
+
+// Stop consuming the topic and save checkpoint.
+func (m *Materializer[T]) Stop() {
+	m.lock.Lock()
+	if m.stop == nil {
+		m.lock.Unlock()
+		return
+	}
+	m.unsubscribe()
+	m.stop()
+	m.stop = nil
+	m.lock.Unlock()
+
+	m.wg.Wait()
+
+	// Wait for the message being applied.
+	m.applylock.Lock()
+	defer m.applylock.Unlock()
+
+	_ = m.checkpoint(context.Background())
+}
+
+// Replay clears the projection and consumes the topic from the beginning.
+//
+// Items are deleted only if cache instance supports Clear, otherwise they are
+// overwritten by replayed messages. If materializer is not running, topic is
+// replayed when it is started.
+func (m *Materializer[T]) Replay(ctx context.Context) error {
+	if _, ok := m.source.(queue.Replayer); !ok {
+		return ErrReplayNotSupported
+	}
+
+	m.applylock.Lock()
+	defer m.applylock.Unlock()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	running := m.stop != nil
+	if running {
+		m.unsubscribe()
+	}
+
+	err := m.reset(ctx)
+	if running {
+		// Consuming is resumed from the last applied message if reset has failed.
+		if serr := m.subscribe(m.stats.Offset); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// reset clears the projection and its checkpoint. Must be called with
+// applylock and lock held.
+func (m *Materializer[T]) reset(ctx context.Context) error {
+	if err := cache.Clear(ctx, m.instance); err != nil && !errors.Is(err, cache.ErrNotSupported) {
+		return err
+	}
+	if err := m.opts.Checkpoints.Save(ctx, m.opts.Name, 0); err != nil {
+		return fmt.Errorf("failed to save materializer checkpoint: %w", err)
+	}
+	m.stats.Offset, m.stats.Checkpoint = 0, 0
+	return nil
+}
+
+func (m *Materializer[T]) apply(ctx context.Context, gen uint64, msg *queue.Message) error {
+	m.applylock.Lock()
+	defer m.applylock.Unlock()
+
+	m.lock.Lock()
+	if gen != m.gen {
+		m.lock.Unlock()
+		return nil
+	}
+	// Message could be redelivered after checkpoint was restored.
+	if msg.Offset != 0 && msg.Offset <= m.stats.Offset {
+		m.stats.Skipped++
+		m.lock.Unlock()
+		return nil
+	}
+	m.lock.Unlock()
+
+	finish := m.opts.Instrumenter.Observe(ctx, InstrumentationApply,
+		instrumenter.Label{Name: "materializer", Value: m.opts.Name},
+		instrumenter.Label{Name: "topic", Value: m.topic},
+	)
+
+	err := m.update(ctx, msg)
+	finish(err)
+
+	m.lock.Lock()
+	switch {
+	case errors.Is(err, ErrSkip):
+		m.stats.Skipped++
+		err = nil
+	case err != nil:
+		m.stats.Failed++
+	default:
+		m.stats.Applied++
+	}
+	if msg.Offset != 0 {
+		m.stats.Offset = msg.Offset
+	}
+	pending := m.stats.Offset-m.stats.Checkpoint >= uint64(m.opts.CheckpointEvery)
+	m.lock.Unlock()
+
+	if pending {
+		if cerr := m.checkpoint(ctx); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (m *Materializer[T]) update(ctx context.Context, msg *queue.Message) error {
+	u, err := m.decode(ctx, msg)
+	if err != nil {
+		return err
+	}
+	if u.Delete {
+		return m.instance.Delete(ctx, u.Key)
+	}
+	return m.instance.Set(ctx, u.Key, u.Value)
+}
+
+// checkpoint saves offset of the last applied message. Must be called with
+// applylock held.
+func (m *Materializer[T]) checkpoint(ctx context.Context) error {
+	m.lock.Lock()
+	offset := m.stats.Offset
+	saved := offset == m.stats.Checkpoint
+	m.lock.Unlock()
+
+	if saved {
+		return nil
+	}
+	if err := m.opts.Checkpoints.Save(ctx, m.opts.Name, offset); err != nil {
+		return fmt.Errorf("failed to save materializer checkpoint: %w", err)
+	}
+
+	m.lock.Lock()
+	m.stats.Checkpoint = offset
+	m.lock.Unlock()
+	return nil
+}
+
+// Stats returns materializer stats with the lag behind the topic.
+func (m *Materializer[T]) Stats(ctx context.Context) (Stats, error) {
+	var head uint64
+	if r, ok := m.source.(queue.Replayer); ok {
+		var err error
+		if head, err = r.Offset(ctx, m.topic); err != nil {
+			return Stats{}, err
+		}
+	}
+
+	m.lock.Lock()
+	s := m.stats
+	m.lock.Unlock()
+
+	s.Head = head
+	if head > s.Offset {
+		s.Lag = head - s.Offset
+	}
+	return s, nil
+}
+
+// Report lag to the instrumenter as InstrumentationLag operation with
+// "materializer", "topic" and "lag" labels.
+func (m *Materializer[T]) Report(ctx context.Context) {
+	s, err := m.Stats(ctx)
+	m.opts.Instrumenter.Observe(ctx, InstrumentationLag,
+		instrumenter.Label{Name: "materializer", Value: m.opts.Name},
+		instrumenter.Label{Name: "topic", Value: m.topic},
+		instrumenter.Label{Name: "lag", Value: strconv.FormatUint(s.Lag, 10)},
+	)(err)
+}
+
+// Check reports warning when projection lags behind the topic more than MaxLag
+// messages and critical status when materializer is not running.
+func Check[T any](m *Materializer[T]) diagnostics.Check {
+	return func(ctx context.Context) diagnostics.Result {
+		s, err := m.Stats(ctx)
+		if err != nil {
+			return diagnostics.Result{
+				Status:  diagnostics.Critical,
+				Message: err.Error(),
+			}
+		}
+		res := diagnostics.Result{
+			Status: diagnostics.OK,
+			Details: map[string]any{
+				"offset":     s.Offset,
+				"checkpoint": s.Checkpoint,
+				"head":       s.Head,
+				"lag":        s.Lag,
+				"applied":    s.Applied,
+				"skipped":    s.Skipped,
+				"failed":     s.Failed,
+			},
+		}
+		switch {
+		case !s.Running:
+			res.Status = diagnostics.Critical
+			res.Message = fmt.Sprintf("materializer of topic %s is not running", m.topic)
+		case s.Lag > m.opts.MaxLag:
+			res.Status = diagnostics.Warning
+			res.Message = fmt.Sprintf("projection lags %d messages behind topic %s", s.Lag, m.topic)
+		}
+		return res
+	}
+}
@@ -0,0 +1,221 @@
+package materialize
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/diagnostics"
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/queue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUser struct {
+	Name string `json:"name"`
+}
+
+func newTestInstance(t *testing.T) cache.CacheInstance[testUser] {
+	t.Helper()
+
+	c := cache.New(cache.MemoryCache)
+	require.NoError(t, c.Start(context.TODO()))
+	t.Cleanup(c.Close)
+
+	i, err := cache.Create[testUser](c, "users")
+	require.NoError(t, err)
+	return i
+}
+
+func publish(t *testing.T, q queue.Publisher, key, body string) {
+	t.Helper()
+
+	require.NoError(t, q.Publish(context.TODO(), &queue.Message{Topic: "users", Key: key, Body: []byte(body)}))
+}
+
+func waitOffset[T any](t *testing.T, m *Materializer[T], offset uint64) {
+	t.Helper()
+
+	assert.Eventually(t, func() bool {
+		s, err := m.Stats(context.TODO())
+		return err == nil && s.Offset == offset
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestMaterializer(t *testing.T) {
+	q := queue.NewMemory(10, queue.Retention(100))
+	defer q.Close()
+	i := newTestInstance(t)
+
+	m := New[testUser](q, "users", i, nil)
+	assert.Equal(t, "materializer:users", m.Name())
+	require.NoError(t, m.Start(context.TODO()))
+	defer m.Stop()
+
+	publish(t, q, "1", `{"name":"John"}`)
+	publish(t, q, "2", `{"name":"Jane"}`)
+	publish(t, q, "1", ``)
+	publish(t, q, "3", `invalid`)
+	waitOffset(t, m, 4)
+
+	ok, err := i.Exists(context.TODO(), "1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	v, err := i.Get(context.TODO(), "2")
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", v.Name)
+
+	s, err := m.Stats(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), s.Applied)
+	assert.Equal(t, uint64(1), s.Failed)
+	assert.Equal(t, uint64(4), s.Head)
+	assert.Zero(t, s.Lag)
+	assert.True(t, s.Running)
+}
+
+func TestMaterializerCheckpoint(t *testing.T) {
+	q := queue.NewMemory(10, queue.Retention(100))
+	defer q.Close()
+	i := newTestInstance(t)
+	checkpoints := NewMemoryCheckpoints()
+
+	m := New[testUser](q, "users", i, nil, Checkpoints{checkpoints}, CheckpointEvery(2))
+	require.NoError(t, m.Start(context.TODO()))
+	for _, name := range []string{"John", "Jane", "Jack"} {
+		publish(t, q, name, `{"name":"`+name+`"}`)
+	}
+	waitOffset(t, m, 3)
+
+	s, _ := m.Stats(context.TODO())
+	assert.Equal(t, uint64(2), s.Checkpoint)
+	m.Stop()
+	offset, err := checkpoints.Load(context.TODO(), "materializer:users")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), offset)
+
+	// Messages published while stopped are consumed after restart.
+	publish(t, q, "Jill", `{"name":"Jill"}`)
+	s, _ = m.Stats(context.TODO())
+	assert.Equal(t, uint64(1), s.Lag)
+	assert.False(t, s.Running)
+
+	m = New[testUser](q, "users", i, nil, Checkpoints{checkpoints})
+	require.NoError(t, m.Start(context.TODO()))
+	defer m.Stop()
+	waitOffset(t, m, 4)
+
+	s, _ = m.Stats(context.TODO())
+	assert.Equal(t, uint64(1), s.Applied)
+	v, err := i.Get(context.TODO(), "Jill")
+	require.NoError(t, err)
+	assert.Equal(t, "Jill", v.Name)
+}
+
+func TestMaterializerReplay(t *testing.T) {
+	q := queue.NewMemory(10, queue.Retention(100))
+	defer q.Close()
+	i := newTestInstance(t)
+
+	decoder := func(_ context.Context, msg *queue.Message) (*Update[testUser], error) {
+		if msg.Key == "skip" {
+			return nil, ErrSkip
+		}
+		return &Update[testUser]{Key: "user:" + msg.Key, Value: testUser{Name: string(msg.Body)}}, nil
+	}
+	m := New(q, "users", i, decoder)
+	require.NoError(t, m.Start(context.TODO()))
+	defer m.Stop()
+
+	publish(t, q, "1", "John")
+	publish(t, q, "skip", "")
+	waitOffset(t, m, 2)
+
+	// Items not in the topic are removed by replay.
+	require.NoError(t, i.Set(context.TODO(), "user:2", testUser{Name: "Stale"}))
+	require.NoError(t, m.Replay(context.TODO()))
+	waitOffset(t, m, 2)
+
+	ok, err := i.Exists(context.TODO(), "user:2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	v, err := i.Get(context.TODO(), "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, "John", v.Name)
+
+	s, _ := m.Stats(context.TODO())
+	assert.Equal(t, uint64(2), s.Applied)
+	assert.Equal(t, uint64(2), s.Skipped)
+}
+
+type subscriber struct {
+	queue.Subscriber
+}
+
+func TestMaterializerWithoutReplay(t *testing.T) {
+	q := queue.NewMemory(10)
+	defer q.Close()
+	i := newTestInstance(t)
+
+	m := New[testUser](subscriber{q}, "users", i, nil)
+	require.NoError(t, m.Start(context.TODO()))
+	defer m.Stop()
+
+	publish(t, q, "1", `{"name":"John"}`)
+	assert.Eventually(t, func() bool {
+		ok, err := i.Exists(context.TODO(), "1")
+		return err == nil && ok
+	}, time.Second, 5*time.Millisecond)
+
+	assert.ErrorIs(t, m.Replay(context.TODO()), ErrReplayNotSupported)
+	s, err := m.Stats(context.TODO())
+	require.NoError(t, err)
+	assert.Zero(t, s.Head)
+	assert.Zero(t, s.Lag)
+}
+
+func TestCheckAndReport(t *testing.T) {
+	q := queue.NewMemory(10, queue.Retention(100))
+	defer q.Close()
+	i := newTestInstance(t)
+
+	var lock sync.Mutex
+	labels := make([]instrumenter.Label, 0)
+	instr := func(_ context.Context, op string, args ...any) func(err error) {
+		if op == InstrumentationLag {
+			lock.Lock()
+			defer lock.Unlock()
+
+			for _, a := range args {
+				labels = append(labels, a.(instrumenter.Label))
+			}
+		}
+		return func(error) {}
+	}
+
+	m := New[testUser](q, "users", i, nil, MaxLag(1), ReportInterval(10*time.Millisecond), Instrumenter(instr))
+	res := Check(m)(context.TODO())
+	assert.Equal(t, diagnostics.Critical, res.Status)
+
+	publish(t, q, "1", `{"name":"John"}`)
+	publish(t, q, "2", `{"name":"Jane"}`)
+	res = Check(m)(context.TODO())
+	assert.Equal(t, diagnostics.Critical, res.Status)
+	assert.Equal(t, uint64(2), res.Details["lag"])
+
+	require.NoError(t, m.Start(context.TODO()))
+	defer m.Stop()
+	waitOffset(t, m, 2)
+	res = Check(m)(context.TODO())
+	assert.Equal(t, diagnostics.OK, res.Status)
+
+	m.Report(context.TODO())
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Contains(t, labels, instrumenter.Label{Name: "lag", Value: "0"})
+	assert.Contains(t, labels, instrumenter.Label{Name: "topic", Value: "users"})
+}
@@ -0,0 +1,101 @@
+package materialize
+
+import (
+	"time"
+
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/serializer"
+)
+
+type options struct {
+	Name            string
+	Serializer      serializer.Serializer
+	Checkpoints     CheckpointStore
+	CheckpointEvery int
+	MaxLag          uint64
+	ReportInterval  time.Duration
+	Instrumenter    instrumenter.Instrumenter
+}
+
+func newOptions(topic string, opts ...Option) *options {
+	opt := &options{
+		Name:            "materializer:" + topic,
+		Serializer:      serializer.JSON,
+		CheckpointEvery: DefaultCheckpointEvery,
+		MaxLag:          DefaultMaxLag,
+		ReportInterval:  DefaultReportInterval,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Checkpoints == nil {
+		opt.Checkpoints = NewMemoryCheckpoints()
+	}
+	if opt.CheckpointEvery < 1 {
+		opt.CheckpointEvery = 1
+	}
+	return opt
+}
+
+// Option for the materializer.
+type Option interface {
+	apply(*options)
+}
+
+// Name of the materializer used as a checkpoint name and for instrumentation.
+// Defaults to "materializer:" followed by the topic.
+type Name string
+
+func (n Name) apply(o *options) {
+	o.Name = string(n)
+}
+
+// Serializer used by the default decoder to unmarshal message body. Defaults to JSON.
+type Serializer struct {
+	serializer.Serializer
+}
+
+func (s Serializer) apply(o *options) {
+	o.Serializer = s.Serializer
+}
+
+// Checkpoints is a storage of applied message offsets. Defaults to in-memory storage
+// so that projection is replayed from the beginning after restart.
+type Checkpoints struct {
+	CheckpointStore
+}
+
+func (c Checkpoints) apply(o *options) {
+	o.Checkpoints = c.CheckpointStore
+}
+
+// CheckpointEvery is a number of applied messages after which checkpoint is saved.
+// Checkpoint is always saved when materializer is stopped.
+type CheckpointEvery int
+
+func (c CheckpointEvery) apply(o *options) {
+	o.CheckpointEvery = int(c)
+}
+
+// MaxLag is a number of messages projection can lag behind the topic before
+// diagnostics check reports a warning.
+type MaxLag uint64
+
+func (m MaxLag) apply(o *options) {
+	o.MaxLag = uint64(m)
+}
+
+// ReportInterval is an interval of reporting lag to the instrumenter. Zero
+// disables periodic reporting.
+type ReportInterval time.Duration
+
+func (r ReportInterval) apply(o *options) {
+	o.ReportInterval = time.Duration(r)
+}
+
+// Instrumenter to observe applied messages and lag.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"net/http"
+
+	"azugo.io/core/cert"
+	"azugo.io/core/network"
+)
+
+func (a *App) initNetwork() error {
+	a.netlock.Lock()
+	defer a.netlock.Unlock()
+
+	if a.network != nil {
+		return nil
+	}
+
+	conf := a.Config().Network
+	opts := []network.Option{
+		network.Proxy{
+			HTTP:    conf.HTTPProxy,
+			HTTPS:   conf.HTTPSProxy,
+			NoProxy: conf.NoProxy,
+		},
+	}
+	if conf.Timeout > 0 {
+		opts = append(opts, network.Timeout(conf.Timeout))
+	}
+	if conf.TLSDebug {
+		opts = append(opts, network.HandshakeLog{Logger: a.Log().Named("tls")})
+	}
+	if len(conf.CABundle) != 0 {
+		pool, err := cert.LoadCertPoolFromFile(conf.CABundle)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, network.RootCAs{CertPool: pool})
+	}
+	for _, o := range conf.Overrides {
+		ov := network.Override{
+			Host:               o.Host,
+			Proxy:              o.Proxy,
+			NoProxy:            o.NoProxy,
+			InsecureSkipVerify: o.InsecureSkipVerify,
+		}
+		if len(o.CABundle) != 0 {
+			pool, err := cert.LoadCertPoolFromFile(o.CABundle)
+			if err != nil {
+				return err
+			}
+			ov.RootCAs = pool
+		}
+		opts = append(opts, ov)
+	}
+	a.network = network.New(opts...)
+
+	return nil
+}
+
+// Network returns outbound network configuration.
+func (a *App) Network() *network.Network {
+	if err := a.initNetwork(); err != nil {
+		panic(err)
+	}
+	return a.network
+}
+
+// HTTPClient returns new HTTP client that uses application outbound network configuration.
+//
+// If fault injection is enabled in configuration, client calls are subject to injected faults.
+func (a *App) HTTPClient() *http.Client {
+	c := a.Network().Client()
+	if a.Config().Chaos.Enabled {
+		c = a.Chaos().Client(c)
+	}
+	return c
+}
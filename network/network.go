@@ -0,0 +1,185 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package network
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"azugo.io/core/cert"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Network is an outbound connectivity configuration that provides proxy aware
+// HTTP clients and TLS configurations with custom CA bundles and per-destination
+// overrides.
+type Network struct {
+	opts  *options
+	proxy func(*url.URL) (*url.URL, error)
+
+	lock       sync.Mutex
+	transports map[int]*http.Transport
+}
+
+// New creates new outbound network configuration.
+//
+// If proxy is not configured, proxy settings are loaded from HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY environment variables.
+func New(opts ...Option) *Network {
+	opt := newOptions(opts...)
+
+	pc := httpproxy.FromEnvironment()
+	if opt.Proxy != nil {
+		pc = &httpproxy.Config{
+			HTTPProxy:  opt.Proxy.HTTP,
+			HTTPSProxy: opt.Proxy.HTTPS,
+			NoProxy:    opt.Proxy.NoProxy,
+		}
+	}
+
+	return &Network{
+		opts:       opt,
+		proxy:      pc.ProxyFunc(),
+		transports: make(map[int]*http.Transport),
+	}
+}
+
+// With returns new outbound network configuration with additional options
+// applied on top of the current ones, for example to use client certificate
+// only for connections to a specific service.
+func (n *Network) With(opts ...Option) *Network {
+	opt := *n.opts
+	opt.Overrides = append([]Override(nil), n.opts.Overrides...)
+	for _, o := range opts {
+		o.apply(&opt)
+	}
+
+	proxy := n.proxy
+	if opt.Proxy != n.opts.Proxy {
+		proxy = (&httpproxy.Config{
+			HTTPProxy:  opt.Proxy.HTTP,
+			HTTPSProxy: opt.Proxy.HTTPS,
+			NoProxy:    opt.Proxy.NoProxy,
+		}).ProxyFunc()
+	}
+
+	return &Network{
+		opts:       &opt,
+		proxy:      proxy,
+		transports: make(map[int]*http.Transport),
+	}
+}
+
+// override returns index of the override matching host or -1.
+func (n *Network) override(host string) int {
+	host = strings.ToLower(host)
+	for i, o := range n.opts.Overrides {
+		if o.match(host) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ProxyURL returns proxy URL to use for the destination URL or nil if
+// request should not use proxy.
+func (n *Network) ProxyURL(u *url.URL) (*url.URL, error) {
+	if i := n.override(u.Hostname()); i >= 0 {
+		o := n.opts.Overrides[i]
+		if o.NoProxy {
+			return nil, nil
+		}
+		if len(o.Proxy) != 0 {
+			return url.Parse(o.Proxy)
+		}
+	}
+	return n.proxy(u)
+}
+
+// Proxy returns proxy URL for the HTTP request.
+//
+// It can be used as http.Transport Proxy function.
+func (n *Network) Proxy(req *http.Request) (*url.URL, error) {
+	return n.ProxyURL(req.URL)
+}
+
+// TLSConfig returns TLS configuration for connections to the host.
+func (n *Network) TLSConfig(host string) *tls.Config {
+	conf := &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		RootCAs:              n.opts.RootCAs,
+		GetClientCertificate: n.opts.GetClientCertificate,
+	}
+	if i := n.override(host); i >= 0 {
+		o := n.opts.Overrides[i]
+		if o.RootCAs != nil {
+			conf.RootCAs = o.RootCAs
+		}
+		//nolint:gosec
+		conf.InsecureSkipVerify = o.InsecureSkipVerify
+	}
+	return cert.LogHandshakes(conf, n.opts.TLSLog)
+}
+
+// transport returns HTTP transport for the override index or default transport for -1.
+func (n *Network) transport(i int) *http.Transport {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if t, ok := n.transports[i]; ok {
+		return t
+	}
+
+	host := ""
+	if i >= 0 {
+		host = n.opts.Overrides[i].Host
+	}
+
+	t := &http.Transport{
+		Proxy: n.Proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       n.TLSConfig(host),
+	}
+	n.transports[i] = t
+	return t
+}
+
+// RoundTrip implements http.RoundTripper interface applying proxy and TLS
+// configuration for the request destination.
+func (n *Network) RoundTrip(req *http.Request) (*http.Response, error) {
+	return n.transport(n.override(req.URL.Hostname())).RoundTrip(req)
+}
+
+// Client returns new HTTP client that uses network configuration.
+func (n *Network) Client() *http.Client {
+	return &http.Client{
+		Transport: n,
+		Timeout:   n.opts.Timeout,
+	}
+}
+
+// CloseIdleConnections closes idle connections of all transports.
+func (n *Network) CloseIdleConnections() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	for _, t := range n.transports {
+		t.CloseIdleConnections()
+	}
+}
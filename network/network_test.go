@@ -0,0 +1,97 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyURL(t *testing.T) {
+	n := New(
+		Proxy{HTTP: "http://proxy:3128", HTTPS: "http://proxy:3128", NoProxy: "internal.local"},
+		Override{Host: "*.direct.com", NoProxy: true},
+		Override{Host: "special.com", Proxy: "http://other:8080"},
+	)
+
+	tests := []struct {
+		url   string
+		proxy string
+	}{
+		{"https://example.com", "http://proxy:3128"},
+		{"http://internal.local/test", ""},
+		{"https://api.direct.com", ""},
+		{"https://special.com", "http://other:8080"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		require.NoError(t, err)
+		p, err := n.ProxyURL(u)
+		require.NoError(t, err)
+		if tt.proxy == "" {
+			assert.Nil(t, p, tt.url)
+		} else {
+			require.NotNil(t, p, tt.url)
+			assert.Equal(t, tt.proxy, p.String(), tt.url)
+		}
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	global := x509.NewCertPool()
+	custom := x509.NewCertPool()
+	n := New(
+		Proxy{},
+		RootCAs{global},
+		Override{Host: ".corp.local", RootCAs: custom, InsecureSkipVerify: true},
+	)
+
+	c := n.TLSConfig("example.com")
+	assert.Same(t, global, c.RootCAs)
+	assert.False(t, c.InsecureSkipVerify)
+
+	c = n.TLSConfig("api.corp.local")
+	assert.Same(t, custom, c.RootCAs)
+	assert.True(t, c.InsecureSkipVerify)
+}
+
+func TestWith(t *testing.T) {
+	global := x509.NewCertPool()
+	n := New(Proxy{}, RootCAs{global})
+
+	crt := &tls.Certificate{}
+	w := n.With(ClientCertificateFunc(func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return crt, nil
+	}))
+	assert.Nil(t, n.TLSConfig("example.com").GetClientCertificate)
+
+	c := w.TLSConfig("example.com")
+	assert.Same(t, global, c.RootCAs)
+	require.NotNil(t, c.GetClientCertificate)
+	got, err := c.GetClientCertificate(nil)
+	require.NoError(t, err)
+	assert.Same(t, crt, got)
+}
+
+func TestClientProxy(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	n := New(Proxy{HTTP: proxy.URL})
+
+	resp, err := n.Client().Get("http://example.com/test")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "http://example.com/test", requested)
+}
@@ -0,0 +1,117 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	Proxy                *Proxy
+	RootCAs              *x509.CertPool
+	Overrides            []Override
+	Timeout              time.Duration
+	TLSLog               *zap.Logger
+	GetClientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Timeout: 30 * time.Second,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	return opt
+}
+
+// Option for the network configuration.
+type Option interface {
+	apply(*options)
+}
+
+// Proxy configuration.
+type Proxy struct {
+	// HTTP is a proxy URL for HTTP requests.
+	HTTP string
+	// HTTPS is a proxy URL for HTTPS requests.
+	HTTPS string
+	// NoProxy is a comma-separated list of hosts, domains or CIDR ranges
+	// that should not use proxy.
+	NoProxy string
+}
+
+func (p Proxy) apply(o *options) {
+	o.Proxy = &p
+}
+
+// RootCAs is a certificate pool to verify server certificates.
+//
+// Custom CA bundles can be loaded using cert.LoadCertPoolFromFile.
+type RootCAs struct {
+	*x509.CertPool
+}
+
+func (r RootCAs) apply(o *options) {
+	o.RootCAs = r.CertPool
+}
+
+// Timeout is a default HTTP client request timeout.
+type Timeout time.Duration
+
+func (t Timeout) apply(o *options) {
+	o.Timeout = time.Duration(t)
+}
+
+// ClientCertificateFunc returns client certificate for every new TLS connection.
+//
+// Use cert.ClientCertManager GetClientCertificate to rotate client certificates
+// without closing established connections.
+type ClientCertificateFunc func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+
+func (f ClientCertificateFunc) apply(o *options) {
+	o.GetClientCertificate = f
+}
+
+// HandshakeLog logs outbound TLS handshakes to the logger with debug level.
+//
+// Handshakes are logged only if debug level is enabled in the logger.
+type HandshakeLog struct {
+	*zap.Logger
+}
+
+func (h HandshakeLog) apply(o *options) {
+	o.TLSLog = h.Logger
+}
+
+// Override is a connectivity configuration override for a destination host.
+type Override struct {
+	// Host is a destination host name. Prefix with "*." or "." to match all subdomains.
+	Host string
+	// Proxy is a proxy URL to use for the destination.
+	Proxy string
+	// NoProxy disables proxy for the destination.
+	NoProxy bool
+	// RootCAs is a certificate pool to verify destination certificates.
+	RootCAs *x509.CertPool
+	// InsecureSkipVerify disables destination certificate verification.
+	InsecureSkipVerify bool
+}
+
+func (ov Override) apply(o *options) {
+	o.Overrides = append(o.Overrides, ov)
+}
+
+func (ov Override) match(host string) bool {
+	pattern := strings.ToLower(ov.Host)
+	if strings.HasPrefix(pattern, "*.") {
+		pattern = pattern[1:]
+	}
+	if strings.HasPrefix(pattern, ".") {
+		return strings.HasSuffix(host, pattern)
+	}
+	return host == pattern
+}
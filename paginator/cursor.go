@@ -0,0 +1,140 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package paginator
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"azugo.io/core/keyring"
+
+	"github.com/goccy/go-json"
+)
+
+var (
+	// QueryParameterCursor is URL query parameter to specify page cursor.
+	QueryParameterCursor = "cursor"
+	// MaxPageSize is a maximum number of items per page.
+	MaxPageSize = 100
+)
+
+// ErrInvalidCursor is returned when cursor can not be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ClampPageSize returns page size limited to the range between 1 and max page size.
+//
+// If page size is not positive, default page size is returned.
+func ClampPageSize(size, defaultSize, maxSize int) int {
+	if size <= 0 {
+		size = defaultSize
+	}
+	if maxSize > 0 && size > maxSize {
+		size = maxSize
+	}
+	if size <= 0 {
+		size = 1
+	}
+	return size
+}
+
+// CursorCodec encodes and decodes opaque encrypted cursors.
+type CursorCodec[T any] struct {
+	keys *keyring.KeyRing
+}
+
+// NewCursorCodec creates new cursor codec that encrypts cursors using the key ring.
+func NewCursorCodec[T any](keys *keyring.KeyRing) *CursorCodec[T] {
+	return &CursorCodec[T]{
+		keys: keys,
+	}
+}
+
+// Encode cursor value to opaque string.
+func (c *CursorCodec[T]) Encode(v T) (string, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	buf, err = c.keys.Encrypt(buf)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Decode cursor value from opaque string.
+//
+// Returns ErrInvalidCursor if cursor has been tampered with or is not valid.
+func (c *CursorCodec[T]) Decode(s string) (T, error) {
+	var v T
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return v, ErrInvalidCursor
+	}
+	buf, err = c.keys.Decrypt(buf)
+	if err != nil {
+		return v, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return v, ErrInvalidCursor
+	}
+	return v, nil
+}
+
+// CursorRequest is a cursor based page request.
+type CursorRequest struct {
+	// Cursor is an opaque cursor of the page. Empty for the first page.
+	Cursor string
+	// PageSize is a number of items to return.
+	PageSize int
+}
+
+// ParseCursorRequest parses cursor page request from URL query.
+//
+// Page size is clamped between 1 and MaxPageSize with DefaultPageSize used if not specified.
+func ParseCursorRequest(query url.Values) CursorRequest {
+	size, _ := strconv.Atoi(query.Get(QueryParameterPerPage))
+	return CursorRequest{
+		Cursor:   query.Get(QueryParameterCursor),
+		PageSize: ClampPageSize(size, DefaultPageSize, MaxPageSize),
+	}
+}
+
+// CursorPage is a cursor based page of results.
+type CursorPage[T any] struct {
+	Items    []T    `json:"items"`
+	Next     string `json:"next,omitempty"`
+	Previous string `json:"previous,omitempty"`
+}
+
+// HasNext returns true if there is a next page.
+func (p *CursorPage[T]) HasNext() bool {
+	return len(p.Next) != 0
+}
+
+// Page is an offset based page of results.
+type Page[T any] struct {
+	Items      []T `json:"items"`
+	Total      int `json:"total"`
+	Page       int `json:"page"`
+	PageSize   int `json:"per_page"`
+	TotalPages int `json:"total_pages"`
+}
+
+// NewPage creates new page of results from paginator.
+func NewPage[T any](p *Paginator, items []T) *Page[T] {
+	if items == nil {
+		items = make([]T, 0)
+	}
+	return &Page[T]{
+		Items:      items,
+		Total:      p.Total(),
+		Page:       p.Current(),
+		PageSize:   p.PageSize(),
+		TotalPages: p.TotalPages(),
+	}
+}
@@ -0,0 +1,59 @@
+package paginator_test
+
+import (
+	"net/url"
+	"testing"
+
+	"azugo.io/core/keyring"
+	"azugo.io/core/paginator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCursor struct {
+	LastID int    `json:"id"`
+	Sort   string `json:"sort"`
+}
+
+func TestCursorCodec(t *testing.T) {
+	codec := paginator.NewCursorCodec[testCursor](keyring.New(keyring.Key{ID: "k", Secret: []byte("secret")}))
+
+	s, err := codec.Encode(testCursor{LastID: 42, Sort: "name"})
+	require.NoError(t, err)
+
+	c, err := codec.Decode(s)
+	require.NoError(t, err)
+	assert.Equal(t, testCursor{LastID: 42, Sort: "name"}, c)
+
+	_, err = codec.Decode(s[:len(s)-2] + "AA")
+	assert.ErrorIs(t, err, paginator.ErrInvalidCursor)
+
+	_, err = codec.Decode("not a cursor")
+	assert.ErrorIs(t, err, paginator.ErrInvalidCursor)
+}
+
+func TestClampPageSize(t *testing.T) {
+	assert.Equal(t, 20, paginator.ClampPageSize(0, 20, 100))
+	assert.Equal(t, 100, paginator.ClampPageSize(1000, 20, 100))
+	assert.Equal(t, 5, paginator.ClampPageSize(5, 20, 100))
+	assert.Equal(t, 1, paginator.ClampPageSize(-1, 0, 100))
+}
+
+func TestParseCursorRequest(t *testing.T) {
+	q, err := url.ParseQuery("cursor=abc&per_page=500")
+	require.NoError(t, err)
+
+	r := paginator.ParseCursorRequest(q)
+	assert.Equal(t, "abc", r.Cursor)
+	assert.Equal(t, paginator.MaxPageSize, r.PageSize)
+}
+
+func TestNewPage(t *testing.T) {
+	p := paginator.NewPage(paginator.New(105, 20, 2), []int{1, 2})
+
+	assert.Equal(t, 105, p.Total)
+	assert.Equal(t, 2, p.Page)
+	assert.Equal(t, 6, p.TotalPages)
+	assert.Len(t, p.Items, 2)
+}
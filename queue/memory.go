@@ -0,0 +1,215 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import (
+	"context"
+	"sync"
+)
+
+type memorySubscription struct {
+	ch chan *Message
+}
+
+// MemoryOption for the in-memory queue.
+type MemoryOption interface {
+	applyMemory(*MemoryQueue)
+}
+
+// Retention is a number of last messages retained per topic so that they can be
+// replayed by subscribers. Zero disables retention.
+type Retention int
+
+func (r Retention) applyMemory(q *MemoryQueue) {
+	q.retention = int(r)
+}
+
+// MemoryQueue is an in-process queue implementation.
+//
+// Messages are delivered to all active subscribers of the topic and are not persisted.
+// Messages are assigned offsets in the topic and last messages are retained in memory
+// if Retention option is set.
+type MemoryQueue struct {
+	lock   sync.RWMutex
+	subs   map[string][]*memorySubscription
+	size   int
+	closed bool
+
+	// loglock serializes publishing so that messages are delivered in order of offsets.
+	loglock   sync.Mutex
+	retention int
+	offsets   map[string]uint64
+	logs      map[string][]*Message
+}
+
+// NewMemory creates new in-memory queue with specified buffer size per subscription.
+func NewMemory(size int, opts ...MemoryOption) *MemoryQueue {
+	if size <= 0 {
+		size = 100
+	}
+	q := &MemoryQueue{
+		subs:    make(map[string][]*memorySubscription),
+		size:    size,
+		offsets: make(map[string]uint64),
+		logs:    make(map[string][]*Message),
+	}
+	for _, o := range opts {
+		o.applyMemory(q)
+	}
+	return q
+}
+
+// Publish message to all topic subscribers.
+func (q *MemoryQueue) Publish(ctx context.Context, msg *Message) error {
+	q.loglock.Lock()
+	defer q.loglock.Unlock()
+
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	m := *msg
+	m.Offset = q.offsets[msg.Topic] + 1
+	q.offsets[msg.Topic] = m.Offset
+	if q.retention > 0 {
+		log := append(q.logs[msg.Topic], &m)
+		if len(log) > q.retention {
+			log = append(log[:0:0], log[len(log)-q.retention:]...)
+		}
+		q.logs[msg.Topic] = log
+	}
+
+	for _, s := range q.subs[msg.Topic] {
+		select {
+		case s.ch <- &m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Offset returns offset of the last message published to the topic.
+func (q *MemoryQueue) Offset(_ context.Context, topic string) (uint64, error) {
+	q.loglock.Lock()
+	defer q.loglock.Unlock()
+
+	return q.offsets[topic], nil
+}
+
+// Subscribe to the topic.
+//
+// Messages are handled in the background until context is canceled or queue is closed.
+func (q *MemoryQueue) Subscribe(ctx context.Context, topic string, handler Handler) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.subscribe(ctx, topic, nil, handler)
+}
+
+// SubscribeFrom subscribes to the topic delivering retained messages with offset
+// greater than the offset first.
+//
+// Messages are handled in the background until context is canceled or queue is closed.
+func (q *MemoryQueue) SubscribeFrom(ctx context.Context, topic string, offset uint64, handler Handler) error {
+	q.loglock.Lock()
+	defer q.loglock.Unlock()
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	backlog := make([]*Message, 0)
+	for _, m := range q.logs[topic] {
+		if m.Offset > offset {
+			backlog = append(backlog, m)
+		}
+	}
+	return q.subscribe(ctx, topic, backlog, handler)
+}
+
+func (q *MemoryQueue) subscribe(ctx context.Context, topic string, backlog []*Message, handler Handler) error {
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	s := &memorySubscription{
+		ch: make(chan *Message, q.size),
+	}
+	q.subs[topic] = append(q.subs[topic], s)
+
+	go func() {
+		defer q.unsubscribe(topic, s)
+
+		for _, msg := range backlog {
+			if ctx.Err() != nil {
+				return
+			}
+			_ = handler(ctx, msg)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-s.ch:
+				if !ok {
+					return
+				}
+				_ = handler(ctx, msg)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (q *MemoryQueue) unsubscribe(topic string, s *memorySubscription) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	subs := q.subs[topic]
+	for i, ss := range subs {
+		if ss == s {
+			q.subs[topic] = append(subs[:i:i], subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Depths returns number of messages waiting to be handled by topic subscribers.
+func (q *MemoryQueue) Depths() map[string]int {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	depths := make(map[string]int, len(q.subs))
+	for topic, subs := range q.subs {
+		n := 0
+		for _, s := range subs {
+			n += len(s.ch)
+		}
+		depths[topic] = n
+	}
+	return depths
+}
+
+// Close queue and all its subscriptions.
+func (q *MemoryQueue) Close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	for _, subs := range q.subs {
+		for _, s := range subs {
+			close(s.ch)
+		}
+	}
+	q.subs = nil
+}
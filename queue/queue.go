@@ -0,0 +1,60 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrQueueClosed is returned when publishing to or subscribing on closed queue.
+var ErrQueueClosed = errors.New("queue closed")
+
+// Message represents a queue message.
+type Message struct {
+	// Topic to which message is published.
+	Topic string
+	// Key is an optional message key.
+	Key string
+	// Headers contains optional message headers.
+	Headers map[string]string
+	// Body of the message.
+	Body []byte
+	// Offset is a position of the message in the topic set by queues that
+	// retain published messages. Zero if queue does not support offsets.
+	Offset uint64
+}
+
+// Handler is a function that handles received message.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Publisher publishes messages to the queue.
+type Publisher interface {
+	// Publish message to the queue.
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// Subscriber subscribes to the queue topic.
+type Subscriber interface {
+	// Subscribe to the topic. Handler is called for every received message until context is canceled.
+	Subscribe(ctx context.Context, topic string, handler Handler) error
+}
+
+// Queue is a publisher and subscriber.
+type Queue interface {
+	Publisher
+	Subscriber
+}
+
+// Replayer is implemented by queues that retain published messages so that
+// subscribers can replay the topic from the offset.
+type Replayer interface {
+	// SubscribeFrom subscribes to the topic delivering retained messages with offset
+	// greater than the offset before new messages. Messages that are no longer
+	// retained are skipped.
+	SubscribeFrom(ctx context.Context, topic string, offset uint64, handler Handler) error
+	// Offset returns offset of the last message published to the topic.
+	Offset(ctx context.Context, topic string) (uint64, error)
+}
@@ -0,0 +1,49 @@
+package scrub
+
+type options struct {
+	Rules    []Rule
+	HashKey  []byte
+	MaskKeep int
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	return opt
+}
+
+// Option for the scrubber.
+type Option interface {
+	apply(*options)
+}
+
+// Rules are field scrubbing rules. First matching rule is applied.
+type Rules []Rule
+
+func (r Rules) apply(o *options) {
+	o.Rules = append(o.Rules, r...)
+}
+
+func (r Rule) apply(o *options) {
+	o.Rules = append(o.Rules, r)
+}
+
+// HashKey is a secret key used to hash values with HMAC-SHA256.
+//
+// Without key values are hashed using SHA-256 that can be reversed for
+// values with small number of possible values.
+type HashKey []byte
+
+func (k HashKey) apply(o *options) {
+	o.HashKey = append([]byte{}, k...)
+}
+
+// MaskKeep is a number of last characters of the string value to keep
+// visible when masking. Values shorter than twice the number are fully masked.
+type MaskKeep int
+
+func (m MaskKeep) apply(o *options) {
+	o.MaskKeep = int(m)
+}
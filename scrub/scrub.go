@@ -0,0 +1,252 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scrub
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"github.com/goccy/go-json"
+)
+
+// Masked is a replacement of the masked value.
+const Masked = "***"
+
+// Action is a scrubbing action applied to the matching field.
+type Action string
+
+const (
+	// Mask replaces field value with asterisks.
+	Mask Action = "mask"
+	// Hash replaces field value with hex encoded hash so that values can be
+	// correlated without revealing them.
+	Hash Action = "hash"
+	// Drop removes the field.
+	Drop Action = "drop"
+)
+
+// Rule is a field scrubbing rule.
+type Rule struct {
+	// Field is a case-insensitive field name or dot separated field path pattern.
+	//
+	// Pattern without dots matches field with the name at any depth. Wildcard
+	// "*" matches any sequence of characters, for example "*token*".
+	Field string
+	// Action to apply to the matching field.
+	Action Action
+}
+
+func (r Rule) match(p, name string) bool {
+	pattern := strings.ToLower(r.Field)
+	if ok, _ := path.Match(pattern, p); ok {
+		return true
+	}
+	if strings.Contains(pattern, ".") {
+		return false
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// DefaultRules mask commonly used credential fields.
+var DefaultRules = Rules{
+	{Field: "*password*", Action: Mask},
+	{Field: "*secret*", Action: Mask},
+	{Field: "*token*", Action: Mask},
+	{Field: "authorization", Action: Mask},
+	{Field: "cookie", Action: Mask},
+}
+
+// ParseRules parses rules in the format field=action,... for example
+// "password=drop,email=mask,user.id=hash".
+func ParseRules(s string) (Rules, error) {
+	rules := make(Rules, 0, 4)
+	for _, r := range strings.Split(s, ",") {
+		r = strings.TrimSpace(r)
+		if len(r) == 0 {
+			continue
+		}
+		field, action, ok := strings.Cut(r, "=")
+		if !ok || len(strings.TrimSpace(field)) == 0 {
+			return nil, fmt.Errorf("invalid scrub rule %q", r)
+		}
+		a := Action(strings.ToLower(strings.TrimSpace(action)))
+		switch a {
+		case Mask, Hash, Drop:
+		default:
+			return nil, fmt.Errorf("invalid scrub action %q", action)
+		}
+		if _, err := path.Match(field, ""); err != nil {
+			return nil, fmt.Errorf("invalid scrub field pattern %q: %w", field, err)
+		}
+		rules = append(rules, Rule{Field: strings.TrimSpace(field), Action: a})
+	}
+	return rules, nil
+}
+
+// Scrubber masks, hashes or drops sensitive fields of log entries and
+// data published to telemetry.
+type Scrubber struct {
+	opts  *options
+	rules atomic.Pointer[Rules]
+}
+
+// New creates new scrubber.
+func New(opts ...Option) *Scrubber {
+	opt := newOptions(opts...)
+	s := &Scrubber{
+		opts: opt,
+	}
+	s.SetRules(opt.Rules...)
+	return s
+}
+
+// SetRules replaces scrubbing rules.
+func (s *Scrubber) SetRules(rules ...Rule) {
+	r := append(Rules{}, rules...)
+	s.rules.Store(&r)
+}
+
+// Rules returns current scrubbing rules.
+func (s *Scrubber) Rules() Rules {
+	return append(Rules{}, *s.rules.Load()...)
+}
+
+// Enabled returns true if scrubber has any rules.
+func (s *Scrubber) Enabled() bool {
+	return s != nil && len(*s.rules.Load()) != 0
+}
+
+// Match returns action of the first rule matching the field path.
+func (s *Scrubber) Match(p string) (Action, bool) {
+	if s == nil {
+		return "", false
+	}
+	p = strings.ToLower(p)
+	name := p
+	if i := strings.LastIndexByte(p, '.'); i >= 0 {
+		name = p[i+1:]
+	}
+	for _, r := range *s.rules.Load() {
+		if r.match(p, name) {
+			return r.Action, true
+		}
+	}
+	return "", false
+}
+
+func (s *Scrubber) mask(v string) string {
+	keep := s.opts.MaskKeep
+	if keep <= 0 || len(v) < 2*keep {
+		return Masked
+	}
+	return Masked + v[len(v)-keep:]
+}
+
+func (s *Scrubber) hash(v string) string {
+	if len(s.opts.HashKey) == 0 {
+		h := sha256.Sum256([]byte(v))
+		return hex.EncodeToString(h[:16])
+	}
+	h := hmac.New(sha256.New, s.opts.HashKey)
+	_, _ = h.Write([]byte(v))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func (s *Scrubber) apply(action Action, v string) (string, bool) {
+	switch action {
+	case Drop:
+		return "", false
+	case Hash:
+		return s.hash(v), true
+	default:
+		return s.mask(v), true
+	}
+}
+
+// String returns scrubbed value of the field. Returns false if field must be dropped.
+func (s *Scrubber) String(p, v string) (string, bool) {
+	if a, ok := s.Match(p); ok {
+		return s.apply(a, v)
+	}
+	return v, true
+}
+
+func stringify(v any) string {
+	switch vv := v.(type) {
+	case string:
+		return vv
+	case nil:
+		return ""
+	}
+	if b, err := json.Marshal(v); err == nil {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func join(prefix, name string) string {
+	if len(prefix) == 0 {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// Any returns scrubbed copy of the decoded JSON value at the field path.
+// Returns false if field must be dropped.
+//
+// Scrubbed values of matching fields are always strings.
+func (s *Scrubber) Any(p string, v any) (any, bool) {
+	if len(p) != 0 {
+		if a, ok := s.Match(p); ok {
+			return s.apply(a, stringify(v))
+		}
+	}
+	switch vv := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(vv))
+		for k, val := range vv {
+			if sv, ok := s.Any(join(p, k), val); ok {
+				m[k] = sv
+			}
+		}
+		return m, true
+	case []any:
+		// Array elements share path of the array field.
+		a := make([]any, 0, len(vv))
+		for _, val := range vv {
+			a = append(a, s.children(p, val))
+		}
+		return a, true
+	}
+	return v, true
+}
+
+func (s *Scrubber) children(p string, v any) any {
+	switch v.(type) {
+	case map[string]any, []any:
+		sv, _ := s.Any(p, v)
+		return sv
+	}
+	return v
+}
+
+// JSON returns JSON document with fields scrubbed.
+func (s *Scrubber) JSON(data []byte) ([]byte, error) {
+	if !s.Enabled() || len(data) == 0 {
+		return data, nil
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	v, _ = s.Any("", v)
+	return json.Marshal(v)
+}
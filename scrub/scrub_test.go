@@ -0,0 +1,123 @@
+package scrub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("password=drop, Email=MASK,user.id=hash,")
+	require.NoError(t, err)
+	assert.Equal(t, Rules{
+		{Field: "password", Action: Drop},
+		{Field: "Email", Action: Mask},
+		{Field: "user.id", Action: Hash},
+	}, rules)
+
+	_, err = ParseRules("password")
+	assert.Error(t, err)
+	_, err = ParseRules("password=encrypt")
+	assert.Error(t, err)
+	_, err = ParseRules("[=mask")
+	assert.Error(t, err)
+}
+
+func TestScrubberMatch(t *testing.T) {
+	s := New(Rules{
+		{Field: "user.email", Action: Hash},
+		{Field: "email", Action: Mask},
+		{Field: "*token*", Action: Drop},
+	})
+
+	for p, want := range map[string]Action{
+		"email":              Mask,
+		"customer.Email":     Mask,
+		"user.email":         Hash,
+		"access_token":       Drop,
+		"auth.refreshToken":  Drop,
+		"session.user.email": Mask,
+	} {
+		a, ok := s.Match(p)
+		assert.True(t, ok, p)
+		assert.Equal(t, want, a, p)
+	}
+	_, ok := s.Match("user.name")
+	assert.False(t, ok)
+
+	var nilScrubber *Scrubber
+	_, ok = nilScrubber.Match("email")
+	assert.False(t, ok)
+	assert.False(t, nilScrubber.Enabled())
+}
+
+func TestScrubberJSON(t *testing.T) {
+	s := New(Rules{
+		{Field: "password", Action: Drop},
+		{Field: "email", Action: Mask},
+		{Field: "card", Action: Mask},
+		{Field: "user.id", Action: Hash},
+	}, MaskKeep(4), HashKey("key"))
+
+	data, err := s.JSON([]byte(`{"user":{"id":42,"email":"a@b.c","password":"x"},"items":[{"card":"4111111111111111"}],"count":3}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"user":{"id":"`+s.hash("42")+`","email":"***"},"items":[{"card":"***1111"}],"count":3}`, string(data))
+	assert.Len(t, s.hash("42"), 32)
+	assert.NotEqual(t, New().hash("42"), s.hash("42"))
+
+	v, ok := s.String("password", "secret")
+	assert.False(t, ok)
+	assert.Empty(t, v)
+
+	_, err = s.JSON([]byte(`{`))
+	assert.Error(t, err)
+
+	s.SetRules()
+	data, err = s.JSON([]byte(`{"password":"x"}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"password":"x"}`, string(data))
+}
+
+type testUser struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (u testUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.Name)
+	enc.AddString("email", u.Email)
+	return nil
+}
+
+func TestScrubberCore(t *testing.T) {
+	s := New(Rules{
+		{Field: "password", Action: Drop},
+		{Field: "email", Action: Mask},
+		{Field: "ip", Action: Hash},
+	})
+
+	core, logs := observer.New(zap.InfoLevel)
+	log := zap.New(s.Core(core)).With(zap.String("ip", "10.0.0.1"))
+
+	log.Debug("skipped", zap.String("email", "a@b.c"))
+	log.Info("login",
+		zap.String("email", "a@b.c"),
+		zap.String("password", "secret"),
+		zap.Int("attempt", 1),
+		zap.Object("user", testUser{Name: "John", Email: "john@example.com"}),
+		zap.Any("profile", testUser{Name: "Jane", Email: "jane@example.com"}),
+	)
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, s.hash("10.0.0.1"), fields["ip"])
+	assert.Equal(t, Masked, fields["email"])
+	assert.NotContains(t, fields, "password")
+	assert.Equal(t, int64(1), fields["attempt"])
+	assert.Equal(t, map[string]any{"name": "John", "email": Masked}, fields["user"])
+	assert.Equal(t, map[string]any{"name": "Jane", "email": Masked}, fields["profile"])
+}
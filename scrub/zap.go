@@ -0,0 +1,86 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scrub
+
+import (
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldValue returns field value as encoded by zap.
+func fieldValue(f zapcore.Field) any {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return enc.Fields[f.Key]
+}
+
+// Fields returns log fields with scrubbing rules applied.
+//
+// Nested object and reflected fields are scrubbed by their path.
+func (s *Scrubber) Fields(fields []zapcore.Field) []zapcore.Field {
+	if !s.Enabled() {
+		return fields
+	}
+
+	res := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		if f.Type == zapcore.SkipType || f.Type == zapcore.ErrorType || f.Type == zapcore.NamespaceType {
+			res = append(res, f)
+			continue
+		}
+		if a, ok := s.Match(f.Key); ok {
+			if v, ok := s.apply(a, stringify(fieldValue(f))); ok {
+				res = append(res, zap.String(f.Key, v))
+			}
+			continue
+		}
+		switch f.Type {
+		case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.ReflectType:
+			v := fieldValue(f)
+			if f.Type == zapcore.ReflectType {
+				// Reflected values are normalized to decoded JSON.
+				b, err := json.Marshal(v)
+				if err != nil || json.Unmarshal(b, &v) != nil {
+					res = append(res, f)
+					continue
+				}
+			}
+			v, _ = s.Any(f.Key, v)
+			res = append(res, zap.Reflect(f.Key, v))
+		default:
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// Core wraps logger core to scrub fields of all log entries.
+//
+// Core should be wrapped before other cores that filter entries so that
+// scrubbing is applied only to written entries.
+func (s *Scrubber) Core(core zapcore.Core) zapcore.Core {
+	return &scrubCore{Core: core, s: s}
+}
+
+type scrubCore struct {
+	zapcore.Core
+	s *Scrubber
+}
+
+func (c *scrubCore) With(fields []zapcore.Field) zapcore.Core {
+	return &scrubCore{Core: c.Core.With(c.s.Fields(fields)), s: c.s}
+}
+
+func (c *scrubCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *scrubCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.s.Fields(fields))
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"azugo.io/core/internal/awsauth"
+
+	"github.com/goccy/go-json"
+)
+
+// AWSSecretsManager is an AWS Secrets Manager secrets provider.
+//
+// Secret name is a name or ARN of the secret. Secrets have no lease so they are
+// fetched again after DefaultTTL to detect rotation.
+type AWSSecretsManager struct {
+	// Region of the secrets manager.
+	Region string
+	// AccessKeyID of the AWS credentials. If not set, credentials are loaded
+	// from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+	// environment variables.
+	AccessKeyID string
+	// SecretAccessKey of the AWS credentials.
+	SecretAccessKey string
+	// SessionToken of the temporary AWS credentials.
+	SessionToken string
+	// VersionStage of the secret. Defaults to AWSCURRENT.
+	VersionStage string
+	// Endpoint overrides secrets manager endpoint.
+	Endpoint string
+	// Client is HTTP client to use. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+type awsGetSecretValueResponse struct {
+	Name         string `json:"Name"`
+	VersionID    string `json:"VersionId"`
+	SecretString string `json:"SecretString"`
+	SecretBinary []byte `json:"SecretBinary"`
+}
+
+type awsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// Fetch returns secret by its name or ARN.
+func (a *AWSSecretsManager) Fetch(ctx context.Context, name string) (*Secret, error) {
+	stage := a.VersionStage
+	if len(stage) == 0 {
+		stage = "AWSCURRENT"
+	}
+	body, err := json.Marshal(map[string]string{
+		"SecretId":     name,
+		"VersionStage": stage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := a.Endpoint
+	if len(endpoint) == 0 {
+		endpoint = "https://secretsmanager." + a.Region + ".amazonaws.com/"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	creds := awsauth.Credentials{
+		AccessKeyID:     a.AccessKeyID,
+		SecretAccessKey: a.SecretAccessKey,
+		SessionToken:    a.SessionToken,
+	}
+	awsauth.Sign(req, body, creds.Resolve(), a.Region, "secretsmanager", time.Now())
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		e := &awsError{}
+		_ = json.NewDecoder(resp.Body).Decode(e)
+		if strings.HasSuffix(e.Type, "ResourceNotFoundException") {
+			return nil, ErrSecretNotFound
+		}
+		return nil, fmt.Errorf("secrets manager request failed with status %d: %s %s", resp.StatusCode, e.Type, e.Message)
+	}
+
+	res := &awsGetSecretValueResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, fmt.Errorf("invalid secrets manager response: %w", err)
+	}
+	s := &Secret{
+		Name:      name,
+		Version:   res.VersionID,
+		Value:     res.SecretBinary,
+		FetchedAt: time.Now(),
+	}
+	if len(res.SecretString) > 0 {
+		s.Value = []byte(res.SecretString)
+	}
+	return s, nil
+}
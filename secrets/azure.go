@@ -0,0 +1,103 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// AzureKeyVault is an Azure Key Vault secrets provider.
+//
+// Secret name is a name of the Key Vault secret. Expiration date of the secret
+// is used as its TTL.
+type AzureKeyVault struct {
+	// VaultURL is Key Vault URL, for example https://myvault.vault.azure.net.
+	VaultURL string
+	// Token returns access token for the Key Vault resource.
+	Token func(ctx context.Context) (string, error)
+	// APIVersion of the Key Vault REST API. Defaults to 7.4.
+	APIVersion string
+	// Client is HTTP client to use. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+type azureSecretBundle struct {
+	Value      string `json:"value"`
+	ID         string `json:"id"`
+	Attributes struct {
+		Enabled bool  `json:"enabled"`
+		Expires int64 `json:"exp"`
+	} `json:"attributes"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Fetch returns latest version of the secret.
+func (a *AzureKeyVault) Fetch(ctx context.Context, name string) (*Secret, error) {
+	ver := a.APIVersion
+	if len(ver) == 0 {
+		ver = "7.4"
+	}
+	u := strings.TrimSuffix(a.VaultURL, "/") + "/secrets/" + url.PathEscape(name) + "?api-version=" + url.QueryEscape(ver)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if a.Token != nil {
+		token, err := a.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrSecretNotFound
+	}
+	res := &azureSecretBundle{}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil && resp.StatusCode < 300 {
+		return nil, fmt.Errorf("invalid key vault response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		if res.Error != nil {
+			return nil, fmt.Errorf("key vault request failed with status %d: %s", resp.StatusCode, res.Error.Message)
+		}
+		return nil, fmt.Errorf("key vault request failed with status %d", resp.StatusCode)
+	}
+
+	s := &Secret{
+		Name:      name,
+		Value:     []byte(res.Value),
+		Version:   path.Base(res.ID),
+		FetchedAt: time.Now(),
+	}
+	if res.Attributes.Expires > 0 {
+		s.TTL = time.Until(time.Unix(res.Attributes.Expires, 0))
+		if s.TTL <= 0 {
+			return nil, fmt.Errorf("key vault secret %q has expired", name)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,117 @@
+package secrets
+
+import (
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/keyring"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	Name          string
+	Cache         *cache.Cache
+	CacheOptions  []cache.CacheOption
+	KeyRing       *keyring.KeyRing
+	RefreshBefore float64
+	DefaultTTL    time.Duration
+	CheckInterval time.Duration
+	Instrumenter  instrumenter.Instrumenter
+	Logger        *zap.Logger
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Name:          DefaultName,
+		RefreshBefore: 0.2,
+		DefaultTTL:    15 * time.Minute,
+		CheckInterval: 30 * time.Second,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return opt
+}
+
+// Option for the secrets manager.
+type Option interface {
+	apply(*options)
+}
+
+// Name is a cache instance name for the secrets.
+type Name string
+
+func (n Name) apply(o *options) {
+	o.Name = string(n)
+}
+
+// Cache to share fetched secrets between application instances.
+//
+// Secrets are always stored encrypted so KeyRing option is required.
+type Cache struct {
+	*cache.Cache
+}
+
+func (c Cache) apply(o *options) {
+	o.Cache = c.Cache
+}
+
+// CacheOptions are options for the secrets cache instance.
+type CacheOptions []cache.CacheOption
+
+func (c CacheOptions) apply(o *options) {
+	o.CacheOptions = append(o.CacheOptions, c...)
+}
+
+// KeyRing used to encrypt secrets stored in the cache.
+type KeyRing struct {
+	*keyring.KeyRing
+}
+
+func (k KeyRing) apply(o *options) {
+	o.KeyRing = k.KeyRing
+}
+
+// RefreshBefore is a fraction of the secret TTL before its expiry when the
+// secret is refreshed. Defaults to 0.2.
+type RefreshBefore float64
+
+func (r RefreshBefore) apply(o *options) {
+	o.RefreshBefore = float64(r)
+}
+
+// DefaultTTL is a time after which secrets without lease are fetched again
+// to detect rotation. Zero disables refresh of such secrets. Defaults to 15 minutes.
+type DefaultTTL time.Duration
+
+func (d DefaultTTL) apply(o *options) {
+	o.DefaultTTL = time.Duration(d)
+}
+
+// CheckInterval is an interval to check secrets for refresh. Defaults to 30 seconds.
+type CheckInterval time.Duration
+
+func (c CheckInterval) apply(o *options) {
+	o.CheckInterval = time.Duration(c)
+}
+
+// Instrumenter to observe secret fetch and renewal.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
+
+// Logger to log secret refresh errors.
+type Logger struct {
+	*zap.Logger
+}
+
+func (l Logger) apply(o *options) {
+	o.Logger = l.Logger
+}
@@ -0,0 +1,143 @@
+package secrets
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultKV2(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("X-Vault-Token"))
+		if r.URL.Path != "/v1/secret/data/app" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"password":"s3cret","port":6379},"metadata":{"version":3}}}`))
+	}))
+	defer srv.Close()
+
+	v := &Vault{Address: srv.URL, Token: "token", Field: "password"}
+	s, err := v.Fetch(context.TODO(), "secret/data/app")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", string(s.Value))
+	assert.Equal(t, "3", s.Version)
+	assert.Zero(t, s.TTL)
+
+	v.Field = ""
+	s, err = v.Fetch(context.TODO(), "secret/data/app")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"password":"s3cret","port":6379}`, string(s.Value))
+
+	_, err = v.Fetch(context.TODO(), "secret/data/other")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestVaultDynamicLease(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/database/creds/app":
+			_, _ = w.Write([]byte(`{"lease_id":"database/creds/app/abc","lease_duration":3600,"renewable":true,"data":{"username":"u","password":"p"}}`))
+		case "/v1/sys/leases/renew":
+			assert.Equal(t, http.MethodPut, r.Method)
+			body := map[string]any{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "database/creds/app/abc", body["lease_id"])
+			_, _ = w.Write([]byte(`{"lease_id":"database/creds/app/abc","lease_duration":1800,"renewable":true}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+		}
+	}))
+	defer srv.Close()
+
+	v := &Vault{Address: srv.URL, Token: "token", Field: "password"}
+	s, err := v.Fetch(context.TODO(), "database/creds/app")
+	require.NoError(t, err)
+	assert.Equal(t, "p", string(s.Value))
+	assert.True(t, s.Renewable)
+	assert.Equal(t, time.Hour, s.TTL)
+
+	r, err := v.Renew(context.TODO(), s)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, r.TTL)
+	assert.Equal(t, "p", string(r.Value))
+
+	_, err = v.Fetch(context.TODO(), "other")
+	assert.ErrorContains(t, err, "permission denied")
+}
+
+func TestAzureKeyVault(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "7.4", r.URL.Query().Get("api-version"))
+		if r.URL.Path != "/secrets/redis-password" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"value":      "s3cret",
+			"id":         "https://vault/secrets/redis-password/v2",
+			"attributes": map[string]any{"enabled": true, "exp": exp},
+		})
+	}))
+	defer srv.Close()
+
+	a := &AzureKeyVault{VaultURL: srv.URL, Token: func(ctx context.Context) (string, error) {
+		return "token", nil
+	}}
+	s, err := a.Fetch(context.TODO(), "redis-password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", string(s.Value))
+	assert.Equal(t, "v2", s.Version)
+	assert.InDelta(t, time.Hour.Seconds(), s.TTL.Seconds(), 5)
+
+	_, err = a.Fetch(context.TODO(), "other")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestAWSSecretsManager(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		assert.Equal(t, "session", r.Header.Get("X-Amz-Security-Token"))
+		auth := r.Header.Get("Authorization")
+		assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/"))
+		assert.Contains(t, auth, "/eu-west-1/secretsmanager/aws4_request")
+		assert.Contains(t, auth, "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target")
+
+		body, _ := io.ReadAll(r.Body)
+		req := map[string]string{}
+		require.NoError(t, json.Unmarshal(body, &req))
+		if req["SecretId"] != "app/redis" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"Name":"app/redis","VersionId":"v1","SecretString":"s3cret"}`))
+	}))
+	defer srv.Close()
+
+	a := &AWSSecretsManager{
+		Region:          "eu-west-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+		Endpoint:        srv.URL,
+	}
+	s, err := a.Fetch(context.TODO(), "app/redis")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", string(s.Value))
+	assert.Equal(t, "v1", s.Version)
+
+	_, err = a.Fetch(context.TODO(), "other")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
@@ -0,0 +1,466 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"azugo.io/core/cache"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultName is a default cache instance name for the secrets.
+	DefaultName = "secrets"
+
+	InstrumentationSecretFetch = "secret-fetch"
+	InstrumentationSecretRenew = "secret-renew"
+)
+
+// ErrSecretNotFound is returned by providers when secret does not exist.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Secret is a secret value with its lease information.
+type Secret struct {
+	// Name of the secret.
+	Name string `json:"name"`
+	// Value of the secret.
+	Value []byte `json:"value"`
+	// Version of the secret if provider supports versioning.
+	Version string `json:"version,omitempty"`
+	// LeaseID of the secret if provider issues leases.
+	LeaseID string `json:"lease_id,omitempty"`
+	// Renewable is true if the lease can be renewed.
+	Renewable bool `json:"renewable,omitempty"`
+	// TTL of the secret or lease. Zero means that secret does not expire.
+	TTL time.Duration `json:"ttl,omitempty"`
+	// FetchedAt is a time when secret was fetched or its lease renewed.
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// ExpiresAt returns time when secret expires or zero time if it does not expire.
+func (s *Secret) ExpiresAt() time.Time {
+	if s.TTL <= 0 {
+		return time.Time{}
+	}
+	return s.FetchedAt.Add(s.TTL)
+}
+
+// String returns secret name with value redacted.
+func (s *Secret) String() string {
+	return fmt.Sprintf("%s (version %q): [REDACTED]", s.Name, s.Version)
+}
+
+func (s *Secret) clone() *Secret {
+	c := *s
+	c.Value = append([]byte{}, s.Value...)
+	return &c
+}
+
+// rotated returns true if secret value has changed.
+func (s *Secret) rotated(o *Secret) bool {
+	if len(s.Version) > 0 && len(o.Version) > 0 && s.Version != o.Version {
+		return true
+	}
+	return !bytes.Equal(s.Value, o.Value)
+}
+
+// Provider fetches secrets from the secret store.
+type Provider interface {
+	// Fetch returns secret by its name.
+	Fetch(ctx context.Context, name string) (*Secret, error)
+}
+
+// ProviderFunc is a function that implements Provider interface.
+type ProviderFunc func(ctx context.Context, name string) (*Secret, error)
+
+// Fetch returns secret by its name.
+func (f ProviderFunc) Fetch(ctx context.Context, name string) (*Secret, error) {
+	return f(ctx, name)
+}
+
+// Renewer is implemented by providers that support lease renewal.
+type Renewer interface {
+	// Renew extends the lease of the secret and returns secret with updated TTL.
+	Renew(ctx context.Context, secret *Secret) (*Secret, error)
+}
+
+// Subscriber is called when secret is rotated.
+type Subscriber func(ctx context.Context, secret *Secret)
+
+// Manager fetches secrets from the provider, keeps them cached and refreshes
+// them before their lease expires.
+//
+// Subscribers are notified when secret value changes, for example to update
+// a database or Redis password without application restart.
+//
+// Manager implements core.Tasker interface.
+type Manager struct {
+	provider Provider
+	opts     *options
+	store    cache.CacheInstance[[]byte]
+
+	lock    sync.RWMutex
+	secrets map[string]*Secret
+	subs    map[string]map[int]Subscriber
+	nextID  int
+
+	fetchLock sync.Mutex
+	fetching  map[string]*fetchCall
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	now func() time.Time
+}
+
+type fetchCall struct {
+	done   chan struct{}
+	secret *Secret
+	err    error
+}
+
+// New creates new secrets manager.
+func New(provider Provider, opts ...Option) (*Manager, error) {
+	opt := newOptions(opts...)
+
+	m := &Manager{
+		provider: provider,
+		opts:     opt,
+		secrets:  make(map[string]*Secret),
+		subs:     make(map[string]map[int]Subscriber),
+		fetching: make(map[string]*fetchCall),
+		now:      time.Now,
+	}
+
+	if opt.Cache != nil {
+		if opt.KeyRing == nil {
+			return nil, errors.New("key ring is required to cache secrets")
+		}
+		store, err := cache.Create[[]byte](opt.Cache, opt.Name, opt.CacheOptions...)
+		if err != nil {
+			return nil, err
+		}
+		m.store = store
+	}
+
+	return m, nil
+}
+
+// Get returns secret by its name fetching it if not already known.
+func (m *Manager) Get(ctx context.Context, name string) (*Secret, error) {
+	m.lock.RLock()
+	s, ok := m.secrets[name]
+	m.lock.RUnlock()
+	if ok && !m.expired(s) {
+		return s.clone(), nil
+	}
+
+	s, err := m.fetch(ctx, name, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.clone(), nil
+}
+
+// Value returns secret value by its name.
+func (m *Manager) Value(ctx context.Context, name string) ([]byte, error) {
+	s, err := m.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.Value, nil
+}
+
+// Subscribe to secret rotation. Returns function to unsubscribe.
+//
+// Subscribed secrets are refreshed in the background even if not read.
+func (m *Manager) Subscribe(name string, fn Subscriber) func() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.nextID++
+	id := m.nextID
+	if m.subs[name] == nil {
+		m.subs[name] = make(map[int]Subscriber)
+	}
+	m.subs[name][id] = fn
+
+	return func() {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		delete(m.subs[name], id)
+		if len(m.subs[name]) == 0 {
+			delete(m.subs, name)
+		}
+	}
+}
+
+// Forget removes secret from the manager and the cache.
+func (m *Manager) Forget(ctx context.Context, name string) error {
+	m.lock.Lock()
+	delete(m.secrets, name)
+	m.lock.Unlock()
+
+	if m.store != nil {
+		return m.store.Delete(ctx, name)
+	}
+	return nil
+}
+
+// expired returns true if secret has expired.
+func (m *Manager) expired(s *Secret) bool {
+	exp := s.ExpiresAt()
+	return !exp.IsZero() && !m.now().Before(exp)
+}
+
+// due returns true if secret should be refreshed.
+func (m *Manager) due(s *Secret) bool {
+	var refreshAt time.Time
+	switch {
+	case s.TTL > 0:
+		refreshAt = s.FetchedAt.Add(time.Duration(float64(s.TTL) * (1 - m.opts.RefreshBefore)))
+	case m.opts.DefaultTTL > 0:
+		refreshAt = s.FetchedAt.Add(m.opts.DefaultTTL)
+	default:
+		return false
+	}
+	return !m.now().Before(refreshAt)
+}
+
+// fetch returns secret from the cache or the provider. Concurrent fetches of
+// the same secret are merged.
+func (m *Manager) fetch(ctx context.Context, name string, cached bool) (*Secret, error) {
+	m.fetchLock.Lock()
+	if c, ok := m.fetching[name]; ok {
+		m.fetchLock.Unlock()
+		select {
+		case <-c.done:
+			return c.secret, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c := &fetchCall{done: make(chan struct{})}
+	m.fetching[name] = c
+	m.fetchLock.Unlock()
+
+	defer func() {
+		m.fetchLock.Lock()
+		delete(m.fetching, name)
+		m.fetchLock.Unlock()
+		close(c.done)
+	}()
+
+	if cached {
+		if s := m.loadCached(ctx, name); s != nil {
+			c.secret = s
+			m.update(ctx, s)
+			return s, nil
+		}
+	}
+
+	finish := m.opts.Instrumenter.Observe(ctx, InstrumentationSecretFetch, name)
+	s, err := m.provider.Fetch(ctx, name)
+	finish(err)
+	if err != nil {
+		c.err = err
+		return nil, err
+	}
+	s.Name = name
+	s.FetchedAt = m.now()
+	m.storeCached(ctx, s)
+	m.update(ctx, s)
+
+	c.secret = s
+	return s, nil
+}
+
+// renew extends the secret lease. Returns false if secret needs to be fetched again.
+func (m *Manager) renew(ctx context.Context, s *Secret) bool {
+	r, ok := m.provider.(Renewer)
+	if !ok || !s.Renewable || len(s.LeaseID) == 0 {
+		return false
+	}
+	finish := m.opts.Instrumenter.Observe(ctx, InstrumentationSecretRenew, s.Name)
+	rs, err := r.Renew(ctx, s.clone())
+	finish(err)
+	if err != nil {
+		m.opts.Logger.Warn("failed to renew secret lease", zap.String("secret.name", s.Name), zap.Error(err))
+		return false
+	}
+	rs.Name = s.Name
+	rs.FetchedAt = m.now()
+	// Lease has reached its maximum TTL and must be replaced.
+	if rs.TTL <= 0 || m.due(rs) {
+		return false
+	}
+	m.storeCached(ctx, rs)
+	m.update(ctx, rs)
+	return true
+}
+
+// update stores secret and notifies subscribers if its value has changed.
+func (m *Manager) update(ctx context.Context, s *Secret) {
+	m.lock.Lock()
+	old, ok := m.secrets[s.Name]
+	m.secrets[s.Name] = s
+	var subs []Subscriber
+	if ok && old.rotated(s) {
+		subs = make([]Subscriber, 0, len(m.subs[s.Name]))
+		for _, fn := range m.subs[s.Name] {
+			subs = append(subs, fn)
+		}
+	}
+	m.lock.Unlock()
+
+	for _, fn := range subs {
+		fn(ctx, s.clone())
+	}
+}
+
+func (m *Manager) loadCached(ctx context.Context, name string) *Secret {
+	if m.store == nil {
+		return nil
+	}
+	raw, err := m.store.Get(ctx, name)
+	if err != nil || len(raw) == 0 {
+		return nil
+	}
+	data, err := m.opts.KeyRing.Decrypt(raw)
+	if err != nil {
+		m.opts.Logger.Warn("failed to decrypt cached secret", zap.String("secret.name", name), zap.Error(err))
+		return nil
+	}
+	s := &Secret{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil
+	}
+	if s.Name != name || m.due(s) {
+		return nil
+	}
+	return s
+}
+
+func (m *Manager) storeCached(ctx context.Context, s *Secret) {
+	if m.store == nil {
+		return
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	enc, err := m.opts.KeyRing.Encrypt(data)
+	if err != nil {
+		m.opts.Logger.Warn("failed to encrypt secret", zap.String("secret.name", s.Name), zap.Error(err))
+		return
+	}
+	var opts []cache.ItemOption[[]byte]
+	if s.TTL > 0 {
+		opts = append(opts, cache.TTL[[]byte](s.TTL))
+	}
+	if err := m.store.Set(ctx, s.Name, enc, opts...); err != nil {
+		m.opts.Logger.Warn("failed to cache secret", zap.String("secret.name", s.Name), zap.Error(err))
+	}
+}
+
+// Refresh renews or fetches again all secrets that are about to expire.
+//
+// Returns first error that occurred.
+func (m *Manager) Refresh(ctx context.Context) error {
+	m.lock.RLock()
+	due := make([]*Secret, 0, len(m.secrets))
+	for _, s := range m.secrets {
+		if m.due(s) {
+			due = append(due, s)
+		}
+	}
+	// Subscribed secrets are fetched even if they were not read yet.
+	var missing []string
+	for name := range m.subs {
+		if _, ok := m.secrets[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	m.lock.RUnlock()
+
+	var first error
+	for _, s := range due {
+		if m.renew(ctx, s) {
+			continue
+		}
+		if _, err := m.fetch(ctx, s.Name, false); err != nil {
+			m.opts.Logger.Error("failed to refresh secret", zap.String("secret.name", s.Name), zap.Error(err))
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	for _, name := range missing {
+		if _, err := m.fetch(ctx, name, true); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Name returns task name.
+func (m *Manager) Name() string {
+	return "secrets-manager"
+}
+
+// Start refreshing secrets in the background.
+func (m *Manager) Start(ctx context.Context) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.cancel != nil {
+		return nil
+	}
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+	go m.run(ctx, m.done)
+	return nil
+}
+
+// Stop refreshing secrets.
+func (m *Manager) Stop() {
+	m.lock.Lock()
+	if m.cancel == nil {
+		m.lock.Unlock()
+		return
+	}
+	m.cancel()
+	m.cancel = nil
+	done := m.done
+	m.lock.Unlock()
+
+	<-done
+}
+
+func (m *Manager) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	t := time.NewTicker(m.opts.CheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			_ = m.Refresh(ctx)
+		}
+	}
+}
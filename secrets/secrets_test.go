@@ -0,0 +1,186 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/keyring"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testProvider struct {
+	lock    sync.Mutex
+	value   string
+	version int
+	ttl     time.Duration
+	fetches int
+	renews  int
+}
+
+func (p *testProvider) Fetch(ctx context.Context, name string) (*Secret, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if name == "missing" {
+		return nil, ErrSecretNotFound
+	}
+	p.fetches++
+	return &Secret{
+		Value:     []byte(p.value),
+		Version:   strconv.Itoa(p.version),
+		LeaseID:   "lease-" + strconv.Itoa(p.fetches),
+		Renewable: true,
+		TTL:       p.ttl,
+	}, nil
+}
+
+func (p *testProvider) Renew(ctx context.Context, s *Secret) (*Secret, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.renews++
+	if p.renews > 1 {
+		return nil, errors.New("lease expired")
+	}
+	s.TTL = p.ttl
+	s.FetchedAt = time.Now()
+	return s, nil
+}
+
+func (p *testProvider) rotate(value string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.value = value
+	p.version++
+}
+
+func TestManagerGet(t *testing.T) {
+	p := &testProvider{value: "secret", version: 1, ttl: time.Hour}
+	m, err := New(p)
+	require.NoError(t, err)
+
+	v, err := m.Value(context.TODO(), "db")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(v))
+
+	s, err := m.Get(context.TODO(), "db")
+	require.NoError(t, err)
+	assert.Equal(t, "db", s.Name)
+	assert.Equal(t, "1", s.Version)
+	assert.NotContains(t, s.String(), "secret")
+	assert.Equal(t, 1, p.fetches)
+
+	_, err = m.Get(context.TODO(), "missing")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestManagerRefresh(t *testing.T) {
+	p := &testProvider{value: "first", version: 1, ttl: time.Hour}
+	m, err := New(p, RefreshBefore(0.5))
+	require.NoError(t, err)
+
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	var rotated atomic.Int32
+	unsubscribe := m.Subscribe("db", func(ctx context.Context, s *Secret) {
+		rotated.Add(1)
+		assert.Equal(t, "second", string(s.Value))
+	})
+	defer unsubscribe()
+
+	// Subscribed secret is fetched on refresh.
+	require.NoError(t, m.Refresh(context.TODO()))
+	assert.Equal(t, 1, p.fetches)
+
+	// Not due for refresh yet.
+	now = now.Add(10 * time.Minute)
+	require.NoError(t, m.Refresh(context.TODO()))
+	assert.Equal(t, 1, p.fetches)
+	assert.Equal(t, 0, p.renews)
+
+	// Lease is renewed and value is kept.
+	now = now.Add(25 * time.Minute)
+	require.NoError(t, m.Refresh(context.TODO()))
+	assert.Equal(t, 1, p.renews)
+	assert.Equal(t, 1, p.fetches)
+	assert.Equal(t, int32(0), rotated.Load())
+
+	// Lease can not be renewed so rotated secret is fetched.
+	p.rotate("second")
+	now = now.Add(time.Hour)
+	require.NoError(t, m.Refresh(context.TODO()))
+	assert.Equal(t, 2, p.fetches)
+	assert.Equal(t, int32(1), rotated.Load())
+
+	v, err := m.Value(context.TODO(), "db")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(v))
+}
+
+func TestManagerCache(t *testing.T) {
+	s := miniredis.RunT(t)
+	c := cache.New(cache.CacheType(cache.RedisCache), cache.ConnectionString("redis://"+s.Addr()))
+	require.NoError(t, c.Start(context.TODO()))
+	defer c.Close()
+
+	_, err := New(&testProvider{}, Cache{c})
+	assert.Error(t, err, "secrets must not be cached unencrypted")
+
+	ring := keyring.New(keyring.Key{ID: "k1", Secret: []byte("encryption-key")})
+
+	p := &testProvider{value: "secret", version: 1, ttl: time.Hour}
+	m1, err := New(p, Cache{c}, KeyRing{ring})
+	require.NoError(t, err)
+
+	_, err = m1.Get(context.TODO(), "db")
+	require.NoError(t, err)
+
+	enc, err := s.Get(DefaultName + ":db")
+	require.NoError(t, err)
+	assert.NotEmpty(t, enc)
+	assert.NotContains(t, enc, "secret")
+
+	m2, err := New(p, Cache{c}, KeyRing{ring})
+	require.NoError(t, err)
+	v, err := m2.Value(context.TODO(), "db")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(v))
+	assert.Equal(t, 1, p.fetches, "secret must be read from cache")
+
+	require.NoError(t, m2.Forget(context.TODO(), "db"))
+	assert.False(t, s.Exists(DefaultName+":db"))
+}
+
+func TestManagerConcurrentFetch(t *testing.T) {
+	var fetches atomic.Int32
+	m, err := New(ProviderFunc(func(ctx context.Context, name string) (*Secret, error) {
+		fetches.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return &Secret{Value: []byte("secret")}, nil
+	}))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := m.Value(context.TODO(), "db")
+			assert.NoError(t, err)
+			assert.Equal(t, "secret", string(v))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), fetches.Load())
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// Vault is a HashiCorp Vault secrets provider.
+//
+// Secret name is a path of the secret relative to the API root, for example
+// "secret/data/app" for KV version 2 engine or "database/creds/app" for dynamic
+// database credentials. Dynamic secrets leases are renewed.
+type Vault struct {
+	// Address of the Vault server.
+	Address string
+	// Token used to authenticate.
+	Token string
+	// Namespace of the secrets (Vault Enterprise).
+	Namespace string
+	// Field of the secret data to use as the value. If empty, all secret
+	// data is returned as JSON object.
+	Field string
+	// Client is HTTP client to use. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+type vaultResponse struct {
+	LeaseID       string          `json:"lease_id"`
+	LeaseDuration int64           `json:"lease_duration"`
+	Renewable     bool            `json:"renewable"`
+	Data          json.RawMessage `json:"data"`
+	Errors        []string        `json:"errors"`
+}
+
+type vaultKV2Data struct {
+	Data     map[string]json.RawMessage `json:"data"`
+	Metadata *struct {
+		Version int `json:"version"`
+	} `json:"metadata"`
+}
+
+func (v *Vault) do(ctx context.Context, method, path string, body any) (*vaultResponse, error) {
+	var r io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(v.Address, "/")+"/v1/"+strings.TrimPrefix(path, "/"), r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if len(v.Namespace) > 0 {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrSecretNotFound
+	}
+	res := &vaultResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil && resp.StatusCode < 300 {
+		return nil, fmt.Errorf("invalid vault response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("vault request failed with status %d: %s", resp.StatusCode, strings.Join(res.Errors, "; "))
+	}
+	return res, nil
+}
+
+// Fetch returns secret by its path.
+func (v *Vault) Fetch(ctx context.Context, name string) (*Secret, error) {
+	res, err := v.do(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(res.Data, &data); err != nil {
+		return nil, fmt.Errorf("invalid vault secret data: %w", err)
+	}
+	s := &Secret{
+		Name:      name,
+		LeaseID:   res.LeaseID,
+		Renewable: res.Renewable,
+		TTL:       time.Duration(res.LeaseDuration) * time.Second,
+	}
+
+	// KV version 2 engine wraps data with metadata.
+	kv := vaultKV2Data{}
+	if _, ok := data["metadata"]; ok && json.Unmarshal(res.Data, &kv) == nil && kv.Metadata != nil {
+		data = kv.Data
+		s.Version = strconv.Itoa(kv.Metadata.Version)
+	}
+
+	if len(v.Field) == 0 {
+		if s.Value, err = json.Marshal(data); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	raw, ok := data[v.Field]
+	if !ok {
+		return nil, fmt.Errorf("vault secret %q has no field %q: %w", name, v.Field, ErrSecretNotFound)
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		s.Value = []byte(str)
+	} else {
+		s.Value = []byte(raw)
+	}
+	return s, nil
+}
+
+// Renew extends the lease of the secret.
+func (v *Vault) Renew(ctx context.Context, secret *Secret) (*Secret, error) {
+	res, err := v.do(ctx, http.MethodPut, "sys/leases/renew", map[string]any{
+		"lease_id":  secret.LeaseID,
+		"increment": int64(secret.TTL / time.Second),
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := secret.clone()
+	s.LeaseID = res.LeaseID
+	s.Renewable = res.Renewable
+	s.TTL = time.Duration(res.LeaseDuration) * time.Second
+	s.FetchedAt = time.Now()
+	return s, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package serializer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+type gzipSerializer struct {
+	Serializer
+}
+
+// Gzip returns serializer that compresses data encoded by the serializer.
+//
+// Content type is the one of the wrapped serializer so it should be used for
+// storage and not for content negotiation.
+func Gzip(s Serializer) Serializer {
+	return gzipSerializer{Serializer: s}
+}
+
+func (s gzipSerializer) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if err := s.Serializer.NewEncoder(w).Encode(v); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s gzipSerializer) Unmarshal(data []byte, v any) error {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return s.Serializer.NewDecoder(r).Decode(v)
+}
+
+type gzipEncoder struct {
+	w   *gzip.Writer
+	enc Encoder
+}
+
+func (e *gzipEncoder) Encode(v any) error {
+	if err := e.enc.Encode(v); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (s gzipSerializer) NewEncoder(w io.Writer) Encoder {
+	gw := gzip.NewWriter(w)
+	return &gzipEncoder{
+		w:   gw,
+		enc: s.Serializer.NewEncoder(gw),
+	}
+}
+
+type gzipDecoder struct {
+	s   Serializer
+	r   io.Reader
+	dec Decoder
+	err error
+}
+
+func (d *gzipDecoder) Decode(v any) error {
+	if d.dec == nil && d.err == nil {
+		// Reader is opened lazily as gzip header is read on open.
+		r, err := gzip.NewReader(d.r)
+		if err != nil {
+			d.err = err
+		} else {
+			d.dec = d.s.NewDecoder(r)
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+	return d.dec.Decode(v)
+}
+
+func (s gzipSerializer) NewDecoder(r io.Reader) Decoder {
+	return &gzipDecoder{
+		s: s.Serializer,
+		r: r,
+	}
+}
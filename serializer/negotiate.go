@@ -0,0 +1,137 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package serializer
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNotAcceptable is returned when none of the available serializers is accepted by the client.
+	ErrNotAcceptable = errors.New("not acceptable")
+	// ErrUnsupportedMediaType is returned when request content type is not supported.
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	ranges := make([]acceptRange, 0, 4)
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mt := strings.ToLower(strings.TrimSpace(params[0]))
+		typ, subtype, ok := strings.Cut(mt, "/")
+		if !ok {
+			continue
+		}
+		r := acceptRange{typ: typ, subtype: subtype, q: 1}
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if k == "q" {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+func (r acceptRange) match(contentType string) (int, bool) {
+	typ, subtype, _ := strings.Cut(contentType, "/")
+	switch {
+	case r.typ == typ && r.subtype == subtype:
+		return 3, true
+	case r.typ == typ && r.subtype == "*":
+		return 2, true
+	case r.typ == "*" && r.subtype == "*":
+		return 1, true
+	}
+	return 0, false
+}
+
+// Negotiate selects best serializer from offers based on the Accept header value.
+//
+// If offers are not provided JSON, MsgPack and XML serializers are used in that order.
+// If Accept header is empty first offer is returned.
+func Negotiate(accept string, offers ...Serializer) (Serializer, bool) {
+	if len(offers) == 0 {
+		offers = defaults
+	}
+	if len(strings.TrimSpace(accept)) == 0 {
+		return offers[0], true
+	}
+
+	ranges := parseAccept(accept)
+
+	var best Serializer
+	var bestQ float64
+	for _, s := range offers {
+		// Most specific matching range determines quality of the offer.
+		specificity, q := 0, float64(0)
+		for _, r := range ranges {
+			if sp, ok := r.match(s.ContentType()); ok && sp > specificity {
+				specificity, q = sp, r.q
+			}
+		}
+		if q > bestQ {
+			best, bestQ = s, q
+		}
+	}
+	return best, best != nil
+}
+
+// Write encodes value to the response using serializer negotiated from request Accept header.
+//
+// If none of the offers are acceptable, responds with 406 Not Acceptable and returns ErrNotAcceptable.
+func Write(w http.ResponseWriter, r *http.Request, status int, v any, offers ...Serializer) error {
+	s, ok := Negotiate(r.Header.Get("Accept"), offers...)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return ErrNotAcceptable
+	}
+
+	w.Header().Set("Content-Type", s.ContentType())
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+
+	return s.NewEncoder(w).Encode(v)
+}
+
+// Read decodes request body into value using serializer selected by request Content-Type header.
+//
+// If request content type is not set JSON is assumed. If allowed serializers are provided only
+// those are accepted.
+func Read(r *http.Request, v any, allowed ...Serializer) error {
+	ct := r.Header.Get("Content-Type")
+	if len(ct) == 0 {
+		ct = ContentTypeJSON
+	}
+	s, ok := Lookup(ct)
+	if !ok {
+		return ErrUnsupportedMediaType
+	}
+	if len(allowed) > 0 {
+		found := false
+		for _, a := range allowed {
+			if a.ContentType() == s.ContentType() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrUnsupportedMediaType
+		}
+	}
+	return s.NewDecoder(r.Body).Decode(v)
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package serializer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Supported content types.
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeMsgPack = "application/msgpack"
+	ContentTypeXML     = "application/xml"
+)
+
+// Encoder writes encoded values to the output stream.
+type Encoder interface {
+	Encode(v any) error
+}
+
+// Decoder reads and decodes values from the input stream.
+type Decoder interface {
+	Decode(v any) error
+}
+
+// Serializer encodes and decodes values.
+type Serializer interface {
+	// ContentType returns media type of the encoded data.
+	ContentType() string
+	// Marshal returns encoded value.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes data into value.
+	Unmarshal(data []byte, v any) error
+	// NewEncoder returns new encoder that writes to w.
+	NewEncoder(w io.Writer) Encoder
+	// NewDecoder returns new decoder that reads from r.
+	NewDecoder(r io.Reader) Decoder
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (jsonSerializer) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonSerializer) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonSerializer) NewEncoder(w io.Writer) Encoder {
+	return json.NewEncoder(w)
+}
+
+func (jsonSerializer) NewDecoder(r io.Reader) Decoder {
+	return json.NewDecoder(r)
+}
+
+type msgpackSerializer struct{}
+
+func (msgpackSerializer) ContentType() string {
+	return ContentTypeMsgPack
+}
+
+func (s msgpackSerializer) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s msgpackSerializer) Unmarshal(data []byte, v any) error {
+	return s.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (msgpackSerializer) NewEncoder(w io.Writer) Encoder {
+	enc := msgpack.NewEncoder(w)
+	// Use same struct tags as for JSON.
+	enc.SetCustomStructTag("json")
+	return enc
+}
+
+func (msgpackSerializer) NewDecoder(r io.Reader) Decoder {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	return dec
+}
+
+type xmlSerializer struct{}
+
+func (xmlSerializer) ContentType() string {
+	return ContentTypeXML
+}
+
+func (xmlSerializer) Marshal(v any) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (xmlSerializer) Unmarshal(data []byte, v any) error {
+	return xml.Unmarshal(data, v)
+}
+
+func (xmlSerializer) NewEncoder(w io.Writer) Encoder {
+	return xml.NewEncoder(w)
+}
+
+func (xmlSerializer) NewDecoder(r io.Reader) Decoder {
+	return xml.NewDecoder(r)
+}
+
+var (
+	// JSON serializer.
+	JSON Serializer = jsonSerializer{}
+	// MsgPack serializer.
+	MsgPack Serializer = msgpackSerializer{}
+	// XML serializer.
+	XML Serializer = xmlSerializer{}
+)
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]Serializer{
+		ContentTypeJSON:         JSON,
+		ContentTypeMsgPack:      MsgPack,
+		"application/x-msgpack": MsgPack,
+		ContentTypeXML:          XML,
+		"text/xml":              XML,
+	}
+	defaults = []Serializer{JSON, MsgPack, XML}
+)
+
+// Register serializer for its content type and optional aliases.
+func Register(s Serializer, aliases ...string) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	registry[s.ContentType()] = s
+	for _, a := range aliases {
+		registry[strings.ToLower(a)] = s
+	}
+}
+
+// Lookup returns serializer registered for the content type.
+func Lookup(contentType string) (Serializer, bool) {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	s, ok := registry[mt]
+	return s, ok
+}
@@ -0,0 +1,104 @@
+package serializer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testValue struct {
+	Name  string `json:"name" xml:"name"`
+	Count int    `json:"count" xml:"count"`
+}
+
+func TestSerializersRoundTrip(t *testing.T) {
+	for _, s := range []Serializer{JSON, MsgPack, XML} {
+		t.Run(s.ContentType(), func(t *testing.T) {
+			buf, err := s.Marshal(testValue{Name: "test", Count: 3})
+			require.NoError(t, err)
+
+			var v testValue
+			require.NoError(t, s.Unmarshal(buf, &v))
+			assert.Equal(t, testValue{Name: "test", Count: 3}, v)
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	s, ok := Lookup("application/json; charset=utf-8")
+	require.True(t, ok)
+	assert.Equal(t, JSON, s)
+
+	s, ok = Lookup("application/x-msgpack")
+	require.True(t, ok)
+	assert.Equal(t, MsgPack, s)
+
+	_, ok = Lookup("text/plain")
+	assert.False(t, ok)
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected Serializer
+	}{
+		{"", JSON},
+		{"*/*", JSON},
+		{"application/xml", XML},
+		{"application/json;q=0.5, application/msgpack", MsgPack},
+		{"application/*;q=0.2, application/xml;q=0.9", XML},
+		{"text/html, */*;q=0.1", JSON},
+	}
+	for _, tt := range tests {
+		s, ok := Negotiate(tt.accept)
+		require.True(t, ok, tt.accept)
+		assert.Equal(t, tt.expected.ContentType(), s.ContentType(), tt.accept)
+	}
+
+	_, ok := Negotiate("text/html", JSON)
+	assert.False(t, ok)
+}
+
+func TestWriteRead(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/msgpack")
+	w := httptest.NewRecorder()
+
+	require.NoError(t, Write(w, r, http.StatusOK, testValue{Name: "test", Count: 1}))
+	assert.Equal(t, ContentTypeMsgPack, w.Header().Get("Content-Type"))
+
+	r = httptest.NewRequest(http.MethodPost, "/", w.Body)
+	r.Header.Set("Content-Type", ContentTypeMsgPack)
+
+	var v testValue
+	require.NoError(t, Read(r, &v))
+	assert.Equal(t, testValue{Name: "test", Count: 1}, v)
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	r.Header.Set("Content-Type", ContentTypeXML)
+	assert.ErrorIs(t, Read(r, &v, JSON), ErrUnsupportedMediaType)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	assert.ErrorIs(t, Write(w, r, http.StatusOK, testValue{}, JSON), ErrNotAcceptable)
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+}
+
+func TestGzip(t *testing.T) {
+	s := Gzip(JSON)
+	assert.Equal(t, ContentTypeJSON, s.ContentType())
+
+	v := testValue{Name: strings.Repeat("test", 100), Count: 3}
+	buf, err := s.Marshal(v)
+	require.NoError(t, err)
+	assert.Less(t, len(buf), 100)
+
+	var got testValue
+	require.NoError(t, s.Unmarshal(buf, &got))
+	assert.Equal(t, v, got)
+}
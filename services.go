@@ -0,0 +1,251 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Lifetime of the service instance.
+type Lifetime uint8
+
+const (
+	// LifetimeSingleton creates single service instance for the application.
+	LifetimeSingleton Lifetime = iota
+	// LifetimeScoped creates service instance once per scope (for example request).
+	LifetimeScoped
+	// LifetimeTransient creates new service instance every time it is resolved.
+	LifetimeTransient
+)
+
+// ErrServiceNotRegistered is returned when resolving service that has not been registered.
+var ErrServiceNotRegistered = errors.New("service not registered")
+
+// Constructor is a function that creates new service instance.
+//
+// Constructor should use provided context and services container to resolve
+// other services it depends on.
+type Constructor[T any] func(ctx context.Context, s *Services) (T, error)
+
+type serviceProvider struct {
+	lifetime Lifetime
+	ctor     func(ctx context.Context, s *Services) (any, error)
+}
+
+type serviceInstance struct {
+	once  sync.Once
+	value any
+	err   error
+}
+
+// Services is a dependency injection container.
+type Services struct {
+	root *Services
+
+	lock      sync.Mutex
+	providers map[reflect.Type]*serviceProvider
+	instances map[reflect.Type]*serviceInstance
+	order     []reflect.Type
+	closed    bool
+}
+
+// NewServices creates new root services container.
+func NewServices() *Services {
+	s := &Services{
+		providers: make(map[reflect.Type]*serviceProvider),
+		instances: make(map[reflect.Type]*serviceInstance),
+	}
+	s.root = s
+	return s
+}
+
+// NewScope creates new services scope.
+//
+// Scoped services resolved from the scope are created once and disposed when scope is closed.
+func (s *Services) NewScope() *Services {
+	return &Services{
+		root:      s.root,
+		instances: make(map[reflect.Type]*serviceInstance),
+	}
+}
+
+// IsScope returns true if services container is a scope.
+func (s *Services) IsScope() bool {
+	return s.root != s
+}
+
+func (s *Services) provider(typ reflect.Type) *serviceProvider {
+	s.root.lock.Lock()
+	defer s.root.lock.Unlock()
+
+	return s.root.providers[typ]
+}
+
+func (s *Services) instance(typ reflect.Type) (*serviceInstance, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return nil, errors.New("services container closed")
+	}
+
+	i, ok := s.instances[typ]
+	if !ok {
+		i = &serviceInstance{}
+		s.instances[typ] = i
+		s.order = append(s.order, typ)
+	}
+	return i, nil
+}
+
+// Close disposes all instances created by the container.
+//
+// Instances that implement Close() or Close() error methods are closed in reverse order of creation.
+func (s *Services) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	for i := len(s.order) - 1; i >= 0; i-- {
+		inst := s.instances[s.order[i]]
+		if inst.err != nil || inst.value == nil {
+			continue
+		}
+		switch v := inst.value.(type) {
+		case interface{ Close() }:
+			v.Close()
+		case interface{ Close() error }:
+			_ = v.Close()
+		}
+	}
+	s.instances = nil
+	s.order = nil
+}
+
+// Register service constructor with specified lifetime.
+//
+// Services are initialized lazily when first resolved.
+func Register[T any](s *Services, lifetime Lifetime, ctor Constructor[T]) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+
+	s.root.lock.Lock()
+	defer s.root.lock.Unlock()
+
+	s.root.providers[typ] = &serviceProvider{
+		lifetime: lifetime,
+		ctor: func(ctx context.Context, s *Services) (any, error) {
+			return ctor(ctx, s)
+		},
+	}
+}
+
+// RegisterInstance registers already created instance as a singleton service.
+func RegisterInstance[T any](s *Services, value T) {
+	Register(s, LifetimeSingleton, func(context.Context, *Services) (T, error) {
+		return value, nil
+	})
+}
+
+type resolvingKey struct{}
+
+type resolvingChain struct {
+	parent *resolvingChain
+	typ    reflect.Type
+}
+
+func (c *resolvingChain) contains(typ reflect.Type) bool {
+	for ; c != nil; c = c.parent {
+		if c.typ == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve service instance of type T.
+func Resolve[T any](ctx context.Context, s *Services) (T, error) {
+	var val T
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	p := s.provider(typ)
+	if p == nil {
+		return val, fmt.Errorf("%w: %s", ErrServiceNotRegistered, typ)
+	}
+
+	chain, _ := ctx.Value(resolvingKey{}).(*resolvingChain)
+	if chain.contains(typ) {
+		return val, fmt.Errorf("circular dependency detected resolving %s", typ)
+	}
+	ctx = context.WithValue(ctx, resolvingKey{}, &resolvingChain{parent: chain, typ: typ})
+
+	var v any
+	var err error
+	switch p.lifetime {
+	case LifetimeTransient:
+		v, err = p.ctor(ctx, s)
+	case LifetimeSingleton:
+		v, err = resolveInstance(ctx, s.root, typ, p)
+	case LifetimeScoped:
+		if !s.IsScope() {
+			return val, fmt.Errorf("scoped service %s can not be resolved outside of scope", typ)
+		}
+		v, err = resolveInstance(ctx, s, typ, p)
+	}
+	if err != nil {
+		return val, err
+	}
+	if v == nil {
+		return val, nil
+	}
+	return v.(T), nil
+}
+
+func resolveInstance(ctx context.Context, s *Services, typ reflect.Type, p *serviceProvider) (any, error) {
+	i, err := s.instance(typ)
+	if err != nil {
+		return nil, err
+	}
+	i.once.Do(func() {
+		i.value, i.err = p.ctor(ctx, s)
+	})
+	return i.value, i.err
+}
+
+// MustResolve resolves service instance of type T.
+//
+// Panics if service can not be resolved.
+func MustResolve[T any](ctx context.Context, s *Services) T {
+	v, err := Resolve[T](ctx, s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+type servicesKey struct{}
+
+// ContextWithServices returns new context with services container attached.
+func ContextWithServices(ctx context.Context, s *Services) context.Context {
+	return context.WithValue(ctx, servicesKey{}, s)
+}
+
+// ServicesFromContext returns services container attached to the context.
+func ServicesFromContext(ctx context.Context) *Services {
+	s, _ := ctx.Value(servicesKey{}).(*Services)
+	return s
+}
+
+// Services returns application services container.
+func (a *App) Services() *Services {
+	return a.services
+}
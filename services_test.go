@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testService struct {
+	id     int
+	closed bool
+}
+
+func (s *testService) Close() {
+	s.closed = true
+}
+
+type testDependentService struct {
+	svc *testService
+}
+
+func TestServicesSingleton(t *testing.T) {
+	s := NewServices()
+
+	cnt := 0
+	Register(s, LifetimeSingleton, func(context.Context, *Services) (*testService, error) {
+		cnt++
+		return &testService{id: cnt}, nil
+	})
+	assert.Equal(t, 0, cnt, "singleton must be initialized lazily")
+
+	a, err := Resolve[*testService](context.TODO(), s)
+	require.NoError(t, err)
+	b, err := Resolve[*testService](context.TODO(), s.NewScope())
+	require.NoError(t, err)
+
+	assert.Same(t, a, b)
+	assert.Equal(t, 1, cnt)
+
+	s.Close()
+	assert.True(t, a.closed)
+}
+
+func TestServicesScoped(t *testing.T) {
+	s := NewServices()
+
+	cnt := 0
+	Register(s, LifetimeScoped, func(context.Context, *Services) (*testService, error) {
+		cnt++
+		return &testService{id: cnt}, nil
+	})
+	Register(s, LifetimeTransient, func(ctx context.Context, s *Services) (*testDependentService, error) {
+		svc, err := Resolve[*testService](ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		return &testDependentService{svc: svc}, nil
+	})
+
+	_, err := Resolve[*testService](context.TODO(), s)
+	assert.Error(t, err)
+
+	scope1 := s.NewScope()
+	a, err := Resolve[*testService](context.TODO(), scope1)
+	require.NoError(t, err)
+	d, err := Resolve[*testDependentService](context.TODO(), scope1)
+	require.NoError(t, err)
+	assert.Same(t, a, d.svc)
+
+	scope2 := s.NewScope()
+	b, err := Resolve[*testService](context.TODO(), scope2)
+	require.NoError(t, err)
+	assert.NotSame(t, a, b)
+
+	scope1.Close()
+	assert.True(t, a.closed)
+	assert.False(t, b.closed)
+}
+
+func TestServicesCircularDependency(t *testing.T) {
+	s := NewServices()
+
+	Register(s, LifetimeSingleton, func(ctx context.Context, s *Services) (*testService, error) {
+		_, err := Resolve[*testDependentService](ctx, s)
+		return &testService{}, err
+	})
+	Register(s, LifetimeSingleton, func(ctx context.Context, s *Services) (*testDependentService, error) {
+		svc, err := Resolve[*testService](ctx, s)
+		return &testDependentService{svc: svc}, err
+	})
+
+	_, err := Resolve[*testService](context.TODO(), s)
+	assert.ErrorContains(t, err, "circular dependency")
+}
+
+func TestServicesNotRegistered(t *testing.T) {
+	s := NewServices()
+
+	_, err := Resolve[*testService](context.TODO(), s)
+	assert.ErrorIs(t, err, ErrServiceNotRegistered)
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package settings
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/goccy/go-json"
+)
+
+// Backup writes values of all changed settings to the writer.
+//
+// Store implements backup.Handler interface.
+func (s *Store) Backup(ctx context.Context, w io.Writer) error {
+	values := make(map[string]json.RawMessage)
+	for _, key := range s.Keys() {
+		rec, err := s.Record(ctx, key)
+		if err != nil {
+			return err
+		}
+		if rec != nil {
+			values[key] = rec.Value
+		}
+	}
+	return json.NewEncoder(w).Encode(values)
+}
+
+// Restore setting values from the backup. Settings not present in the backup
+// are reset to their default values and unknown settings are ignored.
+func (s *Store) Restore(ctx context.Context, r io.Reader) error {
+	values := make(map[string]json.RawMessage)
+	if err := json.NewDecoder(r).Decode(&values); err != nil {
+		return err
+	}
+
+	const reason = "restored from backup"
+	for _, key := range s.Keys() {
+		rec, err := s.Record(ctx, key)
+		if err != nil {
+			return err
+		}
+		value, ok := values[key]
+		if !ok {
+			if rec != nil {
+				if err := s.Reset(ctx, key, reason); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if rec != nil && bytes.Equal(rec.Value, value) {
+			continue
+		}
+		if err := s.SetValue(ctx, key, value, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
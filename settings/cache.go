@@ -0,0 +1,104 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package settings
+
+import (
+	"context"
+	"fmt"
+
+	"azugo.io/core/cache"
+)
+
+// DefaultCacheModesKey is a default setting key of the cache instance modes.
+const DefaultCacheModesKey = "cache-modes"
+
+// CacheModes is a setting with runtime modes of the cache instances keyed by
+// cache instance name, that allows to bypass, freeze or make read-only any
+// cache instance in all application instances.
+//
+// Only cache instances created with cache.ModeSwitch option are affected.
+type CacheModes struct {
+	*Setting[map[string]cache.Mode]
+	cache *cache.Cache
+}
+
+// RegisterCacheModes registers setting with runtime modes of the cache instances.
+//
+// Cache instances used by the settings store itself can not be switched.
+func RegisterCacheModes(s *Store, c *cache.Cache, key string) (*CacheModes, error) {
+	if len(key) == 0 {
+		key = DefaultCacheModesKey
+	}
+	setting, err := Register(s, key, map[string]cache.Mode{}, func(modes map[string]cache.Mode) error {
+		for name, mode := range modes {
+			if name == s.name || name == s.name+"-history" {
+				return fmt.Errorf("mode of the settings cache instance %q can not be changed", name)
+			}
+			if _, err := cache.ParseMode(string(mode)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m := &CacheModes{
+		Setting: setting,
+		cache:   c,
+	}
+	setting.OnChange(func(_ context.Context, _, modes map[string]cache.Mode) {
+		m.apply(modes)
+	})
+	return m, nil
+}
+
+func (m *CacheModes) apply(modes map[string]cache.Mode) {
+	for name := range m.cache.Modes() {
+		if _, ok := modes[name]; !ok {
+			_ = m.cache.SetMode(name, cache.ModeNormal)
+		}
+	}
+	for name, mode := range modes {
+		_ = m.cache.SetMode(name, mode)
+	}
+}
+
+// Sync applies current cache instance modes to the cache.
+//
+// It should be called after settings store is started, later changes are
+// applied automatically.
+func (m *CacheModes) Sync(ctx context.Context) error {
+	modes, err := m.Get(ctx)
+	if err != nil {
+		return err
+	}
+	m.apply(modes)
+	return nil
+}
+
+// SetMode changes mode of the cache instance with the name in all application
+// instances.
+func (m *CacheModes) SetMode(ctx context.Context, name string, mode cache.Mode, reason string) error {
+	mode, err := cache.ParseMode(string(mode))
+	if err != nil {
+		return ErrInvalidValue{Key: m.Key(), Err: err}
+	}
+	current, err := m.Get(ctx)
+	if err != nil {
+		return err
+	}
+	modes := make(map[string]cache.Mode, len(current)+1)
+	for n, md := range current {
+		modes[n] = md
+	}
+	if mode == cache.ModeNormal {
+		delete(modes, name)
+	} else {
+		modes[name] = mode
+	}
+	return m.Set(ctx, modes, reason)
+}
@@ -0,0 +1,89 @@
+package settings
+
+import (
+	"azugo.io/core/cache"
+	"azugo.io/core/scrub"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	Name         string
+	CacheOptions []cache.CacheOption
+	Backend      Backend
+	MaxHistory   int
+	Scrubber     *scrub.Scrubber
+	Logger       *zap.Logger
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Name:       DefaultName,
+		MaxHistory: 100,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return opt
+}
+
+// Option for the settings store.
+type Option interface {
+	apply(*options)
+}
+
+// Name is a cache instance and channel name prefix for the settings.
+type Name string
+
+func (n Name) apply(o *options) {
+	o.Name = string(n)
+}
+
+// CacheOptions are options for the settings cache instances.
+type CacheOptions []cache.CacheOption
+
+func (c CacheOptions) apply(o *options) {
+	o.CacheOptions = append(o.CacheOptions, c...)
+}
+
+// Storage is a durable storage of the settings, for example a database.
+//
+// Cache is used as a read-through cache of the storage.
+type Storage struct {
+	Backend
+}
+
+func (s Storage) apply(o *options) {
+	o.Backend = s.Backend
+}
+
+// MaxHistory is a maximum number of changes kept in the cache per setting.
+// Defaults to 100.
+type MaxHistory int
+
+func (m MaxHistory) apply(o *options) {
+	o.MaxHistory = int(m)
+}
+
+// Scrub applies scrubbing rules to setting values recorded in the change history.
+//
+// Setting values and change notifications are not affected.
+type Scrub struct {
+	*scrub.Scrubber
+}
+
+func (s Scrub) apply(o *options) {
+	o.Scrubber = s.Scrubber
+}
+
+// Logger to log change propagation errors.
+type Logger struct {
+	*zap.Logger
+}
+
+func (l Logger) apply(o *options) {
+	o.Logger = l.Logger
+}
@@ -0,0 +1,556 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package settings
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"azugo.io/core/cache"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+// DefaultName is a default cache instance and channel name prefix for the settings.
+const DefaultName = "settings"
+
+// ErrUnknownSetting is returned when setting is not registered.
+var ErrUnknownSetting = errors.New("unknown setting")
+
+// ErrInvalidValue is returned when setting value fails validation.
+type ErrInvalidValue struct {
+	Key string
+	Err error
+}
+
+func (e ErrInvalidValue) Error() string {
+	return fmt.Sprintf("invalid value of setting %q: %v", e.Key, e.Err)
+}
+
+func (e ErrInvalidValue) Unwrap() error {
+	return e.Err
+}
+
+type actorKey struct{}
+
+// WithActor returns context with the actor that is recorded in the change history.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns actor from the context.
+func ActorFromContext(ctx context.Context) string {
+	a, _ := ctx.Value(actorKey{}).(string)
+	return a
+}
+
+// Record is a stored setting value.
+type Record struct {
+	// Value of the setting encoded as JSON.
+	Value json.RawMessage `json:"value"`
+	// Version of the setting incremented on each change.
+	Version int `json:"version"`
+	// UpdatedAt is a time when setting was changed.
+	UpdatedAt time.Time `json:"updated_at"`
+	// UpdatedBy is an actor that changed the setting.
+	UpdatedBy string `json:"updated_by,omitempty"`
+}
+
+// Change is an audit record of the setting change.
+type Change struct {
+	// Key of the setting.
+	Key string `json:"key"`
+	// Old value of the setting. Empty if default value was used.
+	Old json.RawMessage `json:"old,omitempty"`
+	// New value of the setting. Empty if setting was reset to default value.
+	New json.RawMessage `json:"new,omitempty"`
+	// Version of the setting after the change.
+	Version int `json:"version"`
+	// Actor that changed the setting.
+	Actor string `json:"actor,omitempty"`
+	// Reason of the change.
+	Reason string `json:"reason,omitempty"`
+	// At is a time of the change.
+	At time.Time `json:"at"`
+}
+
+// Backend is a durable storage of the settings.
+type Backend interface {
+	// Load returns stored setting or nil if setting is not stored.
+	Load(ctx context.Context, key string) (*Record, error)
+	// Save stores setting and its change record. Nil record deletes setting.
+	Save(ctx context.Context, key string, rec *Record, change Change) error
+	// History returns latest changes of the setting, newest first.
+	History(ctx context.Context, key string, limit int) ([]Change, error)
+}
+
+type definition struct {
+	def      json.RawMessage
+	validate func(raw json.RawMessage) error
+}
+
+type changeMessage struct {
+	Origin string `json:"origin"`
+	Change
+}
+
+// Store is a store of runtime-tunable application settings shared between all
+// application instances using cache storage and publish/subscribe for change
+// propagation.
+//
+// Store implements core.Tasker interface.
+type Store struct {
+	id      string
+	name    string
+	cache   *cache.Cache
+	opts    *options
+	records cache.CacheInstance[*Record]
+	history cache.CacheInstance[[]Change]
+
+	lock  sync.RWMutex
+	defs  map[string]definition
+	local map[string]*Record
+	hooks map[string][]func(ctx context.Context, change Change)
+
+	unsubscribe func()
+	started     bool
+}
+
+// New creates new settings store.
+func New(c *cache.Cache, opts ...Option) (*Store, error) {
+	opt := newOptions(opts...)
+
+	records, err := cache.Create[*Record](c, opt.Name, opt.CacheOptions...)
+	if err != nil {
+		return nil, err
+	}
+	history, err := cache.Create[[]Change](c, opt.Name+"-history", opt.CacheOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		id:      hex.EncodeToString(id),
+		name:    opt.Name,
+		cache:   c,
+		opts:    opt,
+		records: records,
+		history: history,
+		defs:    make(map[string]definition),
+		local:   make(map[string]*Record),
+		hooks:   make(map[string][]func(ctx context.Context, change Change)),
+	}, nil
+}
+
+func (s *Store) register(key string, def definition) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.defs[key]; ok {
+		return fmt.Errorf("setting %q is already registered", key)
+	}
+	s.defs[key] = def
+	return nil
+}
+
+func (s *Store) definition(key string) (definition, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	d, ok := s.defs[key]
+	if !ok {
+		return d, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+	return d, nil
+}
+
+// Keys returns sorted keys of all registered settings.
+func (s *Store) Keys() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	keys := make([]string, 0, len(s.defs))
+	for k := range s.defs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Record returns stored setting or nil if setting has default value.
+func (s *Store) Record(ctx context.Context, key string) (*Record, error) {
+	if _, err := s.definition(key); err != nil {
+		return nil, err
+	}
+
+	s.lock.RLock()
+	rec, ok := s.local[key]
+	s.lock.RUnlock()
+	if ok {
+		return rec, nil
+	}
+
+	rec, err := s.records.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil && s.opts.Backend != nil {
+		if rec, err = s.opts.Backend.Load(ctx, key); err != nil {
+			return nil, err
+		}
+		if rec != nil {
+			_ = s.records.Set(ctx, key, rec)
+		}
+	}
+
+	s.lock.Lock()
+	s.local[key] = rec
+	s.lock.Unlock()
+	return rec, nil
+}
+
+// Value returns setting value encoded as JSON.
+func (s *Store) Value(ctx context.Context, key string) (json.RawMessage, error) {
+	d, err := s.definition(key)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := s.Record(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return d.def, nil
+	}
+	return rec.Value, nil
+}
+
+// SetValue validates and stores setting value encoded as JSON.
+func (s *Store) SetValue(ctx context.Context, key string, value json.RawMessage, reason string) error {
+	d, err := s.definition(key)
+	if err != nil {
+		return err
+	}
+	if err := d.validate(value); err != nil {
+		return ErrInvalidValue{Key: key, Err: err}
+	}
+	return s.save(ctx, key, value, reason)
+}
+
+// Reset setting to its default value.
+func (s *Store) Reset(ctx context.Context, key, reason string) error {
+	if _, err := s.definition(key); err != nil {
+		return err
+	}
+	return s.save(ctx, key, nil, reason)
+}
+
+func (s *Store) save(ctx context.Context, key string, value json.RawMessage, reason string) error {
+	old, err := s.Record(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	change := Change{
+		Key:     key,
+		New:     value,
+		Version: 1,
+		Actor:   ActorFromContext(ctx),
+		Reason:  reason,
+		At:      time.Now().UTC(),
+	}
+	if old != nil {
+		if value != nil && bytes.Equal(old.Value, value) {
+			return nil
+		}
+		change.Old = old.Value
+		change.Version = old.Version + 1
+	} else if value == nil {
+		return nil
+	}
+
+	var rec *Record
+	if value != nil {
+		rec = &Record{
+			Value:     value,
+			Version:   change.Version,
+			UpdatedAt: change.At,
+			UpdatedBy: change.Actor,
+		}
+	}
+
+	audit, err := s.auditChange(change)
+	if err != nil {
+		return err
+	}
+	if s.opts.Backend != nil {
+		if err := s.opts.Backend.Save(ctx, key, rec, audit); err != nil {
+			return err
+		}
+	}
+	if rec != nil {
+		err = s.records.Set(ctx, key, rec)
+	} else {
+		err = s.records.Delete(ctx, key)
+	}
+	if err != nil {
+		return err
+	}
+	if s.opts.Backend == nil {
+		if err := s.appendHistory(ctx, audit); err != nil {
+			return err
+		}
+	}
+
+	s.lock.Lock()
+	s.local[key] = rec
+	s.lock.Unlock()
+
+	s.notify(ctx, change)
+
+	buf, err := json.Marshal(changeMessage{Origin: s.id, Change: change})
+	if err != nil {
+		return err
+	}
+	return s.cache.Publish(ctx, s.name, string(buf))
+}
+
+// auditChange returns change record with scrubbing rules applied to the values.
+//
+// Setting key is used as a path of the value so rules can match both setting
+// keys and fields of the value.
+func (s *Store) auditChange(change Change) (Change, error) {
+	if !s.opts.Scrubber.Enabled() {
+		return change, nil
+	}
+	var err error
+	if change.Old, err = s.scrubValue(change.Key, change.Old); err != nil {
+		return change, err
+	}
+	if change.New, err = s.scrubValue(change.Key, change.New); err != nil {
+		return change, err
+	}
+	return change, nil
+}
+
+func (s *Store) scrubValue(key string, value json.RawMessage) (json.RawMessage, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+	var v any
+	if err := json.Unmarshal(value, &v); err != nil {
+		return nil, err
+	}
+	v, ok := s.opts.Scrubber.Any(key, v)
+	if !ok {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+func (s *Store) appendHistory(ctx context.Context, change Change) error {
+	h, err := s.history.Get(ctx, change.Key)
+	if err != nil {
+		return err
+	}
+	h = append([]Change{change}, h...)
+	if s.opts.MaxHistory > 0 && len(h) > s.opts.MaxHistory {
+		h = h[:s.opts.MaxHistory]
+	}
+	return s.history.Set(ctx, change.Key, h)
+}
+
+// History returns latest changes of the setting, newest first.
+func (s *Store) History(ctx context.Context, key string) ([]Change, error) {
+	if _, err := s.definition(key); err != nil {
+		return nil, err
+	}
+	if s.opts.Backend != nil {
+		return s.opts.Backend.History(ctx, key, s.opts.MaxHistory)
+	}
+	return s.history.Get(ctx, key)
+}
+
+// OnChange registers hook that is called when setting changes in any
+// application instance.
+func (s *Store) OnChange(key string, fn func(ctx context.Context, change Change)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.hooks[key] = append(s.hooks[key], fn)
+}
+
+func (s *Store) notify(ctx context.Context, change Change) {
+	s.lock.RLock()
+	hooks := append([]func(ctx context.Context, change Change){}, s.hooks[change.Key]...)
+	s.lock.RUnlock()
+
+	for _, fn := range hooks {
+		fn(ctx, change)
+	}
+}
+
+// Name returns task name.
+func (s *Store) Name() string {
+	return "settings-store"
+}
+
+// Start subscribes to setting changes made by other application instances.
+func (s *Store) Start(ctx context.Context) error {
+	s.lock.Lock()
+	if s.started {
+		s.lock.Unlock()
+		return nil
+	}
+	s.started = true
+	s.lock.Unlock()
+
+	unsubscribe, err := s.cache.Subscribe(ctx, s.name, func(message string) {
+		var m changeMessage
+		if err := json.Unmarshal([]byte(message), &m); err != nil {
+			s.opts.Logger.Warn("invalid settings change message", zap.Error(err))
+			return
+		}
+		if m.Origin == s.id {
+			return
+		}
+		// Value is reloaded from the cache on next read.
+		s.lock.Lock()
+		delete(s.local, m.Key)
+		s.lock.Unlock()
+
+		s.notify(ctx, m.Change)
+	})
+	if err != nil {
+		s.lock.Lock()
+		s.started = false
+		s.lock.Unlock()
+		return err
+	}
+
+	s.lock.Lock()
+	s.unsubscribe = unsubscribe
+	s.lock.Unlock()
+	return nil
+}
+
+// Stop listening for setting changes.
+func (s *Store) Stop() {
+	s.lock.Lock()
+	unsubscribe := s.unsubscribe
+	s.unsubscribe = nil
+	s.started = false
+	s.lock.Unlock()
+
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+}
+
+// Setting is a typed runtime-tunable setting.
+type Setting[T any] struct {
+	store *Store
+	key   string
+	def   T
+}
+
+// Register typed setting with the default value and optional validators.
+func Register[T any](s *Store, key string, def T, validate ...func(T) error) (*Setting[T], error) {
+	raw, err := json.Marshal(def)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.register(key, definition{
+		def: raw,
+		validate: func(raw json.RawMessage) error {
+			var v T
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return err
+			}
+			for _, fn := range validate {
+				if err := fn(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}); err != nil {
+		return nil, err
+	}
+	return &Setting[T]{store: s, key: key, def: def}, nil
+}
+
+// Key returns setting key.
+func (s *Setting[T]) Key() string {
+	return s.key
+}
+
+func (s *Setting[T]) decode(raw json.RawMessage) (T, error) {
+	if raw == nil {
+		return s.def, nil
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return s.def, ErrInvalidValue{Key: s.key, Err: err}
+	}
+	return v, nil
+}
+
+// Get returns setting value or default value if not set.
+func (s *Setting[T]) Get(ctx context.Context) (T, error) {
+	rec, err := s.store.Record(ctx, s.key)
+	if err != nil || rec == nil {
+		return s.def, err
+	}
+	return s.decode(rec.Value)
+}
+
+// Set validates and stores setting value.
+func (s *Setting[T]) Set(ctx context.Context, value T, reason string) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return ErrInvalidValue{Key: s.key, Err: err}
+	}
+	return s.store.SetValue(ctx, s.key, raw, reason)
+}
+
+// Reset setting to its default value.
+func (s *Setting[T]) Reset(ctx context.Context, reason string) error {
+	return s.store.Reset(ctx, s.key, reason)
+}
+
+// History returns latest changes of the setting, newest first.
+func (s *Setting[T]) History(ctx context.Context) ([]Change, error) {
+	return s.store.History(ctx, s.key)
+}
+
+// OnChange registers hook that is called with the old and new setting values
+// when setting changes in any application instance.
+func (s *Setting[T]) OnChange(fn func(ctx context.Context, old, new T)) {
+	s.store.OnChange(s.key, func(ctx context.Context, change Change) {
+		o, err := s.decode(change.Old)
+		if err != nil {
+			return
+		}
+		n, err := s.decode(change.New)
+		if err != nil {
+			return
+		}
+		fn(ctx, o, n)
+	})
+}
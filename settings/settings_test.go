@@ -0,0 +1,238 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/internal/cachetest"
+	"azugo.io/core/scrub"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func positive(v int) error {
+	if v <= 0 {
+		return errors.New("must be positive")
+	}
+	return nil
+}
+
+func TestSettingGetSet(t *testing.T) {
+	s, err := New(cachetest.New(t))
+	require.NoError(t, err)
+
+	limit, err := Register(s, "rate-limit", 100, positive)
+	require.NoError(t, err)
+
+	_, err = Register(s, "rate-limit", 10)
+	assert.Error(t, err)
+
+	v, err := limit.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 100, v)
+
+	ctx := WithActor(context.Background(), "admin")
+	require.NoError(t, limit.Set(ctx, 50, "load test"))
+	v, err = limit.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 50, v)
+
+	err = limit.Set(ctx, -1, "")
+	var invalid ErrInvalidValue
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "rate-limit", invalid.Key)
+
+	require.NoError(t, limit.Reset(ctx, "done"))
+	v, err = limit.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 100, v)
+
+	h, err := limit.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, h, 2)
+	assert.Equal(t, "done", h[0].Reason)
+	assert.Empty(t, h[0].New)
+	assert.Equal(t, 2, h[0].Version)
+	assert.Equal(t, "admin", h[1].Actor)
+	assert.JSONEq(t, "50", string(h[1].New))
+
+	_, err = s.Value(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrUnknownSetting)
+	assert.Equal(t, []string{"rate-limit"}, s.Keys())
+}
+
+func TestSettingPropagation(t *testing.T) {
+	srv := miniredis.RunT(t)
+	newStore := func() *Store {
+		c := cache.New(cache.CacheType(cache.RedisCache), cache.ConnectionString("redis://"+srv.Addr()))
+		require.NoError(t, c.Start(context.Background()))
+		t.Cleanup(c.Close)
+
+		s, err := New(c)
+		require.NoError(t, err)
+		require.NoError(t, s.Start(context.Background()))
+		t.Cleanup(s.Stop)
+		return s
+	}
+
+	s1, s2 := newStore(), newStore()
+	f1, err := Register(s1, "feature", false)
+	require.NoError(t, err)
+	f2, err := Register(s2, "feature", false)
+	require.NoError(t, err)
+
+	// Load value so that it is kept locally.
+	v, err := f2.Get(context.Background())
+	require.NoError(t, err)
+	assert.False(t, v)
+
+	changes := make(chan bool, 1)
+	f2.OnChange(func(ctx context.Context, old, new bool) {
+		assert.False(t, old)
+		changes <- new
+	})
+
+	require.NoError(t, f1.Set(context.Background(), true, ""))
+
+	select {
+	case v := <-changes:
+		assert.True(t, v)
+	case <-time.After(time.Second):
+		require.Fail(t, "setting change was not propagated")
+	}
+	v, err = f2.Get(context.Background())
+	require.NoError(t, err)
+	assert.True(t, v)
+}
+
+type testBackend struct {
+	lock    sync.Mutex
+	records map[string]*Record
+	changes []Change
+}
+
+func (b *testBackend) Load(ctx context.Context, key string) (*Record, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.records[key], nil
+}
+
+func (b *testBackend) Save(ctx context.Context, key string, rec *Record, change Change) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if rec == nil {
+		delete(b.records, key)
+	} else {
+		b.records[key] = rec
+	}
+	b.changes = append([]Change{change}, b.changes...)
+	return nil
+}
+
+func (b *testBackend) History(ctx context.Context, key string, limit int) ([]Change, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.changes, nil
+}
+
+func TestSettingBackend(t *testing.T) {
+	b := &testBackend{records: map[string]*Record{
+		"greeting": {Value: json.RawMessage(`"stored"`), Version: 3},
+	}}
+	s, err := New(cachetest.New(t), Storage{b})
+	require.NoError(t, err)
+
+	greeting, err := Register(s, "greeting", "hello")
+	require.NoError(t, err)
+
+	v, err := greeting.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "stored", v)
+
+	require.NoError(t, greeting.Set(context.Background(), "updated", "rename"))
+	assert.Equal(t, 4, b.records["greeting"].Version)
+
+	h, err := greeting.History(context.Background())
+	require.NoError(t, err)
+	require.Len(t, h, 1)
+	assert.JSONEq(t, `"stored"`, string(h[0].Old))
+}
+
+func TestSettingHistoryScrub(t *testing.T) {
+	type smtp struct {
+		Host     string `json:"host"`
+		Password string `json:"password"`
+	}
+
+	s, err := New(cachetest.New(t), Scrub{scrub.New(scrub.Rules{
+		{Field: "password", Action: scrub.Mask},
+		{Field: "api-key", Action: scrub.Drop},
+	})})
+	require.NoError(t, err)
+
+	mail, err := Register(s, "smtp", smtp{Host: "localhost"})
+	require.NoError(t, err)
+	key, err := Register(s, "api-key", "")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, mail.Set(ctx, smtp{Host: "mail", Password: "secret"}, ""))
+	require.NoError(t, key.Set(ctx, "abc", ""))
+
+	v, err := mail.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", v.Password)
+
+	h, err := mail.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, h, 1)
+	assert.JSONEq(t, `{"host":"mail","password":"***"}`, string(h[0].New))
+
+	h, err = key.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, h, 1)
+	assert.Empty(t, h[0].New)
+}
+
+func TestCacheModes(t *testing.T) {
+	c := cachetest.New(t, cache.ModeSwitch{})
+
+	s, err := New(c)
+	require.NoError(t, err)
+	require.NoError(t, s.Start(context.Background()))
+	t.Cleanup(s.Stop)
+
+	modes, err := RegisterCacheModes(s, c, "")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCacheModesKey, modes.Key())
+	require.NoError(t, modes.Sync(context.Background()))
+
+	users, err := cache.Create[string](c, "users")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, modes.SetMode(ctx, "users", cache.ModeReadOnly, "incident"))
+	assert.Equal(t, cache.ModeReadOnly, c.Mode("users"))
+	assert.ErrorIs(t, users.Set(ctx, "key", "value"), cache.ErrReadOnly)
+
+	require.NoError(t, modes.SetMode(ctx, "sessions", cache.ModeBypass, "incident"))
+	assert.Equal(t, map[string]cache.Mode{"users": cache.ModeReadOnly, "sessions": cache.ModeBypass}, c.Modes())
+
+	require.NoError(t, modes.SetMode(ctx, "users", cache.ModeNormal, "resolved"))
+	assert.Equal(t, map[string]cache.Mode{"sessions": cache.ModeBypass}, c.Modes())
+	require.NoError(t, users.Set(ctx, "key", "value"))
+
+	var invalid ErrInvalidValue
+	assert.ErrorAs(t, modes.SetMode(ctx, "users", cache.Mode("off"), ""), &invalid)
+	assert.ErrorAs(t, modes.SetMode(ctx, DefaultName, cache.ModeReadOnly, ""), &invalid)
+}
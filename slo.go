@@ -0,0 +1,52 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"context"
+
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/slo"
+)
+
+func (a *App) initSLO() {
+	a.slolock.Lock()
+	defer a.slolock.Unlock()
+
+	if a.slo != nil {
+		return
+	}
+
+	// Tracker without objectives can not fail to be created.
+	a.slo, _ = slo.New(slo.Instrumenter(a.Instrumenter()))
+	_ = a.AddTask(a.slo)
+	a.registerDiagnostic("slo", slo.Check(a.slo))
+}
+
+// sloInstrumenter records operations with the name prefix as the dependency
+// operations once SLO tracker is used.
+func (a *App) sloInstrumenter(dependency, prefix string) instrumenter.Instrumenter {
+	return func(ctx context.Context, op string, args ...any) func(err error) {
+		a.slolock.Lock()
+		t := a.slo
+		a.slolock.Unlock()
+
+		if t == nil {
+			return func(error) {}
+		}
+		return t.Instrumenter(dependency, prefix)(ctx, op, args...)
+	}
+}
+
+// SLO returns downstream dependency service level objective tracker.
+//
+// Application cache operations are recorded under the "cache" dependency
+// name once its objective is registered. Objective status is reported under
+// the "slo" name in the application diagnostics and tracker is added to the
+// application tasks.
+func (a *App) SLO() *slo.Tracker {
+	a.initSLO()
+	return a.slo
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package slo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"azugo.io/core/diagnostics"
+	"azugo.io/core/instrumenter"
+)
+
+// Name returns task name.
+func (t *Tracker) Name() string {
+	return "slo"
+}
+
+// Start reporting objective status periodically.
+func (t *Tracker) Start(ctx context.Context) error {
+	t.tasklock.Lock()
+	defer t.tasklock.Unlock()
+
+	if t.stop != nil || t.opts.Interval <= 0 {
+		return nil
+	}
+	stop := make(chan struct{})
+	t.stop = stop
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		tick := time.NewTicker(t.opts.Interval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-tick.C:
+				t.Report(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop reporting objective status.
+func (t *Tracker) Stop() {
+	t.tasklock.Lock()
+	if t.stop == nil {
+		t.tasklock.Unlock()
+		return
+	}
+	close(t.stop)
+	t.stop = nil
+	t.tasklock.Unlock()
+
+	t.wg.Wait()
+}
+
+// Report status and burn rates of all objectives to the instrumenter.
+//
+// Status is reported as InstrumentationStatus operation with "dependency",
+// "status" and "budget_remaining" labels, finished with an error while any
+// alert is firing. Burn rate of every window is reported as
+// InstrumentationBurnRate operation with "dependency", "window" and
+// "burn_rate" labels.
+func (t *Tracker) Report(ctx context.Context) {
+	for _, s := range t.Statuses() {
+		dep := instrumenter.Label{Name: "dependency", Value: s.Dependency}
+		for _, w := range s.Windows {
+			t.opts.Instrumenter.Observe(ctx, InstrumentationBurnRate,
+				dep,
+				instrumenter.Label{Name: "window", Value: w.Duration.String()},
+				instrumenter.Label{Name: "burn_rate", Value: strconv.FormatFloat(w.BurnRate, 'f', -1, 64)},
+			)(nil)
+		}
+
+		var err error
+		if s.Status != diagnostics.OK {
+			err = fmt.Errorf("slo: %s error budget burn rate alert is firing", s.Dependency)
+		}
+		t.opts.Instrumenter.Observe(ctx, InstrumentationStatus,
+			dep,
+			instrumenter.Label{Name: "status", Value: s.Status.String()},
+			instrumenter.Label{Name: "budget_remaining", Value: strconv.FormatFloat(s.BudgetRemaining, 'f', -1, 64)},
+		)(err)
+	}
+}
+
+// Check reports the most severe status of all objectives and includes status
+// of every objective in the details.
+func Check(t *Tracker) diagnostics.Check {
+	return func(_ context.Context) diagnostics.Result {
+		res := diagnostics.Result{
+			Status:  diagnostics.OK,
+			Details: make(map[string]any),
+		}
+		firing := make([]string, 0)
+		for _, s := range t.Statuses() {
+			res.Details[s.Dependency] = s
+			if s.Status > res.Status {
+				res.Status = s.Status
+			}
+			if s.Status != diagnostics.OK {
+				firing = append(firing, s.Dependency)
+			}
+		}
+		if len(firing) > 0 {
+			sort.Strings(firing)
+			res.Message = fmt.Sprintf("error budget burn rate alerts are firing: %v", firing)
+		}
+		return res
+	}
+}
+
+type transport struct {
+	tracker    *Tracker
+	dependency string
+	next       http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	finish := t.tracker.Observe(t.dependency)
+	resp, err := t.next.RoundTrip(req)
+	if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+		finish(fmt.Errorf("slo: %s responded with %s", t.dependency, resp.Status))
+		return resp, nil
+	}
+	finish(err)
+	return resp, err
+}
+
+// Transport wraps HTTP client transport to record outbound calls as the
+// dependency operations. Responses with 5xx status code are recorded as
+// failed operations.
+//
+// If next is nil, http.DefaultTransport is used.
+func (t *Tracker) Transport(dependency string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{
+		tracker:    t,
+		dependency: dependency,
+		next:       next,
+	}
+}
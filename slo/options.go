@@ -0,0 +1,69 @@
+package slo
+
+import (
+	"time"
+
+	"azugo.io/core/instrumenter"
+)
+
+type options struct {
+	Objectives   []Objective
+	Alerts       []Alert
+	Resolution   time.Duration
+	Interval     time.Duration
+	Instrumenter instrumenter.Instrumenter
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Resolution: DefaultResolution,
+		Interval:   DefaultInterval,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if len(opt.Alerts) == 0 {
+		opt.Alerts = DefaultAlerts
+	}
+	return opt
+}
+
+// Option for the SLO tracker.
+type Option interface {
+	apply(*options)
+}
+
+// Objectives to track.
+type Objectives []Objective
+
+func (o Objectives) apply(opts *options) {
+	opts.Objectives = append(opts.Objectives, o...)
+}
+
+// Alerts are burn rate alerts to evaluate for all objectives. Defaults to DefaultAlerts.
+type Alerts []Alert
+
+func (a Alerts) apply(o *options) {
+	o.Alerts = append(o.Alerts, a...)
+}
+
+// Resolution is a duration of a single bucket of the rolling windows.
+type Resolution time.Duration
+
+func (r Resolution) apply(o *options) {
+	o.Resolution = time.Duration(r)
+}
+
+// Interval to evaluate objectives and report their status to the instrumenter.
+type Interval time.Duration
+
+func (i Interval) apply(o *options) {
+	o.Interval = time.Duration(i)
+}
+
+// Instrumenter to report objective status and burn rates.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
@@ -0,0 +1,357 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package slo tracks service level indicators of the downstream dependencies
+// and evaluates multi-window error budget burn rate alerts.
+package slo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"azugo.io/core/diagnostics"
+	"azugo.io/core/instrumenter"
+)
+
+const (
+	// InstrumentationStatus is an instrumentation operation reporting objective status.
+	InstrumentationStatus = "slo-status"
+	// InstrumentationBurnRate is an instrumentation operation reporting objective
+	// error budget burn rate in a single window.
+	InstrumentationBurnRate = "slo-burn-rate"
+)
+
+const (
+	// DefaultResolution is a default duration of a single bucket of the rolling windows.
+	DefaultResolution = time.Minute
+	// DefaultInterval is a default interval to report objective status.
+	DefaultInterval = time.Minute
+)
+
+// DefaultAlerts are multi-window burn rate alerts recommended for 30 day SLO
+// period. Fast burn consumes 2% of the error budget in an hour and slow burn
+// consumes 5% of the error budget in six hours.
+var DefaultAlerts = []Alert{
+	{Long: time.Hour, Short: 5 * time.Minute, BurnRate: 14.4, Status: diagnostics.Critical},
+	{Long: 6 * time.Hour, Short: 30 * time.Minute, BurnRate: 6, Status: diagnostics.Warning},
+}
+
+// ErrInvalidObjective is returned when objective is not valid.
+var ErrInvalidObjective = errors.New("slo: invalid objective")
+
+// Objective of the downstream dependency.
+//
+// Event is good when operation succeeded within the latency threshold.
+type Objective struct {
+	// Dependency name (for example "cache" or "payments-api").
+	Dependency string
+	// Target ratio of good events in range 0 to 1 exclusive (for example 0.999).
+	Target float64
+	// Latency threshold. Slower operations are bad events. Zero means that
+	// only failed operations are bad events.
+	Latency time.Duration
+	// MinEvents is a minimum number of events in the window to evaluate alerts
+	// so that few failures do not trigger alerts on low traffic.
+	MinEvents uint64
+}
+
+func (o Objective) validate() error {
+	if len(o.Dependency) == 0 {
+		return fmt.Errorf("%w: dependency is required", ErrInvalidObjective)
+	}
+	if o.Target <= 0 || o.Target >= 1 {
+		return fmt.Errorf("%w %q: target must be in range 0 to 1 exclusive", ErrInvalidObjective, o.Dependency)
+	}
+	if o.Latency < 0 {
+		return fmt.Errorf("%w %q: latency must not be negative", ErrInvalidObjective, o.Dependency)
+	}
+	return nil
+}
+
+// Alert is a multi-window burn rate alert. Alert fires when error budget burn
+// rate in both long and short windows reaches the threshold. Short window
+// allows alert to reset quickly after dependency recovers.
+type Alert struct {
+	// Long window.
+	Long time.Duration
+	// Short window.
+	Short time.Duration
+	// BurnRate threshold. Burn rate of 1 consumes exactly the whole error
+	// budget over the SLO period.
+	BurnRate float64
+	// Status reported while alert fires.
+	Status diagnostics.Status
+}
+
+// Window is a service level indicator of the rolling window.
+type Window struct {
+	// Duration of the window.
+	Duration time.Duration `json:"duration"`
+	// Total number of events.
+	Total uint64 `json:"total"`
+	// Bad number of events.
+	Bad uint64 `json:"bad"`
+	// SLI is a ratio of good events. It is 1 if there were no events.
+	SLI float64 `json:"sli"`
+	// BurnRate is a rate of error budget consumption.
+	BurnRate float64 `json:"burn_rate"`
+}
+
+// Status of the objective.
+type Status struct {
+	// Dependency name.
+	Dependency string `json:"dependency"`
+	// Target ratio of good events.
+	Target float64 `json:"target"`
+	// Status is the most severe firing alert status.
+	Status diagnostics.Status `json:"status"`
+	// Alerts that are firing.
+	Alerts []Alert `json:"-"`
+	// Windows are indicators of all alert windows sorted by duration.
+	Windows []Window `json:"windows"`
+	// BudgetRemaining is a ratio of error budget remaining in the longest window.
+	// It is negative when budget is exhausted.
+	BudgetRemaining float64 `json:"budget_remaining"`
+}
+
+// Window returns indicator of the window by duration.
+func (s Status) Window(d time.Duration) (Window, bool) {
+	for _, w := range s.Windows {
+		if w.Duration == d {
+			return w, true
+		}
+	}
+	return Window{}, false
+}
+
+type bucket struct {
+	index int64
+	total uint64
+	bad   uint64
+}
+
+// series of the objective events in the ring of buckets.
+type series struct {
+	objective Objective
+	buckets   []bucket
+}
+
+func (s *series) add(index int64, bad bool) {
+	b := &s.buckets[int(index%int64(len(s.buckets)))]
+	if b.index != index {
+		*b = bucket{index: index}
+	}
+	b.total++
+	if bad {
+		b.bad++
+	}
+}
+
+// window returns indicator of n latest buckets ending with the bucket index.
+func (s *series) window(index int64, n int, d time.Duration) Window {
+	w := Window{Duration: d, SLI: 1}
+	for i := index - int64(n) + 1; i <= index; i++ {
+		b := s.buckets[int(i%int64(len(s.buckets)))]
+		if b.index == i {
+			w.Total += b.total
+			w.Bad += b.bad
+		}
+	}
+	if w.Total > 0 {
+		bad := float64(w.Bad) / float64(w.Total)
+		w.SLI = 1 - bad
+		w.BurnRate = bad / (1 - s.objective.Target)
+	}
+	return w
+}
+
+// Tracker records events of the downstream dependencies and evaluates their
+// service level objectives.
+//
+// Tracker implements core.Tasker interface to periodically report objective
+// status and burn rates to the instrumenter so that alerting rules can be
+// driven by the application metrics.
+type Tracker struct {
+	opts    *options
+	windows []time.Duration
+	now     func() time.Time
+
+	lock   sync.RWMutex
+	series map[string]*series
+
+	tasklock sync.Mutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New creates SLO tracker.
+func New(opts ...Option) (*Tracker, error) {
+	opt := newOptions(opts...)
+	if opt.Resolution <= 0 {
+		return nil, errors.New("slo: resolution must be positive")
+	}
+
+	windows := make([]time.Duration, 0, 2*len(opt.Alerts))
+	seen := make(map[time.Duration]bool, 2*len(opt.Alerts))
+	for _, a := range opt.Alerts {
+		if a.Long < a.Short || a.Short < opt.Resolution || a.BurnRate <= 0 {
+			return nil, fmt.Errorf("slo: invalid alert windows %s/%s with burn rate %v", a.Long, a.Short, a.BurnRate)
+		}
+		for _, d := range []time.Duration{a.Short, a.Long} {
+			if !seen[d] {
+				seen[d] = true
+				windows = append(windows, d)
+			}
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+
+	t := &Tracker{
+		opts:    opt,
+		windows: windows,
+		now:     time.Now,
+		series:  make(map[string]*series, len(opt.Objectives)),
+	}
+	for _, o := range opt.Objectives {
+		if err := t.Register(o); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// buckets returns number of buckets in the window.
+func (t *Tracker) buckets(d time.Duration) int {
+	return int((d + t.opts.Resolution - 1) / t.opts.Resolution)
+}
+
+func (t *Tracker) index(now time.Time) int64 {
+	return now.UnixNano() / int64(t.opts.Resolution)
+}
+
+// Register objective of the dependency. Objective of the same dependency is
+// replaced and its recorded events are discarded.
+func (t *Tracker) Register(o Objective) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+	n := 1
+	if len(t.windows) > 0 {
+		n = t.buckets(t.windows[len(t.windows)-1])
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.series[o.Dependency] = &series{
+		objective: o,
+		buckets:   make([]bucket, n),
+	}
+	return nil
+}
+
+// Unregister objective of the dependency.
+func (t *Tracker) Unregister(dependency string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.series, dependency)
+}
+
+// Record operation of the dependency. Operations of the dependencies without
+// registered objective are ignored.
+func (t *Tracker) Record(dependency string, d time.Duration, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s, ok := t.series[dependency]
+	if !ok {
+		return
+	}
+	bad := err != nil || (s.objective.Latency > 0 && d > s.objective.Latency)
+	s.add(t.index(t.now()), bad)
+}
+
+// Observe starts operation of the dependency. Returned function must be called
+// when operation has finished.
+func (t *Tracker) Observe(dependency string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		t.Record(dependency, time.Since(start), err)
+	}
+}
+
+// Instrumenter returns instrumenter that records operations with the name
+// prefix as the dependency operations. Empty prefix records all operations.
+func (t *Tracker) Instrumenter(dependency, prefix string) instrumenter.Instrumenter {
+	return func(_ context.Context, op string, _ ...any) func(err error) {
+		if !strings.HasPrefix(op, prefix) {
+			return func(error) {}
+		}
+		return t.Observe(dependency)
+	}
+}
+
+// Status returns current status of the dependency objective.
+func (t *Tracker) Status(dependency string) (Status, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	s, ok := t.series[dependency]
+	if !ok {
+		return Status{}, false
+	}
+	return t.status(s, t.index(t.now())), true
+}
+
+// Statuses returns current status of all objectives sorted by dependency name.
+func (t *Tracker) Statuses() []Status {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	index := t.index(t.now())
+	res := make([]Status, 0, len(t.series))
+	for _, s := range t.series {
+		res = append(res, t.status(s, index))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Dependency < res[j].Dependency })
+	return res
+}
+
+func (t *Tracker) status(s *series, index int64) Status {
+	st := Status{
+		Dependency:      s.objective.Dependency,
+		Target:          s.objective.Target,
+		Status:          diagnostics.OK,
+		Windows:         make([]Window, 0, len(t.windows)),
+		BudgetRemaining: 1,
+	}
+	byDuration := make(map[time.Duration]Window, len(t.windows))
+	for _, d := range t.windows {
+		w := s.window(index, t.buckets(d), d)
+		st.Windows = append(st.Windows, w)
+		byDuration[d] = w
+	}
+	if len(st.Windows) > 0 {
+		st.BudgetRemaining = 1 - st.Windows[len(st.Windows)-1].BurnRate
+	}
+	for _, a := range t.opts.Alerts {
+		long, short := byDuration[a.Long], byDuration[a.Short]
+		if long.Total < s.objective.MinEvents || short.Total < s.objective.MinEvents {
+			continue
+		}
+		if long.BurnRate >= a.BurnRate && short.BurnRate >= a.BurnRate {
+			st.Alerts = append(st.Alerts, a)
+			if a.Status > st.Status {
+				st.Status = a.Status
+			}
+		}
+	}
+	return st
+}
@@ -0,0 +1,238 @@
+package slo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"azugo.io/core/diagnostics"
+	"azugo.io/core/instrumenter"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTracker(t *testing.T, opts ...Option) (*Tracker, *time.Time) {
+	t.Helper()
+
+	tr, err := New(opts...)
+	require.NoError(t, err)
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time { return now }
+	return tr, &now
+}
+
+func record(t *Tracker, dep string, good, bad int) {
+	for i := 0; i < good; i++ {
+		t.Record(dep, time.Millisecond, nil)
+	}
+	for i := 0; i < bad; i++ {
+		t.Record(dep, time.Millisecond, errors.New("failed"))
+	}
+}
+
+func TestTrackerBurnRate(t *testing.T) {
+	tr, now := newTestTracker(t, Objectives{{Dependency: "cache", Target: 0.99}})
+
+	record(tr, "cache", 80, 20)
+	s, ok := tr.Status("cache")
+	require.True(t, ok)
+	assert.Equal(t, diagnostics.Critical, s.Status)
+	require.Len(t, s.Alerts, 2)
+	assert.Len(t, s.Windows, 4)
+
+	w, ok := s.Window(5 * time.Minute)
+	require.True(t, ok)
+	assert.Equal(t, uint64(100), w.Total)
+	assert.Equal(t, uint64(20), w.Bad)
+	assert.InDelta(t, 0.8, w.SLI, 1e-9)
+	assert.InDelta(t, 20, w.BurnRate, 1e-9)
+	assert.InDelta(t, -19, s.BudgetRemaining, 1e-9)
+
+	// Short window resets fast burn alert after dependency recovers while
+	// slow burn alert is still firing.
+	*now = now.Add(10 * time.Minute)
+	record(tr, "cache", 100, 0)
+	s, _ = tr.Status("cache")
+	assert.Equal(t, diagnostics.Warning, s.Status)
+	w, _ = s.Window(time.Hour)
+	assert.InDelta(t, 10, w.BurnRate, 1e-9)
+
+	*now = now.Add(time.Hour)
+	record(tr, "cache", 100, 0)
+	s, _ = tr.Status("cache")
+	assert.Equal(t, diagnostics.OK, s.Status)
+	assert.Empty(t, s.Alerts)
+
+	// Events older than the longest window are discarded.
+	*now = now.Add(7 * time.Hour)
+	s, _ = tr.Status("cache")
+	assert.Equal(t, diagnostics.OK, s.Status)
+	for _, w := range s.Windows {
+		assert.Zero(t, w.Total)
+		assert.Equal(t, float64(1), w.SLI)
+	}
+	assert.Equal(t, float64(1), s.BudgetRemaining)
+}
+
+func TestTrackerLatency(t *testing.T) {
+	tr, _ := newTestTracker(t, Objectives{{Dependency: "db", Target: 0.99, Latency: 100 * time.Millisecond, MinEvents: 10}})
+
+	tr.Record("db", 200*time.Millisecond, nil)
+	s, _ := tr.Status("db")
+	w, _ := s.Window(5 * time.Minute)
+	assert.Equal(t, uint64(1), w.Bad)
+	// Not enough events to evaluate alerts.
+	assert.Equal(t, diagnostics.OK, s.Status)
+
+	for i := 0; i < 9; i++ {
+		tr.Record("db", 200*time.Millisecond, nil)
+	}
+	s, _ = tr.Status("db")
+	assert.Equal(t, diagnostics.Critical, s.Status)
+
+	tr.Record("unknown", time.Second, errors.New("failed"))
+	_, ok := tr.Status("unknown")
+	assert.False(t, ok)
+
+	tr.Unregister("db")
+	assert.Empty(t, tr.Statuses())
+}
+
+func TestTrackerValidation(t *testing.T) {
+	_, err := New(Objectives{{Dependency: "cache", Target: 1}})
+	assert.ErrorIs(t, err, ErrInvalidObjective)
+
+	_, err = New(Alerts{{Long: time.Minute, Short: time.Hour, BurnRate: 1}})
+	assert.Error(t, err)
+
+	_, err = New(Resolution(time.Hour), Alerts{{Long: time.Hour, Short: time.Minute, BurnRate: 1}})
+	assert.Error(t, err)
+
+	tr, err := New(Alerts{{Long: 10 * time.Minute, Short: time.Minute, BurnRate: 2, Status: diagnostics.Warning}})
+	require.NoError(t, err)
+	assert.ErrorIs(t, tr.Register(Objective{Target: 0.9}), ErrInvalidObjective)
+	require.NoError(t, tr.Register(Objective{Dependency: "api", Target: 0.9}))
+
+	record(tr, "api", 0, 1)
+	s, _ := tr.Status("api")
+	assert.Equal(t, diagnostics.Warning, s.Status)
+	assert.Len(t, s.Windows, 2)
+}
+
+func TestTrackerReport(t *testing.T) {
+	var lock sync.Mutex
+	reported := make(map[string][]instrumenter.Label)
+	failed := make(map[string]bool)
+	instr := func(_ context.Context, op string, args ...any) func(err error) {
+		return func(err error) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			key := op
+			for _, a := range args {
+				l := a.(instrumenter.Label)
+				if l.Name == "window" {
+					key += ":" + l.Value
+				}
+			}
+			labels := make([]instrumenter.Label, 0, len(args))
+			for _, a := range args {
+				labels = append(labels, a.(instrumenter.Label))
+			}
+			reported[key] = labels
+			failed[key] = err != nil
+		}
+	}
+
+	tr, _ := newTestTracker(t, Objectives{{Dependency: "cache", Target: 0.99}}, Instrumenter(instr))
+	record(tr, "cache", 0, 1)
+	tr.Report(context.TODO())
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	assert.Len(t, reported, 5)
+	assert.Contains(t, reported[InstrumentationStatus], instrumenter.Label{Name: "status", Value: "critical"})
+	assert.Contains(t, reported[InstrumentationStatus], instrumenter.Label{Name: "dependency", Value: "cache"})
+	assert.True(t, failed[InstrumentationStatus])
+	assert.Contains(t, reported[InstrumentationBurnRate+":1h0m0s"], instrumenter.Label{Name: "window", Value: "1h0m0s"})
+	assert.False(t, failed[InstrumentationBurnRate+":1h0m0s"])
+}
+
+func TestTrackerTask(t *testing.T) {
+	var lock sync.Mutex
+	var reports int
+	instr := func(_ context.Context, op string, _ ...any) func(err error) {
+		if op == InstrumentationStatus {
+			lock.Lock()
+			reports++
+			lock.Unlock()
+		}
+		return func(error) {}
+	}
+	tr, _ := newTestTracker(t, Objectives{{Dependency: "cache", Target: 0.99}}, Interval(10*time.Millisecond), Instrumenter(instr))
+	assert.Equal(t, "slo", tr.Name())
+
+	require.NoError(t, tr.Start(context.Background()))
+	require.NoError(t, tr.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return reports >= 2
+	}, time.Second, 10*time.Millisecond)
+	tr.Stop()
+	tr.Stop()
+}
+
+func TestCheck(t *testing.T) {
+	tr, _ := newTestTracker(t, Objectives{{Dependency: "cache", Target: 0.99}, {Dependency: "db", Target: 0.99}})
+	record(tr, "cache", 100, 0)
+
+	res := Check(tr)(context.TODO())
+	assert.Equal(t, diagnostics.OK, res.Status)
+	assert.Contains(t, res.Details, "db")
+
+	record(tr, "db", 0, 10)
+	res = Check(tr)(context.TODO())
+	assert.Equal(t, diagnostics.Critical, res.Status)
+	assert.Contains(t, res.Message, "db")
+	assert.NotContains(t, res.Message, "cache")
+}
+
+func TestInstrumenterAndTransport(t *testing.T) {
+	tr, _ := newTestTracker(t, Objectives{{Dependency: "cache", Target: 0.99}, {Dependency: "api", Target: 0.99}})
+
+	instr := tr.Instrumenter("cache", "cache-")
+	instr.Observe(context.TODO(), "cache-get")(nil)
+	instr.Observe(context.TODO(), "cache-set")(errors.New("failed"))
+	instr.Observe(context.TODO(), "queue-publish")(nil)
+	s, _ := tr.Status("cache")
+	w, _ := s.Window(5 * time.Minute)
+	assert.Equal(t, uint64(2), w.Total)
+	assert.Equal(t, uint64(1), w.Bad)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: tr.Transport("api", nil)}
+	for _, p := range []string{"/ok", "/fail"} {
+		resp, err := client.Get(srv.URL + p)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	s, _ = tr.Status("api")
+	w, _ = s.Window(5 * time.Minute)
+	assert.Equal(t, uint64(2), w.Total)
+	assert.Equal(t, uint64(1), w.Bad)
+}
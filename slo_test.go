@@ -0,0 +1,41 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"azugo.io/core/diagnostics"
+	"azugo.io/core/slo"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLO(t *testing.T) {
+	a, cleanup, _, err := newTestApp()
+	require.NoError(t, err)
+	t.Cleanup(cleanup)
+
+	require.NoError(t, a.Start())
+
+	require.NoError(t, a.SLO().Register(slo.Objective{Dependency: "cache", Target: 0.99}))
+	require.NoError(t, a.SLO().Register(slo.Objective{Dependency: "api", Target: 0.99}))
+
+	require.NoError(t, a.Cache().Ping(context.TODO()))
+	s, ok := a.SLO().Status("cache")
+	require.True(t, ok)
+	w, _ := s.Window(5 * time.Minute)
+	assert.Equal(t, uint64(1), w.Total)
+
+	for i := 0; i < 10; i++ {
+		a.SLO().Record("api", time.Millisecond, errors.New("failed"))
+	}
+
+	report := a.Diagnostics(context.TODO())
+	res, ok := report.Result("slo")
+	require.True(t, ok)
+	assert.Equal(t, diagnostics.Critical, res.Status)
+	assert.Contains(t, res.Details, "cache")
+}
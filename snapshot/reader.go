@@ -0,0 +1,239 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// Reader reads snapshot entries.
+type Reader struct {
+	r      *bufio.Reader
+	header Header
+	crc    hash.Hash32
+	count  uint64
+	done   bool
+}
+
+// unexpected converts unexpected end of input to ErrTruncated.
+func unexpected(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
+
+// NewReader reads and validates snapshot header from r and returns reader
+// for the entries.
+func NewReader(r io.Reader) (*Reader, error) {
+	pre := make([]byte, 10)
+	if _, err := io.ReadFull(r, pre); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrInvalidFormat
+		}
+		return nil, err
+	}
+	if !bytes.Equal(pre[:4], magic[:]) {
+		return nil, ErrInvalidFormat
+	}
+	v := Version{Major: int(pre[4]), Minor: int(pre[5])}
+	if v.Major != Major {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedVersion, v)
+	}
+	n := binary.BigEndian.Uint32(pre[6:])
+	if n > maxRecordSize {
+		return nil, ErrInvalidFormat
+	}
+	hbuf := make([]byte, n+4)
+	if _, err := io.ReadFull(r, hbuf); err != nil {
+		return nil, unexpected(err)
+	}
+	if crc32.Checksum(hbuf[:n], castagnoli) != binary.BigEndian.Uint32(hbuf[n:]) {
+		return nil, fmt.Errorf("%w: header", ErrChecksum)
+	}
+
+	sr := &Reader{
+		crc: crc32.New(castagnoli),
+	}
+	if err := json.Unmarshal(hbuf[:n], &sr.header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	sr.header.Version = v
+	for _, f := range sr.header.Required {
+		if !features[f] {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedFeature, f)
+		}
+	}
+
+	switch sr.header.Compression {
+	case NoCompression:
+	case Gzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		r = gz
+	default:
+		return nil, fmt.Errorf("%w: compression %q", ErrUnsupportedFeature, sr.header.Compression)
+	}
+	sr.r = bufio.NewReader(r)
+	return sr, nil
+}
+
+// Header returns snapshot header.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// record reads next record.
+func (r *Reader) record() ([]byte, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	if n == 0 || n > maxRecordSize {
+		return nil, ErrInvalidFormat
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return nil, unexpected(err)
+	}
+	return buf, nil
+}
+
+// hashRecord adds record with its length prefix to the checksum.
+func (r *Reader) hashRecord(rec []byte) {
+	_, _ = r.crc.Write(binary.AppendUvarint(nil, uint64(len(rec))))
+	_, _ = r.crc.Write(rec)
+}
+
+// Next returns next entry of the snapshot.
+//
+// Returns io.EOF after the last entry when snapshot trailer has been
+// validated.
+func (r *Reader) Next() (Entry, error) {
+	for {
+		if r.done {
+			return Entry{}, io.EOF
+		}
+		rec, err := r.record()
+		if err != nil {
+			return Entry{}, err
+		}
+
+		switch rec[0] {
+		case recordEnd:
+			return Entry{}, r.end(rec[1:])
+		case recordEntry:
+			r.hashRecord(rec)
+			e, err := parseEntry(rec[1:])
+			if err != nil {
+				return Entry{}, err
+			}
+			r.count++
+			return e, nil
+		default:
+			// Unknown record kinds of newer minor versions are skipped.
+			r.hashRecord(rec)
+		}
+	}
+}
+
+// end validates snapshot trailer.
+func (r *Reader) end(rec []byte) error {
+	if len(rec) < 12 {
+		return ErrInvalidFormat
+	}
+	if binary.BigEndian.Uint32(rec[8:]) != r.crc.Sum32() {
+		return ErrChecksum
+	}
+	if count := binary.BigEndian.Uint64(rec); count != r.count {
+		return fmt.Errorf("%w: expected %d entries, read %d", ErrChecksum, count, r.count)
+	}
+	r.done = true
+	return io.EOF
+}
+
+func parseEntry(rec []byte) (Entry, error) {
+	br := bytes.NewReader(rec)
+
+	klen, err := binary.ReadUvarint(br)
+	if err != nil || klen > uint64(br.Len()) {
+		return Entry{}, ErrInvalidFormat
+	}
+	key := make([]byte, klen)
+	_, _ = br.Read(key)
+
+	exp, err := binary.ReadVarint(br)
+	if err != nil {
+		return Entry{}, ErrInvalidFormat
+	}
+
+	vlen, err := binary.ReadUvarint(br)
+	if err != nil || vlen > uint64(br.Len()) {
+		return Entry{}, ErrInvalidFormat
+	}
+	value := make([]byte, vlen)
+	_, _ = br.Read(value)
+
+	// Remaining fields of newer minor versions are ignored.
+	e := Entry{
+		Key:   string(key),
+		Value: value,
+	}
+	if exp != 0 {
+		e.ExpiresAt = time.UnixMilli(exp)
+	}
+	return e, nil
+}
+
+// Info is a result of the snapshot validation.
+type Info struct {
+	Header
+	// Entries is a number of entries in the snapshot.
+	Entries int
+	// Expired is a number of entries that have already expired.
+	Expired int
+	// Size is a total size of the entry values in bytes.
+	Size int64
+}
+
+// Validate reads whole snapshot verifying its format and checksums and
+// returns information about its contents.
+func Validate(r io.Reader) (*Info, error) {
+	sr, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{
+		Header: sr.Header(),
+	}
+	now := time.Now()
+	for {
+		e, err := sr.Next()
+		if errors.Is(err, io.EOF) {
+			return info, nil
+		}
+		if err != nil {
+			return info, err
+		}
+		info.Entries++
+		info.Size += int64(len(e.Value))
+		if e.Expired(now) {
+			info.Expired++
+		}
+	}
+}
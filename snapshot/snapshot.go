@@ -0,0 +1,137 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package snapshot implements versioned binary key-value snapshot format shared
+// by cache dumps, memory cache persistence and backups.
+//
+// Snapshot consists of the fixed preamble, header, records and trailer:
+//
+//	magic    "AZKV"
+//	major    uint8, incompatible format changes
+//	minor    uint8, backward compatible format changes
+//	length   uint32, header length
+//	header   JSON encoded Header
+//	checksum uint32, CRC-32C of the header
+//	body     records, compressed as declared in the header
+//
+// Every record is prefixed with its length as uvarint and starts with the record
+// kind byte. Entry record contains key, expiration and value. End record contains
+// number of entries and CRC-32C of all preceding records.
+//
+// Compatibility guarantees:
+//   - readers reject snapshots with different major version;
+//   - readers accept snapshots with any minor version, unknown header fields,
+//     unknown record kinds and unknown trailing fields of the records are skipped;
+//   - writers only add new header fields, record kinds or trailing record
+//     fields in new minor versions;
+//   - features that readers must understand to read snapshot correctly are
+//     listed in Header.Required and readers reject snapshots with unknown
+//     required features.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+)
+
+const (
+	// Major is a major version of the snapshot format written.
+	Major = 1
+	// Minor is a minor version of the snapshot format written.
+	Minor = 0
+)
+
+var magic = [4]byte{'A', 'Z', 'K', 'V'}
+
+// Record kinds.
+const (
+	recordEnd   byte = 0
+	recordEntry byte = 1
+)
+
+// maxRecordSize limits record size to protect against corrupted snapshots.
+const maxRecordSize = 1 << 30
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// ErrInvalidFormat is returned when data is not a snapshot.
+	ErrInvalidFormat = errors.New("snapshot: invalid format")
+	// ErrUnsupportedVersion is returned when snapshot major version is not supported.
+	ErrUnsupportedVersion = errors.New("snapshot: unsupported version")
+	// ErrUnsupportedFeature is returned when snapshot requires unknown feature.
+	ErrUnsupportedFeature = errors.New("snapshot: unsupported feature")
+	// ErrChecksum is returned when snapshot checksum does not match.
+	ErrChecksum = errors.New("snapshot: checksum mismatch")
+	// ErrTruncated is returned when snapshot ends unexpectedly.
+	ErrTruncated = errors.New("snapshot: truncated")
+)
+
+// Compression of the snapshot body.
+type Compression string
+
+const (
+	// NoCompression stores records uncompressed.
+	NoCompression Compression = ""
+	// Gzip compresses records using gzip.
+	Gzip Compression = "gzip"
+)
+
+// features are required features supported by this reader.
+var features = map[string]bool{}
+
+// Version of the snapshot format.
+type Version struct {
+	Major int
+	Minor int
+}
+
+// String returns version in major.minor format.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Header describes snapshot contents.
+type Header struct {
+	// Version of the snapshot format. Set when snapshot is read.
+	Version Version `json:"-"`
+	// Created is a time snapshot was created. Defaults to the current time.
+	Created time.Time `json:"created"`
+	// Source describes origin of the snapshot, for example cache instance name.
+	Source string `json:"source,omitempty"`
+	// Serializer is a content type of the serializer values are encoded with.
+	Serializer string `json:"serializer,omitempty"`
+	// Compression of the snapshot body.
+	Compression Compression `json:"compression,omitempty"`
+	// Required are features readers must support to read the snapshot.
+	Required []string `json:"required,omitempty"`
+	// Metadata are additional application defined properties.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Entry is a key-value entry of the snapshot.
+type Entry struct {
+	// Key of the entry.
+	Key string
+	// Value is an encoded value of the entry.
+	Value []byte
+	// ExpiresAt is a time entry expires or zero time if it does not expire.
+	ExpiresAt time.Time
+}
+
+// Expired returns true if entry has expired at the time.
+func (e *Entry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !e.ExpiresAt.After(now)
+}
+
+// TTL returns remaining time to live of the entry at the time or zero if
+// entry does not expire.
+func (e *Entry) TTL(now time.Time) time.Duration {
+	if e.ExpiresAt.IsZero() {
+		return 0
+	}
+	return e.ExpiresAt.Sub(now)
+}
@@ -0,0 +1,176 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSnapshot(t *testing.T, h Header, entries ...Entry) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, h)
+	require.NoError(t, err)
+	for _, e := range entries {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func readSnapshot(t *testing.T, data []byte) (Header, []Entry) {
+	t.Helper()
+
+	r, err := NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	var entries []Entry
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		entries = append(entries, e)
+	}
+	return r.Header(), entries
+}
+
+func TestRoundTrip(t *testing.T) {
+	exp := time.UnixMilli(1900000000000)
+	entries := []Entry{
+		{Key: "a", Value: []byte("1")},
+		{Key: "b", Value: []byte("2"), ExpiresAt: exp},
+		{Key: "empty", Value: []byte{}},
+	}
+
+	for _, c := range []Compression{NoCompression, Gzip} {
+		t.Run(string(c), func(t *testing.T) {
+			data := writeSnapshot(t, Header{
+				Source:      "test",
+				Serializer:  "application/json",
+				Compression: c,
+				Metadata:    map[string]string{"app": "core"},
+			}, entries...)
+
+			h, read := readSnapshot(t, data)
+			assert.Equal(t, Version{Major: Major, Minor: Minor}, h.Version)
+			assert.Equal(t, "test", h.Source)
+			assert.Equal(t, "application/json", h.Serializer)
+			assert.Equal(t, c, h.Compression)
+			assert.Equal(t, "core", h.Metadata["app"])
+			assert.False(t, h.Created.IsZero())
+
+			require.Len(t, read, 3)
+			assert.Equal(t, "a", read[0].Key)
+			assert.Equal(t, []byte("1"), read[0].Value)
+			assert.True(t, read[0].ExpiresAt.IsZero())
+			assert.True(t, exp.Equal(read[1].ExpiresAt))
+			assert.Empty(t, read[2].Value)
+
+			info, err := Validate(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, 3, info.Entries)
+			assert.Equal(t, int64(2), info.Size)
+		})
+	}
+}
+
+func TestEntryTTL(t *testing.T) {
+	now := time.Now()
+	e := Entry{ExpiresAt: now.Add(time.Minute)}
+	assert.Equal(t, time.Minute, e.TTL(now))
+	assert.False(t, e.Expired(now))
+	assert.True(t, e.Expired(now.Add(time.Minute)))
+
+	e = Entry{}
+	assert.Equal(t, time.Duration(0), e.TTL(now))
+	assert.False(t, e.Expired(now))
+}
+
+func TestValidateErrors(t *testing.T) {
+	data := writeSnapshot(t, Header{}, Entry{Key: "a", Value: []byte("value")})
+
+	_, err := Validate(bytes.NewReader([]byte("not a snapshot")))
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+
+	_, err = Validate(bytes.NewReader(data[:len(data)-3]))
+	assert.ErrorIs(t, err, ErrTruncated)
+
+	corrupted := append([]byte{}, data...)
+	corrupted[bytes.LastIndex(corrupted, []byte("value"))] ^= 0xff
+	_, err = Validate(bytes.NewReader(corrupted))
+	assert.ErrorIs(t, err, ErrChecksum)
+
+	corrupted = append([]byte{}, data...)
+	corrupted[12] ^= 0xff
+	_, err = Validate(bytes.NewReader(corrupted))
+	assert.ErrorIs(t, err, ErrChecksum)
+
+	newer := append([]byte{}, data...)
+	newer[4] = Major + 1
+	_, err = Validate(bytes.NewReader(newer))
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	_, err = NewWriter(&bytes.Buffer{}, Header{Compression: "lz4"})
+	assert.ErrorIs(t, err, ErrUnsupportedFeature)
+}
+
+// rawSnapshot builds snapshot of the future minor version from raw header and records.
+func rawSnapshot(header string, records ...[]byte) []byte {
+	tbl := crc32.MakeTable(crc32.Castagnoli)
+
+	buf := append([]byte{}, magic[:]...)
+	buf = append(buf, Major, Minor+1)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(header)))
+	buf = append(buf, header...)
+	buf = binary.BigEndian.AppendUint32(buf, crc32.Checksum([]byte(header), tbl))
+
+	crc := crc32.New(tbl)
+	var count uint64
+	for _, rec := range records {
+		r := binary.AppendUvarint(nil, uint64(len(rec)))
+		r = append(r, rec...)
+		_, _ = crc.Write(r)
+		buf = append(buf, r...)
+		if rec[0] == recordEntry {
+			count++
+		}
+	}
+	end := []byte{recordEnd}
+	end = binary.BigEndian.AppendUint64(end, count)
+	end = binary.BigEndian.AppendUint32(end, crc.Sum32())
+	buf = binary.AppendUvarint(buf, uint64(len(end)))
+	return append(buf, end...)
+}
+
+func TestForwardCompatibility(t *testing.T) {
+	// Entry with additional trailing field.
+	entry := []byte{recordEntry}
+	entry = binary.AppendUvarint(entry, 3)
+	entry = append(entry, "key"...)
+	entry = binary.AppendVarint(entry, 0)
+	entry = binary.AppendUvarint(entry, 5)
+	entry = append(entry, "value"...)
+	entry = append(entry, 0x42, 0x42)
+
+	data := rawSnapshot(`{"created":"2024-01-01T00:00:00Z","future":{"x":1}}`,
+		[]byte{0x7f, 1, 2, 3},
+		entry,
+	)
+
+	h, entries := readSnapshot(t, data)
+	assert.Equal(t, Version{Major: Major, Minor: Minor + 1}, h.Version)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "key", entries[0].Key)
+	assert.Equal(t, []byte("value"), entries[0].Value)
+
+	_, err := NewReader(bytes.NewReader(rawSnapshot(`{"required":["encryption"]}`)))
+	assert.ErrorIs(t, err, ErrUnsupportedFeature)
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package snapshot
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// Writer writes snapshot.
+type Writer struct {
+	w      io.Writer
+	gz     *gzip.Writer
+	crc    hash.Hash32
+	count  uint64
+	buf    []byte
+	closed bool
+}
+
+// NewWriter writes snapshot header to w and returns writer for the entries.
+//
+// Writer must be closed to write snapshot trailer. Closing writer does not close w.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	if h.Created.IsZero() {
+		h.Created = time.Now()
+	}
+	h.Created = h.Created.UTC()
+
+	switch h.Compression {
+	case NoCompression, Gzip:
+	default:
+		return nil, fmt.Errorf("%w: compression %q", ErrUnsupportedFeature, h.Compression)
+	}
+
+	hbuf, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+
+	pre := make([]byte, 0, 10+len(hbuf)+4)
+	pre = append(pre, magic[:]...)
+	pre = append(pre, Major, Minor)
+	pre = binary.BigEndian.AppendUint32(pre, uint32(len(hbuf)))
+	pre = append(pre, hbuf...)
+	pre = binary.BigEndian.AppendUint32(pre, crc32.Checksum(hbuf, castagnoli))
+	if _, err := w.Write(pre); err != nil {
+		return nil, err
+	}
+
+	sw := &Writer{
+		w:   w,
+		crc: crc32.New(castagnoli),
+	}
+	if h.Compression == Gzip {
+		sw.gz = gzip.NewWriter(w)
+		sw.w = sw.gz
+	}
+	return sw, nil
+}
+
+// record writes record with the length prefix.
+func (w *Writer) record(data []byte) error {
+	buf := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(data)), uint64(len(data)))
+	buf = append(buf, data...)
+	_, _ = w.crc.Write(buf)
+	_, err := w.w.Write(buf)
+	return err
+}
+
+// Write entry to the snapshot.
+func (w *Writer) Write(e Entry) error {
+	if w.closed {
+		return errors.New("snapshot: writer is closed")
+	}
+	var exp int64
+	if !e.ExpiresAt.IsZero() {
+		exp = e.ExpiresAt.UnixMilli()
+	}
+
+	buf := w.buf[:0]
+	buf = append(buf, recordEntry)
+	buf = binary.AppendUvarint(buf, uint64(len(e.Key)))
+	buf = append(buf, e.Key...)
+	buf = binary.AppendVarint(buf, exp)
+	buf = binary.AppendUvarint(buf, uint64(len(e.Value)))
+	buf = append(buf, e.Value...)
+	w.buf = buf
+
+	if err := w.record(buf); err != nil {
+		return err
+	}
+	w.count++
+	return nil
+}
+
+// Close writes snapshot trailer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	buf := []byte{recordEnd}
+	buf = binary.BigEndian.AppendUint64(buf, w.count)
+	buf = binary.BigEndian.AppendUint32(buf, w.crc.Sum32())
+	if err := w.record(buf); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
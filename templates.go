@@ -0,0 +1,35 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"azugo.io/core/templates"
+)
+
+func (a *App) initTemplates() {
+	a.tpllock.Lock()
+	defer a.tpllock.Unlock()
+
+	if a.templates != nil {
+		return
+	}
+
+	conf := a.Config().Templates
+	a.templates = templates.New(
+		templates.Dir(conf.Path),
+		templates.DefaultLayout(conf.DefaultLayout),
+		templates.Reload(conf.Reload || a.Env().IsDevelopment()),
+		templates.Instrumenter(a.Instrumenter()),
+	)
+}
+
+// Templates returns template rendering engine.
+//
+// Templates are loaded from the directory specified in the configuration and are
+// reloaded on change in development environment.
+func (a *App) Templates() *templates.Engine {
+	a.initTemplates()
+	return a.templates
+}
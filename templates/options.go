@@ -0,0 +1,126 @@
+package templates
+
+import (
+	"io/fs"
+	"os"
+
+	"azugo.io/core/instrumenter"
+)
+
+type options struct {
+	FS            fs.FS
+	Funcs         map[string]any
+	Translator    Translator
+	DefaultLayout string
+	Reload        bool
+	Instrumenter  instrumenter.Instrumenter
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Funcs: make(map[string]any),
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.FS == nil {
+		opt.FS = os.DirFS("templates")
+	}
+	return opt
+}
+
+// Option for the template engine.
+type Option interface {
+	apply(*options)
+}
+
+// FS is a file system to load templates from.
+type FS struct {
+	fs.FS
+}
+
+func (f FS) apply(o *options) {
+	o.FS = f.FS
+}
+
+// Dir is a directory to load templates from. Defaults to "templates".
+type Dir string
+
+func (d Dir) apply(o *options) {
+	o.FS = os.DirFS(string(d))
+}
+
+// Funcs are additional functions available in templates.
+type Funcs map[string]any
+
+func (f Funcs) apply(o *options) {
+	for k, v := range f {
+		o.Funcs[k] = v
+	}
+}
+
+// Translator translates message key to the locale.
+//
+// Translator is available in templates as "t" function.
+type Translator func(locale, key string, args ...any) string
+
+func (t Translator) apply(o *options) {
+	o.Translator = t
+}
+
+// DefaultLayout is a layout used to render pages if no layout is specified.
+type DefaultLayout string
+
+func (l DefaultLayout) apply(o *options) {
+	o.DefaultLayout = string(l)
+}
+
+// Reload enables template hot reload. Changes in template files are detected
+// on every render, so it should be used only in development.
+type Reload bool
+
+func (r Reload) apply(o *options) {
+	o.Reload = bool(r)
+}
+
+// Instrumenter to observe template rendering.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
+
+type renderOptions struct {
+	Locale   string
+	Layout   string
+	NoLayout bool
+}
+
+// RenderOption for the template rendering.
+type RenderOption interface {
+	applyRender(*renderOptions)
+}
+
+// Locale to render template in.
+//
+// Most specific template for the locale is used, for example for "lv-LV"
+// templates are looked up for "lv-LV", "lv" and then default template without locale.
+type Locale string
+
+func (l Locale) applyRender(o *renderOptions) {
+	o.Locale = string(l)
+}
+
+// Layout to render page in overriding default layout.
+type Layout string
+
+func (l Layout) applyRender(o *renderOptions) {
+	o.Layout = string(l)
+}
+
+// NoLayout renders page without layout.
+type NoLayout struct{}
+
+func (NoLayout) applyRender(o *renderOptions) {
+	o.NoLayout = true
+}
@@ -0,0 +1,399 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package templates
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+const (
+	// LayoutsDir is a directory containing layouts.
+	LayoutsDir = "layouts"
+	// PartialsDir is a directory containing partial templates available in all templates.
+	PartialsDir = "partials"
+)
+
+// InstrumentationRender is an instrumentation operation name for template rendering.
+const InstrumentationRender = "template-render"
+
+// ErrNotFound is returned when template is not found.
+type ErrNotFound struct {
+	Name string
+}
+
+func (e ErrNotFound) Error() string {
+	return fmt.Sprintf("template '%s' not found", e.Name)
+}
+
+var localeRe = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$`)
+
+type executor interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}
+
+type compiled struct {
+	executor
+	root string
+}
+
+// Engine renders HTML and text templates.
+//
+// Templates are loaded from the file system where template name is its path
+// relative to the root, for example "emails/welcome.html". Files with ".html" and
+// ".htm" extensions are rendered as HTML templates with contextual escaping, all other
+// files as text templates.
+//
+// Localized template variants are stored with locale before the extension, for example
+// "emails/welcome.lv.html". Layouts are stored in the "layouts" directory and must call
+// "content" block (for example {{ block "content" . }}{{ end }}) that page defines.
+// Templates in the "partials" directory are available in all templates with the same
+// extension by their name (for example {{ template "partials/footer.html" . }}).
+//
+// Compiled templates are cached for each locale and layout combination.
+type Engine struct {
+	opt *options
+
+	lock        sync.RWMutex
+	loaded      bool
+	fingerprint uint64
+	files       map[string]map[string]string
+	cache       map[string]*compiled
+}
+
+// New returns new template engine.
+//
+// Templates are loaded lazily on the first render.
+func New(opts ...Option) *Engine {
+	return &Engine{
+		opt:   newOptions(opts...),
+		cache: make(map[string]*compiled),
+	}
+}
+
+// AddFuncs adds functions available in templates. Compiled templates are discarded.
+func (e *Engine) AddFuncs(funcs Funcs) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	funcs.apply(e.opt)
+	e.cache = make(map[string]*compiled)
+}
+
+// SetTranslator sets translator used by "t" template function. Compiled templates are discarded.
+func (e *Engine) SetTranslator(t Translator) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.opt.Translator = t
+	e.cache = make(map[string]*compiled)
+}
+
+// Reload loads templates from the file system discarding compiled templates.
+func (e *Engine) Reload() error {
+	files, fp, err := e.scan()
+	if err != nil {
+		return err
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.update(files, fp)
+	return nil
+}
+
+// Exists returns true if template exists in any locale.
+func (e *Engine) Exists(name string) (bool, error) {
+	if err := e.load(); err != nil {
+		return false, err
+	}
+
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	_, ok := e.files[name]
+	return ok, nil
+}
+
+// Render template with the data to the writer.
+//
+// Output is written only if template is rendered successfully.
+func (e *Engine) Render(ctx context.Context, w io.Writer, name string, data any, opts ...RenderOption) error {
+	finish := e.opt.Instrumenter.Observe(ctx, InstrumentationRender, name)
+
+	buf := &bytes.Buffer{}
+	err := e.render(buf, name, data, opts...)
+	if err == nil {
+		_, err = buf.WriteTo(w)
+	}
+
+	finish(err)
+	return err
+}
+
+// String renders template with the data and returns result as string.
+func (e *Engine) String(ctx context.Context, name string, data any, opts ...RenderOption) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := e.Render(ctx, buf, name, data, opts...); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (e *Engine) render(w io.Writer, name string, data any, opts ...RenderOption) error {
+	if err := e.load(); err != nil {
+		return err
+	}
+
+	ro := &renderOptions{}
+	for _, o := range opts {
+		o.applyRender(ro)
+	}
+	locale := normalizeLocale(ro.Locale)
+
+	ext := path.Ext(name)
+	var layout string
+	if !ro.NoLayout {
+		if len(ro.Layout) != 0 {
+			layout = path.Join(LayoutsDir, ro.Layout+ext)
+		} else if len(e.opt.DefaultLayout) != 0 {
+			layout = path.Join(LayoutsDir, e.opt.DefaultLayout+ext)
+		}
+	}
+
+	key := name + "\x00" + locale + "\x00" + layout
+
+	e.lock.RLock()
+	t, ok := e.cache[key]
+	e.lock.RUnlock()
+
+	if !ok {
+		e.lock.Lock()
+		if t, ok = e.cache[key]; !ok {
+			var err error
+			t, err = e.compile(name, locale, layout, len(ro.Layout) != 0)
+			if err != nil {
+				e.lock.Unlock()
+				return err
+			}
+			e.cache[key] = t
+		}
+		e.lock.Unlock()
+	}
+
+	return t.ExecuteTemplate(w, t.root, data)
+}
+
+// load loads templates on the first use or reloads them if hot reload is
+// enabled and files have changed.
+func (e *Engine) load() error {
+	e.lock.RLock()
+	loaded := e.loaded
+	e.lock.RUnlock()
+
+	if loaded && !e.opt.Reload {
+		return nil
+	}
+
+	files, fp, err := e.scan()
+	if err != nil {
+		return err
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if !e.loaded || e.fingerprint != fp {
+		e.update(files, fp)
+	}
+	return nil
+}
+
+func (e *Engine) update(files map[string]map[string]string, fp uint64) {
+	e.files = files
+	e.fingerprint = fp
+	e.cache = make(map[string]*compiled)
+	e.loaded = true
+}
+
+// scan walks template file system and returns template files by name and locale
+// with fingerprint of the file system state.
+func (e *Engine) scan() (map[string]map[string]string, uint64, error) {
+	files := make(map[string]map[string]string)
+	h := fnv.New64a()
+
+	err := fs.WalkDir(e.opt.FS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == "." && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		_, _ = h.Write([]byte(p + "\x00" + strconv.FormatInt(info.Size(), 10) + "\x00" + strconv.FormatInt(info.ModTime().UnixNano(), 10) + "\x00"))
+
+		ext := path.Ext(p)
+		base := strings.TrimSuffix(p, ext)
+		var locale string
+		if l := path.Ext(base); len(l) > 1 && localeRe.MatchString(l[1:]) {
+			locale = normalizeLocale(l[1:])
+			base = strings.TrimSuffix(base, l)
+		}
+
+		name := base + ext
+		if files[name] == nil {
+			files[name] = make(map[string]string)
+		}
+		files[name][locale] = p
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return files, h.Sum64(), nil
+}
+
+// resolve returns most specific file path of the template for the locale.
+func (e *Engine) resolve(name, locale string) (string, bool) {
+	variants, ok := e.files[name]
+	if !ok {
+		return "", false
+	}
+	for _, l := range localeChain(locale) {
+		if p, ok := variants[l]; ok {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+func (e *Engine) funcs(locale string) map[string]any {
+	funcs := make(map[string]any, len(e.opt.Funcs)+2)
+	for k, v := range e.opt.Funcs {
+		funcs[k] = v
+	}
+	funcs["locale"] = func() string {
+		return locale
+	}
+	tr := e.opt.Translator
+	funcs["t"] = func(key string, args ...any) string {
+		if tr != nil {
+			return tr(locale, key, args...)
+		}
+		if len(args) == 0 {
+			return key
+		}
+		return fmt.Sprintf(key, args...)
+	}
+	return funcs
+}
+
+func (e *Engine) compile(name, locale, layout string, layoutRequired bool) (*compiled, error) {
+	page, ok := e.resolve(name, locale)
+	if !ok {
+		return nil, ErrNotFound{Name: name}
+	}
+
+	type source struct {
+		name string
+		path string
+	}
+
+	ext := path.Ext(name)
+	sources := make([]source, 0, 4)
+	for n := range e.files {
+		if path.Ext(n) != ext || !strings.HasPrefix(n, PartialsDir+"/") || n == name {
+			continue
+		}
+		if p, ok := e.resolve(n, locale); ok {
+			sources = append(sources, source{name: n, path: p})
+		}
+	}
+
+	root := name
+	if len(layout) != 0 && layout != name {
+		if p, ok := e.resolve(layout, locale); ok {
+			sources = append(sources, source{name: layout, path: p})
+			root = layout
+		} else if layoutRequired {
+			return nil, ErrNotFound{Name: layout}
+		}
+	}
+	sources = append(sources, source{name: name, path: page})
+
+	var (
+		add  func(name, text string) error
+		exec executor
+	)
+	if ext == ".html" || ext == ".htm" {
+		t := htmltemplate.New("").Funcs(e.funcs(locale))
+		add = func(n, text string) error {
+			_, err := t.New(n).Parse(text)
+			return err
+		}
+		exec = t
+	} else {
+		t := texttemplate.New("").Funcs(e.funcs(locale))
+		add = func(n, text string) error {
+			_, err := t.New(n).Parse(text)
+			return err
+		}
+		exec = t
+	}
+
+	for _, s := range sources {
+		data, err := fs.ReadFile(e.opt.FS, s.path)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(s.name, string(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &compiled{
+		executor: exec,
+		root:     root,
+	}, nil
+}
+
+// normalizeLocale returns locale in lower case with dash as separator.
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+// localeChain returns locales from the most specific to the default.
+func localeChain(locale string) []string {
+	chain := make([]string, 0, 3)
+	for len(locale) != 0 {
+		chain = append(chain, locale)
+		i := strings.LastIndexByte(locale, '-')
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return append(chain, "")
+}
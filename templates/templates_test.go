@@ -0,0 +1,120 @@
+package templates
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/base.html":       {Data: []byte(`<html lang="{{ locale }}">{{ block "content" . }}{{ end }}{{ template "partials/footer.html" . }}</html>`)},
+		"partials/footer.html":    {Data: []byte(`<footer>{{ t "footer" }}</footer>`)},
+		"partials/footer.lv.html": {Data: []byte(`<footer>kājene</footer>`)},
+		"pages/hello.html":        {Data: []byte(`{{ define "content" }}<p>Hello, {{ .Name }}!</p>{{ end }}`)},
+		"pages/hello.lv.html":     {Data: []byte(`{{ define "content" }}<p>Sveiki, {{ .Name }}!</p>{{ end }}`)},
+		"emails/welcome.txt":      {Data: []byte(`Welcome, {{ .Name | upper }}!`)},
+		"emails/welcome.html":     {Data: []byte(`<b>{{ .Name }}</b>`)},
+	}
+}
+
+func TestRender(t *testing.T) {
+	e := New(FS{testFS()}, DefaultLayout("base"), Funcs{"upper": strings.ToUpper})
+
+	s, err := e.String(context.TODO(), "pages/hello.html", map[string]string{"Name": "<John>"})
+	require.NoError(t, err)
+	assert.Equal(t, `<html lang=""><p>Hello, &lt;John&gt;!</p><footer>footer</footer></html>`, s)
+
+	s, err = e.String(context.TODO(), "emails/welcome.txt", map[string]string{"Name": "<John>"})
+	require.NoError(t, err)
+	assert.Equal(t, `Welcome, <JOHN>!`, s)
+
+	s, err = e.String(context.TODO(), "emails/welcome.html", map[string]string{"Name": "John"}, NoLayout{})
+	require.NoError(t, err)
+	assert.Equal(t, `<b>John</b>`, s)
+}
+
+func TestRenderLocale(t *testing.T) {
+	e := New(FS{testFS()}, DefaultLayout("base"), Translator(func(locale, key string, _ ...any) string {
+		return locale + ":" + key
+	}))
+
+	s, err := e.String(context.TODO(), "pages/hello.html", map[string]string{"Name": "Jānis"}, Locale("lv_LV"))
+	require.NoError(t, err)
+	assert.Equal(t, `<html lang="lv-lv"><p>Sveiki, Jānis!</p><footer>kājene</footer></html>`, s)
+
+	s, err = e.String(context.TODO(), "pages/hello.html", map[string]string{"Name": "Hans"}, Locale("de"))
+	require.NoError(t, err)
+	assert.Equal(t, `<html lang="de"><p>Hello, Hans!</p><footer>de:footer</footer></html>`, s)
+}
+
+func TestRenderErrors(t *testing.T) {
+	e := New(FS{testFS()})
+
+	err := e.Render(context.TODO(), &bytes.Buffer{}, "pages/missing.html", nil)
+	assert.Equal(t, ErrNotFound{Name: "pages/missing.html"}, err)
+
+	err = e.Render(context.TODO(), &bytes.Buffer{}, "pages/hello.html", nil, Layout("missing"))
+	assert.Equal(t, ErrNotFound{Name: "layouts/missing.html"}, err)
+
+	buf := &bytes.Buffer{}
+	err = e.Render(context.TODO(), buf, "emails/welcome.txt", map[string]string{"Name": "John"})
+	assert.Error(t, err, "upper function is not defined")
+	assert.Zero(t, buf.Len())
+
+	ok, err := e.Exists("emails/welcome.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestRenderMissingDir(t *testing.T) {
+	e := New(Dir(t.TempDir() + "/missing"))
+
+	ok, err := e.Exists("pages/hello.html")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestReload(t *testing.T) {
+	fsys := testFS()
+
+	e := New(FS{fsys}, Reload(true))
+
+	s, err := e.String(context.TODO(), "emails/welcome.html", map[string]string{"Name": "John"})
+	require.NoError(t, err)
+	assert.Equal(t, `<b>John</b>`, s)
+
+	fsys["emails/welcome.html"] = &fstest.MapFile{Data: []byte(`<i>{{ .Name }}</i>`), ModTime: time.Now()}
+
+	s, err = e.String(context.TODO(), "emails/welcome.html", map[string]string{"Name": "John"})
+	require.NoError(t, err)
+	assert.Equal(t, `<i>John</i>`, s)
+}
+
+func TestNoReload(t *testing.T) {
+	fsys := testFS()
+
+	e := New(FS{fsys})
+
+	s, err := e.String(context.TODO(), "emails/welcome.html", map[string]string{"Name": "John"})
+	require.NoError(t, err)
+	assert.Equal(t, `<b>John</b>`, s)
+
+	fsys["emails/welcome.html"] = &fstest.MapFile{Data: []byte(`<i>{{ .Name }}</i>`), ModTime: time.Now()}
+
+	s, err = e.String(context.TODO(), "emails/welcome.html", map[string]string{"Name": "John"})
+	require.NoError(t, err)
+	assert.Equal(t, `<b>John</b>`, s)
+
+	require.NoError(t, e.Reload())
+
+	s, err = e.String(context.TODO(), "emails/welcome.html", map[string]string{"Name": "John"})
+	require.NoError(t, err)
+	assert.Equal(t, `<i>John</i>`, s)
+}
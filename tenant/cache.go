@@ -0,0 +1,127 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package tenant
+
+import (
+	"context"
+	"time"
+
+	"azugo.io/core/cache"
+)
+
+type tenantCache[T any] struct {
+	cache.CacheInstance[T]
+}
+
+// Cache returns cache instance that isolates keys by tenant in the context.
+//
+// All operations fail with ErrNoTenant if tenant is not available in the context.
+// Cache loader receives keys prefixed with the tenant ID. Item tags are isolated
+// by tenant as well.
+func Cache[T any](c cache.CacheInstance[T]) cache.CacheInstance[T] {
+	return &tenantCache[T]{
+		CacheInstance: c,
+	}
+}
+
+func key(ctx context.Context, key string) (string, error) {
+	id, err := ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return id + ":" + key, nil
+}
+
+func (c *tenantCache[T]) Get(ctx context.Context, k string, opts ...cache.ItemOption[T]) (T, error) {
+	var val T
+	k, err := key(ctx, k)
+	if err != nil {
+		return val, err
+	}
+	return c.CacheInstance.Get(ctx, k, opts...)
+}
+
+func (c *tenantCache[T]) Exists(ctx context.Context, k string) (bool, error) {
+	k, err := key(ctx, k)
+	if err != nil {
+		return false, err
+	}
+	return c.CacheInstance.Exists(ctx, k)
+}
+
+func (c *tenantCache[T]) Pop(ctx context.Context, k string) (T, error) {
+	var val T
+	k, err := key(ctx, k)
+	if err != nil {
+		return val, err
+	}
+	return c.CacheInstance.Pop(ctx, k)
+}
+
+func (c *tenantCache[T]) PopWithMetadata(ctx context.Context, k string) (T, cache.ItemMetadata, error) {
+	var val T
+	k, err := key(ctx, k)
+	if err != nil {
+		return val, cache.ItemMetadata{}, err
+	}
+	return cache.PopWithMetadata(ctx, c.CacheInstance, k)
+}
+
+func (c *tenantCache[T]) Set(ctx context.Context, k string, value T, opts ...cache.ItemOption[T]) error {
+	k, err := key(ctx, k)
+	if err != nil {
+		return err
+	}
+	return c.CacheInstance.Set(ctx, k, value, tags(ctx, opts)...)
+}
+
+func (c *tenantCache[T]) Delete(ctx context.Context, k string) error {
+	k, err := key(ctx, k)
+	if err != nil {
+		return err
+	}
+	return c.CacheInstance.Delete(ctx, k)
+}
+
+func (c *tenantCache[T]) TTL(ctx context.Context, k string) (time.Duration, error) {
+	k, err := key(ctx, k)
+	if err != nil {
+		return 0, err
+	}
+	return cache.GetTTL(ctx, c.CacheInstance, k)
+}
+
+func (c *tenantCache[T]) Touch(ctx context.Context, k string, ttl time.Duration) error {
+	k, err := key(ctx, k)
+	if err != nil {
+		return err
+	}
+	return cache.Touch(ctx, c.CacheInstance, k, ttl)
+}
+
+// tags returns item options with tags isolated by tenant in the context.
+func tags[T any](ctx context.Context, opts []cache.ItemOption[T]) []cache.ItemOption[T] {
+	res := make([]cache.ItemOption[T], 0, len(opts))
+	for _, opt := range opts {
+		if t, ok := opt.(cache.Tags[T]); ok {
+			prefixed := make(cache.Tags[T], 0, len(t))
+			for _, tag := range t {
+				tag, _ = key(ctx, tag)
+				prefixed = append(prefixed, tag)
+			}
+			opt = prefixed
+		}
+		res = append(res, opt)
+	}
+	return res
+}
+
+func (c *tenantCache[T]) InvalidateTag(ctx context.Context, tag string) error {
+	tag, err := key(ctx, tag)
+	if err != nil {
+		return err
+	}
+	return cache.InvalidateTag(ctx, c.CacheInstance, tag)
+}
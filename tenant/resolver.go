@@ -0,0 +1,102 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package tenant
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolver resolves tenant ID from the request.
+type Resolver interface {
+	// Resolve tenant ID from the request. Returns empty string if tenant can not be resolved.
+	Resolve(r *http.Request) string
+}
+
+// ResolverFunc is a function that resolves tenant ID from the request.
+type ResolverFunc func(r *http.Request) string
+
+// Resolve tenant ID from the request.
+func (f ResolverFunc) Resolve(r *http.Request) string {
+	return f(r)
+}
+
+// HeaderResolver resolves tenant ID from the request header.
+func HeaderResolver(name string) Resolver {
+	return ResolverFunc(func(r *http.Request) string {
+		return strings.TrimSpace(r.Header.Get(name))
+	})
+}
+
+// QueryResolver resolves tenant ID from the URL query parameter.
+func QueryResolver(name string) Resolver {
+	return ResolverFunc(func(r *http.Request) string {
+		return r.URL.Query().Get(name)
+	})
+}
+
+// SubdomainResolver resolves tenant ID from the subdomain of the specified domain.
+func SubdomainResolver(domain string) Resolver {
+	suffix := "." + strings.TrimPrefix(strings.ToLower(domain), ".")
+	return ResolverFunc(func(r *http.Request) string {
+		host := strings.ToLower(r.Host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !strings.HasSuffix(host, suffix) {
+			return ""
+		}
+		sub := strings.TrimSuffix(host, suffix)
+		if strings.Contains(sub, ".") {
+			return ""
+		}
+		return sub
+	})
+}
+
+// ChainResolver returns first resolved tenant ID from the resolvers.
+func ChainResolver(resolvers ...Resolver) Resolver {
+	return ResolverFunc(func(r *http.Request) string {
+		for _, res := range resolvers {
+			if id := res.Resolve(r); len(id) != 0 {
+				return id
+			}
+		}
+		return ""
+	})
+}
+
+// Middleware resolves tenant for every request and attaches it to the request context.
+//
+// If tenant is required and can not be resolved request is rejected.
+func Middleware(resolver Resolver, store Store, required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := resolver.Resolve(r)
+			if len(id) == 0 {
+				if required {
+					http.Error(w, "tenant is required", http.StatusBadRequest)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			t, err := store.Tenant(r.Context(), id)
+			if errors.Is(err, ErrUnknownTenant) {
+				http.Error(w, "unknown tenant", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), t)))
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package tenant
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"azugo.io/core/instrumenter"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNoTenant is returned when tenant is not available in the context.
+	ErrNoTenant = errors.New("tenant not found in context")
+	// ErrUnknownTenant is returned when tenant can not be found in the store.
+	ErrUnknownTenant = errors.New("unknown tenant")
+)
+
+// Tenant information.
+type Tenant struct {
+	// ID of the tenant.
+	ID string
+	// Name of the tenant.
+	Name string
+	// Config contains tenant specific configuration overrides.
+	Config map[string]any
+}
+
+type contextKey struct{}
+
+// NewContext returns new context with the tenant attached.
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns tenant attached to the context.
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(*Tenant)
+	return t, ok && t != nil
+}
+
+// ID returns tenant ID from the context.
+func ID(ctx context.Context) (string, error) {
+	t, ok := FromContext(ctx)
+	if !ok {
+		return "", ErrNoTenant
+	}
+	return t.ID, nil
+}
+
+// Value returns tenant configuration override value or default value
+// if tenant is not in context or override is not set.
+func Value[T any](ctx context.Context, key string, def T) T {
+	t, ok := FromContext(ctx)
+	if !ok || t.Config == nil {
+		return def
+	}
+	v, ok := t.Config[key].(T)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// LogFields returns logger fields for the tenant in the context.
+func LogFields(ctx context.Context) []zap.Field {
+	t, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return []zap.Field{zap.String("tenant.id", t.ID)}
+}
+
+// Logger returns logger with tenant fields from the context.
+func Logger(ctx context.Context, log *zap.Logger) *zap.Logger {
+	fields := LogFields(ctx)
+	if len(fields) == 0 {
+		return log
+	}
+	return log.With(fields...)
+}
+
+// Instrumenter returns instrumenter that adds tenant label to the instrumentation arguments.
+func Instrumenter(next instrumenter.Instrumenter) instrumenter.Instrumenter {
+	return func(ctx context.Context, op string, args ...any) func(err error) {
+		if t, ok := FromContext(ctx); ok {
+			args = append(args, instrumenter.Label{Name: "tenant", Value: t.ID})
+		}
+		return next.Observe(ctx, op, args...)
+	}
+}
+
+// Store provides tenant information.
+type Store interface {
+	// Tenant returns tenant by ID. Returns ErrUnknownTenant if tenant does not exist.
+	Tenant(ctx context.Context, id string) (*Tenant, error)
+}
+
+// MemoryStore is an in-memory tenant store.
+type MemoryStore struct {
+	lock    sync.RWMutex
+	tenants map[string]*Tenant
+}
+
+// NewMemoryStore creates new in-memory tenant store.
+func NewMemoryStore(tenants ...*Tenant) *MemoryStore {
+	s := &MemoryStore{
+		tenants: make(map[string]*Tenant, len(tenants)),
+	}
+	for _, t := range tenants {
+		s.tenants[t.ID] = t
+	}
+	return s
+}
+
+// Add or replace tenant in the store.
+func (s *MemoryStore) Add(t *Tenant) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tenants[t.ID] = t
+}
+
+// Tenant returns tenant by ID.
+func (s *MemoryStore) Tenant(_ context.Context, id string) (*Tenant, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	t, ok := s.tenants[id]
+	if !ok {
+		return nil, ErrUnknownTenant
+	}
+	return t, nil
+}
+
+// Selector selects tenant specific resource, for example database connection.
+type Selector[T any] struct {
+	lock      sync.RWMutex
+	resources map[string]T
+	def       T
+	hasDef    bool
+}
+
+// NewSelector creates new tenant resource selector.
+func NewSelector[T any]() *Selector[T] {
+	return &Selector[T]{
+		resources: make(map[string]T),
+	}
+}
+
+// SetDefault sets resource to use for tenants without explicit resource.
+func (s *Selector[T]) SetDefault(res T) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.def = res
+	s.hasDef = true
+}
+
+// Set resource for the tenant.
+func (s *Selector[T]) Set(id string, res T) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.resources[id] = res
+}
+
+// Select resource for the tenant in the context.
+func (s *Selector[T]) Select(ctx context.Context) (T, error) {
+	var res T
+
+	id, err := ID(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if r, ok := s.resources[id]; ok {
+		return r, nil
+	}
+	if s.hasDef {
+		return s.def, nil
+	}
+	return res, ErrUnknownTenant
+}
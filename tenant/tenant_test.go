@@ -0,0 +1,145 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"azugo.io/core/cache"
+	"azugo.io/core/instrumenter"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	store := NewMemoryStore(&Tenant{ID: "acme", Config: map[string]any{"limit": 10}})
+
+	var got *Tenant
+	h := Middleware(ChainResolver(HeaderResolver("X-Tenant"), SubdomainResolver("example.com")), store, true)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, _ = FromContext(r.Context())
+			assert.Equal(t, 10, Value(r.Context(), "limit", 5))
+			assert.Equal(t, "x", Value(r.Context(), "missing", "x"))
+		}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "http://acme.example.com/", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, got)
+	assert.Equal(t, "acme", got.ID)
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	req.Header.Set("X-Tenant", "other")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestTenantCache(t *testing.T) {
+	c := cache.New(cache.MemoryCache, cache.MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	t.Cleanup(c.Close)
+
+	i, err := cache.Create[string](c, "test")
+	require.NoError(t, err)
+	tc := Cache(i)
+
+	ctx1 := NewContext(context.TODO(), &Tenant{ID: "t1"})
+	ctx2 := NewContext(context.TODO(), &Tenant{ID: "t2"})
+
+	require.NoError(t, tc.Set(ctx1, "key", "value1"))
+	require.NoError(t, tc.Set(ctx2, "key", "value2"))
+
+	v, err := tc.Get(ctx1, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value1", v)
+
+	v, err = i.Get(context.TODO(), "t2:key")
+	require.NoError(t, err)
+	assert.Equal(t, "value2", v)
+
+	ok, err := tc.Exists(ctx1, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = tc.Exists(NewContext(context.TODO(), &Tenant{ID: "t3"}), "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Touch(ctx1, tc, "key", time.Minute))
+	ttl, err := cache.GetTTL(context.TODO(), i, "t1:key")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+	ttl, err = cache.GetTTL(ctx2, tc, "key")
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+
+	_, err = tc.Get(context.TODO(), "key")
+	assert.ErrorIs(t, err, ErrNoTenant)
+	_, err = tc.Exists(context.TODO(), "key")
+	assert.ErrorIs(t, err, ErrNoTenant)
+	assert.ErrorIs(t, cache.Touch(context.TODO(), tc, "key", time.Minute), ErrNoTenant)
+}
+
+func TestTenantSelector(t *testing.T) {
+	s := NewSelector[string]()
+	s.Set("t1", "db1")
+
+	v, err := s.Select(NewContext(context.TODO(), &Tenant{ID: "t1"}))
+	require.NoError(t, err)
+	assert.Equal(t, "db1", v)
+
+	_, err = s.Select(NewContext(context.TODO(), &Tenant{ID: "t2"}))
+	assert.ErrorIs(t, err, ErrUnknownTenant)
+
+	s.SetDefault("shared")
+	v, err = s.Select(NewContext(context.TODO(), &Tenant{ID: "t2"}))
+	require.NoError(t, err)
+	assert.Equal(t, "shared", v)
+}
+
+func TestTenantInstrumenter(t *testing.T) {
+	var labels []any
+	i := Instrumenter(func(ctx context.Context, op string, args ...any) func(err error) {
+		labels = args
+		return func(err error) {}
+	})
+
+	i.Observe(NewContext(context.TODO(), &Tenant{ID: "t1"}), "op", "arg")(nil)
+	assert.Equal(t, []any{"arg", instrumenter.Label{Name: "tenant", Value: "t1"}}, labels)
+}
+
+func TestTenantCacheInvalidateTag(t *testing.T) {
+	c := cache.New(cache.MemoryCache, cache.MemorySyncWrites(true))
+	require.NoError(t, c.Start(context.TODO()))
+	t.Cleanup(c.Close)
+
+	i, err := cache.Create[string](c, "test")
+	require.NoError(t, err)
+	tc := Cache(i)
+
+	ctx1 := NewContext(context.TODO(), &Tenant{ID: "t1"})
+	ctx2 := NewContext(context.TODO(), &Tenant{ID: "t2"})
+
+	require.NoError(t, tc.Set(ctx1, "key", "value1", cache.WithTags[string]("tag")))
+	require.NoError(t, tc.Set(ctx2, "key", "value2", cache.WithTags[string]("tag")))
+
+	require.NoError(t, cache.InvalidateTag(ctx1, tc, "tag"))
+	ok, err := tc.Exists(ctx1, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = tc.Exists(ctx2, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.ErrorIs(t, cache.InvalidateTag(context.TODO(), tc, "tag"), ErrNoTenant)
+}
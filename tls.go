@@ -0,0 +1,61 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"time"
+
+	"azugo.io/core/cert"
+	"azugo.io/core/diagnostics"
+)
+
+func (a *App) initCertificates() error {
+	a.tlslock.Lock()
+	defer a.tlslock.Unlock()
+
+	if a.certificates != nil {
+		return nil
+	}
+
+	conf := a.Config().TLS
+
+	policy, err := cert.ParseExportPolicy(conf.KeyExportPolicy)
+	if err != nil {
+		return err
+	}
+	cert.SetExportPolicy(policy)
+
+	p := cert.NewSNIProvider(conf.ReloadInterval)
+	for _, c := range conf.Certificates {
+		var opts []cert.Option
+		if len(c.Password) != 0 {
+			opts = append(opts, cert.Password(c.Password))
+		}
+		if err := p.SetFile(c.Name, c.Hosts, c.Certificate, c.Key, opts...); err != nil {
+			return err
+		}
+	}
+	if len(conf.Certificates) != 0 {
+		if err := a.AddTask(p); err != nil {
+			return err
+		}
+	}
+	a.certificates = p
+
+	a.registerDiagnostic("certificates", diagnostics.CertificateCheck(p, time.Duration(a.Config().Diagnostics.CertificateExpiry)))
+
+	return nil
+}
+
+// Certificates returns TLS certificate provider with certificates from configuration.
+//
+// Provider selects certificate by the server name requested by the client and can
+// be used as tls.Config GetCertificate function.
+func (a *App) Certificates() *cert.SNIProvider {
+	if err := a.initCertificates(); err != nil {
+		panic(err)
+	}
+	return a.certificates
+}
@@ -0,0 +1,52 @@
+package token
+
+import (
+	"time"
+)
+
+type options struct {
+	TTL        time.Duration
+	ClockSkew  time.Duration
+	QueryParam string
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		TTL:        15 * time.Minute,
+		ClockSkew:  30 * time.Second,
+		QueryParam: "token",
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	return opt
+}
+
+// Option for minting and verifying tokens.
+type Option interface {
+	apply(*options)
+}
+
+// TTL is a default lifetime of minted tokens when claims do not specify
+// expiration. Defaults to 15 minutes.
+type TTL time.Duration
+
+func (t TTL) apply(o *options) {
+	o.TTL = time.Duration(t)
+}
+
+// ClockSkew is a tolerance of clock differences between servers minting and
+// verifying tokens. Defaults to 30 seconds.
+type ClockSkew time.Duration
+
+func (s ClockSkew) apply(o *options) {
+	o.ClockSkew = time.Duration(s)
+}
+
+// QueryParam is a name of the URL query parameter carrying token of the
+// signed URL. Defaults to "token".
+type QueryParam string
+
+func (p QueryParam) apply(o *options) {
+	o.QueryParam = string(p)
+}
@@ -0,0 +1,209 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package token
+
+import (
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"azugo.io/core/cert"
+
+	"github.com/goccy/go-json"
+)
+
+var (
+	// ErrInvalidToken is returned when token is malformed or its signature does not match.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpired is returned when token has expired.
+	ErrExpired = errors.New("token has expired")
+	// ErrNotYetValid is returned when token is used before it becomes valid.
+	ErrNotYetValid = errors.New("token is not valid yet")
+	// ErrAudience is returned when token was minted for another audience.
+	ErrAudience = errors.New("token audience does not match")
+)
+
+// Signer signs token payloads.
+//
+// keyring.KeyRing implements Signer using HMAC-SHA256.
+type Signer interface {
+	// Sign data returning ID of the key used and signature.
+	Sign(data []byte) (string, []byte, error)
+}
+
+// Verifier verifies token payload signatures.
+//
+// keyring.KeyRing implements Verifier using HMAC-SHA256.
+type Verifier interface {
+	// Verify signature of the data created with the key with specified ID.
+	Verify(id string, data, signature []byte) bool
+}
+
+// KeySigner signs tokens using asymmetric private key.
+type KeySigner struct {
+	// ID of the key to include in the token.
+	ID string
+	// Key is a RSA, ECDSA or Ed25519 private key.
+	Key crypto.Signer
+}
+
+// Sign data using private key.
+func (s KeySigner) Sign(data []byte) (string, []byte, error) {
+	sig, err := cert.Sign(data, s.Key, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return s.ID, sig, nil
+}
+
+// PublicKeys verifies tokens signed with asymmetric keys by key ID.
+//
+// Keys can be public keys, private keys or *x509.Certificate.
+type PublicKeys map[string]any
+
+// Verify signature of the data using public key with specified ID.
+func (k PublicKeys) Verify(id string, data, signature []byte) bool {
+	pub, ok := k[id]
+	if !ok {
+		return false
+	}
+	return cert.Verify(data, signature, pub) == nil
+}
+
+// Claims are payload claims of the token.
+type Claims struct {
+	// KeyID is an ID of the key token was signed with. It is set by Verify.
+	KeyID string `json:"-"`
+	// Audience is a purpose of the token (for example "download" or
+	// "email-verification"). Token can only be verified for the same audience.
+	Audience string `json:"aud,omitempty"`
+	// Subject of the token (for example user ID).
+	Subject string `json:"sub,omitempty"`
+	// ID is an unique token ID that can be used to prevent token reuse.
+	ID string `json:"jti,omitempty"`
+	// IssuedAt is a time token was minted.
+	IssuedAt int64 `json:"iat"`
+	// NotBefore is a time before which token is not valid.
+	NotBefore int64 `json:"nbf,omitempty"`
+	// ExpiresAt is a time token expires.
+	ExpiresAt int64 `json:"exp"`
+	// Data are additional payload claims.
+	Data map[string]string `json:"data,omitempty"`
+	// URL is a hash of the signed URL.
+	URL string `json:"url,omitempty"`
+}
+
+// Expires returns time token expires.
+func (c *Claims) Expires() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// Tokens mints and verifies expiring signed tokens and URLs.
+type Tokens struct {
+	signer   Signer
+	verifier Verifier
+	opts     *options
+	now      func() time.Time
+}
+
+// New creates new token minter and verifier.
+//
+// Signer can be nil if tokens are only verified.
+func New(signer Signer, verifier Verifier, opts ...Option) *Tokens {
+	return &Tokens{
+		signer:   signer,
+		verifier: verifier,
+		opts:     newOptions(opts...),
+		now:      time.Now,
+	}
+}
+
+var encoding = base64.RawURLEncoding
+
+// Mint signs claims and returns token.
+//
+// Issue time is set to the current time. If expiration is not set, token
+// expires after the default TTL since it becomes valid.
+//
+// Token consists of the base64url encoded JSON claims, key ID and signature
+// separated by dots. Signature covers encoded claims and key ID.
+func (t *Tokens) Mint(claims Claims) (string, error) {
+	if t.signer == nil {
+		return "", errors.New("token signer is not configured")
+	}
+
+	now := t.now()
+	claims.IssuedAt = now.Unix()
+	if claims.ExpiresAt == 0 {
+		claims.ExpiresAt = now.Add(t.opts.TTL).Unix()
+		if claims.NotBefore > claims.IssuedAt {
+			claims.ExpiresAt = time.Unix(claims.NotBefore, 0).Add(t.opts.TTL).Unix()
+		}
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	data := encoding.EncodeToString(payload)
+
+	// Key ID is not known before signing so it is covered by the signature of
+	// the claims and appended separately.
+	id, sig, err := t.signer.Sign([]byte(data))
+	if err != nil {
+		return "", err
+	}
+	return data + "." + encoding.EncodeToString([]byte(id)) + "." + encoding.EncodeToString(sig), nil
+}
+
+// Verify token signature and validity and returns its claims.
+//
+// Token must be minted for the audience.
+func (t *Tokens) Verify(token, audience string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	id, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	sig, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !t.verifier.Verify(string(id), []byte(parts[0]), sig) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims := &Claims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims.KeyID = string(id)
+
+	if claims.Audience != audience {
+		return nil, fmt.Errorf("%w: %q", ErrAudience, claims.Audience)
+	}
+	now := t.now()
+	skew := t.opts.ClockSkew
+	if now.Add(-skew).Unix() >= claims.ExpiresAt {
+		return nil, ErrExpired
+	}
+	if claims.NotBefore != 0 && now.Add(skew).Unix() < claims.NotBefore {
+		return nil, ErrNotYetValid
+	}
+	if claims.IssuedAt > now.Add(skew).Unix() {
+		return nil, ErrNotYetValid
+	}
+	return claims, nil
+}
@@ -0,0 +1,159 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"azugo.io/core/keyring"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACToken(t *testing.T) {
+	keys := keyring.New(keyring.Key{ID: "k1", Secret: []byte("secret")})
+	tokens := New(keys, keys, TTL(time.Hour), ClockSkew(time.Minute))
+	now := time.Unix(1700000000, 0)
+	tokens.now = func() time.Time { return now }
+
+	tok, err := tokens.Mint(Claims{
+		Audience: "email-verification",
+		Subject:  "user-1",
+		Data:     map[string]string{"email": "user@example.com"},
+	})
+	require.NoError(t, err)
+
+	claims, err := tokens.Verify(tok, "email-verification")
+	require.NoError(t, err)
+	assert.Equal(t, "k1", claims.KeyID)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, "user@example.com", claims.Data["email"])
+	assert.Equal(t, now.Add(time.Hour), claims.Expires())
+
+	_, err = tokens.Verify(tok, "download")
+	assert.ErrorIs(t, err, ErrAudience)
+
+	// Rotated keys keep old tokens valid.
+	keys.Rotate(keyring.Key{ID: "k2", Secret: []byte("new secret")})
+	_, err = tokens.Verify(tok, "email-verification")
+	require.NoError(t, err)
+
+	_, err = tokens.Verify(tok[1:], "email-verification")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = tokens.Verify("invalid", "email-verification")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Expired token is accepted within clock skew.
+	now = now.Add(time.Hour + 30*time.Second)
+	_, err = tokens.Verify(tok, "email-verification")
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	_, err = tokens.Verify(tok, "email-verification")
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestNotBefore(t *testing.T) {
+	keys := keyring.New(keyring.Key{ID: "k1", Secret: []byte("secret")})
+	tokens := New(keys, keys)
+	now := time.Unix(1700000000, 0)
+	tokens.now = func() time.Time { return now }
+
+	tok, err := tokens.Mint(Claims{NotBefore: now.Add(time.Hour).Unix()})
+	require.NoError(t, err)
+
+	_, err = tokens.Verify(tok, "")
+	assert.ErrorIs(t, err, ErrNotYetValid)
+
+	now = now.Add(time.Hour - 10*time.Second)
+	_, err = tokens.Verify(tok, "")
+	require.NoError(t, err)
+}
+
+func TestAsymmetricToken(t *testing.T) {
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	verifier := PublicKeys{"ec": ec.Public(), "ed": ed.Public()}
+
+	for _, signer := range []KeySigner{{ID: "ec", Key: ec}, {ID: "ed", Key: ed}} {
+		t.Run(signer.ID, func(t *testing.T) {
+			tok, err := New(signer, verifier).Mint(Claims{Subject: "user-1"})
+			require.NoError(t, err)
+
+			claims, err := New(nil, verifier).Verify(tok, "")
+			require.NoError(t, err)
+			assert.Equal(t, signer.ID, claims.KeyID)
+			assert.Equal(t, "user-1", claims.Subject)
+		})
+	}
+
+	tok, err := New(KeySigner{ID: "unknown", Key: ec}, verifier).Mint(Claims{})
+	require.NoError(t, err)
+	_, err = New(nil, verifier).Verify(tok, "")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = New(nil, verifier).Mint(Claims{})
+	assert.Error(t, err)
+}
+
+func TestSignedURL(t *testing.T) {
+	keys := keyring.New(keyring.Key{ID: "k1", Secret: []byte("secret")})
+	tokens := New(keys, keys, QueryParam("sig"))
+
+	signed, err := tokens.SignURL("https://example.com/files/report.pdf?b=2&a=1", Claims{Audience: "download"})
+	require.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.NotEmpty(t, u.Query().Get("sig"))
+
+	_, err = tokens.VerifyURL(u, "download")
+	require.NoError(t, err)
+
+	// Host is not part of the signature.
+	u.Host = "internal:8080"
+	_, err = tokens.VerifyURL(u, "download")
+	require.NoError(t, err)
+
+	q := u.Query()
+	q.Set("a", "2")
+	u.RawQuery = q.Encode()
+	_, err = tokens.VerifyURL(u, "download")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	u, _ = url.Parse(signed)
+	u.Path = "/files/other.pdf"
+	_, err = tokens.VerifyURL(u, "download")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestMiddleware(t *testing.T) {
+	keys := keyring.New(keyring.Key{ID: "k1", Secret: []byte("secret")})
+	tokens := New(keys, keys)
+
+	h := tokens.Middleware("download")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(FromContext(r.Context()).Subject))
+	}))
+
+	signed, err := tokens.SignURL("/files/1", Claims{Audience: "download", Subject: "user-1"})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, signed, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "user-1", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/files/1", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
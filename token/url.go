@@ -0,0 +1,84 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package token
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+)
+
+// urlHash returns hash of the URL path and query without the token parameter.
+//
+// Query parameters are sorted so that their order does not matter. Scheme and
+// host are not included as they can differ behind reverse proxies.
+func (t *Tokens) urlHash(u *url.URL) string {
+	q := u.Query()
+	q.Del(t.opts.QueryParam)
+	h := sha256.Sum256([]byte(u.EscapedPath() + "?" + q.Encode()))
+	return encoding.EncodeToString(h[:])
+}
+
+// SignURL returns URL with the token bound to the URL path and query
+// added as a query parameter.
+//
+// Any change to the URL path or query invalidates the token.
+func (t *Tokens) SignURL(rawURL string, claims Claims) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	claims.URL = t.urlHash(u)
+	tok, err := t.Mint(claims)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(t.opts.QueryParam, tok)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// VerifyURL verifies token of the signed URL and returns its claims.
+func (t *Tokens) VerifyURL(u *url.URL, audience string) (*Claims, error) {
+	claims, err := t.Verify(u.Query().Get(t.opts.QueryParam), audience)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.URL), []byte(t.urlHash(u))) != 1 {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+type claimsContextKey struct{}
+
+// FromContext returns claims of the signed URL token or nil if request was
+// not verified by the Middleware.
+func FromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims
+}
+
+// Middleware verifies signed URL of every request for the audience and
+// attaches token claims to the request context.
+//
+// Requests with missing, invalid or expired tokens are rejected with
+// 401 Unauthorized status.
+func (t *Tokens) Middleware(audience string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := t.VerifyURL(r.URL, audience)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey{}, claims)))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package upload
+
+import (
+	"azugo.io/core/instrumenter"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	Prefix       string
+	MaxFileSize  int64
+	MaxFiles     int
+	MaxFormSize  int64
+	AllowedTypes []string
+	Scanner      Scanner
+	Instrumenter instrumenter.Instrumenter
+	Logger       *zap.Logger
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		Prefix:      "uploads",
+		MaxFileSize: 32 << 20,
+		MaxFiles:    10,
+		MaxFormSize: 1 << 20,
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return opt
+}
+
+// Option for the upload handler.
+type Option interface {
+	apply(*options)
+}
+
+// Prefix is a blob name prefix for uploaded files. Defaults to "uploads".
+type Prefix string
+
+func (p Prefix) apply(o *options) {
+	o.Prefix = string(p)
+}
+
+// MaxFileSize is a maximum size of single uploaded file in bytes. Defaults to 32 MiB.
+type MaxFileSize int64
+
+func (s MaxFileSize) apply(o *options) {
+	o.MaxFileSize = int64(s)
+}
+
+// MaxFiles is a maximum number of files in single request. Defaults to 10.
+type MaxFiles int
+
+func (m MaxFiles) apply(o *options) {
+	o.MaxFiles = int(m)
+}
+
+// MaxFormSize is a maximum total size of non-file form values in bytes. Defaults to 1 MiB.
+type MaxFormSize int64
+
+func (s MaxFormSize) apply(o *options) {
+	o.MaxFormSize = int64(s)
+}
+
+// AllowedTypes are content types allowed to be uploaded. Type can contain wildcard
+// subtype, for example "image/*". All types are allowed if not set.
+//
+// Content type is detected from the file content and not from the request.
+type AllowedTypes []string
+
+func (t AllowedTypes) apply(o *options) {
+	o.AllowedTypes = append(o.AllowedTypes, t...)
+}
+
+// Scan sets scanner to check uploaded files for malware.
+type Scan struct {
+	Scanner
+}
+
+func (s Scan) apply(o *options) {
+	o.Scanner = s.Scanner
+}
+
+// Instrumenter to observe file uploads.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
+
+// Logger to log rejected files.
+type Logger struct {
+	*zap.Logger
+}
+
+func (l Logger) apply(o *options) {
+	o.Logger = l.Logger
+}
@@ -0,0 +1,453 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"azugo.io/core/backup"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+const (
+	// InstrumentationUpload is an instrumentation operation name for storing uploaded file.
+	InstrumentationUpload = "upload-store"
+	// InstrumentationScan is an instrumentation operation name for scanning uploaded file.
+	InstrumentationScan = "upload-scan"
+)
+
+var (
+	// ErrNotMultipart is returned when request is not a multipart form.
+	ErrNotMultipart = errors.New("request is not a multipart form")
+	// ErrFileTooLarge is returned when uploaded file exceeds maximum file size.
+	ErrFileTooLarge = errors.New("uploaded file is too large")
+	// ErrTooManyFiles is returned when request contains more files than allowed.
+	ErrTooManyFiles = errors.New("too many uploaded files")
+	// ErrFormTooLarge is returned when form values exceed maximum form size.
+	ErrFormTooLarge = errors.New("form values are too large")
+	// ErrNotFound is returned when uploaded file is not found.
+	ErrNotFound = errors.New("uploaded file not found")
+)
+
+// ErrTypeNotAllowed is returned when uploaded file content type is not allowed.
+type ErrTypeNotAllowed struct {
+	Name        string
+	ContentType string
+}
+
+func (e ErrTypeNotAllowed) Error() string {
+	return fmt.Sprintf("content type '%s' of file '%s' is not allowed", e.ContentType, e.Name)
+}
+
+// ErrMalware is returned by scanner when malware is detected in the uploaded file.
+type ErrMalware struct {
+	Signature string
+}
+
+func (e ErrMalware) Error() string {
+	return fmt.Sprintf("malware detected: %s", e.Signature)
+}
+
+// ErrRejected is returned when uploaded file is rejected by the scanner.
+type ErrRejected struct {
+	Name string
+	Err  error
+}
+
+func (e ErrRejected) Error() string {
+	return fmt.Sprintf("file '%s' rejected by scanner: %s", e.Name, e.Err)
+}
+
+func (e ErrRejected) Unwrap() error {
+	return e.Err
+}
+
+// Scanner scans uploaded files for malware.
+type Scanner interface {
+	// Scan file content. Content is streamed while file is being stored, scanner
+	// must return ErrMalware if malware is detected. Any returned error rejects the file.
+	Scan(ctx context.Context, r io.Reader, file *File) error
+}
+
+// ScannerFunc is a function that scans uploaded files for malware.
+type ScannerFunc func(ctx context.Context, r io.Reader, file *File) error
+
+// Scan file content.
+func (f ScannerFunc) Scan(ctx context.Context, r io.Reader, file *File) error {
+	return f(ctx, r, file)
+}
+
+// File is a metadata record of the uploaded file.
+type File struct {
+	// ID is a unique identifier of the uploaded file.
+	ID string `json:"id"`
+	// Field is a form field name file was uploaded with.
+	Field string `json:"field"`
+	// Name is a sanitized original file name.
+	Name string `json:"name"`
+	// ContentType is a content type detected from the file content.
+	ContentType string `json:"content_type"`
+	// DeclaredType is a content type provided by the client.
+	DeclaredType string `json:"declared_type,omitempty"`
+	// Size of the file in bytes.
+	Size int64 `json:"size"`
+	// SHA256 is a hex encoded SHA-256 checksum of the file content.
+	SHA256 string `json:"sha256"`
+	// Created is a time when file was uploaded.
+	Created time.Time `json:"created"`
+}
+
+// Result is a parsed multipart form.
+type Result struct {
+	// Files are uploaded files stored in the blob storage.
+	Files []*File
+	// Values are non-file form values.
+	Values url.Values
+}
+
+// File returns first uploaded file with the field name or nil if not found.
+func (r *Result) File(field string) *File {
+	for _, f := range r.Files {
+		if f.Field == field {
+			return f
+		}
+	}
+	return nil
+}
+
+// Uploader streams uploaded files from multipart requests into the blob storage.
+type Uploader struct {
+	storage backup.Storage
+	opt     *options
+	now     func() time.Time
+}
+
+// New returns new uploader storing files in the blob storage.
+func New(storage backup.Storage, opts ...Option) *Uploader {
+	return &Uploader{
+		storage: storage,
+		opt:     newOptions(opts...),
+		now:     time.Now,
+	}
+}
+
+// Parse multipart form request streaming files into the blob storage.
+//
+// If any of the files is rejected, all files already stored from the request are
+// deleted and error is returned.
+func (u *Uploader) Parse(r *http.Request) (*Result, error) {
+	ctx := r.Context()
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, ErrNotMultipart
+	}
+
+	res := &Result{
+		Files:  make([]*File, 0, 1),
+		Values: make(url.Values),
+	}
+	cleanup := func() {
+		for _, f := range res.Files {
+			_ = u.Delete(context.Background(), f.ID)
+		}
+	}
+
+	formSize := u.opt.MaxFormSize
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+
+		field := part.FormName()
+		if len(field) == 0 {
+			_ = part.Close()
+			continue
+		}
+
+		if len(part.FileName()) == 0 {
+			v, err := io.ReadAll(io.LimitReader(part, formSize+1))
+			_ = part.Close()
+			if err != nil {
+				cleanup()
+				return nil, err
+			}
+			formSize -= int64(len(v))
+			if formSize < 0 {
+				cleanup()
+				return nil, ErrFormTooLarge
+			}
+			res.Values.Add(field, string(v))
+			continue
+		}
+
+		if len(res.Files) >= u.opt.MaxFiles {
+			_ = part.Close()
+			cleanup()
+			return nil, ErrTooManyFiles
+		}
+
+		f, err := u.store(ctx, field, sanitizeName(part.FileName()), part.Header.Get("Content-Type"), part)
+		_ = part.Close()
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		res.Files = append(res.Files, f)
+	}
+
+	return res, nil
+}
+
+// Store streams file content into the blob storage.
+func (u *Uploader) Store(ctx context.Context, name, contentType string, r io.Reader) (*File, error) {
+	return u.store(ctx, "", sanitizeName(name), contentType, r)
+}
+
+func (u *Uploader) store(ctx context.Context, field, name, declared string, r io.Reader) (*File, error) {
+	finish := u.opt.Instrumenter.Observe(ctx, InstrumentationUpload, name)
+
+	f, err := u.storeFile(ctx, field, name, declared, r)
+	if err != nil {
+		u.opt.Logger.Warn("uploaded file rejected", zap.String("name", name), zap.Error(err))
+	}
+
+	finish(err)
+	return f, err
+}
+
+func (u *Uploader) storeFile(ctx context.Context, field, name, declared string, r io.Reader) (*File, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(r, 512)
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+
+	if mt, _, err := mime.ParseMediaType(declared); err == nil {
+		declared = mt
+	} else {
+		declared = ""
+	}
+
+	f := &File{
+		ID:           id,
+		Field:        field,
+		Name:         name,
+		ContentType:  detectContentType(head, name),
+		DeclaredType: declared,
+		Created:      u.now().UTC(),
+	}
+	if !u.allowed(f.ContentType) {
+		return nil, ErrTypeNotAllowed{Name: name, ContentType: f.ContentType}
+	}
+
+	lr := &limitedReader{r: br, n: u.opt.MaxFileSize, h: sha256.New()}
+
+	var src io.Reader = lr
+	var scanned chan error
+	var pw *io.PipeWriter
+	if u.opt.Scanner != nil {
+		var pr *io.PipeReader
+		pr, pw = io.Pipe()
+		scanned = make(chan error, 1)
+		go func() {
+			finish := u.opt.Instrumenter.Observe(ctx, InstrumentationScan, name)
+			err := u.opt.Scanner.Scan(ctx, pr, f)
+			finish(err)
+			// Drain rest of the content if scanner finished early.
+			_, _ = io.Copy(io.Discard, pr)
+			scanned <- err
+		}()
+		src = io.TeeReader(lr, pw)
+	}
+
+	err = u.storage.Put(ctx, u.blobName(id), src)
+	if pw != nil {
+		_ = pw.CloseWithError(err)
+		if serr := <-scanned; err == nil && serr != nil {
+			err = ErrRejected{Name: name, Err: serr}
+		}
+	}
+	if err != nil {
+		_ = u.storage.Delete(context.Background(), u.blobName(id))
+		return nil, err
+	}
+
+	f.Size = lr.read
+	f.SHA256 = hex.EncodeToString(lr.h.Sum(nil))
+
+	meta, err := json.Marshal(f)
+	if err != nil {
+		_ = u.storage.Delete(context.Background(), u.blobName(id))
+		return nil, err
+	}
+	if err := u.storage.Put(ctx, u.metaName(id), bytes.NewReader(meta)); err != nil {
+		_ = u.storage.Delete(context.Background(), u.blobName(id))
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Stat returns metadata record of the uploaded file.
+func (u *Uploader) Stat(ctx context.Context, id string) (*File, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	r, err := u.storage.Get(ctx, u.metaName(id))
+	if errors.Is(err, backup.ErrBlobNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f := &File{}
+	if err := json.NewDecoder(r).Decode(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Open returns content and metadata record of the uploaded file.
+func (u *Uploader) Open(ctx context.Context, id string) (io.ReadCloser, *File, error) {
+	f, err := u.Stat(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := u.storage.Get(ctx, u.blobName(id))
+	if errors.Is(err, backup.ErrBlobNotFound) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, f, nil
+}
+
+// Delete uploaded file and its metadata record.
+func (u *Uploader) Delete(ctx context.Context, id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	if err := u.storage.Delete(ctx, u.blobName(id)); err != nil {
+		return err
+	}
+	return u.storage.Delete(ctx, u.metaName(id))
+}
+
+func (u *Uploader) blobName(id string) string {
+	return path.Join(u.opt.Prefix, id)
+}
+
+func (u *Uploader) metaName(id string) string {
+	return path.Join(u.opt.Prefix, id+".json")
+}
+
+func (u *Uploader) allowed(contentType string) bool {
+	if len(u.opt.AllowedTypes) == 0 {
+		return true
+	}
+	for _, t := range u.opt.AllowedTypes {
+		if strings.EqualFold(t, contentType) {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(contentType, strings.ToLower(strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectContentType detects content type from the file content. Text files are
+// refined using file name extension.
+func detectContentType(head []byte, name string) string {
+	ct, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if ct == "text/plain" {
+		if et, _, err := mime.ParseMediaType(mime.TypeByExtension(path.Ext(name))); err == nil && strings.HasPrefix(et, "text/") {
+			return et
+		}
+	}
+	return ct
+}
+
+// sanitizeName returns file name without path and control characters.
+func sanitizeName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+	return name
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// limitedReader reads at most n bytes returning ErrFileTooLarge if content is
+// larger and calculates content hash.
+type limitedReader struct {
+	r    io.Reader
+	n    int64
+	read int64
+	h    hash.Hash
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.n {
+		return 0, ErrFileTooLarge
+	}
+	_, _ = l.h.Write(p[:n])
+	return n, err
+}
@@ -0,0 +1,180 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"azugo.io/core/backup"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPart struct {
+	field    string
+	filename string
+	ctype    string
+	content  string
+}
+
+func newUploadRequest(t *testing.T, parts ...testPart) *http.Request {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	for _, p := range parts {
+		if len(p.filename) == 0 {
+			require.NoError(t, w.WriteField(p.field, p.content))
+			continue
+		}
+		h := make(map[string][]string)
+		h["Content-Disposition"] = []string{`form-data; name="` + p.field + `"; filename="` + p.filename + `"`}
+		if len(p.ctype) != 0 {
+			h["Content-Type"] = []string{p.ctype}
+		}
+		pw, err := w.CreatePart(h)
+		require.NoError(t, err)
+		_, err = pw.Write([]byte(p.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", buf)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return r
+}
+
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+func TestUploaderParse(t *testing.T) {
+	storage := &backup.Memory{}
+	u := New(storage)
+
+	res, err := u.Parse(newUploadRequest(t,
+		testPart{field: "title", content: "Holiday"},
+		testPart{field: "photo", filename: "../../photo.png", ctype: "text/plain", content: pngHeader + "data"},
+		testPart{field: "style", filename: "style.css", content: "body { margin: 0; }"},
+	))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Holiday", res.Values.Get("title"))
+	require.Len(t, res.Files, 2)
+
+	photo := res.File("photo")
+	require.NotNil(t, photo)
+	assert.Equal(t, "photo.png", photo.Name)
+	assert.Equal(t, "image/png", photo.ContentType)
+	assert.Equal(t, "text/plain", photo.DeclaredType)
+	assert.Equal(t, int64(len(pngHeader)+4), photo.Size)
+	sum := sha256.Sum256([]byte(pngHeader + "data"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), photo.SHA256)
+
+	assert.Equal(t, "text/css", res.File("style").ContentType)
+	assert.Nil(t, res.File("missing"))
+
+	r, f, err := u.Open(context.TODO(), photo.ID)
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, pngHeader+"data", string(data))
+	assert.Equal(t, photo.SHA256, f.SHA256)
+	assert.Equal(t, photo.Created, f.Created)
+
+	require.NoError(t, u.Delete(context.TODO(), photo.ID))
+	_, err = u.Stat(context.TODO(), photo.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = u.Stat(context.TODO(), "../secret")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUploaderLimits(t *testing.T) {
+	storage := &backup.Memory{}
+	u := New(storage, MaxFileSize(10), MaxFiles(1), MaxFormSize(5))
+
+	_, err := u.Parse(newUploadRequest(t, testPart{field: "f", filename: "a.txt", content: strings.Repeat("a", 11)}))
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+
+	_, err = u.Parse(newUploadRequest(t,
+		testPart{field: "f", filename: "a.txt", content: "a"},
+		testPart{field: "f", filename: "b.txt", content: "b"},
+	))
+	assert.ErrorIs(t, err, ErrTooManyFiles)
+
+	_, err = u.Parse(newUploadRequest(t, testPart{field: "v", content: "123456"}))
+	assert.ErrorIs(t, err, ErrFormTooLarge)
+
+	_, err = u.Parse(httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("{}")))
+	assert.ErrorIs(t, err, ErrNotMultipart)
+
+	// Files stored before failure are deleted.
+	names, err := storage.List(context.TODO(), "")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestUploaderAllowedTypes(t *testing.T) {
+	u := New(&backup.Memory{}, AllowedTypes{"image/*", "application/pdf"})
+
+	_, err := u.Parse(newUploadRequest(t, testPart{field: "f", filename: "image.png", content: "<html><script>alert(1)</script></html>", ctype: "image/png"}))
+	var terr ErrTypeNotAllowed
+	require.ErrorAs(t, err, &terr)
+	assert.Equal(t, "text/html", terr.ContentType)
+
+	res, err := u.Parse(newUploadRequest(t, testPart{field: "f", filename: "image.png", content: pngHeader}))
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", res.Files[0].ContentType)
+}
+
+func TestUploaderScanner(t *testing.T) {
+	storage := &backup.Memory{}
+	var scanned []string
+	u := New(storage, Scan{ScannerFunc(func(ctx context.Context, r io.Reader, f *File) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		scanned = append(scanned, f.Name)
+		if strings.Contains(string(data), "EICAR") {
+			return ErrMalware{Signature: "EICAR-Test-File"}
+		}
+		return nil
+	})})
+
+	res, err := u.Parse(newUploadRequest(t, testPart{field: "f", filename: "clean.txt", content: "clean"}))
+	require.NoError(t, err)
+	require.Len(t, res.Files, 1)
+
+	_, err = u.Parse(newUploadRequest(t,
+		testPart{field: "f", filename: "ok.txt", content: "ok"},
+		testPart{field: "f", filename: "virus.txt", content: "X5O!P%@AP EICAR"},
+	))
+	var merr ErrMalware
+	require.ErrorAs(t, err, &merr)
+	assert.Equal(t, "EICAR-Test-File", merr.Signature)
+	assert.Equal(t, []string{"clean.txt", "ok.txt", "virus.txt"}, scanned)
+
+	names, err := storage.List(context.TODO(), "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"uploads/" + res.Files[0].ID, "uploads/" + res.Files[0].ID + ".json"}, names)
+}
+
+func TestUploaderScannerEarlyExit(t *testing.T) {
+	u := New(&backup.Memory{}, Scan{ScannerFunc(func(ctx context.Context, r io.Reader, f *File) error {
+		return nil
+	})})
+
+	f, err := u.Store(context.TODO(), "large.bin", "", bytes.NewReader(make([]byte, 1<<20)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<20), f.Size)
+	assert.Equal(t, "application/octet-stream", f.ContentType)
+}
@@ -0,0 +1,270 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Dependency is an external service that must be available before application can start.
+type Dependency interface {
+	// Name returns dependency name.
+	Name() string
+	// Check returns error if dependency is not available.
+	Check(ctx context.Context) error
+}
+
+type dependencyFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (d *dependencyFunc) Name() string {
+	return d.name
+}
+
+func (d *dependencyFunc) Check(ctx context.Context) error {
+	return d.check(ctx)
+}
+
+// DependencyFunc returns dependency that is checked using the function.
+func DependencyFunc(name string, check func(ctx context.Context) error) Dependency {
+	return &dependencyFunc{
+		name:  name,
+		check: check,
+	}
+}
+
+// PingDependency returns dependency that is checked by calling Ping method,
+// for example cache instance.
+func PingDependency(name string, p interface{ Ping(ctx context.Context) error }) Dependency {
+	return DependencyFunc(name, p.Ping)
+}
+
+// DatabaseDependency returns dependency that is checked by calling PingContext method,
+// for example *sql.DB.
+func DatabaseDependency(name string, db interface{ PingContext(ctx context.Context) error }) Dependency {
+	return DependencyFunc(name, db.PingContext)
+}
+
+// TCPDependency returns dependency that is checked by opening TCP connection to
+// the address, for example queue broker.
+func TCPDependency(name, addr string) Dependency {
+	return DependencyFunc(name, func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// HTTPDependency returns dependency that is checked by sending GET request to the URL,
+// for example remote CA. Any response with status code below 500 is considered available.
+//
+// If client is nil, http.DefaultClient is used.
+func HTTPDependency(name, url string, client *http.Client) Dependency {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return DependencyFunc(name, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+		return nil
+	})
+}
+
+// WaitError is returned when dependencies did not become available in time.
+type WaitError struct {
+	// Errors contains last check error for each unavailable dependency.
+	Errors map[string]error
+}
+
+func (e *WaitError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", name, e.Errors[name]))
+	}
+	return "dependencies not available: " + strings.Join(msgs, "; ")
+}
+
+type waitOptions struct {
+	Timeout      time.Duration
+	CheckTimeout time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+	Logger       *zap.Logger
+}
+
+// WaitOption is an option for waiting for dependencies.
+type WaitOption interface {
+	applyWait(*waitOptions)
+}
+
+// WaitTimeout is a total time to wait for all dependencies.
+type WaitTimeout time.Duration
+
+func (t WaitTimeout) applyWait(o *waitOptions) {
+	o.Timeout = time.Duration(t)
+}
+
+// WaitCheckTimeout is a time limit for a single dependency check.
+type WaitCheckTimeout time.Duration
+
+func (t WaitCheckTimeout) applyWait(o *waitOptions) {
+	o.CheckTimeout = time.Duration(t)
+}
+
+// WaitBackoff is a minimum and maximum delay between dependency checks.
+type WaitBackoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func (b WaitBackoff) applyWait(o *waitOptions) {
+	o.MinBackoff = b.Min
+	o.MaxBackoff = b.Max
+}
+
+// WaitLogger is a logger to report waiting progress.
+type WaitLogger struct {
+	*zap.Logger
+}
+
+func (l WaitLogger) applyWait(o *waitOptions) {
+	o.Logger = l.Logger
+}
+
+// Waiter waits for dependencies to become available.
+type Waiter struct {
+	opts *waitOptions
+}
+
+// NewWaiter creates new dependency waiter.
+func NewWaiter(opts ...WaitOption) *Waiter {
+	opt := &waitOptions{
+		Timeout:      2 * time.Minute,
+		CheckTimeout: 5 * time.Second,
+		MinBackoff:   500 * time.Millisecond,
+		MaxBackoff:   10 * time.Second,
+	}
+	for _, o := range opts {
+		o.applyWait(opt)
+	}
+	if opt.Logger == nil {
+		opt.Logger = zap.NewNop()
+	}
+	return &Waiter{
+		opts: opt,
+	}
+}
+
+// WaitFor waits for all dependencies to become available using default options.
+func WaitFor(ctx context.Context, deps ...Dependency) error {
+	return NewWaiter().Wait(ctx, deps...)
+}
+
+// Wait polls all dependencies concurrently with exponential backoff until they are available
+// or total deadline is reached.
+func (w *Waiter) Wait(ctx context.Context, deps ...Dependency) error {
+	if w.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.opts.Timeout)
+		defer cancel()
+	}
+
+	var lock sync.Mutex
+	errs := make(map[string]error)
+
+	var wg sync.WaitGroup
+	for _, dep := range deps {
+		wg.Add(1)
+		go func(dep Dependency) {
+			defer wg.Done()
+
+			if err := w.wait(ctx, dep); err != nil {
+				lock.Lock()
+				errs[dep.Name()] = err
+				lock.Unlock()
+			}
+		}(dep)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return &WaitError{Errors: errs}
+	}
+	return nil
+}
+
+func (w *Waiter) check(ctx context.Context, dep Dependency) error {
+	if w.opts.CheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.opts.CheckTimeout)
+		defer cancel()
+	}
+	return dep.Check(ctx)
+}
+
+func (w *Waiter) wait(ctx context.Context, dep Dependency) error {
+	log := w.opts.Logger.With(zap.String("dependency", dep.Name()))
+	backoff := w.opts.MinBackoff
+	for attempt := 1; ; attempt++ {
+		err := w.check(ctx, dep)
+		if err == nil {
+			if attempt > 1 {
+				log.Info("Dependency is available", zap.Int("attempt", attempt))
+			}
+			return nil
+		}
+
+		log.Info("Waiting for dependency", zap.Int("attempt", attempt), zap.Duration("retry_in", backoff), zap.Error(err))
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+
+		backoff *= 2
+		if backoff > w.opts.MaxBackoff {
+			backoff = w.opts.MaxBackoff
+		}
+	}
+}
+
+// WaitFor waits for all dependencies to become available logging progress using
+// application logger.
+func (a *App) WaitFor(deps ...Dependency) error {
+	return NewWaiter(WaitLogger{a.Log()}).Wait(a.BackgroundContext(), deps...)
+}
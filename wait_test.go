@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitFor(t *testing.T) {
+	attempts := 0
+	dep := DependencyFunc("flaky", func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+
+	w := NewWaiter(WaitBackoff{Min: time.Millisecond, Max: 5 * time.Millisecond})
+	require.NoError(t, w.Wait(context.Background(), dep))
+	assert.Equal(t, 3, attempts)
+}
+
+func TestWaitForTimeout(t *testing.T) {
+	ok := DependencyFunc("ok", func(ctx context.Context) error { return nil })
+	down := DependencyFunc("down", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	w := NewWaiter(WaitTimeout(20*time.Millisecond), WaitBackoff{Min: time.Millisecond, Max: time.Millisecond})
+	err := w.Wait(context.Background(), ok, down)
+
+	var werr *WaitError
+	require.ErrorAs(t, err, &werr)
+	assert.Len(t, werr.Errors, 1)
+	assert.EqualError(t, err, "dependencies not available: down: connection refused")
+}
@@ -0,0 +1,271 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"azugo.io/core/drain"
+
+	"go.uber.org/zap"
+)
+
+const (
+	InstrumentationWarmup = "warmup"
+)
+
+// Warmer is a component that runs preparatory work, for example fills caches or
+// loads certificates, before application is reported as ready.
+type Warmer interface {
+	// Name returns warmer name.
+	Name() string
+	// Warmup runs preparatory work. Context is canceled when warmup budget is exceeded.
+	Warmup(ctx context.Context) error
+}
+
+type warmerFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (w *warmerFunc) Name() string {
+	return w.name
+}
+
+func (w *warmerFunc) Warmup(ctx context.Context) error {
+	return w.fn(ctx)
+}
+
+// WarmerFunc returns warmer that runs the function.
+func WarmerFunc(name string, fn func(ctx context.Context) error) Warmer {
+	return &warmerFunc{
+		name: name,
+		fn:   fn,
+	}
+}
+
+type warmerOptions struct {
+	Required bool
+	Budget   time.Duration
+}
+
+// WarmerOption is an option for the warmer.
+type WarmerOption interface {
+	applyWarmer(*warmerOptions)
+}
+
+// WarmerRequired fails application start if warmer fails. Failures of other
+// warmers are only logged.
+type WarmerRequired bool
+
+func (r WarmerRequired) applyWarmer(o *warmerOptions) {
+	o.Required = bool(r)
+}
+
+// WarmerBudget is a time budget for the warmer. Warmer is always limited by the
+// total warmup budget.
+type WarmerBudget time.Duration
+
+func (b WarmerBudget) applyWarmer(o *warmerOptions) {
+	o.Budget = time.Duration(b)
+}
+
+type warmer struct {
+	Warmer
+	opts warmerOptions
+}
+
+// WarmupResult is a result of a single warmer.
+type WarmupResult struct {
+	// Name of the warmer.
+	Name string
+	// Duration of the warmup.
+	Duration time.Duration
+	// Err is an error returned by the warmer.
+	Err error
+}
+
+// WarmupReport is a result of the application warmup phase.
+type WarmupReport struct {
+	// Duration of the whole warmup phase.
+	Duration time.Duration
+	// Results of all warmers.
+	Results []WarmupResult
+}
+
+// WarmupError is returned when required warmers fail.
+type WarmupError struct {
+	// Errors contains errors of failed required warmers.
+	Errors map[string]error
+}
+
+func (e *WarmupError) Error() string {
+	return (&WaitError{Errors: e.Errors}).Error()
+}
+
+// AddWarmer adds component to run in the warmup phase of the application start.
+//
+// If warmup phase has already completed, warmer is run immediately.
+func (a *App) AddWarmer(w Warmer, opts ...WarmerOption) error {
+	ww := &warmer{Warmer: w}
+	for _, o := range opts {
+		o.applyWarmer(&ww.opts)
+	}
+
+	a.warmlock.Lock()
+	if !a.warmedUp {
+		a.warmers = append(a.warmers, ww)
+		a.warmlock.Unlock()
+		return nil
+	}
+	a.warmlock.Unlock()
+
+	res := a.runWarmer(a.BackgroundContext(), ww, time.Duration(a.Config().Warmup.Timeout))
+	if res.Err != nil && ww.opts.Required {
+		return res.Err
+	}
+	return nil
+}
+
+func (a *App) runWarmer(ctx context.Context, w *warmer, budget time.Duration) WarmupResult {
+	if w.opts.Budget > 0 && (budget <= 0 || w.opts.Budget < budget) {
+		budget = w.opts.Budget
+	}
+	if budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+
+	log := a.Log().With(zap.String("warmer", w.Name()))
+
+	a.drainlock.Lock()
+	d := a.drainer
+	a.drainlock.Unlock()
+	if d != nil {
+		release, err := d.Acquire(drain.Background)
+		if err != nil {
+			log.Warn("Warmup skipped", zap.Error(err))
+			return WarmupResult{Name: w.Name(), Err: err}
+		}
+		defer release()
+	}
+
+	finish := a.Instrumenter().Observe(ctx, InstrumentationWarmup, w.Name())
+	start := time.Now()
+	err := w.Warmup(ctx)
+	if err == nil && ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("warmup budget %s exceeded", budget)
+	}
+	res := WarmupResult{
+		Name:     w.Name(),
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	finish(err)
+
+	if err != nil {
+		log.Warn("Warmup failed", zap.Duration("event.duration", res.Duration), zap.Error(err))
+	} else {
+		log.Info("Warmup completed", zap.Duration("event.duration", res.Duration))
+	}
+	return res
+}
+
+// warmup runs all warmers concurrently within the total warmup budget.
+//
+// Warmers added while warmup is in progress are run after the current ones
+// complete, before the application is marked as ready.
+func (a *App) warmup() error {
+	a.warmlock.Lock()
+	if a.warmedUp {
+		a.warmlock.Unlock()
+		return nil
+	}
+	a.warmlock.Unlock()
+
+	budget := time.Duration(a.Config().Warmup.Timeout)
+	ctx := a.BackgroundContext()
+	if budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+
+	start := time.Now()
+	results := make([]WarmupResult, 0)
+	errs := make(map[string]error)
+	for {
+		a.warmlock.Lock()
+		warmers := append([]*warmer{}, a.warmers[len(results):]...)
+		if len(warmers) == 0 {
+			a.warmupReport = WarmupReport{
+				Duration: time.Since(start),
+				Results:  results,
+			}
+			if len(errs) == 0 {
+				a.warmedUp = true
+			}
+			a.warmlock.Unlock()
+			break
+		}
+		a.warmlock.Unlock()
+
+		res := make([]WarmupResult, len(warmers))
+		var wg sync.WaitGroup
+		for i, w := range warmers {
+			wg.Add(1)
+			go func(i int, w *warmer) {
+				defer wg.Done()
+				res[i] = a.runWarmer(ctx, w, budget)
+			}(i, w)
+		}
+		wg.Wait()
+
+		for i, r := range res {
+			if r.Err != nil && warmers[i].opts.Required {
+				errs[r.Name] = r.Err
+			}
+		}
+		results = append(results, res...)
+	}
+
+	if len(errs) > 0 {
+		return &WarmupError{Errors: errs}
+	}
+	return nil
+}
+
+// WarmupReport returns result of the application warmup phase.
+func (a *App) WarmupReport() WarmupReport {
+	a.warmlock.Lock()
+	defer a.warmlock.Unlock()
+
+	return a.warmupReport
+}
+
+// Ready returns true if application has started and warmup phase has completed.
+func (a *App) Ready() bool {
+	a.warmlock.Lock()
+	defer a.warmlock.Unlock()
+
+	return a.warmedUp
+}
+
+// ReadinessHandler returns HTTP handler for the readiness probe that responds
+// with 503 Service Unavailable until application is ready.
+func (a *App) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Ready() {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmup(t *testing.T) {
+	a, cleanup, _, err := newTestApp()
+	require.NoError(t, err)
+	t.Cleanup(cleanup)
+
+	warmups := make(chan string, 10)
+	a.Instrumentation(func(ctx context.Context, op string, args ...any) func(err error) {
+		if op == InstrumentationWarmup {
+			warmups <- args[0].(string)
+		}
+		return func(err error) {}
+	})
+
+	var warmed bool
+	require.NoError(t, a.AddWarmer(WarmerFunc("cache", func(ctx context.Context) error {
+		warmed = true
+		return nil
+	})))
+	require.NoError(t, a.AddWarmer(WarmerFunc("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}), WarmerBudget(10*time.Millisecond)))
+
+	h := a.ReadinessHandler()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	require.NoError(t, a.Start())
+	assert.True(t, warmed)
+	assert.True(t, a.Ready())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	report := a.WarmupReport()
+	require.Len(t, report.Results, 2)
+	assert.Equal(t, "cache", report.Results[0].Name)
+	assert.NoError(t, report.Results[0].Err)
+	assert.ErrorIs(t, report.Results[1].Err, context.DeadlineExceeded)
+	assert.Len(t, warmups, 2)
+
+	// Warmer added after start is run immediately.
+	assert.Error(t, a.AddWarmer(WarmerFunc("late", func(ctx context.Context) error {
+		return errors.New("failed")
+	}), WarmerRequired(true)))
+	assert.True(t, a.Ready())
+
+	a.Stop()
+	assert.False(t, a.Ready())
+}
+
+func TestWarmupRequiredFailure(t *testing.T) {
+	a, cleanup, _, err := newTestApp()
+	require.NoError(t, err)
+	t.Cleanup(cleanup)
+
+	require.NoError(t, a.AddWarmer(WarmerFunc("certificates", func(ctx context.Context) error {
+		return errors.New("certificate not found")
+	}), WarmerRequired(true)))
+
+	err = a.Start()
+	var werr *WarmupError
+	require.ErrorAs(t, err, &werr)
+	assert.EqualError(t, werr.Errors["certificates"], "certificate not found")
+	assert.False(t, a.Ready())
+}
+
+func TestWarmupAddedWhileRunning(t *testing.T) {
+	a, cleanup, _, err := newTestApp()
+	require.NoError(t, err)
+	t.Cleanup(cleanup)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	require.NoError(t, a.AddWarmer(WarmerFunc("slow", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Start()
+	}()
+
+	<-started
+	var warmed bool
+	require.NoError(t, a.AddWarmer(WarmerFunc("late", func(ctx context.Context) error {
+		warmed = true
+		return nil
+	})))
+	close(release)
+
+	require.NoError(t, <-done)
+	assert.True(t, warmed)
+	assert.True(t, a.Ready())
+
+	report := a.WarmupReport()
+	require.Len(t, report.Results, 2)
+	assert.Equal(t, "slow", report.Results[0].Name)
+	assert.Equal(t, "late", report.Results[1].Name)
+
+	a.Stop()
+}
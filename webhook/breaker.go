@@ -0,0 +1,55 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"sync"
+	"time"
+)
+
+type breaker struct {
+	lock      sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// allow returns true if request to the endpoint is allowed.
+func (b *breaker) allow(now time.Time) bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if now.Before(b.openUntil) {
+		return false
+	}
+	// Half-open state, allow single request and wait for its result.
+	b.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+func (b *breaker) success() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures = 0
+}
+
+func (b *breaker) failure(now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
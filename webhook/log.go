@@ -0,0 +1,115 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Delivery is a record of webhook delivery attempt.
+type Delivery struct {
+	ID         string        `json:"id"`
+	EndpointID string        `json:"endpoint_id"`
+	EventID    string        `json:"event_id"`
+	EventType  string        `json:"event_type"`
+	URL        string        `json:"url"`
+	Attempt    int           `json:"attempt"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Success    bool          `json:"success"`
+}
+
+// Filter for querying delivery log.
+type Filter struct {
+	// EndpointID to filter deliveries by.
+	EndpointID string
+	// EventID to filter deliveries by.
+	EventID string
+	// FailedOnly returns only failed deliveries.
+	FailedOnly bool
+	// Limit maximum number of returned deliveries. Zero means no limit.
+	Limit int
+}
+
+func (f Filter) match(d *Delivery) bool {
+	if len(f.EndpointID) != 0 && f.EndpointID != d.EndpointID {
+		return false
+	}
+	if len(f.EventID) != 0 && f.EventID != d.EventID {
+		return false
+	}
+	if f.FailedOnly && d.Success {
+		return false
+	}
+	return true
+}
+
+// DeliveryLog stores webhook delivery attempts.
+type DeliveryLog interface {
+	// Record delivery attempt.
+	Record(ctx context.Context, d *Delivery) error
+	// Query delivery attempts, newest first.
+	Query(ctx context.Context, f Filter) ([]Delivery, error)
+}
+
+// MemoryLog keeps last delivery attempts in memory.
+type MemoryLog struct {
+	lock  sync.RWMutex
+	items []Delivery
+	next  int
+	full  bool
+}
+
+// NewMemoryLog creates new in-memory delivery log that keeps up to size last attempts.
+func NewMemoryLog(size int) *MemoryLog {
+	if size <= 0 {
+		size = 100
+	}
+	return &MemoryLog{
+		items: make([]Delivery, size),
+	}
+}
+
+// Record delivery attempt.
+func (l *MemoryLog) Record(_ context.Context, d *Delivery) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.items[l.next] = *d
+	l.next++
+	if l.next == len(l.items) {
+		l.next = 0
+		l.full = true
+	}
+	return nil
+}
+
+// Query delivery attempts, newest first.
+func (l *MemoryLog) Query(_ context.Context, f Filter) ([]Delivery, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	cnt := l.next
+	if l.full {
+		cnt = len(l.items)
+	}
+
+	res := make([]Delivery, 0)
+	for i := 1; i <= cnt; i++ {
+		d := &l.items[(l.next-i+len(l.items))%len(l.items)]
+		if !f.match(d) {
+			continue
+		}
+		res = append(res, *d)
+		if f.Limit > 0 && len(res) == f.Limit {
+			break
+		}
+	}
+	return res, nil
+}
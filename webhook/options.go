@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"net/http"
+	"time"
+
+	"azugo.io/core/instrumenter"
+	"azugo.io/core/keyring"
+	"azugo.io/core/queue"
+)
+
+type options struct {
+	Client           *http.Client
+	KeyRing          *keyring.KeyRing
+	MaxAttempts      int
+	MinBackoff       time.Duration
+	MaxBackoff       time.Duration
+	Workers          int
+	QueueSize        int
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	DeadLetter       queue.Publisher
+	DeadLetterTopic  string
+	Log              DeliveryLog
+	Instrumenter     instrumenter.Instrumenter
+}
+
+func newOptions(opts ...Option) *options {
+	opt := &options{
+		MaxAttempts:      5,
+		MinBackoff:       time.Second,
+		MaxBackoff:       5 * time.Minute,
+		Workers:          4,
+		QueueSize:        1000,
+		BreakerThreshold: 5,
+		BreakerCooldown:  time.Minute,
+		DeadLetterTopic:  "webhooks.dead-letter",
+	}
+	for _, o := range opts {
+		o.apply(opt)
+	}
+	if opt.Client == nil {
+		opt.Client = &http.Client{
+			Timeout: 30 * time.Second,
+		}
+	}
+	if opt.Log == nil {
+		opt.Log = NewMemoryLog(100)
+	}
+	return opt
+}
+
+// Option for the webhook dispatcher.
+type Option interface {
+	apply(*options)
+}
+
+// HTTPClient to use for webhook delivery.
+type HTTPClient struct {
+	*http.Client
+}
+
+func (c HTTPClient) apply(o *options) {
+	o.Client = c.Client
+}
+
+// KeyRing used to sign webhook payloads.
+type KeyRing struct {
+	*keyring.KeyRing
+}
+
+func (k KeyRing) apply(o *options) {
+	o.KeyRing = k.KeyRing
+}
+
+// MaxAttempts is maximum number of delivery attempts before webhook is dead-lettered.
+type MaxAttempts int
+
+func (m MaxAttempts) apply(o *options) {
+	o.MaxAttempts = int(m)
+}
+
+// Backoff between delivery attempts. Delay is doubled after every failed attempt up to Max.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func (b Backoff) apply(o *options) {
+	o.MinBackoff = b.Min
+	o.MaxBackoff = b.Max
+}
+
+// Workers is a number of concurrent delivery workers.
+type Workers int
+
+func (w Workers) apply(o *options) {
+	o.Workers = int(w)
+}
+
+// QueueSize is a size of pending deliveries buffer.
+type QueueSize int
+
+func (q QueueSize) apply(o *options) {
+	o.QueueSize = int(q)
+}
+
+// CircuitBreaker configures per-endpoint circuit breaker.
+//
+// After Threshold consecutive failures endpoint is not called for Cooldown duration.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+}
+
+func (c CircuitBreaker) apply(o *options) {
+	o.BreakerThreshold = c.Threshold
+	o.BreakerCooldown = c.Cooldown
+}
+
+// DeadLetter publishes webhooks that failed all delivery attempts to the queue topic.
+type DeadLetter struct {
+	Publisher queue.Publisher
+	Topic     string
+}
+
+func (d DeadLetter) apply(o *options) {
+	o.DeadLetter = d.Publisher
+	if len(d.Topic) != 0 {
+		o.DeadLetterTopic = d.Topic
+	}
+}
+
+// Log is a delivery log storage.
+type Log struct {
+	DeliveryLog
+}
+
+func (l Log) apply(o *options) {
+	o.Log = l.DeliveryLog
+}
+
+// Instrumenter is a function that instruments webhook delivery.
+type Instrumenter instrumenter.Instrumenter
+
+func (i Instrumenter) apply(o *options) {
+	o.Instrumenter = instrumenter.Instrumenter(i)
+}
@@ -0,0 +1,85 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"azugo.io/core/keyring"
+)
+
+const (
+	// HeaderID is a header containing unique delivery ID.
+	HeaderID = "Webhook-Id"
+	// HeaderEvent is a header containing event type.
+	HeaderEvent = "Webhook-Event"
+	// HeaderSignature is a header containing payload signature.
+	HeaderSignature = "Webhook-Signature"
+)
+
+// ErrInvalidSignature is returned when webhook signature is invalid.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+func signedPayload(ts int64, body []byte) []byte {
+	buf := make([]byte, 0, len(body)+21)
+	buf = strconv.AppendInt(buf, ts, 10)
+	buf = append(buf, '.')
+	return append(buf, body...)
+}
+
+// Sign creates webhook signature header value for the payload.
+//
+// Signature has format: t=<unix timestamp>,k=<key id>,v1=<hex encoded HMAC-SHA256>
+func Sign(keys *keyring.KeyRing, ts time.Time, body []byte) (string, error) {
+	t := ts.Unix()
+	id, sig, err := keys.Sign(signedPayload(t, body))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("t=%d,k=%s,v1=%s", t, id, hex.EncodeToString(sig)), nil
+}
+
+// Verify webhook signature header value.
+//
+// Tolerance specifies maximum allowed age of the signature. Zero tolerance disables the check.
+func Verify(keys *keyring.KeyRing, header string, body []byte, tolerance time.Duration) error {
+	var ts int64
+	var id string
+	var sig []byte
+	for _, p := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok {
+			continue
+		}
+		var err error
+		switch k {
+		case "t":
+			if ts, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return ErrInvalidSignature
+			}
+		case "k":
+			id = v
+		case "v1":
+			if sig, err = hex.DecodeString(v); err != nil {
+				return ErrInvalidSignature
+			}
+		}
+	}
+	if ts == 0 || len(sig) == 0 {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 && time.Since(time.Unix(ts, 0)) > tolerance {
+		return fmt.Errorf("%w: signature expired", ErrInvalidSignature)
+	}
+	if !keys.Verify(id, signedPayload(ts, body), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
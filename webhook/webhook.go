@@ -0,0 +1,337 @@
+// Copyright 2022 Azugo. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"azugo.io/core/keyring"
+	"azugo.io/core/queue"
+)
+
+const (
+	InstrumentationWebhookDeliver    = "webhook-deliver"
+	InstrumentationWebhookDeadLetter = "webhook-dead-letter"
+)
+
+var (
+	// ErrDispatcherStopped is returned when dispatching webhook while dispatcher is not running.
+	ErrDispatcherStopped = errors.New("webhook dispatcher is not running")
+	// ErrQueueFull is returned when pending deliveries buffer is full.
+	ErrQueueFull = errors.New("webhook delivery queue is full")
+)
+
+// Endpoint is a webhook receiver.
+type Endpoint struct {
+	// ID of the endpoint.
+	ID string
+	// URL to deliver webhooks to.
+	URL string
+	// Headers to add to the webhook request.
+	Headers map[string]string
+	// KeyRing to sign webhooks with. Overrides dispatcher key ring.
+	KeyRing *keyring.KeyRing
+}
+
+// Event is a webhook event.
+type Event struct {
+	// ID of the event.
+	ID string
+	// Type of the event.
+	Type string
+	// Payload is a JSON encoded event payload.
+	Payload []byte
+}
+
+type delivery struct {
+	endpoint Endpoint
+	event    Event
+	attempt  int
+}
+
+// Dispatcher delivers webhooks in the background with retries.
+type Dispatcher struct {
+	opts *options
+
+	lock     sync.Mutex
+	breakers map[string]*breaker
+	pending  chan *delivery
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// New creates new webhook dispatcher.
+func New(opts ...Option) *Dispatcher {
+	opt := newOptions(opts...)
+	return &Dispatcher{
+		opts:     opt,
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// Name returns task name.
+func (d *Dispatcher) Name() string {
+	return "webhook-dispatcher"
+}
+
+// Start delivery workers.
+func (d *Dispatcher) Start(ctx context.Context) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.cancel != nil {
+		return nil
+	}
+
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.pending = make(chan *delivery, d.opts.QueueSize)
+
+	workers := d.opts.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.worker(ctx, d.pending)
+	}
+	return nil
+}
+
+// Stop delivery workers.
+//
+// Pending deliveries that have not been delivered are discarded.
+func (d *Dispatcher) Stop() {
+	d.lock.Lock()
+	if d.cancel == nil {
+		d.lock.Unlock()
+		return
+	}
+	d.cancel()
+	d.cancel = nil
+	d.lock.Unlock()
+
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) worker(ctx context.Context, pending chan *delivery) {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case del := <-pending:
+			d.process(ctx, del)
+		}
+	}
+}
+
+func (d *Dispatcher) enqueue(del *delivery) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.cancel == nil {
+		return ErrDispatcherStopped
+	}
+
+	select {
+	case d.pending <- del:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Dispatch queues webhook event for delivery to the endpoint.
+func (d *Dispatcher) Dispatch(ep Endpoint, ev Event) error {
+	if len(ev.ID) == 0 {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		ev.ID = id
+	}
+	return d.enqueue(&delivery{
+		endpoint: ep,
+		event:    ev,
+	})
+}
+
+func (d *Dispatcher) breaker(id string) *breaker {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	b, ok := d.breakers[id]
+	if !ok {
+		b = &breaker{
+			threshold: d.opts.BreakerThreshold,
+			cooldown:  d.opts.BreakerCooldown,
+		}
+		d.breakers[id] = b
+	}
+	return b
+}
+
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.opts.MinBackoff
+	for i := 1; i < attempt && delay < d.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.opts.MaxBackoff {
+		delay = d.opts.MaxBackoff
+	}
+	return delay
+}
+
+func (d *Dispatcher) retry(ctx context.Context, del *delivery, delay time.Duration) {
+	t := time.NewTimer(delay)
+	go func() {
+		defer t.Stop()
+
+		select {
+		case <-ctx.Done():
+		case <-t.C:
+			if err := d.enqueue(del); errors.Is(err, ErrQueueFull) {
+				d.deadLetter(ctx, del, err)
+			}
+		}
+	}()
+}
+
+func (d *Dispatcher) process(ctx context.Context, del *delivery) {
+	b := d.breaker(del.endpoint.ID)
+	if !b.allow(time.Now()) {
+		// Wait for circuit breaker to be half-open without counting attempt.
+		d.retry(ctx, del, d.opts.BreakerCooldown)
+		return
+	}
+
+	del.attempt++
+	err := d.Send(ctx, del.endpoint, del.event, del.attempt)
+	if err == nil {
+		b.success()
+		return
+	}
+	b.failure(time.Now())
+
+	if del.attempt >= d.opts.MaxAttempts {
+		d.deadLetter(ctx, del, err)
+		return
+	}
+	d.retry(ctx, del, d.backoff(del.attempt))
+}
+
+func (d *Dispatcher) deadLetter(ctx context.Context, del *delivery, cause error) {
+	if d.opts.DeadLetter == nil {
+		return
+	}
+
+	finish := d.opts.Instrumenter.Observe(ctx, InstrumentationWebhookDeadLetter, del.endpoint.URL)
+	err := d.opts.DeadLetter.Publish(ctx, &queue.Message{
+		Topic: d.opts.DeadLetterTopic,
+		Key:   del.endpoint.ID,
+		Headers: map[string]string{
+			HeaderID:     del.event.ID,
+			HeaderEvent:  del.event.Type,
+			"Endpoint":   del.endpoint.URL,
+			"Attempts":   fmt.Sprint(del.attempt),
+			"Last-Error": cause.Error(),
+		},
+		Body: del.event.Payload,
+	})
+	finish(err)
+}
+
+// Send makes single delivery attempt of the webhook event to the endpoint and records it in delivery log.
+func (d *Dispatcher) Send(ctx context.Context, ep Endpoint, ev Event, attempt int) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	rec := &Delivery{
+		ID:         id,
+		EndpointID: ep.ID,
+		EventID:    ev.ID,
+		EventType:  ev.Type,
+		URL:        ep.URL,
+		Attempt:    attempt,
+		Timestamp:  time.Now().UTC(),
+	}
+
+	finish := d.opts.Instrumenter.Observe(ctx, InstrumentationWebhookDeliver, ep.URL)
+	rec.StatusCode, err = d.send(ctx, ep, ev)
+	rec.Duration = time.Since(rec.Timestamp)
+	finish(err)
+
+	if err != nil {
+		rec.Error = err.Error()
+	} else {
+		rec.Success = true
+	}
+	_ = d.opts.Log.Record(ctx, rec)
+
+	return err
+}
+
+func (d *Dispatcher) send(ctx context.Context, ep Endpoint, ev Event) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(ev.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, ev.ID)
+	if len(ev.Type) != 0 {
+		req.Header.Set(HeaderEvent, ev.Type)
+	}
+	for k, v := range ep.Headers {
+		req.Header.Set(k, v)
+	}
+
+	keys := ep.KeyRing
+	if keys == nil {
+		keys = d.opts.KeyRing
+	}
+	if keys != nil {
+		sig, err := Sign(keys, time.Now(), ev.Payload)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set(HeaderSignature, sig)
+	}
+
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Deliveries queries delivery log.
+func (d *Dispatcher) Deliveries(ctx context.Context, f Filter) ([]Delivery, error) {
+	return d.opts.Log.Query(ctx, f)
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
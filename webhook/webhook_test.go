@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"azugo.io/core/keyring"
+	"azugo.io/core/queue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliverSigned(t *testing.T) {
+	keys := keyring.New(keyring.Key{ID: "k1", Secret: []byte("secret")})
+
+	received := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- Verify(keys, r.Header.Get(HeaderSignature), body, time.Minute)
+	}))
+	t.Cleanup(srv.Close)
+
+	d := New(KeyRing{keys})
+	require.NoError(t, d.Start(context.Background()))
+	t.Cleanup(d.Stop)
+
+	require.NoError(t, d.Dispatch(Endpoint{ID: "ep", URL: srv.URL}, Event{Type: "test", Payload: []byte(`{"a":1}`)}))
+
+	select {
+	case err := <-received:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("webhook not delivered")
+	}
+
+	require.Eventually(t, func() bool {
+		l, _ := d.Deliveries(context.TODO(), Filter{EndpointID: "ep"})
+		return len(l) == 1 && l[0].Success
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWebhookRetryDeadLetter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	q := queue.NewMemory(10)
+	t.Cleanup(q.Close)
+
+	dead := make(chan *queue.Message, 1)
+	require.NoError(t, q.Subscribe(context.Background(), "dead", func(_ context.Context, msg *queue.Message) error {
+		dead <- msg
+		return nil
+	}))
+
+	d := New(
+		MaxAttempts(3),
+		Backoff{Min: time.Millisecond, Max: 5 * time.Millisecond},
+		CircuitBreaker{Threshold: 10, Cooldown: time.Second},
+		DeadLetter{Publisher: q, Topic: "dead"},
+	)
+	require.NoError(t, d.Start(context.Background()))
+	t.Cleanup(d.Stop)
+
+	require.NoError(t, d.Dispatch(Endpoint{ID: "ep", URL: srv.URL}, Event{ID: "ev1", Payload: []byte(`{}`)}))
+
+	select {
+	case msg := <-dead:
+		assert.Equal(t, "ev1", msg.Headers[HeaderID])
+		assert.Equal(t, "3", msg.Headers["Attempts"])
+	case <-time.After(time.Second):
+		t.Fatal("webhook not dead-lettered")
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	l, err := d.Deliveries(context.TODO(), Filter{EventID: "ev1", FailedOnly: true})
+	require.NoError(t, err)
+	require.Len(t, l, 3)
+	assert.Equal(t, 3, l[0].Attempt)
+	assert.Equal(t, http.StatusInternalServerError, l[0].StatusCode)
+}
+
+func TestWebhookCircuitBreaker(t *testing.T) {
+	b := &breaker{threshold: 2, cooldown: time.Minute}
+	now := time.Now()
+
+	assert.True(t, b.allow(now))
+	b.failure(now)
+	assert.True(t, b.allow(now))
+	b.failure(now)
+	assert.False(t, b.allow(now))
+
+	// Half-open allows single request.
+	assert.True(t, b.allow(now.Add(2*time.Minute)))
+	assert.False(t, b.allow(now.Add(2*time.Minute)))
+	b.success()
+	assert.True(t, b.allow(now.Add(2*time.Minute)))
+}
+
+func TestVerifyInvalidSignature(t *testing.T) {
+	keys := keyring.New(keyring.Key{ID: "k1", Secret: []byte("secret")})
+
+	sig, err := Sign(keys, time.Now().Add(-time.Hour), []byte("body"))
+	require.NoError(t, err)
+
+	assert.NoError(t, Verify(keys, sig, []byte("body"), 0))
+	assert.ErrorIs(t, Verify(keys, sig, []byte("body"), time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(keys, sig, []byte("other"), 0), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(keys, "garbage", []byte("body"), 0), ErrInvalidSignature)
+}